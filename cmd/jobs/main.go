@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"trade_company/internal/config"
+	"trade_company/internal/database"
+	"trade_company/internal/jobs"
+)
+
+const usage = `Usage: jobs <task> [flags]
+
+Tasks:
+  backfill-stats  Rebuild the admin daily stats snapshots from the raw tables
+  check-enums     List role and status values outside their enums
+`
+
+func main() {
+	// Load environment variables
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "backfill-stats":
+		backfillStats(os.Args[2:])
+	case "check-enums":
+		checkEnums()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown task %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// backfillStats rebuilds daily_stats for a range of days. Days are Taipei
+// calendar days; the default range is the last 90 finished days.
+func backfillStats(args []string) {
+	yesterday := jobs.StatsDay(time.Now()).AddDate(0, 0, -1)
+
+	fs := flag.NewFlagSet("backfill-stats", flag.ExitOnError)
+	from := fs.String("from", yesterday.AddDate(0, 0, -89).Format("2006-01-02"), "First day to rebuild (YYYY-MM-DD)")
+	to := fs.String("to", yesterday.Format("2006-01-02"), "Last day to rebuild (YYYY-MM-DD)")
+	_ = fs.Parse(args)
+
+	fromDay, err := time.ParseInLocation("2006-01-02", *from, time.Local)
+	if err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	toDay, err := time.ParseInLocation("2006-01-02", *to, time.Local)
+	if err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+	if toDay.Before(fromDay) {
+		log.Fatal("-to must not be before -from")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	db, err := database.Connect(cfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	log.Printf("Rebuilding daily stats from %s to %s...", *from, *to)
+	n, err := jobs.BackfillDailyStats(db, fromDay, toDay)
+	if err != nil {
+		log.Fatalf("Backfill failed after %d days: %v", n, err)
+	}
+	log.Printf("Backfill completed: %d days", n)
+}
+
+// checkEnums reports the rows that would keep the enum constraint migration
+// from applying, and exits non-zero if there are any
+func checkEnums() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := database.Connect(cfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	violations, err := database.NonconformingEnumValues(db)
+	if err != nil {
+		log.Fatalf("Check failed: %v", err)
+	}
+	if len(violations) == 0 {
+		log.Println("All role and status values conform")
+		return
+	}
+	for _, v := range violations {
+		fmt.Println(v)
+	}
+	os.Exit(1)
+}
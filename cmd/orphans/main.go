@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"sort"
+
+	"github.com/joho/godotenv"
+
+	"trade_company/internal/config"
+	"trade_company/internal/database"
+	"trade_company/internal/jobs"
+)
+
+func main() {
+	// Load environment variables
+	_ = godotenv.Load()
+
+	// Parse command line flags
+	dryRun := flag.Bool("dry-run", false, "Report orphaned records without changing anything")
+	mode := flag.String("mode", jobs.OrphanModeDelete, `"delete" or "reassign"`)
+	reassignTo := flag.Uint("reassign-to", 0, "User ID that takes over messages, leads and transactions of missing users (reassign mode)")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	db, err := database.Connect(cfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	opts := jobs.OrphanOptions{
+		Mode:       *mode,
+		ReassignTo: uint(*reassignTo),
+		DryRun:     *dryRun,
+	}
+
+	log.Printf("Scanning for orphaned records (mode=%s, dry_run=%t)...", *mode, *dryRun)
+	result, err := jobs.FixOrphans(db, opts)
+	refs := make([]string, 0, len(result.Counts))
+	for ref := range result.Counts {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	for _, ref := range refs {
+		if n := result.Counts[ref]; n > 0 {
+			log.Printf("  %s: %d", ref, n)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Orphan cleanup failed after %d records: %v", result.Total, err)
+	}
+
+	if *dryRun {
+		log.Printf("Dry run: found %d orphaned records", result.Total)
+		return
+	}
+	log.Printf("Orphan cleanup completed: %d records fixed", result.Total)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"trade_company/internal/config"
+	"trade_company/internal/database"
+	"trade_company/internal/jobs"
+)
+
+func main() {
+	// Load environment variables
+	_ = godotenv.Load()
+
+	// Parse command line flags
+	dryRun := flag.Bool("dry-run", false, "Report what would be purged without changing anything")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	db, err := database.Connect(cfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	opts := jobs.RetentionOptions{
+		LeadsOlderThan:    time.Duration(cfg.RetentionLeadsDays) * 24 * time.Hour,
+		MessagesOlderThan: time.Duration(cfg.RetentionMessagesDays) * 24 * time.Hour,
+		Anonymize:         cfg.RetentionMode == "anonymize",
+		BatchSize:         cfg.RetentionBatchSize,
+		DryRun:            *dryRun,
+	}
+
+	log.Printf("Starting data-retention purge (mode=%s, leads>%dd, messages>%dd, dry_run=%t)...",
+		cfg.RetentionMode, cfg.RetentionLeadsDays, cfg.RetentionMessagesDays, *dryRun)
+	result, err := jobs.PurgeOldRecords(db, opts, time.Now())
+	if err != nil {
+		log.Fatalf("Purge failed after %d leads and %d messages: %v", result.Leads, result.Messages, err)
+	}
+
+	if *dryRun {
+		log.Printf("Dry run: would purge %d leads and %d messages", result.Leads, result.Messages)
+		return
+	}
+	log.Printf("Purge completed: %d leads and %d messages", result.Leads, result.Messages)
+}
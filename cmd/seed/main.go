@@ -1,12 +1,22 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"trade_company/internal/config"
 	"trade_company/internal/database"
 )
 
 func main() {
+	var opts database.SeedOptions
+	flag.IntVar(&opts.Users, "users", 0, "number of extra users to generate")
+	flag.IntVar(&opts.Listings, "listings", 0, "number of extra listings to generate")
+	flag.Int64Var(&opts.Seed, "seed", 1, "random seed for generated data; the same seed gives the same data")
+	flag.Parse()
+	if opts.Users < 0 || opts.Listings < 0 {
+		log.Fatal("-users and -listings must not be negative")
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -21,7 +31,7 @@ func main() {
 
 	// Run seed data
 	log.Println("Starting database seeding...")
-	if err := database.SeedData(db, cfg); err != nil {
+	if err := database.SeedDataWithOptions(db, cfg, opts); err != nil {
 		log.Fatalf("Failed to seed database: %v", err)
 	}
 
@@ -25,14 +25,21 @@ import (
 
 	"github.com/joho/godotenv"
 
+	"trade_company/internal/auth"
 	"trade_company/internal/config"
 	"trade_company/internal/database"
+	"trade_company/internal/jobs"
 	"trade_company/internal/logger"
 	"trade_company/internal/models"
+	"trade_company/internal/moderation"
 	"trade_company/internal/redisclient"
 	"trade_company/internal/router"
+	"trade_company/internal/storage"
+	"trade_company/internal/translate"
+	"trade_company/internal/uploads"
 
 	redis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -61,6 +68,14 @@ func main() {
 	zapLogger := logger.New(cfg.AppEnv)
 	defer zapLogger.Sync() // Flush any buffered log entries on exit
 
+	// Time one password hash so operators can see what BCRYPT_COST costs on
+	// this machine; every login and registration pays it
+	if took, err := auth.BenchmarkBcrypt(cfg.BcryptCost); err != nil {
+		zapLogger.Error("bcrypt benchmark failed", logger.Err(err))
+	} else {
+		zapLogger.Info("bcrypt benchmark", zap.Int("cost", cfg.BcryptCost), zap.Duration("hash_time", took))
+	}
+
 	// Database Connection with Retry Logic
 	// Attempt to connect to MySQL database with exponential backoff
 	// The service can start without database connection for health checks
@@ -133,6 +148,53 @@ func main() {
 	// Creates Gin router with all routes, middleware, and dependencies injected
 	engine := router.NewRouter(cfg, zapLogger, db, redisClient)
 
+	// Background Jobs
+	// Finalize listings whose deletion undo window has passed, rebuild the
+	// category/industry counts, expire accounts that never verified their email,
+	// ask returning sellers to confirm their listings, snapshot the admin daily
+	// stats, delete expired refresh tokens, email digests of unread leads and
+	// messages, moderate newly uploaded listing images and translate listings
+	// queued for English. With Redis, abandoned resumable uploads are swept and trending
+	// scores decayed.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if db != nil {
+		go jobs.RunListingCleanup(jobsCtx, db, storage.New(cfg), zapLogger, jobs.ListingCleanupInterval)
+		go jobs.RunListingCountReconciliation(jobsCtx, db, zapLogger, jobs.ListingCountReconcileInterval, cfg.ListingCountDriftThreshold)
+		go jobs.RunUnverifiedAccountCleanup(jobsCtx, db, auth.NewEmailService(cfg), zapLogger, jobs.UnverifiedAccountOptions{
+			MaxAge:    cfg.UnverifiedAccountTTL(),
+			Anonymize: cfg.UnverifiedAccountMode == "anonymize",
+		}, jobs.UnverifiedAccountInterval)
+		go jobs.RunKeepAlive(jobsCtx, db, auth.NewEmailService(cfg), zapLogger, cfg.KeepAliveGrace(), jobs.KeepAliveInterval)
+		go jobs.RunDailyStats(jobsCtx, db, zapLogger, jobs.DailyStatsInterval)
+		go jobs.RunRefreshTokenCleanup(jobsCtx, db, zapLogger, jobs.RefreshTokenCleanupInterval)
+		if cfg.DigestIntervalHours > 0 {
+			every := time.Duration(cfg.DigestIntervalHours) * time.Hour
+			go jobs.RunDigests(jobsCtx, db, auth.NewEmailService(cfg), zapLogger, every, jobs.DigestCheckInterval)
+		}
+
+		checker, err := moderation.NewChecker(cfg)
+		if err != nil {
+			zapLogger.Fatal("Invalid image moderation configuration", logger.Err(err))
+		}
+		go jobs.RunImageModeration(jobsCtx, db, storage.New(cfg), checker, zapLogger, jobs.ImageModerationInterval)
+
+		if cfg.TranslationProvider != translate.ProviderNone {
+			translator, err := translate.NewTranslator(cfg)
+			if err != nil {
+				zapLogger.Fatal("Invalid translation configuration", logger.Err(err))
+			}
+			go jobs.RunTranslations(jobsCtx, db, translator, zapLogger, jobs.TranslationInterval, cfg.TranslationJobsPerMinute, int64(cfg.TranslationMonthlyCharLimit))
+		}
+	}
+	if uploadManager := uploads.NewManager(redisClient, cfg); uploadManager != nil {
+		go jobs.RunUploadCleanup(jobsCtx, uploadManager, zapLogger, jobs.UploadCleanupInterval)
+	}
+	if trending := redisclient.NewTrending(redisClient); trending != nil {
+		halfLife := time.Duration(cfg.TrendingHalfLifeHours) * time.Hour
+		go jobs.RunTrendingDecay(jobsCtx, trending, zapLogger, jobs.TrendingDecayInterval, halfLife)
+	}
+
 	// HTTP Server Configuration
 	srv := &http.Server{
 		Addr:              ":" + cfg.AppPort,        // Listen on configured port (default: 8080)
@@ -162,6 +224,7 @@ func main() {
 	<-quit // Block until signal received
 	
 	zapLogger.Info("Shutdown signal received, initiating graceful shutdown...")
+	stopJobs()
 	
 	// Give server 10 seconds to finish handling existing requests
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
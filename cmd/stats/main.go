@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+
+	"trade_company/internal/config"
+	"trade_company/internal/database"
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+)
+
+// stats prints a read-only snapshot of the marketplace: totals, listings by
+// status, the lead read rate, today's activity and connection pool stats.
+func main() {
+	// Load environment variables
+	_ = godotenv.Load()
+
+	format := flag.String("format", "table", `Output format: "table" or "json"`)
+	flag.Parse()
+	if *format != "table" && *format != "json" {
+		log.Fatalf("Unknown -format %q", *format)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	db, err := database.Connect(cfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	snap, err := jobs.TakeStatsSnapshot(db)
+	if err != nil {
+		log.Fatalf("Failed to take snapshot: %v", err)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(snap); err != nil {
+			log.Fatalf("Failed to write snapshot: %v", err)
+		}
+		return
+	}
+	printTable(snap)
+}
+
+func printTable(snap *jobs.StatsSnapshot) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Snapshot\t%s\n", snap.TakenAt.Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintln(w, "\t")
+	fmt.Fprintf(w, "Users\t%d\n", snap.Users)
+	fmt.Fprintf(w, "Listings\t%d\n", snap.Listings)
+	for _, s := range models.ListingStatuses {
+		fmt.Fprintf(w, "  %s\t%d\n", s, snap.ListingsByStatus[s])
+	}
+	fmt.Fprintf(w, "Transactions\t%d\n", snap.Transactions)
+	fmt.Fprintf(w, "Leads\t%d (%d read, %.1f%%)\n", snap.Leads, snap.LeadsRead, snap.LeadReadRate*100)
+	fmt.Fprintln(w, "\t")
+	fmt.Fprintf(w, "Today (%s)\t\n", snap.Today.Date.Format("2006-01-02"))
+	fmt.Fprintf(w, "  New users\t%d\n", snap.Today.NewUsers)
+	fmt.Fprintf(w, "  New listings\t%d\n", snap.Today.NewListings)
+	fmt.Fprintf(w, "  Leads\t%d\n", snap.Today.Leads)
+	fmt.Fprintf(w, "  Transactions completed\t%d (NT$%d)\n", snap.Today.TransactionsCompleted, snap.Today.TransactionVolume)
+	fmt.Fprintf(w, "  Active users\t%d\n", snap.Today.ActiveUsers)
+	fmt.Fprintln(w, "\t")
+	p := snap.DBPool
+	fmt.Fprintf(w, "DB pool\topen %d/%d, in use %d, idle %d, waits %d (%s)\n", p.Open, p.MaxOpen, p.InUse, p.Idle, p.WaitCount, p.WaitDuration)
+}
@@ -59,6 +59,7 @@ if [ $? -eq 0 ]; then
             --cpu 1 \
             --max-instances 10 \
             --set-env-vars "APP_ENV=production,APP_NAME=BusinessExchange,DB_HOST=127.0.0.1,DB_PORT=3306,DB_USER=app,DB_PASSWORD=app_password,DB_NAME=business_exchange,CLOUDSQL_CONNECTION_NAME=${PROJECT_ID}:${REGION}-c:trade-sql" \
+            --set-secrets "UPLOAD_SIGNING_SECRET=upload-signing-secret:latest" \
             --add-cloudsql-instances ${PROJECT_ID}:${REGION}-c:trade-sql \
             --timeout 300 \
             --cpu-boost
@@ -23,6 +23,7 @@ gcloud run deploy business-exchange-backend \
     --cpu 1 \
     --max-instances 10 \
     --set-env-vars "APP_ENV=production,APP_NAME=BusinessExchange,DB_HOST=127.0.0.1,DB_PORT=3306,DB_USER=app,DB_PASSWORD=app_password,DB_NAME=business_exchange,CLOUDSQL_CONNECTION_NAME=businessexchange-468413:us-central1-c:trade-sql" \
+    --set-secrets "UPLOAD_SIGNING_SECRET=upload-signing-secret:latest" \
     --add-cloudsql-instances businessexchange-468413:us-central1-c:trade-sql
 
 # Get backend URL
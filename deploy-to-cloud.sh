@@ -55,7 +55,8 @@ gcloud run deploy ${SERVICE_NAME} \
     --set-env-vars "DB_NAME=business_exchange" \
     --set-env-vars "JWT_ISSUER=${PROJECT_ID}" \
     --set-env-vars "JWT_SECRET=your-production-jwt-secret-change-me" \
-    --set-env-vars "CORS_ALLOWED_ORIGINS=*" \
+    --set-env-vars "CORS_ALLOWED_ORIGINS=https://business-exchange-frontend-430730011391.us-central1.run.app" \
+    --set-secrets "UPLOAD_SIGNING_SECRET=upload-signing-secret:latest" \
     --add-cloudsql-instances ${CLOUDSQL_CONNECTION_NAME}
 
 # 4. 獲取服務 URL
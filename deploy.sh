@@ -59,6 +59,7 @@ if [ $? -eq 0 ]; then
             --cpu 1 \
             --max-instances 10 \
             --set-env-vars "APP_ENV=production,APP_NAME=BusinessExchange,DB_HOST=/cloudsql/${PROJECT_ID}:${REGION}:trade-sql,DB_USER=app,DB_PASSWORD=app_password,DB_NAME=business_exchange,JWT_SECRET=your-production-jwt-secret-change-me" \
+            --set-secrets "UPLOAD_SIGNING_SECRET=upload-signing-secret:latest" \
             --add-cloudsql-instances ${PROJECT_ID}:${REGION}:trade-sql
         
         if [ $? -eq 0 ]; then
@@ -44,7 +44,8 @@ gcloud run services update ${SERVICE_NAME} \
     --set-env-vars "DB_PASSWORD=app_password" \
     --set-env-vars "DB_NAME=business_exchange" \
     --set-env-vars "JWT_SECRET=your-production-secret-key" \
-    --set-env-vars "REDIS_ADDR=10.80.0.3:6379"
+    --set-env-vars "REDIS_ADDR=10.80.0.3:6379" \
+    --update-secrets "UPLOAD_SIGNING_SECRET=upload-signing-secret:latest"
 
 # 4. 重新部署服務
 echo "🚀 重新部署服務..."
@@ -41,6 +41,7 @@ DB_PASSWORD: "app_password"
 DB_NAME: "business_exchange"
 JWT_SECRET: "your-production-secret-key-change-this"
 JWT_ISSUER: "businessexchange-468413"
+# UPLOAD_SIGNING_SECRET comes from Secret Manager (upload-signing-secret), not this file
 EOF
 
 # 設置環境變數
@@ -48,7 +49,8 @@ echo "🔧 設置環境變數..."
 gcloud run services update ${SERVICE_NAME} \
     --region ${REGION} \
     --project ${PROJECT_ID} \
-    --env-vars-file env-vars-simple.yaml
+    --env-vars-file env-vars-simple.yaml \
+    --update-secrets "UPLOAD_SIGNING_SECRET=upload-signing-secret:latest"
 
 # 清理臨時文件
 rm -f env-vars-simple.yaml
@@ -45,10 +45,11 @@ REDIS_PASSWORD: ""
 REDIS_DB: "0"
 JWT_SECRET: "your-production-secret-key-change-this"
 JWT_ISSUER: "businessexchange-468413"
+# UPLOAD_SIGNING_SECRET comes from Secret Manager (upload-signing-secret), not this file
 JWT_EXPIRY: "24h"
-CORS_ALLOW_ORIGINS: "*"
-CORS_ALLOW_METHODS: "GET,POST,PUT,DELETE,OPTIONS"
-CORS_ALLOW_HEADERS: "*"
+CORS_ALLOWED_ORIGINS: "https://business-exchange-frontend-430730011391.us-central1.run.app"
+CORS_ALLOWED_METHODS: "GET,POST,PUT,PATCH,DELETE,OPTIONS"
+CORS_ALLOWED_HEADERS: "Origin,Content-Type,Content-Length,Accept,Accept-Encoding,Authorization,X-CSRF-Token,X-Request-ID,X-Chunk-Checksum"
 FILE_UPLOAD_MAX_SIZE: "10MB"
 FILE_UPLOAD_ALLOWED_TYPES: "image/jpeg,image/png,image/gif"
 PAGINATION_DEFAULT_LIMIT: "20"
@@ -60,7 +61,8 @@ echo "🔧 設置環境變數..."
 gcloud run services update ${SERVICE_NAME} \
     --region ${REGION} \
     --project ${PROJECT_ID} \
-    --env-vars-file env-vars.yaml
+    --env-vars-file env-vars.yaml \
+    --update-secrets "UPLOAD_SIGNING_SECRET=upload-signing-secret:latest"
 
 # 清理臨時文件
 rm -f env-vars.yaml
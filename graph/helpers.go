@@ -2,10 +2,48 @@ package graph
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
+
+	"trade_company/graph/model"
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
 )
 
 var ErrUnauthorized = errors.New("unauthorized")
+var ErrNotFound = errors.New("not found")
+var ErrDescriptionTooLong = errors.New("description is too long")
+var ErrInvalidID = errors.New("invalid ID")
+var ErrInternal = errors.New("internal error")
+
+// lookupError reports a failed lookup as ErrNotFound only when the row doesn't
+// exist; other database failures become ErrInternal, without their details
+func lookupError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return ErrInternal
+}
+
+// checkListingPrice applies the same price bounds as the REST API
+func checkListingPrice(cfg *config.Config, price int64) error {
+	if price < cfg.ListingPriceMin || price > cfg.ListingPriceMax {
+		return fmt.Errorf("price must be between %d and %d", cfg.ListingPriceMin, cfg.ListingPriceMax)
+	}
+	return nil
+}
+
+// parseID converts a GraphQL ID argument to a database ID
+func parseID(id string) (uint64, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidID
+	}
+	return n, nil
+}
 
 func coalesceStrPtr(s *string) string {
 	if s == nil {
@@ -21,3 +59,39 @@ func timePtrToStringPtr(t *time.Time) *string {
 	s := t.UTC().Format(time.RFC3339Nano)
 	return &s
 }
+
+func uintPtrToIDPtr(id *uint) *string {
+	if id == nil {
+		return nil
+	}
+	s := strconv.FormatUint(uint64(*id), 10)
+	return &s
+}
+
+func toModelMessage(m *models.Message) *model.Message {
+	subject := m.Subject
+	return &model.Message{
+		ID:         strconv.FormatUint(uint64(m.ID), 10),
+		SenderID:   strconv.FormatUint(uint64(m.SenderID), 10),
+		ReceiverID: strconv.FormatUint(uint64(m.ReceiverID), 10),
+		ListingID:  uintPtrToIDPtr(m.ListingID),
+		Subject:    &subject,
+		Content:    m.Content,
+		IsRead:     m.IsRead,
+		ReadAt:     timePtrToStringPtr(m.ReadAt),
+		CreatedAt:  timePtrToStringPtr(&m.CreatedAt),
+	}
+}
+
+func toModelLead(l *models.Lead) *model.Lead {
+	return &model.Lead{
+		ID:         strconv.FormatUint(uint64(l.ID), 10),
+		SenderID:   strconv.FormatUint(uint64(l.SenderID), 10),
+		ReceiverID: strconv.FormatUint(uint64(l.ReceiverID), 10),
+		ListingID:  uintPtrToIDPtr(l.ListingID),
+		Subject:    l.Subject,
+		Message:    l.Message,
+		IsRead:     l.IsRead,
+		CreatedAt:  timePtrToStringPtr(&l.CreatedAt),
+	}
+}
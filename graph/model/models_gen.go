@@ -13,6 +13,17 @@ type CreateListingInput struct {
 	Location    *string `json:"location,omitempty"`
 }
 
+type Lead struct {
+	ID         string  `json:"id"`
+	SenderID   string  `json:"senderID"`
+	ReceiverID string  `json:"receiverID"`
+	ListingID  *string `json:"listingID,omitempty"`
+	Subject    string  `json:"subject"`
+	Message    string  `json:"message"`
+	IsRead     bool    `json:"isRead"`
+	CreatedAt  *string `json:"createdAt,omitempty"`
+}
+
 type Listing struct {
 	ID          string  `json:"id"`
 	Title       string  `json:"title"`
@@ -24,6 +35,18 @@ type Listing struct {
 	UpdatedAt   *string `json:"updatedAt,omitempty"`
 }
 
+type Message struct {
+	ID         string  `json:"id"`
+	SenderID   string  `json:"senderID"`
+	ReceiverID string  `json:"receiverID"`
+	ListingID  *string `json:"listingID,omitempty"`
+	Subject    *string `json:"subject,omitempty"`
+	Content    string  `json:"content"`
+	IsRead     bool    `json:"isRead"`
+	ReadAt     *string `json:"readAt,omitempty"`
+	CreatedAt  *string `json:"createdAt,omitempty"`
+}
+
 type Mutation struct {
 }
 
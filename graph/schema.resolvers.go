@@ -7,21 +7,22 @@ package graph
 import (
 	"context"
 	"strconv"
+	"strings"
+	"time"
 	"trade_company/graph/model"
 	"trade_company/internal/auth"
 	gqlctx "trade_company/internal/graphql"
 	"trade_company/internal/models"
-
-	"golang.org/x/crypto/bcrypt"
+	"trade_company/internal/sanitize"
 )
 
 // Register is the resolver for the register field.
 func (r *mutationResolver) Register(ctx context.Context, email string, password string) (*model.AuthPayload, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := auth.HashPassword(password, r.Cfg.BcryptCost)
 	if err != nil {
 		return nil, err
 	}
-	user := models.User{Email: email, PasswordHash: string(hash)}
+	user := models.User{Email: email, PasswordHash: hash}
 	if err := r.DB.Create(&user).Error; err != nil {
 		return nil, err
 	}
@@ -38,7 +39,7 @@ func (r *mutationResolver) Login(ctx context.Context, email string, password str
 	if err := r.DB.Where("email = ?", email).First(&user).Error; err != nil {
 		return nil, err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	if err := auth.CheckPassword(user.PasswordHash, password); err != nil {
 		return nil, err
 	}
 	token, err := auth.GenerateToken(r.Cfg, user.ID, user.Email)
@@ -54,22 +55,30 @@ func (r *mutationResolver) CreateListing(ctx context.Context, input model.Create
 	if !ok {
 		return nil, ErrUnauthorized
 	}
+	if err := checkListingPrice(r.Cfg, int64(input.Price)); err != nil {
+		return nil, err
+	}
+	desc, descText := sanitize.Description(strings.TrimSpace(coalesceStrPtr(input.Description)))
+	if sanitize.DescriptionTooLong(descText) {
+		return nil, ErrDescriptionTooLong
+	}
 	ls := models.Listing{
-		Title:       input.Title,
-		Description: coalesceStrPtr(input.Description),
-		Price:       int64(input.Price),
-		Location:    coalesceStrPtr(input.Location),
-		OwnerID:     userID,
+		Title:           input.Title,
+		Description:     desc,
+		DescriptionText: descText,
+		Price:           int64(input.Price),
+		Location:        coalesceStrPtr(input.Location),
+		OwnerID:         userID,
 	}
 	if err := r.DB.Create(&ls).Error; err != nil {
 		return nil, err
 	}
-	desc := ls.Description
+	descHTML := ls.DescriptionHTML()
 	loc := ls.Location
 	return &model.Listing{
 		ID:          strconv.FormatUint(uint64(ls.ID), 10),
 		Title:       ls.Title,
-		Description: &desc,
+		Description: &descHTML,
 		Price:       int(ls.Price),
 		Location:    &loc,
 		OwnerID:     strconv.FormatUint(uint64(ls.OwnerID), 10),
@@ -78,6 +87,54 @@ func (r *mutationResolver) CreateListing(ctx context.Context, input model.Create
 	}, nil
 }
 
+// MarkMessageRead is the resolver for the markMessageRead field.
+func (r *mutationResolver) MarkMessageRead(ctx context.Context, id string) (*model.Message, error) {
+	userID, ok := gqlctx.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	idUint, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	var msg models.Message
+	if err := r.DB.Where("id = ? AND receiver_id = ?", idUint, userID).First(&msg).Error; err != nil {
+		return nil, lookupError(err)
+	}
+	if !msg.IsRead || msg.ReadAt == nil {
+		now := time.Now()
+		if err := r.DB.Model(&msg).Updates(map[string]interface{}{"is_read": true, "read_at": &now}).Error; err != nil {
+			return nil, err
+		}
+		msg.IsRead = true
+		msg.ReadAt = &now
+	}
+	return toModelMessage(&msg), nil
+}
+
+// MarkLeadRead is the resolver for the markLeadRead field.
+func (r *mutationResolver) MarkLeadRead(ctx context.Context, id string) (*model.Lead, error) {
+	userID, ok := gqlctx.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	idUint, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	var lead models.Lead
+	if err := r.DB.Where("id = ? AND receiver_id = ?", idUint, userID).First(&lead).Error; err != nil {
+		return nil, lookupError(err)
+	}
+	if !lead.IsRead {
+		if err := r.DB.Model(&lead).Update("is_read", true).Error; err != nil {
+			return nil, err
+		}
+		lead.IsRead = true
+	}
+	return toModelLead(&lead), nil
+}
+
 // Me is the resolver for the me field.
 func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
 	userID, ok := gqlctx.UserIDFromContext(ctx)
@@ -98,16 +155,17 @@ func (r *queryResolver) Listings(ctx context.Context, limit *int) ([]*model.List
 		l = *limit
 	}
 	var listings []models.Listing
-	if err := r.DB.Order("id desc").Limit(l).Find(&listings).Error; err != nil {
+	if err := r.DB.Where("status NOT IN ? AND visibility = ?", models.HiddenListingStatuses, models.ListingVisibilityPublic).Order("id desc").Limit(l).Find(&listings).Error; err != nil {
 		return nil, err
 	}
 	result := make([]*model.Listing, 0, len(listings))
 	for _, ls := range listings {
 		lsCopy := ls
+		descHTML := lsCopy.DescriptionHTML()
 		result = append(result, &model.Listing{
 			ID:          strconv.FormatUint(uint64(lsCopy.ID), 10),
 			Title:       lsCopy.Title,
-			Description: &lsCopy.Description,
+			Description: &descHTML,
 			Price:       int(lsCopy.Price),
 			Location:    &lsCopy.Location,
 			OwnerID:     strconv.FormatUint(uint64(lsCopy.OwnerID), 10),
@@ -122,15 +180,15 @@ func (r *queryResolver) Listings(ctx context.Context, limit *int) ([]*model.List
 func (r *queryResolver) Listing(ctx context.Context, id string) (*model.Listing, error) {
 	idUint, _ := strconv.ParseUint(id, 10, 64)
 	var ls models.Listing
-	if err := r.DB.First(&ls, idUint).Error; err != nil {
+	if err := r.DB.Where("status NOT IN ? AND visibility <> ?", models.HiddenListingStatuses, models.ListingVisibilityPrivate).First(&ls, idUint).Error; err != nil {
 		return nil, nil
 	}
-	desc := ls.Description
+	descHTML := ls.DescriptionHTML()
 	loc := ls.Location
 	return &model.Listing{
 		ID:          strconv.FormatUint(uint64(ls.ID), 10),
 		Title:       ls.Title,
-		Description: &desc,
+		Description: &descHTML,
 		Price:       int(ls.Price),
 		Location:    &loc,
 		OwnerID:     strconv.FormatUint(uint64(ls.OwnerID), 10),
@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gqlctx "trade_company/internal/graphql"
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestResolver(t *testing.T) *Resolver {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Message{}, &models.Lead{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return &Resolver{DB: db}
+}
+
+func TestMarkRead(t *testing.T) {
+	const sender, receiver = 1, 2
+	tests := []struct {
+		name    string
+		userID  uint // 0 means signed out
+		id      string
+		wantErr error
+	}{
+		{name: "receiver marks it read", userID: receiver, id: "1"},
+		{name: "signed out", id: "1", wantErr: ErrUnauthorized},
+		{name: "sender cannot mark it", userID: sender, id: "1", wantErr: ErrNotFound},
+		{name: "unknown ID", userID: receiver, id: "99", wantErr: ErrNotFound},
+		{name: "non-numeric ID", userID: receiver, id: "abc", wantErr: ErrInvalidID},
+		{name: "negative ID", userID: receiver, id: "-1", wantErr: ErrInvalidID},
+		{name: "empty ID", userID: receiver, id: "", wantErr: ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestResolver(t)
+			r.DB.Create(&models.Message{SenderID: sender, ReceiverID: receiver, Content: "Hello"})
+			r.DB.Create(&models.Lead{SenderID: sender, ReceiverID: receiver, Subject: "Hi", Message: "Hello"})
+			ctx := context.Background()
+			if tt.userID != 0 {
+				ctx = gqlctx.WithUserID(ctx, tt.userID)
+			}
+
+			msg, err := r.Mutation().MarkMessageRead(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MarkMessageRead error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (!msg.IsRead || msg.ReadAt == nil) {
+				t.Errorf("message not marked read: %+v", msg)
+			}
+
+			lead, err := r.Mutation().MarkLeadRead(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MarkLeadRead error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !lead.IsRead {
+				t.Errorf("lead not marked read: %+v", lead)
+			}
+
+			var unread int64
+			r.DB.Model(&models.Lead{}).Where("is_read = ?", false).Count(&unread)
+			if wantUnread := tt.wantErr != nil; (unread == 1) != wantUnread {
+				t.Errorf("%d unread leads after the call", unread)
+			}
+		})
+	}
+}
+
+func TestMarkReadDatabaseError(t *testing.T) {
+	r := newTestResolver(t)
+	sqlDB, _ := r.DB.DB()
+	sqlDB.Close()
+	ctx := gqlctx.WithUserID(context.Background(), 2)
+
+	if _, err := r.Mutation().MarkMessageRead(ctx, "1"); !errors.Is(err, ErrInternal) {
+		t.Errorf("MarkMessageRead error %v, want %v", err, ErrInternal)
+	}
+	if _, err := r.Mutation().MarkLeadRead(ctx, "1"); !errors.Is(err, ErrInternal) {
+		t.Errorf("MarkLeadRead error %v, want %v", err, ErrInternal)
+	}
+}
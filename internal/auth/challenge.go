@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"trade_company/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// turnstileVerifyURL is Cloudflare Turnstile's server-side token check
+const turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+// ChallengeVerifier checks a challenge token solved by the client
+type ChallengeVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// TurnstileVerifier verifies Cloudflare Turnstile tokens
+type TurnstileVerifier struct {
+	secret string
+	client *http.Client
+}
+
+// NewTurnstileVerifier creates a verifier using the site's secret key.
+func NewTurnstileVerifier(secret string) *TurnstileVerifier {
+	return &TurnstileVerifier{
+		secret: secret,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Verify asks Turnstile whether the token is valid. An error means the check
+// could not be made, not that the token was rejected.
+func (v *TurnstileVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, turnstileVerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach Turnstile: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("turnstile returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode Turnstile response: %w", err)
+	}
+	return result.Success, nil
+}
+
+// LoginChallenge asks for a challenge token once an email or an IP has too many
+// failed logins in a row, tracked in Redis. Unlike a lockout, the right person
+// can still log in, which matters for offices sharing one IP.
+//
+// A nil *LoginChallenge is valid and never requires a challenge, so callers
+// don't need to check whether the feature is enabled.
+type LoginChallenge struct {
+	redisClient *redis.Client
+	verifier    ChallengeVerifier
+	threshold   int64
+	window      time.Duration
+}
+
+// NewLoginChallenge returns the login challenge tracker, or nil when the feature
+// is disabled or Redis is not configured.
+func NewLoginChallenge(redisClient *redis.Client, config *config.Config, verifier ChallengeVerifier) *LoginChallenge {
+	if !config.LoginChallengeEnabled || redisClient == nil {
+		return nil
+	}
+	return &LoginChallenge{
+		redisClient: redisClient,
+		verifier:    verifier,
+		threshold:   int64(config.LoginChallengeThreshold),
+		window:      time.Duration(config.LoginChallengeWindowMinutes) * time.Minute,
+	}
+}
+
+func challengeEmailKey(email string) string {
+	return fmt.Sprintf("login_challenge:email:%s", strings.ToLower(email))
+}
+
+func challengeIPKey(ip string) string {
+	return fmt.Sprintf("login_challenge:ip:%s", ip)
+}
+
+// Required reports whether the next login for this email or IP must carry a
+// challenge token. Redis errors don't require one.
+func (lc *LoginChallenge) Required(ctx context.Context, email, ip string) bool {
+	if lc == nil {
+		return false
+	}
+	counts, err := lc.redisClient.MGet(ctx, challengeEmailKey(email), challengeIPKey(ip)).Result()
+	if err != nil {
+		return false
+	}
+	for _, v := range counts {
+		if s, ok := v.(string); ok {
+			var n int64
+			if _, err := fmt.Sscan(s, &n); err == nil && n >= lc.threshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RecordFailure counts a failed login for the email and the IP and reports
+// whether the next attempt must carry a challenge token.
+func (lc *LoginChallenge) RecordFailure(ctx context.Context, email, ip string) bool {
+	if lc == nil {
+		return false
+	}
+	pipe := lc.redisClient.TxPipeline()
+	emailFailures := pipe.Incr(ctx, challengeEmailKey(email))
+	pipe.Expire(ctx, challengeEmailKey(email), lc.window)
+	ipFailures := pipe.Incr(ctx, challengeIPKey(ip))
+	pipe.Expire(ctx, challengeIPKey(ip), lc.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false
+	}
+	return emailFailures.Val() >= lc.threshold || ipFailures.Val() >= lc.threshold
+}
+
+// Verify checks a challenge token with the configured verifier.
+func (lc *LoginChallenge) Verify(ctx context.Context, token, ip string) (bool, error) {
+	if lc == nil {
+		return true, nil
+	}
+	if token == "" {
+		return false, nil
+	}
+	return lc.verifier.Verify(ctx, token, ip)
+}
+
+// Reset clears the email's failures after a successful login. The IP count is
+// left to expire so one working account can't clear the way for guessing others.
+func (lc *LoginChallenge) Reset(ctx context.Context, email string) {
+	if lc == nil {
+		return
+	}
+	lc.redisClient.Del(ctx, challengeEmailKey(email))
+}
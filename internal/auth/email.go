@@ -3,20 +3,69 @@ package auth
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"trade_company/internal/config"
+	"trade_company/internal/format"
 	"trade_company/internal/models"
 )
 
+// ErrEmailSuppressed is returned when a non-critical email is not sent because
+// the recipient's address hard-bounced or reported spam
+var ErrEmailSuppressed = errors.New("recipient email is undeliverable")
+
+// ErrEmailOptedOut is returned when an email is not sent because the recipient
+// turned its category off
+var ErrEmailOptedOut = errors.New("recipient opted out of these emails")
+
+// EmailCategory decides which of a user's email preferences apply to an email
+type EmailCategory string
+
+const (
+	// EmailTransactional emails concern the account itself or something the
+	// user did (verification, password resets, security alerts) and always send
+	EmailTransactional EmailCategory = "transactional"
+	// EmailNotification emails report activity, such as new leads; users turn
+	// them off with EmailNotifications
+	EmailNotification EmailCategory = "notification"
+	// EmailMarketing emails are promotional and only go to users who opted in
+	// with MarketingEmails
+	EmailMarketing EmailCategory = "marketing"
+)
+
 type EmailService struct {
 	config *config.Config
+	client *http.Client // SendGrid API client
 }
 
 func NewEmailService(config *config.Config) *EmailService {
 	return &EmailService{
 		config: config,
+		client: &http.Client{},
+	}
+}
+
+// ShouldSend reports whether the user's preferences allow an email of the
+// given category. Every Send method checks it before sending.
+func (es *EmailService) ShouldSend(user *models.User, category EmailCategory) bool {
+	switch category {
+	case EmailTransactional:
+		return true
+	case EmailNotification:
+		return user.EmailNotifications
+	case EmailMarketing:
+		return user.MarketingEmails
 	}
+	return false
+}
+
+// unsubscribeURL is the link in an email that turns its category off
+func (es *EmailService) unsubscribeURL(user *models.User, category EmailCategory) string {
+	return fmt.Sprintf("%s/unsubscribe/%s", es.config.APIBaseURL, UnsubscribeToken(es.config, user.ID, category))
 }
 
 // GenerateVerificationToken generates a random verification token
@@ -35,52 +84,203 @@ func (es *EmailService) GeneratePasswordResetToken() string {
 
 // SendVerificationEmail sends an email verification email
 func (es *EmailService) SendVerificationEmail(user *models.User, verificationToken string) error {
-	// In development, just log the email
-	if es.config.AppEnv == "development" {
-		es.logEmail(user.Email, "Verify Your Email - Business Exchange",
-			es.generateVerificationEmailText(user.FirstName, verificationToken))
-		return nil
+	if !es.ShouldSend(user, EmailTransactional) {
+		return ErrEmailOptedOut
 	}
+	subject := "Verify Your Email - Business Exchange"
 
-	// TODO: Implement SendGrid integration
-	// For now, just log the email
-	es.logEmail(user.Email, "Verify Your Email - Business Exchange",
+	return es.deliver(user, EmailTransactional, subject,
 		es.generateVerificationEmailText(user.FirstName, verificationToken))
-	return nil
 }
 
 // SendPasswordResetEmail sends a password reset email
 func (es *EmailService) SendPasswordResetEmail(user *models.User, resetToken string) error {
-	// In development, just log the email
-	if es.config.AppEnv == "development" {
-		es.logEmail(user.Email, "Reset Your Password - Business Exchange",
-			es.generatePasswordResetEmailText(user.FirstName, resetToken))
-		return nil
+	if !es.ShouldSend(user, EmailTransactional) {
+		return ErrEmailOptedOut
 	}
+	subject := "Reset Your Password - Business Exchange"
 
-	// TODO: Implement SendGrid integration
-	// For now, just log the email
-	es.logEmail(user.Email, "Reset Your Password - Business Exchange",
+	return es.deliver(user, EmailTransactional, subject,
 		es.generatePasswordResetEmailText(user.FirstName, resetToken))
-	return nil
 }
 
 // SendLeadNotification sends a notification to a seller about a new lead
 func (es *EmailService) SendLeadNotification(seller *models.User, lead *models.Lead) error {
+	if seller.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(seller, EmailNotification) {
+		return ErrEmailOptedOut
+	}
 	subject := fmt.Sprintf("New Lead: %s", lead.Subject)
 
-	// In development, just log the email
-	if es.config.AppEnv == "development" {
-		es.logEmail(seller.Email, subject,
-			es.generateLeadNotificationText(seller.FirstName, lead))
-		return nil
+	return es.deliver(seller, EmailNotification, subject,
+		es.generateLeadNotificationText(seller.FirstName, lead, es.unsubscribeURL(seller, EmailNotification)))
+}
+
+// SendLeadCopy copies a new lead to one of the extra addresses in the seller's
+// lead routing rule. The address has no account, so there are no preferences
+// to check; the seller removes it from the rule to stop the copies.
+func (es *EmailService) SendLeadCopy(address string, seller *models.User, lead *models.Lead) error {
+	subject := fmt.Sprintf("New Lead: %s", lead.Subject)
+
+	return es.deliver(&models.User{Email: address}, EmailTransactional, subject,
+		es.generateLeadCopyText(seller, lead))
+}
+
+// SendUnverifiedAccountReminder warns a user that their unverified account is about to be removed
+func (es *EmailService) SendUnverifiedAccountReminder(user *models.User, expiresAt time.Time) error {
+	if user.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(user, EmailTransactional) {
+		return ErrEmailOptedOut
+	}
+	subject := "Verify your email to keep your account - Business Exchange"
+
+	return es.deliver(user, EmailTransactional, subject,
+		es.generateUnverifiedReminderText(user.FirstName, user.EmailVerificationToken, expiresAt))
+}
+
+// DigestItem is one unread lead or message in a digest email
+type DigestItem struct {
+	From       string
+	Subject    string
+	Listing    string // Title of the listing it is about; empty if none
+	ReceivedAt time.Time
+}
+
+// Digest is the content of an unread leads/messages digest. Only the newest
+// items are listed; the More counts say how many others there are.
+type Digest struct {
+	Leads        []DigestItem
+	Messages     []DigestItem
+	MoreLeads    int
+	MoreMessages int
+}
+
+// SendUnreadDigest emails a user a summary of their unread leads and messages
+func (es *EmailService) SendUnreadDigest(user *models.User, digest Digest) error {
+	if user.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(user, EmailNotification) {
+		return ErrEmailOptedOut
+	}
+	total := len(digest.Leads) + digest.MoreLeads + len(digest.Messages) + digest.MoreMessages
+	subject := fmt.Sprintf("You have %d unread leads and messages - Business Exchange", total)
+
+	return es.deliver(user, EmailNotification, subject,
+		es.generateUnreadDigestText(user.FirstName, digest, es.unsubscribeURL(user, EmailNotification)))
+}
+
+// ListingConfirmationLink is one listing in a keep-alive email with the token
+// behind its confirm and mark-sold links
+type ListingConfirmationLink struct {
+	Title string
+	Token string
+}
+
+// GenerateListingConfirmationToken generates a random token for keep-alive links
+func (es *EmailService) GenerateListingConfirmationToken() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// SendListingConfirmationRequest asks a returning seller whether their active
+// listings are still available
+func (es *EmailService) SendListingConfirmationRequest(user *models.User, links []ListingConfirmationLink, deadline time.Time) error {
+	if user.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(user, EmailTransactional) {
+		return ErrEmailOptedOut
+	}
+	subject := "Are your listings still available? - Business Exchange"
+
+	return es.deliver(user, EmailTransactional, subject,
+		es.generateListingConfirmationText(user.FirstName, links, deadline))
+}
+
+// SendImageRejectedNotice tells a seller that a listing image was removed by moderation
+func (es *EmailService) SendImageRejectedNotice(owner *models.User, listing *models.Listing, reason string) error {
+	if owner.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(owner, EmailNotification) {
+		return ErrEmailOptedOut
 	}
+	subject := fmt.Sprintf("An image was removed from \"%s\"", listing.Title)
 
-	// TODO: Implement SendGrid integration
-	// For now, just log the email
-	es.logEmail(seller.Email, subject,
-		es.generateLeadNotificationText(seller.FirstName, lead))
-	return nil
+	return es.deliver(owner, EmailNotification, subject,
+		es.generateImageRejectedText(owner.FirstName, listing.Title, reason, es.unsubscribeURL(owner, EmailNotification)))
+}
+
+// SendFinancialsReviewed tells a seller whether an admin verified their
+// listing's revenue and margin against the statements they submitted
+func (es *EmailService) SendFinancialsReviewed(owner *models.User, listing *models.Listing, verified bool, reason string) error {
+	if owner.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(owner, EmailNotification) {
+		return ErrEmailOptedOut
+	}
+	subject := fmt.Sprintf("The financials of \"%s\" are verified", listing.Title)
+	if !verified {
+		subject = fmt.Sprintf("We couldn't verify the financials of \"%s\"", listing.Title)
+	}
+
+	return es.deliver(owner, EmailNotification, subject,
+		es.generateFinancialsReviewedText(owner.FirstName, listing.Title, verified, reason, es.unsubscribeURL(owner, EmailNotification)))
+}
+
+// SendFinancialsVerificationCleared tells a seller that editing a verified
+// figure removed their listing's verified financials badge
+func (es *EmailService) SendFinancialsVerificationCleared(owner *models.User, listing *models.Listing) error {
+	if owner.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(owner, EmailNotification) {
+		return ErrEmailOptedOut
+	}
+	subject := fmt.Sprintf("\"%s\" is no longer marked as verified", listing.Title)
+
+	return es.deliver(owner, EmailNotification, subject,
+		es.generateFinancialsClearedText(owner.FirstName, listing.Title, es.unsubscribeURL(owner, EmailNotification)))
+}
+
+// SendAuctionResult tells the seller or the winning bidder that an auction for
+// a listing ended in a sale
+func (es *EmailService) SendAuctionResult(user *models.User, listing *models.Listing, amount int64, won bool) error {
+	if user.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(user, EmailTransactional) {
+		return ErrEmailOptedOut
+	}
+	subject := fmt.Sprintf("Your auction for \"%s\" has sold", listing.Title)
+	if won {
+		subject = fmt.Sprintf("You won the auction for \"%s\"", listing.Title)
+	}
+
+	return es.deliver(user, EmailTransactional, subject,
+		es.generateAuctionResultText(user.FirstName, listing.Title, amount, won))
+}
+
+// SendSessionsRevokedNotice tells a user that signing in on a new device
+// signed them out of their oldest sessions
+func (es *EmailService) SendSessionsRevokedNotice(user *models.User, revoked []models.UserSession, newIP string) error {
+	if user.EmailUndeliverable {
+		return ErrEmailSuppressed
+	}
+	if !es.ShouldSend(user, EmailTransactional) {
+		return ErrEmailOptedOut
+	}
+	subject := "You were signed out of an older session - Business Exchange"
+
+	return es.deliver(user, EmailTransactional, subject,
+		es.generateSessionsRevokedText(user.FirstName, revoked, newIP))
 }
 
 // logEmail logs email content in development mode
@@ -130,21 +330,232 @@ Best regards,
 The Business Exchange Team`, firstName, resetURL)
 }
 
+// generateUnverifiedReminderText generates text content for the unverified account reminder
+func (es *EmailService) generateUnverifiedReminderText(firstName, verificationToken string, expiresAt time.Time) string {
+	verificationURL := fmt.Sprintf("%s/verify-email?token=%s", es.config.AppName, verificationToken)
+
+	return fmt.Sprintf(`Your account is about to expire
+
+Hi %s,
+
+You signed up for Business Exchange but haven't verified your email address yet.
+Unverified accounts are removed automatically, and yours will be removed on %s.
+
+Verify your email to keep your account:
+
+%s
+
+If you didn't sign up, you can ignore this email.
+
+Best regards,
+The Business Exchange Team`, firstName, format.DateTime(expiresAt), verificationURL)
+}
+
+// generateImageRejectedText generates text content for the image rejection notice
+func (es *EmailService) generateImageRejectedText(firstName, listingTitle, reason, unsubscribeURL string) string {
+	if reason == "" {
+		reason = "It does not meet our listing guidelines."
+	}
+
+	return fmt.Sprintf(`An image was removed from your listing
+
+Hi %s,
+
+Our moderators removed an image from your listing "%s".
+
+Reason: %s
+
+Your listing is still live. You can upload a replacement image from your dashboard.
+
+Best regards,
+The Business Exchange Team
+
+Stop notification emails: %s`, firstName, listingTitle, reason, unsubscribeURL)
+}
+
+// generateListingConfirmationText generates text content for the keep-alive email
+func (es *EmailService) generateListingConfirmationText(firstName string, links []ListingConfirmationLink, deadline time.Time) string {
+	var list strings.Builder
+	for _, l := range links {
+		keepURL := fmt.Sprintf("%s/keep-alive/%s?action=confirm", es.config.APIBaseURL, l.Token)
+		soldURL := fmt.Sprintf("%s/keep-alive/%s?action=sold", es.config.APIBaseURL, l.Token)
+		fmt.Fprintf(&list, "%s\n  Still available: %s\n  Sold or no longer available: %s\n\n", l.Title, keepURL, soldURL)
+	}
+
+	return fmt.Sprintf(`Are your listings still available?
+
+Hi %s,
+
+Welcome back! Buyers keep contacting sellers about businesses that are long gone,
+so please let us know whether these listings are still available:
+
+%sListings still unanswered on %s will be archived. You can reactivate
+them from your dashboard at any time.
+
+Best regards,
+The Business Exchange Team`, firstName, list.String(), format.DateTime(deadline))
+}
+
+// generateUnreadDigestText generates text content for the unread digest email
+func (es *EmailService) generateUnreadDigestText(firstName string, digest Digest, unsubscribeURL string) string {
+	var body strings.Builder
+	section := func(title string, items []DigestItem, more int) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&body, "%s:\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&body, "  - %s from %s", item.Subject, item.From)
+			if item.Listing != "" {
+				fmt.Fprintf(&body, " about \"%s\"", item.Listing)
+			}
+			fmt.Fprintf(&body, " (%s)\n", format.DateTime(item.ReceivedAt))
+		}
+		if more > 0 {
+			fmt.Fprintf(&body, "  ...and %d more\n", more)
+		}
+		body.WriteString("\n")
+	}
+	section("New leads", digest.Leads, digest.MoreLeads)
+	section("New messages", digest.Messages, digest.MoreMessages)
+
+	return fmt.Sprintf(`Your unread leads and messages
+
+Hi %s,
+
+Buyers have been in touch since your last visit:
+
+%sLog in to your dashboard to reply.
+
+Best regards,
+The Business Exchange Team
+
+Stop notification emails: %s`, firstName, body.String(), unsubscribeURL)
+}
+
+// generateFinancialsReviewedText generates text content for the financials review email
+func (es *EmailService) generateFinancialsReviewedText(firstName, listingTitle string, verified bool, reason, unsubscribeURL string) string {
+	outcome := "We checked the annual revenue and gross margin of your listing against the\nstatements you sent. They match, so buyers now see a verified financials badge\non it."
+	if !verified {
+		outcome = "We checked the annual revenue and gross margin of your listing against the\nstatements you sent, but couldn't verify them."
+		if reason != "" {
+			outcome += "\n\nReason: " + reason
+		}
+		outcome += "\n\nYou can upload updated statements from your dashboard."
+	}
+
+	return fmt.Sprintf(`Financials review for "%s"
+
+Hi %s,
+
+%s
+
+Best regards,
+The Business Exchange Team
+
+Stop notification emails: %s`, listingTitle, firstName, outcome, unsubscribeURL)
+}
+
+// generateFinancialsClearedText generates text content for the verification cleared email
+func (es *EmailService) generateFinancialsClearedText(firstName, listingTitle, unsubscribeURL string) string {
+	return fmt.Sprintf(`Verified financials removed
+
+Hi %s,
+
+You changed the annual revenue or gross margin of "%s", so it no longer
+matches the statements we verified and the verified financials badge has been
+removed. Upload statements for the new figures from your dashboard to have
+them verified again.
+
+Best regards,
+The Business Exchange Team
+
+Stop notification emails: %s`, firstName, listingTitle, unsubscribeURL)
+}
+
+// generateAuctionResultText generates text content for the auction result email
+func (es *EmailService) generateAuctionResultText(firstName, listingTitle string, amount int64, won bool) string {
+	outcome := fmt.Sprintf("Your auction for \"%s\" closed with a winning bid of %s.\nThe listing is now marked as sold.", listingTitle, format.Money(amount))
+	if won {
+		outcome = fmt.Sprintf("Congratulations! Your bid of %s won the auction for \"%s\".", format.Money(amount), listingTitle)
+	}
+
+	return fmt.Sprintf(`Auction result
+
+Hi %s,
+
+%s
+
+A pending transaction has been created. Log in to your dashboard to contact
+the other party and complete the sale.
+
+Best regards,
+The Business Exchange Team`, firstName, outcome)
+}
+
+// generateSessionsRevokedText generates text content for the session eviction notice
+func (es *EmailService) generateSessionsRevokedText(firstName string, revoked []models.UserSession, newIP string) string {
+	var list strings.Builder
+	for _, s := range revoked {
+		fmt.Fprintf(&list, "- %s from %s, signed in %s\n", s.UserAgent, s.IPAddress, format.DateTime(s.CreatedAt))
+	}
+
+	return fmt.Sprintf(`You were signed out of an older session
+
+Hi %s,
+
+Your account was just signed in from %s. You can be signed in on at most %d
+devices at once, so we signed you out of:
+
+%s
+If this wasn't you, change your password right away; that signs you out everywhere.
+
+Best regards,
+The Business Exchange Team`, firstName, newIP, es.config.SessionMaxPerUser, list.String())
+}
+
+// generateLeadCopyText generates text content for the lead copy sent to routing inboxes
+func (es *EmailService) generateLeadCopyText(seller *models.User, lead *models.Lead) string {
+	account := seller.CompanyName
+	if account == "" {
+		account = seller.Email
+	}
+	return fmt.Sprintf(`New Lead Received!
+
+A potential buyer has sent a new lead to %s:
+
+Inquiry: %s
+Subject: %s
+From: %s %s
+Message: %s
+Contact Phone: %s
+Received: %s
+
+You receive a copy of every lead because this address is in the account's lead routing settings.
+
+Best regards,
+The Business Exchange Team`, account, lead.InquiryType.Label(), lead.Subject, lead.Sender.FirstName, lead.Sender.LastName, lead.Message, lead.ContactPhone, format.DateTime(lead.CreatedAt))
+}
+
 // generateLeadNotificationText generates text content for lead notification
-func (es *EmailService) generateLeadNotificationText(firstName string, lead *models.Lead) string {
+func (es *EmailService) generateLeadNotificationText(firstName string, lead *models.Lead, unsubscribeURL string) string {
 	return fmt.Sprintf(`New Lead Received!
 
 Hi %s,
 
 You have received a new lead from a potential buyer:
 
+Inquiry: %s
 Subject: %s
 From: %s %s
 Message: %s
 Contact Phone: %s
+Received: %s
 
 Log in to your dashboard to respond to this lead.
 
 Best regards,
-The Business Exchange Team`, firstName, lead.Subject, lead.Sender.FirstName, lead.Sender.LastName, lead.Message, lead.ContactPhone)
+The Business Exchange Team
+
+Stop notification emails: %s`, firstName, lead.InquiryType.Label(), lead.Subject, lead.Sender.FirstName, lead.Sender.LastName, lead.Message, lead.ContactPhone, format.DateTime(lead.CreatedAt), unsubscribeURL)
 }
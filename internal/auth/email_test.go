@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+)
+
+func TestShouldSend(t *testing.T) {
+	tests := []struct {
+		category      EmailCategory
+		notifications bool
+		marketing     bool
+		want          bool
+	}{
+		{category: EmailTransactional, want: true},
+		{category: EmailTransactional, notifications: true, marketing: true, want: true},
+		{category: EmailNotification, notifications: true, want: true},
+		{category: EmailNotification, marketing: true, want: false},
+		{category: EmailMarketing, marketing: true, want: true},
+		{category: EmailMarketing, notifications: true, want: false},
+		{category: "digest", notifications: true, marketing: true, want: false},
+	}
+
+	es := NewEmailService(&config.Config{AppEnv: "development"})
+	for _, tt := range tests {
+		user := &models.User{EmailNotifications: tt.notifications, MarketingEmails: tt.marketing}
+		if got := es.ShouldSend(user, tt.category); got != tt.want {
+			t.Errorf("%s with notifications %v, marketing %v: got %v, want %v",
+				tt.category, tt.notifications, tt.marketing, got, tt.want)
+		}
+	}
+}
+
+func TestSendRespectsPreferences(t *testing.T) {
+	// Without an API key an email that gets past the checks fails to deliver
+	es := NewEmailService(&config.Config{AppEnv: "test"})
+	lead := &models.Lead{Subject: "Interested", Message: "Is it still for sale?"}
+
+	tests := []struct {
+		name string
+		user models.User
+		send func(user *models.User) error
+		want error
+	}{
+		{name: "notification opted in", user: models.User{EmailNotifications: true},
+			send: func(u *models.User) error { return es.SendLeadNotification(u, lead) }, want: errSendGridNotConfigured},
+		{name: "notification opted out", user: models.User{},
+			send: func(u *models.User) error { return es.SendLeadNotification(u, lead) }, want: ErrEmailOptedOut},
+		{name: "transactional ignores opt-outs", user: models.User{},
+			send: func(u *models.User) error { return es.SendPasswordResetEmail(u, "token") }, want: errSendGridNotConfigured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := tt.user
+			user.Email = "seller@example.com"
+			if err := tt.send(&user); !errors.Is(err, tt.want) {
+				t.Errorf("err %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "secret"}
+
+	for _, category := range []EmailCategory{EmailNotification, EmailMarketing} {
+		userID, got, err := ParseUnsubscribeToken(cfg, UnsubscribeToken(cfg, 42, category))
+		if err != nil || userID != 42 || got != category {
+			t.Errorf("%s round trip: user %d, category %s, err %v", category, userID, got, err)
+		}
+	}
+
+	valid := UnsubscribeToken(cfg, 42, EmailMarketing)
+	invalid := map[string]string{
+		"other secret":   UnsubscribeToken(&config.Config{JWTSecret: "other"}, 42, EmailMarketing),
+		"other user":     strings.Replace(valid, "42.", "43.", 1),
+		"other category": strings.Replace(valid, string(EmailMarketing), string(EmailNotification), 1),
+		"transactional":  UnsubscribeToken(cfg, 42, EmailTransactional),
+		"no signature":   "42.marketing",
+		"empty":          "",
+	}
+	for name, token := range invalid {
+		if _, _, err := ParseUnsubscribeToken(cfg, token); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+			t.Errorf("%s: err %v, want %v", name, err, ErrInvalidUnsubscribeToken)
+		}
+	}
+}
+
+// The emails show amounts and times the way the site does; these snapshots
+// lock the formatted lines
+func TestEmailFormatting(t *testing.T) {
+	es := NewEmailService(&config.Config{AppEnv: "development", AppName: "https://example.com"})
+	at := time.Date(2026, 3, 4, 17, 5, 0, 0, time.UTC)
+	lead := &models.Lead{
+		Subject: "Interested", Message: "Is it still for sale?", ContactPhone: "0912345678",
+		InquiryType: models.InquiryGeneral, CreatedAt: at,
+		Sender: models.User{FirstName: "Mei", LastName: "Lin"},
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "unverified reminder", text: es.generateUnverifiedReminderText("Mei", "tok", at),
+			want: []string{"yours will be removed on 2026年3月5日 01:05."}},
+		{name: "lead notification", text: es.generateLeadNotificationText("Wei", lead, "https://example.com/unsubscribe/x"),
+			want: []string{"Received: 2026年3月5日 01:05\n"}},
+		{name: "auction won", text: es.generateAuctionResultText("Mei", "Corner cafe", 3500000, true),
+			want: []string{`Congratulations! Your bid of NT$3,500,000 won the auction for "Corner cafe".`}},
+		{name: "auction sold", text: es.generateAuctionResultText("Wei", "Corner cafe", 1250000, false),
+			want: []string{`closed with a winning bid of NT$1,250,000.`}},
+		{name: "sessions revoked", text: es.generateSessionsRevokedText("Mei", []models.UserSession{{UserAgent: "Firefox", IPAddress: "192.0.2.1", CreatedAt: at}}, "192.0.2.9"),
+			want: []string{"- Firefox from 192.0.2.1, signed in 2026年3月5日 01:05\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, line := range tt.want {
+				if !strings.Contains(tt.text, line) {
+					t.Errorf("email lacks %q:\n%s", line, tt.text)
+				}
+			}
+			for _, raw := range []string{"UTC", "0001", "+0000"} {
+				if strings.Contains(tt.text, raw) {
+					t.Errorf("email has unformatted %q:\n%s", raw, tt.text)
+				}
+			}
+		})
+	}
+}
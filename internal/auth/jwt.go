@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"trade_company/internal/config"
+	"trade_company/internal/metrics"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -23,16 +24,26 @@ import (
 // Fields:
 //   - UserID: Unique identifier for the authenticated user
 //   - Email: User's email address for identification
+//   - SessionID: The login's refresh token family, for tokens from a login
 //   - RegisteredClaims: Standard JWT claims (issuer, expiration, etc.)
 //
 // The "uid" JSON tag ensures compatibility with the auction service
 // which expects the user ID field to be named "uid".
 type Claims struct {
-	UserID uint   `json:"uid"`   // User identifier (compatible with auction service)
-	Email  string `json:"email"` // User email address
-	jwt.RegisteredClaims          // Standard JWT claims (iss, exp, iat, etc.)
+	UserID               uint   `json:"uid"`           // User identifier (compatible with auction service)
+	Email                string `json:"email"`         // User email address
+	SessionID            string `json:"sid,omitempty"` // Refresh token family of the login, marking the current session
+	jwt.RegisteredClaims        // Standard JWT claims (iss, exp, iat, etc.)
 }
 
+// now is the clock for issuing and checking tokens, a variable so boundary
+// times can be checked against a fake clock
+var now = time.Now
+
+// ErrTokenNotExpiring is returned by ExtendToken for a token that isn't yet in
+// its extension window
+var ErrTokenNotExpiring = errors.New("token is not close to expiry")
+
 // GenerateToken creates a new JWT token for an authenticated user.
 //
 // This function generates a signed JWT token containing the user's ID and email,
@@ -50,20 +61,63 @@ type Claims struct {
 // The token is signed using HMAC-SHA256 algorithm and expires after
 // the configured number of minutes (default: 60 minutes).
 func GenerateToken(cfg *config.Config, userID uint, email string) (string, error) {
+	token, _, err := IssueToken(cfg, userID, email)
+	return token, err
+}
+
+// IssueToken is GenerateToken that also returns when the token expires, for
+// responses that tell the client when to extend it.
+func IssueToken(cfg *config.Config, userID uint, email string) (string, time.Time, error) {
+	return IssueSessionToken(cfg, userID, email, "")
+}
+
+// IssueSessionToken is IssueToken for a login tracked as a session; the token
+// carries the login's refresh token family as its sid claim.
+func IssueSessionToken(cfg *config.Config, userID uint, email, sessionID string) (string, time.Time, error) {
+	defer metrics.JWTGenerateSeconds.Since(time.Now())
+
+	// exp has second precision, so truncate to report the time the token carries
+	issuedAt := now().Truncate(time.Second)
+	expiresAt := issuedAt.Add(time.Duration(cfg.JWTExpireMinutes) * time.Minute)
+
 	// Create JWT claims with user information and metadata
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    cfg.JWTIssuer,                                                                           // Token issuer (typically service name)
-			IssuedAt:  jwt.NewNumericDate(time.Now()),                                                          // Token creation time
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWTExpireMinutes) * time.Minute)), // Token expiration time
+			Issuer:    cfg.JWTIssuer,                 // Token issuer (typically service name)
+			IssuedAt:  jwt.NewNumericDate(issuedAt),  // Token creation time
+			ExpiresAt: jwt.NewNumericDate(expiresAt), // Token expiration time
 		},
 	}
-	
+
 	// Create and sign the token using HMAC-SHA256
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWTSecret))
+	signed, err := token.SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// ExtendToken issues a fresh token for the same user when tokenString is still
+// valid and expires within JWT_EXTEND_WINDOW_MINUTES. Earlier calls get
+// ErrTokenNotExpiring, so clients extend once near the end of a token's life
+// rather than minting a new token on every page load.
+func ExtendToken(cfg *config.Config, tokenString string) (string, time.Time, error) {
+	claims, err := ParseToken(cfg, tokenString)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if claims.ExpiresAt == nil {
+		return "", time.Time{}, errors.New("token has no expiry")
+	}
+	window := time.Duration(cfg.JWTExtendWindowMinutes) * time.Minute
+	if claims.ExpiresAt.Time.Sub(now()) > window {
+		return "", time.Time{}, ErrTokenNotExpiring
+	}
+	return IssueSessionToken(cfg, claims.UserID, claims.Email, claims.SessionID)
 }
 
 // ParseToken validates and parses a JWT token string, returning the contained claims.
@@ -79,12 +133,16 @@ func GenerateToken(cfg *config.Config, userID uint, email string) (string, error
 //   - *Claims: Parsed user claims if token is valid
 //   - error: Authentication error if token is invalid, expired, or malformed
 //
+// Expiry is checked with JWT_LEEWAY_SECONDS of leeway (see ParserOptions).
+//
 // Common errors:
 //   - Token signature verification failure
 //   - Token expiration
 //   - Malformed token structure
 //   - Invalid claims format
 func ParseToken(cfg *config.Config, tokenString string) (*Claims, error) {
+	defer metrics.JWTParseSeconds.Since(time.Now())
+
 	// Parse and validate the token with our claims structure
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
 		// Verify the signing method and return the secret key
@@ -92,7 +150,7 @@ func ParseToken(cfg *config.Config, tokenString string) (*Claims, error) {
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(cfg.JWTSecret), nil
-	})
+	}, ParserOptions(cfg)...)
 	
 	if err != nil {
 		return nil, err
@@ -111,3 +169,13 @@ func ParseToken(cfg *config.Config, tokenString string) (*Claims, error) {
 	
 	return claims, nil
 }
+
+// ParserOptions are the options every token parse uses: the leeway for clock
+// skew between instances, so a token isn't rejected by one instance a few
+// seconds before another would, and the package clock.
+func ParserOptions(cfg *config.Config) []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithLeeway(time.Duration(cfg.JWTLeewaySeconds) * time.Second),
+		jwt.WithTimeFunc(now),
+	}
+}
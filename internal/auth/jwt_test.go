@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"trade_company/internal/config"
+)
+
+func jwtTestConfig() *config.Config {
+	return &config.Config{JWTSecret: "secret", JWTIssuer: "test", JWTExpireMinutes: 60, JWTLeewaySeconds: 30, JWTExtendWindowMinutes: 10}
+}
+
+func TestIssueTokenExpiry(t *testing.T) {
+	cfg := jwtTestConfig()
+	issued := time.Date(2026, 3, 1, 9, 0, 0, 500_000_000, time.UTC)
+	withClock(t, issued)
+
+	token, expiresAt, err := IssueToken(cfg, 7, "seller@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The reported expiry is the second-precision exp the token carries
+	want := issued.Truncate(time.Second).Add(time.Hour)
+	if !expiresAt.Equal(want) {
+		t.Errorf("expires at %v, want %v", expiresAt, want)
+	}
+	claims, err := ParseToken(cfg, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claims.ExpiresAt.Time.Equal(expiresAt) || claims.UserID != 7 {
+		t.Errorf("claims %+v", claims)
+	}
+}
+
+func TestParseTokenLeeway(t *testing.T) {
+	issued := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	expiry := issued.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		leeway int
+		at     time.Time
+		valid  bool
+	}{
+		{name: "before expiry", leeway: 30, at: expiry.Add(-time.Second), valid: true},
+		{name: "just after expiry, within leeway", leeway: 30, at: expiry.Add(29 * time.Second), valid: true},
+		{name: "past the leeway", leeway: 30, at: expiry.Add(31 * time.Second)},
+		{name: "no leeway", leeway: 0, at: expiry.Add(time.Second)},
+		// Another instance whose clock runs behind sees iat in the future
+		{name: "issued slightly in the future", leeway: 30, at: issued.Add(-20 * time.Second), valid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := jwtTestConfig()
+			cfg.JWTLeewaySeconds = tt.leeway
+			withClock(t, issued)
+			token, _, err := IssueToken(cfg, 1, "a@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			withClock(t, tt.at)
+			_, err = ParseToken(cfg, token)
+			if (err == nil) != tt.valid {
+				t.Errorf("parse at %v: error %v, want valid %v", tt.at.Sub(expiry), err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestExtendToken(t *testing.T) {
+	issued := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	expiry := issued.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		wantErr error // nil when extended; errAny for any other failure
+	}{
+		{name: "fresh token", at: issued, wantErr: ErrTokenNotExpiring},
+		{name: "a second before the window", at: expiry.Add(-10*time.Minute - time.Second), wantErr: ErrTokenNotExpiring},
+		{name: "window opens", at: expiry.Add(-10 * time.Minute)},
+		{name: "last second", at: expiry.Add(-time.Second)},
+		{name: "expired, within leeway", at: expiry.Add(20 * time.Second)},
+		{name: "expired", at: expiry.Add(time.Minute), wantErr: errAny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := jwtTestConfig()
+			withClock(t, issued)
+			token, _, err := IssueToken(cfg, 3, "buyer@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			withClock(t, tt.at)
+			extended, expiresAt, err := ExtendToken(cfg, token)
+			switch {
+			case tt.wantErr == errAny:
+				if err == nil || errors.Is(err, ErrTokenNotExpiring) {
+					t.Fatalf("error %v, want the token rejected", err)
+				}
+				return
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error %v, want %v", err, tt.wantErr)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			// A full new lifetime from now, for the same user
+			if want := tt.at.Add(time.Hour); !expiresAt.Equal(want) {
+				t.Errorf("extended until %v, want %v", expiresAt, want)
+			}
+			claims, err := ParseToken(cfg, extended)
+			if err != nil || claims.UserID != 3 || claims.Email != "buyer@example.com" {
+				t.Errorf("extended token claims %+v, %v", claims, err)
+			}
+		})
+	}
+}
+
+// errAny stands for any error other than ErrTokenNotExpiring
+var errAny = errors.New("any error")
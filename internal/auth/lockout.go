@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"trade_company/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Lockout modes
+const (
+	LockoutModeFlat        = "flat"
+	LockoutModeExponential = "exponential"
+)
+
+// LockoutTracker locks accounts after too many failed logins, tracked per email in Redis.
+//
+// In flat mode every lockout lasts LockoutDurationMinutes. In exponential mode each
+// further lockout within the escalation window doubles the duration, up to the cap,
+// so persistent brute force gets slower while a one-off mistake stays cheap.
+//
+// A nil *LockoutTracker is valid and never locks an account.
+type LockoutTracker struct {
+	redisClient *redis.Client
+	config      *config.Config
+}
+
+// NewLockoutTracker creates a lockout tracker, or nil without Redis.
+func NewLockoutTracker(redisClient *redis.Client, config *config.Config) *LockoutTracker {
+	if redisClient == nil {
+		return nil
+	}
+	return &LockoutTracker{
+		redisClient: redisClient,
+		config:      config,
+	}
+}
+
+// LockoutDuration returns how long the given lockout (1 for the first within the
+// escalation window) lasts in the given mode.
+func LockoutDuration(mode string, base, max time.Duration, offense int) time.Duration {
+	if mode != LockoutModeExponential || offense <= 1 {
+		return base
+	}
+
+	d := base
+	for i := 1; i < offense; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return d
+}
+
+// RecordFailure counts a failed login and locks the account once the attempt
+// limit is reached. It returns the lockout duration, or 0 if the account is not locked.
+func (t *LockoutTracker) RecordFailure(ctx context.Context, email string) (time.Duration, error) {
+	if t == nil {
+		return 0, nil
+	}
+	base := time.Duration(t.config.LockoutDurationMinutes) * time.Minute
+
+	failuresKey := fmt.Sprintf("failed_login:%s", email)
+	pipe := t.redisClient.TxPipeline()
+	failures := pipe.Incr(ctx, failuresKey)
+	pipe.Expire(ctx, failuresKey, base)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	if failures.Val() < int64(t.config.MaxLoginAttempts) {
+		return 0, nil
+	}
+
+	// The offense counter outlives individual lockouts so repeat offenders escalate
+	offensesKey := fmt.Sprintf("lockout_offenses:%s", email)
+	window := time.Duration(t.config.LockoutEscalationWindowHours) * time.Hour
+	pipe = t.redisClient.TxPipeline()
+	offenses := pipe.Incr(ctx, offensesKey)
+	pipe.Expire(ctx, offensesKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record lockout: %w", err)
+	}
+
+	max := time.Duration(t.config.LockoutMaxDurationMinutes) * time.Minute
+	duration := LockoutDuration(t.config.LockoutMode, base, max, int(offenses.Val()))
+
+	pipe = t.redisClient.TxPipeline()
+	pipe.Set(ctx, fmt.Sprintf("lockout:%s", email), offenses.Val(), duration)
+	pipe.Del(ctx, failuresKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	return duration, nil
+}
+
+// LockedFor returns how much longer the account stays locked, or 0 if it is not locked.
+func (t *LockoutTracker) LockedFor(ctx context.Context, email string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	ttl, err := t.redisClient.TTL(ctx, fmt.Sprintf("lockout:%s", email)).Result()
+	if err != nil || ttl <= 0 {
+		return 0 // Allow if Redis error
+	}
+	return ttl
+}
+
+// Reset clears failed attempts after a successful login. The offense history is
+// kept so an attacker who occasionally guesses right still escalates.
+func (t *LockoutTracker) Reset(ctx context.Context, email string) {
+	if t == nil {
+		return
+	}
+	t.redisClient.Del(ctx, fmt.Sprintf("failed_login:%s", email))
+}
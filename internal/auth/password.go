@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"time"
+
+	"trade_company/internal/metrics"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashPassword hashes a password at the given bcrypt cost and records the
+// time taken
+func HashPassword(password string, cost int) (string, error) {
+	defer metrics.BcryptHashSeconds.Since(time.Now())
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword compares a password with its bcrypt hash and records the time
+// taken. The hash's own cost applies, so old hashes keep verifying after the
+// configured cost changes.
+func CheckPassword(hash, password string) error {
+	defer metrics.BcryptVerifySeconds.Since(time.Now())
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+// BenchmarkBcrypt times one hash at cost, for the startup log. It isn't
+// recorded in the histograms.
+func BenchmarkBcrypt(cost int) (time.Duration, error) {
+	start := time.Now()
+	_, err := bcrypt.GenerateFromPassword([]byte("benchmark-password"), cost)
+	return time.Since(start), err
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"trade_company/internal/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// observations reads how many durations a registered histogram has recorded
+func observations(t *testing.T, name string) uint64 {
+	t.Helper()
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatalf("histogram %s is not registered", name)
+	}
+	var h struct{ Count uint64 }
+	if err := json.Unmarshal([]byte(v.String()), &h); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return h.Count
+}
+
+func TestHashPasswordCost(t *testing.T) {
+	hashes, verifies := observations(t, "auth_bcrypt_hash_seconds"), observations(t, "auth_bcrypt_verify_seconds")
+
+	for _, cost := range []int{10, 11} {
+		hash, err := HashPassword("correct horse", cost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := bcrypt.Cost([]byte(hash)); got != cost {
+			t.Errorf("hash cost %d, want %d", got, cost)
+		}
+		if err := CheckPassword(hash, "correct horse"); err != nil {
+			t.Errorf("cost %d: right password rejected: %v", cost, err)
+		}
+		if err := CheckPassword(hash, "wrong horse"); err == nil {
+			t.Errorf("cost %d: wrong password accepted", cost)
+		}
+	}
+
+	if got := observations(t, "auth_bcrypt_hash_seconds") - hashes; got != 2 {
+		t.Errorf("%d hashes observed, want 2", got)
+	}
+	if got := observations(t, "auth_bcrypt_verify_seconds") - verifies; got != 4 {
+		t.Errorf("%d verifications observed, want 4", got)
+	}
+
+	// The startup benchmark isn't a real hash and stays out of the histogram
+	before := observations(t, "auth_bcrypt_hash_seconds")
+	if took, err := BenchmarkBcrypt(10); err != nil || took <= 0 {
+		t.Errorf("benchmark took %v: %v", took, err)
+	}
+	if observations(t, "auth_bcrypt_hash_seconds") != before {
+		t.Error("benchmark was recorded")
+	}
+}
+
+func TestJWTMetrics(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "secret", JWTIssuer: "test", JWTExpireMinutes: 5}
+	generated, parsed := observations(t, "auth_jwt_generate_seconds"), observations(t, "auth_jwt_parse_seconds")
+
+	token, err := GenerateToken(cfg, 7, "seller@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(cfg, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(cfg, token+"x"); err == nil {
+		t.Error("tampered token parsed")
+	}
+
+	if got := observations(t, "auth_jwt_generate_seconds") - generated; got != 1 {
+		t.Errorf("%d generations observed, want 1", got)
+	}
+	// Failed parses are timed too
+	if got := observations(t, "auth_jwt_parse_seconds") - parsed; got != 2 {
+		t.Errorf("%d parses observed, want 2", got)
+	}
+}
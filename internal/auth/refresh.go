@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRefreshTokenInvalid is returned for a refresh token that is unknown,
+	// expired or belongs to an inactive user
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated or revoked
+	// refresh token is presented again. Its whole family has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshToken is a refresh token as handed to the client
+type RefreshToken struct {
+	Token     string
+	ExpiresAt time.Time
+	Family    string // The login the token belongs to
+}
+
+// HashRefreshToken returns the stored form of a refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRefreshFamily returns the ID of a new token family. It is generated ahead
+// of IssueRefreshToken so the login's session can be recorded first.
+func NewRefreshFamily() (string, error) {
+	return randomHex(16)
+}
+
+// IssueRefreshToken issues the first token of family for userID, at login or
+// registration
+func IssueRefreshToken(db *gorm.DB, cfg *config.Config, userID uint, family string) (RefreshToken, error) {
+	return issueRefreshToken(db, cfg, userID, family)
+}
+
+// RotateRefreshToken exchanges a refresh token for its successor and returns
+// the user it belongs to. The presented token is revoked; presenting it again
+// revokes every token of its family and returns ErrRefreshTokenReused, since
+// either the client or someone who stole the token is replaying it.
+func RotateRefreshToken(db *gorm.DB, cfg *config.Config, token string) (*models.User, RefreshToken, error) {
+	var user models.User
+	var next RefreshToken
+	reused := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var stored models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", HashRefreshToken(token)).
+			First(&stored).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if stored.Revoked {
+			reused = true
+			return revokeFamily(tx, stored.FamilyID)
+		}
+		if !now().Before(stored.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+		if err := tx.First(&user, stored.UserID).Error; err != nil || !user.IsActive {
+			return ErrRefreshTokenInvalid
+		}
+
+		if err := tx.Model(&stored).Update("revoked", true).Error; err != nil {
+			return err
+		}
+		next, err = issueRefreshToken(tx, cfg, stored.UserID, stored.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, RefreshToken{}, err
+	}
+	// The family is revoked in the committed transaction, then reuse reported
+	if reused {
+		return nil, RefreshToken{}, ErrRefreshTokenReused
+	}
+	return &user, next, nil
+}
+
+// RevokeRefreshToken revokes the family of a refresh token, ending the login it
+// came from. Unknown tokens are ignored.
+func RevokeRefreshToken(db *gorm.DB, token string) error {
+	var stored models.RefreshToken
+	err := db.Where("token_hash = ?", HashRefreshToken(token)).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return revokeFamily(db, stored.FamilyID)
+}
+
+func issueRefreshToken(db *gorm.DB, cfg *config.Config, userID uint, family string) (RefreshToken, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	expiresAt := now().Truncate(time.Second).Add(time.Duration(cfg.JWTRefreshExpireDays) * 24 * time.Hour)
+	row := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  family,
+		TokenHash: HashRefreshToken(token),
+		ExpiresAt: expiresAt,
+	}
+	if err := db.Create(&row).Error; err != nil {
+		return RefreshToken{}, err
+	}
+	return RefreshToken{Token: token, ExpiresAt: expiresAt, Family: family}, nil
+}
+
+func revokeFamily(db *gorm.DB, family string) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked = ?", family, false).
+		Update("revoked", true).Error
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newRefreshTestDB opens an in-memory database with an active user to issue
+// refresh tokens for
+func newRefreshTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	user := &models.User{Email: "seller@example.com", Username: "seller", PasswordHash: "x", IsActive: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, user
+}
+
+// withClock fixes the package clock for the rest of the test
+func withClock(t *testing.T, at time.Time) {
+	t.Helper()
+	previous := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = previous })
+}
+
+func issueFirst(t *testing.T, db *gorm.DB, cfg *config.Config, userID uint) RefreshToken {
+	t.Helper()
+	family, err := NewRefreshFamily()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := IssueRefreshToken(db, cfg, userID, family)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return first
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	cfg := &config.Config{JWTRefreshExpireDays: 7}
+	db, user := newRefreshTestDB(t)
+	first := issueFirst(t, db, cfg, user.ID)
+
+	got, second, err := RotateRefreshToken(db, cfg, first.Token)
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("rotated for user %d, want %d", got.ID, user.ID)
+	}
+	if second.Token == first.Token || second.Family != first.Family {
+		t.Errorf("successor %+v of %+v, want a new token in the same family", second, first)
+	}
+
+	var stored models.RefreshToken
+	db.Where("token_hash = ?", HashRefreshToken(first.Token)).First(&stored)
+	if !stored.Revoked {
+		t.Error("rotated token not revoked")
+	}
+	if stored.TokenHash == first.Token {
+		t.Error("token stored in plain text")
+	}
+
+	if _, _, err := RotateRefreshToken(db, cfg, second.Token); err != nil {
+		t.Errorf("rotate successor: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenReuse(t *testing.T) {
+	cfg := &config.Config{JWTRefreshExpireDays: 7}
+	db, user := newRefreshTestDB(t)
+	first := issueFirst(t, db, cfg, user.ID)
+	other := issueFirst(t, db, cfg, user.ID)
+
+	_, second, err := RotateRefreshToken(db, cfg, first.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying the rotated token ends the whole login, successor included
+	if _, _, err := RotateRefreshToken(db, cfg, first.Token); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replayed token: err %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, _, err := RotateRefreshToken(db, cfg, second.Token); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("successor after reuse: err %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	var live int64
+	db.Model(&models.RefreshToken{}).Where("family_id = ? AND revoked = ?", first.Family, false).Count(&live)
+	if live != 0 {
+		t.Errorf("%d tokens of the reused family still live", live)
+	}
+	// Other logins of the same user are untouched
+	if _, _, err := RotateRefreshToken(db, cfg, other.Token); err != nil {
+		t.Errorf("other login: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenInvalid(t *testing.T) {
+	cfg := &config.Config{JWTRefreshExpireDays: 7}
+	issued := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		present func(t *testing.T, db *gorm.DB, user *models.User) string
+	}{
+		{name: "unknown token", present: func(t *testing.T, db *gorm.DB, user *models.User) string {
+			return "not-a-token"
+		}},
+		{name: "expired token", present: func(t *testing.T, db *gorm.DB, user *models.User) string {
+			token := issueFirst(t, db, cfg, user.ID).Token
+			withClock(t, issued.Add(7*24*time.Hour))
+			return token
+		}},
+		{name: "inactive user", present: func(t *testing.T, db *gorm.DB, user *models.User) string {
+			token := issueFirst(t, db, cfg, user.ID).Token
+			db.Model(user).Update("is_active", false)
+			return token
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withClock(t, issued)
+			db, user := newRefreshTestDB(t)
+			token := tt.present(t, db, user)
+			if _, _, err := RotateRefreshToken(db, cfg, token); !errors.Is(err, ErrRefreshTokenInvalid) {
+				t.Errorf("err %v, want %v", err, ErrRefreshTokenInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"trade_company/internal/models"
+)
+
+// sendGridSendURL is the SendGrid v3 Mail Send endpoint
+const sendGridSendURL = "https://api.sendgrid.com/v3/mail/send"
+
+// sendGridTimeout bounds each attempt to hand an email to SendGrid
+const sendGridTimeout = 10 * time.Second
+
+// errSendGridNotConfigured is returned outside development when there is no API key
+var errSendGridNotConfigured = errors.New("SENDGRID_API_KEY is not set")
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+// sendGridMessage is the Mail Send request body
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"` // text/plain must come before text/html
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+// deliver sends an email to user. In development it is only logged; elsewhere
+// it goes out through SendGrid as plain text with an HTML alternative.
+// Notification and marketing emails carry List-Unsubscribe headers, so mail
+// clients can offer their own one-click unsubscribe button.
+func (es *EmailService) deliver(user *models.User, category EmailCategory, subject, text string) error {
+	if es.config.AppEnv == "development" {
+		es.logEmail(user.Email, subject, text)
+		return nil
+	}
+	if es.config.SendGridAPIKey == "" {
+		return errSendGridNotConfigured
+	}
+
+	msg := sendGridMessage{
+		Personalizations: []sendGridPersonalization{{
+			To: []sendGridAddress{{Email: user.Email, Name: strings.TrimSpace(user.FirstName + " " + user.LastName)}},
+		}},
+		From:    sendGridAddress{Email: es.config.SendGridFromEmail, Name: es.config.SendGridFromName},
+		Subject: subject,
+		Content: []sendGridContent{
+			{Type: "text/plain", Value: text},
+			{Type: "text/html", Value: textToHTML(text)},
+		},
+	}
+	if category != EmailTransactional {
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + es.unsubscribeURL(user, category) + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	// A 5xx is often a passing SendGrid problem, so it gets one more try;
+	// anything else won't go differently the second time
+	status, err := es.postSendGrid(body)
+	if status >= 500 {
+		_, err = es.postSendGrid(body)
+	}
+	return err
+}
+
+// postSendGrid makes one Mail Send request, returning the response status
+// (0 if none came back) and an error unless it was a 2xx
+func (es *EmailService) postSendGrid(body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendGridTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridSendURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+es.config.SendGridAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach SendGrid: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// SendGrid explains rejections in the body; keep the start of it
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("sendgrid returned status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return resp.StatusCode, nil
+}
+
+// emailURLPattern finds the links in an email's text
+var emailURLPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// textToHTML is the HTML alternative of a text email: paragraphs and line
+// breaks are kept and links made clickable
+func textToHTML(text string) string {
+	var b strings.Builder
+	b.WriteString(`<div style="font-family:sans-serif;font-size:14px;line-height:1.5">`)
+	for _, para := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		escaped := html.EscapeString(para)
+		linked := emailURLPattern.ReplaceAllStringFunc(escaped, func(u string) string {
+			return `<a href="` + u + `">` + u + `</a>`
+		})
+		b.WriteString("<p>" + strings.ReplaceAll(linked, "\n", "<br>\n") + "</p>\n")
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
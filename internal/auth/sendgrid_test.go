@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+)
+
+// fakeSendGrid answers Mail Send requests with the queued statuses, the last
+// one repeating, and records each request
+type fakeSendGrid struct {
+	statuses []int
+	err      error // Returned instead of a response when set
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (f *fakeSendGrid) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	f.requests = append(f.requests, req)
+	f.bodies = append(f.bodies, body)
+	if f.err != nil {
+		return nil, f.err
+	}
+	status := f.statuses[0]
+	if len(f.statuses) > 1 {
+		f.statuses = f.statuses[1:]
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(`{"errors":[{"message":"bad request"}]}`)),
+		Request:    req,
+	}, nil
+}
+
+func newSendGridTest(env string, statuses ...int) (*EmailService, *fakeSendGrid) {
+	fake := &fakeSendGrid{statuses: statuses}
+	es := NewEmailService(&config.Config{
+		AppEnv: env, AppName: "https://example.com", APIBaseURL: "https://api.example.com", JWTSecret: "secret",
+		SendGridAPIKey: "SG.test", SendGridFromEmail: "noreply@example.com", SendGridFromName: "Business Exchange",
+	})
+	es.client = &http.Client{Transport: fake}
+	return es, fake
+}
+
+func TestSendGridPayload(t *testing.T) {
+	user := &models.User{ID: 42, Email: "mei@example.com", FirstName: "Mei", LastName: "Lin", EmailNotifications: true}
+	lead := &models.Lead{Subject: "Interested", Message: "Is it still for sale?", Sender: models.User{FirstName: "Wei"}}
+
+	tests := []struct {
+		name        string
+		send        func(es *EmailService) error
+		subject     string
+		unsubscribe bool
+	}{
+		{name: "transactional", send: func(es *EmailService) error { return es.SendVerificationEmail(user, "tok") },
+			subject: "Verify Your Email - Business Exchange"},
+		{name: "notification", send: func(es *EmailService) error { return es.SendLeadNotification(user, lead) },
+			subject: "New Lead: Interested", unsubscribe: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, fake := newSendGridTest("production", http.StatusAccepted)
+			if err := tt.send(es); err != nil {
+				t.Fatal(err)
+			}
+			if len(fake.requests) != 1 {
+				t.Fatalf("%d requests, want 1", len(fake.requests))
+			}
+			req := fake.requests[0]
+			if req.Method != http.MethodPost || req.URL.String() != sendGridSendURL {
+				t.Errorf("request %s %s", req.Method, req.URL)
+			}
+			if req.Header.Get("Authorization") != "Bearer SG.test" || req.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("headers %v", req.Header)
+			}
+			if deadline, ok := req.Context().Deadline(); !ok || time.Until(deadline) > sendGridTimeout {
+				t.Errorf("request deadline %v (%v), want within %v", deadline, ok, sendGridTimeout)
+			}
+
+			// The body has exactly the fields the v3 API takes
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal(fake.bodies[0], &raw); err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for k := range raw {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			want := []string{"content", "from", "personalizations", "subject"}
+			if tt.unsubscribe {
+				want = []string{"content", "from", "headers", "personalizations", "subject"}
+			}
+			if !reflect.DeepEqual(keys, want) {
+				t.Errorf("payload fields %v, want %v", keys, want)
+			}
+
+			var msg sendGridMessage
+			json.Unmarshal(fake.bodies[0], &msg)
+			to := []sendGridPersonalization{{To: []sendGridAddress{{Email: "mei@example.com", Name: "Mei Lin"}}}}
+			if !reflect.DeepEqual(msg.Personalizations, to) {
+				t.Errorf("personalizations %+v", msg.Personalizations)
+			}
+			if msg.From != (sendGridAddress{Email: "noreply@example.com", Name: "Business Exchange"}) || msg.Subject != tt.subject {
+				t.Errorf("from %+v, subject %q", msg.From, msg.Subject)
+			}
+			if len(msg.Content) != 2 || msg.Content[0].Type != "text/plain" || msg.Content[1].Type != "text/html" ||
+				msg.Content[1].Value != textToHTML(msg.Content[0].Value) {
+				t.Errorf("content %+v, want plain text then its HTML", msg.Content)
+			}
+			unsubscribe := msg.Headers["List-Unsubscribe"]
+			if tt.unsubscribe != strings.HasPrefix(unsubscribe, "<https://api.example.com/unsubscribe/") {
+				t.Errorf("List-Unsubscribe %q", unsubscribe)
+			}
+			if tt.unsubscribe && msg.Headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+				t.Errorf("headers %v", msg.Headers)
+			}
+		})
+	}
+}
+
+func TestSendGridRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		err      error
+		requests int
+		wantErr  string
+	}{
+		{name: "accepted", statuses: []int{http.StatusAccepted}, requests: 1},
+		{name: "5xx then accepted", statuses: []int{http.StatusServiceUnavailable, http.StatusAccepted}, requests: 2},
+		{name: "5xx twice", statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}, requests: 2, wantErr: "status 502"},
+		{name: "4xx is not retried", statuses: []int{http.StatusBadRequest}, requests: 1, wantErr: `status 400: {"errors":[{"message":"bad request"}]}`},
+		{name: "unreachable is not retried", err: errors.New("connection refused"), requests: 1, wantErr: "failed to reach SendGrid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, fake := newSendGridTest("production", tt.statuses...)
+			fake.err = tt.err
+			err := es.SendPasswordResetEmail(&models.User{Email: "mei@example.com"}, "tok")
+			if len(fake.requests) != tt.requests {
+				t.Errorf("%d requests, want %d", len(fake.requests), tt.requests)
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendGridSkippedInDevelopment(t *testing.T) {
+	es, fake := newSendGridTest("development", http.StatusAccepted)
+	if err := es.SendPasswordResetEmail(&models.User{Email: "mei@example.com"}, "tok"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.requests) != 0 {
+		t.Errorf("%d requests in development, want only a log", len(fake.requests))
+	}
+}
+
+func TestTextToHTML(t *testing.T) {
+	got := textToHTML("Hi <Mei> & co,\n\nVerify at https://example.com/verify?token=a&b=1\nThanks")
+	want := `<div style="font-family:sans-serif;font-size:14px;line-height:1.5">` +
+		"<p>Hi &lt;Mei&gt; &amp; co,</p>\n" +
+		`<p>Verify at <a href="https://example.com/verify?token=a&amp;b=1">https://example.com/verify?token=a&amp;b=1</a><br>` + "\nThanks</p>\n" +
+		"</div>"
+	if got != want {
+		t.Errorf("textToHTML =\n%s\nwant\n%s", got, want)
+	}
+}
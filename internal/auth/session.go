@@ -11,6 +11,8 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -21,6 +23,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrTooManySessions is returned by CreateSession in strict mode when the user
+// already has the maximum number of active sessions
+var ErrTooManySessions = errors.New("too many active sessions")
+
 // SessionManager handles user session lifecycle management with dual storage.
 //
 // Architecture:
@@ -76,7 +82,12 @@ func NewSessionManager(redisClient *redis.Client, db *gorm.DB, config *config.Co
 //   - Uses crypto/rand for secure session ID generation
 //   - Tracks IP and User-Agent for session hijacking detection
 //   - Automatic expiration based on configured TTL
+//   - At most SessionMaxPerUser active sessions; see enforceSessionLimit
 func (sm *SessionManager) CreateSession(userID uint, ipAddress, userAgent string) (*models.UserSession, error) {
+	if err := sm.enforceSessionLimit(userID, ipAddress); err != nil {
+		return nil, err
+	}
+
 	// Generate cryptographically secure session ID (64 character hex string)
 	sessionID, err := sm.generateSessionID()
 	if err != nil {
@@ -123,6 +134,107 @@ func (sm *SessionManager) CreateSession(userID uint, ipAddress, userAgent string
 	return session, nil
 }
 
+// CreateLoginSession records a login through /auth/login, which authenticates
+// with JWTs rather than a session cookie, under the same per-user limit as
+// CreateSession. The session lives as long as the login's refresh token family:
+// revoking it revokes the family, and it drops out of GetUserSessions once the
+// family is revoked by logout or reuse. Nothing looks these sessions up by ID,
+// so they are only stored in the database.
+func (sm *SessionManager) CreateLoginSession(userID uint, family, ipAddress, userAgent string, expiresAt time.Time) (*models.UserSession, error) {
+	if err := sm.enforceSessionLimit(userID, ipAddress); err != nil {
+		return nil, err
+	}
+
+	sessionID, err := sm.generateSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	session := &models.UserSession{
+		UserID:          userID,
+		SessionID:       sessionID,
+		RefreshFamilyID: family,
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		ExpiresAt:       expiresAt,
+	}
+	if err := sm.db.Create(session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create session in database: %w", err)
+	}
+	return session, nil
+}
+
+// ExtendLoginSession moves the expiry of a login session along with its
+// refresh token family, which each rotation extends
+func (sm *SessionManager) ExtendLoginSession(family string, expiresAt time.Time) error {
+	if err := sm.db.Model(&models.UserSession{}).Where("refresh_family_id = ?", family).Update("expires_at", expiresAt).Error; err != nil {
+		return fmt.Errorf("failed to extend session: %w", err)
+	}
+	return nil
+}
+
+// enforceSessionLimit makes room for a new session. Dozens of simultaneous
+// sessions usually mean leaked credentials, so past the limit the oldest
+// sessions are revoked and the user is told by email; strict mode refuses the
+// new login instead. Either way the event goes to the audit log.
+func (sm *SessionManager) enforceSessionLimit(userID uint, ipAddress string) error {
+	limit := sm.config.SessionMaxPerUser
+	if limit <= 0 {
+		return nil
+	}
+
+	sessions, err := sm.GetUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	excess := len(sessions) - limit + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	if sm.config.SessionLimitStrict {
+		sm.audit(userID, "session_limit_blocked", map[string]interface{}{
+			"active_sessions": len(sessions),
+			"limit":           limit,
+			"ip_address":      ipAddress,
+		})
+		return ErrTooManySessions
+	}
+
+	// GetUserSessions returns the oldest first
+	evicted := sessions[:excess]
+	for _, s := range evicted {
+		if err := sm.RevokeSession(s.SessionID); err != nil {
+			return fmt.Errorf("failed to revoke oldest session: %w", err)
+		}
+	}
+
+	devices := make([]map[string]interface{}, 0, len(evicted))
+	for _, s := range evicted {
+		devices = append(devices, map[string]interface{}{
+			"ip_address": s.IPAddress,
+			"user_agent": s.UserAgent,
+			"created_at": s.CreatedAt,
+		})
+	}
+	sm.audit(userID, "session_evicted", map[string]interface{}{
+		"evicted":    devices,
+		"limit":      limit,
+		"ip_address": ipAddress,
+	})
+
+	var user models.User
+	if err := sm.db.First(&user, userID).Error; err == nil {
+		_ = NewEmailService(sm.config).SendSessionsRevokedNotice(&user, evicted, ipAddress)
+	}
+	return nil
+}
+
+// audit records a session security event; failures don't block the login
+func (sm *SessionManager) audit(userID uint, event string, details map[string]interface{}) {
+	data, _ := json.Marshal(details)
+	_ = sm.db.Create(&models.AuditLog{UserID: &userID, Event: event, Details: string(data)}).Error
+}
+
 // GetSession retrieves and validates a session by session ID.
 //
 // This method implements a tiered lookup strategy:
@@ -189,6 +301,14 @@ func (sm *SessionManager) GetSession(sessionID string) (*models.UserSession, err
 // RevokeSession immediately invalidates and removes a session from both stores.
 // Used for logout, security incidents, or administrative actions.
 func (sm *SessionManager) RevokeSession(sessionID string) error {
+	// A login session ends with its refresh tokens
+	var session models.UserSession
+	if err := sm.db.Where("session_id = ?", sessionID).First(&session).Error; err == nil && session.RefreshFamilyID != "" {
+		if err := revokeFamily(sm.db, session.RefreshFamilyID); err != nil {
+			return fmt.Errorf("failed to revoke session refresh tokens: %w", err)
+		}
+	}
+
 	// Remove from Redis cache
 	if sm.redisClient != nil {
 		key := fmt.Sprintf("session:%s", sessionID)
@@ -205,11 +325,14 @@ func (sm *SessionManager) RevokeSession(sessionID string) error {
 	return nil
 }
 
-// GetUserSessions returns all active (non-expired) sessions for a specific user.
-// Useful for security dashboards and "log out from all devices" functionality.
+// GetUserSessions returns all active (non-expired) sessions for a specific user,
+// oldest first. Useful for security dashboards and "log out from all devices" functionality.
+// Login sessions whose refresh tokens were revoked are left out.
 func (sm *SessionManager) GetUserSessions(userID uint) ([]models.UserSession, error) {
 	var sessions []models.UserSession
-	if err := sm.db.Where("user_id = ? AND expires_at > ?", userID, time.Now()).Find(&sessions).Error; err != nil {
+	if err := sm.db.Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Where("COALESCE(refresh_family_id, '') = '' OR EXISTS (SELECT 1 FROM refresh_tokens WHERE refresh_tokens.family_id = user_sessions.refresh_family_id AND refresh_tokens.revoked = ?)", false).
+		Order("created_at, id").Find(&sessions).Error; err != nil {
 		return nil, fmt.Errorf("failed to get user sessions: %w", err)
 	}
 	return sessions, nil
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 test key "12345678901234567890", base32-encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	// A fixed clock in the middle of step 37037036
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		// The last six digits of the RFC 6238 SHA-1 test vectors
+		{name: "RFC vector", secret: rfcSecret, code: "050471", wantStep: current, wantOK: true},
+		{name: "lower case secret with padding", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq====", code: "050471", wantStep: current, wantOK: true},
+		{name: "previous step within the skew", secret: rfcSecret, code: totpCode(key, current-1), wantStep: current - 1, wantOK: true},
+		{name: "next step within the skew", secret: rfcSecret, code: totpCode(key, current+1), wantStep: current + 1, wantOK: true},
+		{name: "two steps old", secret: rfcSecret, code: totpCode(key, current-2)},
+		{name: "two steps ahead", secret: rfcSecret, code: totpCode(key, current+2)},
+		{name: "wrong code", secret: rfcSecret, code: "000000"},
+		{name: "too short", secret: rfcSecret, code: "05047"},
+		{name: "too long", secret: rfcSecret, code: "0504710"},
+		{name: "invalid secret", secret: "not base32!", code: "050471"},
+		{name: "no secret", code: "050471"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP = %d, %v; want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPCodeMatchesRFCVectors(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		if got := totpCode(key, TOTPStep(time.Unix(unix, 0))); got != want {
+			t.Errorf("code at %d is %s, want %s", unix, got, want)
+		}
+	}
+}
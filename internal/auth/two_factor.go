@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"trade_company/internal/config"
+)
+
+// TwoFactorLoginTTL is how long after the password step the code must be entered
+const TwoFactorLoginTTL = 5 * time.Minute
+
+// ErrInvalidTwoFactorLogin is returned for a tampered, malformed or expired
+// two-factor login token
+var ErrInvalidTwoFactorLogin = errors.New("invalid or expired two-factor login token")
+
+// TwoFactorLoginToken signs the proof that userID passed the password step,
+// which the code step exchanges for the auth cookie. It is not a session:
+// nothing but POST /auth/2fa/login accepts it.
+func TwoFactorLoginToken(cfg *config.Config, userID uint) (string, time.Time) {
+	expiresAt := now().Add(TwoFactorLoginTTL).Truncate(time.Second)
+	payload := strconv.FormatUint(uint64(userID), 10) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + twoFactorLoginSignature(cfg, payload), expiresAt
+}
+
+// ParseTwoFactorLoginToken checks a two-factor login token's signature and
+// expiry and returns the user who passed the password step
+func ParseTwoFactorLoginToken(cfg *config.Config, token string) (uint, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return 0, ErrInvalidTwoFactorLogin
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(twoFactorLoginSignature(cfg, payload))) {
+		return 0, ErrInvalidTwoFactorLogin
+	}
+
+	id, exp, _ := strings.Cut(payload, ".")
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, ErrInvalidTwoFactorLogin
+	}
+	expiresAt, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now().Before(time.Unix(expiresAt, 0)) {
+		return 0, ErrInvalidTwoFactorLogin
+	}
+	return uint(userID), nil
+}
+
+func twoFactorLoginSignature(cfg *config.Config, payload string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.JWTSecret))
+	mac.Write([]byte("2fa-login\n"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/config"
+)
+
+// ErrInvalidUnsubscribeToken is returned for a tampered or malformed unsubscribe token
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeToken signs an unsubscribe link for one user and email category.
+// The link is the only credential, so people can opt out straight from the
+// email without logging in; it doesn't expire, as old emails must keep working.
+func UnsubscribeToken(cfg *config.Config, userID uint, category EmailCategory) string {
+	payload := strconv.FormatUint(uint64(userID), 10) + "." + string(category)
+	return payload + "." + unsubscribeSignature(cfg, payload)
+}
+
+// ParseUnsubscribeToken checks an unsubscribe token's signature and returns
+// the user and category it opts out of
+func ParseUnsubscribeToken(cfg *config.Config, token string) (uint, EmailCategory, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(unsubscribeSignature(cfg, payload))) {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+
+	id, category, _ := strings.Cut(payload, ".")
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	switch EmailCategory(category) {
+	case EmailNotification, EmailMarketing:
+		return uint(userID), EmailCategory(category), nil
+	}
+	// Transactional emails can't be opted out of
+	return 0, "", ErrInvalidUnsubscribeToken
+}
+
+// unsubscribeSignature is keyed on the JWT secret; the prefix keeps these
+// signatures from being valid anywhere else the secret signs
+func unsubscribeSignature(cfg *config.Config, payload string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.JWTSecret))
+	mac.Write([]byte("unsubscribe\n"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
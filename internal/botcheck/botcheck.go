@@ -0,0 +1,274 @@
+// Package botcheck scores form submissions for signs of automation. Each
+// signal adds to a score, and every protected endpoint has its own
+// thresholds for flagging a submission for review or rejecting it outright.
+//
+// Signals:
+//   - honeypot: a decoy field (HONEYPOT_FIELDS) was filled in
+//   - too_fast: the form was submitted within a moment of being rendered,
+//     timed by the signed form token (or the older client-side form_time)
+//   - form_token: the form token is missing, tampered with or too old
+//   - no_user_agent: the request has no User-Agent header
+//   - ip_reputation: the IP has had many 4xx responses recently (needs Redis)
+package botcheck
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/redis/go-redis/v9"
+)
+
+// Signal is one sign of automation found in a submission
+type Signal string
+
+const (
+	SignalHoneypot     Signal = "honeypot"
+	SignalTooFast      Signal = "too_fast"
+	SignalFormToken    Signal = "form_token"
+	SignalNoUserAgent  Signal = "no_user_agent"
+	SignalIPReputation Signal = "ip_reputation"
+)
+
+// signalWeights are what each signal adds to the score. A filled honeypot is
+// conclusive on its own; the others only add up.
+var signalWeights = map[Signal]int{
+	SignalHoneypot:     100,
+	SignalTooFast:      50,
+	SignalFormToken:    20,
+	SignalNoUserAgent:  30,
+	SignalIPReputation: 30,
+}
+
+// Endpoint names a protected form, for its thresholds and metrics
+type Endpoint string
+
+const (
+	EndpointSignup Endpoint = "signup"
+	EndpointLead   Endpoint = "lead"
+)
+
+// Verdict is what to do with a submission
+type Verdict string
+
+const (
+	VerdictAllow  Verdict = "allow"
+	VerdictFlag   Verdict = "flag"   // Accept, but hold for review
+	VerdictReject Verdict = "reject" // Refuse
+)
+
+// Thresholds are the scores at which an endpoint flags and rejects
+type Thresholds struct {
+	Flag   int
+	Reject int
+}
+
+// Result is the scoring of one submission
+type Result struct {
+	Score   int
+	Signals []Signal
+	Verdict Verdict
+}
+
+// Form is what a protected form sends besides its own fields
+type Form struct {
+	Token string // form_token from GET /api/v1/forms/token
+	Time  int64  // form_time, milliseconds; older forms that have no token
+}
+
+const (
+	// minFormAge is how long a person needs at least to fill in a form
+	minFormAge = 800 * time.Millisecond
+	// maxFormAge is how long a form token stays valid
+	maxFormAge = 24 * time.Hour
+
+	errorCountKey = "botcheck:4xx:"
+)
+
+// ErrInvalidFormToken is returned for a tampered or malformed form token
+var ErrInvalidFormToken = errors.New("invalid form token")
+
+// Checker scores submissions. It is safe for concurrent use; without Redis
+// the IP reputation signal is skipped.
+type Checker struct {
+	client     *redis.Client
+	secret     []byte
+	honeypots  []string
+	thresholds map[Endpoint]Thresholds
+
+	errorWindow time.Duration
+	errorLimit  int64
+}
+
+// New creates a Checker from the BOTCHECK_* and HONEYPOT_FIELDS settings
+func New(cfg *config.Config, client *redis.Client) *Checker {
+	return &Checker{
+		client:    client,
+		secret:    []byte(cfg.JWTSecret),
+		honeypots: cfg.HoneypotFieldNames(),
+		thresholds: map[Endpoint]Thresholds{
+			EndpointSignup: {Flag: cfg.BotcheckSignupFlagScore, Reject: cfg.BotcheckSignupRejectScore},
+			EndpointLead:   {Flag: cfg.BotcheckLeadFlagScore, Reject: cfg.BotcheckLeadRejectScore},
+		},
+		errorWindow: time.Duration(cfg.BotcheckIPErrorWindowMinutes) * time.Minute,
+		errorLimit:  int64(cfg.BotcheckIPErrorLimit),
+	}
+}
+
+// BindJSON binds the request body like ShouldBindJSON and reports whether any
+// honeypot field was filled in. The names are configurable, so they are looked
+// up in the raw body rather than bound to struct fields. Any value other than
+// null or "" counts as filled.
+func (ch *Checker) BindJSON(c *gin.Context, obj interface{}) (bool, error) {
+	if err := c.ShouldBindBodyWith(obj, binding.JSON); err != nil {
+		return false, err
+	}
+	body, ok := c.Get(gin.BodyBytesKey)
+	if !ok {
+		return false, nil
+	}
+	return HoneypotFilled(body.([]byte), ch.honeypots), nil
+}
+
+// HoneypotFilled reports whether a JSON body fills any of the named fields
+func HoneypotFilled(body []byte, honeypots []string) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false
+	}
+	for _, name := range honeypots {
+		if v, ok := fields[name]; ok && string(v) != "null" && string(v) != `""` {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate scores a submission to endpoint whose honeypot check came from BindJSON
+func (ch *Checker) Evaluate(c *gin.Context, endpoint Endpoint, honeypot bool, form Form) Result {
+	var signals []Signal
+	if honeypot {
+		signals = append(signals, SignalHoneypot)
+	}
+	signals = append(signals, ch.timingSignals(form, time.Now())...)
+	if c.Request.UserAgent() == "" {
+		signals = append(signals, SignalNoUserAgent)
+	}
+	if ch.badReputation(c.Request.Context(), c.ClientIP()) {
+		signals = append(signals, SignalIPReputation)
+	}
+	r := Score(signals, ch.thresholds[endpoint])
+
+	names := make([]string, len(signals))
+	for i, s := range signals {
+		names[i] = string(s)
+	}
+	metrics.IncBotCheck(string(endpoint), string(r.Verdict), names)
+	return r
+}
+
+// Score adds up the weights of signals and compares the total to t
+func Score(signals []Signal, t Thresholds) Result {
+	r := Result{Signals: signals, Verdict: VerdictAllow}
+	for _, s := range signals {
+		r.Score += signalWeights[s]
+	}
+	switch {
+	case r.Score >= t.Reject:
+		r.Verdict = VerdictReject
+	case r.Score >= t.Flag:
+		r.Verdict = VerdictFlag
+	}
+	return r
+}
+
+// timingSignals checks how long the form was open. The signed token can't be
+// forged, so a missing or bad one counts against the submission; form_time is
+// still honored for forms that predate the token.
+func (ch *Checker) timingSignals(form Form, now time.Time) []Signal {
+	var rendered time.Time
+	switch {
+	case form.Token != "":
+		issued, err := ch.ParseFormToken(form.Token)
+		if err != nil || now.Sub(issued) > maxFormAge {
+			return []Signal{SignalFormToken}
+		}
+		rendered = issued
+	case form.Time > 0:
+		rendered = time.UnixMilli(form.Time)
+	default:
+		return []Signal{SignalFormToken}
+	}
+	if now.Sub(rendered) < minFormAge {
+		return []Signal{SignalTooFast}
+	}
+	return nil
+}
+
+// FormToken signs the time a form is rendered
+func (ch *Checker) FormToken(now time.Time) string {
+	payload := strconv.FormatInt(now.UnixMilli(), 10)
+	return payload + "." + ch.formSignature(payload)
+}
+
+// ParseFormToken checks a form token's signature and returns when it was issued
+func (ch *Checker) ParseFormToken(token string) (time.Time, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(ch.formSignature(payload))) {
+		return time.Time{}, ErrInvalidFormToken
+	}
+	ms, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidFormToken
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// formSignature is keyed on the JWT secret; the prefix keeps these signatures
+// from being valid anywhere else the secret signs
+func (ch *Checker) formSignature(payload string) string {
+	mac := hmac.New(sha256.New, ch.secret)
+	mac.Write([]byte("form\n"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TrackErrors counts 4xx responses per client IP for the reputation signal.
+// Scanners and credential stuffers leave a trail of 400s, 401s and 404s
+// before they reach a form.
+func (ch *Checker) TrackErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if ch.client == nil || c.Writer.Status() < 400 || c.Writer.Status() >= 500 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		key := errorCountKey + c.ClientIP()
+		// The window starts at the first error rather than sliding with each one
+		if n, err := ch.client.Incr(ctx, key).Result(); err == nil && n == 1 {
+			ch.client.Expire(ctx, key, ch.errorWindow)
+		}
+	}
+}
+
+// badReputation reports whether ip had at least the configured number of 4xx
+// responses in the current window. Redis errors count as a clean record.
+func (ch *Checker) badReputation(ctx context.Context, ip string) bool {
+	if ch.client == nil || ch.errorLimit <= 0 {
+		return false
+	}
+	n, err := ch.client.Get(ctx, errorCountKey+ip).Int64()
+	return err == nil && n >= ch.errorLimit
+}
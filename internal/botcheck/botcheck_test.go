@@ -0,0 +1,49 @@
+package botcheck
+
+import "testing"
+
+func TestHoneypotFilled(t *testing.T) {
+	honeypots := []string{"website", "fax"}
+	tests := []struct {
+		body   string
+		filled bool
+	}{
+		{body: `{"subject": "Hi"}`},
+		{body: `{"subject": "Hi", "website": ""}`},
+		{body: `{"subject": "Hi", "website": null, "fax": ""}`},
+		{body: `{"website": "http://spam.example"}`, filled: true},
+		{body: `{"fax": "02-1234-5678"}`, filled: true},
+		{body: `{"fax": 0}`, filled: true},
+		{body: `{"fax": false}`, filled: true},
+		{body: `{"fax": " "}`, filled: true},
+		// Only configured names are decoys
+		{body: `{"homepage": "http://spam.example"}`},
+		{body: `not json`},
+	}
+	for _, tt := range tests {
+		if got := HoneypotFilled([]byte(tt.body), honeypots); got != tt.filled {
+			t.Errorf("%s: filled %v, want %v", tt.body, got, tt.filled)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	thresholds := Thresholds{Flag: 40, Reject: 100}
+	tests := []struct {
+		signals []Signal
+		score   int
+		verdict Verdict
+	}{
+		{verdict: VerdictAllow},
+		{signals: []Signal{SignalFormToken}, score: 20, verdict: VerdictAllow},
+		{signals: []Signal{SignalTooFast}, score: 50, verdict: VerdictFlag},
+		// A filled honeypot rejects on its own
+		{signals: []Signal{SignalHoneypot}, score: 100, verdict: VerdictReject},
+		{signals: []Signal{SignalTooFast, SignalNoUserAgent, SignalFormToken}, score: 100, verdict: VerdictReject},
+	}
+	for _, tt := range tests {
+		if r := Score(tt.signals, thresholds); r.Score != tt.score || r.Verdict != tt.verdict {
+			t.Errorf("%v: score %d %s, want %d %s", tt.signals, r.Score, r.Verdict, tt.score, tt.verdict)
+		}
+	}
+}
@@ -4,8 +4,16 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"trade_company/internal/imagecheck"
+	"trade_company/internal/imageproxy"
 )
 
+// defaultUploadSigningSecret only works in development; validate rejects it elsewhere
+const defaultUploadSigningSecret = "changeme-upload-signing-secret"
+
 type Config struct {
 	AppName string
 	AppEnv  string
@@ -28,16 +36,30 @@ type Config struct {
 	JWTSecret        string
 	JWTIssuer        string
 	JWTExpireMinutes int
+	// Clock skew tolerated when checking exp, nbf and iat
+	JWTLeewaySeconds int
+	// A token may be extended during this many minutes before it expires
+	JWTExtendWindowMinutes int
+	// Lifetime of refresh tokens, which POST /auth/refresh rotates on every use
+	JWTRefreshExpireDays int
+
+	// bcrypt cost for new password hashes, 10-14; each step doubles login time
+	BcryptCost int
 
 	CORSAllowedOrigins string
 	CORSAllowedMethods string
 	CORSAllowedHeaders string
+	// Send Access-Control-Allow-Credentials; never sent alongside a wildcard origin
+	CORSAllowCredentials bool
 
 	// Members service configuration
 	SendGridAPIKey    string
 	SendGridFromEmail string
 	SendGridFromName  string
 
+	// Base64 ECDSA public key from the SendGrid event webhook settings
+	SendGridWebhookPublicKey string
+
 	// Session management
 	SessionSecret         string
 	SessionTTLMinutes     int
@@ -45,28 +67,198 @@ type Config struct {
 	SessionCookieSecure   bool
 	SessionCookieHttpOnly bool
 	SessionCookieSameSite string
+	// Active sessions per user (0 = no limit); past it the oldest session is
+	// revoked, or in strict mode the new login is refused
+	SessionMaxPerUser  int
+	SessionLimitStrict bool
 
 	// Rate limiting
 	RateLimitLoginPerMinute        int
 	RateLimitSignupPerHour         int
 	RateLimitForgotPasswordPerHour int
 	RateLimitContactSellerPerHour  int
+	MaxOpenLeadsPerListing         int
 
 	// Security
 	PasswordMinLength      int
 	MaxLoginAttempts       int
 	LockoutDurationMinutes int
 
+	// Lockout escalation ("flat" or "exponential")
+	LockoutMode                  string
+	LockoutEscalationWindowHours int
+	LockoutMaxDurationMinutes    int
+
+	// Adaptive login challenge (Cloudflare Turnstile) after repeated failures
+	LoginChallengeEnabled       bool
+	LoginChallengeThreshold     int
+	LoginChallengeWindowMinutes int
+	TurnstileSecretKey          string
+
+	// Comma-separated decoy form fields; a request that fills any of them is a bot
+	HoneypotFields string
+	// Bot scores (see package botcheck) at which signups and leads are flagged
+	// for review and rejected
+	BotcheckSignupFlagScore   int
+	BotcheckSignupRejectScore int
+	BotcheckLeadFlagScore     int
+	BotcheckLeadRejectScore   int
+	// An IP with this many 4xx responses within the window scores as a bot; 0 disables
+	BotcheckIPErrorLimit         int
+	BotcheckIPErrorWindowMinutes int
+
+	// Current terms of service version; users who accepted an older one must re-accept
+	TermsVersion string
+
 	// 2FA
 	TwoFactorIssuer string
 
+	// Optional file of extra reserved usernames, one per line
+	ReservedUsernamesFile string
+
+	// Blocked terms in listings, messages and leads: "reject" refuses the text,
+	// "mask" stars the terms out, "off" disables the filter. The optional file
+	// adds terms, one per line.
+	SensitiveWordsMode string
+	SensitiveWordsFile string
+
 	// File upload limits
 	MaxFileSizeMB      int
 	MaxTotalSizeMB     int
 	MaxFilesPerRequest int
 	MaxAvatarSizeMB    int
+	MaxDocumentSizeMB  int
 	GlobalBodyLimitMB  int
 
+	// Listing images: comma-separated formats (jpeg, png, gif, webp) and maximum pixel dimensions
+	ImageAllowedFormats string
+	ImageMaxWidth       int
+	ImageMaxHeight      int
+
+	// Export gate: one running export per user and a cap across all instances
+	ExportMaxConcurrent  int
+	ExportSlotTTLMinutes int
+
+	// Heavy read limiter: weight admitted at once per instance (0 disables) and
+	// how long a request waits for room before a 503
+	HeavyReadMaxConcurrent int
+	HeavyReadQueueMillis   int
+
+	// GraphQL persisted queries: "auto" (APQ) or "allowlist", how long APQ
+	// entries stay cached, and a build-time manifest of registered queries
+	GraphQLAPQMode              string
+	GraphQLAPQTTLHours          int
+	GraphQLPersistedQueriesFile string
+
+	// Resized image variants (GET /img/:imageID?w=): comma-separated widths and an LRU disk cache
+	ImageVariantWidths  string
+	ImageCacheDir       string
+	ImageCacheMaxSizeMB int
+
+	// Upload storage
+	PublicUploadDir     string
+	PrivateUploadDir    string
+	UploadSigningSecret string
+	SignedURLTTLMinutes int
+
+	// Resumable uploads: chunks are staged on disk, upload state lives in Redis
+	UploadChunkDir        string
+	UploadChunkSizeMB     int
+	MaxOpenUploadsPerUser int
+
+	// Image moderation ("none" approves everything, "vision" uses Cloud Vision SafeSearch)
+	ImageModerationProvider string
+	GoogleVisionAPIKey      string
+
+	// Listing translation ("none" disables it, "google" uses Cloud Translation)
+	TranslationProvider   string
+	GoogleTranslateAPIKey string
+	// Listings translated per minute and characters sent per calendar month (0 = no cap)
+	TranslationJobsPerMinute    int
+	TranslationMonthlyCharLimit int
+
+	// Listing publishing rules
+	ListingMinImages int
+	// Days a listing stays active before it expires unless renewed (0 = never)
+	ListingTTLDays int
+	// Cache listing details and search pages in Redis (needs Redis to take effect)
+	ListingCacheEnabled bool
+	// Accepted listing amounts in NT$. Rent, deposit and annual revenue are
+	// optional, so 0 is always accepted for them.
+	ListingPriceMin         int64
+	ListingPriceMax         int64
+	ListingRentMax          int64
+	ListingDepositMax       int64
+	ListingAnnualRevenueMax int64
+
+	// Soft-validation warnings shown to sellers (never block a save)
+	ListingWarningMaxPriceToRevenue   int
+	ListingWarningMinDescriptionRunes int
+	ListingWarningsDisabled           string // comma-separated warning codes
+
+	// Seller contact details are only revealed to buyers who have sent a lead
+	ContactRevealRequiresLead bool
+
+	// Platform-wide defaults for the per-listing statistics privacy flags
+	ListingHideViewCountDefault     bool
+	ListingHideFavoriteCountDefault bool
+	ListingHideLastActiveDefault    bool
+
+	// Popularity integrity (bot guards for views and favorites)
+	ViewVelocityPerMinute      int
+	FavoriteMinAccountAgeHours int
+
+	// Trending leaderboard: activity scores halve every this many hours
+	TrendingHalfLifeHours int
+
+	// Listing search ranking (sort=relevance): component weights and the age in
+	// days at which a listing's freshness score halves
+	SearchRankRelevanceWeight     float64
+	SearchRankRecencyWeight       float64
+	SearchRankVerifiedWeight      float64
+	SearchRankCompletenessWeight  float64
+	SearchRankRecencyHalfLifeDays int
+
+	// Data retention for leads and messages ("delete" or "anonymize")
+	RetentionLeadsDays    int
+	RetentionMessagesDays int
+	RetentionMode         string
+	RetentionBatchSize    int
+
+	// Unverified accounts older than this are removed ("delete" or "anonymize")
+	UnverifiedAccountTTLDays int
+	UnverifiedAccountMode    string
+
+	// Keep-alive: sellers returning after this many days are asked to confirm their
+	// active listings, and unconfirmed ones are archived after the grace period
+	KeepAliveInactiveDays int
+	KeepAliveGraceDays    int
+
+	// Unread leads/messages digest emails: at most one per user every N hours; 0 disables
+	DigestIntervalHours int
+
+	// Nightly listing_counts reconciliation logs an error when a row is off by more than this
+	ListingCountDriftThreshold int
+
+	// Auction proxy: seconds to cache auction GET responses in Redis (0 disables)
+	AuctionCacheTTLSeconds int
+	// Shared secret the auction service signs result webhooks with; empty disables them
+	AuctionWebhookSecret string
+
+	// Saved comparison lists
+	ComparisonMaxListings int
+	ComparisonMaxLists    int
+
+	// Pagination (per endpoint)
+	ListingsDefaultPageSize  int
+	ListingsMaxPageSize      int
+	MessagesDefaultPageSize  int
+	MessagesMaxPageSize      int
+	LeadsDefaultPageSize     int
+	LeadsMaxPageSize         int
+	FavoritesDefaultPageSize int
+	FavoritesMaxPageSize     int
+
 	// API 和靜態文件基礎 URL - 根據環境自動設置
 	APIBaseURL    string
 	StaticBaseURL string
@@ -111,15 +303,29 @@ func Load() (*Config, error) {
 	cfg.JWTSecret = getEnv("JWT_SECRET", "your-local-jwt-secret")
 	cfg.JWTIssuer = getEnv("JWT_ISSUER", "trade_company")
 	cfg.JWTExpireMinutes = getEnvInt("JWT_EXPIRE_MINUTES", 10080) // 7 days default
+	cfg.JWTLeewaySeconds = getEnvInt("JWT_LEEWAY_SECONDS", 30)
+	cfg.JWTExtendWindowMinutes = getEnvInt("JWT_EXTEND_WINDOW_MINUTES", 10)
+	cfg.JWTRefreshExpireDays = getEnvInt("JWT_REFRESH_EXPIRE_DAYS", 7)
+
+	cfg.BcryptCost = getEnvInt("BCRYPT_COST", 10)
 
-	cfg.CORSAllowedOrigins = getEnv("CORS_ALLOWED_ORIGINS", "*")
+	// Origins may use "*" wildcards; without CORS_ALLOWED_ORIGINS production allows
+	// the frontend and everything else allows local and LAN development servers
+	defaultOrigins := "http://localhost:*,https://localhost:*,http://127.0.0.1:*,https://127.0.0.1:*,http://192.168.*,http://172.*"
+	if cfg.AppEnv == "production" {
+		defaultOrigins = "https://business-exchange-frontend-430730011391.us-central1.run.app"
+	}
+	cfg.CORSAllowedOrigins = getEnv("CORS_ALLOWED_ORIGINS", defaultOrigins)
 	cfg.CORSAllowedMethods = getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-	cfg.CORSAllowedHeaders = getEnv("CORS_ALLOWED_HEADERS", "Origin,Content-Type,Accept,Authorization")
+	cfg.CORSAllowedHeaders = getEnv("CORS_ALLOWED_HEADERS", "Origin,Content-Type,Content-Length,Accept,Accept-Encoding,Authorization,X-CSRF-Token,X-Request-ID,X-Chunk-Checksum")
+	// Credentials stay on by default only when a concrete origin allowlist is configured
+	cfg.CORSAllowCredentials = getEnvBool("CORS_ALLOW_CREDENTIALS", cfg.CORSAllowedOrigins != "*")
 
 	// Members service configuration
 	cfg.SendGridAPIKey = getEnv("SENDGRID_API_KEY", "")
 	cfg.SendGridFromEmail = getEnv("SENDGRID_FROM_EMAIL", "noreply@business-exchange.com")
 	cfg.SendGridFromName = getEnv("SENDGRID_FROM_NAME", "Business Exchange")
+	cfg.SendGridWebhookPublicKey = getEnv("SENDGRID_WEBHOOK_PUBLIC_KEY", "")
 
 	// Session management
 	cfg.SessionSecret = getEnv("SESSION_SECRET", "changeme-session-secret")
@@ -128,27 +334,172 @@ func Load() (*Config, error) {
 	cfg.SessionCookieSecure = getEnvBool("SESSION_COOKIE_SECURE", true)
 	cfg.SessionCookieHttpOnly = getEnvBool("SESSION_COOKIE_HTTP_ONLY", true)
 	cfg.SessionCookieSameSite = getEnv("SESSION_COOKIE_SAME_SITE", "Lax")
+	cfg.SessionMaxPerUser = getEnvInt("SESSION_MAX_PER_USER", 5)
+	cfg.SessionLimitStrict = getEnvBool("SESSION_LIMIT_STRICT", false)
 
 	// Rate limiting
 	cfg.RateLimitLoginPerMinute = getEnvInt("RATE_LIMIT_LOGIN_PER_MINUTE", 5)
 	cfg.RateLimitSignupPerHour = getEnvInt("RATE_LIMIT_SIGNUP_PER_HOUR", 3)
 	cfg.RateLimitForgotPasswordPerHour = getEnvInt("RATE_LIMIT_FORGOT_PASSWORD_PER_HOUR", 3)
 	cfg.RateLimitContactSellerPerHour = getEnvInt("RATE_LIMIT_CONTACT_SELLER_PER_HOUR", 10)
+	cfg.MaxOpenLeadsPerListing = getEnvInt("MAX_OPEN_LEADS_PER_LISTING", 2)
 
 	// Security
 	cfg.PasswordMinLength = getEnvInt("PASSWORD_MIN_LENGTH", 8)
 	cfg.MaxLoginAttempts = getEnvInt("MAX_LOGIN_ATTEMPTS", 5)
 	cfg.LockoutDurationMinutes = getEnvInt("LOCKOUT_DURATION_MINUTES", 30)
 
+	// Exponential mode doubles each repeat lockout within the window, up to the max
+	cfg.LockoutMode = getEnv("LOCKOUT_MODE", "flat")
+	cfg.LockoutEscalationWindowHours = getEnvInt("LOCKOUT_ESCALATION_WINDOW_HOURS", 24)
+	cfg.LockoutMaxDurationMinutes = getEnvInt("LOCKOUT_MAX_DURATION_MINUTES", 1440)
+
+	// After this many failed logins for an email or an IP, logins need a Turnstile token
+	cfg.LoginChallengeEnabled = getEnvBool("LOGIN_CHALLENGE_ENABLED", false)
+	cfg.LoginChallengeThreshold = getEnvInt("LOGIN_CHALLENGE_THRESHOLD", 3)
+	cfg.LoginChallengeWindowMinutes = getEnvInt("LOGIN_CHALLENGE_WINDOW_MINUTES", 15)
+	cfg.TurnstileSecretKey = getEnv("TURNSTILE_SECRET_KEY", "")
+
+	// Rename the honeypots once bots learn to skip them; the forms must render the same names
+	cfg.HoneypotFields = getEnv("HONEYPOT_FIELDS", "website")
+
+	// A filled honeypot scores 100, a form sent too fast 50, a missing UA or a
+	// noisy IP 30 and a missing or bad form token 20
+	cfg.BotcheckSignupFlagScore = getEnvInt("BOTCHECK_SIGNUP_FLAG_SCORE", 40)
+	cfg.BotcheckSignupRejectScore = getEnvInt("BOTCHECK_SIGNUP_REJECT_SCORE", 80)
+	cfg.BotcheckLeadFlagScore = getEnvInt("BOTCHECK_LEAD_FLAG_SCORE", 40)
+	cfg.BotcheckLeadRejectScore = getEnvInt("BOTCHECK_LEAD_REJECT_SCORE", 100)
+	cfg.BotcheckIPErrorLimit = getEnvInt("BOTCHECK_IP_4XX_LIMIT", 30)
+	cfg.BotcheckIPErrorWindowMinutes = getEnvInt("BOTCHECK_IP_4XX_WINDOW_MINUTES", 10)
+
+	// Bump when the terms change; signed-in users are then asked to accept them again
+	cfg.TermsVersion = getEnv("TERMS_VERSION", "2024-01")
+
 	// 2FA
 	cfg.TwoFactorIssuer = getEnv("TWO_FACTOR_ISSUER", "Business Exchange")
+	cfg.ReservedUsernamesFile = getEnv("RESERVED_USERNAMES_FILE", "")
+	cfg.SensitiveWordsMode = getEnv("SENSITIVE_WORDS_MODE", "reject")
+	cfg.SensitiveWordsFile = getEnv("SENSITIVE_WORDS_FILE", "")
 
 	// File upload limits
 	cfg.MaxFileSizeMB = getEnvInt("MAX_FILE_SIZE_MB", 5)
 	cfg.MaxTotalSizeMB = getEnvInt("MAX_TOTAL_SIZE_MB", 25)
 	cfg.MaxFilesPerRequest = getEnvInt("MAX_FILES_PER_REQUEST", 10)
 	cfg.MaxAvatarSizeMB = getEnvInt("MAX_AVATAR_SIZE_MB", 1)
+	cfg.MaxDocumentSizeMB = getEnvInt("MAX_DOCUMENT_SIZE_MB", 10)
 	cfg.GlobalBodyLimitMB = getEnvInt("GLOBAL_BODY_LIMIT_MB", 30)
+	cfg.ImageAllowedFormats = getEnv("IMAGE_ALLOWED_FORMATS", "jpeg,png,webp")
+	cfg.ImageMaxWidth = getEnvInt("IMAGE_MAX_WIDTH", 6000)
+	cfg.ImageMaxHeight = getEnvInt("IMAGE_MAX_HEIGHT", 6000)
+
+	// A slot outlives a crashed export by at most the TTL
+	cfg.ExportMaxConcurrent = getEnvInt("EXPORT_MAX_CONCURRENT", 4)
+	cfg.ExportSlotTTLMinutes = getEnvInt("EXPORT_SLOT_TTL_MINUTES", 10)
+
+	// Kept well under DB_MAX_OPEN_CONNS so the cheap endpoints always find a connection
+	cfg.HeavyReadMaxConcurrent = getEnvInt("HEAVY_READ_MAX_CONCURRENT", 20)
+	cfg.HeavyReadQueueMillis = getEnvInt("HEAVY_READ_QUEUE_MS", 200)
+
+	cfg.GraphQLAPQMode = getEnv("GRAPHQL_APQ_MODE", "auto")
+	cfg.GraphQLAPQTTLHours = getEnvInt("GRAPHQL_APQ_TTL_HOURS", 168)
+	cfg.GraphQLPersistedQueriesFile = getEnv("GRAPHQL_PERSISTED_QUERIES_FILE", "")
+
+	// Only these widths are generated, so the variant cache can't be flooded with sizes
+	cfg.ImageVariantWidths = getEnv("IMAGE_VARIANT_WIDTHS", "160,320,400,640,800,1200")
+	cfg.ImageCacheDir = getEnv("IMAGE_CACHE_DIR", "./image_cache")
+	cfg.ImageCacheMaxSizeMB = getEnvInt("IMAGE_CACHE_MAX_SIZE_MB", 512)
+
+	// Upload storage: public files are served statically, private files only through signed URLs
+	cfg.PublicUploadDir = getEnv("PUBLIC_UPLOAD_DIR", "./uploads")
+	cfg.PrivateUploadDir = getEnv("PRIVATE_UPLOAD_DIR", "./private_uploads")
+	cfg.UploadSigningSecret = getEnv("UPLOAD_SIGNING_SECRET", defaultUploadSigningSecret)
+	cfg.SignedURLTTLMinutes = getEnvInt("SIGNED_URL_TTL_MINUTES", 15)
+	cfg.UploadChunkDir = getEnv("UPLOAD_CHUNK_DIR", "./upload_chunks")
+	cfg.UploadChunkSizeMB = getEnvInt("UPLOAD_CHUNK_SIZE_MB", 1)
+	cfg.MaxOpenUploadsPerUser = getEnvInt("MAX_OPEN_UPLOADS_PER_USER", 10)
+
+	// New listing images stay owner-only until the moderation job has checked them
+	cfg.ImageModerationProvider = getEnv("IMAGE_MODERATION_PROVIDER", "none")
+	cfg.GoogleVisionAPIKey = getEnv("GOOGLE_VISION_API_KEY", "")
+
+	// English translations are requested by ?lang=en and filled in by the translation job
+	cfg.TranslationProvider = getEnv("TRANSLATION_PROVIDER", "none")
+	cfg.GoogleTranslateAPIKey = getEnv("GOOGLE_TRANSLATE_API_KEY", "")
+	cfg.TranslationJobsPerMinute = getEnvInt("TRANSLATION_JOBS_PER_MINUTE", 20)
+	cfg.TranslationMonthlyCharLimit = getEnvInt("TRANSLATION_MONTHLY_CHAR_LIMIT", 500000)
+
+	// Listing publishing rules (0 keeps listings publishable without images)
+	cfg.ListingMinImages = getEnvInt("LISTING_MIN_IMAGES", 0)
+	cfg.ListingTTLDays = getEnvInt("LISTING_TTL_DAYS", 90)
+	cfg.ListingCacheEnabled = getEnvBool("LISTING_CACHE_ENABLED", true)
+
+	// Listing amount bounds; generous enough for any real small business sale
+	cfg.ListingPriceMin = getEnvInt64("LISTING_PRICE_MIN", 1)
+	cfg.ListingPriceMax = getEnvInt64("LISTING_PRICE_MAX", 10_000_000_000)
+	cfg.ListingRentMax = getEnvInt64("LISTING_RENT_MAX", 10_000_000)
+	cfg.ListingDepositMax = getEnvInt64("LISTING_DEPOSIT_MAX", 100_000_000)
+	cfg.ListingAnnualRevenueMax = getEnvInt64("LISTING_ANNUAL_REVENUE_MAX", 100_000_000_000)
+
+	// Seller warnings: price above N years of revenue, descriptions shorter than N characters
+	cfg.ListingWarningMaxPriceToRevenue = getEnvInt("LISTING_WARNING_MAX_PRICE_TO_REVENUE", 5)
+	cfg.ListingWarningMinDescriptionRunes = getEnvInt("LISTING_WARNING_MIN_DESCRIPTION_RUNES", 100)
+	cfg.ListingWarningsDisabled = getEnv("LISTING_WARNINGS_DISABLED", "")
+
+	// Contact gating (disable for open marketplaces that show contact details to everyone)
+	cfg.ContactRevealRequiresLead = getEnvBool("CONTACT_REVEAL_REQUIRES_LEAD", true)
+
+	// Defaults for new listings' statistics privacy flags; owners can change them per listing
+	cfg.ListingHideViewCountDefault = getEnvBool("LISTING_HIDE_VIEW_COUNT_DEFAULT", false)
+	cfg.ListingHideFavoriteCountDefault = getEnvBool("LISTING_HIDE_FAVORITE_COUNT_DEFAULT", false)
+	cfg.ListingHideLastActiveDefault = getEnvBool("LISTING_HIDE_LAST_ACTIVE_DEFAULT", false)
+
+	// Popularity integrity: views beyond the per-IP velocity are ignored, and favorites
+	// from accounts younger than the minimum age are stored but not publicly counted
+	cfg.ViewVelocityPerMinute = getEnvInt("VIEW_VELOCITY_PER_MINUTE", 30)
+	cfg.FavoriteMinAccountAgeHours = getEnvInt("FAVORITE_MIN_ACCOUNT_AGE_HOURS", 24)
+	cfg.TrendingHalfLifeHours = getEnvInt("TRENDING_HALF_LIFE_HOURS", 24)
+
+	// Search ranking: relevance leads, freshness next, verification and completeness nudge
+	cfg.SearchRankRelevanceWeight = getEnvFloat("SEARCH_RANK_RELEVANCE_WEIGHT", 1.0)
+	cfg.SearchRankRecencyWeight = getEnvFloat("SEARCH_RANK_RECENCY_WEIGHT", 0.6)
+	cfg.SearchRankVerifiedWeight = getEnvFloat("SEARCH_RANK_VERIFIED_WEIGHT", 0.3)
+	cfg.SearchRankCompletenessWeight = getEnvFloat("SEARCH_RANK_COMPLETENESS_WEIGHT", 0.3)
+	cfg.SearchRankRecencyHalfLifeDays = getEnvInt("SEARCH_RANK_RECENCY_HALF_LIFE_DAYS", 14)
+
+	// Data retention: leads and messages older than these are purged by cmd/purge
+	cfg.RetentionLeadsDays = getEnvInt("RETENTION_LEADS_DAYS", 730)
+	cfg.RetentionMessagesDays = getEnvInt("RETENTION_MESSAGES_DAYS", 730)
+	cfg.RetentionMode = getEnv("RETENTION_MODE", "delete")
+	cfg.RetentionBatchSize = getEnvInt("RETENTION_BATCH_SIZE", 500)
+
+	// Accounts that never verified their email are reminded a day before they expire
+	cfg.UnverifiedAccountTTLDays = getEnvInt("UNVERIFIED_ACCOUNT_TTL_DAYS", 7)
+	cfg.UnverifiedAccountMode = getEnv("UNVERIFIED_ACCOUNT_MODE", "delete")
+
+	// Sellers away this long confirm their listings on return or see them archived
+	cfg.KeepAliveInactiveDays = getEnvInt("KEEP_ALIVE_INACTIVE_DAYS", 45)
+	cfg.KeepAliveGraceDays = getEnvInt("KEEP_ALIVE_GRACE_DAYS", 30)
+
+	// Sellers who don't log in get a summary of what they missed
+	cfg.DigestIntervalHours = getEnvInt("DIGEST_INTERVAL_HOURS", 24)
+
+	cfg.AuctionCacheTTLSeconds = getEnvInt("AUCTION_CACHE_TTL_SECONDS", 5)
+	cfg.AuctionWebhookSecret = getEnv("AUCTION_WEBHOOK_SECRET", "")
+	cfg.ListingCountDriftThreshold = getEnvInt("LISTING_COUNT_DRIFT_THRESHOLD", 5)
+
+	// Saved comparison lists: listings per list and lists per user
+	cfg.ComparisonMaxListings = getEnvInt("COMPARISON_MAX_LISTINGS", 4)
+	cfg.ComparisonMaxLists = getEnvInt("COMPARISON_MAX_LISTS", 10)
+
+	// Pagination (per endpoint)
+	cfg.ListingsDefaultPageSize = getEnvInt("LISTINGS_DEFAULT_PAGE_SIZE", 50)
+	cfg.ListingsMaxPageSize = getEnvInt("LISTINGS_MAX_PAGE_SIZE", 100)
+	cfg.MessagesDefaultPageSize = getEnvInt("MESSAGES_DEFAULT_PAGE_SIZE", 20)
+	cfg.MessagesMaxPageSize = getEnvInt("MESSAGES_MAX_PAGE_SIZE", 100)
+	cfg.LeadsDefaultPageSize = getEnvInt("LEADS_DEFAULT_PAGE_SIZE", 20)
+	cfg.LeadsMaxPageSize = getEnvInt("LEADS_MAX_PAGE_SIZE", 100)
+	cfg.FavoritesDefaultPageSize = getEnvInt("FAVORITES_DEFAULT_PAGE_SIZE", 20)
+	cfg.FavoritesMaxPageSize = getEnvInt("FAVORITES_MAX_PAGE_SIZE", 100)
 
 	// API 和靜態文件基礎 URL - 根據環境自動設置
 	if cfg.AppEnv == "production" {
@@ -161,9 +512,233 @@ func Load() (*Config, error) {
 		cfg.StaticBaseURL = getEnv("STATIC_BASE_URL", "http://127.0.0.1:8080")
 	}
 
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+// validate checks settings that would otherwise fail in confusing ways at request time
+func (c *Config) validate() error {
+	pageSizes := []struct {
+		name        string
+		defaultSize int
+		maxSize     int
+	}{
+		{"LISTINGS", c.ListingsDefaultPageSize, c.ListingsMaxPageSize},
+		{"MESSAGES", c.MessagesDefaultPageSize, c.MessagesMaxPageSize},
+		{"LEADS", c.LeadsDefaultPageSize, c.LeadsMaxPageSize},
+		{"FAVORITES", c.FavoritesDefaultPageSize, c.FavoritesMaxPageSize},
+	}
+	for _, p := range pageSizes {
+		if p.defaultSize <= 0 || p.maxSize <= 0 {
+			return fmt.Errorf("%s_DEFAULT_PAGE_SIZE and %s_MAX_PAGE_SIZE must be positive", p.name, p.name)
+		}
+		if p.maxSize < p.defaultSize {
+			return fmt.Errorf("%s_MAX_PAGE_SIZE (%d) must be >= %s_DEFAULT_PAGE_SIZE (%d)", p.name, p.maxSize, p.name, p.defaultSize)
+		}
+	}
+
+	if c.JWTLeewaySeconds < 0 || c.JWTLeewaySeconds > 300 {
+		return fmt.Errorf("JWT_LEEWAY_SECONDS must be between 0 and 300, got %d", c.JWTLeewaySeconds)
+	}
+	if c.JWTExtendWindowMinutes <= 0 || c.JWTExtendWindowMinutes > c.JWTExpireMinutes {
+		return fmt.Errorf("JWT_EXTEND_WINDOW_MINUTES must be positive and at most JWT_EXPIRE_MINUTES (%d), got %d", c.JWTExpireMinutes, c.JWTExtendWindowMinutes)
+	}
+	if c.JWTRefreshExpireDays <= 0 {
+		return fmt.Errorf("JWT_REFRESH_EXPIRE_DAYS must be positive, got %d", c.JWTRefreshExpireDays)
+	}
+
+	// Below 10 hashes are cheap to brute-force; above 14 a login takes seconds
+	if c.BcryptCost < 10 || c.BcryptCost > 14 {
+		return fmt.Errorf("BCRYPT_COST must be between 10 and 14, got %d", c.BcryptCost)
+	}
+
+	if c.RetentionMode != "delete" && c.RetentionMode != "anonymize" {
+		return fmt.Errorf("RETENTION_MODE must be \"delete\" or \"anonymize\", got %q", c.RetentionMode)
+	}
+	if c.RetentionLeadsDays <= 0 || c.RetentionMessagesDays <= 0 || c.RetentionBatchSize <= 0 {
+		return fmt.Errorf("RETENTION_LEADS_DAYS, RETENTION_MESSAGES_DAYS and RETENTION_BATCH_SIZE must be positive")
+	}
+
+	formats := imagecheck.ParseFormats(c.ImageAllowedFormats)
+	if len(formats) == 0 {
+		return fmt.Errorf("IMAGE_ALLOWED_FORMATS must list at least one format")
+	}
+	for _, f := range formats {
+		if !imagecheck.Known(f) {
+			return fmt.Errorf("IMAGE_ALLOWED_FORMATS: unknown format %q (known: %s)", f, strings.Join(imagecheck.KnownFormats, ", "))
+		}
+	}
+	if c.ImageMaxWidth <= 0 || c.ImageMaxHeight <= 0 {
+		return fmt.Errorf("IMAGE_MAX_WIDTH and IMAGE_MAX_HEIGHT must be positive")
+	}
+	widths, err := imageproxy.ParseWidths(c.ImageVariantWidths)
+	if err != nil {
+		return fmt.Errorf("IMAGE_VARIANT_WIDTHS: %w", err)
+	}
+	if len(widths) == 0 {
+		return fmt.Errorf("IMAGE_VARIANT_WIDTHS must list at least one width")
+	}
+	if c.ImageCacheMaxSizeMB <= 0 {
+		return fmt.Errorf("IMAGE_CACHE_MAX_SIZE_MB must be positive")
+	}
+	if c.ExportMaxConcurrent <= 0 || c.ExportSlotTTLMinutes <= 0 {
+		return fmt.Errorf("EXPORT_MAX_CONCURRENT and EXPORT_SLOT_TTL_MINUTES must be positive")
+	}
+	if c.HeavyReadMaxConcurrent < 0 || c.HeavyReadQueueMillis < 0 {
+		return fmt.Errorf("HEAVY_READ_MAX_CONCURRENT and HEAVY_READ_QUEUE_MS must not be negative")
+	}
+	if c.GraphQLAPQMode != "auto" && c.GraphQLAPQMode != "allowlist" {
+		return fmt.Errorf("GRAPHQL_APQ_MODE must be \"auto\" or \"allowlist\", got %q", c.GraphQLAPQMode)
+	}
+	if c.GraphQLAPQTTLHours <= 0 {
+		return fmt.Errorf("GRAPHQL_APQ_TTL_HOURS must be positive")
+	}
+
+	if c.ImageModerationProvider != "none" && c.ImageModerationProvider != "vision" {
+		return fmt.Errorf("IMAGE_MODERATION_PROVIDER must be \"none\" or \"vision\", got %q", c.ImageModerationProvider)
+	}
+	if c.ImageModerationProvider == "vision" && c.GoogleVisionAPIKey == "" {
+		return fmt.Errorf("GOOGLE_VISION_API_KEY is required when IMAGE_MODERATION_PROVIDER is \"vision\"")
+	}
+
+	if c.TranslationProvider != "none" && c.TranslationProvider != "google" {
+		return fmt.Errorf("TRANSLATION_PROVIDER must be \"none\" or \"google\", got %q", c.TranslationProvider)
+	}
+	if c.TranslationProvider == "google" && c.GoogleTranslateAPIKey == "" {
+		return fmt.Errorf("GOOGLE_TRANSLATE_API_KEY is required when TRANSLATION_PROVIDER is \"google\"")
+	}
+	if c.TranslationJobsPerMinute <= 0 {
+		return fmt.Errorf("TRANSLATION_JOBS_PER_MINUTE must be positive")
+	}
+	if c.TranslationMonthlyCharLimit < 0 {
+		return fmt.Errorf("TRANSLATION_MONTHLY_CHAR_LIMIT must not be negative")
+	}
+
+	if c.UnverifiedAccountMode != "delete" && c.UnverifiedAccountMode != "anonymize" {
+		return fmt.Errorf("UNVERIFIED_ACCOUNT_MODE must be \"delete\" or \"anonymize\", got %q", c.UnverifiedAccountMode)
+	}
+	if c.UnverifiedAccountTTLDays < 2 {
+		return fmt.Errorf("UNVERIFIED_ACCOUNT_TTL_DAYS must be at least 2 so the reminder goes out a day ahead")
+	}
+
+	if c.SessionMaxPerUser < 0 {
+		return fmt.Errorf("SESSION_MAX_PER_USER must not be negative")
+	}
+	if c.ListingTTLDays < 0 {
+		return fmt.Errorf("LISTING_TTL_DAYS must not be negative")
+	}
+	if c.ListingPriceMin < 0 || c.ListingPriceMax < c.ListingPriceMin {
+		return fmt.Errorf("LISTING_PRICE_MIN must not be negative or above LISTING_PRICE_MAX")
+	}
+	if c.ListingRentMax <= 0 || c.ListingDepositMax <= 0 || c.ListingAnnualRevenueMax <= 0 {
+		return fmt.Errorf("LISTING_RENT_MAX, LISTING_DEPOSIT_MAX and LISTING_ANNUAL_REVENUE_MAX must be positive")
+	}
+	if c.DigestIntervalHours < 0 {
+		return fmt.Errorf("DIGEST_INTERVAL_HOURS must not be negative")
+	}
+
+	if c.KeepAliveInactiveDays <= 0 || c.KeepAliveGraceDays <= 0 {
+		return fmt.Errorf("KEEP_ALIVE_INACTIVE_DAYS and KEEP_ALIVE_GRACE_DAYS must be positive")
+	}
+
+	// The default secret is public, so anyone could sign links to private files with it
+	if c.AppEnv != "development" && (c.UploadSigningSecret == "" || c.UploadSigningSecret == defaultUploadSigningSecret) {
+		return fmt.Errorf("UPLOAD_SIGNING_SECRET must be set outside development")
+	}
+	if c.UploadChunkSizeMB <= 0 || c.MaxOpenUploadsPerUser <= 0 {
+		return fmt.Errorf("UPLOAD_CHUNK_SIZE_MB and MAX_OPEN_UPLOADS_PER_USER must be positive")
+	}
+
+	if c.TrendingHalfLifeHours <= 0 {
+		return fmt.Errorf("TRENDING_HALF_LIFE_HOURS must be positive")
+	}
+	if c.SearchRankRelevanceWeight < 0 || c.SearchRankRecencyWeight < 0 || c.SearchRankVerifiedWeight < 0 || c.SearchRankCompletenessWeight < 0 {
+		return fmt.Errorf("SEARCH_RANK_*_WEIGHT settings must not be negative")
+	}
+	if c.SearchRankRecencyHalfLifeDays <= 0 {
+		return fmt.Errorf("SEARCH_RANK_RECENCY_HALF_LIFE_DAYS must be positive")
+	}
+
+	if c.ComparisonMaxListings <= 0 || c.ComparisonMaxLists <= 0 {
+		return fmt.Errorf("COMPARISON_MAX_LISTINGS and COMPARISON_MAX_LISTS must be positive")
+	}
+
+	if c.LockoutMode != "flat" && c.LockoutMode != "exponential" {
+		return fmt.Errorf("LOCKOUT_MODE must be \"flat\" or \"exponential\", got %q", c.LockoutMode)
+	}
+
+	if len(c.HoneypotFieldNames()) == 0 {
+		return fmt.Errorf("HONEYPOT_FIELDS must name at least one field")
+	}
+	for _, t := range []struct {
+		name         string
+		flag, reject int
+	}{
+		{"SIGNUP", c.BotcheckSignupFlagScore, c.BotcheckSignupRejectScore},
+		{"LEAD", c.BotcheckLeadFlagScore, c.BotcheckLeadRejectScore},
+	} {
+		if t.flag <= 0 || t.reject < t.flag {
+			return fmt.Errorf("BOTCHECK_%s_FLAG_SCORE must be positive and at most BOTCHECK_%s_REJECT_SCORE, got %d and %d", t.name, t.name, t.flag, t.reject)
+		}
+	}
+	if c.BotcheckIPErrorLimit < 0 || c.BotcheckIPErrorWindowMinutes <= 0 {
+		return fmt.Errorf("BOTCHECK_IP_4XX_LIMIT must not be negative and BOTCHECK_IP_4XX_WINDOW_MINUTES must be positive")
+	}
+	if strings.TrimSpace(c.TermsVersion) == "" {
+		return fmt.Errorf("TERMS_VERSION must not be empty")
+	}
+	switch c.SensitiveWordsMode {
+	case "off", "reject", "mask":
+	default:
+		return fmt.Errorf("SENSITIVE_WORDS_MODE must be \"off\", \"reject\" or \"mask\", got %q", c.SensitiveWordsMode)
+	}
+
+	if c.LoginChallengeEnabled && c.TurnstileSecretKey == "" {
+		return fmt.Errorf("TURNSTILE_SECRET_KEY is required when LOGIN_CHALLENGE_ENABLED is set")
+	}
+	if c.LoginChallengeThreshold <= 0 || c.LoginChallengeWindowMinutes <= 0 {
+		return fmt.Errorf("LOGIN_CHALLENGE_THRESHOLD and LOGIN_CHALLENGE_WINDOW_MINUTES must be positive")
+	}
+	if c.LockoutMaxDurationMinutes < c.LockoutDurationMinutes {
+		return fmt.Errorf("LOCKOUT_MAX_DURATION_MINUTES (%d) must be >= LOCKOUT_DURATION_MINUTES (%d)", c.LockoutMaxDurationMinutes, c.LockoutDurationMinutes)
+	}
+	return nil
+}
+
+// UnverifiedAccountTTL is how long an account may stay unverified before it is removed
+func (c *Config) UnverifiedAccountTTL() time.Duration {
+	return time.Duration(c.UnverifiedAccountTTLDays) * 24 * time.Hour
+}
+
+// KeepAliveInactivity is how long a seller must have been away to be asked about their listings
+func (c *Config) KeepAliveInactivity() time.Duration {
+	return time.Duration(c.KeepAliveInactiveDays) * 24 * time.Hour
+}
+
+// KeepAliveGrace is how long a seller has to confirm a listing before it is archived
+func (c *Config) KeepAliveGrace() time.Duration {
+	return time.Duration(c.KeepAliveGraceDays) * 24 * time.Hour
+}
+
+// ListingTTL is how long a new or renewed listing stays up; zero means listings don't expire
+func (c *Config) ListingTTL() time.Duration {
+	return time.Duration(c.ListingTTLDays) * 24 * time.Hour
+}
+
+// HoneypotFieldNames are the HONEYPOT_FIELDS names, trimmed
+func (c *Config) HoneypotFieldNames() []string {
+	var names []string
+	for _, name := range strings.Split(c.HoneypotFields, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 func (c *Config) MySQLDSN() string {
 	// Check if DB_HOST is a Unix socket path (Cloud SQL)
 	if len(c.DBHost) > 0 && c.DBHost[0] == '/' {
@@ -191,6 +766,24 @@ func getEnvInt(key string, def int) int {
 	return def
 }
 
+func getEnvInt64(key string, def int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+func getEnvFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
 func getEnvBool(key string, def bool) bool {
 	if v := os.Getenv(key); v != "" {
 		if b, err := strconv.ParseBool(v); err == nil {
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUploadSigningSecretRequiredOutsideDevelopment(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		secret  string // empty falls back to the default
+		wantErr bool
+	}{
+		{name: "development default", env: "development"},
+		{name: "production default", env: "production", wantErr: true},
+		{name: "staging default", env: "staging", wantErr: true},
+		{name: "production default set explicitly", env: "production", secret: defaultUploadSigningSecret, wantErr: true},
+		{name: "production with a secret", env: "production", secret: "a-real-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.env)
+			t.Setenv("UPLOAD_SIGNING_SECRET", tt.secret)
+			_, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "UPLOAD_SIGNING_SECRET") {
+				t.Errorf("error %q does not name UPLOAD_SIGNING_SECRET", err)
+			}
+		})
+	}
+}
+
+func TestBcryptCostRange(t *testing.T) {
+	tests := []struct {
+		value   string // empty uses the default
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 10},
+		{value: "12", want: 12},
+		{value: "14", want: 14},
+		{value: "9", wantErr: true},
+		{value: "15", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("BCRYPT_COST="+tt.value, func(t *testing.T) {
+			t.Setenv("APP_ENV", "development")
+			t.Setenv("BCRYPT_COST", tt.value)
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "BCRYPT_COST") {
+					t.Errorf("error %q does not name BCRYPT_COST", err)
+				}
+				return
+			}
+			if cfg.BcryptCost != tt.want {
+				t.Errorf("BcryptCost = %d, want %d", cfg.BcryptCost, tt.want)
+			}
+		})
+	}
+}
+
+func TestListingAmountBoundsConfig(t *testing.T) {
+	tests := []struct {
+		env     map[string]string
+		wantErr string
+	}{
+		{env: map[string]string{}},
+		{env: map[string]string{"LISTING_PRICE_MIN": "0", "LISTING_PRICE_MAX": "0"}},
+		{env: map[string]string{"LISTING_PRICE_MIN": "-1"}, wantErr: "LISTING_PRICE_MIN"},
+		{env: map[string]string{"LISTING_PRICE_MIN": "500", "LISTING_PRICE_MAX": "100"}, wantErr: "LISTING_PRICE_MAX"},
+		{env: map[string]string{"LISTING_RENT_MAX": "0"}, wantErr: "LISTING_RENT_MAX"},
+		{env: map[string]string{"LISTING_ANNUAL_REVENUE_MAX": "-5"}, wantErr: "LISTING_ANNUAL_REVENUE_MAX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.env), func(t *testing.T) {
+			t.Setenv("APP_ENV", "development")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if cfg.ListingPriceMax < cfg.ListingPriceMin {
+					t.Errorf("price bounds %d to %d", cfg.ListingPriceMin, cfg.ListingPriceMax)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want one naming %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHoneypotFields(t *testing.T) {
+	tests := []struct {
+		value   string // empty uses the default
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: []string{"website"}},
+		{value: "company_url", want: []string{"company_url"}},
+		{value: " company_url , fax,, ", want: []string{"company_url", "fax"}},
+		{value: " , ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("HONEYPOT_FIELDS="+tt.value, func(t *testing.T) {
+			t.Setenv("APP_ENV", "development")
+			t.Setenv("HONEYPOT_FIELDS", tt.value)
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.HoneypotFieldNames(); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("HoneypotFieldNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListingCacheEnabled(t *testing.T) {
+	tests := []struct {
+		value string // empty uses the default
+		want  bool
+	}{
+		{value: "", want: true},
+		{value: "true", want: true},
+		{value: "false", want: false},
+		{value: "0", want: false},
+		{value: "sometimes", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("LISTING_CACHE_ENABLED="+tt.value, func(t *testing.T) {
+			t.Setenv("APP_ENV", "development")
+			t.Setenv("LISTING_CACHE_ENABLED", tt.value)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.ListingCacheEnabled != tt.want {
+				t.Errorf("ListingCacheEnabled = %v, want %v", cfg.ListingCacheEnabled, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"trade_company/internal/config"
 	"trade_company/internal/models"
+	"trade_company/internal/sanitize"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -59,6 +60,12 @@ func Connect(cfg *config.Config, _ any) (*gorm.DB, error) {
 
 // SeedData adds sample data to the database for testing
 func SeedData(db *gorm.DB, cfg *config.Config) error {
+	return SeedDataWithOptions(db, cfg, SeedOptions{})
+}
+
+// SeedDataWithOptions seeds the curated sample data, then generates the extra
+// users and listings requested by opts
+func SeedDataWithOptions(db *gorm.DB, cfg *config.Config, opts SeedOptions) error {
 	log.Println("Seeding database with sample data...")
 
 	// Check if users already exist
@@ -92,7 +99,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			PasswordHash: hashPassword("admin123"),
 			FirstName:    "Admin",
 			LastName:     "User",
-			Role:         "admin",
+			Role:         models.RoleAdmin,
 			IsActive:     true,
 		},
 		{
@@ -101,7 +108,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			PasswordHash: hashPassword("password123"),
 			FirstName:    "John",
 			LastName:     "Doe",
-			Role:         "user",
+			Role:         models.RoleUser,
 			IsActive:     true,
 		},
 		{
@@ -110,7 +117,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			PasswordHash: hashPassword("password123"),
 			FirstName:    "Jane",
 			LastName:     "Smith",
-			Role:         "user",
+			Role:         models.RoleUser,
 			IsActive:     true,
 		},
 		{
@@ -119,7 +126,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			PasswordHash: hashPassword("password123"),
 			FirstName:    "Bob",
 			LastName:     "Wilson",
-			Role:         "user",
+			Role:         models.RoleUser,
 			IsActive:     true,
 		},
 		{
@@ -128,7 +135,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			PasswordHash: hashPassword("password123"),
 			FirstName:    "Alice",
 			LastName:     "Johnson",
-			Role:         "user",
+			Role:         models.RoleUser,
 			IsActive:     true,
 		},
 	}
@@ -153,7 +160,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "狀況良好，9成新",
 			Location:          "台中市西屯區臺灣大道三段99號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[1].ID, // Jane Smith
 			ViewCount:         156,
 			BrandStory:        "我們曾經是製造業，後來改製造夢想了，我們想造福更多人！！！",
@@ -177,7 +184,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "加盟",
 			Condition:         "全新裝修",
 			Location:          "台北市大安區信義路四段88號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[0].ID, // John Doe
 			ViewCount:         320,
 			BrandStory:        "我們秉持『動起來，改變生活』的理念，打造友善社群健身空間。",
@@ -201,7 +208,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "8成新",
 			Location:          "新北市板橋區文化路一段110號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[2].ID, // Bob Wilson
 			ViewCount:         210,
 			BrandStory:        "以『健康、純粹、美味』為核心，打造甜點的新標準。",
@@ -225,7 +232,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "高雄市鳳山區建國路222號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[3].ID, // Alice Johnso
 			ViewCount:         530,
 			BrandStory:        "我們相信教育是改變世界的力量，提供孩子最安心的成長環境。",
@@ -249,7 +256,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "9成新",
 			Location:          "台北市松山區南京東路五段66號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[4].ID, // Alice Johnson
 			ViewCount:         175,
 			BrandStory:        "美，是一種生活態度，我們致力於讓每位客人找到專屬風格。",
@@ -273,7 +280,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "加盟",
 			Condition:         "7成新",
 			Location:          "台南市中西區民族路88號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[0].ID, // John Doe
 			ViewCount:         410,
 			BrandStory:        "打造快樂天堂，讓遊戲連結不同世代的回憶。",
@@ -297,7 +304,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "9成新",
 			Location:          "台北市信義區永春路100號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[0].ID, // John Doe
 			ViewCount:         248,
 			BrandStory:        "用最簡單的配方，做最真誠的好味道。",
@@ -321,7 +328,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "新竹市東區光復路二段200號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[1].ID, // Jane Smith
 			ViewCount:         301,
 			BrandStory:        "讓忙碌工程師也能吃得健康又省時。",
@@ -345,7 +352,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "8成新",
 			Location:          "台中市北區文心路一段220號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[2].ID, // Bob Wilson
 			ViewCount:         187,
 			BrandStory:        "在繁忙城市裡，留下讓人喘口氣的閱讀逗點。",
@@ -369,7 +376,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "加盟",
 			Condition:         "9成新",
 			Location:          "高雄市苓雅區三多一路88號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[3].ID, // Alice Johnso
 			ViewCount:         269,
 			BrandStory:        "把生活的小麻煩交給我們，換你更多的微笑時光。",
@@ -393,7 +400,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "9成新",
 			Location:          "台南市安平區安北路300號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[4].ID, // Alice Johnson
 			ViewCount:         214,
 			BrandStory:        "用花朵，把日常的平凡變成值得紀念的驚喜。",
@@ -417,7 +424,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "全新裝修",
 			Location:          "桃園市中壢區中山東路二段160號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[0].ID, // John Doe
 			ViewCount:         162,
 			BrandStory:        "在呼吸之間，與自己重新對話。",
@@ -441,7 +448,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "新北市新店區北新路二段150號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[1].ID, // Jane Smith
 			ViewCount:         141,
 			BrandStory:        "把平凡的一天，拍成值得珍藏的一天。",
@@ -465,7 +472,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "基隆市仁愛區愛三路60號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[2].ID, // Bob Wilson
 			ViewCount:         403,
 			BrandStory:        "在海風裡醒來，旅行也有家的溫度。",
@@ -489,7 +496,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "屏東縣東港鎮中正路110號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[3].ID, // Alice Johnso
 			ViewCount:         199,
 			BrandStory:        "從海上到餐桌，縮短美味的距離。",
@@ -513,7 +520,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "花蓮縣花蓮市中正路50號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[4].ID, // Alice Johnson
 			ViewCount:         356,
 			BrandStory:        "在山與雲的中間，留一席給咖啡與你。",
@@ -537,7 +544,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "8成新",
 			Location:          "宜蘭縣羅東鎮中正路210號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[0].ID, // John Doe
 			ViewCount:         133,
 			BrandStory:        "用文具陪伴每一段學習與創作。",
@@ -561,7 +568,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "苗栗縣竹南鎮博愛街90號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[1].ID, // Jane Smith
 			ViewCount:         177,
 			BrandStory:        "讓每天的通勤更安全、更放心。",
@@ -585,7 +592,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "加盟",
 			Condition:         "9成新",
 			Location:          "新竹縣竹北市文興路100號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[2].ID, // Bob Wilson
 			ViewCount:         159,
 			BrandStory:        "為每一件衣服恢復初見時的心動。",
@@ -609,7 +616,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "台北市士林區文林路150號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[3].ID, // Alice Johnso
 			ViewCount:         201,
 			BrandStory:        "把快樂變成能分享的禮物。",
@@ -633,7 +640,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "8成新",
 			Location:          "嘉義市西區文化路120號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[4].ID, // Alice Johnson
 			ViewCount:         188,
 			BrandStory:        "一碗豆花，留住童年的味道。",
@@ -657,7 +664,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "台東縣池上鄉中正路88號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[0].ID, // John Doe
 			ViewCount:         144,
 			BrandStory:        "用好米，做出記憶中的家常味。",
@@ -681,7 +688,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "新竹縣新豐鄉建興路60號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[1].ID, // Jane Smith
 			ViewCount:         329,
 			BrandStory:        "把安全與愛，變成每天可見的日常。",
@@ -705,7 +712,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "9成新",
 			Location:          "新北市三重區重新路三段120號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[2].ID, // Bob Wilson
 			ViewCount:         246,
 			BrandStory:        "髮絲之間，讓自信自然流露。",
@@ -729,7 +736,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "台中市西區公益路200號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[3].ID, // Alice Johnso
 			ViewCount:         318,
 			BrandStory:        "把點子做成作品，把作品變成事業。",
@@ -753,7 +760,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "雲林縣斗六市中山路66號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[4].ID, // Alice Johnson
 			ViewCount:         207,
 			BrandStory:        "用時間換來的麥香，值得等候。",
@@ -777,7 +784,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "新北市板橋區文化路二段88號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[0].ID, // John Doe
 			ViewCount:         173,
 			BrandStory:        "讓毛孩更舒服，讓飼主更放心。",
@@ -801,7 +808,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "9成新",
 			Location:          "桃園市桃園區中華路500號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[1].ID, // Jane Smith
 			ViewCount:         220,
 			BrandStory:        "讓車子在十分鐘內煥然一新。",
@@ -825,7 +832,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "台北市中山區南京東路二段120號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[2].ID, // Bob Wilson
 			ViewCount:         195,
 			BrandStory:        "讓視界清晰，讓生活更輕鬆。",
@@ -849,7 +856,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "新北市永和區中山路一段180號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[3].ID, // Alice Johnso
 			ViewCount:         287,
 			BrandStory:        "用好湯底，走十里都要回頭吃。",
@@ -873,7 +880,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			Category:          "直營",
 			Condition:         "良好",
 			Location:          "台南市東區東寧路260號",
-			Status:            "活躍",
+			Status:            models.ListingStatusActive,
 			OwnerID:           users[4].ID, // Alice Johnson
 			ViewCount:         334,
 			BrandStory:        "讓學習變得有方法、有成就感。",
@@ -892,6 +899,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 	}
 	log.Printf("============= start to create listings =============")
 	for i := range listings {
+		listings[i].Description, listings[i].DescriptionText = sanitize.Description(listings[i].Description)
 		log.Printf("listings[i]: %+v\n", listings[i])
 		if err := db.Create(&listings[i]).Error; err != nil {
 			log.Printf("Failed QQ to create listing %s: %v", listings[i].Title, err)
@@ -1223,7 +1231,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			BuyerID:       users[3].ID,    // Bob Wilson
 			SellerID:      users[1].ID,    // John Doe
 			Amount:        280000,         // $2,800.00
-			Status:        "completed",
+			Status:        models.TransactionStatusCompleted,
 			PaymentMethod: "PayPal",
 			CompletedAt:   &[]time.Time{time.Now().Add(-24 * time.Hour)}[0], // 1 day ago
 		},
@@ -1232,7 +1240,7 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 			BuyerID:       users[4].ID,    // Alice Johnson
 			SellerID:      users[3].ID,    // Bob Wilson
 			Amount:        320000,         // $3,200.00
-			Status:        "pending",
+			Status:        models.TransactionStatusPending,
 			PaymentMethod: "Credit Card",
 		},
 	}
@@ -1247,6 +1255,12 @@ func SeedData(db *gorm.DB, cfg *config.Config) error {
 
 	log.Printf("Created %d transactions successfully", len(transactions))
 
+	if opts.Users > 0 || opts.Listings > 0 {
+		if err := GenerateSeedData(db, opts, users, listings); err != nil {
+			return err
+		}
+	}
+
 	log.Println("Database seeding completed successfully!")
 	return nil
 }
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"fmt"
+
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// enumConstraintsVersion is the migration that constrains the enum columns
+const enumConstraintsVersion = 41
+
+// EnumColumn is a status-like column and the values its Go enum allows
+type EnumColumn struct {
+	Table  string
+	Column string
+	Values []string
+}
+
+// EnumColumns are the columns backed by models.Role, models.ListingStatus,
+// models.TransactionStatus and models.InquiryType
+var EnumColumns = []EnumColumn{
+	{"users", "role", enumValues(models.Roles)},
+	{"listings", "status", enumValues(models.ListingStatuses)},
+	{"transactions", "status", enumValues(models.TransactionStatuses)},
+	{"leads", "inquiry_type", enumValues(models.InquiryTypes)},
+}
+
+func enumValues[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// EnumViolation is a value found in an enum column that its Go enum doesn't
+// allow. Value is nil for NULL.
+type EnumViolation struct {
+	Table   string
+	Column  string
+	Value   *string
+	Count   int64
+	FirstID uint
+}
+
+func (v EnumViolation) String() string {
+	value := "NULL"
+	if v.Value != nil {
+		value = fmt.Sprintf("%q", *v.Value)
+	}
+	return fmt.Sprintf("%s.%s = %s: %d rows (first id %d)", v.Table, v.Column, value, v.Count, v.FirstID)
+}
+
+// NonconformingEnumValues reports the values in EnumColumns outside their
+// enum, so they can be fixed before the columns are constrained. Values are
+// compared and grouped byte for byte; the column collations would let "Admin"
+// match "admin". Tables that don't exist yet are skipped.
+func NonconformingEnumValues(db *gorm.DB) ([]EnumViolation, error) {
+	var violations []EnumViolation
+	for _, col := range EnumColumns {
+		if !db.Migrator().HasTable(col.Table) {
+			continue
+		}
+		var rows []struct {
+			Value   *string
+			Count   int64
+			FirstID uint
+		}
+		if err := db.Table(col.Table).
+			Select("BINARY "+col.Column+" AS value, COUNT(*) AS count, MIN(id) AS first_id").
+			Where(col.Column+" IS NULL OR BINARY "+col.Column+" NOT IN ?", col.Values).
+			Group("BINARY " + col.Column).
+			Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("check %s.%s: %w", col.Table, col.Column, err)
+		}
+		for _, r := range rows {
+			violations = append(violations, EnumViolation{
+				Table:   col.Table,
+				Column:  col.Column,
+				Value:   r.Value,
+				Count:   r.Count,
+				FirstID: r.FirstID,
+			})
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnumConstraintsMigration(t *testing.T) {
+	// The nonconforming-value report runs while this migration is pending, so
+	// the version has to be the one that adds the constraints
+	matches, err := filepath.Glob(fmt.Sprintf("../../migrations/%06d_*.up.sql", enumConstraintsVersion))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("migration %d: %v %v", enumConstraintsVersion, matches, err)
+	}
+	sql, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every value the Go enums allow must be allowed by the constraint
+	for _, col := range EnumColumns {
+		if !strings.Contains(string(sql), "ALTER TABLE "+col.Table) {
+			continue
+		}
+		for _, v := range col.Values {
+			if !strings.Contains(string(sql), "'"+v+"'") {
+				t.Errorf("%s.%s constraint is missing %q", col.Table, col.Column, v)
+			}
+		}
+	}
+}
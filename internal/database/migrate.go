@@ -63,6 +63,17 @@ func RunMigrations(db *gorm.DB) error {
 		return fmt.Errorf("failed to create migrate instance: %w", err)
 	}
 
+	// List what would stop the enum constraints from applying while they are pending
+	if version, _, err := m.Version(); err == nil && version < enumConstraintsVersion {
+		violations, err := NonconformingEnumValues(db)
+		if err != nil {
+			log.Printf("Warning: failed to check enum columns: %v", err)
+		}
+		for _, v := range violations {
+			log.Printf("Warning: nonconforming value %s", v)
+		}
+	}
+
 	// Run migrations
 	log.Println("Running database migrations...")
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/sanitize"
+
+	"gorm.io/gorm"
+)
+
+// SeedOptions adds generated users and listings on top of the curated fixture
+// set. The same Seed always produces the same data, so load tests and UI
+// screenshots can be reproduced.
+type SeedOptions struct {
+	Users    int
+	Listings int
+	Seed     int64
+}
+
+// generateBatchSize is how many generated rows are inserted per statement
+const generateBatchSize = 200
+
+// generatedPassword is the password of every generated user
+const generatedPassword = "password123"
+
+// Pools the generated data is drawn from. Categories, industries, conditions,
+// decorations and equipment come from the curated listings instead.
+var (
+	seedFirstNames = []string{"Wei", "Yu", "Chen", "Mei", "Hao", "Ting", "Jun", "Ling", "Kai", "Hsin", "Po", "Yi"}
+	seedLastNames  = []string{"Chen", "Lin", "Huang", "Chang", "Lee", "Wang", "Wu", "Liu", "Tsai", "Yang"}
+	seedDistricts  = []string{
+		"台北市大安區", "台北市信義區", "台北市中山區", "新北市板橋區", "新北市三重區", "桃園市中壢區",
+		"新竹市東區", "台中市西屯區", "台中市北區", "台南市東區", "高雄市苓雅區", "高雄市左營區",
+	}
+	seedRoads       = []string{"中山路", "民生路", "復興路", "中正路", "建國路", "文化路", "忠孝路", "和平路"}
+	seedAdjectives  = []string{"老字號", "人氣", "轉角", "巷弄", "社區型", "文青", "質感", "平價", "排隊名店", "溫馨"}
+	seedShopsByType = map[string][]string{
+		"餐飲業":  {"咖啡館", "早午餐店", "小吃店", "手搖飲店", "便當店"},
+		"教育業":  {"補習班", "才藝教室", "安親班"},
+		"美容美髮": {"髮廊", "美甲工作室", "美容沙龍"},
+		"零售業":  {"選物店", "雜貨店", "文具店"},
+		"運動健身": {"健身房", "瑜伽教室", "拳擊館"},
+		"寵物服務": {"寵物美容店", "寵物旅館"},
+	}
+	seedSentences = []string{
+		"店面位於人潮穩定的商圈，平日與假日客流皆穩定。",
+		"設備齊全可直接接手營運，員工願意留任協助交接。",
+		"累積大量熟客與網路好評，社群粉絲互動活躍。",
+		"因個人生涯規劃轉讓，誠意出售，價格可議。",
+		"租約穩定，房東配合度高，可協助續約。",
+		"近年營收持續成長，財務報表可於簽署保密協議後提供。",
+		"周邊有學校與辦公大樓，外帶與外送需求高。",
+		"空間規劃完善，可依需求調整擴充營業項目。",
+	}
+)
+
+// seedTemplate is the reusable part of a curated listing
+type seedTemplate struct {
+	category, industry, condition, decoration, equipment string
+}
+
+// GenerateSeedData inserts opts.Users users and opts.Listings listings with
+// realistic pseudo-random values. Listings reuse the category, industry and
+// fittings of the curated listings and belong to the generated users, or to
+// the curated non-admin users when none are generated.
+func GenerateSeedData(db *gorm.DB, opts SeedOptions, curatedUsers []models.User, curatedListings []models.Listing) error {
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	// One hash for everyone; bcrypt is too slow to run per generated user
+	passwordHash := hashPassword(generatedPassword)
+	users := generateUsers(rng, opts.Users, passwordHash)
+	if len(users) > 0 {
+		if err := db.CreateInBatches(users, generateBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to create generated users: %w", err)
+		}
+		log.Printf("Created %d generated users (password %q)", len(users), generatedPassword)
+	}
+
+	owners := users
+	if len(owners) == 0 {
+		for _, u := range curatedUsers {
+			if !u.Role.IsAdmin() {
+				owners = append(owners, u)
+			}
+		}
+	}
+	if opts.Listings == 0 {
+		return nil
+	}
+	if len(owners) == 0 || len(curatedListings) == 0 {
+		return fmt.Errorf("generated listings need owners and curated listings to draw from")
+	}
+
+	templates := make([]seedTemplate, len(curatedListings))
+	for i, l := range curatedListings {
+		templates[i] = seedTemplate{l.Category, l.Industry, l.Condition, l.Decoration, l.Equipment}
+	}
+
+	listings := generateListings(rng, opts.Listings, owners, templates)
+	// Created one batch at a time so the listing count hooks stay cheap per statement
+	if err := db.CreateInBatches(listings, generateBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to create generated listings: %w", err)
+	}
+	log.Printf("Created %d generated listings (seed %d)", len(listings), opts.Seed)
+	return nil
+}
+
+// generateUsers builds n users with unique emails and usernames
+func generateUsers(rng *rand.Rand, n int, passwordHash string) []models.User {
+	users := make([]models.User, 0, n)
+	for i := 1; i <= n; i++ {
+		users = append(users, models.User{
+			Email:        fmt.Sprintf("seed.user%05d@example.com", i),
+			Username:     fmt.Sprintf("seeduser%05d", i),
+			PasswordHash: passwordHash,
+			FirstName:    pick(rng, seedFirstNames),
+			LastName:     pick(rng, seedLastNames),
+			Role:         models.RoleUser,
+			IsActive:     true,
+		})
+	}
+	return users
+}
+
+// generateListings builds n listings. Dates are offsets from a fixed day rather
+// than the current time, so two runs with the same seed match exactly.
+func generateListings(rng *rand.Rand, n int, owners []models.User, templates []seedTemplate) []models.Listing {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	listings := make([]models.Listing, 0, n)
+	for i := 0; i < n; i++ {
+		t := templates[rng.Intn(len(templates))]
+		district := pick(rng, seedDistricts)
+		_, area, _ := strings.Cut(district, "市")
+		shop := t.industry + "店"
+		if shops, ok := seedShopsByType[t.industry]; ok {
+			shop = pick(rng, shops)
+		}
+
+		price := int64(30+rng.Intn(470)) * 10000 // 30萬 - 500萬
+		rent := int64(15+rng.Intn(106)) * 1000   // 1.5萬 - 12萬
+		status := models.ListingStatusActive
+		if rng.Intn(10) == 0 {
+			status = models.ListingStatusInactive
+		}
+
+		description := ""
+		for _, j := range rng.Perm(len(seedSentences))[:2+rng.Intn(3)] {
+			description += seedSentences[j]
+		}
+		descHTML, descText := sanitize.Description(description)
+
+		listings = append(listings, models.Listing{
+			Title:             fmt.Sprintf("%s%s（%s）", pick(rng, seedAdjectives), shop, area),
+			Description:       descHTML,
+			DescriptionText:   descText,
+			Price:             price,
+			Category:          t.category,
+			Condition:         t.condition,
+			Location:          fmt.Sprintf("%s%s%d號", district, pick(rng, seedRoads), 1+rng.Intn(300)),
+			Status:            status,
+			Visibility:        models.ListingVisibilityPublic,
+			OwnerID:           owners[rng.Intn(len(owners))].ID,
+			ViewCount:         rng.Intn(500),
+			Rent:              rent,
+			Floor:             1 + rng.Intn(5),
+			Equipment:         t.equipment,
+			Decoration:        t.decoration,
+			AnnualRevenue:     int64(float64(price) * (0.3 + rng.Float64()*1.2)),
+			GrossProfitRate:   math.Round((0.2+rng.Float64()*0.4)*100) / 100,
+			FastestMovingDate: base.AddDate(0, 0, rng.Intn(365)),
+			PhoneNumber:       fmt.Sprintf("09%08d", rng.Intn(100000000)),
+			SquareMeters:      math.Round((20+rng.Float64()*380)*10) / 10,
+			Industry:          t.industry,
+			Deposit:           rent * int64(2+rng.Intn(2)),
+		})
+	}
+	return listings
+}
+
+// pick returns a random element of values
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// seedRun generates data with opts into a fresh database and returns what was stored
+func seedRun(t *testing.T, name string, opts SeedOptions) ([]models.User, []models.Listing) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Listing{}, &models.ListingCount{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	curatedOwner := models.User{Email: "owner@example.com", Username: "owner", Role: "user"}
+	db.Create(&curatedOwner)
+	curated := []models.Listing{
+		{Title: "Cafe", Price: 1, OwnerID: curatedOwner.ID, Category: "餐廳", Industry: "餐飲業", Condition: "良好", Equipment: "咖啡機"},
+		{Title: "Gym", Price: 1, OwnerID: curatedOwner.ID, Category: "健身", Industry: "運動健身", Decoration: "工業風"},
+	}
+	if err := GenerateSeedData(db, opts, []models.User{curatedOwner}, curated); err != nil {
+		t.Fatal(err)
+	}
+
+	var users []models.User
+	var listings []models.Listing
+	db.Where("id <> ?", curatedOwner.ID).Order("id").Find(&users)
+	db.Order("id").Find(&listings)
+	// Timestamps and password salts come from the clock and crypto/rand
+	for i := range users {
+		users[i].CreatedAt, users[i].UpdatedAt, users[i].PasswordHash = time.Time{}, time.Time{}, ""
+	}
+	for i := range listings {
+		listings[i].CreatedAt, listings[i].UpdatedAt = time.Time{}, time.Time{}
+	}
+	return users, listings
+}
+
+func TestGenerateSeedDataDeterministic(t *testing.T) {
+	opts := SeedOptions{Users: 25, Listings: 120, Seed: 42}
+	users, listings := seedRun(t, t.Name()+"1", opts)
+	againUsers, againListings := seedRun(t, t.Name()+"2", opts)
+
+	if len(users) != opts.Users || len(listings) != opts.Listings {
+		t.Fatalf("%d users and %d listings, want %d and %d", len(users), len(listings), opts.Users, opts.Listings)
+	}
+	if !reflect.DeepEqual(users, againUsers) {
+		t.Error("same seed generated different users")
+	}
+	if !reflect.DeepEqual(listings, againListings) {
+		t.Error("same seed generated different listings")
+	}
+
+	_, other := seedRun(t, t.Name()+"3", SeedOptions{Users: 25, Listings: 120, Seed: 7})
+	if reflect.DeepEqual(listings, other) {
+		t.Error("different seeds generated the same listings")
+	}
+
+	// Generated listings draw on the curated ones and belong to generated users
+	generated := map[uint]bool{}
+	for _, u := range users {
+		generated[u.ID] = true
+	}
+	for _, l := range listings {
+		if l.Industry != "餐飲業" && l.Industry != "運動健身" {
+			t.Fatalf("listing %q has industry %q from outside the curated set", l.Title, l.Industry)
+		}
+		if !generated[l.OwnerID] {
+			t.Fatalf("listing %q belongs to user %d, not a generated user", l.Title, l.OwnerID)
+		}
+		if l.GrossProfitRate <= 0 || l.GrossProfitRate >= 1 {
+			t.Fatalf("listing %q gross profit rate %v is not a 0-1 rate", l.Title, l.GrossProfitRate)
+		}
+	}
+}
+
+func TestGenerateSeedDataCuratedOwners(t *testing.T) {
+	// With no generated users, listings go to the curated ones
+	users, listings := seedRun(t, t.Name(), SeedOptions{Listings: 10, Seed: 1})
+	if len(users) != 0 || len(listings) != 10 {
+		t.Fatalf("%d users and %d listings", len(users), len(listings))
+	}
+	for _, l := range listings {
+		if l.OwnerID != 1 {
+			t.Fatalf("listing %q owned by %d, want the curated owner", l.Title, l.OwnerID)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package format renders amounts and dates for Taiwanese (zh-TW) readers. It is
+// shared by the HTML templates and the notification emails so both read alike.
+package format
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Taipei is the time zone dates are shown in; Taiwan has no daylight saving time
+var Taipei = time.FixedZone("Asia/Taipei", 8*60*60)
+
+// Money formats an amount of New Taiwan dollars, e.g. "NT$1,234,567"
+func Money(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "NT$" + b.String()
+}
+
+// Percent formats a 0-1 rate as a percentage with at most one decimal, e.g. "35%" or "12.5%"
+func Percent(rate float64) string {
+	return strconv.FormatFloat(math.Round(rate*1000)/10, 'f', -1, 64) + "%"
+}
+
+// Date formats a date as "2026年10月16日"; the zero time gives ""
+func Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	t = t.In(Taipei)
+	return fmt.Sprintf("%d年%d月%d日", t.Year(), int(t.Month()), t.Day())
+}
+
+// DateTime formats a time as "2026年10月16日 14:04"; the zero time gives ""
+func DateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return Date(t) + " " + t.In(Taipei).Format("15:04")
+}
+
+// Relative describes how long before now t was, e.g. "3天前". Anything older
+// than 30 days is shown as a date instead.
+func Relative(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "剛剛"
+	case d < time.Hour:
+		return fmt.Sprintf("%d分鐘前", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d小時前", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%d天前", int(d/(24*time.Hour)))
+	default:
+		return Date(t)
+	}
+}
+
+// FuncMap exposes the formatters to templates as money, percent, date, datetime and ago
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"money":    Money,
+		"percent":  Percent,
+		"date":     Date,
+		"datetime": DateTime,
+		"ago": func(t time.Time) string {
+			return Relative(t, time.Now())
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package format
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+	"time"
+)
+
+func TestMoney(t *testing.T) {
+	tests := map[int64]string{
+		0:           "NT$0",
+		999:         "NT$999",
+		1000:        "NT$1,000",
+		1234567:     "NT$1,234,567",
+		100000000:   "NT$100,000,000",
+		-2500:       "-NT$2,500",
+		-1234567890: "-NT$1,234,567,890",
+	}
+	for amount, want := range tests {
+		if got := Money(amount); got != want {
+			t.Errorf("Money(%d) = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := map[float64]string{
+		0:       "0%",
+		0.35:    "35%",
+		0.125:   "12.5%",
+		0.1226:  "12.3%",
+		0.99996: "100%",
+		1:       "100%",
+	}
+	for rate, want := range tests {
+		if got := Percent(rate); got != want {
+			t.Errorf("Percent(%v) = %q, want %q", rate, got, want)
+		}
+	}
+}
+
+func TestDates(t *testing.T) {
+	// 16:30 UTC is already the next day in Taipei
+	late := time.Date(2026, 10, 15, 16, 30, 0, 0, time.UTC)
+	if got := Date(late); got != "2026年10月16日" {
+		t.Errorf("Date = %q", got)
+	}
+	if got := DateTime(late); got != "2026年10月16日 00:30" {
+		t.Errorf("DateTime = %q", got)
+	}
+	if Date(time.Time{}) != "" || DateTime(time.Time{}) != "" {
+		t.Error("zero time isn't blank")
+	}
+}
+
+func TestRelative(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, Taipei)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{ago: 0, want: "剛剛"},
+		{ago: 59 * time.Second, want: "剛剛"},
+		{ago: 5 * time.Minute, want: "5分鐘前"},
+		{ago: 59 * time.Minute, want: "59分鐘前"},
+		{ago: 3 * time.Hour, want: "3小時前"},
+		{ago: 3*24*time.Hour + time.Hour, want: "3天前"},
+		{ago: 29 * 24 * time.Hour, want: "29天前"},
+		{ago: 30 * 24 * time.Hour, want: "2026年9月16日"},
+	}
+	for _, tt := range tests {
+		if got := Relative(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("Relative(%v ago) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+	if got := Relative(time.Time{}, now); got != "" {
+		t.Errorf("Relative(zero) = %q", got)
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	tmpl := template.Must(template.New("t").Funcs(FuncMap()).Parse(
+		`{{ money .Price }} {{ percent .Rate }} {{ date .At }} {{ datetime .At }} {{ with date .Zero }}{{ . }}{{ else }}---{{ end }}`))
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, map[string]interface{}{
+		"Price": int64(3500000),
+		"Rate":  0.35,
+		"At":    time.Date(2026, 1, 2, 3, 4, 0, 0, Taipei),
+		"Zero":  time.Time{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "NT$3,500,000 35% 2026年1月2日 2026年1月2日 03:04 ---"; out.String() != want {
+		t.Errorf("rendered %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,269 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"trade_company/internal/metrics"
+
+	gql "github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Persisted query modes selectable with GRAPHQL_APQ_MODE
+const (
+	// APQModeAuto is Automatic Persisted Queries: any query a client sends
+	// with its hash is cached and can then be run by hash alone
+	APQModeAuto = "auto"
+	// APQModeAllowlist only runs queries registered through the manifest or
+	// the admin API, whether sent by hash or in full
+	APQModeAllowlist = "allowlist"
+)
+
+// Redis keys: APQ cache entries expire, registered queries don't
+const (
+	apqKeyPrefix        = "graphql:apq:"
+	registeredKeyPrefix = "graphql:pq:"
+)
+
+// localCacheSize bounds the in-memory APQ cache used without Redis
+const localCacheSize = 1000
+
+// Error codes clients see. PERSISTED_QUERY_NOT_FOUND is the one Apollo-style
+// clients react to by resending the full query.
+const (
+	errPersistedQueryNotFound       = "PersistedQueryNotFound"
+	errPersistedQueryNotFoundCode   = "PERSISTED_QUERY_NOT_FOUND"
+	errPersistedQueryNotAllowedCode = "PERSISTED_QUERY_NOT_ALLOWED"
+)
+
+// ErrManifestQuery is returned when unregistering a query that comes from the
+// manifest file, which only a redeploy can change
+var ErrManifestQuery = errors.New("query is part of the persisted query manifest")
+
+// PersistedQueries is the gqlgen extension resolving persisted query hashes.
+// It replaces gqlgen's own APQ extension so the cache can live in Redis,
+// shared by every instance, and so production can refuse queries nobody
+// registered.
+type PersistedQueries struct {
+	client    *redis.Client
+	allowlist bool
+	ttl       time.Duration
+
+	manifest map[string]string // From GRAPHQL_PERSISTED_QUERIES_FILE, read-only
+
+	// Without Redis, registered queries and the APQ cache are per instance
+	mu         sync.RWMutex
+	registered map[string]string
+	local      *lru.LRU[string]
+}
+
+var _ interface {
+	gql.OperationParameterMutator
+	gql.HandlerExtension
+} = (*PersistedQueries)(nil)
+
+// NewPersistedQueries builds the extension for mode. client may be nil.
+// manifestFile, when set, is a JSON object mapping sha256 hashes to query
+// documents (the shape persisted query build tools emit); every hash is
+// checked against its document.
+func NewPersistedQueries(client *redis.Client, mode string, ttl time.Duration, manifestFile string) (*PersistedQueries, error) {
+	if mode != APQModeAuto && mode != APQModeAllowlist {
+		return nil, fmt.Errorf("unknown persisted query mode %q", mode)
+	}
+	p := &PersistedQueries{
+		client:     client,
+		allowlist:  mode == APQModeAllowlist,
+		ttl:        ttl,
+		manifest:   map[string]string{},
+		registered: map[string]string{},
+		local:      lru.New[string](localCacheSize),
+	}
+	if manifestFile == "" {
+		return p, nil
+	}
+
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persisted query manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &p.manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse persisted query manifest: %w", err)
+	}
+	for hash, query := range p.manifest {
+		if QueryHash(query) != hash {
+			return nil, fmt.Errorf("persisted query manifest: hash %s does not match its query", hash)
+		}
+	}
+	return p, nil
+}
+
+// QueryHash is the APQ hash of a query document: hex sha256 of its exact text
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Allowlist reports whether only registered queries run
+func (p *PersistedQueries) Allowlist() bool {
+	return p.allowlist
+}
+
+// ManifestSize is the number of queries loaded from the manifest file
+func (p *PersistedQueries) ManifestSize() int {
+	return len(p.manifest)
+}
+
+func (p *PersistedQueries) ExtensionName() string {
+	return "PersistedQueries"
+}
+
+func (p *PersistedQueries) Validate(gql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationParameters fills in the query for a request that sent only a
+// hash, caches queries sent with their hash, and in allowlist mode rejects
+// anything unregistered
+func (p *PersistedQueries) MutateOperationParameters(ctx context.Context, rawParams *gql.RawParams) *gqlerror.Error {
+	if rawParams.Extensions["persistedQuery"] == nil {
+		if p.allowlist && !p.isRegistered(ctx, QueryHash(rawParams.Query)) {
+			return p.reject()
+		}
+		return nil
+	}
+
+	var extension struct {
+		Sha256  string `mapstructure:"sha256Hash"`
+		Version int64  `mapstructure:"version"`
+	}
+	if err := mapstructure.Decode(rawParams.Extensions["persistedQuery"], &extension); err != nil {
+		return gqlerror.Errorf("invalid APQ extension data")
+	}
+	if extension.Version != 1 {
+		return gqlerror.Errorf("unsupported APQ version")
+	}
+
+	if rawParams.Query != "" {
+		if QueryHash(rawParams.Query) != extension.Sha256 {
+			return gqlerror.Errorf("provided APQ hash does not match query")
+		}
+		if p.allowlist {
+			if !p.isRegistered(ctx, extension.Sha256) {
+				return p.reject()
+			}
+			return nil
+		}
+		p.store(ctx, extension.Sha256, rawParams.Query)
+		metrics.IncPersistedQuery(metrics.PersistedQueryStored)
+		return nil
+	}
+
+	query, ok := p.lookup(ctx, extension.Sha256)
+	if !ok {
+		metrics.IncPersistedQuery(metrics.PersistedQueryMiss)
+		err := gqlerror.Errorf(errPersistedQueryNotFound)
+		errcode.Set(err, errPersistedQueryNotFoundCode)
+		return err
+	}
+	metrics.IncPersistedQuery(metrics.PersistedQueryHit)
+	rawParams.Query = query
+	return nil
+}
+
+// Register adds query to the allowlist (and so to the queries runnable by
+// hash in either mode) and returns its hash. With Redis it is shared by every
+// instance immediately.
+func (p *PersistedQueries) Register(ctx context.Context, query string) (string, error) {
+	hash := QueryHash(query)
+	if p.client != nil {
+		if err := p.client.Set(ctx, registeredKeyPrefix+hash, query, 0).Err(); err != nil {
+			return "", err
+		}
+	} else {
+		p.mu.Lock()
+		p.registered[hash] = query
+		p.mu.Unlock()
+	}
+	metrics.IncPersistedQuery(metrics.PersistedQueryRegistered)
+	return hash, nil
+}
+
+// Unregister removes a query registered with Register. It reports whether
+// there was one; manifest queries give ErrManifestQuery.
+func (p *PersistedQueries) Unregister(ctx context.Context, hash string) (bool, error) {
+	if _, ok := p.manifest[hash]; ok {
+		return false, ErrManifestQuery
+	}
+	if p.client != nil {
+		n, err := p.client.Del(ctx, registeredKeyPrefix+hash).Result()
+		return n > 0, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.registered[hash]
+	delete(p.registered, hash)
+	return ok, nil
+}
+
+func (p *PersistedQueries) reject() *gqlerror.Error {
+	metrics.IncPersistedQuery(metrics.PersistedQueryRejected)
+	err := gqlerror.Errorf("query is not on the persisted query allowlist")
+	errcode.Set(err, errPersistedQueryNotAllowedCode)
+	return err
+}
+
+func (p *PersistedQueries) isRegistered(ctx context.Context, hash string) bool {
+	_, ok := p.registeredQuery(ctx, hash)
+	return ok
+}
+
+func (p *PersistedQueries) registeredQuery(ctx context.Context, hash string) (string, bool) {
+	if query, ok := p.manifest[hash]; ok {
+		return query, true
+	}
+	if p.client != nil {
+		// A Redis error counts as unknown: in auto mode the client resends
+		// the query, in allowlist mode only manifest queries run meanwhile
+		query, err := p.client.Get(ctx, registeredKeyPrefix+hash).Result()
+		return query, err == nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	query, ok := p.registered[hash]
+	return query, ok
+}
+
+// lookup finds the query for a hash among the registered queries and, in auto
+// mode, the APQ cache
+func (p *PersistedQueries) lookup(ctx context.Context, hash string) (string, bool) {
+	if query, ok := p.registeredQuery(ctx, hash); ok || p.allowlist {
+		return query, ok
+	}
+	if p.client != nil {
+		query, err := p.client.Get(ctx, apqKeyPrefix+hash).Result()
+		return query, err == nil
+	}
+	return p.local.Get(ctx, hash)
+}
+
+// store caches an APQ query. It is best effort: if Redis is down the client
+// just has to send the full query again next time.
+func (p *PersistedQueries) store(ctx context.Context, hash, query string) {
+	if p.client != nil {
+		p.client.Set(ctx, apqKeyPrefix+hash, query, p.ttl)
+		return
+	}
+	p.local.Add(ctx, hash, query)
+}
@@ -0,0 +1,222 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/testserver"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testQuery = "query { name }"
+
+// gqlResponse is what the test server answers
+type gqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// code is the error code of the first error, or "" if the query ran
+func (r gqlResponse) code() string {
+	if len(r.Errors) == 0 {
+		return ""
+	}
+	code, _ := r.Errors[0].Extensions["code"].(string)
+	if code == "" {
+		return r.Errors[0].Message
+	}
+	return code
+}
+
+// persistedTest runs queries through a server using p
+type persistedTest struct {
+	t   *testing.T
+	p   *PersistedQueries
+	srv http.Handler
+}
+
+func newPersistedTest(t *testing.T, withRedis bool, mode, manifest string) *persistedTest {
+	t.Helper()
+	var client *redis.Client
+	if withRedis {
+		mr := miniredis.RunT(t)
+		client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	}
+	p, err := NewPersistedQueries(client, mode, time.Hour, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := testserver.New()
+	srv.AddTransport(transport.POST{})
+	srv.Use(p)
+	return &persistedTest{t: t, p: p, srv: srv}
+}
+
+// send posts a request with the query, its hash, or both
+func (pt *persistedTest) send(query, hash string) gqlResponse {
+	pt.t.Helper()
+	body := map[string]interface{}{}
+	if query != "" {
+		body["query"] = query
+	}
+	if hash != "" {
+		body["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash},
+		}
+	}
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	pt.srv.ServeHTTP(w, req)
+
+	var resp gqlResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		pt.t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return resp
+}
+
+// expect checks the request ran, or failed with code
+func (pt *persistedTest) expect(step, query, hash, code string) {
+	pt.t.Helper()
+	resp := pt.send(query, hash)
+	if got := resp.code(); got != code {
+		pt.t.Errorf("%s: code %q, want %q", step, got, code)
+	}
+	if code == "" && resp.Data["name"] != "test" {
+		pt.t.Errorf("%s: data %v, want the query run", step, resp.Data)
+	}
+}
+
+// stores are the two places queries can be kept
+var stores = map[string]bool{"redis": true, "memory": false}
+
+func TestAutomaticPersistedQueries(t *testing.T) {
+	hash := QueryHash(testQuery)
+	for name, withRedis := range stores {
+		t.Run(name, func(t *testing.T) {
+			pt := newPersistedTest(t, withRedis, APQModeAuto, "")
+
+			pt.expect("unknown hash", "", hash, errPersistedQueryNotFoundCode)
+			pt.expect("query with its hash", testQuery, hash, "")
+			pt.expect("hash alone", "", hash, "")
+			pt.expect("plain query", testQuery, "", "")
+			pt.expect("wrong hash", testQuery, QueryHash("query { other }"), "provided APQ hash does not match query")
+		})
+	}
+}
+
+func TestAutomaticPersistedQueriesShared(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	instance := func() *persistedTest {
+		p, _ := NewPersistedQueries(client, APQModeAuto, time.Hour, "")
+		srv := testserver.New()
+		srv.AddTransport(transport.POST{})
+		srv.Use(p)
+		return &persistedTest{t: t, p: p, srv: srv}
+	}
+	hash := QueryHash(testQuery)
+
+	// Cached by one instance, known to the next, until the TTL passes
+	instance().expect("register", testQuery, hash, "")
+	if ttl := mr.TTL(apqKeyPrefix + hash); ttl != time.Hour {
+		t.Errorf("cache entry TTL %v, want 1h", ttl)
+	}
+	other := instance()
+	other.expect("other instance", "", hash, "")
+	mr.FastForward(time.Hour)
+	other.expect("after the TTL", "", hash, errPersistedQueryNotFoundCode)
+}
+
+func TestPersistedQueryAllowlist(t *testing.T) {
+	ctx := context.Background()
+	hash := QueryHash(testQuery)
+	manifestQuery := "query { find(id: 1) }"
+	manifest := filepath.Join(t.TempDir(), "persisted-queries.json")
+	data, _ := json.Marshal(map[string]string{QueryHash(manifestQuery): manifestQuery})
+	os.WriteFile(manifest, data, 0o644)
+
+	for name, withRedis := range stores {
+		t.Run(name, func(t *testing.T) {
+			pt := newPersistedTest(t, withRedis, APQModeAllowlist, manifest)
+
+			// Nothing sent by clients gets cached or run
+			pt.expect("unregistered query", testQuery, "", errPersistedQueryNotAllowedCode)
+			pt.expect("unregistered query with its hash", testQuery, hash, errPersistedQueryNotAllowedCode)
+			pt.expect("unregistered hash", "", hash, errPersistedQueryNotFoundCode)
+
+			// The manifest's queries run either way
+			pt.expect("manifest query", manifestQuery, "", "")
+			pt.expect("manifest hash", "", QueryHash(manifestQuery), "")
+
+			registered, err := pt.p.Register(ctx, testQuery)
+			if err != nil || registered != hash {
+				t.Fatalf("Register = %q, %v; want %q", registered, err, hash)
+			}
+			pt.expect("registered hash", "", hash, "")
+			pt.expect("registered query", testQuery, "", "")
+
+			if found, err := pt.p.Unregister(ctx, hash); !found || err != nil {
+				t.Errorf("Unregister = %v, %v", found, err)
+			}
+			pt.expect("unregistered again", testQuery, "", errPersistedQueryNotAllowedCode)
+			if found, err := pt.p.Unregister(ctx, hash); found || err != nil {
+				t.Errorf("second Unregister = %v, %v; want not found", found, err)
+			}
+			if _, err := pt.p.Unregister(ctx, QueryHash(manifestQuery)); !errors.Is(err, ErrManifestQuery) {
+				t.Errorf("Unregister of a manifest query: %v, want %v", err, ErrManifestQuery)
+			}
+		})
+	}
+}
+
+func TestNewPersistedQueries(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, manifest interface{}) string {
+		path := filepath.Join(dir, name)
+		data, _ := json.Marshal(manifest)
+		os.WriteFile(path, data, 0o644)
+		return path
+	}
+	good := write("good.json", map[string]string{QueryHash(testQuery): testQuery})
+	tampered := write("tampered.json", map[string]string{QueryHash(testQuery): "query { find(id: 2) }"})
+	notAnObject := write("list.json", []string{testQuery})
+
+	tests := []struct {
+		name, mode, file string
+		ok               bool
+	}{
+		{name: "auto without manifest", mode: APQModeAuto, ok: true},
+		{name: "allowlist with manifest", mode: APQModeAllowlist, file: good, ok: true},
+		{name: "unknown mode", mode: "strict"},
+		{name: "missing manifest", mode: APQModeAllowlist, file: filepath.Join(dir, "missing.json")},
+		{name: "hash not matching its query", mode: APQModeAllowlist, file: tampered},
+		{name: "manifest not an object", mode: APQModeAllowlist, file: notAnObject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPersistedQueries(nil, tt.mode, time.Hour, tt.file)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && p.Allowlist() != (tt.mode == APQModeAllowlist) {
+				t.Errorf("allowlist %v for mode %s", p.Allowlist(), tt.mode)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+	"trade_company/internal/names"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AdminHandler serves maintenance endpoints. Routes must sit behind middleware.RequireAdmin.
+type AdminHandler struct {
+	DB *gorm.DB
+}
+
+// isAdmin reports whether the user has the admin role. JWT claims don't carry
+// the role, so it has to come from the database.
+func isAdmin(db *gorm.DB, userID uint) bool {
+	var user models.User
+	if err := db.Select("role").First(&user, userID).Error; err != nil {
+		return false
+	}
+	return user.Role.IsAdmin()
+}
+
+// RecountPopularity rebuilds every listing's view_count from the daily view table
+// and favorite_count from counted favorites, undoing any drift or manual edits.
+func (h *AdminHandler) RecountPopularity(c *gin.Context) {
+	var views, favorites int64
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Listing{}).Where("1 = 1").
+			UpdateColumn("view_count", gorm.Expr(
+				"(SELECT COALESCE(SUM(d.views), 0) FROM listing_view_daily d WHERE d.listing_id = listings.id)"))
+		if res.Error != nil {
+			return res.Error
+		}
+		views = res.RowsAffected
+
+		res = tx.Model(&models.Listing{}).Where("1 = 1").
+			UpdateColumn("favorite_count", gorm.Expr(
+				"(SELECT COUNT(*) FROM favorites f WHERE f.listing_id = listings.id AND f.counted = ?)", true))
+		if res.Error != nil {
+			return res.Error
+		}
+		favorites = res.RowsAffected
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recount popularity counters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "Popularity counters rebuilt",
+		"view_counts_changed":     views,
+		"favorite_counts_changed": favorites,
+	})
+}
+
+// SetUsername lets an admin assign any well-formed username, including reserved
+// ones (e.g. "support" for the support account)
+func (h *AdminHandler) SetUsername(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input struct {
+		Username string `json:"username" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	username := strings.TrimSpace(input.Username)
+	if err := names.ValidUsername(username); err != nil {
+		nameError(c, err)
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	taken, err := usernameTaken(h.DB, username, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update username"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken", "code": "USERNAME_TAKEN"})
+		return
+	}
+
+	if err := h.DB.Model(&user).Update("username", username).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update username"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Username updated", "user_id": user.ID, "username": username})
+}
+
+// EmailStatus shows an admin whether email reaches a user: the undeliverable
+// flag, per-event totals and the most recent delivery events
+func (h *AdminHandler) EmailStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var totals []struct {
+		Event string
+		Count int64
+	}
+	if err := h.DB.Model(&models.EmailEvent{}).
+		Select("event, COUNT(*) AS count").
+		Where("user_id = ?", user.ID).
+		Group("event").
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load email status"})
+		return
+	}
+	counts := gin.H{}
+	for _, t := range totals {
+		counts[t.Event] = t.Count
+	}
+
+	var events []models.EmailEvent
+	if err := h.DB.Where("user_id = ?", user.ID).
+		Order("occurred_at DESC").
+		Limit(20).
+		Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load email status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":       user.ID,
+		"email":         user.Email,
+		"undeliverable": user.EmailUndeliverable,
+		"counts":        counts,
+		"recent_events": events,
+	})
+}
+
+// Orphans reports rows that reference missing listings or users, by table and
+// column, without changing anything
+func (h *AdminHandler) Orphans(c *gin.Context) {
+	result, err := jobs.FixOrphans(h.DB, jobs.OrphanOptions{Mode: jobs.OrphanModeDelete, DryRun: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan for orphaned records"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// FixOrphans deletes orphaned rows, or in reassign mode hands messages, leads
+// and transactions of missing users to reassign_to. The run is audit logged.
+func (h *AdminHandler) FixOrphans(c *gin.Context) {
+	var input struct {
+		Mode       string `json:"mode" binding:"required"`
+		ReassignTo uint   `json:"reassign_to"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	switch input.Mode {
+	case jobs.OrphanModeDelete:
+	case jobs.OrphanModeReassign:
+		if err := h.DB.Select("id").First(&models.User{}, input.ReassignTo).Error; input.ReassignTo == 0 || err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to must be an existing user in reassign mode"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be \"delete\" or \"reassign\""})
+		return
+	}
+
+	opts := jobs.OrphanOptions{Mode: input.Mode, ReassignTo: input.ReassignTo}
+	if userID, ok := c.Get("user_id"); ok {
+		if uid, ok := userID.(uint); ok {
+			opts.ActorID = &uid
+		}
+	}
+	result, err := jobs.FixOrphans(h.DB, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Orphan cleanup stopped partway", "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
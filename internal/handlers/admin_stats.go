@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the ?days window of the admin stats
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+)
+
+// Stats returns per-day activity for the last ?days days (default 30),
+// including today. Finished days come from the daily_stats snapshots and only
+// today, or a day the job hasn't reached yet, is counted live.
+func (h *AdminHandler) Stats(c *gin.Context) {
+	days := defaultStatsDays
+	if raw := c.Query("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxStatsDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		days = n
+	}
+
+	today := jobs.StatsDay(time.Now())
+	from := today.AddDate(0, 0, -(days - 1))
+
+	var snapshots []models.DailyStat
+	if err := h.DB.Where("date >= ? AND date < ?", from, today).Find(&snapshots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stats"})
+		return
+	}
+	byDate := make(map[string]models.DailyStat, len(snapshots))
+	for _, s := range snapshots {
+		byDate[s.Date.Format("2006-01-02")] = s
+	}
+
+	result := make([]models.DailyStat, 0, days)
+	var totals models.DailyStat
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		stat, ok := byDate[day.Format("2006-01-02")]
+		if !ok {
+			var err error
+			if stat, err = jobs.ComputeDailyStats(h.DB, day); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute stats"})
+				return
+			}
+		}
+		result = append(result, stat)
+
+		totals.NewUsers += stat.NewUsers
+		totals.NewListings += stat.NewListings
+		totals.Leads += stat.Leads
+		totals.TransactionsCompleted += stat.TransactionsCompleted
+		totals.TransactionVolume += stat.TransactionVolume
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from.Format("2006-01-02"),
+		"to":   today.Format("2006-01-02"),
+		"days": result,
+		// Active users aren't summed; the same user is active on many days
+		"totals": gin.H{
+			"new_users":              totals.NewUsers,
+			"new_listings":           totals.NewListings,
+			"leads":                  totals.Leads,
+			"transactions_completed": totals.TransactionsCompleted,
+			"transaction_volume":     totals.TransactionVolume,
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/format"
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminStats(t *testing.T) {
+	db := newTestDB(t, &models.Transaction{}, &models.UserSession{}, &models.DailyStat{})
+	h := &AdminHandler{DB: db}
+	owner := createTestUser(t, db, "seller")
+	y, m, d := time.Now().In(format.Taipei).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, format.Taipei)
+	yesterday := today.AddDate(0, 0, -1)
+	listedAt := func(at time.Time) func(*models.Listing) {
+		return func(l *models.Listing) { l.CreatedAt = at }
+	}
+
+	createTestListing(t, db, owner.ID, listedAt(yesterday.Add(9*time.Hour)))
+	if _, err := jobs.SnapshotDailyStats(db, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	// Added after the snapshot: history is served from the snapshot, today live
+	createTestListing(t, db, owner.ID, listedAt(yesterday.Add(10*time.Hour)))
+	createTestListing(t, db, owner.ID, listedAt(today.Add(time.Minute)))
+	createTestListing(t, db, owner.ID, listedAt(today.Add(2*time.Minute)))
+
+	r := gin.New()
+	r.GET("/admin/stats", h.Stats)
+	w := serve(r, http.MethodGet, "/admin/stats?days=3", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	days := body["days"].([]interface{})
+	if len(days) != 3 || body["to"] != today.Format("2006-01-02") || body["from"] != today.AddDate(0, 0, -2).Format("2006-01-02") {
+		t.Fatalf("window %v to %v with %d days", body["from"], body["to"], len(days))
+	}
+	var listings []float64
+	for _, day := range days {
+		listings = append(listings, day.(map[string]interface{})["new_listings"].(float64))
+	}
+	if listings[0] != 0 || listings[1] != 1 || listings[2] != 2 {
+		t.Errorf("new listings per day %v, want [0 1 2]", listings)
+	}
+	if total := body["totals"].(map[string]interface{})["new_listings"]; total != float64(3) {
+		t.Errorf("total new listings %v, want 3", total)
+	}
+
+	for _, query := range []string{"?days=0", "?days=366", "?days=week"} {
+		if w := serve(r, http.MethodGet, "/admin/stats"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, w.Code)
+		}
+	}
+}
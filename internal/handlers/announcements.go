@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxAnnouncementLength caps each translation of a banner message
+const maxAnnouncementLength = 500
+
+// AnnouncementHandler serves site banners. The admin methods must sit behind middleware.RequireAdmin.
+type AnnouncementHandler struct {
+	DB    *gorm.DB
+	Cache *redisclient.CacheService // optional, nil when Redis is not configured
+}
+
+type announcementRequest struct {
+	Messages models.LocalizedText `json:"messages"`
+	Level    string               `json:"level"`
+	Audience string               `json:"audience"`
+	StartsAt *time.Time           `json:"starts_at"`
+	EndsAt   *time.Time           `json:"ends_at"`
+}
+
+// toModel validates the request and copies it onto a, filling in defaults
+func (r *announcementRequest) toModel(a *models.Announcement) error {
+	if len(r.Messages) == 0 {
+		return errors.New("messages must include at least one locale")
+	}
+	for locale, text := range r.Messages {
+		if strings.TrimSpace(locale) == "" || strings.TrimSpace(text) == "" {
+			return errors.New("messages must not contain empty locales or texts")
+		}
+		if utf8.RuneCountInString(text) > maxAnnouncementLength {
+			return fmt.Errorf("each message must be at most %d characters", maxAnnouncementLength)
+		}
+	}
+
+	level := r.Level
+	if level == "" {
+		level = models.AnnouncementLevelInfo
+	}
+	if level != models.AnnouncementLevelInfo && level != models.AnnouncementLevelWarning {
+		return errors.New("level must be info or warning")
+	}
+
+	audience := r.Audience
+	if audience == "" {
+		audience = models.AnnouncementAudienceAll
+	}
+	switch audience {
+	case models.AnnouncementAudienceAll, models.AnnouncementAudienceSellers, models.AnnouncementAudienceBuyers:
+	default:
+		return errors.New("audience must be all, sellers or buyers")
+	}
+
+	startsAt := time.Now()
+	if r.StartsAt != nil {
+		startsAt = *r.StartsAt
+	}
+	if r.EndsAt != nil && !r.EndsAt.After(startsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	a.Messages = r.Messages
+	a.Level = level
+	a.Audience = audience
+	a.StartsAt = startsAt
+	a.EndsAt = r.EndsAt
+	return nil
+}
+
+// currentAnnouncements returns every announcement that has not ended yet, including
+// scheduled ones, so the cached set stays correct as start times pass
+func currentAnnouncements(db *gorm.DB, cache *redisclient.CacheService, now time.Time) ([]models.Announcement, error) {
+	if cache != nil {
+		if cached, err := cache.GetCachedAnnouncements(); err == nil && cached != nil {
+			return cached, nil
+		}
+	}
+
+	announcements := []models.Announcement{}
+	if err := db.Where("ends_at IS NULL OR ends_at > ?", now).
+		Order("starts_at desc").
+		Find(&announcements).Error; err != nil {
+		return nil, err
+	}
+
+	if cache != nil {
+		_ = cache.CacheAnnouncements(announcements)
+	}
+	return announcements, nil
+}
+
+// announcementAudience classifies a user as a seller (seller role or owns a listing)
+// or a buyer. Anonymous visitors have no audience and only see announcements for all.
+func announcementAudience(db *gorm.DB, userID uint) string {
+	var user models.User
+	if err := db.Select("id", "role").First(&user, userID).Error; err != nil {
+		return ""
+	}
+	if user.Role == models.RoleSeller {
+		return models.AnnouncementAudienceSellers
+	}
+	var listings int64
+	db.Model(&models.Listing{}).
+		Where("owner_id = ? AND status NOT IN ?", userID, models.HiddenListingStatuses).
+		Count(&listings)
+	if listings > 0 {
+		return models.AnnouncementAudienceSellers
+	}
+	return models.AnnouncementAudienceBuyers
+}
+
+// liveAnnouncements returns the announcements live now for the audience, with the
+// message resolved to the requested locale
+func liveAnnouncements(db *gorm.DB, cache *redisclient.CacheService, audience, locale string) ([]gin.H, error) {
+	now := time.Now()
+	announcements, err := currentAnnouncements(db, cache, now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]gin.H, 0, len(announcements))
+	for i := range announcements {
+		a := &announcements[i]
+		if !a.LiveAt(now) || !a.ShownTo(audience) {
+			continue
+		}
+		message, messageLocale := a.Messages.In(locale)
+		result = append(result, gin.H{
+			"id":       a.ID,
+			"message":  message,
+			"locale":   messageLocale,
+			"level":    a.Level,
+			"audience": a.Audience,
+			"ends_at":  a.EndsAt,
+		})
+	}
+	return result, nil
+}
+
+// Active returns the announcements currently live for the requesting user
+func (h *AnnouncementHandler) Active(c *gin.Context) {
+	audience := ""
+	if userID, ok := c.Get("user_id"); ok {
+		if uid, ok := userID.(uint); ok {
+			audience = announcementAudience(h.DB, uid)
+		}
+	}
+
+	announcements, err := liveAnnouncements(h.DB, h.Cache, audience, requestLocale(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"announcements": announcements})
+}
+
+// invalidate drops the cached announcements after a change
+func (h *AnnouncementHandler) invalidate() {
+	if h.Cache != nil {
+		_ = h.Cache.InvalidateAnnouncements()
+	}
+}
+
+// AdminList returns every announcement, newest first
+func (h *AnnouncementHandler) AdminList(c *gin.Context) {
+	var announcements []models.Announcement
+	if err := h.DB.Order("created_at desc").Find(&announcements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"announcements": announcements})
+}
+
+// Create adds an announcement
+func (h *AnnouncementHandler) Create(c *gin.Context) {
+	var req announcementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var announcement models.Announcement
+	if err := req.toModel(&announcement); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.DB.Create(&announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+	h.invalidate()
+
+	c.JSON(http.StatusCreated, gin.H{"announcement": announcement})
+}
+
+// Update replaces an announcement
+func (h *AnnouncementHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement ID"})
+		return
+	}
+
+	var req announcementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var announcement models.Announcement
+	if err := h.DB.First(&announcement, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	if err := req.toModel(&announcement); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.DB.Save(&announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update announcement"})
+		return
+	}
+	h.invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"announcement": announcement})
+}
+
+// Delete removes an announcement
+func (h *AnnouncementHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement ID"})
+		return
+	}
+
+	res := h.DB.Delete(&models.Announcement{}, id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete announcement"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	h.invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Announcement deleted successfully"})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// activeAnnouncementMessages returns the sorted messages of the active
+// announcements the caller of target sees
+func activeAnnouncementMessages(t *testing.T, r http.Handler, target string) []string {
+	t.Helper()
+	w := serve(r, http.MethodGet, target, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var got []string
+	for _, a := range decode(t, w)["announcements"].([]interface{}) {
+		got = append(got, a.(map[string]interface{})["message"].(string))
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestActiveAnnouncementsAudience(t *testing.T) {
+	db := newTestDB(t, &models.Announcement{})
+	h := &AnnouncementHandler{DB: db}
+	buyer := createTestUser(t, db, "buyer")
+	owner := createTestUser(t, db, "owner")
+	createTestListing(t, db, owner.ID)
+	seller := createTestUser(t, db, "seller")
+	db.Model(seller).Update("role", models.RoleSeller)
+
+	start := time.Now().Add(-time.Hour)
+	for _, audience := range []string{models.AnnouncementAudienceAll, models.AnnouncementAudienceSellers, models.AnnouncementAudienceBuyers} {
+		db.Create(&models.Announcement{Messages: models.LocalizedText{"en": audience}, Level: models.AnnouncementLevelInfo, Audience: audience, StartsAt: start})
+	}
+
+	tests := []struct {
+		name   string
+		userID uint
+		want   []string
+	}{
+		{name: "anonymous", want: []string{"all"}},
+		{name: "buyer", userID: buyer.ID, want: []string{"all", "buyers"}},
+		{name: "listing owner", userID: owner.ID, want: []string{"all", "sellers"}},
+		{name: "seller role", userID: seller.ID, want: []string{"all", "sellers"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if tt.userID != 0 {
+				r.Use(asUser(tt.userID))
+			}
+			r.GET("/announcements/active", h.Active)
+			if got := activeAnnouncementMessages(t, r, "/announcements/active?locale=en"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("announcements %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActiveAnnouncementsSchedule(t *testing.T) {
+	db := newTestDB(t, &models.Announcement{})
+	h := &AnnouncementHandler{DB: db}
+	now := time.Now()
+	at := func(d time.Duration) *time.Time { v := now.Add(d); return &v }
+
+	windows := []struct {
+		name     string
+		startsAt time.Time
+		endsAt   *time.Time
+	}{
+		{name: "open-ended", startsAt: now.Add(-time.Hour)},
+		{name: "ending later", startsAt: now.Add(-time.Hour), endsAt: at(time.Hour)},
+		{name: "ended", startsAt: now.Add(-2 * time.Hour), endsAt: at(-time.Hour)},
+		{name: "scheduled", startsAt: now.Add(time.Hour), endsAt: at(2 * time.Hour)},
+	}
+	for _, w := range windows {
+		db.Create(&models.Announcement{Messages: models.LocalizedText{"en": w.name}, Level: models.AnnouncementLevelWarning,
+			Audience: models.AnnouncementAudienceAll, StartsAt: w.startsAt, EndsAt: w.endsAt})
+	}
+
+	r := gin.New()
+	r.GET("/announcements/active", h.Active)
+	if got, want := activeAnnouncementMessages(t, r, "/announcements/active?locale=en"), []string{"ending later", "open-ended"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("announcements %v, want %v", got, want)
+	}
+}
+
+func TestAnnouncementCacheInvalidation(t *testing.T) {
+	db := newTestDB(t, &models.Announcement{})
+	mr := miniredis.RunT(t)
+	h := &AnnouncementHandler{DB: db, Cache: redisclient.NewCacheService(redis.NewClient(&redis.Options{Addr: mr.Addr()}))}
+
+	r := gin.New()
+	r.GET("/announcements/active", h.Active)
+	r.POST("/admin/announcements", h.Create)
+
+	if got := activeAnnouncementMessages(t, r, "/announcements/active?locale=en"); len(got) != 0 {
+		t.Fatalf("announcements %v, want none", got)
+	}
+	if !mr.Exists(redisclient.AnnouncementsKey) {
+		t.Fatal("announcements not cached")
+	}
+
+	w := serve(r, http.MethodPost, "/admin/announcements", map[string]interface{}{"messages": map[string]string{"en": "Maintenance Sunday 2am"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", w.Code, w.Body)
+	}
+	if got, want := activeAnnouncementMessages(t, r, "/announcements/active?locale=en"), []string{"Maintenance Sunday 2am"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("announcements after create %v, want %v", got, want)
+	}
+}
@@ -4,40 +4,88 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"trade_company/internal/config"
 	"trade_company/internal/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
+// auctionServiceURL is the auction service base URL
+const auctionServiceURL = "http://127.0.0.1:8081"
+
+// Redis keys for cached auction responses. Cache keys embed a generation number
+// that is bumped whenever an auction changes, which invalidates every user's
+// cached copy without having to find and delete them.
+const (
+	auctionCachePrefix   = "auction_proxy:"
+	auctionListGenKey    = "auction_proxy:gen:list"
+	auctionDetailGenKeyf = "auction_proxy:gen:auction:%s"
+)
+
 // AuctionProxyHandler handles proxy requests to the auction service.
 // This allows the frontend to use HttpOnly cookies while still accessing auction functionality.
 type AuctionProxyHandler struct {
-	Cfg *config.Config // Configuration for auction service URL
-	Log *zap.Logger    // Logger for proxy requests
+	Cfg   *config.Config // Configuration for auction service URL
+	Log   *zap.Logger    // Logger for proxy requests
+	Redis *redis.Client  // Caches auction GET responses; optional, nil disables caching
+
+	serviceURL string // Overrides auctionServiceURL when set
 }
 
 // NewAuctionProxyHandler creates a new auction proxy handler.
-func NewAuctionProxyHandler(cfg *config.Config, log *zap.Logger) *AuctionProxyHandler {
+func NewAuctionProxyHandler(cfg *config.Config, log *zap.Logger, redisClient *redis.Client) *AuctionProxyHandler {
 	return &AuctionProxyHandler{
-		Cfg: cfg,
-		Log: log,
+		Cfg:   cfg,
+		Log:   log,
+		Redis: redisClient,
+	}
+}
+
+// proxyResponse is an auction service response relayed to the client
+type proxyResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// write sends the upstream response to the client
+func (r *proxyResponse) write(c *gin.Context) {
+	for key, values := range r.Header {
+		for _, value := range values {
+			c.Header(key, value)
+		}
 	}
+	c.Data(r.Status, r.Header.Get("Content-Type"), r.Body)
 }
 
 // getAuctionServiceURL returns the auction service base URL.
 func (h *AuctionProxyHandler) getAuctionServiceURL() string {
+	if h.serviceURL != "" {
+		return h.serviceURL
+	}
 	// Default to localhost for development
-	return "http://127.0.0.1:8081"
+	return auctionServiceURL
 }
 
 // forwardRequest forwards a request to the auction service with proper authentication.
 func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
+	if resp, ok := h.roundTrip(c, path); ok {
+		resp.write(c)
+	}
+}
+
+// roundTrip sends the request to the auction service and returns its response.
+// On failure it has already written an error response and returns false.
+func (h *AuctionProxyHandler) roundTrip(c *gin.Context, path string) (*proxyResponse, bool) {
 	// Get user ID from JWT middleware context
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -45,7 +93,7 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 			zap.String("ip", c.ClientIP()),
 			zap.String("path", path))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
-		return
+		return nil, false
 	}
 
 	userIDValue, ok := userID.(uint)
@@ -55,7 +103,7 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 			zap.String("path", path),
 			zap.Any("user_id_value", userID))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, false
 	}
 
 	// Get the JWT token from the request context (set by JWT middleware)
@@ -66,7 +114,7 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 			zap.String("path", path),
 			zap.Uint("user_id", userIDValue))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
-		return
+		return nil, false
 	}
 
 	tokenString, ok := token.(string)
@@ -76,7 +124,7 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 			zap.String("path", path),
 			zap.Uint("user_id", userIDValue))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, false
 	}
 
 	// Build the target URL
@@ -94,7 +142,7 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 				zap.Uint("user_id", userIDValue),
 				logger.Err(err))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read request body"})
-			return
+			return nil, false
 		}
 	}
 
@@ -107,7 +155,7 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 			zap.Uint("user_id", userIDValue),
 			logger.Err(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create request"})
-		return
+		return nil, false
 	}
 
 	// Copy headers from the original request
@@ -134,7 +182,7 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 			zap.Uint("user_id", userIDValue),
 			logger.Err(err))
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to connect to auction service"})
-		return
+		return nil, false
 	}
 	defer resp.Body.Close()
 
@@ -147,24 +195,103 @@ func (h *AuctionProxyHandler) forwardRequest(c *gin.Context, path string) {
 			zap.Uint("user_id", userIDValue),
 			logger.Err(err))
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to read response"})
-		return
-	}
-
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			c.Header(key, value)
-		}
+		return nil, false
 	}
 
-	// Set the response status and body
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
-
 	h.Log.Info("Auction proxy request completed",
 		zap.String("ip", c.ClientIP()),
 		zap.String("path", path),
 		zap.Uint("user_id", userIDValue),
 		zap.Int("status_code", resp.StatusCode))
+
+	return &proxyResponse{Status: resp.StatusCode, Header: resp.Header, Body: respBody}, true
+}
+
+// cacheTTL is how long auction GET responses are cached; zero when caching is off
+func (h *AuctionProxyHandler) cacheTTL() time.Duration {
+	if h.Redis == nil || h.Cfg.AuctionCacheTTLSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(h.Cfg.AuctionCacheTTLSeconds) * time.Second
+}
+
+// cacheKey builds the cache key for a GET of path by the current user. Responses can
+// be user-specific, so the user is part of the key, as are the generations of genKeys.
+func (h *AuctionProxyHandler) cacheKey(ctx context.Context, c *gin.Context, path string, genKeys []string) (string, error) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return "", fmt.Errorf("no user in context")
+	}
+	gens, err := h.Redis.MGet(ctx, genKeys...).Result()
+	if err != nil {
+		return "", err
+	}
+	key := auctionCachePrefix
+	for _, g := range gens {
+		if g == nil {
+			g = "0"
+		}
+		key += fmt.Sprintf("%v:", g)
+	}
+	return fmt.Sprintf("%s%v:%s", key, userID, path), nil
+}
+
+// cachedForward serves an idempotent GET from the cache when possible, else forwards
+// it and caches a successful response. Redis errors fall back to forwarding.
+func (h *AuctionProxyHandler) cachedForward(c *gin.Context, path string, genKeys ...string) {
+	ttl := h.cacheTTL()
+	if ttl == 0 {
+		h.forwardRequest(c, path)
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := h.cacheKey(ctx, c, path, genKeys)
+	if err != nil {
+		h.forwardRequest(c, path)
+		return
+	}
+
+	if data, err := h.Redis.Get(ctx, key).Bytes(); err == nil {
+		var cached proxyResponse
+		if json.Unmarshal(data, &cached) == nil {
+			c.Header("X-Cache", "HIT")
+			cached.write(c)
+			return
+		}
+	}
+
+	resp, ok := h.roundTrip(c, path)
+	if !ok {
+		return
+	}
+	if resp.Status == http.StatusOK {
+		if data, err := json.Marshal(resp); err == nil {
+			_ = h.Redis.Set(ctx, key, data, ttl).Err()
+		}
+	}
+	c.Header("X-Cache", "MISS")
+	resp.write(c)
+}
+
+// forwardAndInvalidate forwards a state-changing request and, when it succeeds,
+// bumps the given generations so cached GETs of the affected auctions go stale
+func (h *AuctionProxyHandler) forwardAndInvalidate(c *gin.Context, path string, genKeys ...string) {
+	resp, ok := h.roundTrip(c, path)
+	if !ok {
+		return
+	}
+	if h.Redis != nil && resp.Status >= 200 && resp.Status < 300 {
+		ctx := c.Request.Context()
+		for _, key := range genKeys {
+			if err := h.Redis.Incr(ctx, key).Err(); err != nil {
+				h.Log.Warn("Failed to invalidate auction cache",
+					zap.String("key", key),
+					logger.Err(err))
+			}
+		}
+	}
+	resp.write(c)
 }
 
 // GetAuctions proxies GET /api/v1/auctions requests to the auction service.
@@ -173,33 +300,34 @@ func (h *AuctionProxyHandler) GetAuctions(c *gin.Context) {
 	if c.Request.URL.RawQuery != "" {
 		path += "?" + c.Request.URL.RawQuery
 	}
-	h.forwardRequest(c, path)
+	h.cachedForward(c, path, auctionListGenKey)
 }
 
 // GetAuction proxies GET /api/v1/auctions/:id requests to the auction service.
 func (h *AuctionProxyHandler) GetAuction(c *gin.Context) {
 	auctionID := c.Param("id")
 	path := fmt.Sprintf("/api/v1/auctions/%s", auctionID)
-	h.forwardRequest(c, path)
+	h.cachedForward(c, path, fmt.Sprintf(auctionDetailGenKeyf, auctionID))
 }
 
 // CreateAuction proxies POST /api/v1/auctions requests to the auction service.
 func (h *AuctionProxyHandler) CreateAuction(c *gin.Context) {
-	h.forwardRequest(c, "/api/v1/auctions")
+	h.forwardAndInvalidate(c, "/api/v1/auctions", auctionListGenKey)
 }
 
 // ActivateAuction proxies POST /api/v1/auctions/:id:activate requests to the auction service.
 func (h *AuctionProxyHandler) ActivateAuction(c *gin.Context) {
 	auctionID := c.Param("id")
 	path := fmt.Sprintf("/api/v1/auctions/%s:activate", auctionID)
-	h.forwardRequest(c, path)
+	h.forwardAndInvalidate(c, path, auctionListGenKey, fmt.Sprintf(auctionDetailGenKeyf, auctionID))
 }
 
 // PlaceBid proxies POST /api/v1/auctions/:id/bids requests to the auction service.
+// A successful bid invalidates cached copies of the auction and the auction list.
 func (h *AuctionProxyHandler) PlaceBid(c *gin.Context) {
 	auctionID := c.Param("id")
 	path := fmt.Sprintf("/api/v1/auctions/%s/bids", auctionID)
-	h.forwardRequest(c, path)
+	h.forwardAndInvalidate(c, path, auctionListGenKey, fmt.Sprintf(auctionDetailGenKeyf, auctionID))
 }
 
 // GetMyBids proxies GET /api/v1/auctions/:id/my-bids requests to the auction service.
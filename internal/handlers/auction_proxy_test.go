@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// newTestAuctionProxy routes the proxy to a fake auction service and returns
+// the router with a count of the requests that reached the service
+func newTestAuctionProxy(t *testing.T, rdb *redis.Client) (*gin.Engine, *int64) {
+	t.Helper()
+	var upstream int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&upstream, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"path":"` + r.URL.RequestURI() + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig(t)
+	cfg.AuctionCacheTTLSeconds = 30
+	h := NewAuctionProxyHandler(cfg, zap.NewNop(), rdb)
+	h.serviceURL = srv.URL
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		// As the JWT middleware does, with the user taken from a test header
+		id, _ := strconv.Atoi(c.GetHeader("X-Test-User"))
+		c.Set("user_id", uint(id))
+		c.Set("jwt_token", "token")
+	})
+	r.GET("/api/v1/auctions", h.GetAuctions)
+	r.GET("/api/v1/auctions/:id", h.GetAuction)
+	r.POST("/api/v1/auctions/:id/bids", h.PlaceBid)
+	return r, &upstream
+}
+
+// getAuctions sends a GET as the given user
+func getAuctions(r http.Handler, target, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("X-Test-User", user)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuctionProxyCachesGets(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r, upstream := newTestAuctionProxy(t, redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	first := getAuctions(r, "/api/v1/auctions/7", "1")
+	second := getAuctions(r, "/api/v1/auctions/7", "1")
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("statuses %d and %d", first.Code, second.Code)
+	}
+	if first.Header().Get("X-Cache") != "MISS" || second.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("X-Cache %q then %q, want MISS then HIT", first.Header().Get("X-Cache"), second.Header().Get("X-Cache"))
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("cached response %q (%s), want %q", second.Body, second.Header().Get("Content-Type"), first.Body)
+	}
+	if *upstream != 1 {
+		t.Errorf("%d upstream requests, want 1", *upstream)
+	}
+
+	// Another user, another query and a bid each miss the cache
+	tests := []struct {
+		name   string
+		target string
+		user   string
+		bid    bool
+	}{
+		{name: "another user", target: "/api/v1/auctions/7", user: "2"},
+		{name: "another query", target: "/api/v1/auctions?status=active", user: "1"},
+		{name: "after a bid", target: "/api/v1/auctions/7", user: "1", bid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.bid {
+				getAuctions(r, "/api/v1/auctions?status=active", "1")
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/auctions/7/bids", nil)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusCreated {
+					t.Fatalf("bid status %d: %s", w.Code, w.Body)
+				}
+				// The bid also invalidates the auction list
+				if w := getAuctions(r, "/api/v1/auctions?status=active", "1"); w.Header().Get("X-Cache") != "MISS" {
+					t.Errorf("list after bid X-Cache %q, want MISS", w.Header().Get("X-Cache"))
+				}
+			}
+			before := *upstream
+			if w := getAuctions(r, tt.target, tt.user); w.Header().Get("X-Cache") != "MISS" {
+				t.Errorf("X-Cache %q, want MISS", w.Header().Get("X-Cache"))
+			}
+			if *upstream != before+1 {
+				t.Errorf("%d upstream requests, want %d", *upstream, before+1)
+			}
+		})
+	}
+}
+
+func TestAuctionProxyWithoutRedis(t *testing.T) {
+	tests := []struct {
+		name  string
+		redis func(t *testing.T) *redis.Client
+	}{
+		{name: "not configured", redis: func(t *testing.T) *redis.Client { return nil }},
+		{name: "unreachable", redis: func(t *testing.T) *redis.Client {
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+			mr.Close()
+			return rdb
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, upstream := newTestAuctionProxy(t, tt.redis(t))
+			for i := 0; i < 2; i++ {
+				if w := getAuctions(r, "/api/v1/auctions/7", "1"); w.Code != http.StatusOK || w.Header().Get("X-Cache") == "HIT" {
+					t.Fatalf("status %d, X-Cache %q: %s", w.Code, w.Header().Get("X-Cache"), w.Body)
+				}
+			}
+			if *upstream != 2 {
+				t.Errorf("%d upstream requests, want 2", *upstream)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/format"
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Auction service webhook headers
+const (
+	auctionSignatureHeader = "X-Auction-Signature"
+	auctionTimestampHeader = "X-Auction-Timestamp"
+)
+
+// auctionWebhookTolerance bounds how old a signed timestamp may be, so a
+// captured request can't be replayed later
+const auctionWebhookTolerance = 5 * time.Minute
+
+// AuctionWebhookHandler records auction results posted by the auction service
+type AuctionWebhookHandler struct {
+	DB     *gorm.DB
+	Emails *auth.EmailService
+	secret []byte // empty disables the webhook
+}
+
+// NewAuctionWebhookHandler creates the handler. An empty secret leaves the
+// webhook disabled rather than accepting unsigned events.
+func NewAuctionWebhookHandler(db *gorm.DB, emails *auth.EmailService, secret string) *AuctionWebhookHandler {
+	return &AuctionWebhookHandler{DB: db, Emails: emails, secret: []byte(secret)}
+}
+
+// verifyAuctionSignature checks the hex HMAC-SHA256 the auction service computes
+// over the timestamp header, a ".", and the raw request body
+func verifyAuctionSignature(secret []byte, signature, timestamp string, body []byte, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > auctionWebhookTolerance || age < -auctionWebhookTolerance {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// AuctionEvents records auction.closed and bid.winner events. The raw event is
+// stored before it is applied, and deliveries are deduplicated on the event id,
+// so redeliveries are safe and failed events can be replayed by an admin.
+func (h *AuctionWebhookHandler) AuctionEvents(c *gin.Context) {
+	if len(h.secret) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Auction webhook is not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if !verifyAuctionSignature(h.secret, c.GetHeader(auctionSignatureHeader), c.GetHeader(auctionTimestampHeader), body, time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	var payload jobs.AuctionEventPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.ID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event payload"})
+		return
+	}
+	if payload.Type != models.AuctionEventClosed && payload.Type != models.AuctionEventBidWinner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported event type %q", payload.Type)})
+		return
+	}
+	if len(payload.ID) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event id is too long"})
+		return
+	}
+
+	event := models.AuctionEvent{EventID: payload.ID, Type: payload.Type, Payload: string(body)}
+	if err := h.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&event).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record auction event"})
+		return
+	}
+	if err := h.DB.Where("event_id = ?", payload.ID).First(&event).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record auction event"})
+		return
+	}
+	if event.ProcessedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+		return
+	}
+
+	h.apply(c, &event)
+}
+
+// ReplayAuctionEvent applies a stored event that failed earlier
+func (h *AuctionWebhookHandler) ReplayAuctionEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var event models.AuctionEvent
+	if err := h.DB.First(&event, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Auction event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load auction event"})
+		return
+	}
+	if event.ProcessedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Auction event was already processed"})
+		return
+	}
+
+	h.apply(c, &event)
+}
+
+// apply applies a stored event, keeping the error on the event when it fails.
+// Events that can never apply get 422 so the auction service stops retrying;
+// anything else gets 500 so it delivers again.
+func (h *AuctionWebhookHandler) apply(c *gin.Context, event *models.AuctionEvent) {
+	outcome, err := jobs.ApplyAuctionEvent(h.DB, event, time.Now())
+	if err != nil {
+		h.DB.Model(event).Update("error", truncateUTF8(err.Error(), 500))
+		if errors.Is(err, jobs.ErrInvalidAuctionEvent) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply auction event"})
+		return
+	}
+
+	if outcome.Transaction != nil && outcome.Created {
+		h.notifySale(&outcome.Listing, outcome.Transaction)
+	}
+
+	resp := gin.H{"status": "processed", "listing_id": outcome.Listing.ID}
+	if outcome.Transaction != nil {
+		resp["transaction_id"] = outcome.Transaction.ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// notifySale messages the winner on the seller's behalf and emails both sides.
+// The sale is already recorded, so failures here are not reported back.
+func (h *AuctionWebhookHandler) notifySale(listing *models.Listing, txn *models.Transaction) {
+	h.DB.Create(&models.Message{
+		SenderID:   txn.SellerID,
+		ReceiverID: txn.BuyerID,
+		ListingID:  &listing.ID,
+		Subject:    fmt.Sprintf("Auction won: %s", listing.Title),
+		Content:    fmt.Sprintf("Your winning bid of %s for \"%s\" has been recorded. I'll be in touch about completing the sale.", format.Money(txn.Amount), listing.Title),
+	})
+
+	var users []models.User
+	if err := h.DB.Where("id IN ?", []uint{txn.SellerID, txn.BuyerID}).Find(&users).Error; err != nil {
+		return
+	}
+	for i := range users {
+		h.Emails.SendAuctionResult(&users[i], listing, txn.Amount, users[i].ID == txn.BuyerID)
+	}
+}
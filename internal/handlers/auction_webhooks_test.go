@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const testAuctionSecret = "auction-secret"
+
+// newTestAuctionWebhook routes the webhook with emails that fail quietly
+func newTestAuctionWebhook(t *testing.T, db *gorm.DB) *gin.Engine {
+	t.Helper()
+	emailCfg := *testConfig(t)
+	emailCfg.AppEnv = "test"
+	h := NewAuctionWebhookHandler(db, auth.NewEmailService(&emailCfg), testAuctionSecret)
+	r := gin.New()
+	r.POST("/webhooks/auction-events", h.AuctionEvents)
+	return r
+}
+
+// auctionSignature signs body as the auction service does
+func auctionSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postAuctionEvent delivers body signed with secret at the given time
+func postAuctionEvent(r http.Handler, secret string, at time.Time, body []byte) *httptest.ResponseRecorder {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/auction-events", bytes.NewReader(body))
+	req.Header.Set(auctionTimestampHeader, ts)
+	req.Header.Set(auctionSignatureHeader, auctionSignature(secret, ts, body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuctionWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"auction.closed","data":{"auction_id":7,"listing_id":1}}`)
+	tests := []struct {
+		name   string
+		send   func(r http.Handler) *httptest.ResponseRecorder
+		status int
+	}{
+		{name: "valid signature", status: http.StatusOK, send: func(r http.Handler) *httptest.ResponseRecorder {
+			return postAuctionEvent(r, testAuctionSecret, time.Now(), body)
+		}},
+		{name: "wrong secret", status: http.StatusUnauthorized, send: func(r http.Handler) *httptest.ResponseRecorder {
+			return postAuctionEvent(r, "other-secret", time.Now(), body)
+		}},
+		{name: "stale timestamp", status: http.StatusUnauthorized, send: func(r http.Handler) *httptest.ResponseRecorder {
+			return postAuctionEvent(r, testAuctionSecret, time.Now().Add(-auctionWebhookTolerance-time.Minute), body)
+		}},
+		{name: "tampered body", status: http.StatusUnauthorized, send: func(r http.Handler) *httptest.ResponseRecorder {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/auction-events", bytes.NewReader(bytes.Replace(body, []byte(`"listing_id":1`), []byte(`"listing_id":2`), 1)))
+			req.Header.Set(auctionTimestampHeader, ts)
+			req.Header.Set(auctionSignatureHeader, auctionSignature(testAuctionSecret, ts, body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w
+		}},
+		{name: "unsigned", status: http.StatusUnauthorized, send: func(r http.Handler) *httptest.ResponseRecorder {
+			return serve(r, http.MethodPost, "/webhooks/auction-events", nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.AuctionEvent{}, &models.Transaction{})
+			owner := createTestUser(t, db, "seller")
+			createTestListing(t, db, owner.ID)
+			r := newTestAuctionWebhook(t, db)
+
+			if w := tt.send(r); w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var stored int64
+			db.Model(&models.AuctionEvent{}).Count(&stored)
+			if wantStored := tt.status == http.StatusOK; (stored == 1) != wantStored {
+				t.Errorf("%d events stored", stored)
+			}
+		})
+	}
+}
+
+func TestAuctionWebhookRecordsSale(t *testing.T) {
+	db := newTestDB(t, &models.AuctionEvent{}, &models.Transaction{})
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, seller.ID)
+	r := newTestAuctionWebhook(t, db)
+
+	winner := []byte(fmt.Sprintf(`{"id":"evt_win","type":"bid.winner","data":{"auction_id":7,"listing_id":%d,"winner_id":%d,"amount":"1500000.40"}}`, listing.ID, buyer.ID))
+	w := postAuctionEvent(r, testAuctionSecret, time.Now(), winner)
+	if w.Code != http.StatusOK || decode(t, w)["status"] != "processed" {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	var txn models.Transaction
+	if err := db.Where("listing_id = ?", listing.ID).First(&txn).Error; err != nil {
+		t.Fatalf("no transaction recorded: %v", err)
+	}
+	if txn.BuyerID != buyer.ID || txn.SellerID != seller.ID || txn.Amount != 1500000 ||
+		txn.Status != models.TransactionStatusPending || txn.PaymentReference != "auction:7" {
+		t.Errorf("transaction %+v", txn)
+	}
+	var stored models.Listing
+	db.First(&stored, listing.ID)
+	if stored.Status != models.ListingStatusSold {
+		t.Errorf("listing status %s, want %s", stored.Status, models.ListingStatusSold)
+	}
+
+	// A redelivery of the same event and a later event for the same auction
+	// change nothing
+	if w := postAuctionEvent(r, testAuctionSecret, time.Now(), winner); w.Code != http.StatusOK || decode(t, w)["status"] != "duplicate" {
+		t.Errorf("redelivery status %d: %s", w.Code, w.Body)
+	}
+	closed := []byte(fmt.Sprintf(`{"id":"evt_closed","type":"auction.closed","data":{"auction_id":7,"listing_id":%d,"winner_id":%d,"amount":1500000}}`, listing.ID, buyer.ID))
+	if w := postAuctionEvent(r, testAuctionSecret, time.Now(), closed); w.Code != http.StatusOK || decode(t, w)["transaction_id"] != float64(txn.ID) {
+		t.Errorf("closed event status %d: %s", w.Code, w.Body)
+	}
+
+	var transactions, messages int64
+	db.Model(&models.Transaction{}).Count(&transactions)
+	db.Model(&models.Message{}).Where("receiver_id = ?", buyer.ID).Count(&messages)
+	if transactions != 1 || messages != 1 {
+		t.Errorf("%d transactions and %d messages to the winner, want 1 each", transactions, messages)
+	}
+}
+
+func TestAuctionWebhookWithoutWinner(t *testing.T) {
+	db := newTestDB(t, &models.AuctionEvent{}, &models.Transaction{})
+	seller := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, seller.ID)
+	r := newTestAuctionWebhook(t, db)
+
+	body := []byte(fmt.Sprintf(`{"id":"evt_1","type":"auction.closed","data":{"auction_id":7,"listing_id":%d}}`, listing.ID))
+	if w := postAuctionEvent(r, testAuctionSecret, time.Now(), body); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	var transactions int64
+	db.Model(&models.Transaction{}).Count(&transactions)
+	var stored models.Listing
+	db.First(&stored, listing.ID)
+	if transactions != 0 || stored.Status != models.ListingStatusActive {
+		t.Errorf("%d transactions, listing %s; want none and still active", transactions, stored.Status)
+	}
+}
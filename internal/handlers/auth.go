@@ -3,17 +3,20 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"trade_company/internal/auth"
 	"trade_company/internal/config"
 	"trade_company/internal/logger"
 	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -23,11 +26,17 @@ import (
 // Dependencies:
 //   - DB: GORM database connection for user persistence
 //   - Cfg: Application configuration for JWT settings
-//   - Log: Structured logger for security event logging
+//
+// Security events are logged through the request-scoped logger
+// (logger.FromContext), which already carries the request ID, IP and user agent.
 type AuthHandler struct {
-	DB  *gorm.DB       // Database connection for user operations
-	Cfg *config.Config // Configuration for JWT token generation
-	Log *zap.Logger    // Logger for authentication events
+	DB    *gorm.DB                  // Database connection for user operations
+	Cfg   *config.Config            // Configuration for JWT token generation
+	Cache *redisclient.CacheService // Caches profile counts; optional, nil when Redis is not configured
+
+	Challenge      *auth.LoginChallenge // Adaptive login challenge; nil when disabled
+	Lockout        *auth.LockoutTracker // Locks accounts after repeated failures; nil when Redis is not configured
+	SessionManager *auth.SessionManager // Caps and lists each user's logins; nil skips session tracking
 }
 
 // registerRequest defines the JSON payload structure for user registration.
@@ -35,9 +44,12 @@ type AuthHandler struct {
 // Validation rules:
 //   - Email: Must be a valid email format (RFC 5322)
 //   - Password: Minimum 8 characters for security
+//   - AcceptTerms/TermsVersion: Must accept the current terms of service
 type registerRequest struct {
-	Email    string `json:"email" binding:"required,email"`    // User's email address (unique identifier)
-	Password string `json:"password" binding:"required,min=8"` // Plain text password (hashed before storage)
+	Email        string `json:"email" binding:"required,email"`    // User's email address (unique identifier)
+	Password     string `json:"password" binding:"required,min=8"` // Plain text password (hashed before storage)
+	AcceptTerms  bool   `json:"accept_terms"`                      // The terms of service checkbox
+	TermsVersion string `json:"terms_version"`                     // Version of the terms the form showed
 }
 
 // loginRequest defines the JSON payload structure for user authentication.
@@ -48,6 +60,8 @@ type registerRequest struct {
 type loginRequest struct {
 	Email    string `json:"email" binding:"required,email"` // User's email address
 	Password string `json:"password" binding:"required"`    // Plain text password for verification
+
+	ChallengeToken string `json:"challenge_token"` // Turnstile token, required after repeated failures
 }
 
 // Register handles new user registration requests.
@@ -63,267 +77,290 @@ type loginRequest struct {
 //
 //	{
 //	  "email": "user@example.com",    // Valid email address (unique)
-//	  "password": "securepass123"     // Minimum 8 characters
+//	  "password": "securepass123",    // Minimum 8 characters
+//	  "accept_terms": true,           // Must be true
+//	  "terms_version": "2024-01"      // The current version, from GET /api/v1/terms
 //	}
 //
-// Response (201 Created):
+// Response (201 Created), with the refreshToken cookie set:
 //
 //	{
-//	  "message": "User created successfully",
-//	  "user_id": 123
+//	  "token": "eyJhbGciOi...",
+//	  "expires_at": "2024-01-08T10:00:00Z",
+//	  "refresh_token": "9f86d081...",
+//	  "refresh_expires_at": "2024-01-08T10:00:00Z"
 //	}
 //
 // Error Responses:
 //   - 400 Bad Request: Invalid email format or password too short
+//   - 400 Bad Request (TERMS_NOT_ACCEPTED): Current terms not accepted
 //   - 409 Conflict: Email already exists
 //   - 500 Internal Server Error: Database or hashing failure
 //
 // Security features:
-//   - bcrypt password hashing with default cost (10)
+//   - bcrypt password hashing at the configured cost (BCRYPT_COST, default 10)
 //   - Email uniqueness validation
 //   - Input validation and sanitization
 //   - Comprehensive security event logging
 func (h *AuthHandler) Register(c *gin.Context) {
-	requestID := c.GetString("request_id")
-	clientIP := c.ClientIP()
-	userAgent := c.Request.UserAgent()
-
-	h.Log.Info("AuthHandler: Registration attempt started",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
+	log := logger.FromContext(c)
+
+	log.Info("AuthHandler: Registration attempt started",
 		zap.String("endpoint", "/api/v1/auth/register"))
 
 	var req registerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.Log.Warn("AuthHandler: Registration request validation failed",
-			zap.String("request_id", requestID),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
+		log.Warn("AuthHandler: Registration request validation failed",
 			zap.Error(err),
 			zap.String("validation_error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.Log.Info("AuthHandler: Registration request validated successfully",
-		zap.String("request_id", requestID),
+	if !checkTermsAccepted(c, h.Cfg, req.AcceptTerms, req.TermsVersion) {
+		log.Warn("AuthHandler: Registration rejected - terms not accepted",
+			zap.String("email", req.Email),
+			zap.String("terms_version", req.TermsVersion))
+		return
+	}
+
+	log.Info("AuthHandler: Registration request validated successfully",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
 		zap.Int("password_length", len(req.Password)))
 
-	h.Log.Info("AuthHandler: Starting password hashing",
-		zap.String("request_id", requestID),
-		zap.String("email", req.Email),
-		zap.String("ip", clientIP))
+	log.Info("AuthHandler: Starting password hashing",
+		zap.String("email", req.Email))
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hash, err := auth.HashPassword(req.Password, h.Cfg.BcryptCost)
 	if err != nil {
-		h.Log.Error("AuthHandler: Registration failed - password hashing error",
-			zap.String("request_id", requestID),
+		log.Error("AuthHandler: Registration failed - password hashing error",
 			zap.String("email", req.Email),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
 			logger.Err(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash error"})
 		return
 	}
 
-	h.Log.Info("AuthHandler: Password hashing successful - creating user",
-		zap.String("request_id", requestID),
-		zap.String("email", req.Email),
-		zap.String("ip", clientIP))
-
-	user := models.User{Email: req.Email, PasswordHash: string(hash)}
-	if err := h.DB.Create(&user).Error; err != nil {
-		h.Log.Warn("AuthHandler: Registration failed - user creation error",
-			zap.String("request_id", requestID),
+	log.Info("AuthHandler: Password hashing successful - creating user",
+		zap.String("email", req.Email))
+
+	user := models.User{Email: req.Email, PasswordHash: hash}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordTermsAcceptance(tx, user.ID, req.TermsVersion, c.ClientIP(), time.Now())
+	}); err != nil {
+		log.Warn("AuthHandler: Registration failed - user creation error",
 			zap.String("email", req.Email),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
 			logger.Err(err),
 			zap.String("database_error", err.Error()))
 		c.JSON(http.StatusConflict, gin.H{"error": "email exists or invalid"})
 		return
 	}
 
-	h.Log.Info("AuthHandler: User created successfully - generating JWT token",
-		zap.String("request_id", requestID),
+	log.Info("AuthHandler: User created successfully - generating JWT token",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
 		zap.Uint("user_id", user.ID))
 
-	token, err := auth.GenerateToken(h.Cfg, user.ID, user.Email)
+	refresh, err := h.issueRefreshToken(c, &user)
 	if err != nil {
-		h.Log.Error("AuthHandler: Registration failed - token generation error",
-			zap.String("request_id", requestID),
+		log.Error("AuthHandler: Registration failed - refresh token error",
+			zap.Uint("user_id", user.ID),
+			logger.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+
+	token, expiresAt, err := auth.IssueSessionToken(h.Cfg, user.ID, user.Email, refresh.Family)
+	if err != nil {
+		log.Error("AuthHandler: Registration failed - token generation error",
 			zap.String("email", req.Email),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
 			zap.Uint("user_id", user.ID),
 			logger.Err(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
 		return
 	}
 
-	h.Log.Info("AuthHandler: Registration successful - returning token",
-		zap.String("request_id", requestID),
+	log.Info("AuthHandler: Registration successful - returning token",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
 		zap.Uint("user_id", user.ID),
 		zap.Int("token_length", len(token)))
 
-	c.JSON(http.StatusCreated, gin.H{"token": token})
+	c.JSON(http.StatusCreated, gin.H{
+		"token":              token,
+		"expires_at":         expiresAt,
+		"refresh_token":      refresh.Token,
+		"refresh_expires_at": refresh.ExpiresAt,
+	})
 }
 
+// Login checks an email and password and sets the authToken and refreshToken
+// cookies, or answers requires_2fa for accounts with two-factor
+// authentication.
+//
+// Error Responses:
+//   - 401 Unauthorized: Unknown email or wrong password
+//   - 403 Forbidden: Login challenge required (challenge_required)
+//   - 409 Conflict (TOO_MANY_SESSIONS): SESSION_LIMIT_STRICT is on and the
+//     user already has SESSION_MAX_PER_USER sessions; without strict mode the
+//     oldest session is revoked instead
+//   - 429 Too Many Requests (ACCOUNT_LOCKED): Too many failed attempts, with
+//     Retry-After set to the seconds left. In LOCKOUT_MODE=exponential each
+//     further lockout lasts twice as long.
 func (h *AuthHandler) Login(c *gin.Context) {
-	requestID := c.GetString("request_id")
-	clientIP := c.ClientIP()
-	userAgent := c.Request.UserAgent()
-
-	h.Log.Info("AuthHandler: Login attempt started",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
+	log := logger.FromContext(c)
+
+	log.Info("AuthHandler: Login attempt started",
 		zap.String("endpoint", "/api/v1/auth/login"))
 
 	var req loginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.Log.Warn("AuthHandler: Login request validation failed",
-			zap.String("request_id", requestID),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
+		log.Warn("AuthHandler: Login request validation failed",
 			zap.Error(err),
 			zap.String("validation_error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.Log.Info("AuthHandler: Login request validated successfully, Searching for user in database",
-		zap.String("request_id", requestID),
+	log.Info("AuthHandler: Login request validated successfully, Searching for user in database",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
 		zap.Int("password_length", len(req.Password)))
 
-	// h.Log.Info("AuthHandler: Searching for user in database",
-	// 	zap.String("request_id", requestID),
-	// 	zap.String("email", req.Email),
-	// 	zap.String("ip", clientIP))
+	// log.Info("AuthHandler: Searching for user in database",
+	// 	zap.String("email", req.Email))
+
+	// After repeated failures the challenge is checked before the password, so
+	// guessing without solving it reveals nothing
+	if h.Challenge.Required(c, req.Email, c.ClientIP()) {
+		passed, err := h.Challenge.Verify(c, req.ChallengeToken, c.ClientIP())
+		if err != nil {
+			log.Error("AuthHandler: Login challenge verification unavailable",
+				zap.String("email", req.Email),
+				logger.Err(err))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "challenge verification unavailable", "challenge_required": true})
+			return
+		}
+		if !passed {
+			log.Warn("AuthHandler: Login rejected - challenge missing or failed",
+				zap.String("email", req.Email))
+			c.JSON(http.StatusForbidden, gin.H{"error": "challenge required", "challenge_required": true})
+			return
+		}
+	}
 
 	var user models.User
 	if err := h.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		h.Log.Warn("AuthHandler: Login failed - user not found",
-			zap.String("request_id", requestID),
+		log.Warn("AuthHandler: Login failed - user not found",
 			zap.String("email", req.Email),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
 			logger.Err(err),
 			zap.String("database_error", err.Error()))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		challenge := h.Challenge.RecordFailure(c, req.Email, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials", "challenge_required": challenge})
 		return
 	}
 
-	h.Log.Info("AuthHandler: User found - verifying password",
-		zap.String("request_id", requestID),
+	log.Info("AuthHandler: User found - verifying password",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
 		zap.Uint("user_id", user.ID),
 		zap.Bool("user_is_active", user.IsActive))
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		h.Log.Warn("AuthHandler: Login failed - invalid password",
-			zap.String("request_id", requestID),
+	// A locked account is refused before the password is checked
+	if accountLocked(c, h.Lockout, user.Email) {
+		return
+	}
+
+	if err := auth.CheckPassword(user.PasswordHash, req.Password); err != nil {
+		log.Warn("AuthHandler: Login failed - invalid password",
 			zap.String("email", req.Email),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
 			zap.Uint("user_id", user.ID),
 			logger.Err(err))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		recordLoginFailure(c, h.Lockout, user.Email)
+		challenge := h.Challenge.RecordFailure(c, req.Email, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials", "challenge_required": challenge})
+		return
+	}
+	h.Lockout.Reset(c, user.Email)
+	h.Challenge.Reset(c, req.Email)
+
+	// With 2FA on the password only earns a login token; TwoFactorLogin sets
+	// the cookie once the code checks out
+	if user.TwoFactorEnabled {
+		log.Info("AuthHandler: Password verified - two-factor code required",
+			zap.Uint("user_id", user.ID))
+		token, expiresAt := auth.TwoFactorLoginToken(h.Cfg, user.ID)
+		twoFactorRequired(c, token, expiresAt)
 		return
 	}
 
-	h.Log.Info("AuthHandler: Password verification successful - generating JWT token",
-		zap.String("request_id", requestID),
+	if queued, err := recordLogin(h.DB, h.Cfg, &user, time.Now()); err != nil {
+		log.Error("AuthHandler: Failed to record login",
+			zap.Uint("user_id", user.ID),
+			logger.Err(err))
+	} else if queued > 0 {
+		log.Info("AuthHandler: Returning seller asked to confirm listings",
+			zap.Uint("user_id", user.ID),
+			zap.Int("listings", queued))
+	}
+
+	log.Info("AuthHandler: Password verification successful - generating JWT token",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
 		zap.Uint("user_id", user.ID))
 
-	token, err := auth.GenerateToken(h.Cfg, user.ID, user.Email)
+	refresh, err := h.issueRefreshToken(c, &user)
+	if errors.Is(err, auth.ErrTooManySessions) {
+		log.Warn("AuthHandler: Login refused - session limit reached",
+			zap.Uint("user_id", user.ID),
+			zap.Int("max_sessions", h.Cfg.SessionMaxPerUser))
+		tooManySessions(c)
+		return
+	}
 	if err != nil {
-		h.Log.Error("AuthHandler: Login failed - token generation error",
-			zap.String("request_id", requestID),
+		log.Error("AuthHandler: Login failed - refresh token error",
+			zap.Uint("user_id", user.ID),
+			logger.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+
+	token, expiresAt, err := auth.IssueSessionToken(h.Cfg, user.ID, user.Email, refresh.Family)
+	if err != nil {
+		log.Error("AuthHandler: Login failed - token generation error",
 			zap.String("email", req.Email),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
 			zap.Uint("user_id", user.ID),
 			logger.Err(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
 		return
 	}
 
-	h.Log.Info("AuthHandler: JWT token generated successfully - setting cookie",
-		zap.String("request_id", requestID),
+	log.Info("AuthHandler: JWT token generated successfully - setting cookie",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
 		zap.Uint("user_id", user.ID),
 		zap.Int("token_length", len(token)),
 		zap.Int("expire_minutes", h.Cfg.JWTExpireMinutes))
 
-	// Set JWT token as HTTP-only cookie for security
-	if h.Cfg.AppEnv == "development" {
-		// For development, use standard SetCookie with localhost domain
-		// SameSite=Lax works better for localhost development than SameSite=None
-		c.SetCookie(
-			"authToken",                    // Cookie name
-			token,                          // JWT token value
-			int(h.Cfg.JWTExpireMinutes*60), // Max age in seconds
-			"/",                            // Path (all routes)
-			"localhost",                    // Domain (localhost for cross-port support)
-			false,                          // Secure flag (false for HTTP development)
-			true,                           // HttpOnly flag (prevents JavaScript access)
-		)
-		h.Log.Info("AuthHandler: Development cookie set with localhost domain",
-			zap.String("request_id", requestID),
-			zap.String("ip", clientIP),
-			zap.String("domain", "localhost"),
-			zap.String("app_env", h.Cfg.AppEnv),
-			zap.Bool("secure", false),
-			zap.Bool("http_only", true))
-	} else {
-		// Production cookie with Secure flag
-		c.SetCookie(
-			"authToken",                    // Cookie name
-			token,                          // JWT token value
-			int(h.Cfg.JWTExpireMinutes*60), // Max age in seconds
-			"/",                            // Path (all routes)
-			"",                             // Domain (empty for production)
-			true,                           // Secure flag (requires HTTPS)
-			true,                           // HttpOnly flag (prevents JavaScript access)
-		)
-	}
+	h.setAuthCookie(c, token)
 
-	h.Log.Info("AuthHandler: Login successful - cookie set, returning response",
-		zap.String("request_id", requestID),
+	log.Info("AuthHandler: Login successful - cookie set, returning response",
 		zap.String("email", req.Email),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
 		zap.Uint("user_id", user.ID),
 		zap.String("app_env", h.Cfg.AppEnv),
 		zap.Int("cookie_max_age", int(h.Cfg.JWTExpireMinutes*60)))
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"user_id": user.ID,
+		"message":            "Login successful",
+		"user_id":            user.ID,
+		"expires_at":         expiresAt,
+		"refresh_expires_at": refresh.ExpiresAt,
 	})
 }
 
 // Logout handles user logout requests by clearing the authentication cookie.
 //
 // This endpoint securely logs out the user by setting the authToken cookie
-// to expire immediately, effectively clearing it from the browser.
+// to expire immediately, effectively clearing it from the browser. The
+// refresh token, from the refreshToken cookie or an optional
+// {"refresh_token": "..."} body, is revoked along with every token rotated
+// from the same login.
 //
 // HTTP Method: POST
 // Endpoint: /api/v1/auth/logout
@@ -339,24 +376,26 @@ func (h *AuthHandler) Login(c *gin.Context) {
 //   - Clears session on the client side
 //   - Prevents session hijacking after logout
 func (h *AuthHandler) Logout(c *gin.Context) {
-	requestID := c.GetString("request_id")
-	clientIP := c.ClientIP()
-	userAgent := c.Request.UserAgent()
+	log := logger.FromContext(c)
 
 	// Try to get user info before clearing session
 	userID, userIDExists := c.Get("user_id")
 	userEmail, emailExists := c.Get("user_email")
 
-	h.Log.Info("AuthHandler: Logout request started",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
+	log.Info("AuthHandler: Logout request started",
 		zap.String("endpoint", "/api/v1/auth/logout"),
 		zap.Any("user_id", userID),
 		zap.Bool("user_authenticated", userIDExists),
 		zap.Any("user_email", userEmail),
 		zap.Bool("email_exists", emailExists))
 
+	if refresh := refreshTokenFrom(c); refresh != "" {
+		if err := auth.RevokeRefreshToken(h.DB, refresh); err != nil {
+			log.Error("AuthHandler: Failed to revoke refresh token", logger.Err(err))
+		}
+	}
+	h.clearRefreshCookie(c)
+
 	// Clear the authentication cookie by setting it to expire immediately
 	if h.Cfg.AppEnv == "development" {
 		// Development logout cookie with localhost domain
@@ -369,9 +408,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 			false,       // Secure flag (false for HTTP development)
 			true,        // HttpOnly flag
 		)
-		h.Log.Info("AuthHandler: Development logout cookie cleared with localhost domain",
-			zap.String("request_id", requestID),
-			zap.String("ip", clientIP),
+		log.Info("AuthHandler: Development logout cookie cleared with localhost domain",
 			zap.String("domain", "localhost"),
 			zap.String("app_env", h.Cfg.AppEnv))
 	} else {
@@ -387,10 +424,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		)
 	}
 
-	h.Log.Info("AuthHandler: Logout successful - cookie cleared, returning response",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
+	log.Info("AuthHandler: Logout successful - cookie cleared, returning response",
 		zap.Any("logged_out_user_id", userID),
 		zap.Any("logged_out_user_email", userEmail),
 		zap.String("app_env", h.Cfg.AppEnv))
@@ -403,7 +437,8 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 // Me handles requests to get the current user's information.
 //
 // This endpoint returns the authenticated user's profile information
-// based on the JWT token in the request context.
+// based on the JWT token in the request context. GET /api/v1/user/profile
+// serves the identical body.
 //
 // HTTP Method: GET
 // Endpoint: /api/v1/auth/me
@@ -416,48 +451,40 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 //	    "email": "user@example.com",
 //	    "username": "username",
 //	    "first_name": "First",
-//	    "last_name": "Last"
-//	  }
+//	    "last_name": "Last",
+//	    "avatar_url": null,
+//	    "role": "user",
+//	    "badges": {"email_verified": true, "two_factor_enabled": false},
+//	    "counts": {"unread_messages": 2, "unread_leads": 1, "active_listings": 3}
+//	  },
+//	  "user": { ...same as data... }
 //	}
 //
 // Security features:
 //   - Requires valid JWT token
 //   - Returns only the authenticated user's data
 func (h *AuthHandler) Me(c *gin.Context) {
-	requestID := c.GetString("request_id")
-	clientIP := c.ClientIP()
-	userAgent := c.Request.UserAgent()
-
-	h.Log.Info("AuthHandler: Me request started",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
+	log := logger.FromContext(c)
+
+	log.Info("AuthHandler: Me request started",
 		zap.String("endpoint", "/api/v1/auth/me"))
 
 	// Get user ID from JWT middleware context
 	userID, exists := c.Get("user_id")
 	if !exists {
-		h.Log.Warn("AuthHandler: Me request failed - no user ID in context",
-			zap.String("request_id", requestID),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
+		log.Warn("AuthHandler: Me request failed - no user ID in context",
 			zap.String("auth_error", "no_user_id_in_context"))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
 		return
 	}
 
-	h.Log.Info("AuthHandler: User ID found in context - validating type",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
+	log.Info("AuthHandler: User ID found in context - validating type",
 		zap.Any("user_id_raw", userID),
 		zap.String("user_id_type", fmt.Sprintf("%T", userID)))
 
 	userIDValue, ok := userID.(uint)
 	if !ok {
-		h.Log.Error("AuthHandler: Me request failed - invalid user ID type in context",
-			zap.String("request_id", requestID),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
+		log.Error("AuthHandler: Me request failed - invalid user ID type in context",
 			zap.Any("user_id_value", userID),
 			zap.String("expected_type", "uint"),
 			zap.String("actual_type", fmt.Sprintf("%T", userID)))
@@ -465,47 +492,304 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		return
 	}
 
-	h.Log.Info("AuthHandler: User ID validated - fetching user from database",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
+	log.Info("AuthHandler: User ID validated - returning profile",
 		zap.Uint("user_id", userIDValue))
 
-	// Get user information from database
-	var user models.User
-	if err := h.DB.First(&user, userIDValue).Error; err != nil {
-		h.Log.Error("AuthHandler: Me request failed - user not found in database",
-			zap.String("request_id", requestID),
-			zap.String("ip", clientIP),
-			zap.String("user_agent", userAgent),
-			zap.Uint("user_id", userIDValue),
-			logger.Err(err),
-			zap.String("database_error", err.Error()))
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	serveProfile(c, h.DB, h.Cache, userIDValue)
+}
+
+// Extend issues a fresh token to a client whose token is about to expire, so
+// SPAs can keep the user logged in without asking for the password. It sits
+// behind the JWT middleware, so a token the middleware rejects (expired, or
+// revoked once revocation checks are added there) can't be extended.
+//
+// HTTP Method: POST
+// Endpoint: /api/v1/auth/extend
+//
+// Response (200 OK): {"token": "...", "expires_at": "..."}, with the authToken
+// cookie set to the new token.
+//
+// Error Responses:
+//   - 401 Unauthorized: Token missing, invalid or expired
+//   - 409 Conflict: Token isn't yet within JWT_EXTEND_WINDOW_MINUTES of expiry
+//     (code TOKEN_NOT_EXPIRING)
+func (h *AuthHandler) Extend(c *gin.Context) {
+	log := logger.FromContext(c)
+
+	token, expiresAt, err := auth.ExtendToken(h.Cfg, c.GetString("jwt_token"))
+	if errors.Is(err, auth.ErrTokenNotExpiring) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("token can only be extended in the last %d minutes before it expires", h.Cfg.JWTExtendWindowMinutes),
+			"code":  "TOKEN_NOT_EXPIRING",
+		})
+		return
+	}
+	if err != nil {
+		log.Warn("AuthHandler: Token extension failed", logger.Err(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	h.setAuthCookie(c, token)
+	log.Info("AuthHandler: Token extended",
+		zap.Any("user_id", c.Value("user_id")),
+		zap.Time("expires_at", expiresAt))
+	c.JSON(http.StatusOK, gin.H{"token": token, "expires_at": expiresAt})
+}
+
+// Refresh exchanges a refresh token for a fresh access token and the next
+// refresh token. Unlike Extend it works after the access token has expired, for
+// as long as the refresh token lives (JWT_REFRESH_EXPIRE_DAYS).
+//
+// HTTP Method: POST
+// Endpoint: /api/v1/auth/refresh
+//
+// Request Body (optional; the refreshToken cookie is used without it):
+//
+//	{"refresh_token": "9f86d081..."}
+//
+// Response (200 OK): {"token", "expires_at", "refresh_token",
+// "refresh_expires_at"}, with both cookies set to the new tokens. The presented
+// refresh token can't be used again.
+//
+// Error Responses:
+//   - 401 Unauthorized: Refresh token missing, unknown or expired
+//     (code INVALID_REFRESH_TOKEN)
+//   - 401 Unauthorized: Refresh token was already used; every token from the
+//     same login is revoked and the user has to log in again
+//     (code REFRESH_TOKEN_REUSED)
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	log := logger.FromContext(c)
+
+	presented := refreshTokenFrom(c)
+	if presented == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token required", "code": "INVALID_REFRESH_TOKEN"})
 		return
 	}
 
-	h.Log.Info("AuthHandler: User found in database - returning user information",
-		zap.String("request_id", requestID),
-		zap.String("ip", clientIP),
-		zap.String("user_agent", userAgent),
-		zap.Uint("user_id", userIDValue),
-		zap.String("user_email", user.Email),
-		zap.String("user_role", user.Role),
-		zap.Bool("user_is_active", user.IsActive),
-		zap.Bool("has_username", user.Username != ""),
-		zap.Bool("has_first_name", user.FirstName != ""),
-		zap.Bool("has_last_name", user.LastName != ""))
+	user, refresh, err := auth.RotateRefreshToken(h.DB, h.Cfg, presented)
+	if errors.Is(err, auth.ErrRefreshTokenReused) {
+		log.Warn("AuthHandler: Refresh token reused - token family revoked")
+		h.clearRefreshCookie(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token already used, please log in again", "code": "REFRESH_TOKEN_REUSED"})
+		return
+	}
+	if errors.Is(err, auth.ErrRefreshTokenInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token", "code": "INVALID_REFRESH_TOKEN"})
+		return
+	}
+	if err != nil {
+		log.Error("AuthHandler: Refresh token rotation failed", logger.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+
+	if h.SessionManager != nil {
+		if err := h.SessionManager.ExtendLoginSession(refresh.Family, refresh.ExpiresAt); err != nil {
+			log.Error("AuthHandler: Failed to extend session", zap.Uint("user_id", user.ID), logger.Err(err))
+		}
+	}
+
+	token, expiresAt, err := auth.IssueSessionToken(h.Cfg, user.ID, user.Email, refresh.Family)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	h.setAuthCookie(c, token)
+	h.setRefreshCookie(c, refresh)
+
+	log.Info("AuthHandler: Token refreshed",
+		zap.Uint("user_id", user.ID),
+		zap.Time("expires_at", expiresAt))
+	c.JSON(http.StatusOK, gin.H{
+		"token":              token,
+		"expires_at":         expiresAt,
+		"refresh_token":      refresh.Token,
+		"refresh_expires_at": refresh.ExpiresAt,
+	})
+}
+
+// Sessions lists the user's active logins, oldest first, marking the one the
+// request's token came from as current.
+//
+// HTTP Method: GET
+// Endpoint: /api/v1/user/sessions
+//
+// Response (200 OK):
+//
+//	{
+//	  "sessions": [
+//	    {"id": 12, "ip_address": "203.0.113.7", "user_agent": "...",
+//	     "created_at": "...", "expires_at": "...", "current": true}
+//	  ],
+//	  "max_sessions": 5
+//	}
+func (h *AuthHandler) Sessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := make([]gin.H, 0)
+	if h.SessionManager != nil {
+		sessions, err := h.SessionManager.GetUserSessions(userID.(uint))
+		if err != nil {
+			logger.FromContext(c).Error("AuthHandler: Failed to list sessions", logger.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+			return
+		}
+		// Session IDs are bearer credentials, so only the row ID is returned
+		current := c.GetString("login_session")
+		for _, s := range sessions {
+			result = append(result, gin.H{
+				"id":         s.ID,
+				"ip_address": s.IPAddress,
+				"user_agent": s.UserAgent,
+				"created_at": s.CreatedAt,
+				"expires_at": s.ExpiresAt,
+				"current":    current != "" && s.RefreshFamilyID == current,
+			})
+		}
+	}
 
-	// Return user information (excluding sensitive data like password hash)
 	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"username":   user.Username,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"role":       user.Role,
-			"is_active":  user.IsActive,
-		},
+		"sessions":     result,
+		"max_sessions": h.Cfg.SessionMaxPerUser,
 	})
 }
+
+// issueRefreshToken starts a refresh token family for a login and sets the
+// refreshToken cookie. The login is recorded as one of the user's sessions
+// first, which returns auth.ErrTooManySessions in strict mode once the user
+// is at the limit.
+func (h *AuthHandler) issueRefreshToken(c *gin.Context, user *models.User) (auth.RefreshToken, error) {
+	family, err := auth.NewRefreshFamily()
+	if err != nil {
+		return auth.RefreshToken{}, err
+	}
+	if h.SessionManager != nil {
+		expiresAt := time.Now().Add(time.Duration(h.Cfg.JWTRefreshExpireDays) * 24 * time.Hour)
+		if _, err := h.SessionManager.CreateLoginSession(user.ID, family, c.ClientIP(), c.Request.UserAgent(), expiresAt); err != nil {
+			return auth.RefreshToken{}, err
+		}
+	}
+	refresh, err := auth.IssueRefreshToken(h.DB, h.Cfg, user.ID, family)
+	if err != nil {
+		return refresh, err
+	}
+	h.setRefreshCookie(c, refresh)
+	return refresh, nil
+}
+
+// accountLocked answers 429 with Retry-After when repeated failures have
+// locked the account
+func accountLocked(c *gin.Context, lockout *auth.LockoutTracker, email string) bool {
+	lockedFor := lockout.LockedFor(c, email)
+	if lockedFor <= 0 {
+		return false
+	}
+	logger.FromContext(c).Warn("AuthHandler: Login rejected - account locked",
+		zap.String("email", email),
+		zap.Duration("locked_for", lockedFor))
+	c.Header("Retry-After", strconv.Itoa(int(lockedFor.Seconds())))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "account temporarily locked due to too many failed attempts", "code": "ACCOUNT_LOCKED"})
+	return true
+}
+
+// recordLoginFailure counts a wrong password or code towards the lockout
+func recordLoginFailure(c *gin.Context, lockout *auth.LockoutTracker, email string) {
+	log := logger.FromContext(c)
+	lockedFor, err := lockout.RecordFailure(c, email)
+	if err != nil {
+		log.Error("AuthHandler: Failed to record login failure", zap.String("email", email), logger.Err(err))
+		return
+	}
+	if lockedFor > 0 {
+		log.Warn("AuthHandler: Account locked after repeated failures",
+			zap.String("email", email),
+			zap.Duration("locked_for", lockedFor))
+	}
+}
+
+// tooManySessions answers a login refused by the strict session limit
+func tooManySessions(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{"error": "Too many active sessions. Log out on another device and try again.", "code": "TOO_MANY_SESSIONS"})
+}
+
+// setAuthCookie sets the authToken cookie for the configured token lifetime
+func (h *AuthHandler) setAuthCookie(c *gin.Context, token string) {
+	if h.Cfg.AppEnv == "development" {
+		// For development, use standard SetCookie with localhost domain
+		// SameSite=Lax works better for localhost development than SameSite=None
+		c.SetCookie(
+			"authToken",                    // Cookie name
+			token,                          // JWT token value
+			int(h.Cfg.JWTExpireMinutes*60), // Max age in seconds
+			"/",                            // Path (all routes)
+			"localhost",                    // Domain (localhost for cross-port support)
+			false,                          // Secure flag (false for HTTP development)
+			true,                           // HttpOnly flag (prevents JavaScript access)
+		)
+		logger.FromContext(c).Info("AuthHandler: Development cookie set with localhost domain",
+			zap.String("domain", "localhost"),
+			zap.String("app_env", h.Cfg.AppEnv),
+			zap.Bool("secure", false),
+			zap.Bool("http_only", true))
+	} else {
+		// Production cookie with Secure flag
+		c.SetCookie(
+			"authToken",                    // Cookie name
+			token,                          // JWT token value
+			int(h.Cfg.JWTExpireMinutes*60), // Max age in seconds
+			"/",                            // Path (all routes)
+			"",                             // Domain (empty for production)
+			true,                           // Secure flag (requires HTTPS)
+			true,                           // HttpOnly flag (prevents JavaScript access)
+		)
+	}
+}
+
+// refreshCookie holds the refresh token. It is only sent to the auth
+// endpoints, so it doesn't travel with every API request.
+const (
+	refreshCookie     = "refreshToken"
+	refreshCookiePath = "/api/v1/auth"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// refreshTokenFrom reads the refresh token from the request body, for clients
+// that keep it themselves, or else from the refreshToken cookie
+func refreshTokenFrom(c *gin.Context) string {
+	var req refreshRequest
+	if c.Request.ContentLength != 0 {
+		_ = c.ShouldBindJSON(&req)
+	}
+	if req.RefreshToken != "" {
+		return req.RefreshToken
+	}
+	token, _ := c.Cookie(refreshCookie)
+	return token
+}
+
+// setRefreshCookie sets the refreshToken cookie until the token expires
+func (h *AuthHandler) setRefreshCookie(c *gin.Context, refresh auth.RefreshToken) {
+	h.writeRefreshCookie(c, refresh.Token, int(time.Until(refresh.ExpiresAt).Seconds()))
+}
+
+// clearRefreshCookie expires the refreshToken cookie immediately
+func (h *AuthHandler) clearRefreshCookie(c *gin.Context) {
+	h.writeRefreshCookie(c, "", -1)
+}
+
+func (h *AuthHandler) writeRefreshCookie(c *gin.Context, value string, maxAge int) {
+	// Same domain and Secure flag as the authToken cookie
+	if h.Cfg.AppEnv == "development" {
+		c.SetCookie(refreshCookie, value, maxAge, refreshCookiePath, "localhost", false, true)
+	} else {
+		c.SetCookie(refreshCookie, value, maxAge, refreshCookiePath, "", true, true)
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeVerifier accepts only its valid token, or fails every check with err
+type fakeVerifier struct {
+	valid string
+	err   error
+	calls int
+}
+
+func (v *fakeVerifier) Verify(_ context.Context, token, _ string) (bool, error) {
+	v.calls++
+	if v.err != nil {
+		return false, v.err
+	}
+	return token == v.valid, nil
+}
+
+// challengeLogin posts to /auth/login from ip with an optional challenge token
+func challengeLogin(r http.Handler, ip, email, password, token string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password, "challenge_token": token})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// newChallengeRouter routes AuthHandler.Login with a challenge after two failures
+func newChallengeRouter(t *testing.T, verifier auth.ChallengeVerifier) (*gin.Engine, *models.User, *models.User) {
+	t.Helper()
+	db := newTestDB(t, &models.RefreshToken{})
+	cfg := testConfig(t)
+	cfg.LoginChallengeEnabled = true
+	cfg.LoginChallengeThreshold = 2
+	mr := miniredis.RunT(t)
+	h := &AuthHandler{DB: db, Cfg: cfg, Challenge: auth.NewLoginChallenge(redis.NewClient(&redis.Options{Addr: mr.Addr()}), cfg, verifier)}
+	seller := createTestUser(t, db, "seller")
+	withPassword(t, db, seller, "correct horse")
+	buyer := createTestUser(t, db, "buyer")
+	withPassword(t, db, buyer, "battery staple")
+
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+	return r, seller, buyer
+}
+
+func TestLoginChallengeEscalation(t *testing.T) {
+	verifier := &fakeVerifier{valid: "solved"}
+	r, seller, buyer := newChallengeRouter(t, verifier)
+
+	steps := []struct {
+		name          string
+		ip            string
+		email         string
+		password      string
+		token         string
+		status        int
+		wantChallenge bool
+	}{
+		{name: "first failure", ip: "10.0.0.1", email: seller.Email, password: "wrong", status: http.StatusUnauthorized},
+		{name: "second failure asks for a challenge", ip: "10.0.0.1", email: seller.Email, password: "wrong", status: http.StatusUnauthorized, wantChallenge: true},
+		{name: "right password without a token", ip: "10.0.0.1", email: seller.Email, password: "correct horse", status: http.StatusForbidden, wantChallenge: true},
+		{name: "right password with a failed token", ip: "10.0.0.1", email: seller.Email, password: "correct horse", token: "forged", status: http.StatusForbidden, wantChallenge: true},
+		// The email's failures follow it to another IP
+		{name: "email needs the challenge from any IP", ip: "10.0.0.2", email: seller.Email, password: "correct horse", status: http.StatusForbidden, wantChallenge: true},
+		// A solved challenge doesn't vouch for the password
+		{name: "solved challenge with a wrong password", ip: "10.0.0.1", email: seller.Email, password: "wrong", token: "solved", status: http.StatusUnauthorized, wantChallenge: true},
+		{name: "solved challenge with the right password", ip: "10.0.0.1", email: seller.Email, password: "correct horse", token: "solved", status: http.StatusOK},
+		// Success clears the email, but not the IP that was guessing
+		{name: "email cleared from a fresh IP", ip: "10.0.0.3", email: seller.Email, password: "correct horse", status: http.StatusOK},
+		{name: "IP still needs the challenge for others", ip: "10.0.0.1", email: buyer.Email, password: "battery staple", status: http.StatusForbidden, wantChallenge: true},
+	}
+
+	for _, step := range steps {
+		w := challengeLogin(r, step.ip, step.email, step.password, step.token)
+		if w.Code != step.status {
+			t.Fatalf("%s: status %d, want %d: %s", step.name, w.Code, step.status, w.Body)
+		}
+		if step.status != http.StatusOK && decode(t, w)["challenge_required"] != step.wantChallenge {
+			t.Errorf("%s: body %s, want challenge_required %v", step.name, w.Body, step.wantChallenge)
+		}
+	}
+	// Missing tokens are refused without a call to Turnstile
+	if verifier.calls != 3 {
+		t.Errorf("verifier called %d times, want 3", verifier.calls)
+	}
+}
+
+func TestLoginChallengeVerifierDown(t *testing.T) {
+	r, seller, _ := newChallengeRouter(t, &fakeVerifier{err: errors.New("turnstile unreachable")})
+	for i := 0; i < 2; i++ {
+		challengeLogin(r, "10.0.0.1", seller.Email, "wrong", "")
+	}
+	w := challengeLogin(r, "10.0.0.1", seller.Email, "correct horse", "solved")
+	if w.Code != http.StatusServiceUnavailable || decode(t, w)["challenge_required"] != true {
+		t.Errorf("status %d, want 503 with challenge_required: %s", w.Code, w.Body)
+	}
+}
+
+func TestLoginChallengeDisabled(t *testing.T) {
+	cfg := testConfig(t)
+	mr := miniredis.RunT(t)
+	if lc := auth.NewLoginChallenge(redis.NewClient(&redis.Options{Addr: mr.Addr()}), cfg, &fakeVerifier{}); lc != nil {
+		t.Fatal("challenge enabled by default")
+	}
+	cfg.LoginChallengeEnabled = true
+	if lc := auth.NewLoginChallenge(nil, cfg, &fakeVerifier{}); lc != nil {
+		t.Fatal("challenge enabled without Redis")
+	}
+
+	// A nil challenge never gets in the way
+	db := newTestDB(t, &models.RefreshToken{})
+	h := &AuthHandler{DB: db, Cfg: cfg}
+	user := createTestUser(t, db, "seller")
+	withPassword(t, db, user, "correct horse")
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+	for i := 0; i < 5; i++ {
+		if w := challengeLogin(r, "10.0.0.1", user.Email, "wrong", ""); decode(t, w)["challenge_required"] != false {
+			t.Fatalf("failure %d asked for a challenge: %s", i+1, w.Body)
+		}
+	}
+	if w := challengeLogin(r, "10.0.0.1", user.Email, "correct horse", ""); w.Code != http.StatusOK {
+		t.Errorf("status %d: %s", w.Code, w.Body)
+	}
+}
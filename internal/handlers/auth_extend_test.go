@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/middleware"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// withToken sends a request authenticated by token in the Authorization header
+func withToken(r http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// responseTime reads a time field of a JSON response
+func responseTime(t *testing.T, body map[string]interface{}, field string) time.Time {
+	t.Helper()
+	s, _ := body[field].(string)
+	at, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatalf("%s %v: %v", field, body[field], err)
+	}
+	return at
+}
+
+func TestTokenExpiryReported(t *testing.T) {
+	db := newTestDB(t, &models.UserSession{}, &models.RefreshToken{}, &models.AuditLog{})
+	cfg := testConfig(t)
+	sessionCfg := *cfg
+	sessionCfg.AppEnv = "test"
+	h := &AuthHandler{DB: db, Cfg: cfg, SessionManager: auth.NewSessionManager(nil, db, &sessionCfg)}
+	user := createTestUser(t, db, "seller")
+	withPassword(t, db, user, "correct horse")
+
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+	jwtAuth := middleware.JWT(middleware.JWTConfig{Secret: cfg.JWTSecret, Issuer: cfg.JWTIssuer, ParserOptions: auth.ParserOptions(cfg)}, zap.NewNop())
+	r.GET("/auth/me", jwtAuth, h.Me)
+
+	w := login(r, user.Email, "correct horse", "browser")
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status %d: %s", w.Code, w.Body)
+	}
+	expiresAt := responseTime(t, decode(t, w), "expires_at")
+	if lifetime := time.Until(expiresAt); lifetime < time.Duration(cfg.JWTExpireMinutes)*time.Minute-time.Minute {
+		t.Errorf("login token expires in %v, want about %d minutes", lifetime, cfg.JWTExpireMinutes)
+	}
+
+	// /auth/me reports the expiry of the token it was called with
+	w = withToken(r, http.MethodGet, "/auth/me", cookieValue(w, "authToken"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("me status %d: %s", w.Code, w.Body)
+	}
+	if me := responseTime(t, decode(t, w), "expires_at"); !me.Equal(expiresAt) {
+		t.Errorf("me expires_at %v, want %v", me, expiresAt)
+	}
+}
+
+func TestExtendEndpoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		windowMinutes int
+		token         string // empty uses a freshly issued token
+		status        int
+	}{
+		// A 5 minute token is inside a 5 minute window from the start
+		{name: "inside the window", windowMinutes: 5, status: http.StatusOK},
+		{name: "too early", windowMinutes: 1, status: http.StatusConflict},
+		{name: "invalid token", windowMinutes: 5, token: "not-a-token", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.JWTExpireMinutes = 5
+			cfg.JWTExtendWindowMinutes = tt.windowMinutes
+			h := &AuthHandler{DB: db, Cfg: cfg}
+			user := createTestUser(t, db, "seller")
+			r := gin.New()
+			jwtAuth := middleware.JWT(middleware.JWTConfig{Secret: cfg.JWTSecret, Issuer: cfg.JWTIssuer, ParserOptions: auth.ParserOptions(cfg)}, zap.NewNop())
+			r.POST("/auth/extend", jwtAuth, h.Extend)
+
+			token := tt.token
+			if token == "" {
+				var err error
+				if token, _, err = auth.IssueToken(cfg, user.ID, user.Email); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := withToken(r, http.MethodPost, "/auth/extend", token)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			body := decode(t, w)
+			switch tt.status {
+			case http.StatusConflict:
+				if body["code"] != "TOKEN_NOT_EXPIRING" {
+					t.Errorf("body %v, want code TOKEN_NOT_EXPIRING", body)
+				}
+			case http.StatusOK:
+				fresh, _ := body["token"].(string)
+				claims, err := auth.ParseToken(cfg, fresh)
+				if err != nil || claims.UserID != user.ID {
+					t.Fatalf("extended token %v, %v", claims, err)
+				}
+				if cookieValue(w, "authToken") != fresh || !responseTime(t, body, "expires_at").Equal(claims.ExpiresAt.Time) {
+					t.Errorf("cookie or expires_at don't match the new token: %v", body)
+				}
+			}
+		})
+	}
+}
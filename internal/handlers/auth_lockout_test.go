@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLoginLockoutEscalates(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		maxMinutes int
+		want       []time.Duration // Each consecutive lockout
+	}{
+		{name: "flat", mode: auth.LockoutModeFlat, maxMinutes: 60, want: []time.Duration{time.Minute, time.Minute, time.Minute}},
+		{name: "exponential", mode: auth.LockoutModeExponential, maxMinutes: 60, want: []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}},
+		{name: "exponential up to the cap", mode: auth.LockoutModeExponential, maxMinutes: 3, want: []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.RefreshToken{})
+			cfg := testConfig(t)
+			cfg.MaxLoginAttempts = 2
+			cfg.LockoutDurationMinutes = 1
+			cfg.LockoutMode = tt.mode
+			cfg.LockoutMaxDurationMinutes = tt.maxMinutes
+			mr := miniredis.RunT(t)
+			h := &AuthHandler{DB: db, Cfg: cfg, Lockout: auth.NewLockoutTracker(redis.NewClient(&redis.Options{Addr: mr.Addr()}), cfg)}
+			user := createTestUser(t, db, "seller")
+			withPassword(t, db, user, "correct horse")
+
+			r := gin.New()
+			r.POST("/auth/login", h.Login)
+
+			for i, want := range tt.want {
+				for attempt := 0; attempt < cfg.MaxLoginAttempts; attempt++ {
+					if w := login(r, user.Email, "wrong", "browser"); w.Code != http.StatusUnauthorized {
+						t.Fatalf("lockout %d: wrong password status %d: %s", i+1, w.Code, w.Body)
+					}
+				}
+
+				// Locked even with the right password, for the escalated time
+				w := login(r, user.Email, "correct horse", "browser")
+				if w.Code != http.StatusTooManyRequests || decode(t, w)["code"] != "ACCOUNT_LOCKED" {
+					t.Fatalf("lockout %d: status %d, want 429 ACCOUNT_LOCKED: %s", i+1, w.Code, w.Body)
+				}
+				if got := w.Header().Get("Retry-After"); got != strconv.Itoa(int(want.Seconds())) {
+					t.Errorf("lockout %d: Retry-After %s, want %d", i+1, got, int(want.Seconds()))
+				}
+
+				mr.FastForward(want + time.Second)
+			}
+
+			if w := login(r, user.Email, "correct horse", "browser"); w.Code != http.StatusOK {
+				t.Errorf("login after the lockout ended: status %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRefreshRouter routes login, refresh and logout for a user with a password
+func newRefreshRouter(t *testing.T) (*gin.Engine, *models.User) {
+	t.Helper()
+	db := newTestDB(t, &models.RefreshToken{})
+	h := &AuthHandler{DB: db, Cfg: testConfig(t)}
+	user := createTestUser(t, db, "seller")
+	withPassword(t, db, user, "correct horse")
+
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+	r.POST("/auth/refresh", h.Refresh)
+	r.POST("/auth/logout", h.Logout)
+	return r, user
+}
+
+// refresh presents token to /auth/refresh
+func refresh(t *testing.T, r http.Handler, token string) (int, map[string]interface{}) {
+	t.Helper()
+	w := serve(r, http.MethodPost, "/auth/refresh", map[string]string{"refresh_token": token})
+	return w.Code, decode(t, w)
+}
+
+func TestLogoutRevokesRefreshToken(t *testing.T) {
+	r, user := newRefreshRouter(t)
+	first := cookieValue(login(r, user.Email, "correct horse", "device-1"), "refreshToken")
+	other := cookieValue(login(r, user.Email, "correct horse", "device-2"), "refreshToken")
+
+	w := serve(r, http.MethodPost, "/auth/logout", map[string]string{"refresh_token": first})
+	if w.Code != http.StatusOK {
+		t.Fatalf("logout status %d: %s", w.Code, w.Body)
+	}
+	if status, _ := refresh(t, r, first); status != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: status %d, want %d", status, http.StatusUnauthorized)
+	}
+	// Logging out one device leaves the others signed in
+	if status, _ := refresh(t, r, other); status != http.StatusOK {
+		t.Errorf("other device refresh: status %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	r, user := newRefreshRouter(t)
+	first := cookieValue(login(r, user.Email, "correct horse", "device-1"), "refreshToken")
+
+	status, body := refresh(t, r, first)
+	if status != http.StatusOK {
+		t.Fatalf("refresh status %d: %v", status, body)
+	}
+	second, _ := body["refresh_token"].(string)
+	if second == "" || second == first {
+		t.Fatalf("refresh token not rotated: %v", body)
+	}
+
+	if status, body := refresh(t, r, first); status != http.StatusUnauthorized || body["code"] != "REFRESH_TOKEN_REUSED" {
+		t.Errorf("replayed token: status %d, body %v; want 401 REFRESH_TOKEN_REUSED", status, body)
+	}
+	if status, _ := refresh(t, r, second); status != http.StatusUnauthorized {
+		t.Errorf("successor after reuse: status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
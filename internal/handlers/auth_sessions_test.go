@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// withPassword gives user a real password hash so Login can check it
+func withPassword(t *testing.T, db *gorm.DB, user *models.User, password string) {
+	t.Helper()
+	hash, err := auth.HashPassword(password, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(user).Update("password_hash", hash).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// login posts to /auth/login from a device with the given user agent
+func login(r http.Handler, email, password, userAgent string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// cookieValue returns the value of a cookie a response set
+func cookieValue(w *httptest.ResponseRecorder, name string) string {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestLoginSessionLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		wantThird  int
+		wantAgents []string
+		wantEvent  string
+		// Status of refreshing the first login afterwards
+		wantFirstRefresh int
+	}{
+		{name: "oldest session is evicted", wantThird: http.StatusOK, wantAgents: []string{"device-2", "device-3"},
+			wantEvent: "session_evicted", wantFirstRefresh: http.StatusUnauthorized},
+		{name: "strict mode refuses the login", strict: true, wantThird: http.StatusConflict, wantAgents: []string{"device-1", "device-2"},
+			wantEvent: "session_limit_blocked", wantFirstRefresh: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.UserSession{}, &models.RefreshToken{}, &models.AuditLog{})
+			cfg := testConfig(t)
+			cfg.SessionMaxPerUser = 2
+			cfg.SessionLimitStrict = tt.strict
+			// The eviction notice fails quietly instead of being logged
+			sessionCfg := *cfg
+			sessionCfg.AppEnv = "test"
+			h := &AuthHandler{DB: db, Cfg: cfg, SessionManager: auth.NewSessionManager(nil, db, &sessionCfg)}
+			user := createTestUser(t, db, "seller")
+			withPassword(t, db, user, "correct horse")
+
+			r := gin.New()
+			r.POST("/auth/login", h.Login)
+			r.POST("/auth/refresh", h.Refresh)
+			r.GET("/user/sessions", asUser(user.ID), func(c *gin.Context) {
+				// As the JWT middleware does with the token's sid claim
+				if claims, err := auth.ParseToken(cfg, c.GetHeader("X-Test-Token")); err == nil {
+					c.Set("login_session", claims.SessionID)
+				}
+			}, h.Sessions)
+
+			var logins []*httptest.ResponseRecorder
+			for _, agent := range []string{"device-1", "device-2"} {
+				w := login(r, user.Email, "correct horse", agent)
+				if w.Code != http.StatusOK {
+					t.Fatalf("login from %s: status %d: %s", agent, w.Code, w.Body)
+				}
+				logins = append(logins, w)
+			}
+			third := login(r, user.Email, "correct horse", "device-3")
+			if third.Code != tt.wantThird {
+				t.Fatalf("third login status %d, want %d: %s", third.Code, tt.wantThird, third.Body)
+			}
+			if tt.strict && decode(t, third)["code"] != "TOO_MANY_SESSIONS" {
+				t.Errorf("third login body %s, want code TOO_MANY_SESSIONS", third.Body)
+			}
+
+			current := logins[len(logins)-1]
+			if !tt.strict {
+				current = third
+			}
+			req := httptest.NewRequest(http.MethodGet, "/user/sessions", nil)
+			req.Header.Set("X-Test-Token", cookieValue(current, "authToken"))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("sessions status %d: %s", w.Code, w.Body)
+			}
+			sessions := decode(t, w)["sessions"].([]interface{})
+			if len(sessions) != len(tt.wantAgents) {
+				t.Fatalf("%d sessions, want %d: %s", len(sessions), len(tt.wantAgents), w.Body)
+			}
+			for i, s := range sessions {
+				s := s.(map[string]interface{})
+				if s["user_agent"] != tt.wantAgents[i] {
+					t.Errorf("session %d from %v, want %s", i, s["user_agent"], tt.wantAgents[i])
+				}
+				if wantCurrent := i == len(sessions)-1; s["current"] != wantCurrent {
+					t.Errorf("session %d current %v, want %v", i, s["current"], wantCurrent)
+				}
+			}
+
+			// An evicted session's refresh token no longer works
+			w = serve(r, http.MethodPost, "/auth/refresh", map[string]string{"refresh_token": cookieValue(logins[0], "refreshToken")})
+			if w.Code != tt.wantFirstRefresh {
+				t.Errorf("first login refresh status %d, want %d: %s", w.Code, tt.wantFirstRefresh, w.Body)
+			}
+
+			var events int64
+			db.Model(&models.AuditLog{}).Where("user_id = ? AND event = ?", user.ID, tt.wantEvent).Count(&events)
+			if events != 1 {
+				t.Errorf("%d %s audit entries, want 1", events, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestLoggedOutSessionIsNotListed(t *testing.T) {
+	db := newTestDB(t, &models.UserSession{}, &models.RefreshToken{})
+	cfg := testConfig(t)
+	h := &AuthHandler{DB: db, Cfg: cfg, SessionManager: auth.NewSessionManager(nil, db, cfg)}
+	user := createTestUser(t, db, "seller")
+	withPassword(t, db, user, "correct horse")
+
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+	r.POST("/auth/logout", h.Logout)
+	r.GET("/user/sessions", asUser(user.ID), h.Sessions)
+
+	first := login(r, user.Email, "correct horse", "device-1")
+	login(r, user.Email, "correct horse", "device-2")
+	serve(r, http.MethodPost, "/auth/logout", map[string]string{"refresh_token": cookieValue(first, "refreshToken")})
+
+	sessions := decode(t, serve(r, http.MethodGet, "/user/sessions", nil))["sessions"].([]interface{})
+	if len(sessions) != 1 || sessions[0].(map[string]interface{})["user_agent"] != "device-2" {
+		t.Errorf("sessions %v, want only device-2", sessions)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"trade_company/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Capability states reported to the frontend
+const (
+	CapabilityEnabled  = "enabled"
+	CapabilityDegraded = "degraded"
+	CapabilityDisabled = "disabled"
+)
+
+const (
+	// capabilitiesTTL is how long an evaluated capability map is reused
+	capabilitiesTTL = 15 * time.Second
+	// capabilityProbeTimeout bounds each dependency health check
+	capabilityProbeTimeout = 2 * time.Second
+)
+
+// CapabilitiesHandler tells the SPA which features currently work so it can hide
+// or warn about the ones whose dependencies are down. Results only ever name
+// features and states, never hosts or error messages.
+type CapabilitiesHandler struct {
+	Cfg    *config.Config
+	DB     *gorm.DB
+	Redis  *redis.Client // nil when Redis is not configured
+	Client *http.Client  // used to probe the auction service
+
+	mu        sync.Mutex
+	cached    map[string]string
+	expiresAt time.Time
+}
+
+// NewCapabilitiesHandler creates a capabilities handler.
+func NewCapabilitiesHandler(cfg *config.Config, db *gorm.DB, redisClient *redis.Client) *CapabilitiesHandler {
+	return &CapabilitiesHandler{
+		Cfg:    cfg,
+		DB:     db,
+		Redis:  redisClient,
+		Client: &http.Client{Timeout: capabilityProbeTimeout},
+	}
+}
+
+// databaseCapability: listings, accounts and messaging all need the database
+func databaseCapability(ctx context.Context, db *gorm.DB) string {
+	if db == nil {
+		return CapabilityDisabled
+	}
+	sqlDB, err := db.DB()
+	if err != nil || sqlDB.PingContext(ctx) != nil {
+		return CapabilityDisabled
+	}
+	return CapabilityEnabled
+}
+
+// redisCapability: rate-limit headers and live updates need Redis
+func redisCapability(ctx context.Context, client *redis.Client) string {
+	if client == nil || client.Ping(ctx).Err() != nil {
+		return CapabilityDisabled
+	}
+	return CapabilityEnabled
+}
+
+// auctionCapability: the auction tab needs the auction service to answer. Any
+// response counts as reachable; server errors mean it is up but unhealthy.
+func auctionCapability(ctx context.Context, client *http.Client, baseURL string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/health", nil)
+	if err != nil {
+		return CapabilityDisabled
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return CapabilityDisabled
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return CapabilityDegraded
+	}
+	return CapabilityEnabled
+}
+
+// emailCapability: without a SendGrid key emails are only logged, so verification
+// and password reset mails never arrive
+func emailCapability(cfg *config.Config) string {
+	if cfg.SendGridAPIKey == "" {
+		return CapabilityDegraded
+	}
+	return CapabilityEnabled
+}
+
+// evaluate probes every dependency and maps the results onto features
+func (h *CapabilitiesHandler) evaluate(ctx context.Context) map[string]string {
+	probe := func(check func(ctx context.Context) string) string {
+		ctx, cancel := context.WithTimeout(ctx, capabilityProbeTimeout)
+		defer cancel()
+		return check(ctx)
+	}
+
+	database := probe(func(ctx context.Context) string { return databaseCapability(ctx, h.DB) })
+	redisState := probe(func(ctx context.Context) string { return redisCapability(ctx, h.Redis) })
+	auctions := probe(func(ctx context.Context) string { return auctionCapability(ctx, h.Client, auctionServiceURL) })
+
+	return map[string]string{
+		"listings":           database,
+		"accounts":           database,
+		"messaging":          database,
+		"rate_limit_headers": redisState,
+		"live_updates":       redisState,
+		"resumable_uploads":  redisState,
+		"auctions":           auctions,
+		"email_verification": emailCapability(h.Cfg),
+	}
+}
+
+// Get returns the feature → state map, re-evaluated at most every 15 seconds
+func (h *CapabilitiesHandler) Get(c *gin.Context) {
+	h.mu.Lock()
+	if h.cached == nil || time.Now().After(h.expiresAt) {
+		h.cached = h.evaluate(c.Request.Context())
+		h.expiresAt = time.Now().Add(capabilitiesTTL)
+	}
+	capabilities := h.cached
+	expiresAt := h.expiresAt
+	h.mu.Unlock()
+
+	c.Header("Cache-Control", "public, max-age=15")
+	c.JSON(http.StatusOK, gin.H{
+		"capabilities": capabilities,
+		"expires_at":   expiresAt.UTC(),
+	})
+}
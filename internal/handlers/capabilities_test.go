@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"trade_company/internal/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func TestDatabaseCapability(t *testing.T) {
+	tests := []struct {
+		name string
+		db   func(t *testing.T) *gorm.DB
+		want string
+	}{
+		{name: "reachable", db: func(t *testing.T) *gorm.DB { return newTestDB(t) }, want: CapabilityEnabled},
+		{name: "not configured", db: func(t *testing.T) *gorm.DB { return nil }, want: CapabilityDisabled},
+		{name: "closed", db: func(t *testing.T) *gorm.DB {
+			db := newTestDB(t)
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+			return db
+		}, want: CapabilityDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := databaseCapability(context.Background(), tt.db(t)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisCapability(t *testing.T) {
+	tests := []struct {
+		name   string
+		client func(t *testing.T) *redis.Client
+		want   string
+	}{
+		{name: "reachable", client: func(t *testing.T) *redis.Client {
+			return redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		}, want: CapabilityEnabled},
+		{name: "not configured", client: func(t *testing.T) *redis.Client { return nil }, want: CapabilityDisabled},
+		{name: "unreachable", client: func(t *testing.T) *redis.Client {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+			mr.Close()
+			return client
+		}, want: CapabilityDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redisCapability(context.Background(), tt.client(t)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuctionCapability(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int // 0 means the service is down
+		want   string
+	}{
+		{name: "healthy", status: http.StatusOK, want: CapabilityEnabled},
+		{name: "client error still reachable", status: http.StatusNotFound, want: CapabilityEnabled},
+		{name: "server error", status: http.StatusServiceUnavailable, want: CapabilityDegraded},
+		{name: "down", want: CapabilityDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/health" {
+					t.Errorf("probed %s, want /health", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			if tt.status == 0 {
+				srv.Close()
+			} else {
+				defer srv.Close()
+			}
+			if got := auctionCapability(context.Background(), srv.Client(), srv.URL); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailCapability(t *testing.T) {
+	if got := emailCapability(&config.Config{SendGridAPIKey: "SG.key"}); got != CapabilityEnabled {
+		t.Errorf("with a key: got %s, want %s", got, CapabilityEnabled)
+	}
+	if got := emailCapability(&config.Config{}); got != CapabilityDegraded {
+		t.Errorf("without a key: got %s, want %s", got, CapabilityDegraded)
+	}
+}
+
+func TestCapabilitiesCached(t *testing.T) {
+	mr := miniredis.RunT(t)
+	h := NewCapabilitiesHandler(testConfig(t), newTestDB(t), redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1}))
+	h.Client.Timeout = 100 * time.Millisecond
+	r := gin.New()
+	r.GET("/capabilities", h.Get)
+
+	capability := func(feature string) string {
+		t.Helper()
+		w := serve(r, http.MethodGet, "/capabilities", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		// Both Redis and the auction service are on this host
+		if strings.Contains(w.Body.String(), "127.0.0.1") {
+			t.Errorf("response names an internal host: %s", w.Body)
+		}
+		return decode(t, w)["capabilities"].(map[string]interface{})[feature].(string)
+	}
+
+	if got := capability("live_updates"); got != CapabilityEnabled {
+		t.Fatalf("live_updates %s, want %s", got, CapabilityEnabled)
+	}
+	// Redis going down is not noticed until the cached map expires
+	mr.Close()
+	if got := capability("live_updates"); got != CapabilityEnabled {
+		t.Errorf("live_updates %s before expiry, want cached %s", got, CapabilityEnabled)
+	}
+	h.expiresAt = time.Now().Add(-time.Second)
+	if got := capability("live_updates"); got != CapabilityDisabled {
+		t.Errorf("live_updates %s after expiry, want %s", got, CapabilityDisabled)
+	}
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxComparisonNameLength matches the comparison_lists.name column
+const maxComparisonNameLength = 100
+
+type ComparisonHandler struct {
+	DB  *gorm.DB
+	Cfg *config.Config
+}
+
+type comparisonListRequest struct {
+	Name       *string `json:"name"`
+	ListingIDs []uint  `json:"listing_ids"`
+}
+
+var (
+	// errComparisonListing is returned when a requested member listing can't be added
+	errComparisonListing = errors.New("listing not found")
+	// errTooManyComparisonLists is returned when the user already has the maximum number of lists
+	errTooManyComparisonLists = errors.New("too many comparison lists")
+)
+
+// comparisonMetrics derives the side-by-side figures for one listing. Ratios are
+// nil when the inputs they need are missing.
+func comparisonMetrics(l *models.Listing) gin.H {
+	annualGrossProfit := float64(l.AnnualRevenue) * l.GrossProfitRate
+
+	metrics := gin.H{
+		"price":                  l.Price,
+		"rent":                   l.Rent,
+		"deposit":                l.Deposit,
+		"annual_revenue":         l.AnnualRevenue,
+		"gross_profit_rate":      l.GrossProfitRate,
+		"annual_gross_profit":    int64(math.Round(annualGrossProfit)),
+		"square_meters":          l.SquareMeters,
+		"price_per_square_meter": nil,
+		"payback_years":          nil,
+		"rent_to_revenue":        nil,
+		"quality_score":          models.ScoreListing(l, len(l.Images)).Score,
+	}
+	if l.SquareMeters > 0 {
+		metrics["price_per_square_meter"] = math.Round(float64(l.Price) / l.SquareMeters)
+	}
+	if annualGrossProfit > 0 {
+		metrics["payback_years"] = math.Round(float64(l.Price)/annualGrossProfit*10) / 10
+	}
+	if l.AnnualRevenue > 0 {
+		metrics["rent_to_revenue"] = math.Round(float64(l.Rent*12)/float64(l.AnnualRevenue)*1000) / 1000
+	}
+	return metrics
+}
+
+// dedupeListingIDs drops zero and repeated IDs, keeping the first occurrence's order
+func dedupeListingIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// validateComparisonName trims and checks a list name
+func validateComparisonName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxComparisonNameLength {
+		return "", fmt.Errorf("name must be at most %d characters", maxComparisonNameLength)
+	}
+	return name, nil
+}
+
+// replaceComparisonItems swaps a list's members for listingIDs. New members must be visible
+// listings, and not someone else's private ones; members already on the list are kept
+// even if they have since gone inactive.
+func replaceComparisonItems(tx *gorm.DB, list *models.ComparisonList, listingIDs []uint) error {
+	existing := make(map[uint]bool, len(list.Items))
+	for _, item := range list.Items {
+		existing[item.ListingID] = true
+	}
+
+	var added []uint
+	for _, id := range listingIDs {
+		if !existing[id] {
+			added = append(added, id)
+		}
+	}
+	if len(added) > 0 {
+		var found int64
+		if err := tx.Model(&models.Listing{}).
+			Where("id IN ? AND status NOT IN ?", added, models.HiddenListingStatuses).
+			Where("visibility <> ? OR owner_id = ?", models.ListingVisibilityPrivate, list.UserID).
+			Count(&found).Error; err != nil {
+			return err
+		}
+		if int(found) != len(added) {
+			return errComparisonListing
+		}
+	}
+
+	if err := tx.Where("comparison_list_id = ?", list.ID).Delete(&models.ComparisonListItem{}).Error; err != nil {
+		return err
+	}
+	list.Items = make([]models.ComparisonListItem, 0, len(listingIDs))
+	for _, id := range listingIDs {
+		list.Items = append(list.Items, models.ComparisonListItem{ComparisonListID: list.ID, ListingID: id})
+	}
+	if len(list.Items) == 0 {
+		return nil
+	}
+	return tx.Create(&list.Items).Error
+}
+
+// comparisonListSummary is a list without its hydrated listings
+func comparisonListSummary(list *models.ComparisonList) gin.H {
+	ids := make([]uint, 0, len(list.Items))
+	for _, item := range list.Items {
+		ids = append(ids, item.ListingID)
+	}
+	return gin.H{
+		"id":            list.ID,
+		"name":          list.Name,
+		"listing_ids":   ids,
+		"listing_count": len(ids),
+		"created_at":    list.CreatedAt,
+		"updated_at":    list.UpdatedAt,
+	}
+}
+
+// findList loads one of the current user's lists with its items
+func (h *ComparisonHandler) findList(c *gin.Context, userID interface{}) (*models.ComparisonList, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comparison list ID"})
+		return nil, false
+	}
+
+	var list models.ComparisonList
+	if err := h.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comparison list not found"})
+		return nil, false
+	}
+	return &list, true
+}
+
+// List returns the current user's comparison lists
+func (h *ComparisonHandler) List(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var lists []models.ComparisonList
+	if err := h.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("user_id = ?", userID).
+		Order("updated_at desc").
+		Find(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comparison lists"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(lists))
+	for i := range lists {
+		result = append(result, comparisonListSummary(&lists[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"comparison_lists": result,
+		"max_lists":        h.Cfg.ComparisonMaxLists,
+		"max_listings":     h.Cfg.ComparisonMaxListings,
+	})
+}
+
+// Create saves a new named comparison list
+func (h *ComparisonHandler) Create(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req comparisonListRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	name, err := validateComparisonName(*req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	listingIDs := dedupeListingIDs(req.ListingIDs)
+	if len(listingIDs) > h.Cfg.ComparisonMaxListings {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        fmt.Sprintf("A comparison list can hold at most %d listings", h.Cfg.ComparisonMaxListings),
+			"max_listings": h.Cfg.ComparisonMaxListings,
+		})
+		return
+	}
+
+	list := models.ComparisonList{UserID: userID.(uint), Name: name}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.ComparisonList{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) >= h.Cfg.ComparisonMaxLists {
+			return errTooManyComparisonLists
+		}
+		if err := tx.Create(&list).Error; err != nil {
+			return err
+		}
+		return replaceComparisonItems(tx, &list, listingIDs)
+	})
+	switch {
+	case errors.Is(err, errTooManyComparisonLists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     fmt.Sprintf("You can keep at most %d comparison lists", h.Cfg.ComparisonMaxLists),
+			"max_lists": h.Cfg.ComparisonMaxLists,
+		})
+		return
+	case errors.Is(err, errComparisonListing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more listings were not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comparison list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"comparison_list": comparisonListSummary(&list)})
+}
+
+// Get returns a comparison list with its listings and their comparison metrics.
+// Members that are no longer active stay on the list, flagged unavailable.
+func (h *ComparisonHandler) Get(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	list, ok := h.findList(c, userID)
+	if !ok {
+		return
+	}
+
+	ids := make([]uint, 0, len(list.Items))
+	for _, item := range list.Items {
+		ids = append(ids, item.ListingID)
+	}
+	var listings []models.Listing
+	if len(ids) > 0 {
+		if err := h.DB.Preload("Images", "moderation_status = ?", models.ImageModerationApproved).Where("id IN ?", ids).Find(&listings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comparison list"})
+			return
+		}
+	}
+	byID := make(map[uint]*models.Listing, len(listings))
+	for i := range listings {
+		byID[listings[i].ID] = &listings[i]
+	}
+
+	members := make([]gin.H, 0, len(list.Items))
+	for _, item := range list.Items {
+		l, found := byID[item.ListingID]
+		if !found || l.Status.Hidden() || !l.VisibleTo(list.UserID) {
+			// Deleted listings keep their slot but expose nothing
+			members = append(members, gin.H{"listing_id": item.ListingID, "unavailable": true})
+			continue
+		}
+		members = append(members, gin.H{
+			"listing_id":  item.ListingID,
+			"unavailable": l.Status != models.ListingStatusActive,
+			"listing":     listingSummary(l),
+			"metrics":     comparisonMetrics(l),
+		})
+	}
+
+	summary := comparisonListSummary(list)
+	summary["listings"] = members
+	c.JSON(http.StatusOK, gin.H{"comparison_list": summary})
+}
+
+// Update renames a comparison list and/or replaces its listings
+func (h *ComparisonHandler) Update(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req comparisonListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	list, ok := h.findList(c, userID)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		name, err := validateComparisonName(*req.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		list.Name = name
+	}
+	var listingIDs []uint
+	if req.ListingIDs != nil {
+		listingIDs = dedupeListingIDs(req.ListingIDs)
+		if len(listingIDs) > h.Cfg.ComparisonMaxListings {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":        fmt.Sprintf("A comparison list can hold at most %d listings", h.Cfg.ComparisonMaxListings),
+				"max_listings": h.Cfg.ComparisonMaxListings,
+			})
+			return
+		}
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(list).Update("name", list.Name).Error; err != nil {
+			return err
+		}
+		if req.ListingIDs == nil {
+			return nil
+		}
+		return replaceComparisonItems(tx, list, listingIDs)
+	})
+	switch {
+	case errors.Is(err, errComparisonListing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more listings were not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comparison list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comparison_list": comparisonListSummary(list)})
+}
+
+// Delete removes a comparison list
+func (h *ComparisonHandler) Delete(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	list, ok := h.findList(c, userID)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comparison_list_id = ?", list.ID).Delete(&models.ComparisonListItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comparison list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comparison list deleted successfully"})
+}
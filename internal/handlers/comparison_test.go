@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestComparisonListSizeCaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings int // on the new list
+		update   int // listings sent in an update of the first list, if any
+		existing int // lists the user already has
+		want     int
+	}{
+		{name: "at the listing cap", listings: 3, want: http.StatusCreated},
+		{name: "over the listing cap", listings: 4, want: http.StatusBadRequest},
+		{name: "at the list cap", existing: 1, want: http.StatusCreated},
+		{name: "over the list cap", existing: 2, want: http.StatusConflict},
+		{name: "update at the listing cap", existing: 1, update: 3, want: http.StatusOK},
+		{name: "update over the listing cap", existing: 1, update: 4, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.ComparisonList{}, &models.ComparisonListItem{})
+			cfg := testConfig(t)
+			cfg.ComparisonMaxListings = 3
+			cfg.ComparisonMaxLists = 2
+			h := &ComparisonHandler{DB: db, Cfg: cfg}
+			seller := createTestUser(t, db, "seller")
+			buyer := createTestUser(t, db, "buyer")
+			var ids []uint
+			for i := 0; i < 4; i++ {
+				ids = append(ids, createTestListing(t, db, seller.ID).ID)
+			}
+			var lists []models.ComparisonList
+			for i := 0; i < tt.existing; i++ {
+				list := models.ComparisonList{UserID: buyer.ID, Name: fmt.Sprintf("List %d", i)}
+				db.Create(&list)
+				lists = append(lists, list)
+			}
+
+			r := gin.New()
+			r.POST("/comparisons", asUser(buyer.ID), h.Create)
+			r.PUT("/comparisons/:id", asUser(buyer.ID), h.Update)
+
+			var w *httptest.ResponseRecorder
+			if tt.update > 0 {
+				w = serve(r, http.MethodPut, fmt.Sprintf("/comparisons/%d", lists[0].ID), map[string]interface{}{"listing_ids": ids[:tt.update]})
+			} else {
+				w = serve(r, http.MethodPost, "/comparisons", map[string]interface{}{"name": "Cafes", "listing_ids": ids[:tt.listings]})
+			}
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+
+			var stored, items int64
+			db.Model(&models.ComparisonList{}).Where("user_id = ?", buyer.ID).Count(&stored)
+			db.Model(&models.ComparisonListItem{}).Count(&items)
+			if stored > int64(cfg.ComparisonMaxLists) || items > int64(cfg.ComparisonMaxListings) {
+				t.Errorf("%d lists with %d listings stored", stored, items)
+			}
+		})
+	}
+}
+
+func TestComparisonListKeepsInactiveMembers(t *testing.T) {
+	db := newTestDB(t, &models.ComparisonList{}, &models.ComparisonListItem{})
+	h := &ComparisonHandler{DB: db, Cfg: testConfig(t)}
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	active := createTestListing(t, db, seller.ID)
+	later := createTestListing(t, db, seller.ID)
+
+	r := gin.New()
+	r.POST("/comparisons", asUser(buyer.ID), h.Create)
+	r.GET("/comparisons/:id", asUser(buyer.ID), h.Get)
+
+	w := serve(r, http.MethodPost, "/comparisons", map[string]interface{}{"name": "Cafes", "listing_ids": []uint{active.ID, later.ID}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	id := decode(t, w)["comparison_list"].(map[string]interface{})["id"]
+	db.Model(later).Update("status", models.ListingStatusSold)
+
+	members := decode(t, serve(r, http.MethodGet, fmt.Sprintf("/comparisons/%v", id), nil))["comparison_list"].(map[string]interface{})["listings"].([]interface{})
+	if len(members) != 2 {
+		t.Fatalf("%d members, want 2", len(members))
+	}
+	for i, want := range []bool{false, true} {
+		m := members[i].(map[string]interface{})
+		if m["unavailable"] != want || m["metrics"] == nil {
+			t.Errorf("member %d: %v", i, m)
+		}
+	}
+}
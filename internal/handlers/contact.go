@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contactPrompt is shown in place of masked contact details
+const contactPrompt = "Contact the seller through the inquiry form to see their phone number and email"
+
+// MaskPhone keeps the first and last two digits of a phone number
+func MaskPhone(phone string) string {
+	runes := []rune(phone)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:2]) + strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-2:])
+}
+
+// MaskEmail keeps the first letter of the local part and the domain
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return MaskPhone(email)
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// listingContact builds the contact block for a listing, masking it unless reveal is set
+func listingContact(listing *models.Listing, reveal bool) gin.H {
+	phone := listing.PhoneNumber
+	if phone == "" {
+		phone = listing.Owner.ContactPhone
+	}
+	if phone == "" {
+		phone = listing.Owner.Phone
+	}
+	email := listing.Owner.Email
+
+	if reveal {
+		return gin.H{
+			"phone":    phone,
+			"email":    email,
+			"revealed": true,
+		}
+	}
+	return gin.H{
+		"phone":    MaskPhone(phone),
+		"email":    MaskEmail(email),
+		"revealed": false,
+		"prompt":   contactPrompt,
+	}
+}
+
+// maskedOwner returns the public part of a listing owner's profile with contact fields masked
+func maskedOwner(owner models.User) gin.H {
+	return gin.H{
+		"id":            owner.ID,
+		"username":      owner.Username,
+		"first_name":    owner.FirstName,
+		"last_name":     owner.LastName,
+		"company_name":  owner.CompanyName,
+		"email":         MaskEmail(owner.Email),
+		"phone":         MaskPhone(owner.Phone),
+		"contact_phone": MaskPhone(owner.ContactPhone),
+	}
+}
+
+// publicContact returns the listing's phone number and owner as buyers see
+// them on the detail and in search results: masked while contact details are
+// gated behind a lead
+func publicContact(listing *models.Listing, gated bool) (string, interface{}) {
+	if gated {
+		return MaskPhone(listing.PhoneNumber), maskedOwner(listing.Owner)
+	}
+	return listing.PhoneNumber, listing.PublicOwner()
+}
+
+// canSeeContact reports whether a user may see a seller's full contact details:
+// gating is disabled, the user is the seller, or the user has sent the seller a non-spam lead
+func (h *ListingsHandler) canSeeContact(userID, sellerID uint) (bool, error) {
+	if !h.Cfg.ContactRevealRequiresLead || userID == sellerID {
+		return true, nil
+	}
+
+	var count int64
+	err := h.DB.Model(&models.Lead{}).
+		Where("sender_id = ? AND receiver_id = ? AND is_spam = ?", userID, sellerID, false).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RevealContact returns the listing owner's contact details. When contact gating
+// is enabled, buyers without a lead to the seller get masked values and a prompt.
+func (h *ListingsHandler) RevealContact(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Preload("Owner").
+		Where("status NOT IN ?", models.HiddenListingStatuses).
+		First(&listing, id).Error; err != nil || !h.canOpen(c, &listing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	reveal, err := h.canSeeContact(userID.(uint), listing.OwnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check contact history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"listing_id": listing.ID,
+		"seller_id":  listing.OwnerID,
+		"contact":    listingContact(&listing, reveal),
+	})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRevealContact(t *testing.T) {
+	tests := []struct {
+		name       string
+		gated      bool
+		lead       bool // The buyer has sent the seller a lead
+		spam       bool // ... which was marked spam
+		wantReveal bool
+	}{
+		{name: "ungated, no lead", gated: false, wantReveal: true},
+		{name: "gated, no lead", gated: true, wantReveal: false},
+		{name: "gated, with lead", gated: true, lead: true, wantReveal: true},
+		{name: "gated, spam lead only", gated: true, lead: true, spam: true, wantReveal: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.ContactRevealRequiresLead = tt.gated
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			seller := createTestUser(t, db, "seller")
+			buyer := createTestUser(t, db, "buyer")
+			listing := createTestListing(t, db, seller.ID)
+			if tt.lead {
+				lead := models.Lead{SenderID: buyer.ID, ReceiverID: seller.ID, ListingID: &listing.ID, Subject: "Hi", Message: "Hello", IsSpam: tt.spam}
+				if err := db.Create(&lead).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := gin.New()
+			r.GET("/listings/:id/contact", asUser(buyer.ID), h.RevealContact)
+			w := serve(r, http.MethodGet, fmt.Sprintf("/listings/%d/contact", listing.ID), nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			contact := decode(t, w)["contact"].(map[string]interface{})
+			if contact["revealed"] != tt.wantReveal {
+				t.Errorf("revealed = %v, want %v", contact["revealed"], tt.wantReveal)
+			}
+			wantPhone := listing.PhoneNumber
+			if !tt.wantReveal {
+				wantPhone = MaskPhone(wantPhone)
+			}
+			if contact["phone"] != wantPhone {
+				t.Errorf("phone = %v, want %v", contact["phone"], wantPhone)
+			}
+		})
+	}
+}
+
+// A buyer who is refused the contact details can send a lead through the
+// routed endpoint and then see them
+func TestRevealContactAfterLead(t *testing.T) {
+	db := newTestDB(t, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+	cfg := testConfig(t)
+	cfg.ContactRevealRequiresLead = true
+	listH := &ListingsHandler{DB: db, Cfg: cfg}
+	leadH := newTestLeadHandler(t, db, cfg)
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, seller.ID)
+
+	r := gin.New()
+	r.Use(asUser(buyer.ID))
+	r.GET("/listings/:id/contact", listH.RevealContact)
+	r.POST("/listings/:id/leads", leadH.ContactSeller)
+	contactURL := fmt.Sprintf("/listings/%d/contact", listing.ID)
+
+	if got := decode(t, serve(r, http.MethodGet, contactURL, nil))["contact"].(map[string]interface{}); got["revealed"] != false {
+		t.Fatalf("revealed before any lead: %v", got)
+	}
+	w := serve(r, http.MethodPost, fmt.Sprintf("/listings/%d/leads", listing.ID), leadBody(nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("lead status %d: %s", w.Code, w.Body)
+	}
+	if got := decode(t, serve(r, http.MethodGet, contactURL, nil))["contact"].(map[string]interface{}); got["revealed"] != true {
+		t.Errorf("not revealed after a lead: %v", got)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/wordfilter"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// screenContent runs the user-written fields of a listing, message or lead
+// through the sensitive word filter before they are stored. Matches are
+// logged for moderation review. In reject mode a 400 naming the terms is
+// written and ok is false; in mask mode the fields are masked in place.
+func screenContent(c *gin.Context, words *wordfilter.Filter, kind string, fields map[string]*string) bool {
+	matches := gin.H{}
+	for name, value := range fields {
+		if value == nil {
+			continue
+		}
+		masked, found := words.Screen(*value)
+		if len(found) == 0 {
+			continue
+		}
+		matches[name] = found
+		*value = masked
+	}
+	if len(matches) == 0 {
+		return true
+	}
+
+	userID, _ := c.Get("user_id")
+	logger.FromContext(c).Warn("Sensitive words in user content",
+		zap.String("content", kind),
+		zap.String("mode", words.Mode()),
+		zap.Any("user_id", userID),
+		zap.Any("matches", matches))
+
+	if words.Rejects() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         "Content contains prohibited terms",
+			"code":          "CONTENT_BLOCKED",
+			"blocked_terms": matches,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"trade_company/internal/models"
+	"trade_company/internal/wordfilter"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSensitiveWords(t *testing.T) {
+	const blocked = "保證獲利" // "Guaranteed profit"
+	for _, mode := range []string{wordfilter.ModeReject, wordfilter.ModeMask} {
+		t.Run(mode, func(t *testing.T) {
+			db := newTestDB(t, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+			cfg := testConfig(t)
+			words, err := wordfilter.New(mode, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			listingsH := &ListingsHandler{DB: db, Cfg: cfg, Words: words}
+			messages := &MessageHandler{DB: db, Cfg: cfg, Words: words}
+			leads := newTestLeadHandler(t, db, cfg)
+			leads.Words = words
+			seller := createTestUser(t, db, "seller")
+			buyer := createTestUser(t, db, "buyer")
+			listing := createTestListing(t, db, seller.ID)
+			as := func(user *models.User) *gin.Engine {
+				r := gin.New()
+				r.Use(asUser(user.ID))
+				r.POST("/listings", listingsH.Create)
+				r.PUT("/listings/:id", listingsH.Update)
+				r.POST("/listings/:id/leads", leads.ContactSeller)
+				r.POST("/messages", messages.Create)
+				return r
+			}
+
+			requests := []struct {
+				name   string
+				r      *gin.Engine
+				method string
+				target string
+				body   map[string]interface{}
+				field  string
+				stored func() string // The screened field as stored
+			}{
+				{name: "listing create", r: as(seller), method: http.MethodPost, target: "/listings",
+					body:  map[string]interface{}{"title": "Cafe for sale", "description": "Turnkey cafe, " + blocked, "price": 1000000},
+					field: "description",
+					stored: func() string {
+						var l models.Listing
+						db.Where("title = ?", "Cafe for sale").Limit(1).Find(&l)
+						return l.Description
+					}},
+				{name: "listing update", r: as(seller), method: http.MethodPut, target: fmt.Sprintf("/listings/%d", listing.ID),
+					body:  map[string]interface{}{"title": "Bakery " + blocked},
+					field: "title",
+					stored: func() string {
+						var l models.Listing
+						db.First(&l, listing.ID)
+						return l.Title
+					}},
+				{name: "lead", r: as(buyer), method: http.MethodPost, target: fmt.Sprintf("/listings/%d/leads", listing.ID),
+					body:  leadBody(map[string]interface{}{"message": "Is it really " + blocked + "?"}),
+					field: "message",
+					stored: func() string {
+						var l models.Lead
+						db.Limit(1).Find(&l)
+						return l.Message
+					}},
+				{name: "message", r: as(buyer), method: http.MethodPost, target: "/messages",
+					body:  map[string]interface{}{"receiver_id": seller.ID, "listing_id": listing.ID, "content": blocked + " they said"},
+					field: "content",
+					stored: func() string {
+						var m models.Message
+						db.Limit(1).Find(&m)
+						return m.Content
+					}},
+			}
+			for _, req := range requests {
+				w := serve(req.r, req.method, req.target, req.body)
+				stored := req.stored()
+				if mode == wordfilter.ModeReject {
+					body := decode(t, w)
+					terms := body["blocked_terms"].(map[string]interface{})
+					if w.Code != http.StatusBadRequest || body["code"] != "CONTENT_BLOCKED" ||
+						!reflect.DeepEqual(terms, map[string]interface{}{req.field: []interface{}{blocked}}) {
+						t.Errorf("%s: status %d: %v; want 400 CONTENT_BLOCKED for %s", req.name, w.Code, body, req.field)
+					}
+					if strings.Contains(stored, blocked) {
+						t.Errorf("%s: stored %q", req.name, stored)
+					}
+					continue
+				}
+				if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+					t.Errorf("%s: status %d: %s", req.name, w.Code, w.Body)
+				}
+				if strings.Contains(stored, blocked) || !strings.Contains(stored, "****") {
+					t.Errorf("%s: stored %q, want the term masked", req.name, stored)
+				}
+			}
+		})
+	}
+}
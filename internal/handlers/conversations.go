@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conversationRow is one thread as grouped by Conversations
+type conversationRow struct {
+	OtherID       uint
+	ListingID     *uint
+	LastMessageID uint
+	MessageCount  int64
+	UnreadCount   int64
+}
+
+// Conversations lists the caller's message threads, most recent first. A
+// thread is every message between the caller and one other user about one
+// listing (or about none), with its latest message and how many of its
+// messages the caller hasn't read.
+func (h *MessageHandler) Conversations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	p := pagination.Parse(c, pagination.Limits{Default: h.Cfg.MessagesDefaultPageSize, Max: h.Cfg.MessagesMaxPageSize})
+	threads := h.DB.Model(&models.Message{}).
+		Select(`CASE WHEN sender_id = ? THEN receiver_id ELSE sender_id END AS other_id,
+			listing_id,
+			MAX(id) AS last_message_id,
+			COUNT(*) AS message_count,
+			SUM(CASE WHEN receiver_id = ? AND is_read = ? THEN 1 ELSE 0 END) AS unread_count`, uid, uid, false).
+		Where("sender_id = ? OR receiver_id = ?", uid, uid).
+		Group("other_id, listing_id")
+
+	var total int64
+	if err := h.DB.Table("(?) AS threads", threads).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
+		return
+	}
+
+	var rows []conversationRow
+	if err := threads.Order("last_message_id DESC").Scopes(p.Scope()).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
+		return
+	}
+
+	messageIDs := make([]uint, len(rows))
+	userIDs := make([]uint, len(rows))
+	for i, row := range rows {
+		messageIDs[i] = row.LastMessageID
+		userIDs[i] = row.OtherID
+	}
+	var messages []models.Message
+	if len(messageIDs) > 0 {
+		if err := preloadMessage(h.DB.Where("id IN ?", messageIDs)).Find(&messages).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
+			return
+		}
+	}
+	latest := make(map[uint]*models.Message, len(messages))
+	for i := range messages {
+		latest[messages[i].ID] = &messages[i]
+	}
+	participants, err := h.participants(userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		item := gin.H{
+			"participant":    participants[row.OtherID],
+			"listing_id":     row.ListingID,
+			"message_count":  row.MessageCount,
+			"unread_count":   row.UnreadCount,
+			"latest_message": nil,
+		}
+		if m, ok := latest[row.LastMessageID]; ok {
+			item["latest_message"] = messageItem(m, uid)
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversations": result,
+		"pagination":    pagination.NewMeta(p, total),
+	})
+}
+
+// Conversation returns the caller's messages with one other user, optionally
+// only those about ?listing_id=. Pages go back in time from the newest
+// message with cursor/next_cursor, and each page is in chronological order
+// for rendering as a chat. Received messages on the page are marked read
+// unless ?markRead=false.
+func (h *MessageHandler) Conversation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	otherID64, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil || uint(otherID64) == uid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	otherID := uint(otherID64)
+
+	p, err := pagination.ParseCursor(c, pagination.Limits{Default: h.Cfg.MessagesDefaultPageSize, Max: h.Cfg.MessagesMaxPageSize})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor", "code": "INVALID_CURSOR"})
+		return
+	}
+
+	thread := h.DB.Model(&models.Message{}).
+		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)", uid, otherID, otherID, uid)
+	if v := c.Query("listing_id"); v != "" {
+		listingID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+			return
+		}
+		thread = thread.Where("listing_id = ?", listingID)
+	}
+
+	var messages []models.Message
+	if err := preloadMessage(thread).Scopes(p.Scope("id")).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+	// Only participants see a thread; an empty first page means there is none
+	if len(messages) == 0 && p.After == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return
+	}
+
+	ids := make([]uint, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+	meta, n := pagination.NewCursorMeta(p, ids)
+	messages = messages[:n]
+
+	if c.Query("markRead") != "false" {
+		if err := h.markReceivedRead(uid, messages); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages as read"})
+			return
+		}
+	}
+
+	participants, err := h.participants([]uint{otherID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+
+	// Fetched newest first; a chat reads oldest first
+	result := make([]gin.H, len(messages))
+	for i := range messages {
+		result[len(messages)-1-i] = messageItem(&messages[i], uid)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"participant": participants[otherID],
+		"messages":    result,
+		"pagination":  meta,
+	})
+}
+
+// markReceivedRead marks the unread messages among messages that userID
+// received as read, in the database and in the slice
+func (h *MessageHandler) markReceivedRead(userID uint, messages []models.Message) error {
+	var unread []uint
+	for _, m := range messages {
+		if m.ReceiverID == userID && !m.IsRead {
+			unread = append(unread, m.ID)
+		}
+	}
+	if len(unread) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if err := h.DB.Model(&models.Message{}).
+		Where("id IN ? AND receiver_id = ? AND is_read = ?", unread, userID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": now}).Error; err != nil {
+		return err
+	}
+	for i := range messages {
+		if messages[i].ReceiverID == userID && !messages[i].IsRead {
+			messages[i].IsRead = true
+			messages[i].ReadAt = &now
+		}
+	}
+	return nil
+}
+
+// participants loads the public summaries of the other users in threads, by ID
+func (h *MessageHandler) participants(ids []uint) (map[uint]gin.H, error) {
+	summaries := make(map[uint]gin.H, len(ids))
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+	var users []models.User
+	if err := h.DB.Select("id", "username", "first_name", "last_name", "company_name", "avatar_url").
+		Where("id IN ?", ids).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		summaries[u.ID] = gin.H{
+			"id":           u.ID,
+			"username":     u.Username,
+			"first_name":   u.FirstName,
+			"last_name":    u.LastName,
+			"company_name": u.CompanyName,
+			"avatar_url":   u.AvatarURL,
+		}
+	}
+	return summaries, nil
+}
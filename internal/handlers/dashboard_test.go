@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStartOfWeek(t *testing.T) {
+	taipei := time.FixedZone("CST", 8*3600)
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, taipei)
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{name: "monday midnight", at: monday, want: monday},
+		{name: "monday evening", at: monday.Add(20 * time.Hour), want: monday},
+		{name: "friday", at: time.Date(2026, 10, 16, 9, 30, 0, 0, taipei), want: monday},
+		{name: "sunday night", at: time.Date(2026, 10, 18, 23, 59, 0, 0, taipei), want: monday},
+		{name: "across a month", at: time.Date(2026, 10, 1, 12, 0, 0, 0, taipei), want: time.Date(2026, 9, 28, 0, 0, 0, 0, taipei)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startOfWeek(tt.at); !got.Equal(tt.want) {
+				t.Errorf("startOfWeek(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDashboardViewsThisWeek(t *testing.T) {
+	db := newTestDB(t, &models.Transaction{})
+	h := &UserHandler{DB: db}
+	owner := createTestUser(t, db, "seller")
+	other := createTestUser(t, db, "other")
+	active := createTestListing(t, db, owner.ID)
+	sold := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Status = models.ListingStatusSold })
+	deleted := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Status = models.ListingStatusDeleted })
+	notMine := createTestListing(t, db, other.ID)
+
+	weekStart := startOfWeek(time.Now())
+	for _, row := range []models.ListingViewDaily{
+		{ListingID: active.ID, ViewDate: weekStart, Views: 5},
+		{ListingID: active.ID, ViewDate: weekStart.AddDate(0, 0, 1), Views: 2},
+		{ListingID: active.ID, ViewDate: weekStart.AddDate(0, 0, -1), Views: 100}, // last week
+		{ListingID: sold.ID, ViewDate: weekStart, Views: 3},
+		{ListingID: deleted.ID, ViewDate: weekStart, Views: 50},
+		{ListingID: notMine.ID, ViewDate: weekStart, Views: 70},
+	} {
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The lifetime counter is not what the dashboard shows
+	db.Model(active).Update("view_count", 1000)
+
+	r := gin.New()
+	r.GET("/user/dashboard", asUser(owner.ID), h.Dashboard)
+	w := serve(r, http.MethodGet, "/user/dashboard", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	stats := decode(t, w)["dashboard"].(map[string]interface{})
+	if got := stats["total_views"]; got != float64(10) {
+		t.Errorf("total_views = %v, want 10 (5 + 2 + 3 this week)", got)
+	}
+}
+
+// dashboardTables are the tables every dashboard section reads, besides newTestDB's
+var dashboardTables = []interface{}{&models.Transaction{}, &models.ListingConfirmation{}, &models.LeadTemplate{}, &models.Announcement{}}
+
+func TestDashboardShape(t *testing.T) {
+	db := newTestDB(t, dashboardTables...)
+	h := &UserHandler{DB: db}
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, seller.ID)
+	createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Status = models.ListingStatusSold })
+	createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Status = models.ListingStatusDeleted })
+	db.Create(&models.Lead{SenderID: buyer.ID, ReceiverID: seller.ID, ListingID: &listing.ID, Subject: "Hi", Message: "Hello"})
+	db.Create(&models.Lead{SenderID: buyer.ID, ReceiverID: seller.ID, Subject: "Spam", Message: "Buy now", IsSpam: true})
+	db.Create(&models.Message{SenderID: buyer.ID, ReceiverID: seller.ID, Content: "Still available?"})
+	db.Create(&models.Favorite{UserID: seller.ID, ListingID: listing.ID})
+	db.Create(&models.Transaction{ListingID: listing.ID, BuyerID: buyer.ID, SellerID: seller.ID, Amount: 500, Status: models.TransactionStatusPending})
+
+	r := gin.New()
+	r.GET("/user/dashboard", asUser(seller.ID), h.Dashboard)
+	w := serve(r, http.MethodGet, "/user/dashboard", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	if errs, ok := body["errors"].([]interface{}); !ok || len(errs) != 0 {
+		t.Errorf("errors %v, want an empty list", body["errors"])
+	}
+	if _, ok := body["announcements"].([]interface{}); !ok {
+		t.Errorf("announcements %v, want a list", body["announcements"])
+	}
+
+	stats := body["dashboard"].(map[string]interface{})
+	counts := map[string]float64{
+		"active_listings": 1, "total_views": 0, "unread_messages": 1, "unread_leads": 1,
+		"pending_transactions": 1, "favorites": 1,
+	}
+	for key, want := range counts {
+		if stats[key] != want {
+			t.Errorf("%s = %v, want %v", key, stats[key], want)
+		}
+	}
+	byStatus, _ := stats["listings_by_status"].(map[string]interface{})
+	wantByStatus := map[models.ListingStatus]float64{
+		models.ListingStatusActive: 1, models.ListingStatusInactive: 0, models.ListingStatusSold: 1, models.ListingStatusPendingDelete: 0,
+	}
+	if len(byStatus) != len(wantByStatus) {
+		t.Errorf("listings_by_status %v, want %v", byStatus, wantByStatus)
+	}
+	for status, want := range wantByStatus {
+		if byStatus[string(status)] != want {
+			t.Errorf("listings_by_status[%s] = %v, want %v", status, byStatus[string(status)], want)
+		}
+	}
+	lists := map[string]int{"recent_leads": 1, "recent_transactions": 1, "notifications": 2, "top_lead_templates": 0}
+	for key, want := range lists {
+		items, ok := stats[key].([]interface{})
+		if !ok || len(items) != want {
+			t.Errorf("%s = %v, want a list of %d", key, stats[key], want)
+		}
+	}
+	if lead := stats["recent_leads"].([]interface{})[0].(map[string]interface{}); lead["listing_title"] != listing.Title || lead["sender_name"] == nil {
+		t.Errorf("recent lead %v", lead)
+	}
+	if txn := stats["recent_transactions"].([]interface{})[0].(map[string]interface{}); txn["role"] != "seller" || txn["amount"] != float64(500) {
+		t.Errorf("recent transaction %v", txn)
+	}
+}
+
+func TestDashboardPartialFailure(t *testing.T) {
+	// Without the transactions table the two transaction sections fail
+	tables := []interface{}{&models.ListingConfirmation{}, &models.LeadTemplate{}, &models.Announcement{}}
+	db := newTestDB(t, tables...)
+	mr := miniredis.RunT(t)
+	h := &UserHandler{DB: db, Cache: redisclient.NewCacheService(redis.NewClient(&redis.Options{Addr: mr.Addr()}))}
+	seller := createTestUser(t, db, "seller")
+	createTestListing(t, db, seller.ID)
+
+	r := gin.New()
+	r.GET("/user/dashboard", asUser(seller.ID), h.Dashboard)
+	w := serve(r, http.MethodGet, "/user/dashboard", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	if errs := body["errors"].([]interface{}); len(errs) != 2 || errs[0] != "pending_transactions" || errs[1] != "recent_transactions" {
+		t.Errorf("errors %v, want the transaction sections", errs)
+	}
+	stats := body["dashboard"].(map[string]interface{})
+	if v, ok := stats["recent_transactions"]; !ok || v != nil {
+		t.Errorf("recent_transactions %v, want null", v)
+	}
+	if stats["active_listings"] != float64(1) {
+		t.Errorf("active_listings %v, want 1 despite the failure", stats["active_listings"])
+	}
+	// A partial dashboard is not cached, so the next request retries
+	if mr.Exists(fmt.Sprintf("%s%d", redisclient.UserDashboardKey, seller.ID)) {
+		t.Error("partial dashboard was cached")
+	}
+
+	db.AutoMigrate(&models.Transaction{})
+	serve(r, http.MethodGet, "/user/dashboard", nil)
+	if !mr.Exists(fmt.Sprintf("%s%d", redisclient.UserDashboardKey, seller.ID)) {
+		t.Error("complete dashboard was not cached")
+	}
+}
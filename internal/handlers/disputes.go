@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// disputeLimits bounds the dispute list pages
+var disputeLimits = pagination.Limits{Default: 20, Max: 100}
+
+var errDisputeResolved = errors.New("dispute is already resolved")
+
+// DisputeHandler lets the parties to a transaction dispute it and admins settle it
+type DisputeHandler struct {
+	DB *gorm.DB
+}
+
+type openDisputeRequest struct {
+	Reason string `json:"reason" binding:"required,max=5000"`
+}
+
+type resolveDisputeRequest struct {
+	Resolution string `json:"resolution" binding:"required,max=5000"`
+	// Optional outcome applied to the transaction, e.g. "cancelled" or "refunded"
+	TransactionStatus models.TransactionStatus `json:"transaction_status"`
+}
+
+// Open disputes a transaction the caller bought or sold. A transaction has at
+// most one open dispute, and cancelled or refunded transactions can't be disputed.
+func (h *DisputeHandler) Open(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	txnID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	var req openDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+
+	// Non-parties get a 404 so transaction IDs can't be probed
+	var txn models.Transaction
+	if err := h.DB.Where("id = ? AND (buyer_id = ? OR seller_id = ?)", txnID, uid, uid).First(&txn).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load transaction"})
+		return
+	}
+	if txn.Status.Final() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Closed transactions can't be disputed"})
+		return
+	}
+
+	dispute := models.TransactionDispute{
+		TransactionID: txn.ID,
+		OpenedByID:    uid,
+		Reason:        req.Reason,
+		Status:        models.DisputeStatusOpen,
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the transaction row so two parties can't open disputes at once
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Transaction{}, txn.ID).Error; err != nil {
+			return err
+		}
+		var open int64
+		if err := tx.Model(&models.TransactionDispute{}).
+			Where("transaction_id = ? AND status = ?", txn.ID, models.DisputeStatusOpen).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return models.ErrTransactionDisputed
+		}
+		return tx.Create(&dispute).Error
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrTransactionDisputed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Transaction already has an open dispute"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open dispute"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"dispute": dispute})
+}
+
+// List returns disputes on the caller's transactions, or every dispute for an
+// admin. ?status=open|resolved filters them.
+func (h *DisputeHandler) List(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	query := h.DB.Model(&models.TransactionDispute{})
+	if !isAdmin(h.DB, uid) {
+		query = query.Where("transaction_id IN (?)",
+			h.DB.Model(&models.Transaction{}).Select("id").Where("buyer_id = ? OR seller_id = ?", uid, uid))
+	}
+	switch status := c.Query("status"); status {
+	case "":
+	case models.DisputeStatusOpen, models.DisputeStatusResolved:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open or resolved"})
+		return
+	}
+
+	p := pagination.Parse(c, disputeLimits)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch disputes"})
+		return
+	}
+
+	var disputes []models.TransactionDispute
+	if err := query.
+		Preload("Transaction").
+		Preload("OpenedBy").
+		Order("created_at desc").
+		Scopes(p.Scope()).
+		Find(&disputes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch disputes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"disputes":   disputes,
+		"pagination": pagination.NewMeta(p, total),
+	})
+}
+
+// Resolve settles an open dispute with the admin's note and, optionally, moves
+// the transaction to a new status. The move must still be a legal transition;
+// it is the only status change allowed while the dispute is open.
+func (h *DisputeHandler) Resolve(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	adminID := userID.(uint)
+
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dispute ID"})
+		return
+	}
+
+	var req resolveDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Resolution = strings.TrimSpace(req.Resolution)
+	if req.Resolution == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Resolution is required"})
+		return
+	}
+
+	var dispute models.TransactionDispute
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, disputeID).Error; err != nil {
+			return err
+		}
+		if dispute.Status != models.DisputeStatusOpen {
+			return errDisputeResolved
+		}
+
+		if req.TransactionStatus != "" {
+			var txn models.Transaction
+			if err := tx.First(&txn, dispute.TransactionID).Error; err != nil {
+				return err
+			}
+			if err := models.TransitionTransaction(tx, &txn, req.TransactionStatus, true); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		dispute.Status = models.DisputeStatusResolved
+		dispute.Resolution = req.Resolution
+		dispute.ResolvedByID = &adminID
+		dispute.ResolvedAt = &now
+		if err := tx.Model(&dispute).Updates(map[string]interface{}{
+			"status":         dispute.Status,
+			"resolution":     dispute.Resolution,
+			"resolved_by_id": adminID,
+			"resolved_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(gin.H{
+			"dispute_id":         dispute.ID,
+			"transaction_id":     dispute.TransactionID,
+			"transaction_status": req.TransactionStatus,
+		})
+		return tx.Create(&models.AuditLog{UserID: &adminID, Event: "dispute_resolved", Details: string(details)}).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispute not found"})
+		return
+	case errors.Is(err, errDisputeResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": "Dispute is already resolved"})
+		return
+	case errors.Is(err, models.ErrTransactionTransition):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve dispute"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dispute": dispute})
+}
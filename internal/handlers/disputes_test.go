@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/middleware"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// disputeTest has a buyer, a seller and a pending transaction between them,
+// plus an admin and a user with no part in it
+type disputeTest struct {
+	db                             *gorm.DB
+	buyer, seller, stranger, admin *models.User
+	txn                            *models.Transaction
+}
+
+func newDisputeTest(t *testing.T) *disputeTest {
+	t.Helper()
+	db := newTestDB(t, &models.Transaction{}, &models.TransactionDispute{}, &models.AuditLog{})
+	dt := &disputeTest{
+		db:       db,
+		buyer:    createTestUser(t, db, "buyer"),
+		seller:   createTestUser(t, db, "seller"),
+		stranger: createTestUser(t, db, "stranger"),
+		admin:    createTestUser(t, db, "admin"),
+	}
+	db.Model(dt.admin).Update("role", models.RoleAdmin)
+	listing := createTestListing(t, db, dt.seller.ID)
+	dt.txn = &models.Transaction{ListingID: listing.ID, BuyerID: dt.buyer.ID, SellerID: dt.seller.ID, Amount: 1000, Status: models.TransactionStatusPending}
+	if err := db.Create(dt.txn).Error; err != nil {
+		t.Fatal(err)
+	}
+	return dt
+}
+
+// as routes requests from user as the router does
+func (dt *disputeTest) as(user *models.User) *gin.Engine {
+	h := &DisputeHandler{DB: dt.db}
+	txnH := &TransactionHandler{DB: dt.db}
+	r := gin.New()
+	r.Use(asUser(user.ID))
+	r.POST("/transactions/:id/disputes", h.Open)
+	r.PATCH("/transactions/:id/status", txnH.UpdateStatus)
+	r.GET("/disputes", h.List)
+	r.POST("/admin/disputes/:id/resolve", middleware.RequireAdmin(dt.db), h.Resolve)
+	return r
+}
+
+// transactionStatus reloads the disputed transaction's status
+func (dt *disputeTest) transactionStatus(t *testing.T) models.TransactionStatus {
+	t.Helper()
+	var txn models.Transaction
+	dt.db.First(&txn, dt.txn.ID)
+	return txn.Status
+}
+
+func TestOpenDispute(t *testing.T) {
+	dt := newDisputeTest(t)
+	target := fmt.Sprintf("/transactions/%d/disputes", dt.txn.ID)
+
+	steps := []struct {
+		name   string
+		user   *models.User
+		body   map[string]interface{}
+		status int
+	}{
+		{name: "not a party", user: dt.stranger, body: map[string]interface{}{"reason": "curious"}, status: http.StatusNotFound},
+		{name: "admin is not a party", user: dt.admin, body: map[string]interface{}{"reason": "checking"}, status: http.StatusNotFound},
+		{name: "blank reason", user: dt.buyer, body: map[string]interface{}{"reason": "   "}, status: http.StatusBadRequest},
+		{name: "buyer opens", user: dt.buyer, body: map[string]interface{}{"reason": "Inventory was missing"}, status: http.StatusCreated},
+		{name: "one open dispute at a time", user: dt.seller, body: map[string]interface{}{"reason": "Buyer stopped paying"}, status: http.StatusConflict},
+	}
+	for _, step := range steps {
+		w := serve(dt.as(step.user), http.MethodPost, target, step.body)
+		if w.Code != step.status {
+			t.Fatalf("%s: status %d, want %d: %s", step.name, w.Code, step.status, w.Body)
+		}
+	}
+
+	var disputes []models.TransactionDispute
+	dt.db.Find(&disputes)
+	if len(disputes) != 1 || disputes[0].OpenedByID != dt.buyer.ID || disputes[0].Status != models.DisputeStatusOpen {
+		t.Errorf("disputes %+v", disputes)
+	}
+
+	// Cancelled and refunded transactions are closed to disputes
+	closed := &models.Transaction{ListingID: dt.txn.ListingID, BuyerID: dt.buyer.ID, SellerID: dt.seller.ID, Amount: 1, Status: models.TransactionStatusCancelled}
+	dt.db.Create(closed)
+	w := serve(dt.as(dt.seller), http.MethodPost, fmt.Sprintf("/transactions/%d/disputes", closed.ID), map[string]interface{}{"reason": "late"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("dispute on a cancelled transaction: status %d", w.Code)
+	}
+}
+
+func TestDisputePausesTransitions(t *testing.T) {
+	dt := newDisputeTest(t)
+	w := serve(dt.as(dt.buyer), http.MethodPost, fmt.Sprintf("/transactions/%d/disputes", dt.txn.ID), map[string]interface{}{"reason": "Wrong figures"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("open status %d: %s", w.Code, w.Body)
+	}
+	disputeID := uint(decode(t, w)["dispute"].(map[string]interface{})["id"].(float64))
+	status := fmt.Sprintf("/transactions/%d/status", dt.txn.ID)
+
+	// A transition that is legal otherwise waits for the dispute
+	w = serve(dt.as(dt.buyer), http.MethodPatch, status, map[string]interface{}{"status": "paid"})
+	if w.Code != http.StatusConflict || decode(t, w)["code"] != "TRANSACTION_DISPUTED" {
+		t.Fatalf("paying during a dispute: status %d: %s", w.Code, w.Body)
+	}
+	if got := dt.transactionStatus(t); got != models.TransactionStatusPending {
+		t.Fatalf("transaction moved to %s during the dispute", got)
+	}
+
+	// Resolving without an outcome lifts the pause
+	resolve := fmt.Sprintf("/admin/disputes/%d/resolve", disputeID)
+	if w := serve(dt.as(dt.admin), http.MethodPost, resolve, map[string]interface{}{"resolution": "Figures corrected"}); w.Code != http.StatusOK {
+		t.Fatalf("resolve status %d: %s", w.Code, w.Body)
+	}
+	if w := serve(dt.as(dt.buyer), http.MethodPatch, status, map[string]interface{}{"status": "paid"}); w.Code != http.StatusOK {
+		t.Errorf("paying after resolution: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestResolveDispute(t *testing.T) {
+	dt := newDisputeTest(t)
+	dispute := models.TransactionDispute{TransactionID: dt.txn.ID, OpenedByID: dt.seller.ID, Reason: "No payment", Status: models.DisputeStatusOpen}
+	dt.db.Create(&dispute)
+	resolve := fmt.Sprintf("/admin/disputes/%d/resolve", dispute.ID)
+
+	steps := []struct {
+		name   string
+		user   *models.User
+		target string
+		body   map[string]interface{}
+		status int
+	}{
+		{name: "party can't resolve", user: dt.seller, target: resolve, body: map[string]interface{}{"resolution": "I win"}, status: http.StatusForbidden},
+		{name: "unknown dispute", user: dt.admin, target: "/admin/disputes/999/resolve", body: map[string]interface{}{"resolution": "x"}, status: http.StatusNotFound},
+		{name: "note required", user: dt.admin, target: resolve, body: map[string]interface{}{"resolution": " "}, status: http.StatusBadRequest},
+		// The outcome must still be a legal move for the transaction
+		{name: "illegal outcome", user: dt.admin, target: resolve, body: map[string]interface{}{"resolution": "Refund", "transaction_status": "refunded"}, status: http.StatusUnprocessableEntity},
+		{name: "cancel", user: dt.admin, target: resolve, body: map[string]interface{}{"resolution": "Buyer never paid", "transaction_status": "cancelled"}, status: http.StatusOK},
+		{name: "already resolved", user: dt.admin, target: resolve, body: map[string]interface{}{"resolution": "Again"}, status: http.StatusConflict},
+	}
+	for _, step := range steps {
+		w := serve(dt.as(step.user), http.MethodPost, step.target, step.body)
+		if w.Code != step.status {
+			t.Fatalf("%s: status %d, want %d: %s", step.name, w.Code, step.status, w.Body)
+		}
+	}
+
+	dt.db.First(&dispute, dispute.ID)
+	if dispute.Status != models.DisputeStatusResolved || dispute.Resolution != "Buyer never paid" ||
+		dispute.ResolvedByID == nil || *dispute.ResolvedByID != dt.admin.ID || dispute.ResolvedAt == nil {
+		t.Errorf("dispute %+v", dispute)
+	}
+	if got := dt.transactionStatus(t); got != models.TransactionStatusCancelled {
+		t.Errorf("transaction %s, want cancelled", got)
+	}
+	var audits int64
+	dt.db.Model(&models.AuditLog{}).Where("event = ? AND user_id = ?", "dispute_resolved", dt.admin.ID).Count(&audits)
+	if audits != 1 {
+		t.Errorf("%d audit logs, want 1", audits)
+	}
+}
+
+func TestListDisputes(t *testing.T) {
+	dt := newDisputeTest(t)
+	other := &models.Transaction{ListingID: dt.txn.ListingID, BuyerID: dt.stranger.ID, SellerID: dt.seller.ID, Amount: 1, Status: models.TransactionStatusPending}
+	dt.db.Create(other)
+	dt.db.Create(&models.TransactionDispute{TransactionID: dt.txn.ID, OpenedByID: dt.buyer.ID, Reason: "a", Status: models.DisputeStatusOpen})
+	dt.db.Create(&models.TransactionDispute{TransactionID: other.ID, OpenedByID: dt.stranger.ID, Reason: "b", Status: models.DisputeStatusResolved})
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		query  string
+		status int
+		want   int
+	}{
+		{name: "buyer sees their transaction's", user: dt.buyer, status: http.StatusOK, want: 1},
+		{name: "seller is party to both", user: dt.seller, status: http.StatusOK, want: 2},
+		{name: "admin sees all", user: dt.admin, status: http.StatusOK, want: 2},
+		{name: "status filter", user: dt.admin, query: "?status=open", status: http.StatusOK, want: 1},
+		{name: "unknown status", user: dt.admin, query: "?status=closed", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(dt.as(tt.user), http.MethodGet, "/disputes"+tt.query, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := decode(t, w)["disputes"].([]interface{}); len(got) != tt.want {
+				t.Errorf("%d disputes, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SendGrid signed event webhook headers
+const (
+	sendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	sendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// recordedEmailEvents are the SendGrid events kept; opens, clicks and
+// processing notices are ignored
+var recordedEmailEvents = map[string]bool{
+	models.EmailEventDelivered:    true,
+	models.EmailEventBounce:       true,
+	models.EmailEventDropped:      true,
+	models.EmailEventDeferred:     true,
+	models.EmailEventSpamReport:   true,
+	models.EmailEventUnsubscribed: true,
+}
+
+// EmailWebhookHandler records delivery events posted by SendGrid
+type EmailWebhookHandler struct {
+	DB        *gorm.DB
+	publicKey *ecdsa.PublicKey // nil disables the webhook
+}
+
+// NewEmailWebhookHandler parses the webhook verification key. An empty key
+// leaves the webhook disabled rather than accepting unsigned events.
+func NewEmailWebhookHandler(db *gorm.DB, publicKey string) (*EmailWebhookHandler, error) {
+	h := &EmailWebhookHandler{DB: db}
+	if publicKey == "" {
+		return h, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return h, fmt.Errorf("failed to decode SendGrid webhook public key: %w", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return h, fmt.Errorf("failed to parse SendGrid webhook public key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return h, errors.New("SendGrid webhook public key is not an ECDSA key")
+	}
+	h.publicKey = ecKey
+	return h, nil
+}
+
+// sendGridEvent is the subset of a SendGrid event webhook entry we use
+type sendGridEvent struct {
+	Email       string `json:"email"`
+	Event       string `json:"event"`
+	Type        string `json:"type"` // Bounce classification: "bounce" or "blocked"
+	Reason      string `json:"reason"`
+	Timestamp   int64  `json:"timestamp"`
+	SGEventID   string `json:"sg_event_id"`
+	SGMessageID string `json:"sg_message_id"`
+}
+
+// verifySendGridSignature checks the ECDSA signature SendGrid computes over the
+// timestamp header followed by the raw request body
+func verifySendGridSignature(key *ecdsa.PublicKey, signature, timestamp string, body []byte) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || timestamp == "" {
+		return false
+	}
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	return ecdsa.VerifyASN1(key, digest[:], sig)
+}
+
+// SendGrid records delivered, bounce, dropped, deferred, spam report and
+// unsubscribe events. Hard bounces and spam reports mark the recipient's account
+// undeliverable. Events are deduplicated on sg_event_id, so redeliveries are safe.
+func (h *EmailWebhookHandler) SendGrid(c *gin.Context) {
+	if h.publicKey == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Email webhook is not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if !verifySendGridSignature(h.publicKey, c.GetHeader(sendGridSignatureHeader), c.GetHeader(sendGridTimestampHeader), body) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	var payload []sendGridEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event payload"})
+		return
+	}
+
+	events := make([]models.EmailEvent, 0, len(payload))
+	emails := make([]string, 0, len(payload))
+	for _, e := range payload {
+		if !recordedEmailEvents[e.Event] || e.SGEventID == "" || e.Email == "" {
+			continue
+		}
+		email := strings.ToLower(strings.TrimSpace(e.Email))
+		events = append(events, models.EmailEvent{
+			Email:             email,
+			Event:             e.Event,
+			BounceType:        e.Type,
+			Reason:            truncateUTF8(e.Reason, 500),
+			ProviderEventID:   e.SGEventID,
+			ProviderMessageID: e.SGMessageID,
+			OccurredAt:        time.Unix(e.Timestamp, 0),
+		})
+		emails = append(emails, email)
+	}
+	if len(events) == 0 {
+		c.JSON(http.StatusOK, gin.H{"recorded": 0})
+		return
+	}
+
+	var users []models.User
+	if err := h.DB.Select("id", "email").Where("email IN ?", emails).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record email events"})
+		return
+	}
+	userIDs := make(map[string]uint, len(users))
+	for _, u := range users {
+		userIDs[strings.ToLower(u.Email)] = u.ID
+	}
+
+	var undeliverable []uint
+	for i := range events {
+		if id, ok := userIDs[events[i].Email]; ok {
+			events[i].UserID = &id
+			if events[i].MakesUndeliverable() {
+				undeliverable = append(undeliverable, id)
+			}
+		}
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error; err != nil {
+			return err
+		}
+		if len(undeliverable) == 0 {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("id IN ?", undeliverable).Update("email_undeliverable", true).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record email events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recorded": len(events)})
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a UTF-8 character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newSendGridKey returns a webhook signing key and its public half as SendGrid
+// shows it in the settings page
+func newSendGridKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, base64.StdEncoding.EncodeToString(der)
+}
+
+// newTestEmailWebhook routes the SendGrid webhook verified with publicKey
+func newTestEmailWebhook(t *testing.T, db *gorm.DB, publicKey string) *gin.Engine {
+	t.Helper()
+	h, err := NewEmailWebhookHandler(db, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.POST("/webhooks/sendgrid", h.SendGrid)
+	return r
+}
+
+// sendGridSignature signs body as SendGrid does
+func sendGridSignature(t *testing.T, key *ecdsa.PrivateKey, timestamp string, body []byte) string {
+	t.Helper()
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// postSendGridEvents delivers body with the given signature headers
+func postSendGridEvents(r http.Handler, signature, timestamp string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/sendgrid", bytes.NewReader(body))
+	req.Header.Set(sendGridSignatureHeader, signature)
+	req.Header.Set(sendGridTimestampHeader, timestamp)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSendGridWebhookSignature(t *testing.T) {
+	key, publicKey := newSendGridKey(t)
+	otherKey, _ := newSendGridKey(t)
+	body := []byte(`[{"email":"seller@example.com","event":"delivered","timestamp":1700000000,"sg_event_id":"evt-1"}]`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	tests := []struct {
+		name      string
+		publicKey string
+		signature string
+		timestamp string
+		body      []byte
+		status    int
+	}{
+		{name: "valid signature", publicKey: publicKey, signature: sendGridSignature(t, key, ts, body), timestamp: ts, body: body, status: http.StatusOK},
+		{name: "signed by another key", publicKey: publicKey, signature: sendGridSignature(t, otherKey, ts, body), timestamp: ts, body: body, status: http.StatusUnauthorized},
+		{name: "tampered body", publicKey: publicKey, signature: sendGridSignature(t, key, ts, body), timestamp: ts,
+			body: bytes.Replace(body, []byte("delivered"), []byte("bounce"), 1), status: http.StatusUnauthorized},
+		{name: "different timestamp", publicKey: publicKey, signature: sendGridSignature(t, key, ts, body), timestamp: ts + "1", body: body, status: http.StatusUnauthorized},
+		{name: "missing timestamp", publicKey: publicKey, signature: sendGridSignature(t, key, "", body), body: body, status: http.StatusUnauthorized},
+		{name: "signature not base64", publicKey: publicKey, signature: "not base64!", timestamp: ts, body: body, status: http.StatusUnauthorized},
+		{name: "unsigned", publicKey: publicKey, timestamp: ts, body: body, status: http.StatusUnauthorized},
+		{name: "webhook not configured", signature: sendGridSignature(t, key, ts, body), timestamp: ts, body: body, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.EmailEvent{})
+			createTestUser(t, db, "seller")
+			r := newTestEmailWebhook(t, db, tt.publicKey)
+
+			if w := postSendGridEvents(r, tt.signature, tt.timestamp, tt.body); w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var stored int64
+			db.Model(&models.EmailEvent{}).Count(&stored)
+			if wantStored := tt.status == http.StatusOK; (stored == 1) != wantStored {
+				t.Errorf("%d events stored", stored)
+			}
+		})
+	}
+}
+
+func TestNewEmailWebhookHandlerRejectsBadKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string]string{
+		"not base64": "not base64!",
+		"not a key":  base64.StdEncoding.EncodeToString([]byte("hello")),
+		"RSA key":    base64.StdEncoding.EncodeToString(rsaDER),
+	}
+	for name, key := range keys {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewEmailWebhookHandler(nil, key); err == nil {
+				t.Error("key accepted")
+			}
+		})
+	}
+}
+
+func TestSendGridWebhookEvents(t *testing.T) {
+	tests := []struct {
+		name              string
+		event             string
+		wantRecorded      int
+		wantUndeliverable bool
+	}{
+		{name: "delivered", event: `"event":"delivered"`, wantRecorded: 1},
+		{name: "hard bounce", event: `"event":"bounce","type":"bounce","reason":"550 5.1.1 The email account does not exist"`, wantRecorded: 1, wantUndeliverable: true},
+		{name: "blocked bounce", event: `"event":"bounce","type":"blocked","reason":"550 5.7.1 Blocked by policy"`, wantRecorded: 1},
+		{name: "spam report", event: `"event":"spamreport"`, wantRecorded: 1, wantUndeliverable: true},
+		{name: "deferred", event: `"event":"deferred","reason":"421 Try again later"`, wantRecorded: 1},
+		{name: "open is ignored", event: `"event":"open"`, wantRecorded: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.EmailEvent{})
+			user := createTestUser(t, db, "seller")
+			key, publicKey := newSendGridKey(t)
+			r := newTestEmailWebhook(t, db, publicKey)
+
+			// SendGrid posts a batch; the address may differ in case from the account's
+			body := []byte(fmt.Sprintf(`[{"email":"SELLER@example.com",%s,"timestamp":1700000000,"sg_event_id":"evt-1","sg_message_id":"msg-1"}]`, tt.event))
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			sig := sendGridSignature(t, key, ts, body)
+
+			// A redelivered batch is recorded once
+			for i := 0; i < 2; i++ {
+				w := postSendGridEvents(r, sig, ts, body)
+				if w.Code != http.StatusOK || decode(t, w)["recorded"] != float64(tt.wantRecorded) {
+					t.Fatalf("delivery %d: status %d: %s", i+1, w.Code, w.Body)
+				}
+			}
+
+			var events []models.EmailEvent
+			db.Find(&events)
+			if len(events) != tt.wantRecorded {
+				t.Fatalf("%d events stored, want %d", len(events), tt.wantRecorded)
+			}
+			if tt.wantRecorded == 1 {
+				e := events[0]
+				if e.UserID == nil || *e.UserID != user.ID || e.Email != "seller@example.com" || e.ProviderMessageID != "msg-1" {
+					t.Errorf("event %+v", e)
+				}
+			}
+			var stored models.User
+			db.First(&stored, user.ID)
+			if stored.EmailUndeliverable != tt.wantUndeliverable {
+				t.Errorf("email undeliverable %v, want %v", stored.EmailUndeliverable, tt.wantUndeliverable)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUnknownEnumValuesRejected(t *testing.T) {
+	db := newTestDB(t, &models.Transaction{}, &models.TransactionDispute{}, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+	cfg := testConfig(t)
+	listings := &ListingsHandler{DB: db, Cfg: cfg}
+	transactions := &TransactionHandler{DB: db}
+	leads := newTestLeadHandler(t, db, cfg)
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, seller.ID)
+	txn := &models.Transaction{ListingID: listing.ID, BuyerID: buyer.ID, SellerID: seller.ID, Amount: 1, Status: models.TransactionStatusPending}
+	db.Create(txn)
+
+	as := func(user *models.User) *gin.Engine {
+		r := gin.New()
+		r.Use(asUser(user.ID))
+		r.PUT("/listings/:id", listings.Update)
+		r.PATCH("/transactions/:id/status", transactions.UpdateStatus)
+		r.POST("/listings/:id/leads", leads.ContactSeller)
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		method string
+		target string
+		body   map[string]interface{}
+		error  string
+	}{
+		{name: "listing status typo", user: seller, method: http.MethodPut, target: fmt.Sprintf("/listings/%d", listing.ID),
+			body: map[string]interface{}{"status": "active"}, error: `invalid listing status "active"`},
+		{name: "transaction status typo", user: buyer, method: http.MethodPatch, target: fmt.Sprintf("/transactions/%d/status", txn.ID),
+			body: map[string]interface{}{"status": "Paid"}, error: `invalid transaction status "Paid"`},
+		{name: "inquiry type typo", user: buyer, method: http.MethodPost, target: fmt.Sprintf("/listings/%d/leads", listing.ID),
+			body: leadBody(map[string]interface{}{"inquiry_type": "pricing"}), error: `invalid inquiry type "pricing"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(as(tt.user), tt.method, tt.target, tt.body)
+			if w.Code != http.StatusBadRequest || decode(t, w)["error"] != tt.error {
+				t.Errorf("status %d: %s, want 400 with %s", w.Code, w.Body, tt.error)
+			}
+		})
+	}
+
+	// A valid status the owner may not set through an update is a field error
+	w := serve(as(seller), http.MethodPut, fmt.Sprintf("/listings/%d", listing.ID), map[string]interface{}{"status": string(models.ListingStatusDeleted)})
+	if fields, _ := decode(t, w)["fields"].(map[string]interface{}); w.Code != http.StatusBadRequest || fields["status"] == nil {
+		t.Errorf("setting deleted: status %d: %s", w.Code, w.Body)
+	}
+
+	var stored models.Listing
+	db.First(&stored, listing.ID)
+	var storedTxn models.Transaction
+	db.First(&storedTxn, txn.ID)
+	var leadCount int64
+	db.Model(&models.Lead{}).Count(&leadCount)
+	if stored.Status != models.ListingStatusActive || storedTxn.Status != models.TransactionStatusPending || leadCount != 0 {
+		t.Errorf("listing %q, transaction %q, %d leads after rejected requests", stored.Status, storedTxn.Status, leadCount)
+	}
+}
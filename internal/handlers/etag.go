@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondWithETag writes a JSON response with a strong ETag over its body and
+// answers 304 when the client already has it. Responses are per-user, so they
+// are marked private and must be revalidated.
+func respondWithETag(c *gin.Context, status int, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	writeWithETag(c, status, body, "private, no-cache")
+}
+
+// respondPublicWithETag writes an encoded JSON body that is the same for every
+// client. Browsers and shared caches may reuse it for maxAge, then revalidate
+// with If-None-Match.
+func respondPublicWithETag(c *gin.Context, body []byte, maxAge time.Duration) {
+	writeWithETag(c, http.StatusOK, body, fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+}
+
+func writeWithETag(c *gin.Context, status int, body []byte, cacheControl string) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header names etag. The header
+// may list several tags, and caches may send them weak (W/"...").
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportHolder is who an export counts against in the export gate: the user,
+// or the client IP for anonymous requests
+func exportHolder(c *gin.Context) string {
+	if uid, ok := c.Get("user_id"); ok {
+		if id, ok := uid.(uint); ok && id != 0 {
+			return fmt.Sprintf("user:%d", id)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// respondExportBusy writes the 429 for an export turned away by the gate
+func respondExportBusy(c *gin.Context, busy *redisclient.ExportBusyError) {
+	eta := int(math.Ceil(busy.ETA.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(eta))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       busy.Error(),
+		"code":        "EXPORT_BUSY",
+		"position":    busy.Position,
+		"eta_seconds": eta,
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+	"trade_company/internal/storage"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDownloadImagesExportGate(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	cfg.PublicUploadDir = t.TempDir()
+	mr := miniredis.RunT(t)
+	gate := redisclient.NewExportGate(redis.NewClient(&redis.Options{Addr: mr.Addr()}), 1, time.Minute)
+	h := &ListingsHandler{DB: db, Cfg: cfg, Storage: storage.New(cfg), Exports: gate}
+	owner := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, owner.ID)
+	db.Create(&models.Image{ListingID: listing.ID, Filename: "a.jpg", URL: "/uploads/a.jpg", ModerationStatus: models.ImageModerationApproved})
+	os.WriteFile(filepath.Join(cfg.PublicUploadDir, "a.jpg"), []byte("a"), 0o644)
+
+	as := func(viewer uint) *gin.Engine {
+		r := gin.New()
+		if viewer != 0 {
+			r.Use(asUser(viewer))
+		}
+		r.GET("/listings/:id/images.zip", h.DownloadImages)
+		return r
+	}
+	target := fmt.Sprintf("/listings/%d/images.zip", listing.ID)
+	busy := func(t *testing.T, viewer uint, position int) {
+		t.Helper()
+		w := serve(as(viewer), http.MethodGet, target, nil)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("status %d, want 429: %s", w.Code, w.Body)
+		}
+		body := decode(t, w)
+		if body["code"] != "EXPORT_BUSY" || body["position"] != float64(position) {
+			t.Errorf("body %v, want EXPORT_BUSY at position %d", body, position)
+		}
+		if body["eta_seconds"].(float64) < 1 || w.Header().Get("Retry-After") != fmt.Sprint(body["eta_seconds"]) {
+			t.Errorf("Retry-After %q, eta_seconds %v", w.Header().Get("Retry-After"), body["eta_seconds"])
+		}
+	}
+
+	// Finished downloads give their slot back
+	for i := 0; i < 2; i++ {
+		if w := serve(as(owner.ID), http.MethodGet, target, nil); w.Code != http.StatusOK {
+			t.Fatalf("download %d: status %d: %s", i+1, w.Code, w.Body)
+		}
+	}
+
+	// The buyer already has an export running, which also takes the only slot
+	running, err := gate.Acquire(context.Background(), fmt.Sprintf("user:%d", buyer.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy(t, buyer.ID, 0)
+	busy(t, 0, 1)
+	running.Release()
+	if w := serve(as(0), http.MethodGet, target, nil); w.Code != http.StatusOK {
+		t.Errorf("anonymous download after release: status %d", w.Code)
+	}
+
+	// Without Redis, downloads run ungated
+	mr.Close()
+	if w := serve(as(buyer.ID), http.MethodGet, target, nil); w.Code != http.StatusOK {
+		t.Errorf("download with Redis down: status %d: %s", w.Code, w.Body)
+	}
+}
@@ -1,19 +1,29 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"trade_company/internal/config"
+	"trade_company/internal/metrics"
 	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/redisclient"
 )
 
 type FavoriteHandler struct {
-	DB *gorm.DB
+	DB          *gorm.DB
+	Cfg         *config.Config
+	Leaderboard *redisclient.Trending     // nil without Redis
+	Cache       *redisclient.CacheService // nil without Redis
 }
 
-// List returns the current user's favorites
+// List returns the current user's favorites as listing summaries. Favorites whose
+// listing is no longer active are flagged unavailable, or left out with only_active=true.
 func (h *FavoriteHandler) List(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -21,22 +31,114 @@ func (h *FavoriteHandler) List(c *gin.Context) {
 		return
 	}
 
+	p := pagination.Parse(c, pagination.Limits{Default: h.Cfg.FavoritesDefaultPageSize, Max: h.Cfg.FavoritesMaxPageSize})
+	query := h.DB.Model(&models.Favorite{}).Where("favorites.user_id = ?", userID)
+	if c.Query("only_active") == "true" {
+		query = query.Joins("JOIN listings ON listings.id = favorites.listing_id").
+			Where("listings.status = ?", models.ListingStatusActive).
+			Where("listings.visibility <> ? OR listings.owner_id = favorites.user_id", models.ListingVisibilityPrivate)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favorites"})
+		return
+	}
+
+	// Primary images only: a card never needs the full gallery or the owner
 	var favorites []models.Favorite
-	if err := h.DB.Where("user_id = ?", userID).
+	if err := query.
 		Preload("Listing").
-		Preload("Listing.Images").
-		Preload("Listing.Owner").
-		Order("created_at desc").
+		Preload("Listing.Images", "is_primary = ? AND moderation_status = ?", true, models.ImageModerationApproved).
+		Order("favorites.created_at desc").
+		Scopes(p.Scope()).
 		Find(&favorites).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favorites"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"favorites": favorites,
+	result := make([]gin.H, 0, len(favorites))
+	for i := range favorites {
+		result = append(result, favoriteItem(&favorites[i]))
+	}
+
+	respondWithETag(c, http.StatusOK, gin.H{
+		"favorites":  result,
+		"pagination": pagination.NewMeta(p, total),
 	})
 }
 
+// favoriteItem is the response shape of a favorite with its listing preloaded
+func favoriteItem(fav *models.Favorite) gin.H {
+	// A listing its owner has since made private is gone as far as the user can tell
+	visible := fav.Listing.ID != 0 && fav.Listing.VisibleTo(fav.UserID)
+	item := gin.H{
+		"id":          fav.ID,
+		"listing_id":  fav.ListingID,
+		"created_at":  fav.CreatedAt,
+		"unavailable": !fav.Listing.AvailableTo(fav.UserID),
+		"listing":     nil,
+	}
+	if visible {
+		item["listing"] = listingSummary(&fav.Listing)
+	}
+	return item
+}
+
+// Get returns one of the current user's favorites by ID. Other users' favorites
+// are reported as not found.
+func (h *FavoriteHandler) Get(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	favoriteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid favorite ID"})
+		return
+	}
+
+	h.respondWithFavorite(c, h.DB.Where("id = ? AND user_id = ?", favoriteID, userID))
+}
+
+// GetByListing returns the current user's favorite for a listing, or 404 when
+// the listing isn't favorited. The listing detail page uses it for the heart icon.
+func (h *FavoriteHandler) GetByListing(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	listingID, err := strconv.ParseUint(c.Param("listingId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	h.respondWithFavorite(c, h.DB.Where("listing_id = ? AND user_id = ?", listingID, userID))
+}
+
+// respondWithFavorite writes the single favorite matched by query
+func (h *FavoriteHandler) respondWithFavorite(c *gin.Context, query *gorm.DB) {
+	var favorite models.Favorite
+	if err := query.
+		Preload("Listing").
+		Preload("Listing.Images", "is_primary = ? AND moderation_status = ?", true, models.ImageModerationApproved).
+		First(&favorite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Favorite not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favorite"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"favorite": favoriteItem(&favorite)})
+}
+
 // Add adds a listing to user's favorites
 func (h *FavoriteHandler) Add(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -56,7 +158,7 @@ func (h *FavoriteHandler) Add(c *gin.Context) {
 
 	// Check if listing exists
 	var listing models.Listing
-	if err := h.DB.First(&listing, input.ListingID).Error; err != nil {
+	if err := h.DB.First(&listing, input.ListingID).Error; err != nil || !listing.VisibleTo(userID.(uint)) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
 		return
 	}
@@ -69,17 +171,41 @@ func (h *FavoriteHandler) Add(c *gin.Context) {
 		return
 	}
 
-	// Create favorite
+	counted, err := h.countsTowardPopularity(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to favorites"})
+		return
+	}
+
+	// Create favorite; only established accounts bump the public count
 	favorite := models.Favorite{
 		UserID:    userID.(uint),
 		ListingID: input.ListingID,
+		Counted:   counted,
 	}
 
-	if err := h.DB.Create(&favorite).Error; err != nil {
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&favorite).Error; err != nil {
+			return err
+		}
+		if !counted {
+			return nil
+		}
+		return tx.Model(&models.Listing{}).Where("id = ?", input.ListingID).
+			UpdateColumn("favorite_count", gorm.Expr("favorite_count + 1")).Error
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to favorites"})
 		return
 	}
 
+	if counted {
+		h.invalidateDetail(input.ListingID)
+		metrics.IncPopularity(metrics.FavoriteCounted)
+		h.Leaderboard.Record(c.Request.Context(), input.ListingID, redisclient.TrendingFavoriteWeight)
+	} else {
+		metrics.IncPopularity(metrics.FavoriteExcludedNewAccount)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Added to favorites successfully",
 		"favorite": favorite,
@@ -107,10 +233,151 @@ func (h *FavoriteHandler) Remove(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Delete(&favorite).Error; err != nil {
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&favorite).Error; err != nil {
+			return err
+		}
+		if !favorite.Counted {
+			return nil
+		}
+		return tx.Model(&models.Listing{}).Where("id = ? AND favorite_count > 0", favorite.ListingID).
+			UpdateColumn("favorite_count", gorm.Expr("favorite_count - 1")).Error
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from favorites"})
 		return
 	}
+	if favorite.Counted {
+		h.invalidateDetail(favorite.ListingID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Removed from favorites successfully"})
 }
+
+// Toggle favorites a listing the user hasn't favorited and unfavorites one
+// they have, so a heart button needs no prior lookup. Concurrent toggles
+// don't trip the unique (user_id, listing_id) key: the insert is an upsert
+// that leaves an existing row alone, in which case that row is deleted.
+func (h *FavoriteHandler) Toggle(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	var input struct {
+		ListingID uint `json:"listing_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.First(&listing, input.ListingID).Error; err != nil || !listing.VisibleTo(uid) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	counted, err := h.countsTowardPopularity(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update favorites"})
+		return
+	}
+
+	favorite := models.Favorite{UserID: uid, ListingID: input.ListingID, Counted: counted}
+	favorited := false
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			favorited = true
+			return adjustFavoriteCount(tx, &favorite, 1)
+		}
+
+		// Already favorited: remove the existing row, whose Counted may differ
+		var existing models.Favorite
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND listing_id = ?", uid, input.ListingID).
+			First(&existing).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		favorite = existing
+		return adjustFavoriteCount(tx, &favorite, -1)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update favorites"})
+		return
+	}
+
+	if favorite.Counted {
+		h.invalidateDetail(input.ListingID)
+	}
+	if favorited {
+		if favorite.Counted {
+			metrics.IncPopularity(metrics.FavoriteCounted)
+			h.Leaderboard.Record(c.Request.Context(), input.ListingID, redisclient.TrendingFavoriteWeight)
+		} else {
+			metrics.IncPopularity(metrics.FavoriteExcludedNewAccount)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"listing_id": input.ListingID, "favorited": favorited})
+}
+
+// adjustFavoriteCount moves the listing's public favorite count by delta when
+// the favorite is counted
+func adjustFavoriteCount(tx *gorm.DB, favorite *models.Favorite, delta int) error {
+	if !favorite.Counted {
+		return nil
+	}
+	query := tx.Model(&models.Listing{}).Where("id = ?", favorite.ListingID)
+	if delta < 0 {
+		query = query.Where("favorite_count > 0")
+	}
+	return query.UpdateColumn("favorite_count", gorm.Expr("favorite_count + ?", delta)).Error
+}
+
+// CountForListing returns how many users favorited a listing. It is the
+// listing's public favorite count, so it is null when the owner hides it
+// from everyone but themselves and admins.
+func (h *FavoriteHandler) CountForListing(c *gin.Context) {
+	listingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Select("id", "owner_id", "visibility", "favorite_count", "hide_favorite_count").
+		Where("status NOT IN ?", models.HiddenListingStatuses).
+		First(&listing, listingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	viewerID, _ := c.Get("user_id")
+	uid, _ := viewerID.(uint)
+	privileged := uid != 0 && (uid == listing.OwnerID || isAdmin(h.DB, uid))
+	if listing.Visibility == models.ListingVisibilityPrivate && !privileged {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+	if privileged {
+		listing.ShowPrivateStats()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"listing_id": listing.ID, "favorite_count": listing.PublicFavoriteCount()})
+}
+
+// invalidateDetail drops a listing's cached detail after its favorite count
+// changes. Search results don't show the count, so they are left alone.
+func (h *FavoriteHandler) invalidateDetail(listingID uint) {
+	if h.Cache != nil {
+		_ = h.Cache.InvalidateListingDetail(listingID)
+	}
+}
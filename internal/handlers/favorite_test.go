@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// countQueries counts the SELECTs run on db from now on
+func countQueries(t *testing.T, db *gorm.DB) *int {
+	t.Helper()
+	n := new(int)
+	if err := db.Callback().Query().Before("gorm:query").Register("test:count_queries", func(*gorm.DB) { *n++ }); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestFavoritesListBoundedQueries(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	cfg.FavoritesDefaultPageSize = 20
+	h := &FavoriteHandler{DB: db, Cfg: cfg}
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+
+	// A hoarder's favorites, each listing with a gallery of images
+	const favorites = 300
+	for i := 0; i < favorites; i++ {
+		listing := createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Title = fmt.Sprintf("Shop %d", i) })
+		for j := 0; j < 3; j++ {
+			name := fmt.Sprintf("%d-%d.jpg", i, j)
+			db.Create(&models.Image{ListingID: listing.ID, Filename: name, URL: "/uploads/" + name, IsPrimary: j == 0,
+				ModerationStatus: models.ImageModerationApproved})
+		}
+		db.Create(&models.Favorite{UserID: buyer.ID, ListingID: listing.ID})
+	}
+
+	r := gin.New()
+	r.GET("/favorites", asUser(buyer.ID), h.List)
+	queries := countQueries(t, db)
+
+	for _, target := range []string{"/favorites", "/favorites?page=5", "/favorites?only_active=true"} {
+		*queries = 0
+		w := serve(r, http.MethodGet, target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", target, w.Code, w.Body)
+		}
+		body := decode(t, w)
+		items := body["favorites"].([]interface{})
+		if len(items) != 20 || body["pagination"].(map[string]interface{})["total"] != float64(favorites) {
+			t.Errorf("%s: %d favorites, pagination %v", target, len(items), body["pagination"])
+		}
+		// Count, favorites, their listings and the primary images
+		if *queries > 4 {
+			t.Errorf("%s: %d queries for one page", target, *queries)
+		}
+		listing := items[0].(map[string]interface{})["listing"].(map[string]interface{})
+		if image, _ := listing["primary_image"].(map[string]interface{}); !strings.HasSuffix(fmt.Sprint(image["url"]), "-0.jpg") {
+			t.Errorf("%s: card image %v, want the primary one", target, listing["primary_image"])
+		}
+	}
+}
+
+func TestFavoritesListUnavailable(t *testing.T) {
+	db := newTestDB(t)
+	h := &FavoriteHandler{DB: db, Cfg: testConfig(t)}
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+
+	active := createTestListing(t, db, seller.ID)
+	sold := createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Status = models.ListingStatusSold })
+	private := createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Visibility = models.ListingVisibilityPrivate })
+	for _, l := range []*models.Listing{active, sold, private} {
+		db.Create(&models.Favorite{UserID: buyer.ID, ListingID: l.ID})
+	}
+
+	r := gin.New()
+	r.GET("/favorites", asUser(buyer.ID), h.List)
+
+	tests := []struct {
+		target          string
+		wantUnavailable map[uint]bool
+	}{
+		// Favorites of listings that are gone are flagged rather than dropped
+		{target: "/favorites", wantUnavailable: map[uint]bool{active.ID: false, sold.ID: true, private.ID: true}},
+		{target: "/favorites?only_active=true", wantUnavailable: map[uint]bool{active.ID: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(r, http.MethodGet, tt.target, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			body := decode(t, w)
+			items := body["favorites"].([]interface{})
+			if len(items) != len(tt.wantUnavailable) || body["pagination"].(map[string]interface{})["total"] != float64(len(tt.wantUnavailable)) {
+				t.Fatalf("favorites %v, pagination %v", items, body["pagination"])
+			}
+			for _, item := range items {
+				item := item.(map[string]interface{})
+				id := uint(item["listing_id"].(float64))
+				if item["unavailable"] != tt.wantUnavailable[id] {
+					t.Errorf("listing %d unavailable %v, want %v", id, item["unavailable"], tt.wantUnavailable[id])
+				}
+				if id == private.ID && item["listing"] != nil {
+					t.Errorf("private listing shown: %v", item["listing"])
+				}
+			}
+		})
+	}
+}
+
+func TestFavoriteLookups(t *testing.T) {
+	db := newTestDB(t)
+	h := &FavoriteHandler{DB: db, Cfg: testConfig(t)}
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	other := createTestUser(t, db, "other")
+
+	listing := createTestListing(t, db, seller.ID)
+	unfavorited := createTestListing(t, db, seller.ID)
+	private := createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Visibility = models.ListingVisibilityPrivate })
+	mine := models.Favorite{UserID: buyer.ID, ListingID: listing.ID}
+	hidden := models.Favorite{UserID: buyer.ID, ListingID: private.ID}
+	theirs := models.Favorite{UserID: other.ID, ListingID: unfavorited.ID}
+	for _, f := range []*models.Favorite{&mine, &hidden, &theirs} {
+		db.Create(f)
+	}
+
+	r := gin.New()
+	authd := r.Group("/", asUser(buyer.ID))
+	authd.GET("/favorites/:id", h.Get)
+	authd.GET("/favorites/by-listing/:listingId", h.GetByListing)
+
+	tests := []struct {
+		name        string
+		target      string
+		status      int
+		wantID      uint
+		wantListing bool
+	}{
+		{name: "own favorite", target: fmt.Sprintf("/favorites/%d", mine.ID), status: http.StatusOK, wantID: mine.ID, wantListing: true},
+		{name: "another user's favorite", target: fmt.Sprintf("/favorites/%d", theirs.ID), status: http.StatusNotFound},
+		{name: "missing favorite", target: "/favorites/9999", status: http.StatusNotFound},
+		{name: "invalid ID", target: "/favorites/abc", status: http.StatusBadRequest},
+		{name: "favorited listing", target: fmt.Sprintf("/favorites/by-listing/%d", listing.ID), status: http.StatusOK, wantID: mine.ID, wantListing: true},
+		{name: "listing only another user favorited", target: fmt.Sprintf("/favorites/by-listing/%d", unfavorited.ID), status: http.StatusNotFound},
+		{name: "listing made private", target: fmt.Sprintf("/favorites/by-listing/%d", private.ID), status: http.StatusOK, wantID: hidden.ID},
+		{name: "invalid listing ID", target: "/favorites/by-listing/abc", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, tt.target, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			fav := decode(t, w)["favorite"].(map[string]interface{})
+			if fav["id"] != float64(tt.wantID) {
+				t.Errorf("favorite %v, want ID %d", fav["id"], tt.wantID)
+			}
+			if hasListing := fav["listing"] != nil; hasListing != tt.wantListing {
+				t.Errorf("listing %v, want shown %v", fav["listing"], tt.wantListing)
+			}
+			if fav["unavailable"] != !tt.wantListing {
+				t.Errorf("unavailable %v, want %v", fav["unavailable"], !tt.wantListing)
+			}
+		})
+	}
+}
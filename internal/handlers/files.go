@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FileHandler struct {
+	Storage *storage.Storage
+}
+
+// ServePrivate serves a private upload after checking its signed URL
+func (h *FileHandler) ServePrivate(c *gin.Context) {
+	filename := c.Query("filename")
+	path, err := h.Storage.Verify(c.Param("name"), filename, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		if errors.Is(err, storage.ErrExpiredSignature) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Link has expired"})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid link"})
+		return
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	// Signed links are short-lived, so they must never be cached by shared caches
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if filename != "" {
+		c.FileAttachment(path, filename)
+		return
+	}
+	c.File(path)
+}
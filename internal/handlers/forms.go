@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"trade_company/internal/botcheck"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FormHandler serves what the public forms need before they are submitted
+type FormHandler struct {
+	Bots *botcheck.Checker
+}
+
+// Token returns a signed form_token stamped with the current time. Forms fetch
+// it when they render and send it back on submit, so bot scoring knows how
+// long the form was open without trusting a client clock.
+func (h *FormHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"form_token": h.Bots.FormToken(time.Now())})
+}
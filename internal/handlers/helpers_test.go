@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/botcheck"
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestDB opens an empty in-memory SQLite database with the tables most
+// handlers touch, plus any extra models the test needs
+func newTestDB(t *testing.T, extra ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	tables := append([]interface{}{
+		&models.User{}, &models.Listing{}, &models.Image{}, &models.ListingDocument{},
+		&models.Favorite{}, &models.Message{}, &models.Lead{},
+		&models.ListingCount{}, &models.ListingViewDaily{}, &models.ListingViewHourly{}, &models.ListingUserView{},
+	}, extra...)
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// testConfig is the default development configuration
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("APP_ENV", "development")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	return cfg
+}
+
+// asUser authenticates every request as userID, as middleware.AuthRequired does
+func asUser(userID uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
+
+// serve sends a request to r with an optional JSON body
+func serve(r http.Handler, method, target string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "handlers-test")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decode unmarshals a JSON response body
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var out map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return out
+}
+
+// createTestUser adds an active, verified user
+func createTestUser(t *testing.T, db *gorm.DB, username string) *models.User {
+	t.Helper()
+	now := time.Now()
+	user := &models.User{
+		Email:           username + "@example.com",
+		Username:        username,
+		PasswordHash:    "x",
+		Phone:           "0912345678",
+		Role:            models.RoleUser,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+		LastLoginAt:     &now,
+		CreatedAt:       now.Add(-30 * 24 * time.Hour),
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createTestListing adds an active public listing, adjusted by the optional edit
+func createTestListing(t *testing.T, db *gorm.DB, ownerID uint, edit ...func(*models.Listing)) *models.Listing {
+	t.Helper()
+	listing := &models.Listing{
+		Title:       "Corner cafe",
+		Description: "A busy cafe",
+		Price:       1000000,
+		Category:    "food",
+		Industry:    "restaurant",
+		Location:    "Taipei",
+		PhoneNumber: "0987654321",
+		Status:      models.ListingStatusActive,
+		Visibility:  models.ListingVisibilityPublic,
+		OwnerID:     ownerID,
+	}
+	for _, f := range edit {
+		f(listing)
+	}
+	if err := db.Create(listing).Error; err != nil {
+		t.Fatalf("create listing: %v", err)
+	}
+	return listing
+}
+
+// newTestLeadHandler builds a LeadHandler without Redis whose emails fail
+// quietly instead of being logged
+func newTestLeadHandler(t *testing.T, db *gorm.DB, cfg *config.Config) *LeadHandler {
+	t.Helper()
+	emailCfg := *cfg
+	emailCfg.AppEnv = "test"
+	return &LeadHandler{
+		DB:           db,
+		Config:       cfg,
+		EmailService: auth.NewEmailService(&emailCfg),
+		Bots:         botcheck.New(cfg, nil),
+	}
+}
+
+// leadBody is a contact form that passes the bot checks
+func leadBody(extra map[string]interface{}) map[string]interface{} {
+	body := map[string]interface{}{
+		"subject":   "Interested",
+		"message":   "Is the lease transferable?",
+		"form_time": time.Now().Add(-time.Minute).UnixMilli(),
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	return body
+}
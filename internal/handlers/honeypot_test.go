@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContactSellerConfiguredHoneypots(t *testing.T) {
+	tests := []struct {
+		name   string
+		extra  map[string]interface{}
+		status int
+	}{
+		{name: "decoys left empty", extra: map[string]interface{}{"company_url": "", "fax": nil}, status: http.StatusOK},
+		{name: "first decoy filled", extra: map[string]interface{}{"company_url": "http://spam.example"}, status: http.StatusBadRequest},
+		{name: "second decoy filled", extra: map[string]interface{}{"fax": "02-1234-5678"}, status: http.StatusBadRequest},
+		// The default name is no longer a decoy once it has been rotated out
+		{name: "old decoy filled", extra: map[string]interface{}{"website": "http://shop.example"}, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+			cfg := testConfig(t)
+			cfg.HoneypotFields = "company_url, fax"
+			h := newTestLeadHandler(t, db, cfg)
+			seller := createTestUser(t, db, "seller")
+			buyer := createTestUser(t, db, "buyer")
+			listing := createTestListing(t, db, seller.ID)
+			r := gin.New()
+			r.POST("/listings/:id/leads", asUser(buyer.ID), h.ContactSeller)
+
+			w := serve(r, http.MethodPost, fmt.Sprintf("/listings/%d/leads", listing.ID), leadBody(tt.extra))
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var leads int64
+			db.Model(&models.Lead{}).Count(&leads)
+			if rejected := tt.status != http.StatusOK; rejected != (leads == 0) {
+				t.Errorf("%d leads stored after status %d", leads, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/imageproxy"
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// An image ID always refers to the same file, so its variants never change
+	imageVariantCacheControl = "public, max-age=31536000, immutable"
+	// Placeholders stand in for a file that may yet be restored
+	imagePlaceholderCacheControl = "public, max-age=300"
+	// imagePlaceholderWidth is the placeholder width when no width was asked for
+	imagePlaceholderWidth = 800
+)
+
+// ImageProxyHandler serves listing images resized on the fly, for images that
+// predate upload-time thumbnails. Widths are limited to IMAGE_VARIANT_WIDTHS.
+type ImageProxyHandler struct {
+	DB        *gorm.DB
+	Storage   *storage.Storage
+	Cache     *imageproxy.DiskCache // nil disables caching of variants
+	Widths    []int
+	StaticDir string // Root of /static, where the seeded images live
+}
+
+// Serve handles GET /img/:imageID?w=400. Without w the original is served.
+// Only approved images are served. A file missing from disk gets a grey
+// placeholder instead of an error, so a page still lays out; formats that
+// can't be decoded here (WebP) are served at their original size.
+func (h *ImageProxyHandler) Serve(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("imageID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	width := 0
+	if w := c.Query("w"); w != "" {
+		width, err = strconv.Atoi(w)
+		if err != nil || !h.allowedWidth(width) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported width", "code": "UNSUPPORTED_WIDTH", "widths": h.Widths})
+			return
+		}
+	}
+
+	var img models.Image
+	if err := h.DB.Where("moderation_status = ?", models.ImageModerationApproved).First(&img, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load image"})
+		return
+	}
+
+	key := imageVariantKey(&img, width)
+	if width > 0 && h.Cache != nil {
+		if data, ok := h.Cache.Get(key); ok {
+			c.Header("Cache-Control", imageVariantCacheControl)
+			c.Data(http.StatusOK, http.DetectContentType(data), data)
+			return
+		}
+	}
+
+	src, err := h.sourcePath(&img)
+	if err == nil {
+		_, err = os.Stat(src)
+	}
+	if err != nil {
+		h.servePlaceholder(c, width)
+		return
+	}
+	if width == 0 {
+		c.Header("Cache-Control", imageVariantCacheControl)
+		c.File(src)
+		return
+	}
+
+	original, err := os.ReadFile(src)
+	if err != nil {
+		h.servePlaceholder(c, width)
+		return
+	}
+
+	data, err := imageproxy.Variant(original, width)
+	if err != nil {
+		c.Header("Cache-Control", imageVariantCacheControl)
+		c.File(src)
+		return
+	}
+	if h.Cache != nil {
+		if err := h.Cache.Put(key, data); err != nil {
+			_ = c.Error(err)
+		}
+	}
+	c.Header("Cache-Control", imageVariantCacheControl)
+	c.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+func (h *ImageProxyHandler) allowedWidth(width int) bool {
+	for _, w := range h.Widths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcePath is where the original of img is on disk: seeded images point
+// into /static, uploads are in the public upload dir
+func (h *ImageProxyHandler) sourcePath(img *models.Image) (string, error) {
+	if i := strings.Index(img.URL, "/static/"); i >= 0 {
+		// Cleaning as an absolute path drops any ".." that would leave StaticDir
+		rel := path.Clean("/" + strings.TrimPrefix(img.URL[i:], "/static/"))
+		return filepath.Join(h.StaticDir, filepath.FromSlash(rel)), nil
+	}
+	return h.Storage.PublicPath(img.Filename)
+}
+
+func (h *ImageProxyHandler) servePlaceholder(c *gin.Context, width int) {
+	if width == 0 {
+		width = imagePlaceholderWidth
+	}
+	key := fmt.Sprintf("placeholder_w%d", width)
+	var data []byte
+	ok := false
+	if h.Cache != nil {
+		data, ok = h.Cache.Get(key)
+	}
+	if !ok {
+		data = imageproxy.Placeholder(width)
+		if h.Cache != nil {
+			_ = h.Cache.Put(key, data)
+		}
+	}
+	c.Header("Cache-Control", imagePlaceholderCacheControl)
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// imageVariantKey names a variant in the cache. The stored file name is part
+// of it, so a row pointing at a different file never gets a stale variant.
+func imageVariantKey(img *models.Image, width int) string {
+	sum := sha256.Sum256([]byte(img.URL + "\n" + img.Filename))
+	return fmt.Sprintf("%d_w%d_%s", img.ID, width, hex.EncodeToString(sum[:8]))
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"trade_company/internal/imageproxy"
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// imageProxyTest serves /img from a temporary upload dir and static dir
+type imageProxyTest struct {
+	db      *gorm.DB
+	h       *ImageProxyHandler
+	r       *gin.Engine
+	listing *models.Listing
+}
+
+func newImageProxyTest(t *testing.T) *imageProxyTest {
+	t.Helper()
+	db := newTestDB(t)
+	cache, err := imageproxy.NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ImageProxyHandler{
+		DB:        db,
+		Storage:   &storage.Storage{PublicDir: t.TempDir()},
+		Cache:     cache,
+		Widths:    []int{320, 640},
+		StaticDir: t.TempDir(),
+	}
+	r := gin.New()
+	r.GET("/img/:imageID", h.Serve)
+	owner := createTestUser(t, db, "seller")
+	return &imageProxyTest{db: db, h: h, r: r, listing: createTestListing(t, db, owner.ID)}
+}
+
+// upload stores data as an uploaded image with the given moderation status
+func (pt *imageProxyTest) upload(t *testing.T, name string, data []byte, status string) *models.Image {
+	t.Helper()
+	if data != nil {
+		if err := os.WriteFile(filepath.Join(pt.h.Storage.PublicDir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	img := &models.Image{ListingID: pt.listing.ID, Filename: name, URL: "/uploads/" + name, ModerationStatus: status}
+	if err := pt.db.Create(img).Error; err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// imageWidth decodes the width of a served image
+func imageWidth(t *testing.T, data []byte) int {
+	t.Helper()
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode served image: %v", err)
+	}
+	return cfg.Width
+}
+
+func TestImageProxyVariantCached(t *testing.T) {
+	pt := newImageProxyTest(t)
+	img := pt.upload(t, "shop.jpg", encodeImage(t, "jpeg", 1000, 750), models.ImageModerationApproved)
+	target := fmt.Sprintf("/img/%d?w=320", img.ID)
+
+	first := serve(pt.r, http.MethodGet, target, nil)
+	if first.Code != http.StatusOK || imageWidth(t, first.Body.Bytes()) != 320 {
+		t.Fatalf("status %d: %d bytes", first.Code, first.Body.Len())
+	}
+	if first.Header().Get("Cache-Control") != imageVariantCacheControl || first.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("headers %v", first.Header())
+	}
+
+	// With the original gone, the variant still comes from the cache
+	os.Remove(filepath.Join(pt.h.Storage.PublicDir, "shop.jpg"))
+	second := serve(pt.r, http.MethodGet, target, nil)
+	if second.Code != http.StatusOK || !bytes.Equal(second.Body.Bytes(), first.Body.Bytes()) {
+		t.Errorf("second request: status %d, same body %v", second.Code, bytes.Equal(second.Body.Bytes(), first.Body.Bytes()))
+	}
+	if second.Header().Get("Cache-Control") != imageVariantCacheControl {
+		t.Errorf("cache hit Cache-Control %q", second.Header().Get("Cache-Control"))
+	}
+}
+
+func TestImageProxyRequests(t *testing.T) {
+	pt := newImageProxyTest(t)
+	approved := pt.upload(t, "shop.png", encodeImage(t, "png", 800, 600), models.ImageModerationApproved)
+	pending := pt.upload(t, "pending.png", encodeImage(t, "png", 800, 600), models.ImageModerationPending)
+	missing := pt.upload(t, "missing.png", nil, models.ImageModerationApproved)
+	// Seeded images live under /static; ".." must not climb out of it
+	os.MkdirAll(filepath.Join(pt.h.StaticDir, "images"), 0o755)
+	os.WriteFile(filepath.Join(pt.h.StaticDir, "images", "seed.png"), encodeImage(t, "png", 700, 500), 0o644)
+	seeded := &models.Image{ListingID: pt.listing.ID, Filename: "seed.png", URL: "/static/images/seed.png", ModerationStatus: models.ImageModerationApproved}
+	escaping := &models.Image{ListingID: pt.listing.ID, Filename: "x.png", URL: "/static/../../etc/passwd", ModerationStatus: models.ImageModerationApproved}
+	pt.db.Create(seeded)
+	pt.db.Create(escaping)
+
+	tests := []struct {
+		name         string
+		target       string
+		status       int
+		code         string // Error code for a rejected request
+		width        int    // Served image width
+		cacheControl string
+	}{
+		{name: "original", target: fmt.Sprintf("/img/%d", approved.ID), status: http.StatusOK, width: 800, cacheControl: imageVariantCacheControl},
+		{name: "variant", target: fmt.Sprintf("/img/%d?w=640", approved.ID), status: http.StatusOK, width: 640, cacheControl: imageVariantCacheControl},
+		{name: "seeded variant", target: fmt.Sprintf("/img/%d?w=320", seeded.ID), status: http.StatusOK, width: 320, cacheControl: imageVariantCacheControl},
+		{name: "unsupported width", target: fmt.Sprintf("/img/%d?w=500", approved.ID), status: http.StatusBadRequest, code: "UNSUPPORTED_WIDTH"},
+		{name: "width not a number", target: fmt.Sprintf("/img/%d?w=big", approved.ID), status: http.StatusBadRequest, code: "UNSUPPORTED_WIDTH"},
+		{name: "not approved", target: fmt.Sprintf("/img/%d?w=320", pending.ID), status: http.StatusNotFound},
+		{name: "unknown image", target: "/img/999", status: http.StatusNotFound},
+		{name: "missing file", target: fmt.Sprintf("/img/%d?w=320", missing.ID), status: http.StatusOK, width: 320, cacheControl: imagePlaceholderCacheControl},
+		{name: "missing file at full size", target: fmt.Sprintf("/img/%d", missing.ID), status: http.StatusOK, width: imagePlaceholderWidth, cacheControl: imagePlaceholderCacheControl},
+		{name: "path outside static", target: fmt.Sprintf("/img/%d", escaping.ID), status: http.StatusOK, width: imagePlaceholderWidth, cacheControl: imagePlaceholderCacheControl},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(pt.r, http.MethodGet, tt.target, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				if tt.code != "" && decode(t, w)["code"] != tt.code {
+					t.Errorf("body %s, want code %s", w.Body, tt.code)
+				}
+				return
+			}
+			if got := imageWidth(t, w.Body.Bytes()); got != tt.width {
+				t.Errorf("width %d, want %d", got, tt.width)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Cache-Control %q, want %q", got, tt.cacheControl)
+			}
+		})
+	}
+}
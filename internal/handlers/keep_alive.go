@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// keepAliveActions maps the action in a keep-alive link to the outcome it records
+var keepAliveActions = map[string]string{
+	"confirm": models.ListingConfirmationConfirmed,
+	"sold":    models.ListingConfirmationSold,
+}
+
+// recordLogin stamps the user's login time. A seller returning after the
+// configured absence gets their active listings queued for confirmation; the
+// number queued is returned.
+func recordLogin(db *gorm.DB, cfg *config.Config, user *models.User, now time.Time) (int, error) {
+	// Copied rather than kept as a pointer: the update writes the new time
+	// through user.LastLoginAt's existing pointer
+	var previous time.Time
+	if user.LastLoginAt != nil {
+		previous = *user.LastLoginAt
+	}
+	if err := db.Model(user).Update("last_login_at", now).Error; err != nil {
+		return 0, err
+	}
+	if previous.IsZero() || now.Sub(previous) < cfg.KeepAliveInactivity() {
+		return 0, nil
+	}
+	return jobs.RequestListingConfirmations(db, user.ID)
+}
+
+// KeepAliveHandler serves the links in keep-alive emails. The token in the link
+// is the only credential, so sellers can answer without logging in.
+type KeepAliveHandler struct {
+	DB *gorm.DB
+}
+
+// load finds the confirmation behind the :token link
+func (h *KeepAliveHandler) load(c *gin.Context) (*models.ListingConfirmation, bool) {
+	var conf models.ListingConfirmation
+	err := h.DB.Preload("Listing").Where("token_hash = ?", jobs.HashConfirmationToken(c.Param("token"))).First(&conf).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.HTML(http.StatusNotFound, "keep_alive.html", gin.H{"invalid": true})
+		return nil, false
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "keep_alive.html", gin.H{"failed": true})
+		return nil, false
+	}
+	return &conf, true
+}
+
+// Show renders the page a keep-alive link opens. Nothing changes until the
+// seller presses its button: mail scanners prefetch links, and a GET that marks
+// listings sold would take them down without anyone asking.
+func (h *KeepAliveHandler) Show(c *gin.Context) {
+	conf, ok := h.load(c)
+	if !ok {
+		return
+	}
+	action := c.Query("action")
+	if _, known := keepAliveActions[action]; !known {
+		action = "confirm"
+	}
+	c.HTML(http.StatusOK, "keep_alive.html", gin.H{"confirmation": conf, "action": action})
+}
+
+// Resolve records the seller's answer from the keep-alive page
+func (h *KeepAliveHandler) Resolve(c *gin.Context) {
+	conf, ok := h.load(c)
+	if !ok {
+		return
+	}
+	resolution, known := keepAliveActions[c.PostForm("action")]
+	if !known {
+		c.HTML(http.StatusBadRequest, "keep_alive.html", gin.H{"confirmation": conf, "action": "confirm"})
+		return
+	}
+	// Answering twice changes nothing; the page shows the first answer
+	if conf.Resolution == "" {
+		if err := jobs.ResolveListingConfirmation(h.DB, conf, resolution, time.Now()); err != nil {
+			c.HTML(http.StatusInternalServerError, "keep_alive.html", gin.H{"failed": true})
+			return
+		}
+	}
+	c.HTML(http.StatusOK, "keep_alive.html", gin.H{"confirmation": conf})
+}
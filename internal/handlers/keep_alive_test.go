@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"trade_company/internal/format"
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newKeepAliveRouter serves the keep-alive links with the real page template
+func newKeepAliveRouter(db *gorm.DB) *gin.Engine {
+	h := &KeepAliveHandler{DB: db}
+	r := gin.New()
+	r.SetFuncMap(format.FuncMap())
+	r.LoadHTMLFiles("../../templates/keep_alive.html")
+	r.GET("/keep-alive/:token", h.Show)
+	r.POST("/keep-alive/:token", h.Resolve)
+	return r
+}
+
+// createSentConfirmation adds a confirmation for listing as the keep-alive job
+// would after emailing token
+func createSentConfirmation(t *testing.T, db *gorm.DB, listing *models.Listing, token string) *models.ListingConfirmation {
+	t.Helper()
+	hash := jobs.HashConfirmationToken(token)
+	now := time.Now()
+	deadline := now.Add(30 * 24 * time.Hour)
+	conf := &models.ListingConfirmation{ListingID: listing.ID, UserID: listing.OwnerID, TokenHash: &hash, NotifiedAt: &now, Deadline: &deadline}
+	if err := db.Create(conf).Error; err != nil {
+		t.Fatal(err)
+	}
+	return conf
+}
+
+// postAction presses the keep-alive page's button
+func postAction(r http.Handler, token, action string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/keep-alive/"+token, strings.NewReader(url.Values{"action": {action}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestKeepAliveLinks(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		status     int
+		resolution string
+		listing    models.ListingStatus
+		page       string
+	}{
+		{name: "confirm", action: "confirm", status: http.StatusOK, resolution: models.ListingConfirmationConfirmed,
+			listing: models.ListingStatusActive, page: "stays live"},
+		{name: "mark sold", action: "sold", status: http.StatusOK, resolution: models.ListingConfirmationSold,
+			listing: models.ListingStatusSold, page: "taken down"},
+		{name: "unknown action", action: "delete", status: http.StatusBadRequest,
+			listing: models.ListingStatusActive, page: "Yes, it's still available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.ListingConfirmation{})
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID)
+			conf := createSentConfirmation(t, db, listing, "tok")
+			r := newKeepAliveRouter(db)
+
+			// Opening the link only shows the button for the action in it
+			w := serve(r, http.MethodGet, "/keep-alive/tok?action="+tt.action, nil)
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), listing.Title) {
+				t.Fatalf("page status %d: %s", w.Code, w.Body)
+			}
+			var stored models.ListingConfirmation
+			db.First(&stored, conf.ID)
+			if stored.Resolution != "" {
+				t.Fatalf("opening the link resolved it as %q", stored.Resolution)
+			}
+
+			w = postAction(r, "tok", tt.action)
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.page) {
+				t.Fatalf("status %d, want %d with %q: %s", w.Code, tt.status, tt.page, w.Body)
+			}
+			db.First(&stored, conf.ID)
+			if stored.Resolution != tt.resolution || (tt.resolution != "") != (stored.ResolvedAt != nil) {
+				t.Errorf("resolution %q at %v, want %q", stored.Resolution, stored.ResolvedAt, tt.resolution)
+			}
+			var l models.Listing
+			db.First(&l, listing.ID)
+			if l.Status != tt.listing {
+				t.Errorf("listing status %q, want %q", l.Status, tt.listing)
+			}
+		})
+	}
+}
+
+func TestKeepAliveLinkEdgeCases(t *testing.T) {
+	db := newTestDB(t, &models.ListingConfirmation{})
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID)
+	createSentConfirmation(t, db, listing, "tok")
+	r := newKeepAliveRouter(db)
+
+	if w := serve(r, http.MethodGet, "/keep-alive/tok?action=sold", nil); !strings.Contains(w.Body.String(), `value="sold"`) {
+		t.Errorf("sold link page lacks the sold button: %s", w.Body)
+	}
+	if w := serve(r, http.MethodGet, "/keep-alive/tok?action=nonsense", nil); !strings.Contains(w.Body.String(), `value="confirm"`) {
+		t.Errorf("unknown action page isn't the confirm page: %s", w.Body)
+	}
+	for _, w := range []*httptest.ResponseRecorder{
+		serve(r, http.MethodGet, "/keep-alive/forged", nil),
+		postAction(r, "forged", "sold"),
+	} {
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "not valid") {
+			t.Errorf("forged token: status %d: %s", w.Code, w.Body)
+		}
+	}
+
+	// The first answer sticks
+	if w := postAction(r, "tok", "confirm"); w.Code != http.StatusOK {
+		t.Fatalf("confirm status %d", w.Code)
+	}
+	if w := postAction(r, "tok", "sold"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "stays live") {
+		t.Errorf("second answer: status %d: %s", w.Code, w.Body)
+	}
+	var l models.Listing
+	db.First(&l, listing.ID)
+	if l.Status != models.ListingStatusActive {
+		t.Errorf("listing status %q after a late sold answer", l.Status)
+	}
+}
+
+func TestRecordLoginQueuesConfirmations(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name      string
+		lastLogin *time.Duration // Before now; nil for a first login
+		want      int
+	}{
+		{name: "first login", want: 0},
+		{name: "recent login", lastLogin: durationPtr(44 * day), want: 0},
+		{name: "back after the absence", lastLogin: durationPtr(45 * day), want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.ListingConfirmation{})
+			cfg := testConfig(t)
+			now := time.Now()
+			seller := createTestUser(t, db, "seller")
+			seller.LastLoginAt = nil
+			if tt.lastLogin != nil {
+				at := now.Add(-*tt.lastLogin)
+				seller.LastLoginAt = &at
+			}
+			createTestListing(t, db, seller.ID)
+			createTestListing(t, db, seller.ID)
+			createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Status = models.ListingStatusInactive })
+
+			queued, err := recordLogin(db, cfg, seller, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if queued != tt.want {
+				t.Errorf("queued %d, want %d", queued, tt.want)
+			}
+			var stored models.User
+			db.First(&stored, seller.ID)
+			if stored.LastLoginAt == nil || !stored.LastLoginAt.Equal(now) {
+				t.Errorf("last_login_at %v, want %v", stored.LastLoginAt, now)
+			}
+
+			// Logging in again doesn't queue the same listings twice
+			if tt.want > 0 {
+				back := now.Add(-60 * day)
+				seller.LastLoginAt = &back
+				if queued, _ := recordLogin(db, cfg, seller, now); queued != 0 {
+					t.Errorf("second login queued %d more", queued)
+				}
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration { return &d }
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/middleware"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type leadRoutingRequest struct {
+	// At most 10 so they fit lead_routing_rules.notify_emails
+	NotifyEmails []string `json:"notify_emails" binding:"max=10,dive,email,max=95"`
+	RoundRobin   bool     `json:"round_robin"`
+}
+
+type organizationMemberRequest struct {
+	Email string            `json:"email" binding:"required,email"`
+	Role  models.MemberRole `json:"role"` // Optional; agent when omitted
+}
+
+type leadAssigneeRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// notifyLead emails a new lead to its assignee and copies it to the extra
+// addresses of the seller's routing rule. It is best effort, like the single
+// seller email it replaces.
+func (h *LeadHandler) notifyLead(seller *models.User, lead *models.Lead, route models.LeadRoute) {
+	assignee := seller
+	if route.AssigneeID != seller.ID {
+		var member models.User
+		if err := h.DB.First(&member, route.AssigneeID).Error; err == nil {
+			assignee = &member
+		}
+	}
+	_ = h.EmailService.SendLeadNotification(assignee, lead)
+
+	for _, address := range route.NotifyEmails {
+		if strings.EqualFold(address, assignee.Email) {
+			continue
+		}
+		_ = h.EmailService.SendLeadCopy(address, seller, lead)
+	}
+}
+
+// administeredOrganizations lists the organizations whose leads userID may see
+// in full: their own account and those where they are an admin member
+func (h *LeadHandler) administeredOrganizations(userID uint) ([]uint, error) {
+	var orgs []uint
+	if err := h.DB.Model(&models.OrganizationMember{}).
+		Where("user_id = ? AND role = ?", userID, models.MemberRoleAdmin).
+		Pluck("organization_id", &orgs).Error; err != nil {
+		return nil, err
+	}
+	return append(orgs, userID), nil
+}
+
+// GetLeadRouting returns the caller's lead routing rule and organization members
+func (h *LeadHandler) GetLeadRouting(c *gin.Context) {
+	orgID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	rule := models.LeadRoutingRule{OrganizationID: orgID}
+	if err := h.DB.First(&rule, orgID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lead routing"})
+		return
+	}
+	members := []models.OrganizationMember{}
+	if err := h.DB.Preload("User").
+		Where("organization_id = ?", orgID).
+		Order("user_id").
+		Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load organization members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notify_emails": nonNilStrings(rule.Emails()),
+		"round_robin":   rule.RoundRobin,
+		"members":       members,
+	})
+}
+
+// UpdateLeadRouting replaces the caller's extra notification addresses and
+// turns round-robin assignment among their members on or off
+func (h *LeadHandler) UpdateLeadRouting(c *gin.Context) {
+	orgID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req leadRoutingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	seen := make(map[string]bool)
+	emails := make([]string, 0, len(req.NotifyEmails))
+	for _, e := range req.NotifyEmails {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if !seen[e] {
+			seen[e] = true
+			emails = append(emails, e)
+		}
+	}
+
+	rule := models.LeadRoutingRule{
+		OrganizationID: orgID,
+		NotifyEmails:   strings.Join(emails, ","),
+		RoundRobin:     req.RoundRobin,
+	}
+	// The round-robin position survives turning it off and on again
+	if err := h.DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"notify_emails", "round_robin", "updated_at"}),
+	}).Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save lead routing"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notify_emails": emails,
+		"round_robin":   rule.RoundRobin,
+	})
+}
+
+// AddOrganizationMember adds the user with the given email to the caller's
+// organization, or changes their role if they are already a member
+func (h *LeadHandler) AddOrganizationMember(c *gin.Context) {
+	orgID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.MemberRoleAgent
+	}
+
+	var user models.User
+	if err := h.DB.Where("email = ? AND is_active = ?", strings.ToLower(strings.TrimSpace(req.Email)), true).
+		First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.ID == orgID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your own account is always part of your organization"})
+		return
+	}
+
+	member := models.OrganizationMember{OrganizationID: orgID, UserID: user.ID, Role: req.Role}
+	if err := h.DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+	member.User = user
+
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// RemoveOrganizationMember removes a member from the caller's organization.
+// Leads assigned to them go back to the organization's own account, so they
+// stop seeing them.
+func (h *LeadHandler) RemoveOrganizationMember(c *gin.Context) {
+	orgID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var removed int64
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&models.OrganizationMember{})
+		if res.Error != nil {
+			return res.Error
+		}
+		removed = res.RowsAffected
+		if removed == 0 {
+			return nil
+		}
+		return tx.Model(&models.Lead{}).
+			Where("receiver_id = ? AND assigned_to = ?", orgID, userID).
+			Update("assigned_to", orgID).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if removed == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// ReassignLead hands a lead to another member of its organization. Only the
+// organization's account and its admin members may reassign.
+func (h *LeadHandler) ReassignLead(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req leadAssigneeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	leadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+		return
+	}
+	var lead models.Lead
+	if err := h.DB.First(&lead, leadID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+		return
+	}
+	admin, err := models.OrganizationAdmin(h.DB, lead.ReceiverID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if !admin {
+		// Don't reveal leads of other organizations
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+		return
+	}
+
+	member, err := models.OrganizationMemberOf(h.DB, lead.ReceiverID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check assignee"})
+		return
+	}
+	if !member {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee is not a member of this organization", "code": "NOT_A_MEMBER"})
+		return
+	}
+
+	if err := h.DB.Model(&lead).Update("assigned_to", req.UserID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reassign lead"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lead reassigned", "lead_id": lead.ID, "assigned_to": req.UserID})
+}
+
+// nonNilStrings keeps an empty list an empty JSON array rather than null
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
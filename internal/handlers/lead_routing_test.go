@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newRoutingTest sets up a seller organization with an admin and two agents
+func newRoutingTest(t *testing.T) (db *gorm.DB, h *LeadHandler, seller *models.User, admin, agentA, agentB *models.User) {
+	t.Helper()
+	db = newTestDB(t, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+	cfg := testConfig(t)
+	cfg.MaxOpenLeadsPerListing = 0
+	h = newTestLeadHandler(t, db, cfg)
+	seller = createTestUser(t, db, "seller")
+	admin = createTestUser(t, db, "admin")
+	agentA = createTestUser(t, db, "agent_a")
+	agentB = createTestUser(t, db, "agent_b")
+	for _, m := range []struct {
+		user *models.User
+		role models.MemberRole
+	}{{admin, models.MemberRoleAdmin}, {agentA, models.MemberRoleAgent}, {agentB, models.MemberRoleAgent}} {
+		if err := db.Create(&models.OrganizationMember{OrganizationID: seller.ID, UserID: m.user.ID, Role: m.role}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return
+}
+
+func TestLeadRoundRobin(t *testing.T) {
+	tests := []struct {
+		name       string
+		roundRobin bool
+		leads      int
+		want       func(seller, admin, agentA, agentB uint) map[uint]int
+	}{
+		{name: "off sends everything to the seller", roundRobin: false, leads: 4,
+			want: func(seller, _, _, _ uint) map[uint]int { return map[uint]int{seller: 4} }},
+		{name: "on rotates evenly", roundRobin: true, leads: 9,
+			want: func(_, admin, agentA, agentB uint) map[uint]int {
+				return map[uint]int{admin: 3, agentA: 3, agentB: 3}
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, h, seller, admin, agentA, agentB := newRoutingTest(t)
+			listing := createTestListing(t, db, seller.ID)
+
+			r := gin.New()
+			r.PUT("/user/lead-routing", asUser(seller.ID), h.UpdateLeadRouting)
+			if w := serve(r, http.MethodPut, "/user/lead-routing", map[string]interface{}{"round_robin": tt.roundRobin}); w.Code != http.StatusOK {
+				t.Fatalf("update routing: %d %s", w.Code, w.Body)
+			}
+
+			// One buyer per lead keeps the per-sender limits out of the way
+			for i := 0; i < tt.leads; i++ {
+				buyer := createTestUser(t, db, fmt.Sprintf("buyer%d", i))
+				br := gin.New()
+				br.POST("/listings/:id/leads", asUser(buyer.ID), h.ContactSeller)
+				if w := serve(br, http.MethodPost, fmt.Sprintf("/listings/%d/leads", listing.ID), leadBody(nil)); w.Code != http.StatusOK {
+					t.Fatalf("lead %d: %d %s", i, w.Code, w.Body)
+				}
+			}
+
+			var leads []models.Lead
+			db.Find(&leads)
+			got := map[uint]int{}
+			for _, l := range leads {
+				got[*l.AssignedTo]++
+			}
+			want := tt.want(seller.ID, admin.ID, agentA.ID, agentB.ID)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("assignments %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReassignLead(t *testing.T) {
+	tests := []struct {
+		name     string
+		caller   string // seller, admin or agent_a
+		assignee string
+		want     int
+	}{
+		{name: "seller reassigns", caller: "seller", assignee: "agent_b", want: http.StatusOK},
+		{name: "admin member reassigns", caller: "admin", assignee: "agent_b", want: http.StatusOK},
+		{name: "back to the seller account", caller: "admin", assignee: "seller", want: http.StatusOK},
+		{name: "agent cannot reassign", caller: "agent_a", assignee: "agent_b", want: http.StatusNotFound},
+		{name: "outsider cannot be assigned", caller: "seller", assignee: "outsider", want: http.StatusBadRequest},
+		{name: "outsider cannot reassign", caller: "outsider", assignee: "agent_b", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, h, seller, admin, agentA, agentB := newRoutingTest(t)
+			users := map[string]*models.User{
+				"seller": seller, "admin": admin, "agent_a": agentA, "agent_b": agentB,
+				"outsider": createTestUser(t, db, "outsider"),
+			}
+			buyer := createTestUser(t, db, "buyer")
+			lead := models.Lead{SenderID: buyer.ID, ReceiverID: seller.ID, AssignedTo: &agentA.ID, Subject: "Hi", Message: "Hello"}
+			if err := db.Create(&lead).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			r := gin.New()
+			r.PUT("/leads/:id/assignee", asUser(users[tt.caller].ID), h.ReassignLead)
+			w := serve(r, http.MethodPut, fmt.Sprintf("/leads/%d/assignee", lead.ID), map[string]interface{}{"user_id": users[tt.assignee].ID})
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+
+			wantAssignee := agentA.ID
+			if tt.want == http.StatusOK {
+				wantAssignee = users[tt.assignee].ID
+			}
+			db.First(&lead, lead.ID)
+			if *lead.AssignedTo != wantAssignee {
+				t.Errorf("assigned to %d, want %d", *lead.AssignedTo, wantAssignee)
+			}
+		})
+	}
+}
+
+func TestRemovedMemberLeadsReturnToSeller(t *testing.T) {
+	db, h, seller, _, agentA, agentB := newRoutingTest(t)
+	buyer := createTestUser(t, db, "buyer")
+	for _, assignee := range []uint{agentA.ID, agentA.ID, agentB.ID} {
+		assignee := assignee
+		if err := db.Create(&models.Lead{SenderID: buyer.ID, ReceiverID: seller.ID, AssignedTo: &assignee, Subject: "Hi", Message: "Hello"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	r.DELETE("/user/organization/members/:userId", asUser(seller.ID), h.RemoveOrganizationMember)
+	if w := serve(r, http.MethodDelete, fmt.Sprintf("/user/organization/members/%d", agentA.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("remove member: %d %s", w.Code, w.Body)
+	}
+
+	counts := map[uint]int64{}
+	for _, id := range []uint{seller.ID, agentA.ID, agentB.ID} {
+		var n int64
+		db.Model(&models.Lead{}).Where("assigned_to = ?", id).Count(&n)
+		counts[id] = n
+	}
+	if counts[seller.ID] != 2 || counts[agentA.ID] != 0 || counts[agentB.ID] != 1 {
+		t.Errorf("assignments after removal: seller %d, removed agent %d, other agent %d; want 2, 0, 1",
+			counts[seller.ID], counts[agentA.ID], counts[agentB.ID])
+	}
+}
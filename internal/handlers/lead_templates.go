@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxLeadTemplateLength caps each translation of a template question
+const maxLeadTemplateLength = 300
+
+// leadTemplateKey is the shape of a template key, e.g. reason_for_sale
+var leadTemplateKey = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// errInvalidLeadTemplate is returned for a template ID that is unknown or retired
+var errInvalidLeadTemplate = errors.New("invalid lead template")
+
+// LeadTemplateHandler serves the question templates buyers start inquiries
+// from. The admin methods must sit behind middleware.RequireAdmin.
+type LeadTemplateHandler struct {
+	DB *gorm.DB
+}
+
+type leadTemplateRequest struct {
+	Key       string               `json:"key"`
+	Industry  string               `json:"industry"`
+	Questions models.LocalizedText `json:"questions"`
+	SortOrder int                  `json:"sort_order"`
+	Active    *bool                `json:"active"` // Optional; active when omitted
+}
+
+// toModel validates the request and copies it onto t
+func (r *leadTemplateRequest) toModel(t *models.LeadTemplate) error {
+	key := strings.TrimSpace(r.Key)
+	if !leadTemplateKey.MatchString(key) {
+		return errors.New("key must be 1-50 lowercase letters, digits or underscores")
+	}
+	industry := strings.TrimSpace(r.Industry)
+	if utf8.RuneCountInString(industry) > 100 {
+		return errors.New("industry must be at most 100 characters")
+	}
+	if len(r.Questions) == 0 {
+		return errors.New("questions must include at least one locale")
+	}
+	for locale, text := range r.Questions {
+		if strings.TrimSpace(locale) == "" || strings.TrimSpace(text) == "" {
+			return errors.New("questions must not contain empty locales or texts")
+		}
+		if utf8.RuneCountInString(text) > maxLeadTemplateLength {
+			return fmt.Errorf("each question must be at most %d characters", maxLeadTemplateLength)
+		}
+	}
+
+	t.Key = key
+	t.Industry = industry
+	t.Questions = r.Questions
+	t.SortOrder = r.SortOrder
+	t.Active = r.Active == nil || *r.Active
+	return nil
+}
+
+// activeLeadTemplate loads an active template for a new lead
+func activeLeadTemplate(db *gorm.DB, id uint) (*models.LeadTemplate, error) {
+	var template models.LeadTemplate
+	err := db.Where("id = ? AND active = ?", id, true).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errInvalidLeadTemplate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List returns the active templates in the request's locale. ?listing_id=
+// narrows them to the ones for that listing's industry, as does ?industry=;
+// templates without an industry are always included.
+func (h *LeadTemplateHandler) List(c *gin.Context) {
+	industry := strings.TrimSpace(c.Query("industry"))
+	if v := c.Query("listing_id"); v != "" {
+		listingID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+			return
+		}
+		var listing models.Listing
+		if err := h.DB.Select("id", "industry").
+			Where("id = ? AND status NOT IN ?", listingID, models.HiddenListingStatuses).
+			First(&listing).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+			return
+		}
+		industry = listing.Industry
+	}
+
+	query := h.DB.Where("active = ?", true)
+	if industry != "" {
+		query = query.Where("industry IN ?", []string{"", industry})
+	}
+	var templates []models.LeadTemplate
+	if err := query.Order("sort_order, id").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead templates"})
+		return
+	}
+
+	locale := requestLocale(c)
+	result := make([]gin.H, len(templates))
+	for i, t := range templates {
+		question, questionLocale := t.Questions.In(locale)
+		result[i] = gin.H{
+			"id":       t.ID,
+			"key":      t.Key,
+			"industry": t.Industry,
+			"question": question,
+			"locale":   questionLocale,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": result})
+}
+
+// AdminList returns every template with all its translations, retired ones included
+func (h *LeadTemplateHandler) AdminList(c *gin.Context) {
+	templates := []models.LeadTemplate{}
+	if err := h.DB.Order("sort_order, id").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead templates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// Create adds a template
+func (h *LeadTemplateHandler) Create(c *gin.Context) {
+	var req leadTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var template models.LeadTemplate
+	if err := req.toModel(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.keyAvailable(c, template.Key, 0) {
+		return
+	}
+	if err := h.DB.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create lead template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"template": template})
+}
+
+// Update replaces a template. Leads keep pointing at it, so rewording a
+// question should keep its meaning; add a new template for a new question.
+func (h *LeadTemplateHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead template ID"})
+		return
+	}
+
+	var req leadTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var template models.LeadTemplate
+	if err := h.DB.First(&template, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lead template not found"})
+		return
+	}
+	if err := req.toModel(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.keyAvailable(c, template.Key, template.ID) {
+		return
+	}
+	if err := h.DB.Save(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lead template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
+// Delete retires a template. It stops being offered, but the leads it seeded
+// keep counting towards the sellers' template usage.
+func (h *LeadTemplateHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead template ID"})
+		return
+	}
+
+	res := h.DB.Model(&models.LeadTemplate{}).Where("id = ?", id).Update("active", false)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lead template"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		// MySQL doesn't count rows that were already inactive
+		var count int64
+		if err := h.DB.Model(&models.LeadTemplate{}).Where("id = ?", id).Count(&count).Error; err != nil || count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lead template not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lead template deactivated"})
+}
+
+// keyAvailable responds with 409 and returns false when another template than
+// id already uses key
+func (h *LeadTemplateHandler) keyAvailable(c *gin.Context, key string, id uint) bool {
+	var count int64
+	if err := h.DB.Model(&models.LeadTemplate{}).Where("template_key = ? AND id <> ?", key, id).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check template key"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Template key already exists", "code": "TEMPLATE_KEY_TAKEN"})
+		return false
+	}
+	return true
+}
@@ -2,15 +2,21 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"trade_company/internal/auth"
+	"trade_company/internal/botcheck"
 	"trade_company/internal/config"
 	"trade_company/internal/middleware"
 	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/redisclient"
+	"trade_company/internal/wordfilter"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -22,53 +28,91 @@ type LeadHandler struct {
 	RedisClient  *redis.Client
 	Config       *config.Config
 	EmailService *auth.EmailService
+	Leaderboard  *redisclient.Trending // nil without Redis
+	Words        *wordfilter.Filter    // Sensitive word filter; nil when off
+	Bots         *botcheck.Checker
 }
 
 func NewLeadHandler(db *gorm.DB, redisClient *redis.Client, config *config.Config) *LeadHandler {
 	emailService := auth.NewEmailService(config)
+	words, err := wordfilter.New(config.SensitiveWordsMode, config.SensitiveWordsFile)
+	if err != nil {
+		// Fall back to the built-in terms rather than letting everything through
+		words, _ = wordfilter.New(config.SensitiveWordsMode, "")
+	}
 
 	return &LeadHandler{
 		DB:           db,
 		RedisClient:  redisClient,
 		Config:       config,
 		EmailService: emailService,
+		Leaderboard:  redisclient.NewTrending(redisClient),
+		Words:        words,
+		Bots:         botcheck.New(config, redisClient),
 	}
 }
 
+// inquiryTypeFilterValues are the values GetUserLeads accepts in ?inquiry_type=
+var inquiryTypeFilterValues = []string{
+	string(models.InquiryGeneral),
+	string(models.InquiryPriceNegotiation),
+	string(models.InquiryRequestFinancials),
+	string(models.InquiryScheduleViewing),
+	string(models.InquiryFinancingQuestion),
+}
+
 type contactSellerRequest struct {
-	SellerID     uint   `json:"seller_id" binding:"required"`
-	ListingID    *uint  `json:"listing_id"`
-	Subject      string `json:"subject" binding:"required,max=255"`
-	Message      string `json:"message" binding:"required,max=2000"`
-	ContactPhone string `json:"contact_phone"`
-
-	// Anti-spam fields
-	Honeypot       string `json:"website"`               // Hidden field to catch bots
-	FormTime       int64  `json:"form_time"`             // Time when form was rendered
+	SellerID     uint               `json:"seller_id"`  // Optional with a listing; must be its owner
+	ListingID    *uint              `json:"listing_id"` // Taken from the path on POST /listings/:id/leads
+	Subject      string             `json:"subject" binding:"required,max=255"`
+	InquiryType  models.InquiryType `json:"inquiry_type"` // Optional; general when omitted
+	TemplateID   *uint              `json:"template_id"`  // Lead template the buyer started from, if any
+	Message      string             `json:"message" binding:"required,max=2000"`
+	ContactPhone string             `json:"contact_phone"`
+
+	// Anti-spam fields; HONEYPOT_FIELDS are checked in the raw body
+	FormToken      string `json:"form_token"`            // Signed render time from GET /forms/token
+	FormTime       int64  `json:"form_time"`             // Time when form was rendered, for forms without a token
 	TurnstileToken string `json:"cf-turnstile-response"` // Cloudflare Turnstile token
 }
 
-// ContactSeller handles contact form submissions from buyers to sellers
+// ContactSeller handles contact form submissions from buyers to sellers. On
+// POST /listings/:id/leads the listing comes from the path and its owner is
+// the seller.
 func (h *LeadHandler) ContactSeller(c *gin.Context) {
-	var req contactSellerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// There are no anonymous leads: the open-lead cap, the rate limit and the
+	// contact reveal all key on the sender's account, so visitors sign in first
+	senderID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to contact the seller", "code": "LOGIN_REQUIRED"})
 		return
 	}
 
-	// Anti-bot checks
-	if req.Honeypot != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	var req contactSellerRequest
+	honeypot, err := h.Bots.BindJSON(c, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Check if form was submitted too quickly (less than 800ms)
-	if req.FormTime > 0 {
-		elapsed := time.Now().UnixMilli() - req.FormTime
-		if elapsed < 800 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
 			return
 		}
+		listingID := uint(id)
+		req.ListingID = &listingID
+	}
+	if req.ListingID == nil && req.SellerID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "seller_id or listing_id is required"})
+		return
+	}
+
+	// Anti-bot checks; leads that only look suspicious are kept but marked spam
+	bot := h.Bots.Evaluate(c, botcheck.EndpointLead, honeypot, botcheck.Form{Token: req.FormToken, Time: req.FormTime})
+	if bot.Verdict == botcheck.VerdictReject {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
 	}
 
 	// Verify Turnstile token (if enabled)
@@ -79,11 +123,20 @@ func (h *LeadHandler) ContactSeller(c *gin.Context) {
 		}
 	}
 
-	// Get sender user ID from session
-	senderID, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
-		return
+	// Verify listing exists, belongs to the seller and still takes inquiries
+	if req.ListingID != nil {
+		listing, err := models.InquiryListing(h.DB, *req.ListingID, senderID)
+		if listing != nil && req.SellerID == 0 {
+			req.SellerID = listing.OwnerID
+		}
+		if errors.Is(err, models.ErrListingUnavailable) && listing.OwnerID == req.SellerID {
+			respondListingUnavailable(c)
+			return
+		}
+		if err != nil || listing.OwnerID != req.SellerID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing"})
+			return
+		}
 	}
 
 	// Check if sender is trying to contact themselves
@@ -99,27 +152,53 @@ func (h *LeadHandler) ContactSeller(c *gin.Context) {
 		return
 	}
 
-	// Verify listing exists if provided
-	if req.ListingID != nil {
-		var listing models.Listing
-		if err := h.DB.Where("id = ? AND owner_id = ?", req.ListingID, req.SellerID).First(&listing).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing"})
+	// Limit unanswered leads per listing; the Redis rate limit below stays as the hourly backstop
+	if req.ListingID != nil && h.Config.MaxOpenLeadsPerListing > 0 {
+		openLeads, err := h.countOpenLeads(senderID, req.SellerID, *req.ListingID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing leads"})
+			return
+		}
+		if openLeads >= int64(h.Config.MaxOpenLeadsPerListing) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have open inquiries about this listing. Please wait for the seller to reply."})
 			return
 		}
 	}
 
+	// Only offered templates can seed a lead, so usage counts stay meaningful
+	if req.TemplateID != nil {
+		if _, err := activeLeadTemplate(h.DB, *req.TemplateID); err != nil {
+			if errors.Is(err, errInvalidLeadTemplate) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead template", "code": "INVALID_TEMPLATE"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check lead template"})
+			}
+			return
+		}
+	}
+
+	if !screenContent(c, h.Words, "lead", map[string]*string{"subject": &req.Subject, "message": &req.Message}) {
+		return
+	}
+
 	// Check rate limiting
 	if !h.checkContactRateLimit(senderID, req.SellerID) {
 		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many contact requests. Please try again later."})
 		return
 	}
 
-	// Create lead
+	if req.InquiryType == "" {
+		req.InquiryType = models.InquiryGeneral
+	}
+
+	// Create lead; the seller's routing rule picks who works it
 	lead := models.Lead{
 		SenderID:     senderID,
 		ReceiverID:   req.SellerID,
 		ListingID:    req.ListingID,
 		Subject:      req.Subject,
+		InquiryType:  req.InquiryType,
+		TemplateID:   req.TemplateID,
 		Message:      req.Message,
 		ContactPhone: req.ContactPhone,
 		IsRead:       false,
@@ -127,22 +206,32 @@ func (h *LeadHandler) ContactSeller(c *gin.Context) {
 	}
 
 	// Check for spam indicators
-	if h.isSpam(lead) {
+	if h.isSpam(lead) || bot.Verdict == botcheck.VerdictFlag {
 		lead.IsSpam = true
 	}
 
-	if err := h.DB.Create(&lead).Error; err != nil {
+	var route models.LeadRoute
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if route, err = models.RouteLead(tx, seller.ID); err != nil {
+			return err
+		}
+		lead.AssignedTo = &route.AssigneeID
+		return tx.Create(&lead).Error
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
 		return
 	}
 
-	// Send email notification to seller
-	if err := h.EmailService.SendLeadNotification(&seller, &lead); err != nil {
-		// Log error but don't fail the request
-	}
+	// Email the assignee and copy the seller's extra addresses; failures
+	// don't fail the request
+	h.notifyLead(&seller, &lead, route)
 
 	// Record contact for rate limiting
 	h.recordContact(senderID, req.SellerID)
+	if req.ListingID != nil && !lead.IsSpam {
+		h.Leaderboard.Record(c.Request.Context(), *req.ListingID, redisclient.TrendingLeadWeight)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Message sent successfully",
@@ -150,7 +239,10 @@ func (h *LeadHandler) ContactSeller(c *gin.Context) {
 	})
 }
 
-// GetUserLeads returns leads for the authenticated user
+// GetUserLeads returns the leads assigned to the authenticated user, optionally
+// only those of the inquiry types in ?inquiry_type= (repeated or
+// comma-separated). With ?all=true it returns every lead of the organizations
+// the user administers, their own account's included, whoever works them.
 func (h *LeadHandler) GetUserLeads(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -158,22 +250,53 @@ func (h *LeadHandler) GetUserLeads(c *gin.Context) {
 		return
 	}
 
+	p := pagination.Parse(c, pagination.Limits{Default: h.Config.LeadsDefaultPageSize, Max: h.Config.LeadsMaxPageSize})
+	query := h.DB.Model(&models.Lead{}).Where("assigned_to = ?", userID)
+	if c.Query("all") == "true" {
+		orgs, err := h.administeredOrganizations(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leads"})
+			return
+		}
+		query = h.DB.Model(&models.Lead{}).Where("receiver_id IN ?", orgs)
+	}
+
+	if types := queryValues(c, "inquiry_type"); len(types) > 0 {
+		if missing := missingValues(types, inquiryTypeFilterValues); len(missing) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":          "Unknown filter values",
+				"invalid_values": gin.H{"inquiry_type": missing},
+			})
+			return
+		}
+		query = query.Where("inquiry_type IN ?", types)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leads"})
+		return
+	}
+
 	var leads []models.Lead
-	if err := h.DB.Where("receiver_id = ?", userID).
+	if err := query.
 		Preload("Sender").
+		Preload("Assignee").
 		Preload("Listing").
 		Order("created_at DESC").
+		Scopes(p.Scope()).
 		Find(&leads).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leads"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"leads": leads,
+		"leads":      leads,
+		"pagination": pagination.NewMeta(p, total),
 	})
 }
 
-// MarkLeadAsRead marks a lead as read
+// MarkLeadAsRead marks a lead as read, for its receiver or assignee
 func (h *LeadHandler) MarkLeadAsRead(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -184,7 +307,7 @@ func (h *LeadHandler) MarkLeadAsRead(c *gin.Context) {
 	leadID := c.Param("id")
 
 	var lead models.Lead
-	if err := h.DB.Where("id = ? AND receiver_id = ?", leadID, userID).First(&lead).Error; err != nil {
+	if err := h.DB.Where("id = ? AND (receiver_id = ? OR assigned_to = ?)", leadID, userID, userID).First(&lead).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
 		return
 	}
@@ -219,6 +342,9 @@ func (h *LeadHandler) AdminGetLeads(c *gin.Context) {
 
 // Helper methods
 func (h *LeadHandler) checkContactRateLimit(senderID, receiverID uint) bool {
+	if h.RedisClient == nil {
+		return true // No shared counter without Redis; the open-lead cap still applies
+	}
 	key := fmt.Sprintf("contact_rate_limit:%d:%d", senderID, receiverID)
 	ctx := context.Background()
 
@@ -234,7 +360,33 @@ func (h *LeadHandler) checkContactRateLimit(senderID, receiverID uint) bool {
 	return true
 }
 
+// countOpenLeads counts the sender's leads on a listing that the seller has not replied to yet.
+// A message from the seller to the sender about the listing answers every lead sent before it.
+func (h *LeadHandler) countOpenLeads(senderID, receiverID, listingID uint) (int64, error) {
+	query := h.DB.Model(&models.Lead{}).
+		Where("sender_id = ? AND receiver_id = ? AND listing_id = ?", senderID, receiverID, listingID)
+
+	var lastReply models.Message
+	err := h.DB.Where("sender_id = ? AND receiver_id = ? AND listing_id = ?", receiverID, senderID, listingID).
+		Order("created_at DESC").
+		First(&lastReply).Error
+	if err == nil {
+		query = query.Where("created_at > ?", lastReply.CreatedAt)
+	} else if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (h *LeadHandler) recordContact(senderID, receiverID uint) {
+	if h.RedisClient == nil {
+		return
+	}
 	key := fmt.Sprintf("contact_rate_limit:%d:%d", senderID, receiverID)
 	ctx := context.Background()
 
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContactSellerInquiryType(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{} // nil leaves the field out
+		wantCode int
+		wantType models.InquiryType
+	}{
+		{name: "omitted defaults to general", wantCode: http.StatusOK, wantType: models.InquiryGeneral},
+		{name: "empty defaults to general", value: "", wantCode: http.StatusOK, wantType: models.InquiryGeneral},
+		{name: "price negotiation", value: "price_negotiation", wantCode: http.StatusOK, wantType: models.InquiryPriceNegotiation},
+		{name: "financing question", value: "financing_question", wantCode: http.StatusOK, wantType: models.InquiryFinancingQuestion},
+		{name: "unknown", value: "buy_now", wantCode: http.StatusBadRequest},
+		{name: "wrong case", value: "General", wantCode: http.StatusBadRequest},
+		{name: "not a string", value: 3, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+			h := newTestLeadHandler(t, db, testConfig(t))
+			seller := createTestUser(t, db, "seller")
+			buyer := createTestUser(t, db, "buyer")
+			listing := createTestListing(t, db, seller.ID)
+
+			extra := map[string]interface{}{}
+			if tt.value != nil {
+				extra["inquiry_type"] = tt.value
+			}
+			r := gin.New()
+			r.POST("/listings/:id/leads", asUser(buyer.ID), h.ContactSeller)
+			w := serve(r, http.MethodPost, fmt.Sprintf("/listings/%d/leads", listing.ID), leadBody(extra))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var lead models.Lead
+			if err := db.First(&lead).Error; err != nil {
+				t.Fatal(err)
+			}
+			if lead.InquiryType != tt.wantType {
+				t.Errorf("stored inquiry_type %q, want %q", lead.InquiryType, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestGetUserLeadsInquiryTypeFilter(t *testing.T) {
+	db := newTestDB(t)
+	h := newTestLeadHandler(t, db, testConfig(t))
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	for _, typ := range []models.InquiryType{models.InquiryGeneral, models.InquiryPriceNegotiation, models.InquiryPriceNegotiation, models.InquiryScheduleViewing} {
+		lead := models.Lead{SenderID: buyer.ID, ReceiverID: seller.ID, AssignedTo: &seller.ID, Subject: "Hi", Message: "Hello", InquiryType: typ}
+		if err := db.Create(&lead).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query     string
+		wantCode  int
+		wantCount int
+	}{
+		{query: "", wantCode: http.StatusOK, wantCount: 4},
+		{query: "?inquiry_type=price_negotiation", wantCode: http.StatusOK, wantCount: 2},
+		{query: "?inquiry_type=general,schedule_viewing", wantCode: http.StatusOK, wantCount: 2},
+		{query: "?inquiry_type=general&inquiry_type=price_negotiation", wantCode: http.StatusOK, wantCount: 3},
+		{query: "?inquiry_type=financing_question", wantCode: http.StatusOK, wantCount: 0},
+		{query: "?inquiry_type=bogus", wantCode: http.StatusBadRequest},
+	}
+
+	r := gin.New()
+	r.GET("/leads", asUser(seller.ID), h.GetUserLeads)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/leads"+tt.query, nil)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if got := len(decode(t, w)["leads"].([]interface{})); got != tt.wantCount {
+				t.Errorf("%d leads, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
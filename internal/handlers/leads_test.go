@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContactSellerOpenLeadCap(t *testing.T) {
+	// Each step is a lead from a buyer, or with reply set a message from the
+	// seller to buyer 0, about listing 0 or 1
+	type step struct {
+		buyer, listing int
+		reply          bool
+		want           int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{name: "third open lead is refused", steps: []step{
+			{want: http.StatusOK}, {want: http.StatusOK}, {want: http.StatusConflict},
+		}},
+		{name: "seller reply resets the cap", steps: []step{
+			{want: http.StatusOK}, {want: http.StatusOK}, {reply: true},
+			{want: http.StatusOK}, {want: http.StatusOK}, {want: http.StatusConflict},
+		}},
+		{name: "reply about another listing does not reset it", steps: []step{
+			{want: http.StatusOK}, {want: http.StatusOK}, {reply: true, listing: 1},
+			{want: http.StatusConflict},
+		}},
+		{name: "cap is per listing", steps: []step{
+			{want: http.StatusOK}, {want: http.StatusOK}, {listing: 1, want: http.StatusOK},
+		}},
+		{name: "cap is per buyer", steps: []step{
+			{want: http.StatusOK}, {want: http.StatusOK}, {buyer: 1, want: http.StatusOK},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+			cfg := testConfig(t)
+			cfg.MaxOpenLeadsPerListing = 2
+			h := newTestLeadHandler(t, db, cfg)
+			seller := createTestUser(t, db, "seller")
+			buyers := []*models.User{createTestUser(t, db, "buyer0"), createTestUser(t, db, "buyer1")}
+			listings := []*models.Listing{createTestListing(t, db, seller.ID), createTestListing(t, db, seller.ID)}
+
+			for i, s := range tt.steps {
+				if s.reply {
+					reply := models.Message{SenderID: seller.ID, ReceiverID: buyers[0].ID, ListingID: &listings[s.listing].ID, Content: "Thanks", CreatedAt: time.Now()}
+					if err := db.Create(&reply).Error; err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				r := gin.New()
+				r.POST("/listings/:id/leads", asUser(buyers[s.buyer].ID), h.ContactSeller)
+				w := serve(r, http.MethodPost, fmt.Sprintf("/listings/%d/leads", listings[s.listing].ID), leadBody(nil))
+				if w.Code != s.want {
+					t.Fatalf("step %d: status %d, want %d: %s", i, w.Code, s.want, w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestContactSellerRequiresSignIn(t *testing.T) {
+	db := newTestDB(t)
+	h := newTestLeadHandler(t, db, testConfig(t))
+	seller := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, seller.ID)
+
+	r := gin.New()
+	r.POST("/listings/:id/leads", h.ContactSeller)
+	w := serve(r, http.MethodPost, fmt.Sprintf("/listings/%d/leads", listing.ID), leadBody(nil))
+	if w.Code != http.StatusUnauthorized || decode(t, w)["code"] != "LOGIN_REQUIRED" {
+		t.Errorf("anonymous lead: status %d %s, want 401 LOGIN_REQUIRED", w.Code, w.Body)
+	}
+
+	var leads int64
+	db.Model(&models.Lead{}).Count(&leads)
+	if leads != 0 {
+		t.Errorf("%d leads stored for an anonymous visitor", leads)
+	}
+}
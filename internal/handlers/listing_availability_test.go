@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInquiriesAboutUnavailableListings(t *testing.T) {
+	db := newTestDB(t, &models.LeadRoutingRule{}, &models.OrganizationMember{})
+	cfg := testConfig(t)
+	leads := newTestLeadHandler(t, db, cfg)
+	messages := &MessageHandler{DB: db, Cfg: cfg}
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	withStatus := func(status models.ListingStatus) func(*models.Listing) {
+		return func(l *models.Listing) { l.Status = status }
+	}
+	listings := map[string]*models.Listing{
+		"active":   createTestListing(t, db, seller.ID),
+		"sold":     createTestListing(t, db, seller.ID, withStatus(models.ListingStatusSold)),
+		"inactive": createTestListing(t, db, seller.ID, withStatus(models.ListingStatusInactive)),
+		"deleted":  createTestListing(t, db, seller.ID, withStatus(models.ListingStatusDeleted)),
+		// Someone else's private listing doesn't exist as far as the buyer knows
+		"private": createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Visibility = models.ListingVisibilityPrivate }),
+	}
+	as := func(user *models.User) *gin.Engine {
+		r := gin.New()
+		r.Use(asUser(user.ID))
+		r.POST("/listings/:id/leads", leads.ContactSeller)
+		r.POST("/messages", messages.Create)
+		return r
+	}
+
+	tests := []struct {
+		listing       string
+		lead, message int
+	}{
+		{listing: "active", lead: http.StatusOK, message: http.StatusCreated},
+		{listing: "sold", lead: http.StatusConflict, message: http.StatusConflict},
+		{listing: "inactive", lead: http.StatusConflict, message: http.StatusConflict},
+		{listing: "deleted", lead: http.StatusConflict, message: http.StatusConflict},
+		{listing: "private", lead: http.StatusBadRequest, message: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.listing, func(t *testing.T) {
+			id := listings[tt.listing].ID
+			w := serve(as(buyer), http.MethodPost, fmt.Sprintf("/listings/%d/leads", id), leadBody(nil))
+			if w.Code != tt.lead {
+				t.Fatalf("lead: status %d, want %d: %s", w.Code, tt.lead, w.Body)
+			}
+			if tt.lead == http.StatusConflict && decode(t, w)["code"] != "LISTING_UNAVAILABLE" {
+				t.Errorf("lead: %s, want LISTING_UNAVAILABLE", w.Body)
+			}
+
+			w = serve(as(buyer), http.MethodPost, "/messages", map[string]interface{}{
+				"receiver_id": seller.ID, "listing_id": id, "content": "Is it still available?",
+			})
+			if w.Code != tt.message {
+				t.Fatalf("message: status %d, want %d: %s", w.Code, tt.message, w.Body)
+			}
+			if tt.message == http.StatusConflict && decode(t, w)["code"] != "LISTING_UNAVAILABLE" {
+				t.Errorf("message: %s, want LISTING_UNAVAILABLE", w.Body)
+			}
+		})
+	}
+
+	// The owner can still answer earlier inquiries about a sold listing
+	w := serve(as(seller), http.MethodPost, "/messages", map[string]interface{}{
+		"receiver_id": buyer.ID, "listing_id": listings["sold"].ID, "content": "Sorry, it sold last week",
+	})
+	if w.Code != http.StatusCreated {
+		t.Errorf("owner reply: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestMessageThreadTombstone(t *testing.T) {
+	db := newTestDB(t)
+	h := &MessageHandler{DB: db, Cfg: testConfig(t)}
+	seller := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	active := createTestListing(t, db, seller.ID, func(l *models.Listing) { l.Title = "Open shop" })
+	sold := createTestListing(t, db, seller.ID, func(l *models.Listing) {
+		l.Title = "Sold shop"
+		l.Status = models.ListingStatusSold
+	})
+	for _, l := range []*models.Listing{active, sold} {
+		db.Create(&models.Message{SenderID: buyer.ID, ReceiverID: seller.ID, ListingID: &l.ID, Content: "Hello"})
+	}
+
+	r := gin.New()
+	r.GET("/messages", asUser(buyer.ID), h.List)
+	r.GET("/messages/:id", asUser(buyer.ID), h.Get)
+
+	w := serve(r, http.MethodGet, "/messages", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: status %d: %s", w.Code, w.Body)
+	}
+	items := decode(t, w)["messages"].([]interface{})
+	if len(items) != 2 {
+		t.Fatalf("%d messages, want 2", len(items))
+	}
+	for _, item := range items {
+		listing := item.(map[string]interface{})["listing"].(map[string]interface{})
+		switch uint(listing["id"].(float64)) {
+		case active.ID:
+			if listing["unavailable"] != false || listing["title"] != "Open shop" || listing["price"] == nil {
+				t.Errorf("active listing %v, want the full summary", listing)
+			}
+		case sold.ID:
+			// The title stays readable; nothing else to link to
+			if len(listing) != 3 || listing["unavailable"] != true || listing["title"] != "Sold shop" {
+				t.Errorf("sold listing %v, want a tombstone", listing)
+			}
+		}
+	}
+
+	var message models.Message
+	db.Where("listing_id = ?", sold.ID).First(&message)
+	w = serve(r, http.MethodGet, fmt.Sprintf("/messages/%d", message.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: status %d: %s", w.Code, w.Body)
+	}
+	listing := decode(t, w)["message"].(map[string]interface{})["listing"].(map[string]interface{})
+	if listing["unavailable"] != true || listing["title"] != "Sold shop" {
+		t.Errorf("message listing %v, want a tombstone", listing)
+	}
+}
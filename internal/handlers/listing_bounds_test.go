@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListingAmountBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		value   int64
+		invalid string // Expected field error; empty when in range
+	}{
+		{name: "price below min", field: "price", value: 99, invalid: "must be between 100 and 5000"},
+		{name: "price at min", field: "price", value: 100},
+		{name: "price at max", field: "price", value: 5000},
+		{name: "price above max", field: "price", value: 5001, invalid: "must be between 100 and 5000"},
+		{name: "no rent", field: "rent", value: 0},
+		{name: "rent in range", field: "rent", value: 300},
+		{name: "negative rent", field: "rent", value: -1, invalid: "must be between 0 and 500"},
+		{name: "rent above max", field: "rent", value: 501, invalid: "must be between 0 and 500"},
+		{name: "deposit in range", field: "deposit", value: 600},
+		{name: "deposit above max", field: "deposit", value: 601, invalid: "must be between 0 and 600"},
+		{name: "annual revenue in range", field: "annual_revenue", value: 7000},
+		{name: "annual revenue above max", field: "annual_revenue", value: 7001, invalid: "must be between 0 and 7000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.ListingPriceMin, cfg.ListingPriceMax = 100, 5000
+			cfg.ListingRentMax, cfg.ListingDepositMax, cfg.ListingAnnualRevenueMax = 500, 600, 7000
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Price = 1000 })
+			r := gin.New()
+			r.POST("/listings", asUser(owner.ID), h.Create)
+			r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+
+			// A rejected amount names its bounds
+			check := func(action string, body map[string]interface{}) {
+				t.Helper()
+				if tt.invalid == "" {
+					return
+				}
+				fields, _ := body["fields"].(map[string]interface{})
+				if fields[tt.field] != tt.invalid {
+					t.Errorf("%s: fields %v, want %s %q", action, fields, tt.field, tt.invalid)
+				}
+			}
+
+			want := http.StatusOK
+			if tt.invalid != "" {
+				want = http.StatusBadRequest
+			}
+			w := serve(r, http.MethodPut, fmt.Sprintf("/listings/%d", listing.ID), map[string]interface{}{tt.field: tt.value})
+			if w.Code != want {
+				t.Fatalf("update: status %d, want %d: %s", w.Code, want, w.Body)
+			}
+			check("update", decode(t, w))
+
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if tt.invalid != "" && stored.Price != 1000 {
+				t.Errorf("price changed to %d by a rejected update", stored.Price)
+			}
+
+			// Only the price is taken on create
+			if tt.field != "price" {
+				return
+			}
+			want = http.StatusCreated
+			if tt.invalid != "" {
+				want = http.StatusBadRequest
+			}
+			w = serve(r, http.MethodPost, "/listings", map[string]interface{}{"title": "Corner shop", "price": tt.value})
+			if w.Code != want {
+				t.Fatalf("create: status %d, want %d: %s", w.Code, want, w.Body)
+			}
+			check("create", decode(t, w))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// searchCacheIgnoredParams don't change which listings a search returns
+var searchCacheIgnoredParams = []string{"lang", "explain", "page", "limit"}
+
+// loadListingDetail loads a visible listing with the relations the detail
+// page shows, from the cache when it has it. The returned listing is the
+// viewer-independent copy; callers apply owner/admin visibility on top.
+func (h *ListingsHandler) loadListingDetail(id uint) (*models.Listing, error) {
+	if h.Cache != nil {
+		if cached, err := h.Cache.GetCachedListingDetail(id); err == nil && cached != nil {
+			return cached, nil
+		}
+	}
+
+	var listing models.Listing
+	if err := h.DB.Preload("Images").
+		Preload("Owner").
+		Preload("Documents").
+		Where("status NOT IN ?", models.HiddenListingStatuses).
+		First(&listing, id).Error; err != nil {
+		return nil, err
+	}
+
+	if h.Cache != nil {
+		_ = h.Cache.CacheListingDetail(listing.ID, &listing)
+	}
+	return &listing, nil
+}
+
+// searchListings counts and loads page p of a filtered and ordered List query, or
+// serves the page from the search cache. The cache key is the request's query
+// string (minus params that only change the presentation) with the page and
+// limit as clamped by pagination.Parse.
+func (h *ListingsHandler) searchListings(c *gin.Context, query *gorm.DB, p pagination.Params) (*redisclient.ListingSearchResult, error) {
+	var key string
+	var keyParams map[string]interface{}
+	if h.Cache != nil {
+		values := c.Request.URL.Query()
+		for _, param := range searchCacheIgnoredParams {
+			values.Del(param)
+		}
+		key = values.Encode()
+		keyParams = map[string]interface{}{"page": p.Page, "limit": p.Limit}
+		if cached, err := h.Cache.GetCachedListingSearch(key, keyParams); err == nil && cached != nil {
+			return cached, nil
+		}
+	}
+
+	result := &redisclient.ListingSearchResult{}
+	if err := query.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := query.Preload("Images", "moderation_status = ?", models.ImageModerationApproved).
+		Preload("Owner").
+		Scopes(p.Scope()).
+		Find(&result.Listings).Error; err != nil {
+		return nil, err
+	}
+
+	if h.Cache != nil {
+		_ = h.Cache.CacheListingSearch(key, keyParams, result)
+	}
+	return result, nil
+}
+
+// invalidateListing drops the cached detail and searches after a listing
+// changes. It is best effort: a stale entry expires with its TTL.
+func (h *ListingsHandler) invalidateListing(id uint) {
+	invalidateListingCache(h.Cache, id)
+}
+
+// invalidateListingCache is invalidateListing for handlers outside
+// ListingsHandler that change what a listing's detail shows
+func invalidateListingCache(cache *redisclient.CacheService, id uint) {
+	if cache != nil {
+		_ = cache.InvalidateListingCache(id)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+	"trade_company/internal/storage"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestListingDetailCache(t *testing.T) {
+	db := newTestDB(t)
+	mr := miniredis.RunT(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t), Cache: redisclient.NewCacheService(redis.NewClient(&redis.Options{Addr: mr.Addr()}))}
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Title = "Corner cafe" })
+	db.Create(&models.Image{ListingID: listing.ID, Filename: "a.jpg", URL: "/uploads/a.jpg", ModerationStatus: models.ImageModerationApproved})
+
+	r := gin.New()
+	r.GET("/listings/:id", h.Get)
+	r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+	target := fmt.Sprintf("/listings/%d", listing.ID)
+	queries := countQueries(t, db)
+	get := func(step string) map[string]interface{} {
+		t.Helper()
+		*queries = 0
+		w := serve(r, http.MethodGet, target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", step, w.Code, w.Body)
+		}
+		return decode(t, w)["listing"].(map[string]interface{})
+	}
+
+	first := get("miss")
+	if *queries == 0 {
+		t.Fatal("miss: no queries, want the listing loaded from the database")
+	}
+	if !mr.Exists(fmt.Sprintf("%s%d", redisclient.ListingDetailKey, listing.ID)) {
+		t.Fatal("detail not cached after the miss")
+	}
+
+	// Changed behind the handler's back, so only a database read would see it
+	db.Model(&models.Listing{}).Where("id = ?", listing.ID).UpdateColumn("title", "Changed in the database")
+	second := get("hit")
+	if *queries != 0 {
+		t.Errorf("hit: %d queries, want none", *queries)
+	}
+	if second["title"] != "Corner cafe" || len(second["images"].([]interface{})) != 1 {
+		t.Errorf("hit: %v, want the cached listing with its image", second)
+	}
+	// Each view counts, and the cached entry shows the current total
+	if first["view_count"] != float64(1) || second["view_count"] != float64(2) {
+		t.Errorf("view counts %v then %v, want 1 then 2", first["view_count"], second["view_count"])
+	}
+
+	// Updates drop the entry
+	if w := serve(r, http.MethodPut, target, map[string]interface{}{"title": "Renamed cafe"}); w.Code != http.StatusOK {
+		t.Fatalf("update: status %d: %s", w.Code, w.Body)
+	}
+	third := get("after update")
+	if *queries == 0 || third["title"] != "Renamed cafe" || third["view_count"] != float64(3) {
+		t.Errorf("after update: %d queries, title %v, views %v", *queries, third["title"], third["view_count"])
+	}
+}
+
+func TestListingDetailWithoutCache(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Title = "Corner cafe" })
+	r := gin.New()
+	r.GET("/listings/:id", h.Get)
+	queries := countQueries(t, db)
+
+	for i := 1; i <= 2; i++ {
+		*queries = 0
+		w := serve(r, http.MethodGet, fmt.Sprintf("/listings/%d", listing.ID), nil)
+		if w.Code != http.StatusOK || *queries == 0 {
+			t.Fatalf("get %d: status %d after %d queries", i, w.Code, *queries)
+		}
+		if views := decode(t, w)["listing"].(map[string]interface{})["view_count"]; views != float64(i) {
+			t.Errorf("get %d: view_count %v", i, views)
+		}
+	}
+}
+
+func TestListingSearchCache(t *testing.T) {
+	db := newTestDB(t)
+	mr := miniredis.RunT(t)
+	cfg := testConfig(t)
+	cfg.PublicUploadDir = t.TempDir()
+	h := &ListingsHandler{DB: db, Cfg: cfg, Storage: storage.New(cfg), Cache: redisclient.NewCacheService(redis.NewClient(&redis.Options{Addr: mr.Addr()}))}
+	owner := createTestUser(t, db, "seller")
+	kept := createTestListing(t, db, owner.ID)
+	removed := createTestListing(t, db, owner.ID)
+
+	r := gin.New()
+	r.GET("/listings", h.List)
+	r.POST("/listings", asUser(owner.ID), h.Create)
+	r.DELETE("/listings/:id", asUser(owner.ID), h.Delete)
+	r.POST("/listings/:id/images", asUser(owner.ID), h.UploadImages)
+	queries := countQueries(t, db)
+	search := func(target string) (total float64, queried bool) {
+		t.Helper()
+		*queries = 0
+		w := serve(r, http.MethodGet, target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", target, w.Code, w.Body)
+		}
+		return decode(t, w)["pagination"].(map[string]interface{})["total"].(float64), *queries > 0
+	}
+	cachedSearches := func() int {
+		n := 0
+		for _, key := range mr.Keys() {
+			if strings.HasPrefix(key, redisclient.ListingSearchKey) {
+				n++
+			}
+		}
+		return n
+	}
+
+	if total, queried := search("/listings?location=Taipei&limit=10"); total != 2 || !queried {
+		t.Fatalf("miss: total %v, queried %v; want 2 from the database", total, queried)
+	}
+	// Added behind the handler's back, so only a database read would count it
+	createTestListing(t, db, owner.ID)
+	for _, target := range []string{
+		"/listings?location=Taipei&limit=10",
+		"/listings?limit=10&location=Taipei&page=1",
+		"/listings?location=Taipei&limit=10&lang=zh-TW",
+	} {
+		if total, queried := search(target); total != 2 || queried {
+			t.Errorf("GET %s: total %v, queried %v; want the cached 2", target, total, queried)
+		}
+	}
+	if total, queried := search("/listings?location=Taipei&limit=5"); total != 3 || !queried {
+		t.Errorf("other limit: total %v, queried %v; want 3 from the database", total, queried)
+	}
+
+	mutations := []struct {
+		name string
+		do   func() int
+	}{
+		{"create", func() int {
+			return serve(r, http.MethodPost, "/listings", map[string]interface{}{"title": "Noodle stand", "price": 500000, "location": "Taipei"}).Code
+		}},
+		{"delete", func() int {
+			return serve(r, http.MethodDelete, fmt.Sprintf("/listings/%d", removed.ID), nil).Code
+		}},
+		{"upload images", func() int {
+			return uploadImages(t, r, fmt.Sprintf("/listings/%d/images", kept.ID), map[string][]byte{"a.png": encodeImage(t, "png", 8, 8)}).Code
+		}},
+	}
+	for _, m := range mutations {
+		search("/listings?location=Taipei&limit=10")
+		if cachedSearches() == 0 {
+			t.Fatalf("%s: nothing cached before the change", m.name)
+		}
+		if code := m.do(); code >= 300 {
+			t.Fatalf("%s: status %d", m.name, code)
+		}
+		if n := cachedSearches(); n != 0 {
+			t.Errorf("%s: %d search pages still cached", m.name, n)
+		}
+		if _, queried := search("/listings?location=Taipei&limit=10"); !queried {
+			t.Errorf("%s: next search served from the cache", m.name)
+		}
+	}
+}
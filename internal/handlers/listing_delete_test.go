@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeleteAndRestoreListing(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    models.ListingStatus
+		images    int
+		minImages int
+		legacy    bool // deleted before the previous status was recorded
+		expired   bool // undo window has passed
+		want      int
+		wantAfter models.ListingStatus
+	}{
+		{name: "active comes back active", status: models.ListingStatusActive, want: http.StatusOK, wantAfter: models.ListingStatusActive},
+		{name: "sold stays sold", status: models.ListingStatusSold, want: http.StatusOK, wantAfter: models.ListingStatusSold},
+		{name: "inactive stays inactive", status: models.ListingStatusInactive, images: 3, want: http.StatusOK, wantAfter: models.ListingStatusInactive},
+		{name: "active without enough images goes inactive", status: models.ListingStatusActive, images: 1, minImages: 2, want: http.StatusOK, wantAfter: models.ListingStatusInactive},
+		{name: "active with enough images", status: models.ListingStatusActive, images: 2, minImages: 2, want: http.StatusOK, wantAfter: models.ListingStatusActive},
+		{name: "legacy deletion counts as active", status: models.ListingStatusSold, legacy: true, want: http.StatusOK, wantAfter: models.ListingStatusActive},
+		{name: "undo window expired", status: models.ListingStatusSold, expired: true, want: http.StatusGone, wantAfter: models.ListingStatusPendingDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.ListingMinImages = tt.minImages
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Status = tt.status })
+			for i := 0; i < tt.images; i++ {
+				name := fmt.Sprintf("listing_%d_%d.jpg", listing.ID, i)
+				if err := db.Create(&models.Image{ListingID: listing.ID, Filename: name, URL: "/uploads/" + name}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := gin.New()
+			r.DELETE("/listings/:id", asUser(owner.ID), h.Delete)
+			r.POST("/listings/:id/restore", asUser(owner.ID), h.Restore)
+			r.GET("/user/listings", asUser(owner.ID), h.Mine)
+			target := fmt.Sprintf("/listings/%d", listing.ID)
+			if w := serve(r, http.MethodDelete, target, nil); w.Code != http.StatusOK {
+				t.Fatalf("delete: %d %s", w.Code, w.Body)
+			}
+
+			mine := decode(t, serve(r, http.MethodGet, "/user/listings", nil))["listings"].([]interface{})
+			if len(mine) != 1 || mine[0].(map[string]interface{})["can_restore"] != true {
+				t.Fatalf("pending deletion should be listed as restorable: %v", mine)
+			}
+
+			if tt.legacy {
+				db.Model(listing).Update("status_before_delete", nil)
+			}
+			if tt.expired {
+				db.Model(listing).Update("delete_after", time.Now().Add(-time.Minute))
+			}
+
+			w := serve(r, http.MethodPost, target+"/restore", nil)
+			if w.Code != tt.want {
+				t.Fatalf("restore: %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+
+			var after models.Listing
+			db.First(&after, listing.ID)
+			if after.Status != tt.wantAfter {
+				t.Errorf("status after restore %q, want %q", after.Status, tt.wantAfter)
+			}
+			if tt.want == http.StatusOK && (after.DeleteAfter != nil || after.StatusBeforeDelete != nil) {
+				t.Errorf("restore left delete_after %v, status_before_delete %v", after.DeleteAfter, after.StatusBeforeDelete)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDescriptionSanitizedOnSave(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantHTML string
+		wantText string
+	}{
+		{name: "plain text keeps its ampersands", input: "Tom & Jerry's café, 3 < 5",
+			wantHTML: "Tom &amp; Jerry&#39;s café, 3 &lt; 5", wantText: "Tom & Jerry's café, 3 < 5"},
+		{name: "allowed markup", input: "<p>Open <b>daily</b></p>",
+			wantHTML: "<p>Open <strong>daily</strong></p>", wantText: "Open daily"},
+		{name: "scripts removed", input: `Hi<script>alert(1)</script><img src=x onerror=alert(1)>`,
+			wantHTML: "Hi", wantText: "Hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+			owner := createTestUser(t, db, "seller")
+
+			r := gin.New()
+			r.POST("/listings", asUser(owner.ID), h.Create)
+			r.GET("/listings/:id", h.Get)
+			w := serve(r, http.MethodPost, "/listings", map[string]interface{}{
+				"title": "Corner shop", "description": tt.input, "price": 1000000,
+			})
+			if w.Code != http.StatusCreated {
+				t.Fatalf("create status %d: %s", w.Code, w.Body)
+			}
+			created := decode(t, w)["listing"].(map[string]interface{})
+			if created["description"] != tt.wantHTML {
+				t.Errorf("create response description %q, want %q", created["description"], tt.wantHTML)
+			}
+
+			var stored models.Listing
+			db.First(&stored, uint(created["id"].(float64)))
+			if stored.Description != tt.wantHTML {
+				t.Errorf("stored description %q, want %q", stored.Description, tt.wantHTML)
+			}
+			if stored.DescriptionText != tt.wantText {
+				t.Errorf("stored description_text %q, want %q", stored.DescriptionText, tt.wantText)
+			}
+
+			// Listings only show once they meet the image minimum
+			db.Model(&stored).Update("status", models.ListingStatusActive)
+			w = serve(r, http.MethodGet, fmt.Sprintf("/listings/%d", stored.ID), nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("get status %d: %s", w.Code, w.Body)
+			}
+			got := decode(t, w)["listing"].(map[string]interface{})
+			if got["description"] != tt.wantHTML {
+				t.Errorf("detail description %q, want %q", got["description"], tt.wantHTML)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pdfMagic is the signature every PDF file starts with
+var pdfMagic = []byte("%PDF-")
+
+// documentURL is where a listing document is downloaded from
+func documentURL(doc *models.ListingDocument) string {
+	return fmt.Sprintf("/api/v1/listings/%d/documents/%d", doc.ListingID, doc.ID)
+}
+
+// documentSummaries lists a listing's documents and their counts by visibility for the detail response
+func documentSummaries(docs []models.ListingDocument) (gin.H, []gin.H) {
+	list := make([]gin.H, 0, len(docs))
+	counts := gin.H{
+		"total":                             len(docs),
+		models.DocumentVisibilityPublic:     0,
+		models.DocumentVisibilityBuyersOnly: 0,
+		models.DocumentVisibilityReview:     0,
+	}
+	for i := range docs {
+		doc := &docs[i]
+		counts[doc.Visibility] = counts[doc.Visibility].(int) + 1
+		list = append(list, gin.H{
+			"id":           doc.ID,
+			"filename":     doc.Filename,
+			"size":         doc.Size,
+			"mime_type":    doc.MimeType,
+			"visibility":   doc.Visibility,
+			"download_url": documentURL(doc),
+		})
+	}
+	return counts, list
+}
+
+// publicDocuments drops the documents only the owner and admins may see
+func publicDocuments(docs []models.ListingDocument) []models.ListingDocument {
+	public := make([]models.ListingDocument, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Visibility != models.DocumentVisibilityReview {
+			public = append(public, doc)
+		}
+	}
+	return public
+}
+
+// canDownloadBuyerDocuments reports whether a user may see buyers-only documents:
+// the owner, an admin, or a buyer with a non-spam lead or a transaction on the listing
+func (h *ListingsHandler) canDownloadBuyerDocuments(userID uint, listing *models.Listing) (bool, error) {
+	if userID == listing.OwnerID || isAdmin(h.DB, userID) {
+		return true, nil
+	}
+
+	var count int64
+	if err := h.DB.Model(&models.Lead{}).
+		Where("sender_id = ? AND listing_id = ? AND is_spam = ?", userID, listing.ID, false).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := h.DB.Model(&models.Transaction{}).
+		Where("buyer_id = ? AND listing_id = ?", userID, listing.ID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UploadDocument attaches a PDF to the owner's listing. A document uploaded
+// with visibility=review is a financial statement for the admins to check the
+// listing's figures against; it puts the listing in the verification queue.
+func (h *ListingsHandler) UploadDocument(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("id = ? AND owner_id = ? AND status NOT IN ?", id, userID, models.HiddenListingStatuses).
+		First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	}
+
+	visibility := c.DefaultPostForm("visibility", models.DocumentVisibilityPublic)
+	switch visibility {
+	case models.DocumentVisibilityPublic, models.DocumentVisibilityBuyersOnly, models.DocumentVisibilityReview:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, buyers_only or review"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No document provided"})
+		return
+	}
+
+	maxSize := int64(h.Cfg.MaxDocumentSizeMB) << 20
+	if file.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":       "Document is too large",
+			"max_size_mb": h.Cfg.MaxDocumentSizeMB,
+		})
+		return
+	}
+
+	// Trust the file's bytes, not its name or declared Content-Type
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read document"})
+		return
+	}
+	header := make([]byte, len(pdfMagic))
+	_, err = io.ReadFull(src, header)
+	src.Close()
+	if err != nil || !bytes.Equal(header, pdfMagic) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only PDF documents are allowed"})
+		return
+	}
+
+	key, err := h.Storage.SavePrivate(file, fmt.Sprintf("listing_%d_doc", listing.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+
+	doc := models.ListingDocument{
+		ListingID:  listing.ID,
+		Filename:   file.Filename,
+		Size:       file.Size,
+		MimeType:   "application/pdf",
+		StorageKey: key,
+		Visibility: visibility,
+	}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&doc).Error; err != nil {
+			return err
+		}
+		if visibility != models.DocumentVisibilityReview {
+			return nil
+		}
+		return tx.Model(&listing).Update("financials_submitted_at", time.Now()).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document"})
+		return
+	}
+	h.invalidateListing(listing.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Document uploaded successfully",
+		"document":     doc,
+		"download_url": documentURL(&doc),
+	})
+}
+
+// DownloadDocument enforces a listing document's visibility, then redirects to
+// a signed link to the file
+func (h *ListingsHandler) DownloadDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+	docID, err := strconv.ParseUint(c.Param("docId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("status NOT IN ?", models.HiddenListingStatuses).First(&listing, id).Error; err != nil || !h.canOpen(c, &listing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	var doc models.ListingDocument
+	if err := h.DB.Where("id = ? AND listing_id = ?", docID, listing.ID).First(&doc).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	if doc.Visibility != models.DocumentVisibilityPublic {
+		userID, ok := c.Get("user_id")
+		uid, isUint := userID.(uint)
+		if !ok || !isUint {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to download this document"})
+			return
+		}
+		if doc.Visibility == models.DocumentVisibilityReview {
+			if uid != listing.OwnerID && !isAdmin(h.DB, uid) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+				return
+			}
+		}
+		allowed, err := h.canDownloadBuyerDocuments(uid, &listing)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check document access"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Contact the seller to access this document"})
+			return
+		}
+	}
+
+	// The file itself is served from a short-lived signed link, like every private upload
+	c.Header("Cache-Control", "private, no-store")
+	c.Redirect(http.StatusFound, h.Storage.SignedURL(doc.StorageKey, doc.Filename))
+}
+
+// DeleteDocument removes a document from the owner's listing
+func (h *ListingsHandler) DeleteDocument(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+	docID, err := strconv.ParseUint(c.Param("docId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var doc models.ListingDocument
+	if err := h.DB.Joins("JOIN listings ON listings.id = listing_documents.listing_id").
+		Where("listing_documents.id = ? AND listing_documents.listing_id = ? AND listings.owner_id = ?", docID, id, userID).
+		First(&doc).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found or access denied"})
+		return
+	}
+
+	if err := h.DB.Delete(&doc).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+		return
+	}
+	_ = jobs.RemoveDocumentFile(h.DB, h.Storage, doc.StorageKey)
+	h.invalidateListing(doc.ListingID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDownloadDocumentUsesSignedLink(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+		caller     string // owner, buyer (has a lead), stranger, or anonymous
+		want       int
+	}{
+		{name: "public document, anonymous", visibility: models.DocumentVisibilityPublic, caller: "anonymous", want: http.StatusFound},
+		{name: "buyers-only, buyer with a lead", visibility: models.DocumentVisibilityBuyersOnly, caller: "buyer", want: http.StatusFound},
+		{name: "buyers-only, stranger", visibility: models.DocumentVisibilityBuyersOnly, caller: "stranger", want: http.StatusForbidden},
+		{name: "buyers-only, anonymous", visibility: models.DocumentVisibilityBuyersOnly, caller: "anonymous", want: http.StatusUnauthorized},
+		{name: "review, owner", visibility: models.DocumentVisibilityReview, caller: "owner", want: http.StatusFound},
+		{name: "review, buyer", visibility: models.DocumentVisibilityReview, caller: "buyer", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.Transaction{})
+			cfg := testConfig(t)
+			cfg.PrivateUploadDir = t.TempDir()
+			store := storage.New(cfg)
+			h := &ListingsHandler{DB: db, Cfg: cfg, Storage: store}
+			fileH := &FileHandler{Storage: store}
+
+			owner := createTestUser(t, db, "seller")
+			buyer := createTestUser(t, db, "buyer")
+			stranger := createTestUser(t, db, "stranger")
+			listing := createTestListing(t, db, owner.ID)
+			if err := db.Create(&models.Lead{SenderID: buyer.ID, ReceiverID: owner.ID, ListingID: &listing.ID, Subject: "Hi", Message: "Hello"}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			const key = "listing_1_doc_0123456789abcdef.pdf"
+			content := "%PDF-1.4 statement"
+			if err := os.WriteFile(filepath.Join(cfg.PrivateUploadDir, key), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			doc := models.ListingDocument{ListingID: listing.ID, Filename: "statement.pdf", Size: int64(len(content)),
+				MimeType: "application/pdf", StorageKey: key, Visibility: tt.visibility}
+			if err := db.Create(&doc).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			r := gin.New()
+			callers := map[string]uint{"owner": owner.ID, "buyer": buyer.ID, "stranger": stranger.ID}
+			handlers := []gin.HandlerFunc{h.DownloadDocument}
+			if id, ok := callers[tt.caller]; ok {
+				handlers = append([]gin.HandlerFunc{asUser(id)}, handlers...)
+			}
+			r.GET("/listings/:id/documents/:docId", handlers...)
+			r.GET(storage.PrivateURLPrefix+"/:name", fileH.ServePrivate)
+
+			w := serve(r, http.MethodGet, fmt.Sprintf("/listings/%d/documents/%d", listing.ID, doc.ID), nil)
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if w.Code != http.StatusFound {
+				return
+			}
+
+			location := w.Header().Get("Location")
+			if !strings.HasPrefix(location, storage.PrivateURLPrefix+"/") || !strings.Contains(location, "signature=") {
+				t.Fatalf("redirected to %q, want a signed private link", location)
+			}
+			if cc := w.Header().Get("Cache-Control"); cc != "private, no-store" {
+				t.Errorf("redirect Cache-Control %q", cc)
+			}
+
+			w = serve(r, http.MethodGet, location, nil)
+			if w.Code != http.StatusOK || w.Body.String() != content {
+				t.Fatalf("signed link: status %d, body %q", w.Code, w.Body)
+			}
+			if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") || !strings.Contains(cd, "statement.pdf") {
+				t.Errorf("Content-Disposition %q, want an attachment named statement.pdf", cd)
+			}
+
+			tampered := strings.Replace(location, "filename=statement.pdf", "filename=statement.html", 1)
+			if w := serve(r, http.MethodGet, tampered, nil); w.Code != http.StatusForbidden {
+				t.Errorf("link with a changed filename: status %d, want 403", w.Code)
+			}
+		})
+	}
+}
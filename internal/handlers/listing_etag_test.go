@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// getIfNoneMatch sends a GET with an optional If-None-Match header
+func getIfNoneMatch(r http.Handler, target, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestListingOptionsRevalidation(t *testing.T) {
+	for _, target := range []string{"/listings/categories", "/listings/metadata"} {
+		for _, cached := range []bool{false, true} {
+			name := target
+			if cached {
+				name += " with redis"
+			}
+			t.Run(name, func(t *testing.T) {
+				db := newTestDB(t)
+				owner := createTestUser(t, db, "seller")
+				createTestListing(t, db, owner.ID)
+				h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+				var mr *miniredis.Miniredis
+				if cached {
+					mr = miniredis.RunT(t)
+					h.RedisClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+				}
+				r := gin.New()
+				r.GET("/listings/categories", h.GetCategories)
+				r.GET("/listings/metadata", h.Metadata)
+
+				first := getIfNoneMatch(r, target, "")
+				etag := first.Header().Get("ETag")
+				if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "public, max-age=60" {
+					t.Fatalf("status %d, ETag %q, Cache-Control %q", first.Code, etag, first.Header().Get("Cache-Control"))
+				}
+
+				// Served from Redis or not, an unchanged response keeps its tag
+				for _, header := range []string{etag, "W/" + etag, `"stale", ` + etag} {
+					w := getIfNoneMatch(r, target, header)
+					if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+						t.Errorf("If-None-Match %s: status %d, ETag %q, %d byte body", header, w.Code, w.Header().Get("ETag"), w.Body.Len())
+					}
+				}
+				if w := getIfNoneMatch(r, target, `"stale"`); w.Code != http.StatusOK || w.Body.Len() == 0 {
+					t.Errorf("stale tag: status %d", w.Code)
+				}
+
+				// New options change the tag once the server cache lets them through
+				createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Category = "retail"; l.Industry = "retail" })
+				if cached {
+					mr.FlushAll()
+				}
+				w := getIfNoneMatch(r, target, etag)
+				if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+					t.Errorf("after a change: status %d, ETag %q", w.Code, w.Header().Get("ETag"))
+				}
+			})
+		}
+	}
+}
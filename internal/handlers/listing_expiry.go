@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the ?within_days window for expiring listings
+const (
+	defaultExpiringWithinDays = 14
+	maxExpiringWithinDays     = 365
+)
+
+// renewedExpiry is the expiry a listing gets when it is renewed now, or nil
+// when listings don't expire
+func (h *ListingsHandler) renewedExpiry(now time.Time) *time.Time {
+	ttl := h.Cfg.ListingTTL()
+	if ttl <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(ttl)
+	return &expiresAt
+}
+
+// listingExpired reports whether a listing's expiry has passed
+func listingExpired(listing *models.Listing, now time.Time) bool {
+	return listing.ExpiresAt != nil && !listing.ExpiresAt.After(now)
+}
+
+// daysUntil rounds the time left up to whole days, so anything expiring later
+// today is 1 and already expired listings are 0
+func daysUntil(t, now time.Time) int {
+	if !t.After(now) {
+		return 0
+	}
+	return int(math.Ceil(t.Sub(now).Hours() / 24))
+}
+
+// Expiring lists the caller's active listings that expire within ?within_days
+// (default 14), soonest first, so the dashboard can prompt renewals. Listings
+// without an expiry never show up here.
+func (h *ListingsHandler) Expiring(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	withinDays := defaultExpiringWithinDays
+	if raw := c.Query("within_days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxExpiringWithinDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "within_days must be between 1 and 365"})
+			return
+		}
+		withinDays = n
+	}
+
+	now := time.Now()
+	var listings []models.Listing
+	if err := h.DB.Select("id", "title", "price", "status", "visibility", "expires_at", "created_at", "updated_at").
+		Where("owner_id = ? AND status = ? AND expires_at IS NOT NULL AND expires_at <= ?",
+			userID, models.ListingStatusActive, now.AddDate(0, 0, withinDays)).
+		Order("expires_at, id").
+		Find(&listings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expiring listings"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(listings))
+	for _, listing := range listings {
+		result = append(result, gin.H{
+			"id":                listing.ID,
+			"title":             listing.Title,
+			"price":             listing.Price,
+			"status":            listing.Status,
+			"visibility":        listing.Visibility,
+			"expires_at":        listing.ExpiresAt,
+			"days_until_expiry": daysUntil(*listing.ExpiresAt, now),
+			"created_at":        listing.CreatedAt,
+			"updated_at":        listing.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"listings":    result,
+		"within_days": withinDays,
+	})
+}
+
+// Renew restarts a listing's expiry period. A listing that was taken down
+// because it expired goes active again if it still meets the publishing rules.
+func (h *ListingsHandler) Renew(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("id = ? AND owner_id = ? AND status IN ?", id, userID,
+		[]models.ListingStatus{models.ListingStatusActive, models.ListingStatusInactive}).
+		First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	}
+
+	now := time.Now()
+	status := listing.Status
+	if listingExpired(&listing, now) && status == models.ListingStatusInactive {
+		if !h.checkPublishable(c, listing.ID) {
+			return
+		}
+		status = models.ListingStatusActive
+	}
+	expiresAt := h.renewedExpiry(now)
+
+	if err := h.DB.Model(&listing).Updates(map[string]interface{}{
+		"status":     status,
+		"expires_at": expiresAt,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to renew listing"})
+		return
+	}
+	h.invalidateListing(listing.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Listing renewed",
+		"status":     status,
+		"expires_at": expiresAt,
+	})
+}
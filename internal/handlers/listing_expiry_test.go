@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExpiringListings(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	other := createTestUser(t, db, "other")
+	now := time.Now()
+	expiring := func(title string, in time.Duration, edits ...func(*models.Listing)) {
+		at := now.Add(in)
+		createTestListing(t, db, owner.ID, append(edits, func(l *models.Listing) {
+			l.Title = title
+			l.ExpiresAt = &at
+		})...)
+	}
+	day := 24 * time.Hour
+	expiring("in 10 days", 10*day)
+	expiring("later today", 3*time.Hour)
+	expiring("in 3 days", 3*day-time.Hour)
+	expiring("already expired", -time.Hour)
+	expiring("in 20 days", 20*day)
+	expiring("in 60 days", 60*day)
+	expiring("inactive", day, func(l *models.Listing) { l.Status = models.ListingStatusInactive })
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Title = "never expires"; l.ExpiresAt = nil })
+	createTestListing(t, db, other.ID, func(l *models.Listing) { at := now.Add(day); l.ExpiresAt = &at })
+
+	r := gin.New()
+	r.GET("/user/listings/expiring", asUser(owner.ID), h.Expiring)
+
+	tests := []struct {
+		query  string
+		status int
+		titles []string
+		days   []float64
+	}{
+		{query: "", status: http.StatusOK,
+			titles: []string{"already expired", "later today", "in 3 days", "in 10 days"}, days: []float64{0, 1, 3, 10}},
+		{query: "?within_days=1", status: http.StatusOK, titles: []string{"already expired", "later today"}, days: []float64{0, 1}},
+		{query: "?within_days=30", status: http.StatusOK,
+			titles: []string{"already expired", "later today", "in 3 days", "in 10 days", "in 20 days"}, days: []float64{0, 1, 3, 10, 20}},
+		{query: "?within_days=0", status: http.StatusBadRequest},
+		{query: "?within_days=366", status: http.StatusBadRequest},
+		{query: "?within_days=soon", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run("within_days"+tt.query, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/user/listings/expiring"+tt.query, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var titles []string
+			var days []float64
+			for _, entry := range decode(t, w)["listings"].([]interface{}) {
+				l := entry.(map[string]interface{})
+				titles = append(titles, l["title"].(string))
+				days = append(days, l["days_until_expiry"].(float64))
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(tt.titles) || fmt.Sprint(days) != fmt.Sprint(tt.days) {
+				t.Errorf("got %q %v, want %q %v", titles, days, tt.titles, tt.days)
+			}
+		})
+	}
+}
+
+func TestRenewListing(t *testing.T) {
+	now := time.Now()
+	past, soon := now.Add(-time.Hour), now.Add(24*time.Hour)
+	tests := []struct {
+		name      string
+		status    models.ListingStatus
+		expiresAt *time.Time
+		ttlDays   int
+		code      int
+		want      models.ListingStatus
+	}{
+		{name: "active listing renewed", status: models.ListingStatusActive, expiresAt: &soon, ttlDays: 90, code: http.StatusOK, want: models.ListingStatusActive},
+		{name: "expired listing goes live again", status: models.ListingStatusInactive, expiresAt: &past, ttlDays: 90, code: http.StatusOK, want: models.ListingStatusActive},
+		{name: "inactive by choice stays inactive", status: models.ListingStatusInactive, expiresAt: &soon, ttlDays: 90, code: http.StatusOK, want: models.ListingStatusInactive},
+		{name: "expiry turned off", status: models.ListingStatusActive, expiresAt: &soon, ttlDays: 0, code: http.StatusOK, want: models.ListingStatusActive},
+		{name: "sold listings can't be renewed", status: models.ListingStatusSold, expiresAt: &past, ttlDays: 90, code: http.StatusNotFound, want: models.ListingStatusSold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.ListingTTLDays = tt.ttlDays
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) {
+				l.Status = tt.status
+				l.ExpiresAt = tt.expiresAt
+			})
+			r := gin.New()
+			r.POST("/listings/:id/renew", asUser(owner.ID), h.Renew)
+
+			w := serve(r, http.MethodPost, fmt.Sprintf("/listings/%d/renew", listing.ID), nil)
+			if w.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if stored.Status != tt.want {
+				t.Errorf("status %q, want %q", stored.Status, tt.want)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			if tt.ttlDays == 0 {
+				if stored.ExpiresAt != nil {
+					t.Errorf("expires_at %v, want none", stored.ExpiresAt)
+				}
+				return
+			}
+			if stored.ExpiresAt == nil || stored.ExpiresAt.Sub(now) < 89*24*time.Hour {
+				t.Errorf("expires_at %v, want about 90 days out", stored.ExpiresAt)
+			}
+		})
+	}
+}
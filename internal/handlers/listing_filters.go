@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listingAttributeFilters are the multi-select filters on the public listing search;
+// each query param filters the listings column of the same name
+var listingAttributeFilters = []string{"category", "condition", "industry"}
+
+// listingRangeFilter is a numeric bound on a listings column
+type listingRangeFilter struct {
+	param  string
+	column string
+	op     string // ">=" or "<="
+}
+
+// listingRangeFilters are the numeric bounds on the public listing search. A
+// zero bound is the same as leaving the param out.
+var listingRangeFilters = []listingRangeFilter{
+	{"min_rent", "rent", ">="},
+	{"max_rent", "rent", "<="},
+	{"min_sqm", "square_meters", ">="},
+	{"max_sqm", "square_meters", "<="},
+	{"min_annual_revenue", "annual_revenue", ">="},
+	{"min_gross_profit_rate", "gross_profit_rate", ">="},
+}
+
+// ownerStatusFilterValues are the statuses owners can filter their own listings by
+var ownerStatusFilterValues = []string{
+	string(models.ListingStatusActive),
+	string(models.ListingStatusInactive),
+	string(models.ListingStatusSold),
+	string(models.ListingStatusPendingDelete),
+}
+
+// queryValues collects a multi-value query param given either repeated
+// (?category=a&category=b) or comma-separated (?category=a,b), dropping blanks
+// and duplicates while keeping the order given
+func queryValues(c *gin.Context, key string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" || seen[v] {
+				continue
+			}
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// missingValues returns the values not in known
+func missingValues(values, known []string) []string {
+	set := make(map[string]bool, len(known))
+	for _, k := range known {
+		set[k] = true
+	}
+	var missing []string
+	for _, v := range values {
+		if !set[v] {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// applyAttributeFilters narrows query with an IN clause per category/condition/industry
+// param present. Each value must appear on at least one visible listing; otherwise a
+// 400 naming the unknown values is written and ok is false. The applied filters are
+// returned so the response can echo them back.
+func (h *ListingsHandler) applyAttributeFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, gin.H, bool) {
+	applied := gin.H{}
+	invalid := gin.H{}
+	for _, column := range listingAttributeFilters {
+		values := queryValues(c, column)
+		if len(values) == 0 {
+			continue
+		}
+
+		var known []string
+		if err := h.DB.Model(&models.Listing{}).
+			Where("status NOT IN ? AND visibility = ?", models.HiddenListingStatuses, models.ListingVisibilityPublic).
+			Where(map[string]interface{}{column: values}).
+			Distinct(column).
+			Pluck(column, &known).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listings"})
+			return nil, nil, false
+		}
+		if missing := missingValues(values, known); len(missing) > 0 {
+			invalid[column] = missing
+			continue
+		}
+
+		query = query.Where(map[string]interface{}{column: values})
+		applied[column] = values
+	}
+
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Unknown filter values",
+			"invalid_values": invalid,
+		})
+		return nil, nil, false
+	}
+	return query, applied, true
+}
+
+// applyRangeFilters narrows query by each range param set to a positive number,
+// recording it in applied. A malformed or negative value, or a min above its
+// max, writes a 400 and ok is false.
+func applyRangeFilters(c *gin.Context, query *gorm.DB, applied gin.H) (*gorm.DB, bool) {
+	bounds := make(map[string]float64)
+	for _, f := range listingRangeFilters {
+		raw := strings.TrimSpace(c.Query(f.param))
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be a non-negative number", f.param)})
+			return nil, false
+		}
+		if v == 0 {
+			continue
+		}
+		bounds[f.param] = v
+	}
+	for _, pair := range [][2]string{{"min_rent", "max_rent"}, {"min_sqm", "max_sqm"}} {
+		min, minSet := bounds[pair[0]]
+		max, maxSet := bounds[pair[1]]
+		if minSet && maxSet && min > max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must not exceed %s", pair[0], pair[1])})
+			return nil, false
+		}
+	}
+
+	for _, f := range listingRangeFilters {
+		if v, ok := bounds[f.param]; ok {
+			query = query.Where(f.column+" "+f.op+" ?", v)
+			applied[f.param] = v
+		}
+	}
+	return query, true
+}
+
+// applyOwnerStatusFilter narrows an owner's listings to the requested statuses
+func applyOwnerStatusFilter(c *gin.Context, query *gorm.DB, applied gin.H) (*gorm.DB, bool) {
+	statuses := queryValues(c, "status")
+	if len(statuses) == 0 {
+		return query, true
+	}
+	if missing := missingValues(statuses, ownerStatusFilterValues); len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Unknown filter values",
+			"invalid_values": gin.H{"status": missing},
+		})
+		return nil, false
+	}
+	applied["status"] = statuses
+	return query.Where("status IN ?", statuses), true
+}
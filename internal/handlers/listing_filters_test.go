@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListingMultiValueFilters(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	seller := createTestUser(t, db, "seller")
+	seed := []struct{ category, condition string }{
+		{"直營", "全新裝修"},
+		{"加盟", "9成新"},
+		{"加盟", "全新裝修"},
+		{"個人", "9成新"},
+	}
+	for i, s := range seed {
+		createTestListing(t, db, seller.ID, func(l *models.Listing) {
+			l.Title = "Shop " + string(rune('A'+i))
+			l.Category, l.Condition = s.category, s.condition
+		})
+	}
+
+	r := gin.New()
+	r.GET("/listings", h.List)
+
+	tests := []struct {
+		name        string
+		query       url.Values
+		want        int
+		wantTitles  []string
+		wantFilters map[string]interface{}
+		wantInvalid map[string]interface{}
+	}{
+		{name: "comma-separated", query: url.Values{"category": {"直營,加盟"}},
+			want: http.StatusOK, wantTitles: []string{"Shop A", "Shop B", "Shop C"},
+			wantFilters: map[string]interface{}{"category": []interface{}{"直營", "加盟"}}},
+		{name: "repeated", query: url.Values{"category": {"直營", "加盟"}},
+			want: http.StatusOK, wantTitles: []string{"Shop A", "Shop B", "Shop C"},
+			wantFilters: map[string]interface{}{"category": []interface{}{"直營", "加盟"}}},
+		{name: "duplicates", query: url.Values{"category": {"加盟,加盟", "加盟"}},
+			want: http.StatusOK, wantTitles: []string{"Shop B", "Shop C"},
+			wantFilters: map[string]interface{}{"category": []interface{}{"加盟"}}},
+		{name: "empty values", query: url.Values{"category": {"", " , "}},
+			want: http.StatusOK, wantTitles: []string{"Shop A", "Shop B", "Shop C", "Shop D"},
+			wantFilters: map[string]interface{}{}},
+		{name: "two filters", query: url.Values{"category": {"加盟,個人"}, "condition": {"9成新"}},
+			want: http.StatusOK, wantTitles: []string{"Shop B", "Shop D"},
+			wantFilters: map[string]interface{}{"category": []interface{}{"加盟", "個人"}, "condition": []interface{}{"9成新"}}},
+		{name: "one invalid among many", query: url.Values{"category": {"直營,火星,加盟"}},
+			want: http.StatusBadRequest, wantInvalid: map[string]interface{}{"category": []interface{}{"火星"}}},
+		{name: "invalid in two filters", query: url.Values{"category": {"火星"}, "condition": {"全新裝修,破舊"}},
+			want: http.StatusBadRequest, wantInvalid: map[string]interface{}{"category": []interface{}{"火星"}, "condition": []interface{}{"破舊"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/listings?"+tt.query.Encode(), nil)
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			body := decode(t, w)
+			if tt.want != http.StatusOK {
+				if !reflect.DeepEqual(body["invalid_values"], tt.wantInvalid) {
+					t.Errorf("invalid values %v, want %v", body["invalid_values"], tt.wantInvalid)
+				}
+				return
+			}
+
+			var got []string
+			for _, l := range body["listings"].([]interface{}) {
+				got = append(got, l.(map[string]interface{})["title"].(string))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantTitles) {
+				t.Errorf("listings %v, want %v", got, tt.wantTitles)
+			}
+			if !reflect.DeepEqual(body["filters"], tt.wantFilters) {
+				t.Errorf("filters %v, want %v", body["filters"], tt.wantFilters)
+			}
+		})
+	}
+}
+
+func TestOwnerListingStatusFilter(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	for _, status := range []models.ListingStatus{models.ListingStatusActive, models.ListingStatusInactive, models.ListingStatusSold} {
+		createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Status = status })
+	}
+
+	r := gin.New()
+	r.GET("/listings/mine", asUser(owner.ID), h.Mine)
+
+	query := url.Values{"status": {string(models.ListingStatusSold) + "," + string(models.ListingStatusInactive), string(models.ListingStatusSold)}}
+	w := serve(r, http.MethodGet, "/listings/mine?"+query.Encode(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if n := len(decode(t, w)["listings"].([]interface{})); n != 2 {
+		t.Errorf("%d listings, want 2", n)
+	}
+
+	w = serve(r, http.MethodGet, "/listings/mine?"+url.Values{"status": {string(models.ListingStatusSold) + ",archived"}}.Encode(), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// financialsQueueLimits are the page sizes of the admin financials queue
+var financialsQueueLimits = pagination.Limits{Default: 50, Max: 100}
+
+// clearFinancialsVerification adds the updates that drop a listing's verified
+// financials when its annual revenue or gross profit rate changes, and reports
+// whether it did. The badge vouches for the figures that were checked, not
+// whatever the seller enters afterwards.
+func clearFinancialsVerification(updates map[string]interface{}, listing *models.Listing) bool {
+	if !listing.FinancialsVerified {
+		return false
+	}
+	revenue, revenueSet := updates["annual_revenue"]
+	rate, rateSet := updates["gross_profit_rate"]
+	if (revenueSet && revenue != listing.AnnualRevenue) || (rateSet && rate != listing.GrossProfitRate) {
+		updates["financials_verified"] = false
+		updates["financials_verified_at"] = nil
+		updates["financials_verified_by"] = nil
+		return true
+	}
+	return false
+}
+
+// FinancialsHandler serves the admin queue of listings whose sellers sent
+// financial statements for verification
+type FinancialsHandler struct {
+	DB     *gorm.DB
+	Emails *auth.EmailService
+	Cache  *redisclient.CacheService // nil without Redis
+}
+
+// Queue lists listings with statements awaiting review, oldest submission first,
+// with their review documents
+func (h *FinancialsHandler) Queue(c *gin.Context) {
+	p := pagination.Parse(c, financialsQueueLimits)
+	query := h.DB.Model(&models.Listing{}).
+		Where("financials_submitted_at IS NOT NULL AND status NOT IN ?", models.HiddenListingStatuses)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch financials queue"})
+		return
+	}
+
+	var listings []models.Listing
+	if err := query.Preload("Documents", "visibility = ?", models.DocumentVisibilityReview).
+		Order("financials_submitted_at").
+		Scopes(p.Scope()).
+		Find(&listings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch financials queue"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(listings))
+	for i := range listings {
+		l := &listings[i]
+		_, documents := documentSummaries(l.Documents)
+		items = append(items, gin.H{
+			"listing_id":          l.ID,
+			"title":               l.Title,
+			"owner_id":            l.OwnerID,
+			"annual_revenue":      l.AnnualRevenue,
+			"gross_profit_rate":   l.GrossProfitRate,
+			"financials_verified": l.FinancialsVerified,
+			"submitted_at":        l.FinancialsSubmittedAt,
+			"documents":           documents,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"listings":   items,
+		"pagination": pagination.NewMeta(p, total),
+	})
+}
+
+// Verify marks the listing's figures as checked by the calling admin and
+// emails the seller
+func (h *FinancialsHandler) Verify(c *gin.Context) {
+	listing, ok := h.loadListing(c)
+	if !ok {
+		return
+	}
+	adminID := c.MustGet("user_id").(uint)
+
+	now := time.Now()
+	if err := h.DB.Model(listing).Updates(map[string]interface{}{
+		"financials_verified":     true,
+		"financials_verified_at":  now,
+		"financials_verified_by":  adminID,
+		"financials_submitted_at": nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify financials"})
+		return
+	}
+	invalidateListingCache(h.Cache, listing.ID)
+	_ = h.Emails.SendFinancialsReviewed(&listing.Owner, listing, true, "")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                "Financials verified",
+		"listing_id":             listing.ID,
+		"financials_verified_at": now,
+	})
+}
+
+// Reject takes the listing out of the queue without verifying it and emails
+// the seller the optional reason. An earlier verification, of figures that
+// haven't changed since, stands.
+func (h *FinancialsHandler) Reject(c *gin.Context) {
+	listing, ok := h.loadListing(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is fine
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.DB.Model(listing).Update("financials_submitted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject financials"})
+		return
+	}
+	_ = h.Emails.SendFinancialsReviewed(&listing.Owner, listing, false, strings.TrimSpace(input.Reason))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Financials rejected", "listing_id": listing.ID})
+}
+
+// loadListing loads the queued listing named by the :id param, writing the
+// error response if it can't
+func (h *FinancialsHandler) loadListing(c *gin.Context) (*models.Listing, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return nil, false
+	}
+
+	var listing models.Listing
+	if err := h.DB.Preload("Owner").
+		Where("financials_submitted_at IS NOT NULL").
+		First(&listing, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No financials awaiting review for this listing"})
+		return nil, false
+	}
+	return &listing, true
+}
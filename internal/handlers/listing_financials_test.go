@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifiedFinancials marks a listing's figures as checked by reviewer
+func verifiedFinancials(reviewer uint) func(*models.Listing) {
+	return func(l *models.Listing) {
+		at := time.Now().Add(-24 * time.Hour)
+		l.AnnualRevenue, l.GrossProfitRate = 5200000, 0.35
+		l.FinancialsVerified, l.FinancialsVerifiedAt, l.FinancialsVerifiedBy = true, &at, &reviewer
+	}
+}
+
+func TestFinancialsClearedOnEdit(t *testing.T) {
+	tests := []struct {
+		name       string
+		unverified bool // The listing was never verified
+		update     map[string]interface{}
+		cleared    bool
+	}{
+		{name: "other fields", update: map[string]interface{}{"title": "Renamed cafe", "rent": 80000}},
+		{name: "same figures", update: map[string]interface{}{"annual_revenue": 5200000, "gross_profit_rate": 0.35}},
+		{name: "annual revenue", update: map[string]interface{}{"annual_revenue": 6000000}, cleared: true},
+		{name: "gross profit rate", update: map[string]interface{}{"gross_profit_rate": 0.4}, cleared: true},
+		{name: "never verified", unverified: true, update: map[string]interface{}{"annual_revenue": 6000000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			emailCfg := *cfg
+			emailCfg.AppEnv, emailCfg.SendGridAPIKey = "test", "SG.test"
+			subjects := recordSendGridSubjects(t)
+			h := &ListingsHandler{DB: db, Cfg: cfg, Emails: auth.NewEmailService(&emailCfg)}
+			owner := createTestUser(t, db, "seller")
+			admin := createTestUser(t, db, "admin")
+			edits := []func(*models.Listing){verifiedFinancials(admin.ID)}
+			if tt.unverified {
+				edits = nil
+			}
+			listing := createTestListing(t, db, owner.ID, edits...)
+			r := gin.New()
+			r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+
+			w := serve(r, http.MethodPut, fmt.Sprintf("/listings/%d", listing.ID), tt.update)
+			if w.Code != http.StatusOK {
+				t.Fatalf("update status %d: %s", w.Code, w.Body)
+			}
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			wantVerified := !tt.unverified && !tt.cleared
+			if stored.FinancialsVerified != wantVerified || (stored.FinancialsVerifiedAt != nil) != wantVerified || (stored.FinancialsVerifiedBy != nil) != wantVerified {
+				t.Errorf("verified %v at %v by %v, want verified %v", stored.FinancialsVerified, stored.FinancialsVerifiedAt, stored.FinancialsVerifiedBy, wantVerified)
+			}
+			var want []string
+			if tt.cleared {
+				want = []string{fmt.Sprintf("%q is no longer marked as verified", listing.Title)}
+			}
+			if !reflect.DeepEqual(*subjects, want) {
+				t.Errorf("emails %q, want %q", *subjects, want)
+			}
+		})
+	}
+}
+
+func TestVerifiedFinancialsFilter(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	emails := auth.NewEmailService(cfg)
+	h := &ListingsHandler{DB: db, Cfg: cfg, Emails: emails}
+	financials := &FinancialsHandler{DB: db, Emails: emails}
+	owner := createTestUser(t, db, "seller")
+	admin := createTestUser(t, db, "admin")
+	submitted := time.Now().Add(-time.Hour)
+	pending := createTestListing(t, db, owner.ID, func(l *models.Listing) {
+		l.Title, l.AnnualRevenue, l.FinancialsSubmittedAt = "Pending cafe", 3000000, &submitted
+	})
+	verified := createTestListing(t, db, owner.ID, verifiedFinancials(admin.ID), func(l *models.Listing) { l.Title = "Verified cafe" })
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Title = "Plain cafe" })
+
+	r := gin.New()
+	r.GET("/listings", h.List)
+	r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+	r.POST("/admin/listings/:id/financials/verify", asUser(admin.ID), financials.Verify)
+	search := func(target string) map[string]bool {
+		t.Helper()
+		w := serve(r, http.MethodGet, target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", target, w.Code, w.Body)
+		}
+		badges := map[string]bool{}
+		for _, l := range decode(t, w)["listings"].([]interface{}) {
+			entry := l.(map[string]interface{})
+			badges[entry["title"].(string)] = entry["financials_verified"].(bool)
+		}
+		return badges
+	}
+	check := func(step, target string, want map[string]bool) {
+		t.Helper()
+		if got := search(target); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %s returned %v, want %v", step, target, got, want)
+		}
+	}
+
+	check("before review", "/listings?verified_financials=true", map[string]bool{"Verified cafe": true})
+	check("before review", "/listings", map[string]bool{"Verified cafe": true, "Pending cafe": false, "Plain cafe": false})
+
+	if w := serve(r, http.MethodPost, fmt.Sprintf("/admin/listings/%d/financials/verify", pending.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("verify: status %d: %s", w.Code, w.Body)
+	}
+	check("after verifying", "/listings?verified_financials=true", map[string]bool{"Verified cafe": true, "Pending cafe": true})
+
+	// Editing the revenue takes the listing out of the filter
+	if w := serve(r, http.MethodPut, fmt.Sprintf("/listings/%d", verified.ID), map[string]interface{}{"annual_revenue": 9000000}); w.Code != http.StatusOK {
+		t.Fatalf("update: status %d: %s", w.Code, w.Body)
+	}
+	check("after the edit", "/listings?verified_financials=true", map[string]bool{"Pending cafe": true})
+	// Anything but true doesn't filter
+	check("after the edit", "/listings?verified_financials=false", map[string]bool{"Verified cafe": false, "Pending cafe": true, "Plain cafe": false})
+}
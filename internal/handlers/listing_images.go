@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+)
+
+// imageArchiveName is the ZIP entry name of the n-th image (1-based), keeping
+// the display order and the stored file's extension
+func imageArchiveName(n int, img *models.Image) string {
+	return fmt.Sprintf("%02d%s", n, filepath.Ext(img.Filename))
+}
+
+// DownloadImages streams all images of a listing as a ZIP. The archive is
+// written straight to the response one file at a time, so memory use doesn't
+// grow with the number of images. Viewers get the same images as on the
+// listing page: approved ones, or all of them for the owner and admins.
+// Downloads go through the export gate: one at a time per viewer and
+// EXPORT_MAX_CONCURRENT across the service.
+func (h *ListingsHandler) DownloadImages(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("status NOT IN ?", models.HiddenListingStatuses).First(&listing, id).Error; err != nil || !h.canOpen(c, &listing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	var images []models.Image
+	if err := h.DB.Where("listing_id = ?", listing.ID).Order("`order`, id").Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load images"})
+		return
+	}
+	viewerID, _ := c.Get("user_id")
+	uid, _ := viewerID.(uint)
+	if uid == 0 || (uid != listing.OwnerID && !isAdmin(h.DB, uid)) {
+		images = models.ApprovedImages(images)
+	}
+	if len(images) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing has no images"})
+		return
+	}
+
+	// Archives are built from disk for as long as the client takes to read
+	// them, so each viewer gets one at a time. If Redis is down they run ungated.
+	slot, err := h.Exports.Acquire(c.Request.Context(), exportHolder(c))
+	var busy *redisclient.ExportBusyError
+	if errors.As(err, &busy) {
+		respondExportBusy(c, busy)
+		return
+	}
+	defer slot.Release()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="listing-%d-images.zip"`, listing.ID))
+	c.Header("Cache-Control", "private, no-store")
+	c.Status(http.StatusOK)
+
+	// The status is sent with the first byte, so from here a failure can only
+	// cut the archive short
+	zw := zip.NewWriter(c.Writer)
+	for i := range images {
+		if err := writeArchiveImage(zw, h.Storage.PublicPath, imageArchiveName(i+1, &images[i]), images[i].Filename); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// writeArchiveImage copies one stored image into the archive. Images are
+// already compressed, so they are stored rather than deflated. A file missing
+// from disk is skipped instead of failing the whole download.
+func writeArchiveImage(zw *zip.Writer, publicPath func(string) (string, error), entry, filename string) error {
+	path, err := publicPath(filename)
+	if err != nil {
+		return nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = entry
+	header.Method = zip.Store
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPublishRequiresMinimumImages(t *testing.T) {
+	tests := []struct {
+		name          string
+		minImages     int
+		images        int
+		wantCreated   models.ListingStatus
+		wantPublished int
+	}{
+		{name: "no minimum", minImages: 0, images: 0, wantCreated: models.ListingStatusActive, wantPublished: http.StatusOK},
+		{name: "negative minimum is none", minImages: -1, images: 0, wantCreated: models.ListingStatusActive, wantPublished: http.StatusOK},
+		{name: "too few images", minImages: 3, images: 2, wantCreated: models.ListingStatusInactive, wantPublished: http.StatusBadRequest},
+		{name: "exactly the minimum", minImages: 3, images: 3, wantCreated: models.ListingStatusInactive, wantPublished: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.ListingMinImages = tt.minImages
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			owner := createTestUser(t, db, "seller")
+
+			r := gin.New()
+			r.POST("/listings", asUser(owner.ID), h.Create)
+			r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+
+			w := serve(r, http.MethodPost, "/listings", map[string]interface{}{"title": "Corner shop", "price": 1000000})
+			if w.Code != http.StatusCreated {
+				t.Fatalf("create status %d: %s", w.Code, w.Body)
+			}
+			created := decode(t, w)["listing"].(map[string]interface{})
+			if created["status"] != string(tt.wantCreated) {
+				t.Errorf("new listing status %v, want %s", created["status"], tt.wantCreated)
+			}
+			id := uint(created["id"].(float64))
+			// Publishing only applies to listings that aren't active yet
+			db.Model(&models.Listing{}).Where("id = ?", id).Update("status", models.ListingStatusInactive)
+			for i := 0; i < tt.images; i++ {
+				db.Create(&models.Image{ListingID: id, Filename: fmt.Sprintf("%d.jpg", i)})
+			}
+
+			w = serve(r, http.MethodPut, fmt.Sprintf("/listings/%d", id), map[string]interface{}{"status": models.ListingStatusActive})
+			if w.Code != tt.wantPublished {
+				t.Fatalf("publish status %d, want %d: %s", w.Code, tt.wantPublished, w.Body)
+			}
+			if w.Code == http.StatusBadRequest {
+				body := decode(t, w)
+				if body["min_images"] != float64(tt.minImages) || body["image_count"] != float64(tt.images) {
+					t.Errorf("min_images %v image_count %v, want %d %d", body["min_images"], body["image_count"], tt.minImages, tt.images)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadImages(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	cfg.PublicUploadDir = t.TempDir()
+	h := &ListingsHandler{DB: db, Cfg: cfg, Storage: storage.New(cfg)}
+	owner := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, owner.ID)
+	empty := createTestListing(t, db, owner.ID)
+	private := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Visibility = models.ListingVisibilityPrivate })
+
+	images := []struct {
+		filename, status string
+		order            int
+		onDisk           bool
+	}{
+		{filename: "b.png", status: models.ImageModerationApproved, order: 2, onDisk: true},
+		{filename: "a.jpg", status: models.ImageModerationApproved, order: 1, onDisk: true},
+		{filename: "pending.jpg", status: models.ImageModerationPending, order: 3, onDisk: true},
+		// Missing from disk, so left out rather than failing the download
+		{filename: "gone.jpg", status: models.ImageModerationApproved, order: 4},
+	}
+	for _, img := range images {
+		db.Create(&models.Image{ListingID: listing.ID, Filename: img.filename, URL: "/uploads/" + img.filename, Order: img.order, ModerationStatus: img.status})
+		if img.onDisk {
+			if err := os.WriteFile(filepath.Join(cfg.PublicUploadDir, img.filename), []byte("contents of "+img.filename), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	db.Create(&models.Image{ListingID: private.ID, Filename: "a.jpg", URL: "/uploads/a.jpg", ModerationStatus: models.ImageModerationApproved})
+
+	router := func(viewer uint) *gin.Engine {
+		r := gin.New()
+		if viewer != 0 {
+			r.Use(asUser(viewer))
+		}
+		r.GET("/listings/:id/images.zip", h.DownloadImages)
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		viewer  uint
+		listing uint
+		status  int
+		entries map[string]string
+	}{
+		{name: "approved images in order", listing: listing.ID, status: http.StatusOK,
+			entries: map[string]string{"01.jpg": "contents of a.jpg", "02.png": "contents of b.png"}},
+		{name: "owner also gets pending images", viewer: owner.ID, listing: listing.ID, status: http.StatusOK,
+			entries: map[string]string{"01.jpg": "contents of a.jpg", "02.png": "contents of b.png", "03.jpg": "contents of pending.jpg"}},
+		{name: "no images", listing: empty.ID, status: http.StatusNotFound},
+		{name: "private listing", viewer: buyer.ID, listing: private.ID, status: http.StatusNotFound},
+		{name: "unknown listing", listing: 999, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router(tt.viewer), http.MethodGet, fmt.Sprintf("/listings/%d/images.zip", tt.listing), nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+				t.Errorf("Content-Type %q", ct)
+			}
+			if cd, want := w.Header().Get("Content-Disposition"), fmt.Sprintf(`attachment; filename="listing-%d-images.zip"`, listing.ID); cd != want {
+				t.Errorf("Content-Disposition %q, want %q", cd, want)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+			if err != nil {
+				t.Fatalf("read archive: %v", err)
+			}
+			got := map[string]string{}
+			var names []string
+			for _, f := range zr.File {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(rc)
+				rc.Close()
+				got[f.Name] = string(data)
+				names = append(names, f.Name)
+				if f.Method != zip.Store {
+					t.Errorf("%s is compressed, want stored", f.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.entries) {
+				t.Errorf("entries %v, want %v", got, tt.entries)
+			}
+			if !sort.StringsAreSorted(names) {
+				t.Errorf("entries out of order: %v", names)
+			}
+		})
+	}
+}
+
+// uploadImages posts files, keyed by filename, as the images form field
+func uploadImages(t *testing.T, r http.Handler, target string, files map[string][]byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, data := range files {
+		part, err := mw.CreateFormFile("images", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func encodeImage(t *testing.T, format string, w, h int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadImagesPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string][]byte
+		status int
+		file   string // Named in the rejection
+		saved  int
+	}{
+		{name: "allowed", status: http.StatusOK, saved: 2, files: map[string][]byte{
+			"front.jpg": encodeImage(t, "jpeg", 400, 300),
+			"menu.png":  encodeImage(t, "png", 800, 600),
+		}},
+		{name: "disallowed format", status: http.StatusBadRequest, file: "anim.gif", files: map[string][]byte{
+			"anim.gif": encodeImage(t, "gif", 10, 10),
+		}},
+		{name: "over the dimension limit", status: http.StatusBadRequest, file: "huge.png", files: map[string][]byte{
+			"huge.png": encodeImage(t, "png", 801, 10),
+		}},
+		// An image content type isn't enough
+		{name: "not an image", status: http.StatusBadRequest, file: "fake.jpg", files: map[string][]byte{
+			"fake.jpg": []byte("GIF? no, plain text"),
+		}},
+		{name: "one bad file rejects the batch", status: http.StatusBadRequest, file: "huge.png", files: map[string][]byte{
+			"front.jpg": encodeImage(t, "jpeg", 400, 300),
+			"huge.png":  encodeImage(t, "png", 10, 601),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.PublicUploadDir = t.TempDir()
+			cfg.ImageAllowedFormats = "jpeg,png,webp"
+			cfg.ImageMaxWidth, cfg.ImageMaxHeight = 800, 600
+			h := &ListingsHandler{DB: db, Cfg: cfg, Storage: storage.New(cfg)}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID)
+			r := gin.New()
+			r.POST("/listings/:id/images", asUser(owner.ID), h.UploadImages)
+
+			w := uploadImages(t, r, fmt.Sprintf("/listings/%d/images", listing.ID), tt.files)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.file != "" {
+				if got := decode(t, w)["file"]; got != tt.file {
+					t.Errorf("rejected file %v, want %s", got, tt.file)
+				}
+			}
+			var saved int64
+			db.Model(&models.Image{}).Where("listing_id = ?", listing.ID).Count(&saved)
+			entries, _ := os.ReadDir(cfg.PublicUploadDir)
+			if saved != int64(tt.saved) || len(entries) != tt.saved {
+				t.Errorf("%d image rows and %d files saved, want %d", saved, len(entries), tt.saved)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// interestTimelineLimit is how many recent interest events are returned
+const interestTimelineLimit = 50
+
+// interestEventsSQL is one row per favorite, non-spam lead and message on a
+// listing from someone other than the owner, as (action, user_id, created_at)
+const interestEventsSQL = `
+	SELECT 'favorite' AS action, user_id, created_at FROM favorites
+		WHERE listing_id = @listing AND user_id <> @owner
+	UNION ALL
+	SELECT 'lead' AS action, sender_id, created_at FROM leads
+		WHERE listing_id = @listing AND sender_id <> @owner AND is_spam = FALSE
+	UNION ALL
+	SELECT 'message' AS action, sender_id, created_at FROM messages
+		WHERE listing_id = @listing AND sender_id <> @owner`
+
+// interestEvent is one entry of the anonymous interest timeline
+type interestEvent struct {
+	Action    string    `json:"action"` // favorite, lead or message
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Interest shows the owner how many distinct users have favorited, sent a lead
+// or messaged about a listing, and when. The timeline is anonymous: it carries
+// only the action and time, never who.
+func (h *ListingsHandler) Interest(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Select("id", "owner_id").Where("id = ? AND owner_id = ?", id, userID).First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	}
+	args := map[string]interface{}{"listing": listing.ID, "owner": listing.OwnerID, "limit": interestTimelineLimit}
+
+	// A user who both favorited and messaged counts once in the total, and once
+	// under each action
+	var interested int64
+	if err := h.DB.Raw("SELECT COUNT(DISTINCT user_id) FROM ("+interestEventsSQL+") AS interest", args).
+		Scan(&interested).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count interest"})
+		return
+	}
+
+	var byAction []struct {
+		Action string
+		Users  int64
+	}
+	if err := h.DB.Raw("SELECT action, COUNT(DISTINCT user_id) AS users FROM ("+interestEventsSQL+") AS interest GROUP BY action", args).
+		Scan(&byAction).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count interest"})
+		return
+	}
+	users := gin.H{"favorite": int64(0), "lead": int64(0), "message": int64(0)}
+	for _, row := range byAction {
+		users[row.Action] = row.Users
+	}
+
+	timeline := make([]interestEvent, 0, interestTimelineLimit)
+	if err := h.DB.Raw("SELECT action, created_at FROM ("+interestEventsSQL+") AS interest ORDER BY created_at DESC LIMIT @limit", args).
+		Scan(&timeline).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load interest timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"listing_id":       listing.ID,
+		"interested_users": interested,
+		"users_by_action":  users,
+		"recent":           timeline,
+	})
+}
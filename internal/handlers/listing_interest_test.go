@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListingInterest(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	spammer := createTestUser(t, db, "spammer")
+	listing := createTestListing(t, db, owner.ID)
+	other := createTestListing(t, db, owner.ID)
+	create := func(v interface{}) {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := time.Now().Add(-time.Hour)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	// Alice favorites and messages: one interested user, counted under both actions
+	create(&models.Favorite{UserID: alice.ID, ListingID: listing.ID, CreatedAt: at(1)})
+	create(&models.Message{SenderID: alice.ID, ReceiverID: owner.ID, ListingID: &listing.ID, Content: "Hi", CreatedAt: at(2)})
+	create(&models.Message{SenderID: alice.ID, ReceiverID: owner.ID, ListingID: &listing.ID, Content: "Again", CreatedAt: at(3)})
+	create(&models.Lead{SenderID: bob.ID, ReceiverID: owner.ID, ListingID: &listing.ID, Subject: "Offer", Message: "Interested", CreatedAt: at(4)})
+	// Not counted: spam, the owner's own replies and other listings
+	create(&models.Lead{SenderID: spammer.ID, ReceiverID: owner.ID, ListingID: &listing.ID, Subject: "Buy", Message: "Cheap", IsSpam: true, CreatedAt: at(5)})
+	create(&models.Message{SenderID: owner.ID, ReceiverID: alice.ID, ListingID: &listing.ID, Content: "Thanks", CreatedAt: at(6)})
+	create(&models.Favorite{UserID: spammer.ID, ListingID: other.ID, CreatedAt: at(7)})
+
+	as := func(user *models.User) *gin.Engine {
+		r := gin.New()
+		r.GET("/listings/:id/interest", asUser(user.ID), h.Interest)
+		return r
+	}
+	target := fmt.Sprintf("/listings/%d/interest", listing.ID)
+
+	w := serve(as(owner), http.MethodGet, target, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	if body["interested_users"] != float64(2) {
+		t.Errorf("interested_users %v, want 2", body["interested_users"])
+	}
+	byAction := body["users_by_action"].(map[string]interface{})
+	if byAction["favorite"] != float64(1) || byAction["message"] != float64(1) || byAction["lead"] != float64(1) {
+		t.Errorf("users_by_action %v, want one each", byAction)
+	}
+
+	// Newest first, and nothing that identifies who
+	var actions []string
+	for _, entry := range body["recent"].([]interface{}) {
+		event := entry.(map[string]interface{})
+		if len(event) != 2 {
+			t.Errorf("timeline entry %v carries more than action and time", event)
+		}
+		actions = append(actions, event["action"].(string))
+	}
+	if fmt.Sprint(actions) != "[lead message message favorite]" {
+		t.Errorf("timeline %v", actions)
+	}
+
+	if w := serve(as(alice), http.MethodGet, target, nil); w.Code != http.StatusNotFound {
+		t.Errorf("non-owner: status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// listingMetadataKey caches the listing form options in Redis
+	listingMetadataKey = "listing:metadata"
+	// listingMetadataTTL bounds how long a newly used option takes to show up
+	listingMetadataTTL = 10 * time.Minute
+
+	// listingOptionsKey caches the category/industry counts served by GetCategories
+	listingOptionsKey = "listing:options"
+	// listingOptionsTTL is short because counts change with every publish
+	listingOptionsTTL = time.Minute
+
+	// listingOptionsMaxAge is how long clients reuse the metadata and category
+	// responses before revalidating them by ETag
+	listingOptionsMaxAge = time.Minute
+)
+
+// PublicListingStatuses are the statuses clients may set on a listing; see
+// models.ListingStatus.OwnerSettable
+var PublicListingStatuses = []models.ListingStatus{models.ListingStatusActive, models.ListingStatusInactive}
+
+// listingStatusList joins statuses for a validation message
+func listingStatusList(statuses []models.ListingStatus) string {
+	names := make([]string, len(statuses))
+	for i, s := range statuses {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// listingMetadata holds the option values for the listing form dropdowns
+type listingMetadata struct {
+	Categories       []string               `json:"categories"`
+	Conditions       []string               `json:"conditions"`
+	Industries       []string               `json:"industries"`
+	DecorationStyles []string               `json:"decoration_styles"`
+	Statuses         []models.ListingStatus `json:"statuses"`
+	Visibilities     []string               `json:"visibilities"`
+}
+
+// loadListingMetadata collects the distinct non-empty values of each option
+// column across public listings
+func (h *ListingsHandler) loadListingMetadata() (*listingMetadata, error) {
+	meta := &listingMetadata{Statuses: PublicListingStatuses, Visibilities: models.ListingVisibilities}
+	columns := []struct {
+		name string
+		dest *[]string
+	}{
+		{"category", &meta.Categories},
+		{"condition", &meta.Conditions},
+		{"industry", &meta.Industries},
+		{"decoration", &meta.DecorationStyles},
+	}
+	for _, col := range columns {
+		*col.dest = []string{}
+		if err := h.DB.Model(&models.Listing{}).
+			Where("status NOT IN ? AND visibility = ?", models.HiddenListingStatuses, models.ListingVisibilityPublic).
+			Where(col.name+" <> ''").
+			Distinct(col.name).
+			Order(col.name).
+			Pluck(col.name, col.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return meta, nil
+}
+
+// Metadata returns the values the listing form dropdowns offer, so the frontend
+// doesn't have to hardcode them
+func (h *ListingsHandler) Metadata(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.RedisClient != nil {
+		if data, err := h.RedisClient.Get(ctx, listingMetadataKey).Bytes(); err == nil {
+			var meta listingMetadata
+			if json.Unmarshal(data, &meta) == nil {
+				h.respondMetadata(c, &meta)
+				return
+			}
+		}
+	}
+
+	meta, err := h.loadListingMetadata()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listing metadata"})
+		return
+	}
+
+	if h.RedisClient != nil {
+		if data, err := json.Marshal(meta); err == nil {
+			_ = h.RedisClient.Set(ctx, listingMetadataKey, data, listingMetadataTTL).Err()
+		}
+	}
+	h.respondMetadata(c, meta)
+}
+
+func (h *ListingsHandler) respondMetadata(c *gin.Context, meta *listingMetadata) {
+	body, err := json.Marshal(gin.H{"metadata": meta})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode listing metadata"})
+		return
+	}
+	respondPublicWithETag(c, body, listingOptionsMaxAge)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// metadataStrings returns the string values of one metadata field
+func metadataStrings(t *testing.T, meta map[string]interface{}, field string) []string {
+	t.Helper()
+	values, ok := meta[field].([]interface{})
+	if !ok {
+		t.Fatalf("metadata %s is %T, want a list", field, meta[field])
+	}
+	out := []string{}
+	for _, v := range values {
+		out = append(out, v.(string))
+	}
+	return out
+}
+
+func TestListingMetadata(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "seller")
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Category = "retail"; l.Decoration = "modern" })
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Condition = "new" })
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Industry = "" })
+	// Deleted and private listings don't contribute options
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Category = "gone"; l.Status = models.ListingStatusDeleted })
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Category = "secret"; l.Visibility = models.ListingVisibilityPrivate })
+
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	r := gin.New()
+	r.GET("/listings/metadata", h.Metadata)
+
+	w := serve(r, http.MethodGet, "/listings/metadata", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	meta := decode(t, w)["metadata"].(map[string]interface{})
+
+	want := map[string][]string{
+		"statuses":          {string(models.ListingStatusActive), string(models.ListingStatusInactive)},
+		"visibilities":      models.ListingVisibilities,
+		"categories":        {"food", "retail"},
+		"conditions":        {"new", "used"},
+		"industries":        {"restaurant"},
+		"decoration_styles": {"modern"},
+	}
+	for field, values := range want {
+		if got := metadataStrings(t, meta, field); !reflect.DeepEqual(got, values) {
+			t.Errorf("%s %v, want %v", field, got, values)
+		}
+	}
+}
+
+func TestListingMetadataCached(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "seller")
+	createTestListing(t, db, owner.ID)
+
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t), RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	r := gin.New()
+	r.GET("/listings/metadata", h.Metadata)
+
+	first := serve(r, http.MethodGet, "/listings/metadata", nil)
+	if first.Code != http.StatusOK || !mr.Exists(listingMetadataKey) {
+		t.Fatalf("status %d, cached %v: %s", first.Code, mr.Exists(listingMetadataKey), first.Body)
+	}
+
+	categories := func() []string {
+		meta := decode(t, serve(r, http.MethodGet, "/listings/metadata", nil))["metadata"].(map[string]interface{})
+		return metadataStrings(t, meta, "categories")
+	}
+
+	// A new category only shows up once the cached copy expires
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Category = "retail" })
+	if got := categories(); !reflect.DeepEqual(got, []string{"food"}) {
+		t.Errorf("categories %v from cache, want [food]", got)
+	}
+	mr.FastForward(listingMetadataTTL)
+	if got := categories(); !reflect.DeepEqual(got, []string{"food", "retail"}) {
+		t.Errorf("categories %v after expiry, want [food retail]", got)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPreviewMatchesSearchEntry(t *testing.T) {
+	tests := []struct {
+		name  string
+		gated bool
+		edit  func(*models.Listing)
+	}{
+		{name: "contact gated", gated: true},
+		{name: "contact open", gated: false},
+		{name: "hidden counters", gated: true, edit: func(l *models.Listing) {
+			l.HideViewCount, l.HideFavoriteCount, l.HideLastActive = true, true, true
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.ContactRevealRequiresLead = tt.gated
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			owner := createTestUser(t, db, "seller")
+			edit := func(*models.Listing) {}
+			if tt.edit != nil {
+				edit = tt.edit
+			}
+			listing := createTestListing(t, db, owner.ID, edit)
+
+			r := gin.New()
+			r.GET("/listings", h.List)
+			r.GET("/listings/:id/preview", asUser(owner.ID), h.Preview)
+
+			w := serve(r, http.MethodGet, fmt.Sprintf("/listings/%d/preview", listing.ID), nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("preview status %d: %s", w.Code, w.Body)
+			}
+			preview := decode(t, w)
+			search := decode(t, serve(r, http.MethodGet, "/listings", nil))["listings"].([]interface{})
+			if len(search) != 1 {
+				t.Fatalf("search returned %d listings, want 1", len(search))
+			}
+			if !reflect.DeepEqual(preview["search_result"], search[0]) {
+				t.Errorf("preview search_result = %v\nwant the list entry %v", preview["search_result"], search[0])
+			}
+
+			phone := preview["search_result"].(map[string]interface{})["phone_number"]
+			if masked := phone != listing.PhoneNumber; masked != tt.gated {
+				t.Errorf("phone_number = %v, masked %v, want masked %v", phone, masked, tt.gated)
+			}
+			for _, key := range []string{"card", "quality", "warnings", "searchable"} {
+				if _, ok := preview[key]; !ok {
+					t.Errorf("preview is missing %q", key)
+				}
+			}
+		})
+	}
+}
+
+func TestPreviewIsOwnerOnly(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, owner.ID)
+
+	r := gin.New()
+	r.GET("/listings/:id/preview", asUser(buyer.ID), h.Preview)
+	if w := serve(r, http.MethodGet, fmt.Sprintf("/listings/%d/preview", listing.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("buyer preview status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListingPrivacyFlags(t *testing.T) {
+	tests := []struct {
+		name                         string
+		hideViews, hideFavs, hideLog bool
+	}{
+		{name: "nothing hidden"},
+		{name: "views hidden", hideViews: true},
+		{name: "favorites hidden", hideFavs: true},
+		{name: "last active hidden", hideLog: true},
+		{name: "everything hidden", hideViews: true, hideFavs: true, hideLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) {
+				l.ViewCount, l.FavoriteCount = 7, 3
+				l.HideViewCount, l.HideFavoriteCount, l.HideLastActive = tt.hideViews, tt.hideFavs, tt.hideLog
+			})
+
+			public := gin.New()
+			public.GET("/listings", h.List)
+			public.GET("/listings/:id", h.Get)
+			ownerView := gin.New()
+			ownerView.GET("/listings/:id", asUser(owner.ID), h.Get)
+
+			detail := decode(t, serve(public, http.MethodGet, fmt.Sprintf("/listings/%d", listing.ID), nil))["listing"].(map[string]interface{})
+			search := decode(t, serve(public, http.MethodGet, "/listings", nil))["listings"].([]interface{})
+			if len(search) != 1 {
+				t.Fatalf("search returned %d listings, want 1", len(search))
+			}
+			for where, entry := range map[string]map[string]interface{}{"detail": detail, "search": search[0].(map[string]interface{})} {
+				assertHidden(t, where+" view_count", entry["view_count"], tt.hideViews)
+				assertHidden(t, where+" favorite_count", entry["favorite_count"], tt.hideFavs)
+			}
+
+			// The owner always sees their own numbers
+			own := decode(t, serve(ownerView, http.MethodGet, fmt.Sprintf("/listings/%d", listing.ID), nil))["listing"].(map[string]interface{})
+			assertHidden(t, "owner view_count", own["view_count"], false)
+			assertHidden(t, "owner favorite_count", own["favorite_count"], false)
+		})
+	}
+}
+
+// assertHidden checks a public counter is null exactly when the listing hides it
+func assertHidden(t *testing.T, what string, value interface{}, hidden bool) {
+	t.Helper()
+	if hidden && value != nil {
+		t.Errorf("%s = %v, want it hidden", what, value)
+	}
+	if !hidden && value == nil {
+		t.Errorf("%s is hidden, want it shown", what)
+	}
+}
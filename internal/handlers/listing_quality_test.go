@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMineIncludesQuality(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	other := createTestUser(t, db, "other")
+	listing := createTestListing(t, db, owner.ID)
+	createTestListing(t, db, other.ID)
+
+	r := gin.New()
+	r.GET("/user/listings", asUser(owner.ID), h.Mine)
+	w := serve(r, http.MethodGet, "/user/listings", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	listings := decode(t, w)["listings"].([]interface{})
+	if len(listings) != 1 {
+		t.Fatalf("got %d listings, want only the caller's one", len(listings))
+	}
+
+	quality, ok := listings[0].(map[string]interface{})["quality"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("listing has no quality breakdown: %v", listings[0])
+	}
+	want := models.ScoreListing(listing, 0)
+	if got := int(quality["score"].(float64)); got != want.Score {
+		t.Errorf("quality score %d, want %d", got, want.Score)
+	}
+	if got := len(quality["factors"].([]interface{})); got != len(want.Factors) {
+		t.Errorf("%d quality factors, want %d", got, len(want.Factors))
+	}
+}
+
+func TestMineQualityScores(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		fill        bool // financials, contact, location and industry
+		images      int
+		want        int
+	}{
+		{name: "minimal listing scores low", want: 0},
+		{name: "fully populated listing scores high", description: "<p>" + strings.Repeat("好", 300) + "</p>", fill: true, images: 3, want: 100},
+		{name: "markup does not pad the description", description: "<ul>" + strings.Repeat("<li><strong></strong></li>", 100) + "<li>" + strings.Repeat("a", 30) + "</li></ul>",
+			fill: true, images: 3, want: 82},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+			owner := createTestUser(t, db, "seller")
+
+			r := gin.New()
+			r.POST("/listings", asUser(owner.ID), h.Create)
+			r.GET("/user/listings", asUser(owner.ID), h.Mine)
+			w := serve(r, http.MethodPost, "/listings", map[string]interface{}{"title": "Corner shop", "description": tt.description, "price": 1000000})
+			if w.Code != http.StatusCreated {
+				t.Fatalf("create status %d: %s", w.Code, w.Body)
+			}
+			id := uint(decode(t, w)["listing"].(map[string]interface{})["id"].(float64))
+			if tt.fill {
+				db.Model(&models.Listing{}).Where("id = ?", id).Updates(map[string]interface{}{
+					"annual_revenue": 1200000, "gross_profit_rate": 0.3, "rent": 40000,
+					"phone_number": "0987654321", "location": "Taipei", "industry": "restaurant",
+				})
+			}
+			for i := 0; i < tt.images; i++ {
+				db.Create(&models.Image{ListingID: id, Filename: fmt.Sprintf("%d.jpg", i)})
+			}
+
+			w = serve(r, http.MethodGet, "/user/listings", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			listing := decode(t, w)["listings"].([]interface{})[0].(map[string]interface{})
+			quality := listing["quality"].(map[string]interface{})
+			if got := int(quality["score"].(float64)); got != tt.want {
+				t.Errorf("quality score %d, want %d: %v", got, tt.want, quality["factors"])
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rankSortOption is the listing sort that orders by the blended ranking score
+const rankSortOption = "relevance"
+
+// Each ranking component is computed in SQL and scores 0-1. Relevance is 1 for
+// every listing unless applySearch has a FULLTEXT match to score it on.
+const (
+	rankRelevanceSQL = "1"
+	// Halves every half-life; the ? is the half-life in seconds
+	rankRecencySQL = "POW(0.5, GREATEST(TIMESTAMPDIFF(SECOND, listings.created_at, NOW()), 0) / ?)"
+	// The owner has verified their email
+	rankVerifiedSQL = "COALESCE((SELECT owners.email_verified_at IS NOT NULL FROM users AS owners WHERE owners.id = listings.owner_id), 0)"
+	// Half for an approved image, half for revenue and margin figures; the ? is the approved status
+	rankCompletenessSQL = "((EXISTS (SELECT 1 FROM images WHERE images.listing_id = listings.id AND images.moderation_status = ?))" +
+		" + (listings.annual_revenue > 0 AND listings.gross_profit_rate > 0)) / 2"
+)
+
+// rankWeights weigh the ranking components, from the SEARCH_RANK_* settings
+type rankWeights struct {
+	Relevance       float64 `json:"relevance"`
+	Recency         float64 `json:"recency"`
+	Verified        float64 `json:"verified"`
+	Completeness    float64 `json:"completeness"`
+	halfLifeSeconds int64
+	relevanceSQL    string
+	relevanceVars   []interface{}
+}
+
+func (h *ListingsHandler) rankWeights() rankWeights {
+	return rankWeights{
+		Relevance:       h.Cfg.SearchRankRelevanceWeight,
+		Recency:         h.Cfg.SearchRankRecencyWeight,
+		Verified:        h.Cfg.SearchRankVerifiedWeight,
+		Completeness:    h.Cfg.SearchRankCompletenessWeight,
+		halfLifeSeconds: int64(h.Cfg.SearchRankRecencyHalfLifeDays) * 24 * 60 * 60,
+		relevanceSQL:    rankRelevanceSQL,
+	}
+}
+
+// rankComponents are one listing's ranking inputs, as returned by ?explain=true
+type rankComponents struct {
+	ID           uint    `json:"id"`
+	Relevance    float64 `json:"relevance"`
+	Recency      float64 `json:"recency"`
+	Verified     float64 `json:"verified"`
+	Completeness float64 `json:"completeness"`
+	Score        float64 `json:"score"`
+}
+
+// score blends the components. orderBy computes the same sum in SQL so pages
+// come back in score order; the two must stay in step.
+func (w rankWeights) score(c rankComponents) float64 {
+	return w.Relevance*c.Relevance + w.Recency*c.Recency + w.Verified*c.Verified + w.Completeness*c.Completeness
+}
+
+// orderBy orders listings by descending score, newest first among ties
+func (w rankWeights) orderBy() clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL: fmt.Sprintf("(? * %s + ? * %s + ? * %s + ? * %s) DESC, listings.created_at DESC",
+			w.relevanceSQL, rankRecencySQL, rankVerifiedSQL, rankCompletenessSQL),
+		Vars: append(append([]interface{}{w.Relevance}, w.relevanceVars...),
+			w.Recency, w.halfLifeSeconds,
+			w.Verified,
+			w.Completeness, models.ImageModerationApproved,
+		),
+		WithoutParentheses: true,
+	}}
+}
+
+// explain loads the ranking components of the given listings and scores them,
+// in the order of ids
+func (w rankWeights) explain(db *gorm.DB, ids []uint) ([]rankComponents, error) {
+	var rows []rankComponents
+	if len(ids) > 0 {
+		if err := db.Model(&models.Listing{}).
+			Select(fmt.Sprintf("listings.id, %s AS relevance, %s AS recency, %s AS verified, %s AS completeness",
+				w.relevanceSQL, rankRecencySQL, rankVerifiedSQL, rankCompletenessSQL),
+				append(append([]interface{}{}, w.relevanceVars...), w.halfLifeSeconds, models.ImageModerationApproved)...).
+			Where("listings.id IN ?", ids).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byID := make(map[uint]rankComponents, len(rows))
+	for _, r := range rows {
+		r.Score = w.score(r)
+		byID[r.ID] = r
+	}
+	result := make([]rankComponents, 0, len(ids))
+	for _, id := range ids {
+		if r, ok := byID[id]; ok {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
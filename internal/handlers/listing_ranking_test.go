@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// rankCorpus are listings of different ages, owners and completeness, scored
+// as the ranking SQL would. Every relevance is 1, as without a keyword.
+func rankCorpus(halfLifeDays float64) map[string]rankComponents {
+	recency := func(days float64) float64 { return math.Pow(0.5, days/halfLifeDays) }
+	return map[string]rankComponents{
+		"fresh, bare":                     {Relevance: 1, Recency: recency(0)},
+		"week old, verified, complete":    {Relevance: 1, Recency: recency(7), Verified: 1, Completeness: 1},
+		"week old, verified, images only": {Relevance: 1, Recency: recency(7), Verified: 1, Completeness: 0.5},
+		"month old, complete":             {Relevance: 1, Recency: recency(30), Completeness: 1},
+		"year old, verified":              {Relevance: 1, Recency: recency(365), Verified: 1},
+	}
+}
+
+// ranked orders the corpus by descending score
+func ranked(w rankWeights, corpus map[string]rankComponents) []string {
+	names := make([]string, 0, len(corpus))
+	for name := range corpus {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return w.score(corpus[names[i]]) > w.score(corpus[names[j]]) })
+	return names
+}
+
+func TestRankOrdering(t *testing.T) {
+	tests := []struct {
+		name   string
+		adjust func(w *rankWeights)
+		want   []string
+	}{
+		{name: "default weights", want: []string{
+			"week old, verified, complete", "week old, verified, images only", "fresh, bare",
+			"month old, complete", "year old, verified",
+		}},
+		{name: "freshness first", adjust: func(w *rankWeights) { w.Recency = 3 }, want: []string{
+			"fresh, bare", "week old, verified, complete", "week old, verified, images only",
+			"month old, complete", "year old, verified",
+		}},
+		{name: "verification over freshness", adjust: func(w *rankWeights) { w.Recency, w.Verified = 0, 0.5 }, want: []string{
+			"week old, verified, complete", "week old, verified, images only", "year old, verified",
+			"month old, complete", "fresh, bare",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &ListingsHandler{Cfg: testConfig(t)}
+			w := h.rankWeights()
+			if tt.adjust != nil {
+				tt.adjust(&w)
+			}
+			corpus := rankCorpus(float64(h.Cfg.SearchRankRecencyHalfLifeDays))
+			if got := ranked(w, corpus); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("order\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankRelevanceOutweighsTheRest(t *testing.T) {
+	// A strong keyword match on a bare listing beats a weak one on a verified,
+	// complete listing of the same age
+	w := (&ListingsHandler{Cfg: testConfig(t)}).rankWeights()
+	strong := rankComponents{Relevance: 0.9, Recency: 0.5}
+	weak := rankComponents{Relevance: 0.1, Recency: 0.5, Verified: 1, Completeness: 1}
+	if w.score(strong) <= w.score(weak) {
+		t.Errorf("strong match scores %v, weak match %v", w.score(strong), w.score(weak))
+	}
+}
+
+func TestRankOrderByVars(t *testing.T) {
+	browse := (&ListingsHandler{Cfg: testConfig(t)}).rankWeights()
+	keyword := browse
+	keyword.relevanceSQL = "(MATCH(title) AGAINST (?) / (MATCH(title) AGAINST (?) + 1))"
+	keyword.relevanceVars = []interface{}{"cafe", "cafe"}
+
+	for name, w := range map[string]rankWeights{"browse": browse, "keyword": keyword} {
+		expr := w.orderBy().Expression.(clause.Expr)
+		// Placeholders and values line up, so each weight multiplies its component
+		if n := strings.Count(expr.SQL, "?"); n != len(expr.Vars) {
+			t.Errorf("%s: %d placeholders for %d values", name, n, len(expr.Vars))
+		}
+		want := []interface{}{w.Relevance}
+		want = append(want, w.relevanceVars...)
+		want = append(want, w.Recency, w.halfLifeSeconds, w.Verified, w.Completeness)
+		if fmt.Sprint(expr.Vars[:len(want)]) != fmt.Sprint(want) {
+			t.Errorf("%s: values %v, want %v first", name, expr.Vars, want)
+		}
+	}
+}
+
+func TestListingsExplainAdminOnly(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	user := createTestUser(t, db, "user")
+
+	anonymous := gin.New()
+	anonymous.GET("/listings", h.List)
+	signedIn := gin.New()
+	signedIn.GET("/listings", asUser(user.ID), h.List)
+
+	for name, r := range map[string]*gin.Engine{"anonymous": anonymous, "regular user": signedIn} {
+		if w := serve(r, http.MethodGet, "/listings?sort=relevance&explain=true", nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", name, w.Code)
+		}
+	}
+	if w := serve(anonymous, http.MethodGet, "/listings?sort=best", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown sort: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// listingSearchIndex is the FULLTEXT index over listingSearchColumns, added by
+// migration 000044. The plain-text description is searched, not the HTML.
+const listingSearchIndex = "ft_listings_search"
+
+var listingSearchColumns = []string{"title", "description_text", "brand_story", "equipment"}
+
+const (
+	// ngramTokenSize is MySQL's default ngram_token_size. The index holds no
+	// shorter tokens, so a shorter term can only be found with LIKE.
+	ngramTokenSize = 2
+	// listingSearchMaxTerms bounds the clauses a single search can add
+	listingSearchMaxTerms = 10
+)
+
+// listingSearchTerms splits ?q= into its whitespace-separated terms. Double
+// quotes are dropped, as each term is quoted as a phrase for MATCH.
+func listingSearchTerms(q string) []string {
+	var terms []string
+	for _, t := range strings.Fields(strings.ReplaceAll(q, `"`, " ")) {
+		if len(terms) == listingSearchMaxTerms {
+			break
+		}
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// hasSearchIndex reports whether the listing FULLTEXT index exists. It is
+// looked up once; without it (migration not run yet, or a database that
+// can't build ngram indexes) searches fall back to LIKE.
+func (h *ListingsHandler) hasSearchIndex() bool {
+	h.searchIndexOnce.Do(func() {
+		var n int64
+		err := h.DB.Raw("SELECT COUNT(*) FROM information_schema.statistics"+
+			" WHERE table_schema = DATABASE() AND table_name = 'listings' AND index_name = ? AND index_type = 'FULLTEXT'",
+			listingSearchIndex).Scan(&n).Error
+		h.searchIndex = err == nil && n > 0
+	})
+	return h.searchIndex
+}
+
+// applySearch narrows query to listings containing every term in one of the
+// searched columns, and scores relevance on the match when the FULLTEXT index
+// is used. Both ways match case-insensitively, under the columns' collation.
+func (h *ListingsHandler) applySearch(query *gorm.DB, terms []string, weights *rankWeights) *gorm.DB {
+	fulltext := h.hasSearchIndex()
+	for _, t := range terms {
+		if utf8.RuneCountInString(t) < ngramTokenSize {
+			fulltext = false
+		}
+	}
+
+	if fulltext {
+		phrases := make([]string, len(terms))
+		for i, t := range terms {
+			phrases[i] = `+"` + t + `"`
+		}
+		against := strings.Join(phrases, " ")
+		match := "MATCH(listings." + strings.Join(listingSearchColumns, ", listings.") + ") AGAINST (? IN BOOLEAN MODE)"
+
+		// MATCH scores are unbounded; s/(s+1) maps them onto 0-1 like the other components
+		weights.relevanceSQL = "(" + match + " / (" + match + " + 1))"
+		weights.relevanceVars = []interface{}{against, against}
+		return query.Where(match, against)
+	}
+
+	for _, t := range terms {
+		pattern := "%" + escapeLike(t) + "%"
+		conds := make([]string, len(listingSearchColumns))
+		vars := make([]interface{}, len(listingSearchColumns))
+		for i, column := range listingSearchColumns {
+			conds[i] = "listings." + column + " LIKE ?"
+			vars[i] = pattern
+		}
+		query = query.Where(strings.Join(conds, " OR "), vars...)
+	}
+	return query
+}
+
+// likeEscaper escapes LIKE wildcards, with MySQL's default \ escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
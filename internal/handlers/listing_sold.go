@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// offPlatformPaymentMethod marks transactions the seller recorded themselves
+// for a sale settled outside the platform
+const offPlatformPaymentMethod = "off_platform"
+
+// errListingNotSellable is returned inside the mark-sold transaction when the
+// listing is already sold or pending deletion
+var errListingNotSellable = errors.New("listing cannot be marked as sold")
+
+// MarkSoldRequest optionally records who bought the listing and for how much
+type MarkSoldRequest struct {
+	BuyerID *uint  `json:"buyer_id"`
+	Amount  *int64 `json:"amount"`
+}
+
+// MarkSold closes a listing as sold, which takes it out of browsing and search
+// for good; unlike a deactivated listing it can't be reopened. When a buyer
+// and amount are given a completed transaction is recorded for the sale.
+func (h *ListingsHandler) MarkSold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	ownerID := userID.(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	// The body is optional; an empty one just marks the listing sold
+	var req MarkSoldRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	errs := fieldErrors{}
+	if req.Amount != nil && req.BuyerID == nil {
+		errs["buyer_id"] = "is required when amount is given"
+	}
+	if req.BuyerID != nil {
+		switch {
+		case req.Amount == nil:
+			errs["amount"] = "is required when buyer_id is given"
+		case *req.Amount <= 0:
+			errs["amount"] = "must be greater than 0"
+		}
+		if *req.BuyerID == ownerID {
+			errs["buyer_id"] = "cannot be the seller"
+		}
+	}
+	if !errs.check(c) {
+		return
+	}
+
+	if req.BuyerID != nil {
+		var buyer models.User
+		if err := h.DB.Select("id").First(&buyer, *req.BuyerID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Buyer not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up buyer"})
+			return
+		}
+	}
+
+	var listing models.Listing
+	var transaction *models.Transaction
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND owner_id = ? AND status <> ?", id, ownerID, models.ListingStatusDeleted).
+			First(&listing).Error; err != nil {
+			return err
+		}
+		if listing.Status != models.ListingStatusActive && listing.Status != models.ListingStatusInactive {
+			return errListingNotSellable
+		}
+
+		if req.BuyerID != nil {
+			now := time.Now()
+			transaction = &models.Transaction{
+				ListingID:     listing.ID,
+				BuyerID:       *req.BuyerID,
+				SellerID:      ownerID,
+				Amount:        *req.Amount,
+				Status:        models.TransactionStatusCompleted,
+				PaymentMethod: offPlatformPaymentMethod,
+				CompletedAt:   &now,
+			}
+			if err := tx.Create(transaction).Error; err != nil {
+				return err
+			}
+		}
+
+		// Through the model so the listing count hooks see the status change
+		return tx.Model(&listing).Update("status", models.ListingStatusSold).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	case errors.Is(err, errListingNotSellable):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Only active or inactive listings can be marked as sold",
+			"code":   "LISTING_NOT_SELLABLE",
+			"status": listing.Status,
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark listing as sold"})
+		return
+	}
+	h.invalidateListing(listing.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Listing marked as sold",
+		"status":      listing.Status,
+		"transaction": transaction,
+	})
+}
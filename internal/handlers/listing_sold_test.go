@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMarkSold(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          models.ListingStatus
+		body            func(buyer *models.User) map[string]interface{}
+		code            int
+		wantTransaction bool
+	}{
+		{name: "without transaction", status: models.ListingStatusActive, code: http.StatusOK},
+		{name: "with transaction", status: models.ListingStatusActive, code: http.StatusOK, wantTransaction: true,
+			body: func(buyer *models.User) map[string]interface{} {
+				return map[string]interface{}{"buyer_id": buyer.ID, "amount": 850000}
+			}},
+		{name: "inactive listing", status: models.ListingStatusInactive, code: http.StatusOK},
+		{name: "already sold", status: models.ListingStatusSold, code: http.StatusConflict},
+		{name: "amount without buyer", status: models.ListingStatusActive, code: http.StatusBadRequest,
+			body: func(*models.User) map[string]interface{} { return map[string]interface{}{"amount": 1} }},
+		{name: "buyer without amount", status: models.ListingStatusActive, code: http.StatusBadRequest,
+			body: func(buyer *models.User) map[string]interface{} { return map[string]interface{}{"buyer_id": buyer.ID} }},
+		{name: "unknown buyer", status: models.ListingStatusActive, code: http.StatusBadRequest,
+			body: func(*models.User) map[string]interface{} { return map[string]interface{}{"buyer_id": 999, "amount": 1} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.Transaction{})
+			h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+			owner := createTestUser(t, db, "seller")
+			buyer := createTestUser(t, db, "buyer")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Status = tt.status })
+			r := gin.New()
+			r.POST("/listings/:id/mark-sold", asUser(owner.ID), h.MarkSold)
+
+			var body interface{}
+			if tt.body != nil {
+				body = tt.body(buyer)
+			}
+			w := serve(r, http.MethodPost, fmt.Sprintf("/listings/%d/mark-sold", listing.ID), body)
+			if w.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			want := tt.status
+			if tt.code == http.StatusOK {
+				want = models.ListingStatusSold
+			}
+			if stored.Status != want {
+				t.Errorf("listing status %q, want %q", stored.Status, want)
+			}
+
+			var transactions []models.Transaction
+			db.Find(&transactions)
+			if !tt.wantTransaction {
+				if len(transactions) != 0 {
+					t.Errorf("recorded %d transactions, want none", len(transactions))
+				}
+				return
+			}
+			if len(transactions) != 1 {
+				t.Fatalf("recorded %d transactions, want 1", len(transactions))
+			}
+			txn := transactions[0]
+			if txn.BuyerID != buyer.ID || txn.SellerID != owner.ID || txn.Amount != 850000 ||
+				txn.Status != models.TransactionStatusCompleted || txn.PaymentMethod != offPlatformPaymentMethod || txn.CompletedAt == nil {
+				t.Errorf("transaction %+v", txn)
+			}
+		})
+	}
+}
+
+func TestMarkSoldOwnerOnlyAndLeavesSearch(t *testing.T) {
+	db := newTestDB(t, &models.Transaction{})
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	other := createTestUser(t, db, "other")
+	sold := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Title = "Sold cafe" })
+	createTestListing(t, db, owner.ID, func(l *models.Listing) { l.Title = "Open cafe" })
+
+	as := func(user *models.User) *gin.Engine {
+		r := gin.New()
+		r.GET("/listings", h.List)
+		r.POST("/listings/:id/mark-sold", asUser(user.ID), h.MarkSold)
+		return r
+	}
+	target := fmt.Sprintf("/listings/%d/mark-sold", sold.ID)
+	if w := serve(as(other), http.MethodPost, target, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("non-owner: status %d, want 404", w.Code)
+	}
+	if w := serve(as(owner), http.MethodPost, target, nil); w.Code != http.StatusOK {
+		t.Fatalf("owner: status %d: %s", w.Code, w.Body)
+	}
+
+	for _, query := range []string{"", "?q=cafe&sort=newest"} {
+		w := serve(as(owner), http.MethodGet, "/listings"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("search %q: status %d: %s", query, w.Code, w.Body)
+		}
+		var titles []string
+		for _, l := range decode(t, w)["listings"].([]interface{}) {
+			titles = append(titles, l.(map[string]interface{})["title"].(string))
+		}
+		if fmt.Sprint(titles) != "[Open cafe]" {
+			t.Errorf("search %q found %q, want only the open listing", query, titles)
+		}
+	}
+}
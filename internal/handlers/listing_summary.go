@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// primaryImage picks the image shown on listing cards: the one flagged primary,
+// else the first one loaded
+func primaryImage(images []models.Image) *models.Image {
+	for i := range images {
+		if images[i].IsPrimary {
+			return &images[i]
+		}
+	}
+	if len(images) > 0 {
+		return &images[0]
+	}
+	return nil
+}
+
+// listingSummary is the compact card representation of a listing used in
+// list-style responses. Only the primary image is included.
+func listingSummary(l *models.Listing) gin.H {
+	summary := gin.H{
+		"id":             l.ID,
+		"title":          l.Title,
+		"price":          l.Price,
+		"category":       l.Category,
+		"location":       l.Location,
+		"industry":       l.Industry,
+		"status":         l.Status,
+		"view_count":     l.PublicViewCount(),
+		"favorite_count": l.PublicFavoriteCount(),
+		"price_range": gin.H{
+			"low":  int64(float64(l.Price) * 0.85),
+			"high": int64(float64(l.Price) * 1.15),
+		},
+		"primary_image":       nil,
+		"financials_verified": l.FinancialsVerified,
+	}
+	if img := primaryImage(l.Images); img != nil {
+		summary["primary_image"] = gin.H{
+			"url":      img.URL,
+			"alt_text": img.AltText,
+		}
+	}
+	return summary
+}
+
+// listingSearchEntry is a listing as it appears in the public search results
+// of GET /listings, with its approved images and owner loaded. Contact details
+// are masked as on the detail while gated is set.
+func listingSearchEntry(l *models.Listing, gated bool) gin.H {
+	phone, owner := publicContact(l, gated)
+	return gin.H{
+		"id":                  l.ID,
+		"title":               l.Title,
+		"description":         l.DescriptionHTML(),
+		"description_text":    l.DescriptionText,
+		"price":               l.Price,
+		"category":            l.Category,
+		"condition":           l.Condition,
+		"location":            l.Location,
+		"status":              l.Status,
+		"owner_id":            l.OwnerID,
+		"view_count":          l.PublicViewCount(),
+		"favorite_count":      l.PublicFavoriteCount(),
+		"created_at":          l.CreatedAt,
+		"updated_at":          l.UpdatedAt,
+		"brand_story":         l.BrandStory,
+		"rent":                l.Rent,
+		"floor":               l.Floor,
+		"equipment":           l.Equipment,
+		"decoration":          l.Decoration,
+		"annual_revenue":      l.AnnualRevenue,
+		"gross_profit_rate":   l.GrossProfitRate,
+		"fastest_moving_date": l.FastestMovingDate,
+		"phone_number":        phone,
+		"square_meters":       l.SquareMeters,
+		"industry":            l.Industry,
+		"deposit":             l.Deposit,
+		"owner":               owner,
+		"images":              l.Images,
+
+		"financials_verified":    l.FinancialsVerified,
+		"financials_verified_at": l.FinancialsVerifiedAt,
+		"price_range": gin.H{
+			"low":  int64(float64(l.Price) * 0.85),
+			"high": int64(float64(l.Price) * 1.15),
+		},
+	}
+}
+
+// listingTombstone stands in for a listing that is no longer available in
+// favorites and message threads: the title stays readable but there is nothing
+// to link to
+func listingTombstone(l *models.Listing) gin.H {
+	return gin.H{
+		"id":          l.ID,
+		"title":       l.Title,
+		"unavailable": true,
+	}
+}
+
+// respondListingUnavailable rejects a new lead or message about a listing that
+// is sold, inactive or deleted
+func respondListingUnavailable(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{"error": "This listing is no longer available", "code": "LISTING_UNAVAILABLE"})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/sanitize"
+	"trade_company/internal/translate"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listingLang reads ?lang. Listings are written in Traditional Chinese; "en"
+// asks for the machine translation.
+func listingLang(c *gin.Context) (english bool, ok bool) {
+	switch c.Query("lang") {
+	case "", "zh-TW":
+		return false, true
+	case "en":
+		return true, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "lang must be en or zh-TW"})
+	return false, false
+}
+
+// translateListingFields swaps a listing response's title and description for
+// the English translation when there is one. "translated" tells the client
+// whether it got English or the original.
+func translateListingFields(entry gin.H, listing *models.Listing) {
+	if listing.TitleEn == nil {
+		entry["translated"] = false
+		return
+	}
+	entry["title"] = *listing.TitleEn
+	desc, descText := "", ""
+	if listing.DescriptionEn != nil {
+		desc, descText = sanitize.Description(*listing.DescriptionEn)
+	}
+	entry["description"] = desc
+	entry["description_text"] = descText
+	entry["translated"] = true
+}
+
+// queueTranslations asks the translation job to translate listings that have
+// no translation yet. Nothing is queued while translation is disabled.
+func (h *ListingsHandler) queueTranslations(listings []models.Listing) {
+	if h.Cfg.TranslationProvider == translate.ProviderNone {
+		return
+	}
+	var ids []uint
+	for _, l := range listings {
+		if l.TitleEn == nil && l.TranslationRequestedAt == nil {
+			ids = append(ids, l.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	// Best effort: a failed enqueue is retried by the next request
+	_ = h.DB.Model(&models.Listing{}).
+		Where("id IN ? AND title_en IS NULL AND translation_requested_at IS NULL", ids).
+		UpdateColumn("translation_requested_at", time.Now()).Error
+}
+
+// clearTranslations adds the updates that drop a listing's translation when its
+// title or description changes
+func clearTranslations(updates map[string]interface{}, listing *models.Listing) {
+	title, titleSet := updates["title"]
+	desc, descSet := updates["description"]
+	if (titleSet && title != listing.Title) || (descSet && desc != listing.Description) {
+		updates["title_en"] = nil
+		updates["description_en"] = nil
+		updates["translation_requested_at"] = nil
+	}
+}
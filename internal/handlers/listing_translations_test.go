@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+	"trade_company/internal/translate"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListingEnglishTranslation(t *testing.T) {
+	titleEn, descEn := "Corner cafe (EN)", "<p>A busy cafe <script>x</script></p>"
+
+	tests := []struct {
+		name       string
+		provider   string
+		titleEn    *string
+		descEn     *string
+		wantTitle  string
+		wantDesc   string
+		translated bool
+		wantQueued bool
+	}{
+		{name: "no translation falls back to the original", provider: translate.ProviderGoogle,
+			wantTitle: "Corner cafe", wantDesc: "A busy cafe", wantQueued: true},
+		{name: "nothing queued while translation is disabled", provider: translate.ProviderNone,
+			wantTitle: "Corner cafe", wantDesc: "A busy cafe"},
+		{name: "stored translation is served", provider: translate.ProviderGoogle, titleEn: &titleEn, descEn: &descEn,
+			wantTitle: titleEn, wantDesc: "<p>A busy cafe </p>", translated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.TranslationProvider = tt.provider
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) {
+				l.TitleEn = tt.titleEn
+				l.DescriptionEn = tt.descEn
+			})
+
+			r := gin.New()
+			r.GET("/listings", h.List)
+			r.GET("/listings/:id", h.Get)
+			for _, target := range []string{fmt.Sprintf("/listings/%d?lang=en", listing.ID), "/listings?lang=en"} {
+				w := serve(r, http.MethodGet, target, nil)
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s status %d: %s", target, w.Code, w.Body)
+				}
+				body := decode(t, w)
+				got, ok := body["listing"].(map[string]interface{})
+				if !ok {
+					got = body["listings"].([]interface{})[0].(map[string]interface{})
+				}
+				if got["title"] != tt.wantTitle || got["description"] != tt.wantDesc {
+					t.Errorf("%s title %q description %q, want %q %q", target, got["title"], got["description"], tt.wantTitle, tt.wantDesc)
+				}
+				if got["translated"] != tt.translated {
+					t.Errorf("%s translated %v, want %v", target, got["translated"], tt.translated)
+				}
+			}
+
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if queued := stored.TranslationRequestedAt != nil; queued != tt.wantQueued {
+				t.Errorf("queued for translation %v, want %v", queued, tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestListingTranslationInvalidation(t *testing.T) {
+	titleEn, descEn := "Corner cafe (EN)", "<p>A busy cafe</p>"
+	tests := []struct {
+		name    string
+		update  map[string]interface{}
+		cleared bool
+	}{
+		{name: "title changed", update: map[string]interface{}{"title": "Corner bakery"}, cleared: true},
+		{name: "description changed", update: map[string]interface{}{"description": "<p>Now a bakery</p>"}, cleared: true},
+		{name: "other field changed", update: map[string]interface{}{"price": 2000000}},
+		{name: "same title resent", update: map[string]interface{}{"title": "Corner cafe", "price": 2000000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.TranslationProvider = translate.ProviderGoogle
+			h := &ListingsHandler{DB: db, Cfg: cfg}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) {
+				l.TitleEn = &titleEn
+				l.DescriptionEn = &descEn
+			})
+			r := gin.New()
+			r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+
+			w := serve(r, http.MethodPut, fmt.Sprintf("/listings/%d", listing.ID), tt.update)
+			if w.Code != http.StatusOK {
+				t.Fatalf("update status %d: %s", w.Code, w.Body)
+			}
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if cleared := stored.TitleEn == nil && stored.DescriptionEn == nil; cleared != tt.cleared {
+				t.Errorf("translation cleared %v, want %v", cleared, tt.cleared)
+			}
+		})
+	}
+}
+
+func TestListingLangParam(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID)
+	r := gin.New()
+	r.GET("/listings", h.List)
+	r.GET("/listings/:id", h.Get)
+
+	for _, target := range []string{"/listings?lang=fr", fmt.Sprintf("/listings/%d?lang=fr", listing.ID)} {
+		if w := serve(r, http.MethodGet, target, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s status %d, want 400", target, w.Code)
+		}
+	}
+	w := serve(r, http.MethodGet, fmt.Sprintf("/listings/%d?lang=zh-TW", listing.ID), nil)
+	if got := decode(t, w)["listing"].(map[string]interface{}); w.Code != http.StatusOK || got["translated"] != nil {
+		t.Errorf("zh-TW status %d translated %v, want the original without a translated flag", w.Code, got["translated"])
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListingVisibility(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	stranger := createTestUser(t, db, "buyer")
+	admin := createTestUser(t, db, "admin")
+	db.Model(admin).Update("role", models.RoleAdmin)
+
+	listings := map[string]*models.Listing{}
+	for visibility, category := range map[string]string{
+		models.ListingVisibilityPublic:   "food",
+		models.ListingVisibilityUnlisted: "retail",
+		models.ListingVisibilityPrivate:  "services",
+	} {
+		visibility, category := visibility, category
+		listings[visibility] = createTestListing(t, db, owner.ID, func(l *models.Listing) {
+			l.Title = visibility + " cafe"
+			l.Category = category
+			l.Visibility = visibility
+		})
+	}
+
+	routers := map[string]*gin.Engine{}
+	for name, viewer := range map[string]*models.User{"anonymous": nil, "stranger": stranger, "owner": owner, "admin": admin} {
+		r := gin.New()
+		if viewer != nil {
+			r.Use(asUser(viewer.ID))
+		}
+		r.GET("/listings", h.List)
+		r.GET("/listings/categories", h.GetCategories)
+		r.GET("/listings/:id", h.Get)
+		routers[name] = r
+	}
+
+	// Browsing, search and category counts only ever show the public listing,
+	// even to the owner
+	for name, r := range routers {
+		for _, target := range []string{"/listings", "/listings?q=cafe&sort=newest", "/listings?category=food"} {
+			var titles []string
+			w := serve(r, http.MethodGet, target, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s %s: status %d: %s", name, target, w.Code, w.Body)
+			}
+			for _, l := range decode(t, w)["listings"].([]interface{}) {
+				titles = append(titles, l.(map[string]interface{})["title"].(string))
+			}
+			if len(titles) != 1 || titles[0] != "public cafe" {
+				t.Errorf("%s %s: listings %v, want only the public one", name, target, titles)
+			}
+		}
+	}
+	// Filter values only known from hidden listings are rejected like any unknown value
+	if w := serve(routers["owner"], http.MethodGet, "/listings?category=retail", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("filtering on an unlisted category: status %d, want 400: %s", w.Code, w.Body)
+	}
+	var categories []string
+	for _, c := range decode(t, serve(routers["anonymous"], http.MethodGet, "/listings/categories", nil))["categories"].([]interface{}) {
+		categories = append(categories, c.(string))
+	}
+	if len(categories) != 1 || categories[0] != "food" {
+		t.Errorf("categories %v, want food only", categories)
+	}
+
+	// Direct links: unlisted opens for anyone, private only for the owner and admins
+	tests := []struct {
+		visibility string
+		viewer     string
+		status     int
+	}{
+		{visibility: models.ListingVisibilityPublic, viewer: "anonymous", status: http.StatusOK},
+		{visibility: models.ListingVisibilityUnlisted, viewer: "anonymous", status: http.StatusOK},
+		{visibility: models.ListingVisibilityUnlisted, viewer: "stranger", status: http.StatusOK},
+		{visibility: models.ListingVisibilityPrivate, viewer: "anonymous", status: http.StatusNotFound},
+		{visibility: models.ListingVisibilityPrivate, viewer: "stranger", status: http.StatusNotFound},
+		{visibility: models.ListingVisibilityPrivate, viewer: "owner", status: http.StatusOK},
+		{visibility: models.ListingVisibilityPrivate, viewer: "admin", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.visibility+" to "+tt.viewer, func(t *testing.T) {
+			w := serve(routers[tt.viewer], http.MethodGet, fmt.Sprintf("/listings/%d", listings[tt.visibility].ID), nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			noindex := w.Header().Get("X-Robots-Tag") == "noindex"
+			if w.Code == http.StatusOK && noindex != (tt.visibility != models.ListingVisibilityPublic) {
+				t.Errorf("X-Robots-Tag %q for a %s listing", w.Header().Get("X-Robots-Tag"), tt.visibility)
+			}
+		})
+	}
+}
+
+func TestListingVisibilityInput(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	r := gin.New()
+	r.POST("/listings", asUser(owner.ID), h.Create)
+	r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+
+	tests := []struct {
+		visibility string
+		status     int
+		want       string
+	}{
+		{visibility: "", status: http.StatusCreated, want: models.ListingVisibilityPublic},
+		{visibility: models.ListingVisibilityUnlisted, status: http.StatusCreated, want: models.ListingVisibilityUnlisted},
+		{visibility: models.ListingVisibilityPrivate, status: http.StatusCreated, want: models.ListingVisibilityPrivate},
+		{visibility: "friends", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run("create "+tt.visibility, func(t *testing.T) {
+			w := serve(r, http.MethodPost, "/listings", map[string]interface{}{"title": "Corner shop", "price": 1000000, "visibility": tt.visibility})
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if w.Code != http.StatusCreated {
+				allowed := fmt.Sprint(decode(t, w)["allowed"])
+				if !strings.Contains(allowed, "unlisted") {
+					t.Errorf("allowed %s", allowed)
+				}
+				return
+			}
+			var stored models.Listing
+			db.First(&stored, uint(decode(t, w)["listing"].(map[string]interface{})["id"].(float64)))
+			if stored.Visibility != tt.want {
+				t.Errorf("visibility %q, want %q", stored.Visibility, tt.want)
+			}
+		})
+	}
+
+	listing := createTestListing(t, db, owner.ID)
+	for _, tt := range []struct {
+		visibility string
+		status     int
+	}{
+		{visibility: models.ListingVisibilityUnlisted, status: http.StatusOK},
+		{visibility: "hidden", status: http.StatusBadRequest},
+	} {
+		w := serve(r, http.MethodPut, fmt.Sprintf("/listings/%d", listing.ID), map[string]interface{}{"visibility": tt.visibility})
+		if w.Code != tt.status {
+			t.Errorf("update to %q: status %d, want %d: %s", tt.visibility, w.Code, tt.status, w.Body)
+		}
+	}
+	var stored models.Listing
+	db.First(&stored, listing.ID)
+	if stored.Visibility != models.ListingVisibilityUnlisted {
+		t.Errorf("visibility %q after updates, want unlisted", stored.Visibility)
+	}
+}
@@ -1,42 +1,156 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"trade_company/internal/auth"
+	"trade_company/internal/config"
+	"trade_company/internal/imagecheck"
+	"trade_company/internal/jobs"
 	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/redisclient"
+	"trade_company/internal/sanitize"
+	"trade_company/internal/storage"
+	"trade_company/internal/wordfilter"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 type ListingsHandler struct {
-	DB *gorm.DB
+	DB          *gorm.DB
+	Cfg         *config.Config
+	Storage     *storage.Storage
+	RedisClient *redis.Client
+	Leaderboard *redisclient.Trending   // nil without Redis
+	Exports     *redisclient.ExportGate // nil without Redis
+	Emails      *auth.EmailService
+	Words       *wordfilter.Filter        // Sensitive word filter; nil when off
+	Cache       *redisclient.CacheService // Listing detail and search cache; nil without Redis or when disabled
+
+	searchIndexOnce sync.Once
+	searchIndex     bool
 }
 
-func (h *ListingsHandler) checkDB(c *gin.Context) bool {
-	if h.DB == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database not available"})
-		return false
+// warningRules returns the seller warning rules minus the ones disabled in config
+func (h *ListingsHandler) warningRules() []models.WarningRule {
+	disabled := map[string]bool{}
+	for _, code := range strings.Split(h.Cfg.ListingWarningsDisabled, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			disabled[code] = true
+		}
 	}
 
-	// Check if database connection is alive
-	sqlDB, err := h.DB.DB()
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection error"})
+	all := models.DefaultWarningRules(models.WarningOptions{
+		MaxPriceToRevenue:   int64(h.Cfg.ListingWarningMaxPriceToRevenue),
+		MinDescriptionRunes: h.Cfg.ListingWarningMinDescriptionRunes,
+	})
+	rules := make([]models.WarningRule, 0, len(all))
+	for _, r := range all {
+		if !disabled[r.Code] {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// imagePolicy returns the configured formats and dimensions listing images must satisfy
+func imagePolicy(cfg *config.Config) imagecheck.Policy {
+	return imagecheck.Policy{
+		Formats:   imagecheck.ParseFormats(cfg.ImageAllowedFormats),
+		MaxWidth:  cfg.ImageMaxWidth,
+		MaxHeight: cfg.ImageMaxHeight,
+	}
+}
+
+// rejectImage writes the 400 for an upload that failed the image policy
+func rejectImage(c *gin.Context, filename string, err error) {
+	switch {
+	case errors.Is(err, imagecheck.ErrUnrecognized):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is not a recognized image", "file": filename})
+	case errors.Is(err, imagecheck.ErrFormat), errors.Is(err, imagecheck.ErrDimensions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "file": filename})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image", "file": filename})
+	}
+}
+
+// minImages returns the configured number of images a listing needs before it can go active.
+func (h *ListingsHandler) minImages() int {
+	if h.Cfg == nil || h.Cfg.ListingMinImages < 0 {
+		return 0
+	}
+	return h.Cfg.ListingMinImages
+}
+
+// checkPublishable rejects publishing a listing that has fewer images than the configured minimum.
+func (h *ListingsHandler) checkPublishable(c *gin.Context, listingID uint) bool {
+	minImages := h.minImages()
+	if minImages == 0 {
+		return true
+	}
+
+	var imageCount int64
+	if err := h.DB.Model(&models.Image{}).Where("listing_id = ?", listingID).Count(&imageCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listing images"})
 		return false
 	}
 
-	if err := sqlDB.Ping(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database ping failed"})
+	if imageCount < int64(minImages) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":       fmt.Sprintf("Listing needs at least %d images before it can be published", minImages),
+			"min_images":  minImages,
+			"image_count": imageCount,
+		})
 		return false
 	}
 
 	return true
 }
 
+// checkDescription sanitizes a description to the allowed HTML subset and
+// enforces the length policy on its plain-text version. It returns the
+// sanitized HTML and the plain text to store.
+func checkDescription(c *gin.Context, raw string) (string, string, bool) {
+	desc, descText := sanitize.Description(strings.TrimSpace(raw))
+	if sanitize.DescriptionTooLong(descText) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Description must be at most %d characters", sanitize.MaxDescriptionRunes),
+		})
+		return "", "", false
+	}
+	return desc, descText, true
+}
+
+// canOpen reports whether the current viewer may open the listing by its link:
+// private listings are limited to the owner and admins.
+func (h *ListingsHandler) canOpen(c *gin.Context, listing *models.Listing) bool {
+	viewerID, _ := c.Get("user_id")
+	uid, _ := viewerID.(uint)
+	return listing.VisibleTo(uid) || (uid != 0 && isAdmin(h.DB, uid))
+}
+
+// checkVisibility rejects a visibility value other than public, unlisted or private.
+func checkVisibility(c *gin.Context, visibility string) bool {
+	if !models.ValidListingVisibility(visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid visibility",
+			"allowed": models.ListingVisibilities,
+		})
+		return false
+	}
+	return true
+}
+
 type listingRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
@@ -44,23 +158,33 @@ type listingRequest struct {
 	Category    string `json:"category"`
 	Condition   string `json:"condition"`
 	Location    string `json:"location"`
+	Visibility  string `json:"visibility"` // Defaults to public
 }
 
 type listingUpdateRequest struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	Price       *int64  `json:"price"`
-	Category    *string `json:"category"`
-	Condition   *string `json:"condition"`
-	Location    *string `json:"location"`
-	Status      *string `json:"status"`
+	Title       *string               `json:"title"`
+	Description *string               `json:"description"`
+	Price       *int64                `json:"price"`
+	Category    *string               `json:"category"`
+	Condition   *string               `json:"condition"`
+	Location    *string               `json:"location"`
+	Status      *models.ListingStatus `json:"status"` // Unknown values fail to bind
+	Visibility  *string               `json:"visibility"`
+
+	// Business details
+	Rent            *int64   `json:"rent"`
+	Deposit         *int64   `json:"deposit"`
+	AnnualRevenue   *int64   `json:"annual_revenue"`
+	GrossProfitRate *float64 `json:"gross_profit_rate"` // 0-1
+	PhoneNumber     *string  `json:"phone_number"`
+
+	// Statistics privacy flags
+	HideViewCount     *bool `json:"hide_view_count"`
+	HideFavoriteCount *bool `json:"hide_favorite_count"`
+	HideLastActive    *bool `json:"hide_last_active"`
 }
 
 func (h *ListingsHandler) Create(c *gin.Context) {
-	if !h.checkDB(c) {
-		return
-	}
-
 	var req listingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -73,22 +197,60 @@ func (h *ListingsHandler) Create(c *gin.Context) {
 		return
 	}
 
+	errs := fieldErrors{}
+	errs.between("price", &req.Price, h.Cfg.ListingPriceMin, h.Cfg.ListingPriceMax)
+	if !errs.check(c) {
+		return
+	}
+	if !screenContent(c, h.Words, "listing", map[string]*string{"title": &req.Title, "description": &req.Description}) {
+		return
+	}
+
+	desc, descText, ok := checkDescription(c, req.Description)
+	if !ok {
+		return
+	}
+	if req.Visibility == "" {
+		req.Visibility = models.ListingVisibilityPublic
+	}
+	if !checkVisibility(c, req.Visibility) {
+		return
+	}
+
+	// A new listing has no images yet, so it stays inactive until it meets the image minimum
+	status := models.ListingStatusActive
+	if h.minImages() > 0 {
+		status = models.ListingStatusInactive
+	}
+
 	ownerID := userID.(uint)
 	listing := models.Listing{
-		Title:       req.Title,
-		Description: req.Description,
-		Price:       req.Price,
-		Category:    req.Category,
-		Condition:   req.Condition,
-		Location:    req.Location,
-		OwnerID:     ownerID,
-		Status:      "活躍",
+		Title:           req.Title,
+		Description:     desc,
+		DescriptionText: descText,
+		Price:           req.Price,
+		Category:        req.Category,
+		Condition:       req.Condition,
+		Location:        req.Location,
+		OwnerID:         ownerID,
+		Status:          status,
+		Visibility:      req.Visibility,
+
+		HideViewCount:     h.Cfg.ListingHideViewCountDefault,
+		HideFavoriteCount: h.Cfg.ListingHideFavoriteCountDefault,
+		HideLastActive:    h.Cfg.ListingHideLastActiveDefault,
+	}
+	if ttl := h.Cfg.ListingTTL(); ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		listing.ExpiresAt = &expiresAt
 	}
+	listing.ShowPrivateStats()
 
 	if err := h.DB.Create(&listing).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create listing"})
 		return
 	}
+	h.invalidateListing(listing.ID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Listing created successfully",
@@ -97,27 +259,69 @@ func (h *ListingsHandler) Create(c *gin.Context) {
 }
 
 func (h *ListingsHandler) Get(c *gin.Context) {
-	if !h.checkDB(c) {
-		return
-	}
-
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
 		return
 	}
+	english, ok := listingLang(c)
+	if !ok {
+		return
+	}
 
-	var listing models.Listing
-	if err := h.DB.Preload("Images").
-		Preload("Owner").
-		First(&listing, id).Error; err != nil {
+	cached, err := h.loadListingDetail(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+	listing := *cached
+
+	// Owners and admins see statistics the listing hides from the public
+	viewerID, _ := c.Get("user_id")
+	uid, _ := viewerID.(uint)
+	isOwner, privileged := false, false
+	if uid != 0 && (uid == listing.OwnerID || isAdmin(h.DB, uid)) {
+		listing.ShowPrivateStats()
+		isOwner = uid == listing.OwnerID
+		privileged = true
+	}
+	// Private listings don't exist for anyone else; unlisted ones are open to
+	// anyone with the link but kept out of search engines
+	if listing.Visibility == models.ListingVisibilityPrivate && !privileged {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
 		return
 	}
+	if !listing.IsPublic() {
+		c.Header("X-Robots-Tag", "noindex")
+	}
 
-	// Increment view count
-	h.DB.Model(&listing).Update("view_count", listing.ViewCount+1)
+	// Increment view count (bot-like traffic is filtered out). The row was read
+	// before the increment, so count this view in the response too.
+	if h.recordView(c, listing.ID) {
+		listing.ViewCount++
+		if h.Cache != nil {
+			_ = h.Cache.IncrCachedListingViews(listing.ID)
+		}
+	}
+	if uid != 0 && !isOwner {
+		h.rememberView(uid, listing.ID)
+	}
+	// Images still in moderation are only shown to the owner and admins
+	if !privileged {
+		listing.Images = models.ApprovedImages(listing.Images)
+	}
+
+	// Contact details stay masked on the public detail; buyers who have sent a
+	// lead fetch them from RevealContact
+	gated := h.Cfg.ContactRevealRequiresLead
+	phone, owner := publicContact(&listing, gated)
+
+	// Statements sent for verification are between the seller and the reviewers
+	if !privileged {
+		listing.Documents = publicDocuments(listing.Documents)
+	}
+	documentCounts, documents := documentSummaries(listing.Documents)
 
 	// Add price range to listing
 	low := int64(float64(listing.Price) * 0.85)
@@ -126,14 +330,17 @@ func (h *ListingsHandler) Get(c *gin.Context) {
 	listingWithRange := gin.H{
 		"id":                  listing.ID,
 		"title":               listing.Title,
-		"description":         listing.Description,
+		"description":         listing.DescriptionHTML(),
+		"description_text":    listing.DescriptionText,
 		"price":               listing.Price,
 		"category":            listing.Category,
 		"condition":           listing.Condition,
 		"location":            listing.Location,
 		"status":              listing.Status,
+		"visibility":          listing.Visibility,
 		"owner_id":            listing.OwnerID,
-		"view_count":          listing.ViewCount,
+		"view_count":          listing.PublicViewCount(),
+		"favorite_count":      listing.PublicFavoriteCount(),
 		"created_at":          listing.CreatedAt,
 		"updated_at":          listing.UpdatedAt,
 		"brand_story":         listing.BrandStory,
@@ -144,51 +351,103 @@ func (h *ListingsHandler) Get(c *gin.Context) {
 		"annual_revenue":      listing.AnnualRevenue,
 		"gross_profit_rate":   listing.GrossProfitRate,
 		"fastest_moving_date": listing.FastestMovingDate,
-		"phone_number":        listing.PhoneNumber,
+		"phone_number":        phone,
 		"square_meters":       listing.SquareMeters,
 		"industry":            listing.Industry,
 		"deposit":             listing.Deposit,
-		"owner":               listing.Owner,
+		"owner":               owner,
+		"contact":             listingContact(&listing, !gated),
 		"images":              listing.Images,
+		"documents":           documents,
+		"document_counts":     documentCounts,
+
+		"financials_verified":    listing.FinancialsVerified,
+		"financials_verified_at": listing.FinancialsVerifiedAt,
 		"price_range": gin.H{
 			"low":  low,
 			"high": high,
 		},
 	}
+	if isOwner {
+		listingWithRange["warnings"] = models.ListingWarnings(&listing, len(listing.Images), h.warningRules())
+	}
+	// The cached detail is the same for every viewer, so whether this one
+	// favorited it is looked up separately
+	if uid != 0 {
+		var favorites int64
+		if err := h.DB.Model(&models.Favorite{}).
+			Where("user_id = ? AND listing_id = ?", uid, listing.ID).
+			Count(&favorites).Error; err == nil {
+			listingWithRange["favorited"] = favorites > 0
+		}
+	}
+	if english {
+		translateListingFields(listingWithRange, &listing)
+		h.queueTranslations([]models.Listing{listing})
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"listing": listingWithRange,
 	})
 }
 
-func (h *ListingsHandler) List(c *gin.Context) {
-	if !h.checkDB(c) {
-		return
+// listingSortOrders maps the public sort options to ORDER BY clauses;
+// rankSortOption orders by the ranking score instead
+var listingSortOrders = map[string]string{
+	"newest":         "created_at desc",
+	"views_desc":     "view_count desc, created_at desc",
+	"favorites_desc": "favorite_count desc, created_at desc",
+}
+
+// ListWeight is what a List request counts against the heavy read limiter: a
+// keyword search scores every match, so it costs more than browsing
+func ListWeight(c *gin.Context) int64 {
+	if len(listingSearchTerms(c.Query("q"))) > 0 {
+		return 2
 	}
+	return 1
+}
 
+func (h *ListingsHandler) List(c *gin.Context) {
 	// Parse query parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	category := c.Query("category")
+	p := pagination.Parse(c, pagination.Limits{Default: h.Cfg.ListingsDefaultPageSize, Max: h.Cfg.ListingsMaxPageSize})
 	location := c.Query("location")
 	minPrice, _ := strconv.ParseInt(c.Query("min_price"), 10, 64)
 	maxPrice, _ := strconv.ParseInt(c.Query("max_price"), 10, 64)
-	condition := c.Query("condition")
-
-	// Validate pagination
-	if page < 1 {
-		page = 1
+	terms := listingSearchTerms(c.Query("q"))
+	// A keyword search is ordered by how well listings match unless asked otherwise
+	defaultSort := "newest"
+	if len(terms) > 0 {
+		defaultSort = rankSortOption
 	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	sortOption := c.DefaultQuery("sort", defaultSort)
+	order, ok := listingSortOrders[sortOption]
+	if !ok && sortOption != rankSortOption {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort option"})
+		return
+	}
+	// Score components are for tuning the ranking weights, so only admins see them
+	explain := c.Query("explain") == "true"
+	if explain {
+		viewerID, _ := c.Get("user_id")
+		uid, _ := viewerID.(uint)
+		if uid == 0 || !isAdmin(h.DB, uid) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can explain the ranking"})
+			return
+		}
+	}
+	english, ok := listingLang(c)
+	if !ok {
+		return
 	}
-	offset := (page - 1) * limit
 
 	// Build query
-	query := h.DB.Model(&models.Listing{}).Where("status = ?", "活躍")
+	query := h.DB.Model(&models.Listing{}).Where("status = ? AND visibility = ?", models.ListingStatusActive, models.ListingVisibilityPublic)
 
-	if category != "" {
-		query = query.Where("category = ?", category)
+	// category, condition and industry accept several values each
+	query, filters, ok := h.applyAttributeFilters(c, query)
+	if !ok {
+		return
 	}
 	if location != "" {
 		query = query.Where("location LIKE ?", "%"+location+"%")
@@ -199,75 +458,60 @@ func (h *ListingsHandler) List(c *gin.Context) {
 	if maxPrice > 0 {
 		query = query.Where("price <= ?", maxPrice)
 	}
-	if condition != "" {
-		query = query.Where("condition = ?", condition)
+	if query, ok = applyRangeFilters(c, query, filters); !ok {
+		return
+	}
+	if c.Query("verified_financials") == "true" {
+		query = query.Where("financials_verified = ?", true)
+		filters["verified_financials"] = true
+	}
+	weights := h.rankWeights()
+	if len(terms) > 0 {
+		query = h.applySearch(query, terms, &weights)
+		filters["q"] = strings.Join(terms, " ")
 	}
 
-	// Get total count
-	var total int64
-	query.Count(&total)
-
-	// Get listings with pagination
-	var listings []models.Listing
-	if err := query.Preload("Images").
-		Preload("Owner").
-		Order("created_at desc").
-		Offset(offset).
-		Limit(limit).
-		Find(&listings).Error; err != nil {
+	if sortOption == rankSortOption {
+		query = query.Order(weights.orderBy())
+	} else {
+		query = query.Order(order)
+	}
+	page, err := h.searchListings(c, query, p)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listings"})
 		return
 	}
+	listings, total := page.Listings, page.Total
 
-	// Add price ranges to listings
 	listingsWithRanges := make([]gin.H, len(listings))
-	for i, listing := range listings {
-		low := int64(float64(listing.Price) * 0.85)
-		high := int64(float64(listing.Price) * 1.15)
-
-		listingsWithRanges[i] = gin.H{
-			"id":                  listing.ID,
-			"title":               listing.Title,
-			"description":         listing.Description,
-			"price":               listing.Price,
-			"category":            listing.Category,
-			"condition":           listing.Condition,
-			"location":            listing.Location,
-			"status":              listing.Status,
-			"owner_id":            listing.OwnerID,
-			"view_count":          listing.ViewCount,
-			"created_at":          listing.CreatedAt,
-			"updated_at":          listing.UpdatedAt,
-			"brand_story":         listing.BrandStory,
-			"rent":                listing.Rent,
-			"floor":               listing.Floor,
-			"equipment":           listing.Equipment,
-			"decoration":          listing.Decoration,
-			"annual_revenue":      listing.AnnualRevenue,
-			"gross_profit_rate":   listing.GrossProfitRate,
-			"fastest_moving_date": listing.FastestMovingDate,
-			"phone_number":        listing.PhoneNumber,
-			"square_meters":       listing.SquareMeters,
-			"industry":            listing.Industry,
-			"deposit":             listing.Deposit,
-			"owner":               listing.Owner,
-			"images":              listing.Images,
-			"price_range": gin.H{
-				"low":  low,
-				"high": high,
-			},
+	for i := range listings {
+		listingsWithRanges[i] = listingSearchEntry(&listings[i], h.Cfg.ContactRevealRequiresLead)
+		if english {
+			translateListingFields(listingsWithRanges[i], &listings[i])
 		}
 	}
+	if english {
+		h.queueTranslations(listings)
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"listings": listingsWithRanges,
-		"pagination": gin.H{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": (int(total) + limit - 1) / limit,
-		},
-	})
+	response := gin.H{
+		"listings":   listingsWithRanges,
+		"filters":    filters,
+		"pagination": pagination.NewMeta(p, total),
+	}
+	if explain {
+		ids := make([]uint, len(listings))
+		for i := range listings {
+			ids[i] = listings[i].ID
+		}
+		components, err := weights.explain(h.DB, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to explain ranking"})
+			return
+		}
+		response["ranking"] = gin.H{"weights": weights, "listings": components}
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *ListingsHandler) Update(c *gin.Context) {
@@ -290,9 +534,30 @@ func (h *ListingsHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Check if listing exists and user owns it
+	// Reject any invalid field before changing anything
+	errs := fieldErrors{}
+	errs.required("title", req.Title)
+	errs.between("price", req.Price, h.Cfg.ListingPriceMin, h.Cfg.ListingPriceMax)
+	if req.Status != nil && !req.Status.OwnerSettable() {
+		errs["status"] = "must be one of " + listingStatusList(PublicListingStatuses)
+	}
+	errs.oneOf("visibility", req.Visibility, models.ListingVisibilities)
+	errs.between("rent", req.Rent, 0, h.Cfg.ListingRentMax)
+	errs.between("deposit", req.Deposit, 0, h.Cfg.ListingDepositMax)
+	errs.between("annual_revenue", req.AnnualRevenue, 0, h.Cfg.ListingAnnualRevenueMax)
+	errs.fraction("gross_profit_rate", req.GrossProfitRate)
+	errs.phone("phone_number", req.PhoneNumber)
+	if !errs.check(c) {
+		return
+	}
+	if !screenContent(c, h.Words, "listing", map[string]*string{"title": req.Title, "description": req.Description}) {
+		return
+	}
+
+	// Check if listing exists and user owns it; pending deletions must be restored first
 	var listing models.Listing
-	if err := h.DB.Where("id = ? AND owner_id = ?", id, userID).First(&listing).Error; err != nil {
+	if err := h.DB.Where("id = ? AND owner_id = ? AND status NOT IN ?", id, userID, models.HiddenListingStatuses).
+		First(&listing).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
 		return
 	}
@@ -300,10 +565,15 @@ func (h *ListingsHandler) Update(c *gin.Context) {
 	// Update fields if provided
 	updates := make(map[string]interface{})
 	if req.Title != nil {
-		updates["title"] = *req.Title
+		updates["title"] = strings.TrimSpace(*req.Title)
 	}
 	if req.Description != nil {
-		updates["description"] = *req.Description
+		desc, descText, ok := checkDescription(c, *req.Description)
+		if !ok {
+			return
+		}
+		updates["description"] = desc
+		updates["description_text"] = descText
 	}
 	if req.Price != nil {
 		updates["price"] = *req.Price
@@ -318,20 +588,72 @@ func (h *ListingsHandler) Update(c *gin.Context) {
 		updates["location"] = *req.Location
 	}
 	if req.Status != nil {
+		// A sale is final; mark-sold is the only way into or out of it
+		if listing.Status == models.ListingStatusSold && *req.Status != listing.Status {
+			c.JSON(http.StatusConflict, gin.H{"error": "Sold listings cannot be reopened", "code": "LISTING_SOLD"})
+			return
+		}
+		if *req.Status == models.ListingStatusActive && listing.Status != models.ListingStatusActive && !h.checkPublishable(c, listing.ID) {
+			return
+		}
 		updates["status"] = *req.Status
+		// Reactivating an expired listing renews it, or the cleanup job would take it down again
+		if *req.Status == models.ListingStatusActive && listingExpired(&listing, time.Now()) {
+			updates["expires_at"] = h.renewedExpiry(time.Now())
+		}
+	}
+	if req.Visibility != nil {
+		updates["visibility"] = *req.Visibility
+	}
+	if req.Rent != nil {
+		updates["rent"] = *req.Rent
+	}
+	if req.Deposit != nil {
+		updates["deposit"] = *req.Deposit
+	}
+	if req.AnnualRevenue != nil {
+		updates["annual_revenue"] = *req.AnnualRevenue
 	}
+	if req.GrossProfitRate != nil {
+		updates["gross_profit_rate"] = *req.GrossProfitRate
+	}
+	if req.PhoneNumber != nil {
+		updates["phone_number"] = strings.TrimSpace(*req.PhoneNumber)
+	}
+	if req.HideViewCount != nil {
+		updates["hide_view_count"] = *req.HideViewCount
+	}
+	if req.HideFavoriteCount != nil {
+		updates["hide_favorite_count"] = *req.HideFavoriteCount
+	}
+	if req.HideLastActive != nil {
+		updates["hide_last_active"] = *req.HideLastActive
+	}
+	clearTranslations(updates, &listing)
+	unverified := clearFinancialsVerification(updates, &listing)
 
 	if err := h.DB.Model(&listing).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update listing"})
 		return
 	}
+	h.invalidateListing(listing.ID)
+	if unverified {
+		var owner models.User
+		if err := h.DB.First(&owner, listing.OwnerID).Error; err == nil {
+			_ = h.Emails.SendFinancialsVerificationCleared(&owner, &listing)
+		}
+	}
 
+	listing.ShowPrivateStats()
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Listing updated successfully",
 		"listing": listing,
 	})
 }
 
+// Delete starts the two-step deletion: the listing is hidden from public endpoints
+// immediately and finalized by the cleanup job once the undo window passes.
+// Admins can pass ?force=true to delete right away.
 func (h *ListingsHandler) Delete(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -346,22 +668,193 @@ func (h *ListingsHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	if c.Query("force") == "true" {
+		h.forceDelete(c, userID.(uint), uint(id))
+		return
+	}
+
 	// Check if listing exists and user owns it
 	var listing models.Listing
-	if err := h.DB.Where("id = ? AND owner_id = ?", id, userID).First(&listing).Error; err != nil {
+	if err := h.DB.Where("id = ? AND owner_id = ? AND status NOT IN ?", id, userID, models.HiddenListingStatuses).
+		First(&listing).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
 		return
 	}
 
-	// Soft delete by setting status to deleted
-	if err := h.DB.Model(&listing).Update("status", "deleted").Error; err != nil {
+	deleteAfter := time.Now().Add(models.ListingDeleteUndoWindow)
+	if err := h.DB.Model(&listing).Updates(map[string]interface{}{
+		"status":               models.ListingStatusPendingDelete,
+		"delete_after":         deleteAfter,
+		"status_before_delete": listing.Status,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete listing"})
+		return
+	}
+	h.invalidateListing(listing.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Listing scheduled for deletion",
+		"delete_after": deleteAfter,
+	})
+}
+
+// forceDelete finalizes a listing's deletion immediately. Admin only.
+func (h *ListingsHandler) forceDelete(c *gin.Context, userID, listingID uint) {
+	if !isAdmin(h.DB, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can force deletion"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("id = ? AND status <> ?", listingID, models.ListingStatusDeleted).
+		First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	if err := jobs.FinalizeListingDeletion(h.DB, h.Storage, &listing); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete listing"})
 		return
 	}
+	h.invalidateListing(listing.ID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Listing deleted successfully"})
 }
 
+// Restore cancels a pending deletion while the undo window is still open
+func (h *ListingsHandler) Restore(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("id = ? AND owner_id = ? AND status = ?", id, userID, models.ListingStatusPendingDelete).
+		First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or not pending deletion"})
+		return
+	}
+
+	if listing.DeleteAfter == nil || !time.Now().Before(*listing.DeleteAfter) {
+		c.JSON(http.StatusGone, gin.H{"error": "Undo window has expired"})
+		return
+	}
+
+	// Go back to the status the listing had when it was deleted; listings
+	// deleted before that was recorded count as active. An active listing only
+	// goes live again if it still meets the publishing requirements.
+	status := models.ListingStatusActive
+	if listing.StatusBeforeDelete != nil {
+		status = *listing.StatusBeforeDelete
+	}
+	if status == models.ListingStatusActive {
+		var imageCount int64
+		if err := h.DB.Model(&models.Image{}).Where("listing_id = ?", listing.ID).Count(&imageCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listing images"})
+			return
+		}
+		if imageCount < int64(h.minImages()) {
+			status = models.ListingStatusInactive
+		}
+	}
+	updates := map[string]interface{}{
+		"status":               status,
+		"delete_after":         nil,
+		"status_before_delete": nil,
+	}
+	if status == models.ListingStatusActive && listingExpired(&listing, time.Now()) {
+		updates["expires_at"] = h.renewedExpiry(time.Now())
+	}
+
+	if err := h.DB.Model(&listing).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore listing"})
+		return
+	}
+	h.invalidateListing(listing.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Listing restored",
+		"status":  status,
+	})
+}
+
+// Mine lists the current user's listings, including ones pending deletion so
+// they can be restored
+func (h *ListingsHandler) Mine(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	p := pagination.Parse(c, pagination.Limits{Default: h.Cfg.ListingsDefaultPageSize, Max: h.Cfg.ListingsMaxPageSize})
+
+	query := h.DB.Model(&models.Listing{}).
+		Where("owner_id = ? AND status <> ?", userID, models.ListingStatusDeleted)
+
+	filters := gin.H{}
+	query, ok := applyOwnerStatusFilter(c, query, filters)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listings"})
+		return
+	}
+
+	var listings []models.Listing
+	if err := query.Preload("Images").
+		Order("created_at DESC").
+		Scopes(p.Scope()).
+		Find(&listings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listings"})
+		return
+	}
+
+	rules := h.warningRules()
+	result := make([]gin.H, 0, len(listings))
+	for _, listing := range listings {
+		result = append(result, gin.H{
+			"id":                  listing.ID,
+			"title":               listing.Title,
+			"price":               listing.Price,
+			"category":            listing.Category,
+			"location":            listing.Location,
+			"status":              listing.Status,
+			"visibility":          listing.Visibility,
+			"quality":             models.ScoreListing(&listing, len(listing.Images)),
+			"warnings":            models.ListingWarnings(&listing, len(listing.Images), rules),
+			"delete_after":        listing.DeleteAfter,
+			"expires_at":          listing.ExpiresAt,
+			"can_restore":         listing.Status == models.ListingStatusPendingDelete,
+			"view_count":          listing.ViewCount,
+			"favorite_count":      listing.FavoriteCount,
+			"hide_view_count":     listing.HideViewCount,
+			"hide_favorite_count": listing.HideFavoriteCount,
+			"hide_last_active":    listing.HideLastActive,
+			"created_at":          listing.CreatedAt,
+			"updated_at":          listing.UpdatedAt,
+			"images":              listing.Images,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"listings":   result,
+		"filters":    filters,
+		"pagination": pagination.NewMeta(p, total),
+	})
+}
+
 func (h *ListingsHandler) UploadImages(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -396,19 +889,29 @@ func (h *ListingsHandler) UploadImages(c *gin.Context) {
 		return
 	}
 
-	var uploadedImages []models.Image
-	for i, file := range files {
-		// Validate file type
-		if !strings.HasPrefix(file.Header.Get("Content-Type"), "image/") {
-			continue
+	// Every file is checked before anything is saved, so a rejected upload leaves
+	// the listing unchanged
+	policy := imagePolicy(h.Cfg)
+	for _, file := range files {
+		src, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload", "file": file.Filename})
+			return
+		}
+		_, err = policy.Check(src)
+		src.Close()
+		if err != nil {
+			rejectImage(c, file.Filename, err)
+			return
 		}
+	}
 
-		// Generate filename
-		filename := fmt.Sprintf("listing_%d_%d_%s", listing.ID, i, file.Filename)
-		filepath := fmt.Sprintf("./uploads/%s", filename)
+	var uploadedImages []models.Image
+	for i, file := range files {
 
-		// Save file
-		if err := c.SaveUploadedFile(file, filepath); err != nil {
+		// Save under a content-hashed name so the public URL can be cached as immutable
+		filename, url, err := h.Storage.SavePublic(file, fmt.Sprintf("listing_%d", listing.ID))
+		if err != nil {
 			continue
 		}
 
@@ -416,7 +919,7 @@ func (h *ListingsHandler) UploadImages(c *gin.Context) {
 		image := models.Image{
 			ListingID: listing.ID,
 			Filename:  filename,
-			URL:       fmt.Sprintf("/uploads/%s", filename),
+			URL:       url,
 			Order:     i,
 			IsPrimary: i == 0, // First image is primary
 		}
@@ -427,6 +930,9 @@ func (h *ListingsHandler) UploadImages(c *gin.Context) {
 
 		uploadedImages = append(uploadedImages, image)
 	}
+	if len(uploadedImages) > 0 {
+		h.invalidateListing(listing.ID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": fmt.Sprintf("Uploaded %d images successfully", len(uploadedImages)),
@@ -434,18 +940,119 @@ func (h *ListingsHandler) UploadImages(c *gin.Context) {
 	})
 }
 
-func (h *ListingsHandler) GetCategories(c *gin.Context) {
-	if !h.checkDB(c) {
+// Preview shows the owner their listing the way buyers see it: the entry from
+// the GET /listings search results and the card used in recommendations and
+// favorites, built from the same approved images, plus its quality score and
+// warnings. searchable says whether buyers can find it at all right now.
+func (h *ListingsHandler) Preview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	var categories []string
-	h.DB.Model(&models.Listing{}).
-		Where("status = ?", "active").
-		Distinct().
-		Pluck("category", &categories)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("id = ? AND owner_id = ?", id, userID).
+		Preload("Images", "moderation_status = ?", models.ImageModerationApproved).
+		Preload("Owner").
+		First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	}
+
+	// Quality counts every image, as Mine does; pending ones will show once
+	// approved
+	var imageCount int64
+	if err := h.DB.Model(&models.Image{}).Where("listing_id = ?", listing.ID).Count(&imageCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listing images"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"categories": categories,
+		"search_result": listingSearchEntry(&listing, h.Cfg.ContactRevealRequiresLead),
+		"card":          listingSummary(&listing),
+		"searchable":    listing.Status == models.ListingStatusActive && listing.IsPublic(),
+		"quality":       models.ScoreListing(&listing, int(imageCount)),
+		"warnings":      models.ListingWarnings(&listing, int(imageCount), h.warningRules()),
 	})
 }
+
+// GetCategories returns the categories and industries that have active listings,
+// with their counts, read from the materialized listing_counts table
+func (h *ListingsHandler) GetCategories(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.RedisClient != nil {
+		if data, err := h.RedisClient.Get(ctx, listingOptionsKey).Bytes(); err == nil {
+			respondPublicWithETag(c, data, listingOptionsMaxAge)
+			return
+		}
+	}
+
+	var counts []models.ListingCount
+	if err := h.DB.Where("total > 0").Order("total desc, value").Find(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	categories := []string{}
+	categoryCounts := []gin.H{}
+	industryCounts := []gin.H{}
+	for _, count := range counts {
+		option := gin.H{"value": count.Value, "count": count.Total}
+		switch count.Dimension {
+		case models.ListingCountCategory:
+			categories = append(categories, count.Value)
+			categoryCounts = append(categoryCounts, option)
+		case models.ListingCountIndustry:
+			industryCounts = append(industryCounts, option)
+		}
+	}
+
+	data, err := json.Marshal(gin.H{
+		"categories":      categories,
+		"category_counts": categoryCounts,
+		"industry_counts": industryCounts,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+	if h.RedisClient != nil {
+		_ = h.RedisClient.Set(ctx, listingOptionsKey, data, listingOptionsTTL).Err()
+	}
+	respondPublicWithETag(c, data, listingOptionsMaxAge)
+}
+
+// GetIndustries returns the industries that have active listings, with their
+// counts, for the search filter dropdown
+func (h *ListingsHandler) GetIndustries(c *gin.Context) {
+	var counts []models.ListingCount
+	if err := h.DB.Where("dimension = ? AND total > 0", models.ListingCountIndustry).
+		Order("value").
+		Find(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch industries"})
+		return
+	}
+
+	industries := make([]string, len(counts))
+	industryCounts := make([]gin.H, len(counts))
+	for i, count := range counts {
+		industries[i] = count.Value
+		industryCounts[i] = gin.H{"value": count.Value, "count": count.Total}
+	}
+	data, err := json.Marshal(gin.H{
+		"industries":      industries,
+		"industry_counts": industryCounts,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch industries"})
+		return
+	}
+	respondPublicWithETag(c, data, listingOptionsMaxAge)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"strings"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLocale picks the ?locale= param, else the first Accept-Language tag
+func requestLocale(c *gin.Context) string {
+	if locale := c.Query("locale"); locale != "" {
+		return locale
+	}
+	header := c.GetHeader("Accept-Language")
+	if header == "" {
+		return models.DefaultLocale
+	}
+	tag := strings.TrimSpace(strings.SplitN(strings.SplitN(header, ",", 2)[0], ";", 2)[0])
+	if tag == "" || tag == "*" {
+		return models.DefaultLocale
+	}
+	return tag
+}
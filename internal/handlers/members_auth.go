@@ -1,19 +1,22 @@
 package handlers
 
 import (
-	"context"
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"trade_company/internal/auth"
+	"trade_company/internal/botcheck"
 	"trade_company/internal/config"
+	"trade_company/internal/jobs"
+	"trade_company/internal/logger"
 	"trade_company/internal/middleware"
 	"trade_company/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/crypto/bcrypt"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -23,6 +26,9 @@ type MembersAuthHandler struct {
 	Config         *config.Config
 	SessionManager *auth.SessionManager
 	EmailService   *auth.EmailService
+	Lockout        *auth.LockoutTracker
+	Challenge      *auth.LoginChallenge
+	Bots           *botcheck.Checker
 }
 
 func NewMembersAuthHandler(db *gorm.DB, redisClient *redis.Client, config *config.Config) *MembersAuthHandler {
@@ -35,30 +41,38 @@ func NewMembersAuthHandler(db *gorm.DB, redisClient *redis.Client, config *confi
 		Config:         config,
 		SessionManager: sessionManager,
 		EmailService:   emailService,
+		Lockout:        auth.NewLockoutTracker(redisClient, config),
+		Challenge:      auth.NewLoginChallenge(redisClient, config, auth.NewTurnstileVerifier(config.TurnstileSecretKey)),
+		Bots:           botcheck.New(config, redisClient),
 	}
 }
 
 type signupRequest struct {
-	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Phone     string `json:"phone"`
-	Role      string `json:"role"`
+	Email     string      `json:"email" binding:"required,email"`
+	Password  string      `json:"password" binding:"required,min=8"`
+	FirstName string      `json:"first_name" binding:"required"`
+	LastName  string      `json:"last_name" binding:"required"`
+	Phone     string      `json:"phone"`
+	Role      models.Role `json:"role"`
 
 	// Seller-specific fields
 	CompanyName  string `json:"company_name"`
 	TaxID        string `json:"tax_id"`
 	ContactPhone string `json:"contact_phone"`
 
-	// Anti-bot fields
-	Honeypot string `json:"website"`   // Hidden field to catch bots
-	FormTime int64  `json:"form_time"` // Time when form was rendered
+	// Terms of service checkbox and the version the form showed
+	AcceptTerms  bool   `json:"accept_terms"`
+	TermsVersion string `json:"terms_version"`
+
+	// Anti-bot fields, besides the configured honeypots
+	FormToken string `json:"form_token"` // Signed render time from GET /forms/token
+	FormTime  int64  `json:"form_time"`  // Time when form was rendered, for forms without a token
 }
 
 type membersLoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email          string `json:"email" binding:"required,email"`
+	Password       string `json:"password" binding:"required"`
+	ChallengeToken string `json:"challenge_token"`
 }
 
 type verifyEmailRequest struct {
@@ -77,35 +91,51 @@ type resetPasswordRequest struct {
 // Signup handles user registration
 func (h *MembersAuthHandler) Signup(c *gin.Context) {
 	var req signupRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	honeypot, err := h.Bots.BindJSON(c, &req)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Anti-bot checks
-	if req.Honeypot != "" {
+	// Anti-bot checks. Accounts have nowhere to hold a flag, so flagged
+	// signups go through and are logged for review.
+	bot := h.Bots.Evaluate(c, botcheck.EndpointSignup, honeypot, botcheck.Form{Token: req.FormToken, Time: req.FormTime})
+	if bot.Verdict == botcheck.VerdictReject {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
+	if bot.Verdict == botcheck.VerdictFlag {
+		logger.FromContext(c).Warn("MembersAuthHandler: Signup flagged as a possible bot",
+			zap.String("email", req.Email),
+			zap.Int("bot_score", bot.Score),
+			zap.Any("signals", bot.Signals))
+	}
 
-	// Check if form was submitted too quickly (less than 800ms)
-	if req.FormTime > 0 {
-		elapsed := time.Now().UnixMilli() - req.FormTime
-		if elapsed < 800 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
-			return
-		}
+	if !checkTermsAccepted(c, h.Config, req.AcceptTerms, req.TermsVersion) {
+		return
 	}
 
-	// Check if email already exists
+	// Check if email already exists. An expired unverified account doesn't hold
+	// the address hostage; it is replaced by the new registration.
 	var existingUser models.User
 	if err := h.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
-		return
+		stale, err := jobs.StaleUnverifiedUser(h.DB, req.Email, h.Config.UnverifiedAccountTTL(), time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			return
+		}
+		if stale == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+		if err := jobs.RemoveUnverifiedUser(h.DB, stale, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			return
+		}
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := auth.HashPassword(req.Password, h.Config.BcryptCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
 		return
@@ -117,7 +147,7 @@ func (h *MembersAuthHandler) Signup(c *gin.Context) {
 	// Create user
 	user := models.User{
 		Email:                  req.Email,
-		PasswordHash:           string(hashedPassword),
+		PasswordHash:           hashedPassword,
 		FirstName:              req.FirstName,
 		LastName:               req.LastName,
 		Phone:                  req.Phone,
@@ -131,7 +161,12 @@ func (h *MembersAuthHandler) Signup(c *gin.Context) {
 		MarketingEmails:        false,
 	}
 
-	if err := h.DB.Create(&user).Error; err != nil {
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordTermsAcceptance(tx, user.ID, req.TermsVersion, c.ClientIP(), time.Now())
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
@@ -159,10 +194,24 @@ func (h *MembersAuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Repeated failures require a solved challenge before anything else is checked
+	if h.Challenge.Required(c, req.Email, c.ClientIP()) {
+		passed, err := h.Challenge.Verify(c, req.ChallengeToken, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Challenge verification unavailable", "challenge_required": true})
+			return
+		}
+		if !passed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Challenge required", "challenge_required": true})
+			return
+		}
+	}
+
 	// Find user
 	var user models.User
 	if err := h.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		challenge := h.Challenge.RecordFailure(c, req.Email, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials", "challenge_required": challenge})
 		return
 	}
 
@@ -172,21 +221,83 @@ func (h *MembersAuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Check if account is locked before looking at the password
+	if lockedFor := h.Lockout.LockedFor(c, req.Email); lockedFor > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(lockedFor.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Account temporarily locked due to too many failed attempts"})
+		return
+	}
+
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		h.recordFailedLogin(c, req.Email)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if err := auth.CheckPassword(user.PasswordHash, req.Password); err != nil {
+		h.Lockout.RecordFailure(c, req.Email)
+		challenge := h.Challenge.RecordFailure(c, req.Email, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials", "challenge_required": challenge})
+		return
+	}
+	h.Lockout.Reset(c, req.Email)
+	h.Challenge.Reset(c, req.Email)
+
+	// Check if 2FA is required; the session only comes with the code
+	if user.TwoFactorEnabled {
+		token, expiresAt := auth.TwoFactorLoginToken(h.Config, user.ID)
+		twoFactorRequired(c, token, expiresAt)
+		return
+	}
+
+	h.completeLogin(c, &user)
+}
+
+// TwoFactorLogin completes a login that returned requires_2fa, creating the
+// session once the code from the user's authenticator app checks out
+func (h *MembersAuthHandler) TwoFactorLogin(c *gin.Context) {
+	var req twoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	uid, err := auth.ParseTwoFactorLoginToken(h.Config, req.LoginToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please log in again", "code": "TWO_FACTOR_LOGIN_EXPIRED"})
+		return
+	}
+	var user models.User
+	if err := h.DB.First(&user, uid).Error; err != nil || !user.TwoFactorEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please log in again", "code": "TWO_FACTOR_LOGIN_EXPIRED"})
 		return
 	}
 
-	// Check if account is locked
-	if h.isAccountLocked(req.Email) {
+	// Wrong codes lock the account like wrong passwords
+	if lockedFor := h.Lockout.LockedFor(c, user.Email); lockedFor > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(lockedFor.Seconds())))
 		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Account temporarily locked due to too many failed attempts"})
 		return
 	}
+	ok, err := consumeTwoFactorCode(h.DB, &user, req.Code, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		h.Lockout.RecordFailure(c, user.Email)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or already used code", "code": "INVALID_TWO_FACTOR_CODE"})
+		return
+	}
+	h.Lockout.Reset(c, user.Email)
+
+	h.completeLogin(c, &user)
+}
 
+// completeLogin creates the session of a user who passed every login step
+// and sets its cookie
+func (h *MembersAuthHandler) completeLogin(c *gin.Context, user *models.User) {
 	// Create session
 	session, err := h.SessionManager.CreateSession(user.ID, c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, auth.ErrTooManySessions) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many active sessions. Log out on another device and try again."})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
@@ -195,22 +306,12 @@ func (h *MembersAuthHandler) Login(c *gin.Context) {
 	// Set session cookie
 	h.setSessionCookie(c, session.SessionID)
 
-	// Update last login time
-	h.DB.Model(&user).Update("last_login_at", time.Now())
+	// Update last login time; sellers back after a long absence confirm their listings
+	_, _ = recordLogin(h.DB, h.Config, user, time.Now())
 
 	// Log successful login
 	h.recordSuccessfulLogin(c, user.ID)
 
-	// Check if 2FA is required
-	if user.TwoFactorEnabled {
-		c.JSON(http.StatusOK, gin.H{
-			"message":      "2FA required",
-			"requires_2fa": true,
-			"session_id":   session.SessionID,
-		})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
 		"user": gin.H{
@@ -328,7 +429,7 @@ func (h *MembersAuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := auth.HashPassword(req.Password, h.Config.BcryptCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
 		return
@@ -336,7 +437,7 @@ func (h *MembersAuthHandler) ResetPassword(c *gin.Context) {
 
 	// Update user password
 	if err := h.DB.Model(&models.User{}).Where("id = ?", resetToken.UserID).
-		Update("password_hash", string(hashedPassword)).Error; err != nil {
+		Update("password_hash", hashedPassword).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
@@ -371,12 +472,47 @@ func (h *MembersAuthHandler) Logout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
+// Sessions lists the user's active sessions, oldest first, marking the one
+// making the request as current
+func (h *MembersAuthHandler) Sessions(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	currentID, _ := middleware.GetSessionID(c)
+
+	sessions, err := h.SessionManager.GetUserSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	// Session IDs are bearer credentials, so only the row ID is returned
+	result := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, gin.H{
+			"id":         s.ID,
+			"ip_address": s.IPAddress,
+			"user_agent": s.UserAgent,
+			"created_at": s.CreatedAt,
+			"expires_at": s.ExpiresAt,
+			"current":    s.SessionID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":     result,
+		"max_sessions": h.Config.SessionMaxPerUser,
+	})
+}
+
 // Helper methods
-func (h *MembersAuthHandler) getDefaultRole(requestedRole string) string {
-	if requestedRole == "seller" || requestedRole == "admin" {
-		return "user" // Default to user role, admin can promote later
+func (h *MembersAuthHandler) getDefaultRole(requestedRole models.Role) models.Role {
+	if requestedRole == models.RoleSeller || requestedRole == models.RoleAdmin {
+		return models.RoleUser // Default to user role, admin can promote later
 	}
-	return "user"
+	return models.RoleUser
 }
 
 func (h *MembersAuthHandler) setSessionCookie(c *gin.Context, sessionID string) {
@@ -390,28 +526,11 @@ func (h *MembersAuthHandler) clearSessionCookie(c *gin.Context) {
 	c.SetCookie("sid", "", -1, "/", "", false, true)
 }
 
-func (h *MembersAuthHandler) recordFailedLogin(c *gin.Context, email string) {
-	// Record failed login attempt in Redis
-	key := fmt.Sprintf("failed_login:%s", email)
-	h.RedisClient.Incr(c, key)
-	h.RedisClient.Expire(c, key, time.Duration(h.Config.LockoutDurationMinutes)*time.Minute)
-}
-
 func (h *MembersAuthHandler) recordSuccessfulLogin(c *gin.Context, userID uint) {
 	// Clear failed login attempts
 	// This would be implemented based on your audit logging requirements
 }
 
-func (h *MembersAuthHandler) isAccountLocked(email string) bool {
-	key := fmt.Sprintf("failed_login:%s", email)
-	ctx := context.Background()
-	count, err := h.RedisClient.Get(ctx, key).Int()
-	if err != nil {
-		return false
-	}
-	return count >= h.Config.MaxLoginAttempts
-}
-
 func (h *MembersAuthHandler) revokeAllUserSessions(userID uint) {
 	// Get all user sessions and revoke them
 	sessions, err := h.SessionManager.GetUserSessions(userID)
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestSignupReclaimsStaleUnverifiedEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup adjusts the account already holding the email
+		setup  func(t *testing.T, db *gorm.DB, existing *models.User)
+		status int
+	}{
+		{name: "stale unverified account is replaced", status: http.StatusCreated},
+		{name: "unverified account still within its TTL", status: http.StatusConflict,
+			setup: func(t *testing.T, db *gorm.DB, existing *models.User) {
+				db.Model(existing).Update("created_at", time.Now().Add(-24*time.Hour))
+			}},
+		{name: "verified account", status: http.StatusConflict,
+			setup: func(t *testing.T, db *gorm.DB, existing *models.User) {
+				db.Model(existing).Updates(map[string]interface{}{"is_active": true, "email_verified_at": time.Now()})
+			}},
+		{name: "stale account that owns a listing", status: http.StatusConflict,
+			setup: func(t *testing.T, db *gorm.DB, existing *models.User) {
+				createTestListing(t, db, existing.ID)
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.Transaction{}, &models.UserSession{}, &models.PasswordResetToken{}, &models.TermsAcceptance{})
+			cfg := testConfig(t)
+			existing := createTestUser(t, db, "taken")
+			db.Model(existing).Updates(map[string]interface{}{
+				"is_active": false, "email_verified_at": nil, "created_at": time.Now().Add(-cfg.UnverifiedAccountTTL() - time.Hour),
+			})
+			if tt.setup != nil {
+				tt.setup(t, db, existing)
+			}
+
+			h := NewMembersAuthHandler(db, nil, cfg)
+			// The verification email fails quietly instead of being logged
+			emailCfg := *cfg
+			emailCfg.AppEnv = "test"
+			h.EmailService = auth.NewEmailService(&emailCfg)
+			r := gin.New()
+			r.POST("/auth/signup", h.Signup)
+
+			w := serve(r, http.MethodPost, "/auth/signup", map[string]interface{}{
+				"email": existing.Email, "password": "correct horse", "first_name": "Mei", "last_name": "Lin",
+				"accept_terms": true, "terms_version": cfg.TermsVersion,
+				"form_time": time.Now().Add(-time.Minute).UnixMilli(),
+			})
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			var users []models.User
+			db.Where("email = ?", existing.Email).Find(&users)
+			if len(users) != 1 {
+				t.Fatalf("%d users with the email, want 1", len(users))
+			}
+			if replaced := users[0].ID != existing.ID; replaced != (tt.status == http.StatusCreated) {
+				t.Errorf("user %d holds the email, existing was %d", users[0].ID, existing.ID)
+			}
+			if tt.status == http.StatusCreated && users[0].FirstName != "Mei" {
+				t.Errorf("new user %+v", users[0])
+			}
+		})
+	}
+}
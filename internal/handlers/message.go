@@ -1,19 +1,30 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"trade_company/internal/config"
 	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/wordfilter"
 )
 
 type MessageHandler struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Cfg   *config.Config
+	Words *wordfilter.Filter // Sensitive word filter; nil when off
 }
 
-// List returns the current user's messages
+// List returns the current user's messages, newest first. Optional filters:
+// listing_id (a listing the user owns or has messaged about), unread=true
+// (received and not yet read), direction=sent|received and since (RFC3339).
+// With a cursor param, empty for the first page, it pages by keyset instead of
+// page/limit.
 func (h *MessageHandler) List(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -21,22 +32,154 @@ func (h *MessageHandler) List(c *gin.Context) {
 		return
 	}
 
+	query, ok := h.filterMessages(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if _, keyset := c.GetQuery("cursor"); keyset {
+		h.listByCursor(c, userID.(uint), query)
+		return
+	}
+
+	p := pagination.Parse(c, pagination.Limits{Default: h.Cfg.MessagesDefaultPageSize, Max: h.Cfg.MessagesMaxPageSize})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+
 	var messages []models.Message
-	if err := h.DB.Where("sender_id = ? OR receiver_id = ?", userID, userID).
-		Preload("Sender").
-		Preload("Receiver").
-		Preload("Listing").
+	if err := preloadMessage(query).
 		Order("created_at desc").
+		Scopes(p.Scope()).
 		Find(&messages).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"messages": messages,
+		"messages":   messageItems(messages, userID.(uint)),
+		"pagination": pagination.NewMeta(p, total),
 	})
 }
 
+// listByCursor writes a keyset page of the filtered messages
+func (h *MessageHandler) listByCursor(c *gin.Context, userID uint, query *gorm.DB) {
+	p, err := pagination.ParseCursor(c, pagination.Limits{Default: h.Cfg.MessagesDefaultPageSize, Max: h.Cfg.MessagesMaxPageSize})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor", "code": "INVALID_CURSOR"})
+		return
+	}
+
+	var messages []models.Message
+	if err := preloadMessage(query).Scopes(p.Scope("id")).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+	ids := make([]uint, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+	meta, n := pagination.NewCursorMeta(p, ids)
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages":   messageItems(messages[:n], userID),
+		"pagination": meta,
+	})
+}
+
+// filterMessages builds the List query from its filter params, or writes a 400
+// (or 404 for a listing the user has nothing to do with) and returns false.
+// Each filter narrows the sender_id/receiver_id/listing_id/is_read/created_at
+// columns the messages indexes cover.
+func (h *MessageHandler) filterMessages(c *gin.Context, userID uint) (*gorm.DB, bool) {
+	query := h.DB.Model(&models.Message{})
+
+	switch direction := c.Query("direction"); direction {
+	case "":
+		query = query.Where("sender_id = ? OR receiver_id = ?", userID, userID)
+	case "sent":
+		query = query.Where("sender_id = ?", userID)
+	case "received":
+		query = query.Where("receiver_id = ?", userID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be sent or received", "code": "INVALID_DIRECTION"})
+		return nil, false
+	}
+
+	// Only received messages can be unread by the user
+	if c.Query("unread") == "true" {
+		query = query.Where("receiver_id = ? AND is_read = ?", userID, false)
+	}
+
+	if v := c.Query("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, expected an RFC3339 time", "code": "INVALID_SINCE"})
+			return nil, false
+		}
+		query = query.Where("created_at >= ?", since)
+	}
+
+	if v := c.Query("listing_id"); v != "" {
+		listingID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+			return nil, false
+		}
+		participant, err := h.listingParticipant(userID, uint(listingID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+			return nil, false
+		}
+		if !participant {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+			return nil, false
+		}
+		query = query.Where("listing_id = ?", listingID)
+	}
+
+	return query, true
+}
+
+// listingParticipant reports whether userID owns the listing or has sent or
+// received a message about it
+func (h *MessageHandler) listingParticipant(userID, listingID uint) (bool, error) {
+	var owned int64
+	if err := h.DB.Model(&models.Listing{}).
+		Where("id = ? AND owner_id = ?", listingID, userID).
+		Count(&owned).Error; err != nil {
+		return false, err
+	}
+	if owned > 0 {
+		return true, nil
+	}
+	var messages int64
+	err := h.DB.Model(&models.Message{}).
+		Where("listing_id = ? AND (sender_id = ? OR receiver_id = ?)", listingID, userID, userID).
+		Count(&messages).Error
+	return messages > 0, err
+}
+
+// preloadMessage loads the relations messageItem shows
+func preloadMessage(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Sender").
+		Preload("Receiver").
+		Preload("Listing").
+		Preload("Listing.Images", "is_primary = ? AND moderation_status = ?", true, models.ImageModerationApproved)
+}
+
+func messageItems(messages []models.Message, viewerID uint) []gin.H {
+	result := make([]gin.H, 0, len(messages))
+	for i := range messages {
+		result = append(result, messageItem(&messages[i], viewerID))
+	}
+	return result
+}
+
 // Get returns a specific message
 func (h *MessageHandler) Get(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -53,20 +196,48 @@ func (h *MessageHandler) Get(c *gin.Context) {
 	}
 
 	var message models.Message
-	if err := h.DB.Where("id = ? AND (sender_id = ? OR receiver_id = ?)", messageID, userID, userID).
-		Preload("Sender").
-		Preload("Receiver").
-		Preload("Listing").
+	if err := preloadMessage(h.DB.Where("id = ? AND (sender_id = ? OR receiver_id = ?)", messageID, userID, userID)).
 		First(&message).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": message,
+		"message": messageItem(&message, userID.(uint)),
 	})
 }
 
+// messageItem is the response shape of a message with its users and listing
+// preloaded. A listing that is no longer available to the viewer is shown as a
+// tombstone so the thread stays readable.
+func messageItem(m *models.Message, viewerID uint) gin.H {
+	item := gin.H{
+		"id":          m.ID,
+		"sender_id":   m.SenderID,
+		"receiver_id": m.ReceiverID,
+		"listing_id":  m.ListingID,
+		"subject":     m.Subject,
+		"content":     m.Content,
+		"is_read":     m.IsRead,
+		"read_at":     m.ReadAt,
+		"created_at":  m.CreatedAt,
+		"updated_at":  m.UpdatedAt,
+		"sender":      m.Sender,
+		"receiver":    m.Receiver,
+		"listing":     nil,
+	}
+	switch {
+	case m.Listing == nil:
+	case m.Listing.AvailableTo(viewerID):
+		summary := listingSummary(m.Listing)
+		summary["unavailable"] = false
+		item["listing"] = summary
+	default:
+		item["listing"] = listingTombstone(m.Listing)
+	}
+	return item
+}
+
 // Create creates a new message
 func (h *MessageHandler) Create(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -91,6 +262,9 @@ func (h *MessageHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Message content is required"})
 		return
 	}
+	if !screenContent(c, h.Words, "message", map[string]*string{"subject": &input.Subject, "content": &input.Content}) {
+		return
+	}
 
 	// Check if receiver exists
 	var receiver models.User
@@ -99,10 +273,13 @@ func (h *MessageHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Check if listing exists (if provided)
+	// Check if listing exists and still takes inquiries (if provided)
 	if input.ListingID != nil {
-		var listing models.Listing
-		if err := h.DB.First(&listing, *input.ListingID).Error; err != nil {
+		if _, err := models.InquiryListing(h.DB, *input.ListingID, userID.(uint)); err != nil {
+			if errors.Is(err, models.ErrListingUnavailable) {
+				respondListingUnavailable(c)
+				return
+			}
 			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
 			return
 		}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMessageListFilters(t *testing.T) {
+	db := newTestDB(t)
+	h := &MessageHandler{DB: db, Cfg: testConfig(t)}
+	me := createTestUser(t, db, "me")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	cafe := createTestListing(t, db, alice.ID)
+	bakery := createTestListing(t, db, bob.ID)
+	mine := createTestListing(t, db, me.ID)
+	others := createTestListing(t, db, bob.ID)
+
+	// Created oldest first, so IDs follow created_at
+	now := time.Now().UTC().Truncate(time.Second)
+	ids := map[string]uint{}
+	for _, m := range []struct {
+		name      string
+		from, to  uint
+		listingID uint
+		read      bool
+		age       time.Duration
+	}{
+		{"cafe question", alice.ID, me.ID, cafe.ID, true, 3 * time.Hour},
+		{"cafe reply", me.ID, alice.ID, cafe.ID, false, 2 * time.Hour},
+		{"cafe follow-up", alice.ID, me.ID, cafe.ID, false, time.Hour},
+		{"bakery offer", bob.ID, me.ID, bakery.ID, false, 30 * time.Minute},
+		{"bakery reply", me.ID, bob.ID, bakery.ID, false, 10 * time.Minute},
+		{"not mine", alice.ID, bob.ID, others.ID, false, 5 * time.Minute},
+	} {
+		listingID := m.listingID
+		msg := models.Message{SenderID: m.from, ReceiverID: m.to, ListingID: &listingID, Content: m.name, IsRead: m.read, CreatedAt: now.Add(-m.age)}
+		if err := db.Create(&msg).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[m.name] = msg.ID
+	}
+
+	r := gin.New()
+	r.GET("/messages", asUser(me.ID), h.List)
+	since := url.QueryEscape(now.Add(-90 * time.Minute).Format(time.RFC3339))
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string // newest first
+	}{
+		{"no filters", "", []string{"bakery reply", "bakery offer", "cafe follow-up", "cafe reply", "cafe question"}},
+		{"listing", fmt.Sprintf("listing_id=%d", cafe.ID), []string{"cafe follow-up", "cafe reply", "cafe question"}},
+		{"own listing without messages", fmt.Sprintf("listing_id=%d", mine.ID), nil},
+		{"unread", "unread=true", []string{"bakery offer", "cafe follow-up"}},
+		{"unread=false is no filter", "unread=false", []string{"bakery reply", "bakery offer", "cafe follow-up", "cafe reply", "cafe question"}},
+		{"sent", "direction=sent", []string{"bakery reply", "cafe reply"}},
+		{"received", "direction=received", []string{"bakery offer", "cafe follow-up", "cafe question"}},
+		{"since", "since=" + since, []string{"bakery reply", "bakery offer", "cafe follow-up"}},
+		{"listing and unread", fmt.Sprintf("listing_id=%d&unread=true", cafe.ID), []string{"cafe follow-up"}},
+		{"listing and sent", fmt.Sprintf("listing_id=%d&direction=sent", bakery.ID), []string{"bakery reply"}},
+		{"sent and unread", "direction=sent&unread=true", nil},
+		{"listing, received and since", fmt.Sprintf("listing_id=%d&direction=received&since=%s", cafe.ID, since), []string{"cafe follow-up"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/messages?"+tt.query, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			body := decode(t, w)
+			var want []uint
+			for _, name := range tt.want {
+				want = append(want, ids[name])
+			}
+			if got := messageIDs(body); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("messages %v, want %v (%q)", got, want, tt.want)
+			}
+			if total := body["pagination"].(map[string]interface{})["total"]; total != float64(len(want)) {
+				t.Errorf("total %v, want %d", total, len(want))
+			}
+		})
+	}
+
+	t.Run("keyset pages keep the filters", func(t *testing.T) {
+		var got []uint
+		target := fmt.Sprintf("/messages?listing_id=%d&limit=2&cursor=", cafe.ID)
+		for pages := 0; ; pages++ {
+			if pages == 3 {
+				t.Fatal("too many pages")
+			}
+			w := serve(r, http.MethodGet, target, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			body := decode(t, w)
+			got = append(got, messageIDs(body)...)
+			meta := body["pagination"].(map[string]interface{})
+			if meta["has_more"] != true {
+				break
+			}
+			target = fmt.Sprintf("/messages?listing_id=%d&limit=2&cursor=%s", cafe.ID, meta["next_cursor"])
+		}
+		want := []uint{ids["cafe follow-up"], ids["cafe reply"], ids["cafe question"]}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("messages %v, want %v", got, want)
+		}
+	})
+
+	invalid := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"listing the user has no part in", fmt.Sprintf("listing_id=%d", others.ID), http.StatusNotFound},
+		{"missing listing", "listing_id=9999", http.StatusNotFound},
+		{"malformed listing", "listing_id=cafe", http.StatusBadRequest},
+		{"unknown direction", "direction=all", http.StatusBadRequest},
+		{"malformed since", "since=yesterday", http.StatusBadRequest},
+		{"malformed cursor", "cursor=bogus", http.StatusBadRequest},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(r, http.MethodGet, "/messages?"+tt.query, nil); w.Code != tt.code {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+		})
+	}
+}
+
+// messageIDs lists the IDs of a message list response, in order
+func messageIDs(body map[string]interface{}) []uint {
+	var ids []uint
+	for _, m := range body["messages"].([]interface{}) {
+		ids = append(ids, uint(m.(map[string]interface{})["id"].(float64)))
+	}
+	return ids
+}
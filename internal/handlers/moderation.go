@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/redisclient"
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// moderationQueueLimits are the page sizes of the admin moderation queue
+var moderationQueueLimits = pagination.Limits{Default: 50, Max: 100}
+
+// ModerationHandler serves the admin queue of listing images held back by moderation
+type ModerationHandler struct {
+	DB      *gorm.DB
+	Storage *storage.Storage
+	Emails  *auth.EmailService
+	Cache   *redisclient.CacheService // nil without Redis
+}
+
+// Queue lists images awaiting an admin decision, oldest first. ?status=pending
+// shows images the moderation job hasn't checked yet instead of flagged ones.
+func (h *ModerationHandler) Queue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ImageModerationFlagged)
+	if status != models.ImageModerationFlagged && status != models.ImageModerationPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be flagged or pending"})
+		return
+	}
+
+	p := pagination.Parse(c, moderationQueueLimits)
+	query := h.DB.Model(&models.Image{}).Where("moderation_status = ?", status)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation queue"})
+		return
+	}
+
+	var images []models.Image
+	if err := query.Preload("Listing").
+		Order("id").
+		Scopes(p.Scope()).
+		Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation queue"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(images))
+	for _, img := range images {
+		items = append(items, gin.H{
+			"id":                img.ID,
+			"url":               img.URL,
+			"listing_id":        img.ListingID,
+			"listing_title":     img.Listing.Title,
+			"owner_id":          img.Listing.OwnerID,
+			"moderation_status": img.ModerationStatus,
+			"moderation_reason": img.ModerationReason,
+			"moderated_at":      img.ModeratedAt,
+			"created_at":        img.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"images":     items,
+		"pagination": pagination.NewMeta(p, total),
+	})
+}
+
+// Approve publishes a pending or flagged image
+func (h *ModerationHandler) Approve(c *gin.Context) {
+	img, ok := h.loadImage(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Model(img).Updates(map[string]interface{}{
+		"moderation_status": models.ImageModerationApproved,
+		"moderation_reason": "",
+		"moderated_at":      time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve image"})
+		return
+	}
+	invalidateListingCache(h.Cache, img.ListingID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image approved", "image_id": img.ID})
+}
+
+// Reject removes an image and its file and emails the listing owner. If it was
+// the listing's primary image, the next image in order takes its place.
+func (h *ModerationHandler) Reject(c *gin.Context) {
+	img, ok := h.loadImage(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is fine
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(img).Error; err != nil {
+			return err
+		}
+		if !img.IsPrimary {
+			return nil
+		}
+		var next models.Image
+		err := tx.Where("listing_id = ?", img.ListingID).Order("`order`, id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_primary", true).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject image"})
+		return
+	}
+
+	// The record is gone, so a leftover file is only wasted disk space
+	_ = jobs.RemoveImageFile(h.DB, h.Storage, img.Filename)
+	invalidateListingCache(h.Cache, img.ListingID)
+
+	var listing models.Listing
+	if err := h.DB.Preload("Owner").First(&listing, img.ListingID).Error; err == nil {
+		_ = h.Emails.SendImageRejectedNotice(&listing.Owner, &listing, reason)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image rejected", "image_id": img.ID})
+}
+
+// loadImage loads the image named by the :id param, writing the error response if it can't
+func (h *ModerationHandler) loadImage(c *gin.Context) (*models.Image, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return nil, false
+	}
+
+	var img models.Image
+	if err := h.DB.First(&img, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return nil, false
+	}
+	return &img, true
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// roundTripFunc answers HTTP requests in place of the network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// recordSendGridSubjects answers SendGrid requests and records each email's subject
+func recordSendGridSubjects(t *testing.T) *[]string {
+	t.Helper()
+	var subjects []string
+	original := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var msg struct {
+			Subject string `json:"subject"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&msg); err == nil {
+			subjects = append(subjects, msg.Subject)
+		}
+		return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = original })
+	return &subjects
+}
+
+func TestImageModerationVisibility(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID)
+	for _, status := range []string{models.ImageModerationApproved, models.ImageModerationPending, models.ImageModerationFlagged} {
+		db.Create(&models.Image{ListingID: listing.ID, Filename: status + ".jpg", URL: "/uploads/" + status + ".jpg", ModerationStatus: status})
+	}
+
+	public := gin.New()
+	public.GET("/listings", h.List)
+	public.GET("/listings/:id", h.Get)
+	ownerView := gin.New()
+	ownerView.GET("/listings/:id", asUser(owner.ID), h.Get)
+
+	imageURLs := func(entry interface{}) []string {
+		var urls []string
+		for _, img := range entry.(map[string]interface{})["images"].([]interface{}) {
+			urls = append(urls, img.(map[string]interface{})["url"].(string))
+		}
+		return urls
+	}
+	target := fmt.Sprintf("/listings/%d", listing.ID)
+	detail := decode(t, serve(public, http.MethodGet, target, nil))["listing"]
+	search := decode(t, serve(public, http.MethodGet, "/listings", nil))["listings"].([]interface{})[0]
+	for where, entry := range map[string]interface{}{"detail": detail, "search": search} {
+		if urls := imageURLs(entry); len(urls) != 1 || urls[0] != "/uploads/approved.jpg" {
+			t.Errorf("public %s images %v, want only the approved one", where, urls)
+		}
+	}
+	if urls := imageURLs(decode(t, serve(ownerView, http.MethodGet, target, nil))["listing"]); len(urls) != 3 {
+		t.Errorf("owner sees images %v, want all 3", urls)
+	}
+}
+
+func TestModerationQueue(t *testing.T) {
+	db := newTestDB(t)
+	h := &ModerationHandler{DB: db}
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID)
+	for _, status := range []string{models.ImageModerationFlagged, models.ImageModerationPending, models.ImageModerationApproved, models.ImageModerationFlagged} {
+		db.Create(&models.Image{ListingID: listing.ID, Filename: "a.jpg", URL: "/uploads/a.jpg", ModerationStatus: status, ModerationReason: "racy"})
+	}
+	r := gin.New()
+	r.GET("/admin/moderation/images", h.Queue)
+
+	tests := []struct {
+		query  string
+		status int
+		want   int
+	}{
+		{query: "", status: http.StatusOK, want: 2},
+		{query: "?status=pending", status: http.StatusOK, want: 1},
+		{query: "?status=approved", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/admin/moderation/images"+tt.query, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			images := decode(t, w)["images"].([]interface{})
+			if len(images) != tt.want {
+				t.Fatalf("%d images, want %d", len(images), tt.want)
+			}
+			first := images[0].(map[string]interface{})
+			if first["listing_title"] != listing.Title || first["owner_id"] != float64(owner.ID) {
+				t.Errorf("queue entry %v lacks its listing", first)
+			}
+		})
+	}
+}
+
+func TestModerationDecisions(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	// Emails go to a recorded SendGrid instead of the log
+	emailCfg := *cfg
+	emailCfg.AppEnv, emailCfg.SendGridAPIKey = "test", "SG.test"
+	subjects := recordSendGridSubjects(t)
+	store := storage.New(&emailCfg)
+	store.PublicDir = t.TempDir()
+	h := &ModerationHandler{DB: db, Storage: store, Emails: auth.NewEmailService(&emailCfg)}
+
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID)
+	images := map[string]*models.Image{}
+	for i, name := range []string{"primary.jpg", "next.jpg", "approve.jpg"} {
+		img := &models.Image{ListingID: listing.ID, Filename: name, URL: "/uploads/" + name, Order: i,
+			IsPrimary: i == 0, ModerationStatus: models.ImageModerationFlagged, ModerationReason: "racy"}
+		db.Create(img)
+		images[name] = img
+		if err := os.WriteFile(filepath.Join(store.PublicDir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	r.POST("/admin/moderation/images/:id/approve", h.Approve)
+	r.POST("/admin/moderation/images/:id/reject", h.Reject)
+
+	w := serve(r, http.MethodPost, fmt.Sprintf("/admin/moderation/images/%d/approve", images["approve.jpg"].ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("approve status %d: %s", w.Code, w.Body)
+	}
+	var approved models.Image
+	db.First(&approved, images["approve.jpg"].ID)
+	if approved.ModerationStatus != models.ImageModerationApproved || approved.ModerationReason != "" || approved.ModeratedAt == nil {
+		t.Errorf("approved image %+v", approved)
+	}
+
+	w = serve(r, http.MethodPost, fmt.Sprintf("/admin/moderation/images/%d/reject", images["primary.jpg"].ID), map[string]string{"reason": " Not the business "})
+	if w.Code != http.StatusOK {
+		t.Fatalf("reject status %d: %s", w.Code, w.Body)
+	}
+	if err := db.First(&models.Image{}, images["primary.jpg"].ID).Error; err == nil {
+		t.Error("rejected image is still stored")
+	}
+	if _, err := os.Stat(filepath.Join(store.PublicDir, "primary.jpg")); !os.IsNotExist(err) {
+		t.Errorf("rejected file still on disk: %v", err)
+	}
+	var next models.Image
+	db.First(&next, images["next.jpg"].ID)
+	if !next.IsPrimary {
+		t.Error("the next image didn't become primary")
+	}
+	if len(*subjects) != 1 || !strings.Contains((*subjects)[0], listing.Title) {
+		t.Errorf("emails %q, want one rejection notice to the owner", *subjects)
+	}
+
+	// Rejecting without a reason works too, and unknown images are reported
+	if w := serve(r, http.MethodPost, fmt.Sprintf("/admin/moderation/images/%d/reject", images["next.jpg"].ID), nil); w.Code != http.StatusOK {
+		t.Errorf("reject without reason status %d: %s", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodPost, "/admin/moderation/images/999/approve", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown image status %d", w.Code)
+	}
+	if w := serve(r, http.MethodPost, "/admin/moderation/images/abc/reject", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid id status %d", w.Code)
+	}
+}
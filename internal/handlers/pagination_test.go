@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListDefaultPageSizes(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	cfg.ListingsDefaultPageSize, cfg.ListingsMaxPageSize = 3, 30
+	cfg.MessagesDefaultPageSize, cfg.MessagesMaxPageSize = 4, 40
+	cfg.LeadsDefaultPageSize, cfg.LeadsMaxPageSize = 5, 50
+	cfg.FavoritesDefaultPageSize, cfg.FavoritesMaxPageSize = 6, 60
+	user := createTestUser(t, db, "seller")
+
+	r := gin.New()
+	r.GET("/listings", (&ListingsHandler{DB: db, Cfg: cfg}).List)
+	authd := r.Group("/", asUser(user.ID))
+	authd.GET("/messages", (&MessageHandler{DB: db, Cfg: cfg}).List)
+	authd.GET("/leads", newTestLeadHandler(t, db, cfg).GetUserLeads)
+	authd.GET("/favorites", (&FavoriteHandler{DB: db, Cfg: cfg}).List)
+
+	tests := []struct {
+		target    string
+		wantLimit int
+	}{
+		{target: "/listings", wantLimit: 3},
+		{target: "/messages", wantLimit: 4},
+		{target: "/leads", wantLimit: 5},
+		{target: "/favorites", wantLimit: 6},
+		// An explicit limit is kept up to the endpoint's own maximum
+		{target: "/listings?limit=25", wantLimit: 25},
+		{target: "/messages?limit=1000", wantLimit: 40},
+		{target: "/leads?limit=45", wantLimit: 45},
+		{target: "/favorites?limit=1000", wantLimit: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(r, http.MethodGet, tt.target, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			meta, _ := decode(t, w)["pagination"].(map[string]interface{})
+			if meta["limit"] != float64(tt.wantLimit) {
+				t.Errorf("pagination %v, want limit %d", meta, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestPaginationBlockJSON(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	user := createTestUser(t, db, "seller")
+	for i := 0; i < 3; i++ {
+		createTestListing(t, db, user.ID)
+	}
+
+	r := gin.New()
+	r.GET("/listings", (&ListingsHandler{DB: db, Cfg: cfg}).List)
+	authd := r.Group("/", asUser(user.ID))
+	authd.GET("/messages", (&MessageHandler{DB: db, Cfg: cfg}).List)
+	authd.GET("/leads", newTestLeadHandler(t, db, cfg).GetUserLeads)
+	authd.GET("/favorites", (&FavoriteHandler{DB: db, Cfg: cfg}).List)
+
+	// Existing clients read these blocks; the golden strings lock their shape
+	tests := []struct {
+		target string
+		golden string
+	}{
+		{target: "/listings?page=2&limit=2", golden: `{"limit":2,"page":2,"total":3,"total_pages":2}`},
+		{target: "/messages?page=2&limit=2", golden: `{"limit":2,"page":2,"total":0,"total_pages":0}`},
+		{target: "/leads?page=2&limit=2", golden: `{"limit":2,"page":2,"total":0,"total_pages":0}`},
+		{target: "/favorites?page=2&limit=2", golden: `{"limit":2,"page":2,"total":0,"total_pages":0}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(r, http.MethodGet, tt.target, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			var body struct {
+				Pagination json.RawMessage `json:"pagination"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if string(body.Pagination) != tt.golden {
+				t.Errorf("pagination %s, want %s", body.Pagination, tt.golden)
+			}
+		})
+	}
+}
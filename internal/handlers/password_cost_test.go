@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRegisterUsesBcryptCost(t *testing.T) {
+	db := newTestDB(t, &models.RefreshToken{}, &models.TermsAcceptance{})
+	cfg := testConfig(t)
+	cfg.BcryptCost = 11
+	h := &AuthHandler{DB: db, Cfg: cfg}
+	r := gin.New()
+	r.POST("/auth/register", h.Register)
+
+	w := serve(r, http.MethodPost, "/auth/register", map[string]interface{}{
+		"email": "new@example.com", "password": "correct horse", "accept_terms": true, "terms_version": cfg.TermsVersion,
+	})
+	if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var user models.User
+	db.Where("email = ?", "new@example.com").First(&user)
+	if cost, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil || cost != 11 {
+		t.Errorf("stored hash cost %d (%v), want 11", cost, err)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	gqlctx "trade_company/internal/graphql"
+	"trade_company/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PersistedQueryHandler lets admins manage the GraphQL queries allowed to run
+// in allowlist mode
+type PersistedQueryHandler struct {
+	Queries *gqlctx.PersistedQueries
+}
+
+type registerPersistedQueryRequest struct {
+	Query string `json:"query" binding:"required"`
+	// Optional; when given it must be the hash of query, to catch a client
+	// and server disagreeing on the exact text
+	Sha256Hash string `json:"sha256_hash"`
+}
+
+// Register adds a query document to the allowlist and returns its hash
+func (h *PersistedQueryHandler) Register(c *gin.Context) {
+	var req registerPersistedQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Sha256Hash != "" && req.Sha256Hash != gqlctx.QueryHash(req.Query) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sha256_hash does not match query", "code": "HASH_MISMATCH"})
+		return
+	}
+
+	hash, err := h.Queries.Register(c, req.Query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register query"})
+		return
+	}
+
+	logger.FromContext(c).Info("PersistedQueryHandler: Query registered", zap.String("hash", hash))
+	c.JSON(http.StatusCreated, gin.H{"sha256_hash": hash})
+}
+
+// Unregister removes a query added with Register
+func (h *PersistedQueryHandler) Unregister(c *gin.Context) {
+	hash := c.Param("hash")
+	found, err := h.Queries.Unregister(c, hash)
+	if errors.Is(err, gqlctx.ErrManifestQuery) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Query comes from the manifest file", "code": "MANIFEST_QUERY"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unregister query"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+		return
+	}
+
+	logger.FromContext(c).Info("PersistedQueryHandler: Query unregistered", zap.String("hash", hash))
+	c.JSON(http.StatusOK, gin.H{"message": "Query unregistered"})
+}
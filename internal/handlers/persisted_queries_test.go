@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gqlctx "trade_company/internal/graphql"
+
+	gql "github.com/99designs/gqlgen/graphql"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPersistedQueryAdmin(t *testing.T) {
+	const query, manifestQuery = "query { listings { id } }", "query { categories }"
+	manifest := filepath.Join(t.TempDir(), "persisted-queries.json")
+	data, _ := json.Marshal(map[string]string{gqlctx.QueryHash(manifestQuery): manifestQuery})
+	os.WriteFile(manifest, data, 0o644)
+	queries, err := gqlctx.NewPersistedQueries(nil, gqlctx.APQModeAllowlist, time.Hour, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &PersistedQueryHandler{Queries: queries}
+	r := gin.New()
+	r.POST("/admin/graphql/persisted-queries", h.Register)
+	r.DELETE("/admin/graphql/persisted-queries/:hash", h.Unregister)
+	hash := gqlctx.QueryHash(query)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   map[string]interface{}
+		status int
+		code   string
+	}{
+		{name: "hash of other text", method: http.MethodPost, target: "/admin/graphql/persisted-queries",
+			body: map[string]interface{}{"query": query, "sha256_hash": gqlctx.QueryHash(query + " ")}, status: http.StatusBadRequest, code: "HASH_MISMATCH"},
+		{name: "no query", method: http.MethodPost, target: "/admin/graphql/persisted-queries",
+			body: map[string]interface{}{}, status: http.StatusBadRequest},
+		{name: "register", method: http.MethodPost, target: "/admin/graphql/persisted-queries",
+			body: map[string]interface{}{"query": query, "sha256_hash": hash}, status: http.StatusCreated},
+		{name: "unregister", method: http.MethodDelete, target: "/admin/graphql/persisted-queries/" + hash, status: http.StatusOK},
+		{name: "unregister again", method: http.MethodDelete, target: "/admin/graphql/persisted-queries/" + hash, status: http.StatusNotFound},
+		{name: "manifest query", method: http.MethodDelete, target: "/admin/graphql/persisted-queries/" + gqlctx.QueryHash(manifestQuery),
+			status: http.StatusConflict, code: "MANIFEST_QUERY"},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.target, tt.body)
+		if w.Code != tt.status {
+			t.Fatalf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+		body := decode(t, w)
+		if tt.code != "" && body["code"] != tt.code {
+			t.Errorf("%s: %v, want code %s", tt.name, body, tt.code)
+		}
+		if tt.name == "register" {
+			if body["sha256_hash"] != hash {
+				t.Errorf("register: %v, want hash %s", body, hash)
+			}
+			// Registered queries can be run by hash at once
+			params := &gql.RawParams{Extensions: map[string]interface{}{
+				"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash},
+			}}
+			if err := queries.MutateOperationParameters(context.Background(), params); err != nil || params.Query != query {
+				t.Errorf("registered query refused: %v, query %q", err, params.Query)
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"trade_company/internal/metrics"
+	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// viewDedupeTTL is how long a viewer's repeat visits to a listing don't count again
+const viewDedupeTTL = 24 * time.Hour
+
+// recordView counts a listing view unless it looks like bot traffic or a
+// repeat: requests without a User-Agent, IPs viewing faster than the
+// configured velocity, and viewers who already viewed the listing within
+// viewDedupeTTL are dropped. It reports whether the view was counted.
+func (h *ListingsHandler) recordView(c *gin.Context, listingID uint) bool {
+	if c.Request.UserAgent() == "" {
+		metrics.IncPopularity(metrics.ViewExcludedNoUserAgent)
+		return false
+	}
+	if h.overViewVelocity(c.ClientIP()) {
+		metrics.IncPopularity(metrics.ViewExcludedVelocity)
+		return false
+	}
+	if !h.firstView(c, listingID) {
+		metrics.IncPopularity(metrics.ViewExcludedRepeat)
+		return false
+	}
+
+	if err := h.countView(listingID, time.Now()); err != nil {
+		return false
+	}
+	metrics.IncPopularity(metrics.ViewCounted)
+	h.Leaderboard.Record(c.Request.Context(), listingID, redisclient.TrendingViewWeight)
+	return true
+}
+
+// trendingLimits are the page sizes of the trending listings
+var trendingLimits = pagination.Limits{Default: 10, Max: 50}
+
+// Trending returns the listings with the most recent activity (views, favorites
+// and leads, with older activity decayed). Without Redis, or if it fails, it
+// falls back to the all-time most viewed listings.
+func (h *ListingsHandler) Trending(c *gin.Context) {
+	limit := pagination.Parse(c, trendingLimits).Limit
+
+	source := "activity"
+	var ids []uint
+	if h.Leaderboard != nil {
+		// Inactive and non-public listings keep their scores, so fetch extra to fill the page
+		top, err := h.Leaderboard.Top(c.Request.Context(), limit*3)
+		if err == nil {
+			ids = top
+		}
+	}
+	if len(ids) == 0 {
+		source = "views"
+		if err := h.DB.Model(&models.Listing{}).
+			Where("status = ? AND visibility = ?", models.ListingStatusActive, models.ListingVisibilityPublic).
+			Order("view_count DESC, id DESC").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending listings"})
+			return
+		}
+	}
+
+	var listings []models.Listing
+	if len(ids) > 0 {
+		if err := h.DB.Preload("Images", "is_primary = ? AND moderation_status = ?", true, models.ImageModerationApproved).
+			Where("id IN ? AND status = ? AND visibility = ?", ids, models.ListingStatusActive, models.ListingVisibilityPublic).
+			Find(&listings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending listings"})
+			return
+		}
+	}
+	byID := make(map[uint]*models.Listing, len(listings))
+	for i := range listings {
+		byID[listings[i].ID] = &listings[i]
+	}
+	items := make([]gin.H, 0, limit)
+	for _, id := range ids {
+		if l, ok := byID[id]; ok && len(items) < limit {
+			items = append(items, listingSummary(l))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"listings": items, "source": source})
+}
+
+// rememberView records that a signed-in buyer opened the listing, for their
+// recommendations. It is best effort: a failure never fails the page.
+func (h *ListingsHandler) rememberView(userID, listingID uint) {
+	_ = h.DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"viewed_at"}),
+	}).Create(&models.ListingUserView{UserID: userID, ListingID: listingID, ViewedAt: time.Now()}).Error
+}
+
+// incrementViews upserts a +1 on the views column of a per-listing aggregate row
+var incrementViews = clause.OnConflict{
+	DoUpdates: clause.Assignments(map[string]interface{}{"views": gorm.Expr("views + 1")}),
+}
+
+// countView records an accepted view at the given time in the running total,
+// the daily table and the hour-of-day histogram. Every write is an atomic
+// in-database increment, so concurrent views of the same listing are never lost.
+func (h *ListingsHandler) countView(listingID uint, now time.Time) error {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Listing{}).Where("id = ?", listingID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(incrementViews).
+			Create(&models.ListingViewDaily{ListingID: listingID, ViewDate: today, Views: 1}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(incrementViews).
+			Create(&models.ListingViewHourly{ListingID: listingID, ViewDate: today, Hour: now.Hour(), Views: 1}).Error
+	})
+}
+
+// overViewVelocity reports whether ip has gone over its per-minute view budget.
+// Without Redis there is no shared counter, so every view is allowed.
+func (h *ListingsHandler) overViewVelocity(ip string) bool {
+	if h.RedisClient == nil || h.Cfg.ViewVelocityPerMinute <= 0 {
+		return false
+	}
+
+	ctx := context.Background()
+	key := fmt.Sprintf("view_velocity:%s", ip)
+
+	pipe := h.RedisClient.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false // Allow if Redis error
+	}
+
+	return incr.Val() > int64(h.Cfg.ViewVelocityPerMinute)
+}
+
+// firstView reports whether this is the viewer's first view of the listing
+// within viewDedupeTTL. Viewers are users when logged in, IPs otherwise.
+// Without Redis, or if it fails, every view is a first view.
+func (h *ListingsHandler) firstView(c *gin.Context, listingID uint) bool {
+	if h.RedisClient == nil {
+		return true
+	}
+
+	viewer := "ip:" + c.ClientIP()
+	if uid, ok := c.Get("user_id"); ok {
+		viewer = fmt.Sprintf("user:%v", uid)
+	}
+	key := fmt.Sprintf("view:listing:%d:%s", listingID, viewer)
+	first, err := h.RedisClient.SetNX(c.Request.Context(), key, 1, viewDedupeTTL).Result()
+	if err != nil {
+		return true // Count if Redis error
+	}
+	return first
+}
+
+// countsTowardPopularity reports whether a favorite from this user should be
+// included in public favorite counts. Young accounts are a cheap bot signal.
+func (h *FavoriteHandler) countsTowardPopularity(userID uint) (bool, error) {
+	minAge := time.Duration(h.Cfg.FavoriteMinAccountAgeHours) * time.Hour
+	if minAge <= 0 {
+		return true, nil
+	}
+
+	var user models.User
+	if err := h.DB.Select("created_at").First(&user, userID).Error; err != nil {
+		return false, err
+	}
+	return time.Since(user.CreatedAt) >= minAge, nil
+}
+
+// ViewsByHour returns the owner a 24-bucket histogram of views by hour of day
+// between from and to (YYYY-MM-DD, inclusive; defaults to the last 30 days)
+func (h *ListingsHandler) ViewsByHour(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := to.AddDate(0, 0, -29)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.ParseInLocation("2006-01-02", v, now.Location()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, now.Location()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Select("id").Where("id = ? AND owner_id = ?", id, userID).First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	}
+
+	var rows []struct {
+		Hour  int
+		Views int
+	}
+	if err := h.DB.Model(&models.ListingViewHourly{}).
+		Select("hour, SUM(views) AS views").
+		Where("listing_id = ? AND view_date BETWEEN ? AND ?", listing.ID, from, to).
+		Group("hour").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch view histogram"})
+		return
+	}
+
+	buckets := make([]int, 24)
+	for _, r := range rows {
+		if r.Hour >= 0 && r.Hour < 24 {
+			buckets[r.Hour] = r.Views
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"listing_id": listing.ID,
+		"from":       from.Format("2006-01-02"),
+		"to":         to.Format("2006-01-02"),
+		"timezone":   now.Location().String(),
+		"hours":      buckets,
+	})
+}
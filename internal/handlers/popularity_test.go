@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestConcurrentViewsAreAllCounted(t *testing.T) {
+	// A file database in WAL mode lets the requests write from separate
+	// connections, as they would against MySQL
+	dsn := "file:" + filepath.Join(t.TempDir(), "views.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Listing{}, &models.Image{}, &models.ListingDocument{},
+		&models.ListingCount{}, &models.ListingViewDaily{}, &models.ListingViewHourly{}); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// Without Redis there is no repeat-view filter, so every request counts
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	listing := createTestListing(t, db, owner.ID)
+
+	r := gin.New()
+	r.GET("/listings/:id", h.Get)
+
+	const views = 100
+	var wg sync.WaitGroup
+	for i := 0; i < views; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := serve(r, http.MethodGet, fmt.Sprintf("/listings/%d", listing.ID), nil); w.Code != http.StatusOK {
+				t.Errorf("status %d: %s", w.Code, w.Body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var stored models.Listing
+	db.First(&stored, listing.ID)
+	if stored.ViewCount != views {
+		t.Errorf("view_count %d, want %d", stored.ViewCount, views)
+	}
+	var daily, hourly int
+	db.Model(&models.ListingViewDaily{}).Where("listing_id = ?", listing.ID).Select("COALESCE(SUM(views), 0)").Scan(&daily)
+	db.Model(&models.ListingViewHourly{}).Where("listing_id = ?", listing.ID).Select("COALESCE(SUM(views), 0)").Scan(&hourly)
+	if daily != views || hourly != views {
+		t.Errorf("daily views %d, hourly views %d, want %d", daily, hourly, views)
+	}
+}
+
+func TestRecordView(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		velocity  int
+		// Views already made from the same IP, of other listings, and of this one
+		earlierViews, earlierSameListing int
+		want                             bool
+	}{
+		{name: "first view", userAgent: "browser", velocity: 30, want: true},
+		{name: "no user agent", velocity: 30, want: false},
+		{name: "repeat view", userAgent: "browser", velocity: 30, earlierSameListing: 1, want: false},
+		{name: "within the velocity", userAgent: "browser", velocity: 3, earlierViews: 2, want: true},
+		{name: "over the velocity", userAgent: "browser", velocity: 3, earlierViews: 3, want: false},
+		{name: "velocity disabled", userAgent: "browser", velocity: 0, earlierViews: 100, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.ViewVelocityPerMinute = tt.velocity
+			mr := miniredis.RunT(t)
+			h := &ListingsHandler{DB: db, Cfg: cfg, RedisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID)
+			other := createTestListing(t, db, owner.ID)
+
+			view := func(listingID uint) bool {
+				c, _ := gin.CreateTestContext(httptest.NewRecorder())
+				c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+				c.Request.Header.Set("User-Agent", tt.userAgent)
+				return h.recordView(c, listingID)
+			}
+			for i := 0; i < tt.earlierViews; i++ {
+				mr.Del(fmt.Sprintf("view:listing:%d:ip:192.0.2.1", other.ID))
+				view(other.ID)
+			}
+			for i := 0; i < tt.earlierSameListing; i++ {
+				view(listing.ID)
+			}
+
+			wantCount := tt.earlierSameListing
+			if tt.want {
+				wantCount++
+			}
+			if got := view(listing.ID); got != tt.want {
+				t.Errorf("recordView %v, want %v", got, tt.want)
+			}
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if stored.ViewCount != wantCount {
+				t.Errorf("view_count %d, want %d", stored.ViewCount, wantCount)
+			}
+		})
+	}
+}
+
+func TestCountsTowardPopularity(t *testing.T) {
+	tests := []struct {
+		name       string
+		minAgeHrs  int
+		accountAge time.Duration
+		want       bool
+	}{
+		{name: "no minimum", minAgeHrs: 0, accountAge: time.Minute, want: true},
+		{name: "young account", minAgeHrs: 24, accountAge: time.Hour, want: false},
+		{name: "old enough", minAgeHrs: 24, accountAge: 25 * time.Hour, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			cfg := testConfig(t)
+			cfg.FavoriteMinAccountAgeHours = tt.minAgeHrs
+			h := &FavoriteHandler{DB: db, Cfg: cfg}
+			user := createTestUser(t, db, "buyer")
+			db.Model(user).Update("created_at", time.Now().Add(-tt.accountAge))
+
+			got, err := h.countsTowardPopularity(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("countsTowardPopularity %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetShowsCountedView(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      int
+	}{
+		{name: "counted view is included", userAgent: "browser", want: 8},
+		{name: "excluded view is not", want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID, func(l *models.Listing) { l.ViewCount = 7 })
+
+			r := gin.New()
+			r.GET("/listings/:id", h.Get)
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/listings/%d", listing.ID), nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+
+			got := decode(t, w)["listing"].(map[string]interface{})["view_count"]
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if got != float64(tt.want) || stored.ViewCount != tt.want {
+				t.Errorf("response view_count %v, stored %d, want %d", got, stored.ViewCount, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewsByHour(t *testing.T) {
+	db := newTestDB(t)
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+	owner := createTestUser(t, db, "seller")
+	buyer := createTestUser(t, db, "buyer")
+	listing := createTestListing(t, db, owner.ID)
+	other := createTestListing(t, db, owner.ID)
+
+	day := func(d, hour, min int) time.Time { return time.Date(2024, 3, d, hour, min, 0, 0, time.Local) }
+	views := []struct {
+		listingID uint
+		at        time.Time
+	}{
+		{listing.ID, day(10, 0, 0)},
+		{listing.ID, day(10, 9, 5)},
+		{listing.ID, day(10, 9, 59)},
+		{listing.ID, day(11, 9, 30)},
+		{listing.ID, day(11, 23, 59)},
+		{listing.ID, day(12, 14, 0)}, // after the range
+		{other.ID, day(10, 9, 0)},
+	}
+	for _, v := range views {
+		if err := h.countView(v.listingID, v.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(userID uint, query string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/listings/:id/views-by-hour", asUser(userID), h.ViewsByHour)
+		return serve(r, http.MethodGet, fmt.Sprintf("/listings/%d/views-by-hour%s", listing.ID, query), nil)
+	}
+
+	w := get(owner.ID, "?from=2024-03-10&to=2024-03-11")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	want := make([]interface{}, 24)
+	for i := range want {
+		want[i] = float64(0)
+	}
+	want[0], want[9], want[23] = float64(1), float64(3), float64(1)
+	if got := decode(t, w)["hours"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("hours %v, want %v", got, want)
+	}
+
+	if w := get(owner.ID, "?from=2024-03-12&to=2024-03-12"); decode(t, w)["hours"].([]interface{})[14] != float64(1) {
+		t.Errorf("single day: %s", w.Body)
+	}
+	if w := get(buyer.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("another user's listing: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := get(owner.ID, "?from=2024-03-11&to=2024-03-10"); w.Code != http.StatusBadRequest {
+		t.Errorf("reversed range: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTrending(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "seller")
+	views := func(n int) func(*models.Listing) { return func(l *models.Listing) { l.ViewCount = n } }
+	quiet := createTestListing(t, db, owner.ID, views(500))
+	busy := createTestListing(t, db, owner.ID, views(10))
+	busier := createTestListing(t, db, owner.ID, views(20))
+	unlisted := createTestListing(t, db, owner.ID, views(1000), func(l *models.Listing) { l.Visibility = models.ListingVisibilityUnlisted })
+	sold := createTestListing(t, db, owner.ID, views(1000), func(l *models.Listing) { l.Status = models.ListingStatusSold })
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	leaderboard := redisclient.NewTrending(client)
+	ctx := context.Background()
+	leaderboard.Record(ctx, busy.ID, redisclient.TrendingFavoriteWeight)
+	leaderboard.Record(ctx, busier.ID, redisclient.TrendingLeadWeight)
+	leaderboard.Record(ctx, unlisted.ID, 100)
+	leaderboard.Record(ctx, sold.ID, 100)
+
+	trending := func(h *ListingsHandler, query string) (string, []uint) {
+		t.Helper()
+		r := gin.New()
+		r.GET("/listings/trending", h.Trending)
+		w := serve(r, http.MethodGet, "/listings/trending"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		body := decode(t, w)
+		var ids []uint
+		for _, l := range body["listings"].([]interface{}) {
+			ids = append(ids, uint(l.(map[string]interface{})["id"].(float64)))
+		}
+		return body["source"].(string), ids
+	}
+
+	// Recent activity outranks all-time views, and hidden listings never show
+	h := &ListingsHandler{DB: db, Cfg: testConfig(t), Leaderboard: leaderboard}
+	if source, ids := trending(h, ""); source != "activity" || !reflect.DeepEqual(ids, []uint{busier.ID, busy.ID}) {
+		t.Errorf("trending %s %v, want activity %v", source, ids, []uint{busier.ID, busy.ID})
+	}
+	if _, ids := trending(h, "?limit=1"); !reflect.DeepEqual(ids, []uint{busier.ID}) {
+		t.Errorf("limit 1 gave %v", ids)
+	}
+
+	// A counted view adds to the leaderboard
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("User-Agent", "browser")
+	if !h.recordView(c, quiet.ID) {
+		t.Fatal("view not counted")
+	}
+	if score, _ := mr.ZScore(redisclient.TrendingKey, fmt.Sprint(quiet.ID)); score != redisclient.TrendingViewWeight {
+		t.Errorf("view scored %v, want %v", score, redisclient.TrendingViewWeight)
+	}
+
+	// Without Redis, or with Redis down, the all-time most viewed listings are shown
+	want := []uint{quiet.ID, busier.ID, busy.ID}
+	if source, ids := trending(&ListingsHandler{DB: db, Cfg: testConfig(t)}, ""); source != "views" || !reflect.DeepEqual(ids, want) {
+		t.Errorf("without Redis %s %v, want views %v", source, ids, want)
+	}
+	mr.Close()
+	if source, ids := trending(h, ""); source != "views" || !reflect.DeepEqual(ids, want) {
+		t.Errorf("with Redis down %s %v, want views %v", source, ids, want)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// profileBadges are the verification marks shown next to a user's name
+type profileBadges struct {
+	EmailVerified    bool `json:"email_verified"`
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+}
+
+// profileCounts are the header UI counters
+type profileCounts struct {
+	UnreadMessages int64 `json:"unread_messages"`
+	UnreadLeads    int64 `json:"unread_leads"`
+	ActiveListings int64 `json:"active_listings"`
+}
+
+// profileResponse is the current user's profile as served by both /auth/me and /user/profile
+type profileResponse struct {
+	ID        uint          `json:"id"`
+	Email     string        `json:"email"`
+	Username  string        `json:"username"`
+	FirstName string        `json:"first_name"`
+	LastName  string        `json:"last_name"`
+	Phone     string        `json:"phone"`
+	AvatarURL *string       `json:"avatar_url"`
+	Role      models.Role   `json:"role"`
+	IsActive  bool          `json:"is_active"`
+	Badges    profileBadges `json:"badges"`
+	Counts    profileCounts `json:"counts"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// loadProfileCounts counts the user's unread messages, unread leads and active listings,
+// caching the result for a short while since the header polls it on every page
+func loadProfileCounts(db *gorm.DB, cache *redisclient.CacheService, userID uint) (profileCounts, error) {
+	if cache != nil {
+		if cached, err := cache.GetCachedUserCounts(userID); err == nil && cached != nil {
+			return profileCounts{
+				UnreadMessages: cached["unread_messages"],
+				UnreadLeads:    cached["unread_leads"],
+				ActiveListings: cached["active_listings"],
+			}, nil
+		}
+	}
+
+	var counts profileCounts
+	queries := []struct {
+		query *gorm.DB
+		dest  *int64
+	}{
+		{db.Model(&models.Message{}).Where("receiver_id = ? AND is_read = ?", userID, false), &counts.UnreadMessages},
+		{db.Model(&models.Lead{}).Where("receiver_id = ? AND is_read = ? AND is_spam = ?", userID, false, false), &counts.UnreadLeads},
+		{db.Model(&models.Listing{}).Where("owner_id = ? AND status = ?", userID, models.ListingStatusActive), &counts.ActiveListings},
+	}
+	for _, q := range queries {
+		if err := q.query.Count(q.dest).Error; err != nil {
+			return counts, err
+		}
+	}
+
+	if cache != nil {
+		_ = cache.CacheUserCounts(userID, map[string]int64{
+			"unread_messages": counts.UnreadMessages,
+			"unread_leads":    counts.UnreadLeads,
+			"active_listings": counts.ActiveListings,
+		})
+	}
+	return counts, nil
+}
+
+// serveProfile writes the current user's profile. The body carries the profile under
+// both "data" (the /auth/me shape) and "user" (the /user/profile shape) so existing
+// clients of either route keep working while both routes return identical bodies.
+func serveProfile(c *gin.Context, db *gorm.DB, cache *redisclient.CacheService, userID uint) {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	counts, err := loadProfileCounts(db, cache, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load profile"})
+		return
+	}
+
+	profile := profileResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Phone:     user.Phone,
+		Role:      user.Role,
+		IsActive:  user.IsActive,
+		Badges: profileBadges{
+			EmailVerified:    user.EmailVerifiedAt != nil,
+			TwoFactorEnabled: user.TwoFactorEnabled,
+		},
+		Counts:    counts,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+	if user.AvatarURL != "" {
+		profile.AvatarURL = &user.AvatarURL
+	}
+
+	body := gin.H{
+		"data": profile,
+		"user": profile,
+	}
+	// When the session's token expires, so the client can extend it in time
+	if expiresAt, ok := c.Get("jwt_expires_at"); ok {
+		body["expires_at"] = expiresAt
+	}
+	c.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/recommend"
+	"trade_company/internal/redisclient"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// recommendationLimit is how many listings the home feed recommends
+	recommendationLimit = 20
+	// recommendationCandidates caps how many listings are scored per request
+	recommendationCandidates = 500
+	// recommendationSignals caps how many favorites and how many views shape a profile
+	recommendationSignals = 50
+	// recommendationViewWindow is how far back viewed listings count as intent
+	recommendationViewWindow = 30 * 24 * time.Hour
+	// trendingWindow is the period trending listings are ranked by views over
+	trendingWindow = 7 * 24 * time.Hour
+)
+
+// RecommendationHandler serves the home feed's recommended listings
+type RecommendationHandler struct {
+	DB    *gorm.DB
+	Cache *redisclient.CacheService
+}
+
+// signalRow is a favorited or viewed listing's attributes
+type signalRow struct {
+	ListingID uint
+	Industry  string
+	Location  string
+	Price     int64
+}
+
+// List recommends active listings for the signed-in user from the industries,
+// cities and prices of their favorites and recent views, leaving out their own
+// and already-favorited listings. Anonymous visitors and users without any
+// history get the most viewed listings of the week. Responses are cached per
+// user for ten minutes.
+func (h *RecommendationHandler) List(c *gin.Context) {
+	viewerID, _ := c.Get("user_id")
+	uid, _ := viewerID.(uint)
+
+	if h.Cache != nil {
+		if cached, err := h.Cache.GetCachedRecommendations(uid); err == nil && cached != nil {
+			c.JSON(http.StatusOK, cached)
+			return
+		}
+	}
+
+	var ids []uint
+	strategy := "trending"
+	if uid != 0 {
+		profile, favorited, err := h.intentProfile(uid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load recommendations"})
+			return
+		}
+		if !profile.Empty() {
+			candidates, err := h.candidates(uid, favorited, profile)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load recommendations"})
+				return
+			}
+			ids = recommend.Rank(profile, candidates, time.Now(), recommendationLimit)
+			strategy = "personalized"
+		}
+	}
+	if len(ids) == 0 {
+		var err error
+		if ids, err = h.trending(uid); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load recommendations"})
+			return
+		}
+		strategy = "trending"
+	}
+
+	var listings []models.Listing
+	if len(ids) > 0 {
+		if err := h.DB.Preload("Images", "is_primary = ? AND moderation_status = ?", true, models.ImageModerationApproved).
+			Where("id IN ?", ids).
+			Find(&listings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load recommendations"})
+			return
+		}
+	}
+	byID := make(map[uint]*models.Listing, len(listings))
+	for i := range listings {
+		byID[listings[i].ID] = &listings[i]
+	}
+	items := make([]gin.H, 0, len(ids))
+	for _, id := range ids {
+		if l, ok := byID[id]; ok {
+			items = append(items, listingSummary(l))
+		}
+	}
+
+	response := gin.H{"strategy": strategy, "listings": items}
+	if h.Cache != nil {
+		_ = h.Cache.CacheRecommendations(uid, response)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// intentProfile builds the user's profile from their latest favorites and views,
+// and returns the IDs of every listing they have favorited
+func (h *RecommendationHandler) intentProfile(userID uint) (recommend.Profile, []uint, error) {
+	var favorites []signalRow
+	if err := h.DB.Table("favorites").
+		Select("listings.id AS listing_id, listings.industry, listings.location, listings.price").
+		Joins("JOIN listings ON listings.id = favorites.listing_id").
+		Where("favorites.user_id = ?", userID).
+		Order("favorites.created_at DESC").
+		Limit(recommendationSignals).
+		Scan(&favorites).Error; err != nil {
+		return recommend.Profile{}, nil, err
+	}
+
+	var views []signalRow
+	if err := h.DB.Table("listing_user_views").
+		Select("listings.id AS listing_id, listings.industry, listings.location, listings.price").
+		Joins("JOIN listings ON listings.id = listing_user_views.listing_id").
+		Where("listing_user_views.user_id = ? AND listing_user_views.viewed_at >= ?", userID, time.Now().Add(-recommendationViewWindow)).
+		Order("listing_user_views.viewed_at DESC").
+		Limit(recommendationSignals).
+		Scan(&views).Error; err != nil {
+		return recommend.Profile{}, nil, err
+	}
+
+	var favorited []uint
+	if err := h.DB.Model(&models.Favorite{}).Where("user_id = ?", userID).Pluck("listing_id", &favorited).Error; err != nil {
+		return recommend.Profile{}, nil, err
+	}
+
+	signals := make([]recommend.Signal, 0, len(favorites)+len(views))
+	for _, f := range favorites {
+		signals = append(signals, recommend.Signal{Industry: f.Industry, Location: f.Location, Price: f.Price, Weight: recommend.FavoriteWeight})
+	}
+	for _, v := range views {
+		signals = append(signals, recommend.Signal{Industry: v.Industry, Location: v.Location, Price: v.Price, Weight: recommend.ViewWeight})
+	}
+	return recommend.NewProfile(signals), favorited, nil
+}
+
+// candidates loads active public listings in the profile's industries or cities,
+// plus every listing still fresh enough for exploration, with their views this week
+func (h *RecommendationHandler) candidates(userID uint, favorited []uint, profile recommend.Profile) ([]recommend.Candidate, error) {
+	now := time.Now()
+	matches := h.DB.Where("created_at >= ?", now.Add(-recommend.FreshFor))
+	if len(profile.Industries) > 0 {
+		industries := make([]string, 0, len(profile.Industries))
+		for industry := range profile.Industries {
+			industries = append(industries, industry)
+		}
+		matches = matches.Or("industry IN ?", industries)
+	}
+	for city := range profile.Cities {
+		matches = matches.Or("location LIKE ?", city+"%")
+	}
+
+	query := h.DB.Model(&models.Listing{}).
+		Select("id", "industry", "location", "price", "created_at").
+		Where("status = ? AND visibility = ? AND owner_id <> ?", models.ListingStatusActive, models.ListingVisibilityPublic, userID).
+		Where(matches)
+	if len(favorited) > 0 {
+		query = query.Where("id NOT IN ?", favorited)
+	}
+	var listings []models.Listing
+	if err := query.Order("created_at DESC").Limit(recommendationCandidates).Find(&listings).Error; err != nil {
+		return nil, err
+	}
+	if len(listings) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(listings))
+	for i, l := range listings {
+		ids[i] = l.ID
+	}
+	var weekly []struct {
+		ListingID uint
+		Views     int64
+	}
+	if err := h.DB.Model(&models.ListingViewDaily{}).
+		Select("listing_id, SUM(views) AS views").
+		Where("listing_id IN ? AND view_date >= ?", ids, now.Add(-trendingWindow)).
+		Group("listing_id").
+		Scan(&weekly).Error; err != nil {
+		return nil, err
+	}
+	views := make(map[uint]int64, len(weekly))
+	for _, w := range weekly {
+		views[w.ListingID] = w.Views
+	}
+
+	candidates := make([]recommend.Candidate, len(listings))
+	for i, l := range listings {
+		candidates[i] = recommend.Candidate{
+			ID:          l.ID,
+			Industry:    l.Industry,
+			Location:    l.Location,
+			Price:       l.Price,
+			CreatedAt:   l.CreatedAt,
+			WeeklyViews: views[l.ID],
+		}
+	}
+	return candidates, nil
+}
+
+// trending returns the most viewed active public listings of the week, topped up
+// with the newest ones when too few were viewed. userID's own listings are left out.
+func (h *RecommendationHandler) trending(userID uint) ([]uint, error) {
+	var ids []uint
+	if err := h.DB.Model(&models.Listing{}).
+		Joins("JOIN listing_view_daily ON listing_view_daily.listing_id = listings.id AND listing_view_daily.view_date >= ?", time.Now().Add(-trendingWindow)).
+		Where("listings.status = ? AND listings.visibility = ? AND listings.owner_id <> ?", models.ListingStatusActive, models.ListingVisibilityPublic, userID).
+		Group("listings.id").
+		Order("SUM(listing_view_daily.views) DESC, listings.id DESC").
+		Limit(recommendationLimit).
+		Pluck("listings.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) >= recommendationLimit {
+		return ids, nil
+	}
+
+	query := h.DB.Model(&models.Listing{}).
+		Where("status = ? AND visibility = ? AND owner_id <> ?", models.ListingStatusActive, models.ListingVisibilityPublic, userID)
+	if len(ids) > 0 {
+		query = query.Where("id NOT IN ?", ids)
+	}
+	var newest []uint
+	if err := query.Order("created_at DESC").Limit(recommendationLimit-len(ids)).Pluck("id", &newest).Error; err != nil {
+		return nil, err
+	}
+	return append(ids, newest...), nil
+}
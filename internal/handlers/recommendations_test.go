@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+	"trade_company/internal/recommend"
+	"trade_company/internal/redisclient"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// recommended returns the strategy and listing IDs of a recommendations response
+func recommended(t *testing.T, r http.Handler) (string, []uint) {
+	t.Helper()
+	w := serve(r, http.MethodGet, "/recommendations", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	var ids []uint
+	for _, l := range body["listings"].([]interface{}) {
+		ids = append(ids, uint(l.(map[string]interface{})["id"].(float64)))
+	}
+	return body["strategy"].(string), ids
+}
+
+func TestRecommendations(t *testing.T) {
+	db := newTestDB(t)
+	buyer := createTestUser(t, db, "buyer")
+	browser := createTestUser(t, db, "browser")
+	newcomer := createTestUser(t, db, "newcomer")
+	seller := createTestUser(t, db, "seller")
+
+	listing := func(owner *models.User, industry, location string, edit ...func(*models.Listing)) uint {
+		return createTestListing(t, db, owner.ID, append([]func(*models.Listing){func(l *models.Listing) {
+			l.Industry, l.Location = industry, location
+		}}, edit...)...).ID
+	}
+	stale := func(l *models.Listing) { l.CreatedAt = time.Now().Add(-2 * recommend.FreshFor) }
+	favorite := listing(seller, "restaurant", "台北市大安區", stale)
+	match := listing(seller, "restaurant", "台北市信義區", stale)
+	own := listing(buyer, "restaurant", "台北市中山區", stale)
+	unlisted := listing(seller, "restaurant", "台北市", stale, func(l *models.Listing) { l.Visibility = models.ListingVisibilityUnlisted })
+	sold := listing(seller, "restaurant", "台北市", stale, func(l *models.Listing) { l.Status = models.ListingStatusSold })
+	viewed := listing(seller, "retail", "高雄市", stale)
+	similar := listing(seller, "retail", "高雄市前鎮區", stale)
+	fresh := listing(seller, "laundry", "花蓮縣")
+
+	db.Create(&models.Favorite{UserID: buyer.ID, ListingID: favorite})
+	db.Create(&models.ListingUserView{UserID: browser.ID, ListingID: viewed, ViewedAt: time.Now().Add(-time.Hour)})
+	today := time.Now().Truncate(24 * time.Hour)
+	db.Create(&models.ListingViewDaily{ListingID: similar, ViewDate: today, Views: 10})
+	db.Create(&models.ListingViewDaily{ListingID: match, ViewDate: today, Views: 5})
+
+	h := &RecommendationHandler{DB: db}
+	router := func(viewer *models.User) *gin.Engine {
+		r := gin.New()
+		if viewer != nil {
+			r.Use(asUser(viewer.ID))
+		}
+		r.GET("/recommendations", h.List)
+		return r
+	}
+	contains := func(ids []uint, id uint) bool {
+		for _, got := range ids {
+			if got == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Favorites: the matching listing leads, and the favorited, own, unlisted
+	// and sold ones are left out. Fresh listings are mixed in for exploration.
+	strategy, ids := recommended(t, router(buyer))
+	if strategy != "personalized" || len(ids) == 0 || ids[0] != match {
+		t.Errorf("buyer got %s %v, want personalized led by %d", strategy, ids, match)
+	}
+	for _, excluded := range []uint{favorite, own, unlisted, sold} {
+		if contains(ids, excluded) {
+			t.Errorf("buyer recommendations %v include %d", ids, excluded)
+		}
+	}
+	if !contains(ids, fresh) {
+		t.Errorf("buyer recommendations %v don't explore the fresh listing %d", ids, fresh)
+	}
+
+	// View history alone is enough for a profile
+	strategy, ids = recommended(t, router(browser))
+	if strategy != "personalized" || len(ids) < 2 || !contains(ids[:2], viewed) || !contains(ids[:2], similar) {
+		t.Errorf("browser got %s %v, want personalized led by %d and %d", strategy, ids, viewed, similar)
+	}
+
+	// Without history: most viewed this week first, topped up with the newest
+	for name, viewer := range map[string]*models.User{"anonymous": nil, "newcomer": newcomer} {
+		strategy, ids = recommended(t, router(viewer))
+		if strategy != "trending" || len(ids) < 3 || ids[0] != similar || ids[1] != match || ids[2] != fresh {
+			t.Errorf("%s got %s %v, want trending %d, %d, %d first", name, strategy, ids, similar, match, fresh)
+		}
+		if contains(ids, unlisted) || contains(ids, sold) {
+			t.Errorf("%s trending %v includes hidden listings", name, ids)
+		}
+	}
+	// Trending leaves out the viewer's own listings
+	db.Create(&models.ListingViewDaily{ListingID: own, ViewDate: today, Views: 50})
+	if _, ids = recommended(t, router(buyer)); contains(ids, own) {
+		t.Errorf("buyer recommendations %v include their own listing", ids)
+	}
+	if _, ids = recommended(t, router(nil)); len(ids) == 0 || ids[0] != own {
+		t.Errorf("anonymous trending %v, want %d first", ids, own)
+	}
+}
+
+func TestRecommendationsCached(t *testing.T) {
+	db := newTestDB(t)
+	buyer := createTestUser(t, db, "buyer")
+	seller := createTestUser(t, db, "seller")
+	first := createTestListing(t, db, seller.ID)
+
+	mr := miniredis.RunT(t)
+	h := &RecommendationHandler{DB: db, Cache: redisclient.NewCacheService(redis.NewClient(&redis.Options{Addr: mr.Addr()}))}
+	r := gin.New()
+	r.GET("/recommendations", asUser(buyer.ID), h.List)
+
+	if _, ids := recommended(t, r); len(ids) != 1 || ids[0] != first.ID {
+		t.Fatalf("recommendations %v, want [%d]", ids, first.ID)
+	}
+	key := fmt.Sprintf("%s%d", redisclient.RecommendationsKey, buyer.ID)
+	if ttl := mr.TTL(key); ttl != redisclient.RecommendationsTTL {
+		t.Errorf("cache TTL %v, want %v", ttl, redisclient.RecommendationsTTL)
+	}
+
+	// A new listing only shows once the cached feed expires
+	second := createTestListing(t, db, seller.ID)
+	if _, ids := recommended(t, r); len(ids) != 1 {
+		t.Errorf("recommendations %v, want the cached feed", ids)
+	}
+	mr.FastForward(redisclient.RecommendationsTTL)
+	if _, ids := recommended(t, r); len(ids) != 2 || ids[0] != second.ID {
+		t.Errorf("recommendations %v after expiry, want %d added", ids, second.ID)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TermsHandler serves the current terms of service version and records users
+// accepting it
+type TermsHandler struct {
+	DB  *gorm.DB
+	Cfg *config.Config
+}
+
+type acceptTermsRequest struct {
+	Version string `json:"version" binding:"required"`
+}
+
+// checkTermsAccepted is the signup check: the form must tick accept_terms for
+// the version currently in force. A stale version means the user saw older
+// terms than the ones they would be bound by.
+func checkTermsAccepted(c *gin.Context, cfg *config.Config, accepted bool, version string) bool {
+	if accepted && version == cfg.TermsVersion {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":         "You must accept the current terms of service",
+		"code":          "TERMS_NOT_ACCEPTED",
+		"terms_version": cfg.TermsVersion,
+	})
+	return false
+}
+
+// recordTermsAcceptance stores proof that userID accepted version. Accepting
+// the same version again keeps the first record.
+func recordTermsAcceptance(db *gorm.DB, userID uint, version, ip string, now time.Time) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.TermsAcceptance{
+		UserID:     userID,
+		Version:    version,
+		AcceptedAt: now,
+		IP:         ip,
+	}).Error
+}
+
+// Current handles GET /api/v1/terms. Signed-in users also learn whether they
+// have accepted this version.
+func (h *TermsHandler) Current(c *gin.Context) {
+	response := gin.H{"version": h.Cfg.TermsVersion}
+	if userID, ok := c.Get("user_id"); ok {
+		var acceptance models.TermsAcceptance
+		err := h.DB.Where("user_id = ? AND version = ?", userID, h.Cfg.TermsVersion).First(&acceptance).Error
+		switch {
+		case err == nil:
+			response["accepted"] = true
+			response["accepted_at"] = acceptance.AcceptedAt
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response["accepted"] = false
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check terms acceptance"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Accept handles POST /api/v1/user/accept-terms. The version accepted must be
+// the current one, so an outdated page can't accept terms it never showed.
+func (h *TermsHandler) Accept(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+
+	var req acceptTermsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Version != h.Cfg.TermsVersion {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "These are not the current terms of service",
+			"code":          "TERMS_VERSION_MISMATCH",
+			"terms_version": h.Cfg.TermsVersion,
+		})
+		return
+	}
+
+	if err := recordTermsAcceptance(h.DB, uid, req.Version, c.ClientIP(), time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record terms acceptance"})
+		return
+	}
+	var acceptance models.TermsAcceptance
+	if err := h.DB.Where("user_id = ? AND version = ?", uid, req.Version).First(&acceptance).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record terms acceptance"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Terms accepted",
+		"version":     acceptance.Version,
+		"accepted_at": acceptance.AcceptedAt,
+	})
+}
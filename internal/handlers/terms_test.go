@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/middleware"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTermsReacceptanceGate(t *testing.T) {
+	db := newTestDB(t, &models.TermsAcceptance{})
+	cfg := testConfig(t)
+	cfg.TermsVersion = "2025-06"
+	h := &TermsHandler{DB: db, Cfg: cfg}
+	user := createTestUser(t, db, "seller")
+	// Accepted the terms in force when they signed up
+	if err := recordTermsAcceptance(db, user.ID, "2024-01", "192.0.2.9", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	router := func(viewer uint, version string) *gin.Engine {
+		r := gin.New()
+		api := r.Group("/api/v1")
+		if viewer != 0 {
+			api.Use(asUser(viewer))
+		}
+		api.Use(middleware.RequireTerms(db, version))
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		api.GET("/user/dashboard", ok)
+		api.POST("/listings", ok)
+		api.POST("/auth/logout", ok)
+		api.GET("/terms", h.Current)
+		api.POST("/user/accept-terms", h.Accept)
+		return r
+	}
+	r := router(user.ID, cfg.TermsVersion)
+	blocked := func(t *testing.T, r *gin.Engine, version string) {
+		t.Helper()
+		w := serve(r, http.MethodPost, "/api/v1/listings", nil)
+		if body := decode(t, w); w.Code != http.StatusForbidden || body["code"] != "TERMS_REACCEPT_REQUIRED" || body["terms_version"] != version {
+			t.Errorf("write: status %d %v, want 403 TERMS_REACCEPT_REQUIRED for %s", w.Code, body, version)
+		}
+	}
+
+	// Reads and auth endpoints stay open; writes wait for the new terms
+	blocked(t, r, "2025-06")
+	for _, req := range []struct{ method, target string }{
+		{http.MethodGet, "/api/v1/user/dashboard"},
+		{http.MethodPost, "/api/v1/auth/logout"},
+	} {
+		if w := serve(r, req.method, req.target, nil); w.Code != http.StatusOK {
+			t.Errorf("%s %s: status %d, want 200", req.method, req.target, w.Code)
+		}
+	}
+	if body := decode(t, serve(r, http.MethodGet, "/api/v1/terms", nil)); body["accepted"] != false || body["version"] != "2025-06" {
+		t.Errorf("terms before accepting %v", body)
+	}
+
+	// Accepting the old version again doesn't lift the block
+	w := serve(r, http.MethodPost, "/api/v1/user/accept-terms", map[string]string{"version": "2024-01"})
+	if w.Code != http.StatusConflict || decode(t, w)["code"] != "TERMS_VERSION_MISMATCH" {
+		t.Errorf("accept old version: status %d: %s", w.Code, w.Body)
+	}
+	blocked(t, r, "2025-06")
+
+	if w := serve(r, http.MethodPost, "/api/v1/user/accept-terms", map[string]string{"version": "2025-06"}); w.Code != http.StatusOK {
+		t.Fatalf("accept: status %d: %s", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodPost, "/api/v1/listings", nil); w.Code != http.StatusOK {
+		t.Errorf("write after accepting: status %d: %s", w.Code, w.Body)
+	}
+	if body := decode(t, serve(r, http.MethodGet, "/api/v1/terms", nil)); body["accepted"] != true {
+		t.Errorf("terms after accepting %v", body)
+	}
+
+	// The next bump blocks again, and a signed-out write is still a 401
+	blocked(t, router(user.ID, "2026-01"), "2026-01")
+	if w := serve(router(0, cfg.TermsVersion), http.MethodPost, "/api/v1/listings", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("signed-out write: status %d, want 401", w.Code)
+	}
+}
+
+func TestTermsAcceptanceAuditTrail(t *testing.T) {
+	// Each signup gets its own database: both flows leave the username empty
+	newSignup := func(t *testing.T) (*gin.Engine, *TermsHandler) {
+		db := newTestDB(t, &models.RefreshToken{}, &models.Transaction{}, &models.UserSession{}, &models.PasswordResetToken{}, &models.TermsAcceptance{})
+		cfg := testConfig(t)
+		emailCfg := *cfg
+		emailCfg.AppEnv = "test"
+		members := NewMembersAuthHandler(db, nil, cfg)
+		members.EmailService = auth.NewEmailService(&emailCfg)
+		authH := &AuthHandler{DB: db, Cfg: cfg}
+		r := gin.New()
+		r.POST("/auth/register", authH.Register)
+		r.POST("/auth/signup", members.Signup)
+		return r, &TermsHandler{DB: db, Cfg: cfg}
+	}
+
+	signups := []struct {
+		name   string
+		target string
+		body   map[string]interface{}
+	}{
+		{name: "register", target: "/auth/register", body: map[string]interface{}{"password": "correct horse"}},
+		{name: "signup", target: "/auth/signup", body: map[string]interface{}{
+			"password": "correct horse", "first_name": "Mei", "last_name": "Lin", "form_time": time.Now().Add(-time.Minute).UnixMilli(),
+		}},
+	}
+	for _, s := range signups {
+		t.Run(s.name, func(t *testing.T) {
+			r, terms := newSignup(t)
+			db, cfg := terms.DB, terms.Cfg
+			email := s.name + "@example.com"
+			attempt := func(accept bool, version string) int {
+				body := map[string]interface{}{"email": email, "accept_terms": accept, "terms_version": version}
+				for k, v := range s.body {
+					body[k] = v
+				}
+				w := serve(r, http.MethodPost, s.target, body)
+				if w.Code == http.StatusBadRequest && decode(t, w)["code"] != "TERMS_NOT_ACCEPTED" {
+					t.Errorf("rejected for another reason: %s", w.Body)
+				}
+				return w.Code
+			}
+			if code := attempt(false, cfg.TermsVersion); code != http.StatusBadRequest {
+				t.Errorf("without accepting: status %d, want 400", code)
+			}
+			if code := attempt(true, "2023-01"); code != http.StatusBadRequest {
+				t.Errorf("accepting old terms: status %d, want 400", code)
+			}
+			var count int64
+			if db.Model(&models.User{}).Where("email = ?", email).Count(&count); count != 0 {
+				t.Fatal("user created without accepting the terms")
+			}
+			if code := attempt(true, cfg.TermsVersion); code != http.StatusCreated && code != http.StatusOK {
+				t.Fatalf("accepting: status %d", code)
+			}
+
+			var user models.User
+			db.Where("email = ?", email).First(&user)
+			var rows []models.TermsAcceptance
+			db.Where("user_id = ?", user.ID).Find(&rows)
+			if len(rows) != 1 || rows[0].Version != cfg.TermsVersion || rows[0].IP != "192.0.2.1" || rows[0].AcceptedAt.IsZero() {
+				t.Errorf("acceptances %+v, want one for %s from 192.0.2.1", rows, cfg.TermsVersion)
+			}
+		})
+	}
+
+	// Accepting again keeps the original record, and older versions stay on file
+	_, terms := newSignup(t)
+	db, cfg := terms.DB, terms.Cfg
+	user := createTestUser(t, db, "longtime")
+	first := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	recordTermsAcceptance(db, user.ID, "2023-01", "198.51.100.7", first.Add(-time.Hour))
+	recordTermsAcceptance(db, user.ID, cfg.TermsVersion, "198.51.100.7", first)
+	ar := gin.New()
+	ar.POST("/user/accept-terms", asUser(user.ID), terms.Accept)
+	w := serve(ar, http.MethodPost, "/user/accept-terms", map[string]string{"version": cfg.TermsVersion})
+	if w.Code != http.StatusOK {
+		t.Fatalf("accept again: status %d: %s", w.Code, w.Body)
+	}
+	var rows []models.TermsAcceptance
+	db.Where("user_id = ?", user.ID).Order("accepted_at").Find(&rows)
+	if len(rows) != 2 || rows[0].Version != "2023-01" || !rows[1].AcceptedAt.Equal(first) || rows[1].IP != "198.51.100.7" {
+		t.Errorf("acceptances %+v, want the 2023-01 one and the original %s one", rows, cfg.TermsVersion)
+	}
+	if got, _ := time.Parse(time.RFC3339, decode(t, w)["accepted_at"].(string)); !got.Equal(first) {
+		t.Errorf("accepted_at %v, want the original %v", got, first)
+	}
+}
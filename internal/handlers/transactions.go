@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/models"
+	"trade_company/internal/pagination"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// transactionLimits bounds the transaction list pages
+var transactionLimits = pagination.Limits{Default: 20, Max: 100}
+
+var errTransactionOpen = errors.New("buyer already has an open transaction for this listing")
+
+// TransactionHandler lets buyers start purchases of listings and both parties
+// move them through pending -> paid -> completed
+type TransactionHandler struct {
+	DB *gorm.DB
+}
+
+type createTransactionRequest struct {
+	ListingID uint `json:"listing_id" binding:"required"`
+	// Optional; when given it must be the listing's owner
+	SellerID      *uint  `json:"seller_id"`
+	Amount        *int64 `json:"amount"` // Defaults to the asking price
+	PaymentMethod string `json:"payment_method" binding:"max=50"`
+}
+
+type transactionStatusRequest struct {
+	Status models.TransactionStatus `json:"status" binding:"required"` // Unknown values fail to bind
+}
+
+// Create starts a pending purchase of a listing by the caller. A buyer has at
+// most one open (pending or paid) transaction per listing.
+func (h *TransactionHandler) Create(c *gin.Context) {
+	buyerID := c.MustGet("user_id").(uint)
+
+	var req createTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	listing, err := models.InquiryListing(h.DB, req.ListingID, buyerID)
+	if errors.Is(err, models.ErrListingUnavailable) {
+		respondListingUnavailable(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+	if listing.OwnerID == buyerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot buy your own listing"})
+		return
+	}
+	if req.SellerID != nil && *req.SellerID != listing.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Seller does not own this listing", "code": "SELLER_MISMATCH"})
+		return
+	}
+
+	amount := listing.Price
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be positive"})
+		return
+	}
+
+	txn := models.Transaction{
+		ListingID:     listing.ID,
+		BuyerID:       buyerID,
+		SellerID:      listing.OwnerID,
+		Amount:        amount,
+		Status:        models.TransactionStatusPending,
+		PaymentMethod: strings.TrimSpace(req.PaymentMethod),
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the listing so one buyer's double submit can't open two
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Listing{}, listing.ID).Error; err != nil {
+			return err
+		}
+		var open int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("listing_id = ? AND buyer_id = ? AND status IN ?", listing.ID, buyerID,
+				[]models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusPaid}).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return errTransactionOpen
+		}
+		return tx.Create(&txn).Error
+	})
+	if errors.Is(err, errTransactionOpen) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have an open transaction for this listing", "code": "TRANSACTION_OPEN"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"transaction": txn})
+}
+
+// List returns the caller's transactions as buyer or seller, newest first.
+// ?role=buyer|seller and ?status= (repeated or comma-separated) narrow them.
+func (h *TransactionHandler) List(c *gin.Context) {
+	uid := c.MustGet("user_id").(uint)
+
+	query := h.DB.Model(&models.Transaction{})
+	switch role := c.Query("role"); role {
+	case "":
+		query = query.Where("buyer_id = ? OR seller_id = ?", uid, uid)
+	case string(models.TransactionActorBuyer):
+		query = query.Where("buyer_id = ?", uid)
+	case string(models.TransactionActorSeller):
+		query = query.Where("seller_id = ?", uid)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be buyer or seller"})
+		return
+	}
+
+	if statuses := queryValues(c, "status"); len(statuses) > 0 {
+		known := make([]string, len(models.TransactionStatuses))
+		for i, s := range models.TransactionStatuses {
+			known[i] = string(s)
+		}
+		if missing := missingValues(statuses, known); len(missing) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":          "Unknown filter values",
+				"invalid_values": gin.H{"status": missing},
+			})
+			return
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+
+	p := pagination.Parse(c, transactionLimits)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+		return
+	}
+
+	var transactions []models.Transaction
+	if err := query.
+		Preload("Listing").
+		Order("created_at desc").
+		Scopes(p.Scope()).
+		Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+		"pagination":   pagination.NewMeta(p, total),
+	})
+}
+
+// Get returns one of the caller's transactions with its listing and parties
+func (h *TransactionHandler) Get(c *gin.Context) {
+	txn, ok := h.loadTransaction(c, true)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transaction": txn})
+}
+
+// UpdateStatus moves one of the caller's transactions to a new status. The
+// move must be a legal transition made by the party it belongs to: the buyer
+// pays or cancels a pending transaction, the seller completes or refunds.
+func (h *TransactionHandler) UpdateStatus(c *gin.Context) {
+	var req transactionStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	txn, ok := h.loadTransaction(c, false)
+	if !ok {
+		return
+	}
+
+	uid := c.MustGet("user_id").(uint)
+	actor := req.Status.Actor()
+	if (actor == models.TransactionActorBuyer && uid != txn.BuyerID) ||
+		(actor == models.TransactionActorSeller && uid != txn.SellerID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only the " + string(actor) + " can mark a transaction " + string(req.Status),
+			"code":  "TRANSACTION_WRONG_PARTY",
+		})
+		return
+	}
+
+	from := txn.Status
+	if err := models.TransitionTransaction(h.DB, txn, req.Status, false); err != nil {
+		switch {
+		case errors.Is(err, models.ErrTransactionTransition):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":  "Cannot change a " + string(from) + " transaction to " + string(req.Status),
+				"code":   "TRANSACTION_TRANSITION",
+				"status": from,
+			})
+		case errors.Is(err, models.ErrTransactionDisputed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Transaction has an open dispute", "code": "TRANSACTION_DISPUTED"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transaction": txn})
+}
+
+// loadTransaction loads the transaction named by the :id param if the caller
+// is a party to it, writing the error response if it can't. Non-parties get a
+// 404 so transaction IDs can't be probed.
+func (h *TransactionHandler) loadTransaction(c *gin.Context, withRelations bool) (*models.Transaction, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return nil, false
+	}
+	uid := c.MustGet("user_id").(uint)
+
+	query := h.DB.Where("id = ? AND (buyer_id = ? OR seller_id = ?)", id, uid, uid)
+	if withRelations {
+		query = query.Preload("Listing").Preload("Buyer").Preload("Seller")
+	}
+	var txn models.Transaction
+	if err := query.First(&txn).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load transaction"})
+		return nil, false
+	}
+	return &txn, true
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type twoFactorLoginRequest struct {
+	LoginToken     string `json:"login_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// consumeTwoFactorCode checks code against the user's TOTP secret and marks
+// the step it matched as used. ok is false for a wrong code, and for a code
+// from a step at or before the last one accepted, so each code works once.
+func consumeTwoFactorCode(db *gorm.DB, user *models.User, code string, now time.Time) (bool, error) {
+	step, ok := auth.ValidateTOTP(user.TwoFactorSecret, code, now)
+	if !ok {
+		return false, nil
+	}
+	// Conditional on the stored step, so two requests racing with one code
+	// can't both get through
+	result := db.Model(&models.User{}).
+		Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+		Update("two_factor_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// twoFactorRequired is the Login response for an account with 2FA on: no
+// cookie yet, only a token to send back with the code
+func twoFactorRequired(c *gin.Context, token string, expiresAt time.Time) {
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "2FA required",
+		"requires_2fa":     true,
+		"login_token":      token,
+		"login_expires_at": expiresAt,
+	})
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the caller and returns it
+// with its otpauth:// URL. 2FA stays off until VerifyTwoFactor sees a code
+// from it; enrolling again before then replaces the secret.
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	uid := c.MustGet("user_id").(uint)
+
+	var user models.User
+	if err := h.DB.First(&user, uid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled", "code": "TWO_FACTOR_ENABLED"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := h.DB.Model(&user).Updates(map[string]interface{}{
+		"two_factor_secret":    secret,
+		"two_factor_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_url": auth.TOTPURL(h.Cfg.TwoFactorIssuer, user.Email, secret),
+	})
+}
+
+// VerifyTwoFactor turns 2FA on once the caller proves their authenticator
+// app has the enrolled secret
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	uid := c.MustGet("user_id").(uint)
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, uid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled", "code": "TWO_FACTOR_ENABLED"})
+		return
+	}
+	if user.TwoFactorSecret == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Enroll before verifying", "code": "TWO_FACTOR_NOT_ENROLLED"})
+		return
+	}
+
+	ok, err := consumeTwoFactorCode(h.DB, &user, req.Code, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or already used code", "code": "INVALID_TWO_FACTOR_CODE"})
+		return
+	}
+	if err := h.DB.Model(&user).Update("two_factor_enabled", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	logger.FromContext(c).Info("AuthHandler: Two-factor authentication enabled", zap.Uint("user_id", user.ID))
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "two_factor_enabled": true})
+}
+
+// TwoFactorLogin completes a login that returned requires_2fa: with the login
+// token from the password step and a current code, it sets the authToken
+// cookie. Wrong codes count towards the login challenge and the lockout like
+// wrong passwords.
+func (h *AuthHandler) TwoFactorLogin(c *gin.Context) {
+	log := logger.FromContext(c)
+
+	var req twoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	uid, err := auth.ParseTwoFactorLoginToken(h.Cfg, req.LoginToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please log in again", "code": "TWO_FACTOR_LOGIN_EXPIRED"})
+		return
+	}
+	var user models.User
+	if err := h.DB.First(&user, uid).Error; err != nil || !user.TwoFactorEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please log in again", "code": "TWO_FACTOR_LOGIN_EXPIRED"})
+		return
+	}
+
+	if h.Challenge.Required(c, user.Email, c.ClientIP()) {
+		passed, err := h.Challenge.Verify(c, req.ChallengeToken, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "challenge verification unavailable", "challenge_required": true})
+			return
+		}
+		if !passed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "challenge required", "challenge_required": true})
+			return
+		}
+	}
+
+	// Wrong codes lock the account like wrong passwords
+	if accountLocked(c, h.Lockout, user.Email) {
+		return
+	}
+	ok, err := consumeTwoFactorCode(h.DB, &user, req.Code, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		log.Warn("AuthHandler: Two-factor login failed - invalid code", zap.Uint("user_id", user.ID))
+		recordLoginFailure(c, h.Lockout, user.Email)
+		challenge := h.Challenge.RecordFailure(c, user.Email, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or already used code", "code": "INVALID_TWO_FACTOR_CODE", "challenge_required": challenge})
+		return
+	}
+	h.Lockout.Reset(c, user.Email)
+	h.Challenge.Reset(c, user.Email)
+
+	if _, err := recordLogin(h.DB, h.Cfg, &user, time.Now()); err != nil {
+		log.Error("AuthHandler: Failed to record login", zap.Uint("user_id", user.ID), logger.Err(err))
+	}
+
+	refresh, err := h.issueRefreshToken(c, &user)
+	if errors.Is(err, auth.ErrTooManySessions) {
+		tooManySessions(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	token, expiresAt, err := auth.IssueSessionToken(h.Cfg, user.ID, user.Email, refresh.Family)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	h.setAuthCookie(c, token)
+
+	log.Info("AuthHandler: Two-factor login successful", zap.Uint("user_id", user.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Login successful",
+		"user_id":            user.ID,
+		"expires_at":         expiresAt,
+		"refresh_expires_at": refresh.ExpiresAt,
+	})
+}
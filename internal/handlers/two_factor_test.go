@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+)
+
+func TestConsumeTwoFactorCode(t *testing.T) {
+	// The RFC 6238 test key. At the fixed clock 050471 is the current code
+	// and 081804 the previous step's, still inside the skew window.
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(1111111111, 0)
+
+	type attempt struct {
+		code  string
+		after time.Duration
+		want  bool
+	}
+	tests := []struct {
+		name     string
+		attempts []attempt
+	}{
+		{name: "current code", attempts: []attempt{{code: "050471", want: true}}},
+		{name: "previous step within the skew", attempts: []attempt{{code: "081804", want: true}}},
+		{name: "wrong code", attempts: []attempt{{code: "123456", want: false}}},
+		{name: "reused code", attempts: []attempt{{code: "050471", want: true}, {code: "050471", want: false}}},
+		{name: "reused later in the same window", attempts: []attempt{{code: "050471", want: true}, {code: "050471", after: 20 * time.Second, want: false}}},
+		{name: "older step after a newer one", attempts: []attempt{{code: "050471", want: true}, {code: "081804", want: false}}},
+		{name: "newer step after an older one", attempts: []attempt{{code: "081804", want: true}, {code: "050471", want: true}}},
+		{name: "expired code", attempts: []attempt{{code: "081804", after: 2 * time.Minute, want: false}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			user := createTestUser(t, db, "seller")
+			db.Model(user).Updates(map[string]interface{}{"two_factor_enabled": true, "two_factor_secret": secret})
+
+			for i, a := range tt.attempts {
+				var stored models.User
+				db.First(&stored, user.ID)
+				ok, err := consumeTwoFactorCode(db, &stored, a.code, now.Add(a.after))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != a.want {
+					t.Errorf("attempt %d with %s: ok %v, want %v", i+1, a.code, ok, a.want)
+				}
+			}
+		})
+	}
+}
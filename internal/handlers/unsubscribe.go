@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// unsubscribeColumns maps an email category to the users column that opts out of it
+var unsubscribeColumns = map[auth.EmailCategory]string{
+	auth.EmailNotification: "email_notifications",
+	auth.EmailMarketing:    "marketing_emails",
+}
+
+// UnsubscribeHandler serves the unsubscribe links in notification and
+// marketing emails. The signed token is the only credential.
+type UnsubscribeHandler struct {
+	DB  *gorm.DB
+	Cfg *config.Config
+}
+
+// parse checks the :token link, rendering the invalid page if it doesn't verify
+func (h *UnsubscribeHandler) parse(c *gin.Context) (uint, auth.EmailCategory, bool) {
+	userID, category, err := auth.ParseUnsubscribeToken(h.Cfg, c.Param("token"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "unsubscribe.html", gin.H{"invalid": true})
+		return 0, "", false
+	}
+	return userID, category, true
+}
+
+// Show asks the user to confirm. As with keep-alive links, the GET changes
+// nothing, since mail scanners open links before anyone reads the email.
+func (h *UnsubscribeHandler) Show(c *gin.Context) {
+	_, category, ok := h.parse(c)
+	if !ok {
+		return
+	}
+	c.HTML(http.StatusOK, "unsubscribe.html", gin.H{"category": string(category)})
+}
+
+// Resolve turns the category off. It also answers one-click unsubscribe
+// POSTs from mail clients (RFC 8058), which land on the same URL.
+func (h *UnsubscribeHandler) Resolve(c *gin.Context) {
+	userID, category, ok := h.parse(c)
+	if !ok {
+		return
+	}
+	// Unsubscribing twice, or after deleting the account, is not an error
+	if err := h.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update(unsubscribeColumns[category], false).Error; err != nil {
+		c.HTML(http.StatusInternalServerError, "unsubscribe.html", gin.H{"failed": true})
+		return
+	}
+	c.HTML(http.StatusOK, "unsubscribe.html", gin.H{"category": string(category), "done": true})
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"trade_company/internal/config"
+	"trade_company/internal/imagecheck"
+	"trade_company/internal/jobs"
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+	"trade_company/internal/uploads"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// chunkChecksumHeader carries the hex SHA-256 of an uploaded chunk
+const chunkChecksumHeader = "X-Chunk-Checksum"
+
+// UploadHandler serves resumable listing image uploads: initiate, send chunks in
+// any order, then complete to turn the assembled file into a listing image
+type UploadHandler struct {
+	DB      *gorm.DB
+	Cfg     *config.Config
+	Storage *storage.Storage
+	Uploads *uploads.Manager // nil when Redis is not configured
+}
+
+// uploadError maps upload errors to responses
+func uploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found or expired"})
+	case errors.Is(err, uploads.ErrTooManyUploads):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many unfinished uploads; complete or cancel one first"})
+	case errors.Is(err, uploads.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is empty or too large"})
+	case errors.Is(err, uploads.ErrInvalidChunk):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chunk index"})
+	case errors.Is(err, uploads.ErrChunkSize):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chunk has the wrong size"})
+	case errors.Is(err, uploads.ErrChecksumMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Chunk checksum mismatch", "code": "CHECKSUM_MISMATCH"})
+	case errors.Is(err, uploads.ErrIncomplete):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is missing chunks", "code": "UPLOAD_INCOMPLETE"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload failed"})
+	}
+}
+
+// available writes a 503 when resumable uploads can't work without Redis
+func (h *UploadHandler) available(c *gin.Context) bool {
+	if h.Uploads == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Resumable uploads are unavailable"})
+		return false
+	}
+	return true
+}
+
+// loadUpload loads the :id upload of the current user, writing the error response if it can't
+func (h *UploadHandler) loadUpload(c *gin.Context) (*uploads.Upload, bool) {
+	if !h.available(c) {
+		return nil, false
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+	u, err := h.Uploads.Get(c.Request.Context(), c.Param("id"), userID.(uint))
+	if err != nil {
+		uploadError(c, err)
+		return nil, false
+	}
+	return u, true
+}
+
+// Initiate starts a resumable upload of an image for one of the user's listings
+// and returns the upload ID and the chunk size to split the file into
+func (h *UploadHandler) Initiate(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input struct {
+		ListingID   uint   `json:"listing_id" binding:"required"`
+		Filename    string `json:"filename" binding:"required"`
+		Size        int64  `json:"size" binding:"required"`
+		ContentType string `json:"content_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !strings.HasPrefix(input.ContentType, "image/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only images can be uploaded"})
+		return
+	}
+
+	var listing models.Listing
+	if err := h.DB.Where("id = ? AND owner_id = ? AND status NOT IN ?", input.ListingID, userID, models.HiddenListingStatuses).
+		First(&listing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	}
+
+	u, err := h.Uploads.Initiate(c.Request.Context(), userID.(uint), listing.ID, input.Filename, input.Size)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, u)
+}
+
+// Status reports which chunks have arrived so a client can resume
+func (h *UploadHandler) Status(c *gin.Context) {
+	u, ok := h.loadUpload(c)
+	if !ok {
+		return
+	}
+	received, err := h.Uploads.Received(c.Request.Context(), u)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upload": u, "received_chunks": received})
+}
+
+// PutChunk stores one chunk sent as the raw request body, with its SHA-256 in
+// the X-Chunk-Checksum header. Resending a chunk replaces it.
+func (h *UploadHandler) PutChunk(c *gin.Context) {
+	u, ok := h.loadUpload(c)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chunk index"})
+		return
+	}
+	checksum := strings.ToLower(c.GetHeader(chunkChecksumHeader))
+	if checksum == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": chunkChecksumHeader + " header is required"})
+		return
+	}
+
+	if err := h.Uploads.PutChunk(c.Request.Context(), u, index, checksum, c.Request.Body); err != nil {
+		uploadError(c, err)
+		return
+	}
+	received, err := h.Uploads.Received(c.Request.Context(), u)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"received_chunks": len(received), "total_chunks": u.TotalChunks})
+}
+
+// Complete assembles the chunks, checks the result is an image and adds it to
+// the listing like a regular upload, pending moderation
+func (h *UploadHandler) Complete(c *gin.Context) {
+	u, ok := h.loadUpload(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var listing models.Listing
+	if err := h.DB.Where("id = ? AND owner_id = ? AND status NOT IN ?", u.ListingID, u.UserID, models.HiddenListingStatuses).
+		First(&listing).Error; err != nil {
+		_ = h.Uploads.Remove(ctx, u)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found or access denied"})
+		return
+	}
+
+	path, err := h.Uploads.Assemble(ctx, u)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	// The upload is finished either way from here; the staged chunks go with it
+	defer h.Uploads.Remove(ctx, u)
+
+	if err := checkImageFile(path, imagePolicy(h.Cfg)); err != nil {
+		rejectImage(c, u.Filename, err)
+		return
+	}
+
+	filename, url, err := h.Storage.SavePublicFile(path, u.Filename, fmt.Sprintf("listing_%d", listing.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+		return
+	}
+
+	image, err := appendListingImage(h.DB, listing.ID, filename, url)
+	if err != nil {
+		_ = jobs.RemoveImageFile(h.DB, h.Storage, filename)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Image uploaded", "image": image})
+}
+
+// Abort cancels an upload and discards its chunks
+func (h *UploadHandler) Abort(c *gin.Context) {
+	u, ok := h.loadUpload(c)
+	if !ok {
+		return
+	}
+	if err := h.Uploads.Remove(c.Request.Context(), u); err != nil {
+		uploadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+}
+
+// checkImageFile decodes the header of the file on disk rather than trusting the
+// client's content type
+func checkImageFile(path string, policy imagecheck.Policy) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = policy.Check(f)
+	return err
+}
+
+// appendListingImage adds an image after the listing's existing ones, as the
+// primary image when the listing has none yet
+func appendListingImage(db *gorm.DB, listingID uint, filename, url string) (*models.Image, error) {
+	image := models.Image{ListingID: listingID, Filename: filename, URL: url}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.Image
+		if err := tx.Select("id", "order", "is_primary").Where("listing_id = ?", listingID).Find(&existing).Error; err != nil {
+			return err
+		}
+		image.IsPrimary = true
+		for _, img := range existing {
+			if img.Order >= image.Order {
+				image.Order = img.Order + 1
+			}
+			if img.IsPrimary {
+				image.IsPrimary = false
+			}
+		}
+		return tx.Create(&image).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
@@ -1,16 +1,43 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"trade_company/internal/auth"
 	"trade_company/internal/models"
+	"trade_company/internal/names"
+	"trade_company/internal/redisclient"
 )
 
 type UserHandler struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Cache *redisclient.CacheService // optional, nil when Redis is not configured
+	Names *names.Checker
+
+	BcryptCost int // For new password hashes
+}
+
+// nameError writes a rejected username or organization name with its error code
+func nameError(c *gin.Context, err error) {
+	var nameErr *names.Error
+	if errors.As(err, &nameErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": nameErr.Message, "code": nameErr.Code})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// usernameTaken reports whether another user already has the username
+func usernameTaken(db *gorm.DB, username string, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.User{}).Where("username = ? AND id <> ?", username, userID).Count(&count).Error
+	return count > 0, err
 }
 
 // GetProfile returns the current user's profile
@@ -21,26 +48,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	if err := h.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"username":   user.Username,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"phone":      user.Phone,
-			"role":       user.Role,
-			"is_active":  user.IsActive,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
-		},
-	})
+	serveProfile(c, h.DB, h.Cache, userID.(uint))
 }
 
 // UpdateProfile updates the current user's profile
@@ -52,9 +60,11 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	var input struct {
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Phone     string `json:"phone"`
+		FirstName   *string `json:"first_name"`
+		LastName    *string `json:"last_name"`
+		Phone       *string `json:"phone"`
+		Username    *string `json:"username"`
+		CompanyName *string `json:"company_name"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -62,16 +72,57 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	// Only the fields sent are changed; each is checked before anything is saved
+	errs := fieldErrors{}
+	errs.required("first_name", input.FirstName)
+	errs.required("last_name", input.LastName)
+	errs.phone("phone", input.Phone)
+	if !errs.check(c) {
+		return
+	}
+
 	var user models.User
 	if err := h.DB.First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Update fields
-	user.FirstName = input.FirstName
-	user.LastName = input.LastName
-	user.Phone = input.Phone
+	// Usernames and organization names are public, so they go through the name checks
+	if input.Username != nil && *input.Username != user.Username {
+		username := strings.TrimSpace(*input.Username)
+		if err := h.Names.CheckUsername(username); err != nil {
+			nameError(c, err)
+			return
+		}
+		taken, err := usernameTaken(h.DB, username, user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken", "code": "USERNAME_TAKEN"})
+			return
+		}
+		user.Username = username
+	}
+	if input.CompanyName != nil {
+		companyName := strings.TrimSpace(*input.CompanyName)
+		if err := h.Names.CheckOrganizationName(companyName); err != nil {
+			nameError(c, err)
+			return
+		}
+		user.CompanyName = companyName
+	}
+
+	if input.FirstName != nil {
+		user.FirstName = strings.TrimSpace(*input.FirstName)
+	}
+	if input.LastName != nil {
+		user.LastName = strings.TrimSpace(*input.LastName)
+	}
+	if input.Phone != nil {
+		user.Phone = strings.TrimSpace(*input.Phone)
+	}
 
 	if err := h.DB.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -81,16 +132,17 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Profile updated successfully",
 		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"username":   user.Username,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"phone":      user.Phone,
-			"role":       user.Role,
-			"is_active":  user.IsActive,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
+			"id":           user.ID,
+			"email":        user.Email,
+			"username":     user.Username,
+			"first_name":   user.FirstName,
+			"last_name":    user.LastName,
+			"phone":        user.Phone,
+			"company_name": user.CompanyName,
+			"role":         user.Role,
+			"is_active":    user.IsActive,
+			"created_at":   user.CreatedAt,
+			"updated_at":   user.UpdatedAt,
 		},
 	})
 }
@@ -125,19 +177,19 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// Verify current password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.CurrentPassword)); err != nil {
+	if err := auth.CheckPassword(user.PasswordHash, input.CurrentPassword); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
 		return
 	}
 
 	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := auth.HashPassword(input.NewPassword, h.BcryptCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
 	}
 
-	user.PasswordHash = string(hashedPassword)
+	user.PasswordHash = hashedPassword
 	if err := h.DB.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
@@ -145,3 +197,321 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
 }
+
+// Dashboard returns the quick stats shown on the current user's dashboard
+func (h *UserHandler) Dashboard(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	// Each section is loaded on its own; a failing one is listed in "errors"
+	// with a null value instead of failing the whole dashboard
+	failed := []string{}
+
+	// Announcements have their own cache and schedule, so they sit outside the stats
+	announcements, err := liveAnnouncements(h.DB, h.Cache, announcementAudience(h.DB, uid), requestLocale(c))
+	if err != nil {
+		failed = append(failed, "announcements")
+	}
+
+	if h.Cache != nil {
+		if stats, err := h.Cache.GetCachedUserDashboard(uid); err == nil && stats != nil {
+			c.JSON(http.StatusOK, gin.H{"dashboard": stats, "announcements": announcements, "errors": failed})
+			return
+		}
+	}
+
+	stats := map[string]interface{}{}
+	statsFailed := len(failed)
+	section := func(name string, load func() (interface{}, error)) {
+		value, err := load()
+		if err != nil {
+			failed = append(failed, name)
+			value = nil
+		}
+		stats[name] = value
+	}
+	count := func(query *gorm.DB) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			var n int64
+			err := query.Count(&n).Error
+			return n, err
+		}
+	}
+
+	section("listings_by_status", func() (interface{}, error) {
+		return h.listingCountsByStatus(uid)
+	})
+	if byStatus, ok := stats["listings_by_status"].(map[models.ListingStatus]int64); ok {
+		stats["active_listings"] = byStatus[models.ListingStatusActive]
+	} else {
+		stats["active_listings"] = nil
+	}
+
+	// Views this week, from the daily view table
+	section("total_views", func() (interface{}, error) {
+		var totalViews int64
+		err := h.DB.Model(&models.ListingViewDaily{}).
+			Joins("JOIN listings ON listings.id = listing_view_daily.listing_id").
+			Where("listings.owner_id = ? AND listings.status <> ? AND listing_view_daily.view_date >= ?",
+				uid, models.ListingStatusDeleted, startOfWeek(time.Now())).
+			Select("COALESCE(SUM(listing_view_daily.views), 0)").
+			Scan(&totalViews).Error
+		return totalViews, err
+	})
+	section("unread_messages", count(h.DB.Model(&models.Message{}).Where("receiver_id = ? AND is_read = ?", uid, false)))
+	section("unread_leads", count(h.DB.Model(&models.Lead{}).Where("receiver_id = ? AND is_read = ? AND is_spam = ?", uid, false, false)))
+	section("pending_transactions", count(h.DB.Model(&models.Transaction{}).Where("(buyer_id = ? OR seller_id = ?) AND status = ?", uid, uid, models.TransactionStatusPending)))
+	section("favorites", count(h.DB.Model(&models.Favorite{}).Where("user_id = ?", uid)))
+	section("notifications", func() (interface{}, error) {
+		return h.recentNotifications(uid, 3)
+	})
+	section("recent_leads", func() (interface{}, error) {
+		return h.recentLeads(uid, dashboardRecentLimit)
+	})
+	section("recent_transactions", func() (interface{}, error) {
+		return h.recentTransactions(uid, dashboardRecentLimit)
+	})
+	section("top_lead_templates", func() (interface{}, error) {
+		return h.topLeadTemplates(uid, dashboardTopLeadTemplates)
+	})
+
+	// Partial results are not cached so the next request retries the failed sections
+	if h.Cache != nil && len(failed) == statsFailed {
+		_ = h.Cache.CacheUserDashboard(uid, stats)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dashboard": stats, "announcements": announcements, "errors": failed})
+}
+
+// dashboardRecentLimit is how many recent leads and transactions the dashboard shows
+const dashboardRecentLimit = 5
+
+// startOfWeek returns midnight on the Monday of t's week, in t's location
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+// listingCountsByStatus counts the user's listings per status, leaving out finalized deletions
+func (h *UserHandler) listingCountsByStatus(userID uint) (map[models.ListingStatus]int64, error) {
+	var rows []struct {
+		Status models.ListingStatus
+		Count  int64
+	}
+	if err := h.DB.Model(&models.Listing{}).
+		Select("status, COUNT(*) AS count").
+		Where("owner_id = ? AND status <> ?", userID, models.ListingStatusDeleted).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := map[models.ListingStatus]int64{
+		models.ListingStatusActive:        0,
+		models.ListingStatusInactive:      0,
+		models.ListingStatusSold:          0,
+		models.ListingStatusPendingDelete: 0,
+	}
+	for _, r := range rows {
+		counts[r.Status] = r.Count
+	}
+	return counts, nil
+}
+
+// recentLeads returns the latest non-spam leads the user received
+func (h *UserHandler) recentLeads(userID uint, limit int) ([]gin.H, error) {
+	var leads []models.Lead
+	if err := h.DB.Preload("Sender").
+		Preload("Listing").
+		Where("receiver_id = ? AND is_spam = ?", userID, false).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&leads).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]gin.H, 0, len(leads))
+	for _, l := range leads {
+		item := gin.H{
+			"id":            l.ID,
+			"subject":       l.Subject,
+			"is_read":       l.IsRead,
+			"sender_name":   strings.TrimSpace(l.Sender.FirstName + " " + l.Sender.LastName),
+			"listing_id":    l.ListingID,
+			"listing_title": nil,
+			"created_at":    l.CreatedAt,
+		}
+		if l.Listing != nil {
+			item["listing_title"] = l.Listing.Title
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// recentTransactions returns the latest transactions the user took part in, as buyer or seller
+func (h *UserHandler) recentTransactions(userID uint, limit int) ([]gin.H, error) {
+	var transactions []models.Transaction
+	if err := h.DB.Preload("Listing").
+		Where("buyer_id = ? OR seller_id = ?", userID, userID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]gin.H, 0, len(transactions))
+	for _, t := range transactions {
+		role := "buyer"
+		if t.SellerID == userID {
+			role = "seller"
+		}
+		result = append(result, gin.H{
+			"id":            t.ID,
+			"listing_id":    t.ListingID,
+			"listing_title": t.Listing.Title,
+			"amount":        t.Amount,
+			"status":        t.Status,
+			"role":          role,
+			"created_at":    t.CreatedAt,
+			"completed_at":  t.CompletedAt,
+		})
+	}
+	return result, nil
+}
+
+// dashboardTopLeadTemplates is how many of the lead templates buyers use most
+// the dashboard lists
+const dashboardTopLeadTemplates = 5
+
+// topLeadTemplates returns the templates buyers started the user's non-spam
+// leads from most often. The dashboard is cached for every locale, so each
+// template comes with all its translations.
+func (h *UserHandler) topLeadTemplates(userID uint, limit int) ([]gin.H, error) {
+	var rows []struct {
+		TemplateID uint
+		Leads      int64
+	}
+	if err := h.DB.Model(&models.Lead{}).
+		Select("template_id, COUNT(*) AS leads").
+		Where("receiver_id = ? AND is_spam = ? AND template_id IS NOT NULL", userID, false).
+		Group("template_id").
+		Order("leads DESC, template_id").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []gin.H{}, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.TemplateID
+	}
+	var templates []models.LeadTemplate
+	if err := h.DB.Where("id IN ?", ids).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.LeadTemplate, len(templates))
+	for i := range templates {
+		byID[templates[i].ID] = &templates[i]
+	}
+
+	result := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		t, ok := byID[row.TemplateID]
+		if !ok {
+			continue
+		}
+		result = append(result, gin.H{
+			"template_id": t.ID,
+			"key":         t.Key,
+			"questions":   t.Questions,
+			"leads":       row.Leads,
+		})
+	}
+	return result, nil
+}
+
+// recentNotifications merges the latest messages and leads received by the user
+// with listings waiting for the seller to confirm they are still available
+func (h *UserHandler) recentNotifications(userID uint, limit int) ([]gin.H, error) {
+	var messages []models.Message
+	if err := h.DB.Where("receiver_id = ?", userID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	var leads []models.Lead
+	if err := h.DB.Where("receiver_id = ? AND is_spam = ?", userID, false).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&leads).Error; err != nil {
+		return nil, err
+	}
+
+	var confirmations []models.ListingConfirmation
+	if err := h.DB.Preload("Listing").
+		Where("user_id = ? AND resolution = '' AND notified_at IS NOT NULL", userID).
+		Order("notified_at desc").
+		Limit(limit).
+		Find(&confirmations).Error; err != nil {
+		return nil, err
+	}
+
+	type notification struct {
+		item      gin.H
+		createdAt time.Time
+	}
+	items := make([]notification, 0, len(messages)+len(leads)+len(confirmations))
+	for _, m := range messages {
+		items = append(items, notification{gin.H{
+			"type":       "message",
+			"id":         m.ID,
+			"subject":    m.Subject,
+			"is_read":    m.IsRead,
+			"listing_id": m.ListingID,
+			"created_at": m.CreatedAt,
+		}, m.CreatedAt})
+	}
+	for _, l := range leads {
+		items = append(items, notification{gin.H{
+			"type":       "lead",
+			"id":         l.ID,
+			"subject":    l.Subject,
+			"is_read":    l.IsRead,
+			"listing_id": l.ListingID,
+			"created_at": l.CreatedAt,
+		}, l.CreatedAt})
+	}
+
+	for _, lc := range confirmations {
+		items = append(items, notification{gin.H{
+			"type":       "listing_confirmation",
+			"id":         lc.ID,
+			"subject":    lc.Listing.Title,
+			"is_read":    false,
+			"listing_id": lc.ListingID,
+			"deadline":   lc.Deadline,
+			"created_at": *lc.NotifiedAt,
+		}, *lc.NotifiedAt})
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].createdAt.After(items[j].createdAt) })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	result := make([]gin.H, len(items))
+	for i, n := range items {
+		result[i] = n.item
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// phonePattern accepts local and international numbers with the usual separators,
+// e.g. "02-2345-6789", "(02) 2345 6789" or "+886 912 345 678"
+var phonePattern = regexp.MustCompile(`^\+?[0-9()\- ]+$`)
+
+// validPhone reports whether s looks like a phone number with 7 to 15 digits
+func validPhone(s string) bool {
+	if !phonePattern.MatchString(s) {
+		return false
+	}
+	digits := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+// fieldErrors collects validation failures of a partial update keyed by JSON
+// field name. Each check skips fields the client didn't send.
+type fieldErrors map[string]string
+
+// required rejects a string that is sent but blank
+func (e fieldErrors) required(field string, v *string) {
+	if v != nil && strings.TrimSpace(*v) == "" {
+		e[field] = "must not be empty"
+	}
+}
+
+// between rejects an amount outside min to max
+func (e fieldErrors) between(field string, v *int64, min, max int64) {
+	if v != nil && (*v < min || *v > max) {
+		e[field] = fmt.Sprintf("must be between %d and %d", min, max)
+	}
+}
+
+// fraction rejects a rate outside 0 to 1
+func (e fieldErrors) fraction(field string, v *float64) {
+	if v != nil && (*v < 0 || *v > 1) {
+		e[field] = "must be between 0 and 1"
+	}
+}
+
+// phone rejects a malformed phone number; an empty string clears the field
+func (e fieldErrors) phone(field string, v *string) {
+	if v != nil && *v != "" && !validPhone(strings.TrimSpace(*v)) {
+		e[field] = "must be a valid phone number"
+	}
+}
+
+// oneOf rejects a value outside allowed
+func (e fieldErrors) oneOf(field string, v *string, allowed []string) {
+	if v == nil {
+		return
+	}
+	for _, a := range allowed {
+		if *v == a {
+			return
+		}
+	}
+	e[field] = "must be one of " + strings.Join(allowed, ", ")
+}
+
+// check writes a 400 listing every invalid field and reports whether all passed
+func (e fieldErrors) check(c *gin.Context) bool {
+	if len(e) == 0 {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fields", "fields": e})
+	return false
+}
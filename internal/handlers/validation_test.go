@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"trade_company/internal/models"
+	"trade_company/internal/names"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateProfileInvalidFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  map[string]interface{}
+		field string
+	}{
+		{name: "blank first name", body: map[string]interface{}{"first_name": "  "}, field: "first_name"},
+		{name: "blank last name", body: map[string]interface{}{"last_name": ""}, field: "last_name"},
+		{name: "letters in phone", body: map[string]interface{}{"phone": "call me"}, field: "phone"},
+		{name: "phone too short", body: map[string]interface{}{"phone": "12-34"}, field: "phone"},
+		{name: "phone too long", body: map[string]interface{}{"phone": "+886 912 345 678 901 234"}, field: "phone"},
+		{name: "one bad field among good ones", body: map[string]interface{}{"first_name": "Mei", "phone": "abc"}, field: "phone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			user := createTestUser(t, db, "seller")
+			db.Model(user).Updates(map[string]interface{}{"first_name": "Lin", "phone": "0912345678"})
+			h := &UserHandler{DB: db}
+			r := gin.New()
+			r.PUT("/user/profile", asUser(user.ID), h.UpdateProfile)
+
+			w := serve(r, http.MethodPut, "/user/profile", tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			fields, _ := decode(t, w)["fields"].(map[string]interface{})
+			if _, ok := fields[tt.field]; !ok || len(fields) != 1 {
+				t.Errorf("fields %v, want only %s", fields, tt.field)
+			}
+			var stored models.User
+			db.First(&stored, user.ID)
+			if stored.FirstName != "Lin" || stored.Phone != "0912345678" {
+				t.Errorf("profile changed to %q, %q", stored.FirstName, stored.Phone)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileKeepsUnsetFields(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "seller")
+	db.Model(user).Updates(map[string]interface{}{"first_name": "Lin", "last_name": "Chen", "phone": "0912345678"})
+	h := &UserHandler{DB: db}
+	r := gin.New()
+	r.PUT("/user/profile", asUser(user.ID), h.UpdateProfile)
+
+	// An empty phone clears it; the names weren't sent
+	if w := serve(r, http.MethodPut, "/user/profile", map[string]interface{}{"phone": ""}); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var stored models.User
+	db.First(&stored, user.ID)
+	if stored.FirstName != "Lin" || stored.LastName != "Chen" || stored.Phone != "" {
+		t.Errorf("profile %q %q %q", stored.FirstName, stored.LastName, stored.Phone)
+	}
+}
+
+func TestUpdateListingInvalidFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  map[string]interface{}
+		field string
+	}{
+		{name: "blank title", body: map[string]interface{}{"title": " "}, field: "title"},
+		{name: "negative price", body: map[string]interface{}{"price": -1}, field: "price"},
+		{name: "zero price", body: map[string]interface{}{"price": 0}, field: "price"},
+		{name: "negative rent", body: map[string]interface{}{"rent": -100}, field: "rent"},
+		{name: "negative deposit", body: map[string]interface{}{"deposit": -100}, field: "deposit"},
+		{name: "negative annual revenue", body: map[string]interface{}{"annual_revenue": -1}, field: "annual_revenue"},
+		{name: "profit rate below 0", body: map[string]interface{}{"gross_profit_rate": -0.1}, field: "gross_profit_rate"},
+		{name: "profit rate above 1", body: map[string]interface{}{"gross_profit_rate": 1.5}, field: "gross_profit_rate"},
+		{name: "invalid phone", body: map[string]interface{}{"phone_number": "not a phone"}, field: "phone_number"},
+		{name: "unknown visibility", body: map[string]interface{}{"visibility": "secret"}, field: "visibility"},
+		{name: "one bad field among good ones", body: map[string]interface{}{"title": "New name", "rent": -5}, field: "rent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			h := &ListingsHandler{DB: db, Cfg: testConfig(t)}
+			owner := createTestUser(t, db, "seller")
+			listing := createTestListing(t, db, owner.ID)
+			r := gin.New()
+			r.PUT("/listings/:id", asUser(owner.ID), h.Update)
+
+			w := serve(r, http.MethodPut, fmt.Sprintf("/listings/%d", listing.ID), tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			fields, _ := decode(t, w)["fields"].(map[string]interface{})
+			if _, ok := fields[tt.field]; !ok || len(fields) != 1 {
+				t.Errorf("fields %v, want only %s", fields, tt.field)
+			}
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if stored.Title != listing.Title || stored.Price != listing.Price || stored.PhoneNumber != listing.PhoneNumber {
+				t.Errorf("listing changed to %+v", stored)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileNameChecks(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         map[string]interface{}
+		status       int
+		code         string
+		wantUsername string
+	}{
+		{name: "new username", body: map[string]interface{}{"username": "corner_cafe"}, status: http.StatusOK, wantUsername: "corner_cafe"},
+		{name: "unchanged username", body: map[string]interface{}{"username": "seller"}, status: http.StatusOK, wantUsername: "seller"},
+		{name: "reserved", body: map[string]interface{}{"username": "admın"}, status: http.StatusBadRequest, code: names.CodeUsernameReserved},
+		{name: "profane", body: map[string]interface{}{"username": "5h1t_seller"}, status: http.StatusBadRequest, code: names.CodeUsernameProfane},
+		{name: "malformed", body: map[string]interface{}{"username": "a b"}, status: http.StatusBadRequest, code: names.CodeUsernameInvalid},
+		{name: "taken", body: map[string]interface{}{"username": "buyer"}, status: http.StatusConflict, code: "USERNAME_TAKEN"},
+		{name: "profane organization", body: map[string]interface{}{"company_name": "雞掰有限公司"}, status: http.StatusBadRequest, code: names.CodeOrganizationNameProfane},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			user := createTestUser(t, db, "seller")
+			createTestUser(t, db, "buyer")
+			checker, err := names.NewChecker("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := &UserHandler{DB: db, Names: checker}
+			r := gin.New()
+			r.PUT("/user/profile", asUser(user.ID), h.UpdateProfile)
+
+			w := serve(r, http.MethodPut, "/user/profile", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.code != "" && decode(t, w)["code"] != tt.code {
+				t.Errorf("body %s, want code %s", w.Body, tt.code)
+			}
+			want := "seller"
+			if tt.wantUsername != "" {
+				want = tt.wantUsername
+			}
+			var stored models.User
+			db.First(&stored, user.ID)
+			if stored.Username != want {
+				t.Errorf("username %q, want %q", stored.Username, want)
+			}
+		})
+	}
+}
+
+func TestAdminSetUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		status   int
+	}{
+		{name: "reserved name is allowed", username: "support", status: http.StatusOK},
+		{name: "malformed", username: "a b", status: http.StatusBadRequest},
+		{name: "taken", username: "buyer", status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			user := createTestUser(t, db, "seller")
+			createTestUser(t, db, "buyer")
+			h := &AdminHandler{DB: db}
+			r := gin.New()
+			r.PUT("/admin/users/:id/username", h.SetUsername)
+
+			w := serve(r, http.MethodPut, fmt.Sprintf("/admin/users/%d/username", user.ID), map[string]string{"username": tt.username})
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			want := "seller"
+			if tt.status == http.StatusOK {
+				want = tt.username
+			}
+			var stored models.User
+			db.First(&stored, user.ID)
+			if stored.Username != want {
+				t.Errorf("username %q, want %q", stored.Username, want)
+			}
+		})
+	}
+}
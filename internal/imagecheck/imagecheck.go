@@ -0,0 +1,142 @@
+// Package imagecheck validates uploaded images by decoding their headers instead
+// of trusting the content type the client sent. Only the header is read, so a
+// huge image is rejected without being decoded.
+package imagecheck
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+
+	// Register the standard decoders with image.DecodeConfig
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+var (
+	ErrUnrecognized = errors.New("file is not a recognized image")
+	ErrFormat       = errors.New("image format is not allowed")
+	ErrDimensions   = errors.New("image dimensions exceed the limit")
+)
+
+// KnownFormats are the formats that can be recognized and allowed
+var KnownFormats = []string{"jpeg", "png", "gif", "webp"}
+
+// Known reports whether format is one of KnownFormats
+func Known(format string) bool {
+	for _, f := range KnownFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFormats splits a comma-separated format list such as "jpeg,png,webp"
+func ParseFormats(list string) []string {
+	var formats []string
+	for _, f := range strings.Split(list, ",") {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// Info describes an image as read from its header
+type Info struct {
+	Format string
+	Width  int
+	Height int
+}
+
+// Policy is what uploads must satisfy. A zero MaxWidth or MaxHeight means no limit.
+type Policy struct {
+	Formats   []string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Inspect reads the image header from r
+func Inspect(r io.Reader) (Info, error) {
+	cfg, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return Info{}, ErrUnrecognized
+	}
+	return Info{Format: format, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// Check inspects r and rejects images in a format the policy doesn't allow or
+// larger than its maximum dimensions
+func (p Policy) Check(r io.Reader) (Info, error) {
+	info, err := Inspect(r)
+	if err != nil {
+		return info, err
+	}
+
+	allowed := false
+	for _, f := range p.Formats {
+		if f == info.Format {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return info, fmt.Errorf("%w: %s (allowed: %s)", ErrFormat, info.Format, strings.Join(p.Formats, ", "))
+	}
+
+	if (p.MaxWidth > 0 && info.Width > p.MaxWidth) || (p.MaxHeight > 0 && info.Height > p.MaxHeight) {
+		return info, fmt.Errorf("%w: %dx%d (max %dx%d)", ErrDimensions, info.Width, info.Height, p.MaxWidth, p.MaxHeight)
+	}
+	return info, nil
+}
+
+func init() {
+	// The standard library has no WebP decoder; only the header is needed here
+	image.RegisterFormat("webp", "RIFF????WEBP", decodeWebP, decodeWebPConfig)
+}
+
+func decodeWebP(io.Reader) (image.Image, error) {
+	return nil, errors.New("imagecheck: webp decoding is not supported")
+}
+
+// decodeWebPConfig reads the canvas size from the first chunk of a WebP file:
+// lossy (VP8), lossless (VP8L) or extended (VP8X)
+func decodeWebPConfig(r io.Reader) (image.Config, error) {
+	var header [30]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return image.Config{}, err
+	}
+	chunk, data := string(header[12:16]), header[20:]
+
+	var width, height int
+	switch chunk {
+	case "VP8 ":
+		// Frame tag (3 bytes), then the 9d 01 2a start code and 14-bit sizes
+		if data[3] != 0x9d || data[4] != 0x01 || data[5] != 0x2a {
+			return image.Config{}, errors.New("imagecheck: invalid VP8 start code")
+		}
+		width = int(binary.LittleEndian.Uint16(data[6:8]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(data[8:10]) & 0x3fff)
+	case "VP8L":
+		// Signature byte, then 14-bit width-1 and height-1
+		if data[0] != 0x2f {
+			return image.Config{}, errors.New("imagecheck: invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		// Flags (4 bytes), then 24-bit canvas width-1 and height-1
+		width = int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+		height = int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+	default:
+		return image.Config{}, fmt.Errorf("imagecheck: unknown WebP chunk %q", chunk)
+	}
+	return image.Config{ColorModel: color.RGBAModel, Width: width, Height: height}, nil
+}
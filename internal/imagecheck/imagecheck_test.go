@@ -0,0 +1,130 @@
+package imagecheck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// webpHeader builds the first bytes of a WebP file with the given chunk
+func webpHeader(chunk string, data []byte) []byte {
+	b := []byte("RIFF\x00\x00\x00\x00WEBP" + chunk + "\x00\x00\x00\x00")
+	b = append(b, data...)
+	for len(b) < 30 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func TestInspect(t *testing.T) {
+	var jpg, gf bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 30, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gf, image.NewGray(image.Rect(0, 0, 5, 6)), nil); err != nil {
+		t.Fatal(err)
+	}
+	vp8 := []byte{0, 0, 0, 0x9d, 0x01, 0x2a}
+	vp8 = binary.LittleEndian.AppendUint16(vp8, 640)
+	vp8 = binary.LittleEndian.AppendUint16(vp8, 480)
+	vp8l := []byte{0x2f}
+	vp8l = binary.LittleEndian.AppendUint32(vp8l, uint32(800-1)|uint32(600-1)<<14)
+	// Flags, then 24-bit width-1 and height-1: 7000x100
+	vp8x := []byte{0, 0, 0, 0, 0x57, 0x1b, 0x00, 0x63, 0x00, 0x00}
+
+	tests := []struct {
+		name string
+		data []byte
+		want Info
+	}{
+		{name: "png", data: encodePNG(t, 40, 10), want: Info{Format: "png", Width: 40, Height: 10}},
+		{name: "jpeg", data: jpg.Bytes(), want: Info{Format: "jpeg", Width: 30, Height: 20}},
+		{name: "gif", data: gf.Bytes(), want: Info{Format: "gif", Width: 5, Height: 6}},
+		{name: "lossy webp", data: webpHeader("VP8 ", vp8), want: Info{Format: "webp", Width: 640, Height: 480}},
+		{name: "lossless webp", data: webpHeader("VP8L", vp8l), want: Info{Format: "webp", Width: 800, Height: 600}},
+		{name: "extended webp", data: webpHeader("VP8X", vp8x), want: Info{Format: "webp", Width: 7000, Height: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inspect(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Inspect = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for name, data := range map[string][]byte{
+		"text":          []byte("<html>not an image</html>"),
+		"truncated png": encodePNG(t, 4, 4)[:12],
+		"bad VP8 webp":  webpHeader("VP8 ", []byte{0, 0, 0, 1, 2, 3}),
+		"unknown webp":  webpHeader("ABCD", nil),
+		"empty":         nil,
+	} {
+		if _, err := Inspect(bytes.NewReader(data)); !errors.Is(err, ErrUnrecognized) {
+			t.Errorf("%s: err %v, want ErrUnrecognized", name, err)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{Formats: []string{"jpeg", "png", "webp"}, MaxWidth: 100, MaxHeight: 50}
+	var gf bytes.Buffer
+	if err := gif.Encode(&gf, image.NewGray(image.Rect(0, 0, 10, 10)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "allowed", data: encodePNG(t, 100, 50)},
+		{name: "disallowed format", data: gf.Bytes(), want: ErrFormat},
+		{name: "too wide", data: encodePNG(t, 101, 10), want: ErrDimensions},
+		{name: "too tall", data: encodePNG(t, 10, 51), want: ErrDimensions},
+		{name: "not an image", data: []byte("hello"), want: ErrUnrecognized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.Check(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Check err %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Zero limits mean no limit
+	if _, err := (Policy{Formats: []string{"png"}}).Check(bytes.NewReader(encodePNG(t, 5000, 5000))); err != nil {
+		t.Errorf("unlimited policy: %v", err)
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	if got := ParseFormats(" JPEG, png,,webp "); !reflect.DeepEqual(got, []string{"jpeg", "png", "webp"}) {
+		t.Errorf("ParseFormats = %q", got)
+	}
+	if got := ParseFormats(""); len(got) != 0 {
+		t.Errorf("ParseFormats(\"\") = %q", got)
+	}
+	if !Known("webp") || Known("bmp") {
+		t.Error("Known disagrees with KnownFormats")
+	}
+}
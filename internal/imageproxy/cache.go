@@ -0,0 +1,153 @@
+package imageproxy
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DiskCache keeps generated variants as files in one directory and removes
+// the least recently used ones once they take more than maxBytes. The LRU
+// order is kept in memory and rebuilt from file modification times on start.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // Most recently used first; values are *cacheEntry
+	entries map[string]*list.Element
+}
+
+// tempPrefix marks files Put hasn't finished writing
+const tempPrefix = ".tmp-"
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// NewDiskCache opens the cache in dir, creating it if needed, and indexes the
+// variants already there
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create image cache dir: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image cache dir: %w", err)
+	}
+
+	c := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	var infos []os.FileInfo
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), tempPrefix) {
+			// Left behind by an interrupted Put
+			os.Remove(filepath.Join(dir, f.Name()))
+			continue
+		}
+		if info, err := f.Info(); err == nil && info.Mode().IsRegular() {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().Before(infos[j].ModTime()) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, info := range infos {
+		c.add(info.Name(), info.Size())
+	}
+	c.evict()
+	return c, nil
+}
+
+// Get returns a cached variant and marks it as recently used
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(el)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		// Removed behind our back; forget it
+		c.mu.Lock()
+		if el, ok := c.entries[key]; ok {
+			c.remove(el)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores a variant, evicting the least recently used ones if the cache is
+// over its size. The file is written under a temporary name and renamed, so a
+// concurrent Get never reads half a file.
+func (c *DiskCache) Put(key string, data []byte) error {
+	tmp, err := os.CreateTemp(c.dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*cacheEntry).size
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	c.add(key, int64(len(data)))
+	c.evict()
+	return nil
+}
+
+// add records a file as the most recently used; c.mu must be held
+func (c *DiskCache) add(key string, size int64) {
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, size: size})
+	c.size += size
+}
+
+// remove deletes an entry and its file; c.mu must be held
+func (c *DiskCache) remove(el *list.Element) {
+	e := el.Value.(*cacheEntry)
+	c.order.Remove(el)
+	delete(c.entries, e.key)
+	c.size -= e.size
+	os.Remove(filepath.Join(c.dir, e.key))
+}
+
+// evict removes the least recently used files until the cache fits, always
+// keeping the newest one; c.mu must be held
+func (c *DiskCache) evict() {
+	for c.size > c.maxBytes && c.order.Len() > 1 {
+		c.remove(c.order.Back())
+	}
+}
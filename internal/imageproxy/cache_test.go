@@ -0,0 +1,87 @@
+package imageproxy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskCacheLRU(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir(), 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ten := bytes.Repeat([]byte("x"), 10)
+	for _, key := range []string{"a", "b"} {
+		if err := c.Put(key, ten); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading a makes b the least recently used, so b goes when c arrives
+	if data, ok := c.Get("a"); !ok || !bytes.Equal(data, ten) {
+		t.Fatalf("get a: %q, %v", data, ok)
+	}
+	if err := c.Put("c", ten); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("%s cached %v, want %v", key, ok, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(c.dir, "b")); !os.IsNotExist(err) {
+		t.Errorf("evicted file still on disk: %v", err)
+	}
+
+	// Replacing a variant doesn't count it twice
+	if err := c.Put("a", ten); err != nil {
+		t.Fatal(err)
+	}
+	if c.size != 20 {
+		t.Errorf("size %d after replacing, want 20", c.size)
+	}
+
+	// A variant bigger than the whole cache is still kept until the next Put
+	big := bytes.Repeat([]byte("y"), 40)
+	if err := c.Put("big", big); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("big"); !ok || c.order.Len() != 1 {
+		t.Errorf("big variant cached %v with %d entries", ok, c.order.Len())
+	}
+}
+
+func TestDiskCacheReopen(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for i, key := range []string{"older", "newer"} {
+		path := filepath.Join(dir, key)
+		os.WriteFile(path, bytes.Repeat([]byte("x"), 10), 0o644)
+		at := old.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, at, at)
+	}
+	os.WriteFile(filepath.Join(dir, tempPrefix+"123"), []byte("half"), 0o644)
+
+	// Reopening indexes what's there by age and drops unfinished writes
+	c, err := NewDiskCache(dir, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("older"); ok {
+		t.Error("least recently used file survived the size limit")
+	}
+	if _, ok := c.Get("newer"); !ok {
+		t.Error("newest file was not indexed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, tempPrefix+"123")); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	// A file removed behind the cache's back is a miss, not an error
+	os.Remove(filepath.Join(dir, "newer"))
+	if _, ok := c.Get("newer"); ok || len(c.entries) != 0 {
+		t.Errorf("removed file still cached: %v, %d entries", ok, len(c.entries))
+	}
+}
@@ -0,0 +1,117 @@
+// Package imageproxy produces resized variants of listing images on request,
+// for images uploaded before thumbnails were generated at upload time.
+// Variants are only made at a fixed set of widths, so the variant cache can't
+// be filled with arbitrary sizes, and are kept in an LRU cache on disk.
+package imageproxy
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"sort"
+	"strconv"
+	"strings"
+
+	// Register the standard decoders with image.Decode
+	_ "image/gif"
+)
+
+// jpegQuality is the quality variants of JPEG originals are encoded at
+const jpegQuality = 85
+
+// ParseWidths splits a comma-separated width list such as "320,640,1200" into
+// ascending widths
+func ParseWidths(list string) ([]int, error) {
+	var widths []int
+	for _, w := range strings.Split(list, ",") {
+		if w = strings.TrimSpace(w); w == "" {
+			continue
+		}
+		n, err := strconv.Atoi(w)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid width %q", w)
+		}
+		widths = append(widths, n)
+	}
+	sort.Ints(widths)
+	return widths, nil
+}
+
+// Variant decodes an image and scales it down to width, keeping the aspect
+// ratio. Images already narrower than width keep their size. JPEGs are
+// re-encoded as JPEG; everything else as PNG, which keeps transparency.
+func Variant(original []byte, width int) ([]byte, error) {
+	src, format, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	resized := Resize(src, width)
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, resized)
+	}
+	return buf.Bytes(), err
+}
+
+// Resize scales src down to width with a box filter: each output pixel is the
+// average of the source pixels it covers, which avoids the aliasing of
+// nearest-neighbour sampling without needing an imaging library
+func Resize(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if width <= 0 || width >= sw {
+		return src
+	}
+	height := (sh*width + sw/2) / sw
+	if height < 1 {
+		height = 1
+	}
+
+	// Work on RGBA pixels directly; At on an arbitrary image is far too slow
+	// for photos. RGBA is alpha-premultiplied, so plain averages are correct.
+	rgba := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					bl += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// placeholderColor is the flat grey shown in place of a missing image
+var placeholderColor = color.RGBA{0xe5, 0xe7, 0xeb, 0xff}
+
+// Placeholder is a flat grey 4:3 PNG of the given width, served when an
+// image's file is missing from disk
+func Placeholder(width int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, width*3/4))
+	draw.Draw(img, img.Bounds(), image.NewUniform(placeholderColor), image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img) // Can't fail writing to memory
+	return buf.Bytes()
+}
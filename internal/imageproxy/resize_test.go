@@ -0,0 +1,93 @@
+package imageproxy
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func TestParseWidths(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{list: "1200, 320,640", want: []int{320, 640, 1200}},
+		{list: "400,", want: []int{400}},
+		{list: "", want: nil},
+		{list: "400,wide", wantErr: true},
+		{list: "0", wantErr: true},
+		{list: "-320", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWidths(tt.list)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseWidths(%q) = %v, %v; want %v, error %v", tt.list, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	// Left half black, right half white
+	src := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for y := 0; y < 600; y++ {
+		for x := 400; x < 800; x++ {
+			src.Set(x, y, color.White)
+		}
+	}
+
+	dst := Resize(src, 400)
+	if b := dst.Bounds(); b.Dx() != 400 || b.Dy() != 300 {
+		t.Fatalf("resized to %v, want 400x300", b)
+	}
+	if r, _, _, _ := dst.At(10, 10).RGBA(); r != 0 {
+		t.Errorf("left side red %d, want black", r)
+	}
+	if r, _, _, _ := dst.At(390, 10).RGBA(); r != 0xffff {
+		t.Errorf("right side red %d, want white", r)
+	}
+
+	// Narrower images are never scaled up
+	if Resize(src, 1200) != image.Image(src) {
+		t.Error("image narrower than the width was resized")
+	}
+}
+
+func TestVariantKeepsFormat(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 640, 480))
+	var jpg, pngData bytes.Buffer
+	jpeg.Encode(&jpg, img, nil)
+	png.Encode(&pngData, img)
+
+	for name, tt := range map[string]struct {
+		original []byte
+		format   string
+	}{
+		"jpeg": {jpg.Bytes(), "jpeg"},
+		"png":  {pngData.Bytes(), "png"},
+	} {
+		data, err := Variant(tt.original, 320)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || format != tt.format || cfg.Width != 320 || cfg.Height != 240 {
+			t.Errorf("%s variant: %s %dx%d, %v", name, format, cfg.Width, cfg.Height, err)
+		}
+	}
+
+	if _, err := Variant([]byte("not an image"), 320); err == nil {
+		t.Error("variant of a non-image succeeded")
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(Placeholder(400)))
+	if err != nil || format != "png" || cfg.Width != 400 || cfg.Height != 300 {
+		t.Errorf("placeholder %s %dx%d, %v", format, cfg.Width, cfg.Height, err)
+	}
+}
@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAuctionEvent marks an event that can never be applied as sent, so
+// redelivering it won't help
+var ErrInvalidAuctionEvent = errors.New("invalid auction event")
+
+// AuctionEventPayload is the body the auction service posts
+type AuctionEventPayload struct {
+	ID   string           `json:"id"`
+	Type string           `json:"type"`
+	Data AuctionEventData `json:"data"`
+}
+
+// AuctionEventData carries the outcome. A closed auction without a winner has
+// no winner_id; amounts may be sent as numbers or decimal strings.
+type AuctionEventData struct {
+	AuctionID uint64      `json:"auction_id"`
+	ListingID uint        `json:"listing_id"`
+	WinnerID  uint        `json:"winner_id"`
+	Amount    json.Number `json:"amount"`
+}
+
+// AuctionOutcome is what applying an event changed, for notifying the parties
+type AuctionOutcome struct {
+	Listing     models.Listing
+	Transaction *models.Transaction // nil when the auction ended without a winner
+	Created     bool                // The sale was recorded by this event rather than an earlier one
+}
+
+// auctionPaymentReference ties a transaction to the auction it came from
+func auctionPaymentReference(auctionID uint64) string {
+	return "auction:" + strconv.FormatUint(auctionID, 10)
+}
+
+// ApplyAuctionEvent records an auction result: a winning bid marks the listing
+// sold and creates a pending transaction for the winning amount. The auction
+// service sends both bid.winner and auction.closed for the same sale, so the
+// transaction is keyed on the auction and whichever event comes second only
+// confirms it. The event is marked processed in the same transaction.
+func ApplyAuctionEvent(db *gorm.DB, event *models.AuctionEvent, now time.Time) (*AuctionOutcome, error) {
+	var payload AuctionEventPayload
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAuctionEvent, err)
+	}
+	data := payload.Data
+	if data.AuctionID == 0 || data.ListingID == 0 {
+		return nil, fmt.Errorf("%w: auction_id and listing_id are required", ErrInvalidAuctionEvent)
+	}
+	if payload.Type == models.AuctionEventBidWinner && data.WinnerID == 0 {
+		return nil, fmt.Errorf("%w: bid.winner without winner_id", ErrInvalidAuctionEvent)
+	}
+
+	var amount int64
+	if data.WinnerID != 0 {
+		f, err := strconv.ParseFloat(data.Amount.String(), 64)
+		if err != nil || f <= 0 {
+			return nil, fmt.Errorf("%w: invalid amount %q", ErrInvalidAuctionEvent, data.Amount)
+		}
+		amount = int64(math.Round(f))
+	}
+
+	outcome := &AuctionOutcome{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&outcome.Listing, data.ListingID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: listing %d not found", ErrInvalidAuctionEvent, data.ListingID)
+			}
+			return err
+		}
+
+		if data.WinnerID != 0 {
+			if data.WinnerID == outcome.Listing.OwnerID {
+				return fmt.Errorf("%w: winner %d owns listing %d", ErrInvalidAuctionEvent, data.WinnerID, data.ListingID)
+			}
+			if err := tx.Select("id").First(&models.User{}, data.WinnerID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: winner %d not found", ErrInvalidAuctionEvent, data.WinnerID)
+				}
+				return err
+			}
+
+			var existing []models.Transaction
+			if err := tx.Where("payment_reference = ?", auctionPaymentReference(data.AuctionID)).Limit(1).Find(&existing).Error; err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				outcome.Transaction = &existing[0]
+			} else {
+				outcome.Transaction = &models.Transaction{
+					ListingID:        outcome.Listing.ID,
+					BuyerID:          data.WinnerID,
+					SellerID:         outcome.Listing.OwnerID,
+					Amount:           amount,
+					Status:           models.TransactionStatusPending,
+					PaymentMethod:    "auction",
+					PaymentReference: auctionPaymentReference(data.AuctionID),
+				}
+				if err := tx.Create(outcome.Transaction).Error; err != nil {
+					return err
+				}
+				outcome.Created = true
+			}
+
+			// Deleted listings stay deleted; the sale is still on record
+			if outcome.Listing.Status == models.ListingStatusActive || outcome.Listing.Status == models.ListingStatusInactive {
+				if err := tx.Model(&outcome.Listing).Update("status", models.ListingStatusSold).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		event.ProcessedAt = &now
+		event.Error = ""
+		return tx.Model(event).Updates(map[string]interface{}{"processed_at": now, "error": ""}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outcome, nil
+}
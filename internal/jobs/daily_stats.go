@@ -0,0 +1,161 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"trade_company/internal/format"
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DailyStatsInterval is how often the daily stats job checks for days to snapshot
+const DailyStatsInterval = time.Hour
+
+// dailyStatsCatchUpDays is how far back the job fills in missing snapshots, so
+// a few days of downtime leave no gaps
+const dailyStatsCatchUpDays = 7
+
+// StatsDay is the calendar day the instant t falls on in Taipei
+func StatsDay(t time.Time) time.Time {
+	return statsDate(t.In(format.Taipei))
+}
+
+// statsDate keys a calendar day as midnight in time.Local, so the driver
+// stores the same date in the DATE column. Only the day's date is used.
+func statsDate(day time.Time) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// statsDayBounds is the [start, end) range of a calendar day in Taipei
+func statsDayBounds(day time.Time) (time.Time, time.Time) {
+	y, m, d := day.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, format.Taipei)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// ComputeDailyStats counts the activity of a calendar day (only its date is
+// used) from the raw tables
+func ComputeDailyStats(db *gorm.DB, day time.Time) (models.DailyStat, error) {
+	day = statsDate(day)
+	start, end := statsDayBounds(day)
+	stat := models.DailyStat{Date: day}
+
+	counts := []struct {
+		name  string
+		query *gorm.DB
+		dest  *int64
+	}{
+		{"users", db.Model(&models.User{}).Where("created_at >= ? AND created_at < ?", start, end), &stat.NewUsers},
+		{"listings", db.Model(&models.Listing{}).Where("created_at >= ? AND created_at < ?", start, end), &stat.NewListings},
+		{"leads", db.Model(&models.Lead{}).Where("created_at >= ? AND created_at < ? AND is_spam = ?", start, end, false), &stat.Leads},
+		{"transactions", db.Model(&models.Transaction{}).Where("completed_at >= ? AND completed_at < ?", start, end), &stat.TransactionsCompleted},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return stat, fmt.Errorf("failed to count %s: %w", c.name, err)
+		}
+	}
+
+	if err := db.Model(&models.Transaction{}).
+		Where("completed_at >= ? AND completed_at < ?", start, end).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&stat.TransactionVolume).Error; err != nil {
+		return stat, fmt.Errorf("failed to sum transaction volume: %w", err)
+	}
+
+	if err := db.Raw(`SELECT COUNT(DISTINCT user_id) FROM (
+			SELECT user_id FROM user_sessions WHERE created_at >= ? AND created_at < ?
+			UNION SELECT sender_id FROM messages WHERE created_at >= ? AND created_at < ?
+			UNION SELECT sender_id FROM leads WHERE created_at >= ? AND created_at < ?
+		) AS active`, start, end, start, end, start, end).
+		Scan(&stat.ActiveUsers).Error; err != nil {
+		return stat, fmt.Errorf("failed to count active users: %w", err)
+	}
+
+	return stat, nil
+}
+
+// SnapshotDailyStats computes a day's stats and upserts its snapshot, so
+// running it twice for the same day is harmless
+func SnapshotDailyStats(db *gorm.DB, day time.Time) (models.DailyStat, error) {
+	stat, err := ComputeDailyStats(db, day)
+	if err != nil {
+		return stat, err
+	}
+	stat.UpdatedAt = time.Now()
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&stat).Error; err != nil {
+		return stat, fmt.Errorf("failed to store stats for %s: %w", stat.Date.Format("2006-01-02"), err)
+	}
+	return stat, nil
+}
+
+// BackfillDailyStats rebuilds the snapshots of the calendar days from to to,
+// inclusive, and returns how many were written
+func BackfillDailyStats(db *gorm.DB, from, to time.Time) (int, error) {
+	written := 0
+	for day := statsDate(from); !day.After(statsDate(to)); day = day.AddDate(0, 0, 1) {
+		if _, err := SnapshotDailyStats(db, day); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// snapshotMissingDays writes snapshots for finished days in the catch-up
+// window that don't have one yet. Today is never snapshotted; it is still changing.
+func snapshotMissingDays(db *gorm.DB, now time.Time) (int, error) {
+	today := StatsDay(now)
+	from := today.AddDate(0, 0, -dailyStatsCatchUpDays)
+
+	var existing []models.DailyStat
+	if err := db.Select("date").Where("date >= ? AND date < ?", from, today).Find(&existing).Error; err != nil {
+		return 0, fmt.Errorf("failed to load snapshots: %w", err)
+	}
+	have := make(map[string]bool, len(existing))
+	for _, s := range existing {
+		have[s.Date.Format("2006-01-02")] = true
+	}
+
+	written := 0
+	for day := from; day.Before(today); day = day.AddDate(0, 0, 1) {
+		if have[day.Format("2006-01-02")] {
+			continue
+		}
+		if _, err := SnapshotDailyStats(db, day); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// RunDailyStats snapshots each day once it has ended, checking every interval
+// until ctx is cancelled
+func RunDailyStats(ctx context.Context, db *gorm.DB, log *zap.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := snapshotMissingDays(db, time.Now())
+		if err != nil {
+			log.Error("Failed to snapshot daily stats", logger.Err(err))
+		}
+		if n > 0 {
+			log.Info("Snapshotted daily stats", zap.Int("days", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"trade_company/internal/format"
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// seedStatsActivity creates activity over the three days before today and
+// today itself; at gives an instant on the day offset days from today
+func seedStatsActivity(t *testing.T, db *gorm.DB, at func(offset int, clock time.Duration) time.Time) {
+	t.Helper()
+	create := func(v interface{}) {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := func(name string, created time.Time) *models.User {
+		u := &models.User{Email: name + "@example.com", Username: name, CreatedAt: created}
+		create(u)
+		return u
+	}
+	listing := func(owner uint, created time.Time) {
+		create(&models.Listing{Title: "Shop", Price: 1, OwnerID: owner, Status: models.ListingStatusActive, CreatedAt: created})
+	}
+	completed := func(buyer, seller uint, amount int64, at time.Time) {
+		create(&models.Transaction{ListingID: 1, BuyerID: buyer, SellerID: seller, Amount: amount, Status: models.TransactionStatusCompleted, CompletedAt: &at, CreatedAt: at})
+	}
+	hour := time.Hour
+
+	// Three days ago
+	alice := user("alice", at(-3, 9*hour))
+	bob := user("bob", at(-3, 10*hour))
+	listing(alice.ID, at(-3, 11*hour))
+	create(&models.Lead{SenderID: bob.ID, ReceiverID: alice.ID, Subject: "Hi", Message: "Interested", CreatedAt: at(-3, 12*hour)})
+	create(&models.Lead{SenderID: bob.ID, ReceiverID: alice.ID, Subject: "Spam", Message: "Buy now", IsSpam: true, CreatedAt: at(-3, 12*hour)})
+	completed(bob.ID, alice.ID, 500, at(-3, 13*hour))
+	create(&models.UserSession{UserID: alice.ID, SessionID: "s1", ExpiresAt: at(1, 0), CreatedAt: at(-3, 14*hour)})
+
+	// Two days ago
+	carol := user("carol", at(-2, 8*hour))
+	listing(carol.ID, at(-2, 9*hour))
+	listing(alice.ID, at(-2, 10*hour))
+	create(&models.Message{SenderID: carol.ID, ReceiverID: alice.ID, Content: "Still open?", CreatedAt: at(-2, 11*hour)})
+	completed(carol.ID, alice.ID, 1500, at(-2, 12*hour))
+	completed(bob.ID, carol.ID, 2500, at(-2, 13*hour))
+	create(&models.Transaction{ListingID: 1, BuyerID: bob.ID, SellerID: alice.ID, Amount: 9999, Status: models.TransactionStatusPending, CreatedAt: at(-2, 14*hour)})
+
+	// Yesterday's last minute and today's first belong to different days
+	listing(bob.ID, at(-1, 24*hour-time.Minute))
+	listing(bob.ID, at(0, time.Minute))
+	user("dave", at(0, 2*hour))
+}
+
+// newStatsTest seeds activity around today in Taipei and returns the instant helper
+func newStatsTest(t *testing.T) (*gorm.DB, func(int, time.Duration) time.Time) {
+	t.Helper()
+	db := newUnverifiedTest(t)
+	if err := db.AutoMigrate(&models.Lead{}, &models.DailyStat{}); err != nil {
+		t.Fatal(err)
+	}
+	y, m, d := time.Now().In(format.Taipei).Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, format.Taipei)
+	at := func(offset int, clock time.Duration) time.Time { return midnight.AddDate(0, 0, offset).Add(clock) }
+	seedStatsActivity(t, db, at)
+	return db, at
+}
+
+// statKey drops what differs between a snapshot and a live count of the same day
+func statKey(s models.DailyStat) string {
+	s.UpdatedAt = time.Time{}
+	date := s.Date.Format("2006-01-02")
+	s.Date = time.Time{}
+	return fmt.Sprintf("%s %+v", date, s)
+}
+
+func TestComputeDailyStats(t *testing.T) {
+	db, at := newStatsTest(t)
+	want := map[int]models.DailyStat{
+		-3: {NewUsers: 2, NewListings: 1, Leads: 1, TransactionsCompleted: 1, TransactionVolume: 500, ActiveUsers: 2},
+		-2: {NewUsers: 1, NewListings: 2, TransactionsCompleted: 2, TransactionVolume: 4000, ActiveUsers: 1},
+		-1: {NewListings: 1},
+		0:  {NewUsers: 1, NewListings: 1},
+	}
+	for offset, w := range want {
+		day := at(offset, 0)
+		w.Date = StatsDay(day)
+		got, err := ComputeDailyStats(db, day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if statKey(got) != statKey(w) {
+			t.Errorf("day %d: %s, want %s", offset, statKey(got), statKey(w))
+		}
+	}
+}
+
+func TestBackfillDailyStatsMatchesLive(t *testing.T) {
+	db, at := newStatsTest(t)
+	n, err := BackfillDailyStats(db, at(-5, 0), at(-1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("backfilled %d days, want 5", n)
+	}
+	// Running it again overwrites rather than duplicates
+	if _, err := BackfillDailyStats(db, at(-5, 0), at(-1, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshots []models.DailyStat
+	db.Order("date").Find(&snapshots)
+	if len(snapshots) != 5 {
+		t.Fatalf("%d snapshots, want 5", len(snapshots))
+	}
+	for i, snap := range snapshots {
+		live, err := ComputeDailyStats(db, at(i-5, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if statKey(snap) != statKey(live) {
+			t.Errorf("snapshot %s differs from live %s", statKey(snap), statKey(live))
+		}
+	}
+}
+
+func TestSnapshotMissingDays(t *testing.T) {
+	db, at := newStatsTest(t)
+	if _, err := SnapshotDailyStats(db, at(-2, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every finished day in the catch-up window, but not today
+	n, err := snapshotMissingDays(db, at(0, 12*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != dailyStatsCatchUpDays-1 {
+		t.Errorf("wrote %d snapshots, want %d", n, dailyStatsCatchUpDays-1)
+	}
+	var today int64
+	db.Model(&models.DailyStat{}).Where("date >= ?", StatsDay(at(0, 0))).Count(&today)
+	if today != 0 {
+		t.Error("today was snapshotted")
+	}
+	if n, _ := snapshotMissingDays(db, at(0, 13*time.Hour)); n != 0 {
+		t.Errorf("second run wrote %d snapshots", n)
+	}
+}
@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DigestCheckInterval is how often users due an unread digest are looked for
+const DigestCheckInterval = time.Hour
+
+// DigestMaxItems is how many leads, and how many messages, a digest lists;
+// the rest are only counted
+const DigestMaxItems = 5
+
+// DigestResult reports what one digest pass did
+type DigestResult struct {
+	Sent int
+}
+
+// BuildDigest assembles a digest from unread leads and messages, newest first.
+// ok is false when there is nothing to report.
+func BuildDigest(leads []models.Lead, messages []models.Message) (digest auth.Digest, ok bool) {
+	for _, l := range leads {
+		if len(digest.Leads) == DigestMaxItems {
+			digest.MoreLeads++
+			continue
+		}
+		item := auth.DigestItem{From: digestSender(l.Sender), Subject: l.Subject, ReceivedAt: l.CreatedAt}
+		if l.Listing != nil {
+			item.Listing = l.Listing.Title
+		}
+		digest.Leads = append(digest.Leads, item)
+	}
+	for _, m := range messages {
+		if len(digest.Messages) == DigestMaxItems {
+			digest.MoreMessages++
+			continue
+		}
+		item := auth.DigestItem{From: digestSender(m.Sender), Subject: m.Subject, ReceivedAt: m.CreatedAt}
+		if m.Listing != nil {
+			item.Listing = m.Listing.Title
+		}
+		digest.Messages = append(digest.Messages, item)
+	}
+	return digest, len(leads)+len(messages) > 0
+}
+
+// digestSender is how the sender of a lead or message is named in a digest
+func digestSender(u models.User) string {
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.Username
+}
+
+// SendDigests emails every user with email notifications on, who has unread
+// leads or messages newer than their last digest and hasn't had one within
+// every, a digest of those items.
+func SendDigests(db *gorm.DB, emails *auth.EmailService, every time.Duration, now time.Time) (DigestResult, error) {
+	var result DigestResult
+
+	var users []models.User
+	if err := db.Model(&models.User{}).
+		Joins("LEFT JOIN notification_digests nd ON nd.user_id = users.id").
+		Where("users.email_notifications = ? AND users.is_active = ?", true, true).
+		Where("nd.sent_at IS NULL OR nd.sent_at <= ?", now.Add(-every)).
+		Where("EXISTS (SELECT 1 FROM leads WHERE leads.receiver_id = users.id AND leads.is_read = ? AND leads.is_spam = ? AND leads.id > COALESCE(nd.last_lead_id, 0))"+
+			" OR EXISTS (SELECT 1 FROM messages WHERE messages.receiver_id = users.id AND messages.is_read = ? AND messages.id > COALESCE(nd.last_message_id, 0))",
+			false, false, false).
+		Find(&users).Error; err != nil {
+		return result, fmt.Errorf("failed to load users due a digest: %w", err)
+	}
+
+	for i := range users {
+		user := &users[i]
+		var last models.NotificationDigest
+		if err := db.Where("user_id = ?", user.ID).Limit(1).Find(&last).Error; err != nil {
+			return result, fmt.Errorf("failed to load last digest of user %d: %w", user.ID, err)
+		}
+
+		var leads []models.Lead
+		if err := db.Preload("Sender").Preload("Listing").
+			Where("receiver_id = ? AND is_read = ? AND is_spam = ? AND id > ?", user.ID, false, false, last.LastLeadID).
+			Order("id DESC").
+			Find(&leads).Error; err != nil {
+			return result, fmt.Errorf("failed to load unread leads of user %d: %w", user.ID, err)
+		}
+		var messages []models.Message
+		if err := db.Preload("Sender").Preload("Listing").
+			Where("receiver_id = ? AND is_read = ? AND id > ?", user.ID, false, last.LastMessageID).
+			Order("id DESC").
+			Find(&messages).Error; err != nil {
+			return result, fmt.Errorf("failed to load unread messages of user %d: %w", user.ID, err)
+		}
+
+		digest, ok := BuildDigest(leads, messages)
+		if !ok {
+			continue
+		}
+		// A suppressed or opted-out address still moves the watermark, or the
+		// same items would be retried every pass
+		err := emails.SendUnreadDigest(user, digest)
+		if err != nil && !errors.Is(err, auth.ErrEmailSuppressed) && !errors.Is(err, auth.ErrEmailOptedOut) {
+			return result, fmt.Errorf("failed to email user %d: %w", user.ID, err)
+		}
+
+		// Items are newest first, so the first of each is the new watermark
+		next := models.NotificationDigest{UserID: user.ID, LastLeadID: last.LastLeadID, LastMessageID: last.LastMessageID, SentAt: now}
+		if len(leads) > 0 {
+			next.LastLeadID = leads[0].ID
+		}
+		if len(messages) > 0 {
+			next.LastMessageID = messages[0].ID
+		}
+		if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&next).Error; err != nil {
+			return result, fmt.Errorf("failed to record digest of user %d: %w", user.ID, err)
+		}
+		result.Sent++
+	}
+	return result, nil
+}
+
+// RunDigests sends the due unread digests every interval until ctx is cancelled.
+func RunDigests(ctx context.Context, db *gorm.DB, emails *auth.EmailService, log *zap.Logger, every, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := SendDigests(db, emails, every, time.Now())
+		if err != nil {
+			log.Error("Failed to send unread digests", logger.Err(err))
+		}
+		if result.Sent > 0 {
+			log.Info("Sent unread digests", zap.Int("sent", result.Sent))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
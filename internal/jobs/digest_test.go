@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/models"
+)
+
+func TestBuildDigest(t *testing.T) {
+	received := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	named := models.User{Username: "bob", FirstName: "Bob", LastName: "Lin"}
+	unnamed := models.User{Username: "carol"}
+	cafe := &models.Listing{Title: "Corner cafe"}
+
+	var leads []models.Lead
+	for i := 7; i > 0; i-- {
+		leads = append(leads, models.Lead{Sender: named, Subject: fmt.Sprintf("Lead %d", i), Listing: cafe, CreatedAt: received})
+	}
+	messages := []models.Message{
+		{Sender: unnamed, Subject: "Still open?", CreatedAt: received},
+		{Sender: named, Subject: "Re: price"},
+	}
+
+	tests := []struct {
+		name     string
+		leads    []models.Lead
+		messages []models.Message
+		want     auth.Digest
+		ok       bool
+	}{
+		{name: "nothing unread"},
+		{name: "newest leads listed and the rest counted", leads: leads, ok: true, want: auth.Digest{
+			Leads: []auth.DigestItem{
+				{From: "Bob Lin", Subject: "Lead 7", Listing: "Corner cafe", ReceivedAt: received},
+				{From: "Bob Lin", Subject: "Lead 6", Listing: "Corner cafe", ReceivedAt: received},
+				{From: "Bob Lin", Subject: "Lead 5", Listing: "Corner cafe", ReceivedAt: received},
+				{From: "Bob Lin", Subject: "Lead 4", Listing: "Corner cafe", ReceivedAt: received},
+				{From: "Bob Lin", Subject: "Lead 3", Listing: "Corner cafe", ReceivedAt: received},
+			},
+			MoreLeads: 2,
+		}},
+		{name: "messages only, sender without a name", messages: messages, ok: true, want: auth.Digest{
+			Messages: []auth.DigestItem{
+				{From: "carol", Subject: "Still open?", ReceivedAt: received},
+				{From: "Bob Lin", Subject: "Re: price"},
+			},
+		}},
+		{name: "leads and messages", leads: leads[5:], messages: messages[:1], ok: true, want: auth.Digest{
+			Leads: []auth.DigestItem{
+				{From: "Bob Lin", Subject: "Lead 2", Listing: "Corner cafe", ReceivedAt: received},
+				{From: "Bob Lin", Subject: "Lead 1", Listing: "Corner cafe", ReceivedAt: received},
+			},
+			Messages: []auth.DigestItem{{From: "carol", Subject: "Still open?", ReceivedAt: received}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := BuildDigest(tt.leads, tt.messages)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildDigest = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSendDigests(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	every := 24 * time.Hour
+	db := newUnverifiedTest(t)
+	if err := db.AutoMigrate(&models.Lead{}, &models.NotificationDigest{}); err != nil {
+		t.Fatal(err)
+	}
+	emails, sent := newRecordingEmailService(t)
+
+	user := func(name string, edits map[string]interface{}) *models.User {
+		u := &models.User{Email: name + "@example.com", Username: name, EmailNotifications: true}
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+		// Booleans default to true in the schema, so false has to be set explicitly
+		if len(edits) > 0 {
+			db.Model(u).Updates(edits)
+		}
+		return u
+	}
+	buyer := user("buyer", nil)
+	seller := user("seller", nil)
+	optedOut := user("optedout", map[string]interface{}{"email_notifications": false})
+	bounced := user("bounced", map[string]interface{}{"email_undeliverable": true})
+	inactive := user("inactive", map[string]interface{}{"is_active": false})
+	user("quiet", nil)
+
+	lead := func(to uint, edits ...func(*models.Lead)) *models.Lead {
+		l := &models.Lead{SenderID: buyer.ID, ReceiverID: to, Subject: "Interested", Message: "Hi"}
+		for _, edit := range edits {
+			edit(l)
+		}
+		if err := db.Create(l).Error; err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	message := func(to uint) *models.Message {
+		m := &models.Message{SenderID: buyer.ID, ReceiverID: to, Content: "Still open?"}
+		if err := db.Create(m).Error; err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	lead(seller.ID, func(l *models.Lead) { l.IsRead = true })
+	lead(seller.ID, func(l *models.Lead) { l.IsSpam = true })
+	firstLead := lead(seller.ID)
+	firstMessage := message(seller.ID)
+	lead(optedOut.ID)
+	lead(bounced.ID)
+	lead(inactive.ID)
+
+	pass := func(at time.Time, want []string, sentCount int) {
+		t.Helper()
+		result, err := SendDigests(db, emails, every, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sent.recipients(); strings.Join(got, ",") != strings.Join(want, ",") || result.Sent != sentCount {
+			t.Errorf("pass at %v: %d sent, emails to %v; want %d and %v", at, result.Sent, got, sentCount, want)
+		}
+	}
+	watermark := func(u *models.User) models.NotificationDigest {
+		t.Helper()
+		var d models.NotificationDigest
+		db.Where("user_id = ?", u.ID).Limit(1).Find(&d)
+		return d
+	}
+
+	// The bounced address isn't emailed but still counts as covered
+	pass(now, []string{"seller@example.com"}, 2)
+	if got := watermark(seller); got.LastLeadID != firstLead.ID || got.LastMessageID != firstMessage.ID || !got.SentAt.Equal(now) {
+		t.Errorf("seller's digest %+v, want lead %d and message %d at %v", got, firstLead.ID, firstMessage.ID, now)
+	}
+	if got := watermark(optedOut); got.UserID != 0 {
+		t.Errorf("opted-out user has a digest record %+v", got)
+	}
+
+	// Nothing new: no digest even once the cadence allows one
+	pass(now.Add(every), nil, 0)
+
+	// A new lead waits for the cadence, then only it is covered
+	secondLead := lead(seller.ID)
+	pass(now.Add(2*time.Hour), nil, 0)
+	pass(now.Add(every), []string{"seller@example.com"}, 1)
+	if got := watermark(seller); got.LastLeadID != secondLead.ID || got.LastMessageID != firstMessage.ID {
+		t.Errorf("seller's digest %+v, want lead %d and message %d", got, secondLead.ID, firstMessage.ID)
+	}
+
+	// Reading everything leaves nothing to send
+	message(seller.ID)
+	db.Model(&models.Message{}).Where("receiver_id = ?", seller.ID).Update("is_read", true)
+	pass(now.Add(3*every), nil, 0)
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+	"trade_company/internal/moderation"
+	"trade_company/internal/storage"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ImageModerationInterval is how often pending listing images are checked
+const ImageModerationInterval = 30 * time.Second
+
+// imageModerationBatch caps how many images one pass checks
+const imageModerationBatch = 50
+
+// ImageModerationResult reports what one moderation pass did
+type ImageModerationResult struct {
+	Approved int
+	Flagged  int
+	Failed   int // Left pending to be retried on the next pass
+}
+
+// ModeratePendingImages runs the checker over the oldest pending images and marks
+// each approved or flagged. Images the checker can't handle stay pending.
+func ModeratePendingImages(ctx context.Context, db *gorm.DB, store *storage.Storage, checker moderation.Checker, log *zap.Logger) (ImageModerationResult, error) {
+	var result ImageModerationResult
+
+	var images []models.Image
+	if err := db.Where("moderation_status = ?", models.ImageModerationPending).
+		Order("id").
+		Limit(imageModerationBatch).
+		Find(&images).Error; err != nil {
+		return result, fmt.Errorf("failed to load pending images: %w", err)
+	}
+
+	for i := range images {
+		img := &images[i]
+		verdict, err := checkImage(ctx, store, checker, img)
+		if err != nil {
+			log.Warn("Image moderation check failed", zap.Uint("image_id", img.ID), logger.Err(err))
+			result.Failed++
+			continue
+		}
+
+		status := models.ImageModerationApproved
+		if verdict.Flagged {
+			status = models.ImageModerationFlagged
+		}
+		// Only pending rows are updated so an admin decision made meanwhile wins
+		if err := db.Model(&models.Image{}).
+			Where("id = ? AND moderation_status = ?", img.ID, models.ImageModerationPending).
+			Updates(map[string]interface{}{
+				"moderation_status": status,
+				"moderation_reason": verdict.Reason,
+				"moderated_at":      time.Now(),
+			}).Error; err != nil {
+			return result, fmt.Errorf("failed to record moderation verdict for image %d: %w", img.ID, err)
+		}
+		if verdict.Flagged {
+			result.Flagged++
+		} else {
+			result.Approved++
+		}
+	}
+
+	return result, nil
+}
+
+// checkImage reads an image from storage and passes it to the checker
+func checkImage(ctx context.Context, store *storage.Storage, checker moderation.Checker, img *models.Image) (moderation.Verdict, error) {
+	path, err := store.PublicPath(img.Filename)
+	if err != nil {
+		return moderation.Verdict{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return moderation.Verdict{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	return checker.Check(ctx, data)
+}
+
+// RunImageModeration checks pending images every interval until ctx is cancelled.
+func RunImageModeration(ctx context.Context, db *gorm.DB, store *storage.Storage, checker moderation.Checker, log *zap.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := ModeratePendingImages(ctx, db, store, checker, log)
+		if err != nil {
+			log.Error("Failed to moderate images", logger.Err(err))
+		}
+		if result.Approved > 0 || result.Flagged > 0 {
+			log.Info("Moderated listing images", zap.Int("approved", result.Approved), zap.Int("flagged", result.Flagged), zap.Int("failed", result.Failed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"trade_company/internal/models"
+	"trade_company/internal/moderation"
+
+	"go.uber.org/zap"
+)
+
+// fakeChecker flags or fails images by their contents and approves the rest
+type fakeChecker struct {
+	checked int
+}
+
+func (f *fakeChecker) Check(_ context.Context, image []byte) (moderation.Verdict, error) {
+	f.checked++
+	switch string(image) {
+	case "nsfw":
+		return moderation.Verdict{Flagged: true, Reason: "adult content"}, nil
+	case "broken":
+		return moderation.Verdict{}, errors.New("vision API unavailable")
+	}
+	return moderation.Verdict{}, nil
+}
+
+func TestModeratePendingImages(t *testing.T) {
+	db, store := newCleanupTest(t)
+	owner := models.User{Email: "seller@example.com", Username: "seller"}
+	db.Create(&owner)
+	listing := models.Listing{Title: "Shop", Price: 1, OwnerID: owner.ID, Status: models.ListingStatusActive}
+	db.Create(&listing)
+
+	images := []struct {
+		filename, contents, status string
+	}{
+		{filename: "ok.jpg", contents: "storefront", status: models.ImageModerationPending},
+		{filename: "nsfw.jpg", contents: "nsfw", status: models.ImageModerationPending},
+		{filename: "broken.jpg", contents: "broken", status: models.ImageModerationPending},
+		{filename: "missing.jpg", status: models.ImageModerationPending},
+		// Already decided images are left alone
+		{filename: "approved.jpg", contents: "nsfw", status: models.ImageModerationApproved},
+		{filename: "flagged.jpg", contents: "storefront", status: models.ImageModerationFlagged},
+	}
+	ids := map[string]uint{}
+	for _, img := range images {
+		row := models.Image{ListingID: listing.ID, Filename: img.filename, URL: "/uploads/" + img.filename, ModerationStatus: img.status}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[img.filename] = row.ID
+		if img.contents != "" {
+			if err := os.WriteFile(filepath.Join(store.PublicDir, img.filename), []byte(img.contents), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	checker := &fakeChecker{}
+	result, err := ModeratePendingImages(context.Background(), db, store, checker, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (ImageModerationResult{Approved: 1, Flagged: 1, Failed: 2}) {
+		t.Errorf("result %+v, want 1 approved, 1 flagged, 2 failed", result)
+	}
+	// The missing file fails before it reaches the checker
+	if checker.checked != 3 {
+		t.Errorf("checker saw %d images, want 3", checker.checked)
+	}
+
+	want := map[string]string{
+		"ok.jpg":       models.ImageModerationApproved,
+		"nsfw.jpg":     models.ImageModerationFlagged,
+		"broken.jpg":   models.ImageModerationPending,
+		"missing.jpg":  models.ImageModerationPending,
+		"approved.jpg": models.ImageModerationApproved,
+		"flagged.jpg":  models.ImageModerationFlagged,
+	}
+	for filename, status := range want {
+		var stored models.Image
+		db.First(&stored, ids[filename])
+		if stored.ModerationStatus != status {
+			t.Errorf("%s is %q, want %q", filename, stored.ModerationStatus, status)
+		}
+		if decided := stored.ModeratedAt != nil; decided != (filename == "ok.jpg" || filename == "nsfw.jpg") {
+			t.Errorf("%s moderated_at %v", filename, stored.ModeratedAt)
+		}
+	}
+	var flagged models.Image
+	db.First(&flagged, ids["nsfw.jpg"])
+	if flagged.ModerationReason != "adult content" {
+		t.Errorf("flagged reason %q", flagged.ModerationReason)
+	}
+
+	// Failures are retried on the next pass and nothing else is checked again
+	checker.checked = 0
+	result, err = ModeratePendingImages(context.Background(), db, store, checker, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (ImageModerationResult{Failed: 2}) || checker.checked != 1 {
+		t.Errorf("second pass %+v after %d checks, want 2 failed after 1", result, checker.checked)
+	}
+}
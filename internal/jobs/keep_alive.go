@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// KeepAliveInterval is how often listing confirmations are sent and expired
+const KeepAliveInterval = time.Hour
+
+// KeepAliveResult reports what one keep-alive pass did
+type KeepAliveResult struct {
+	Notified int // Sellers emailed
+	Archived int // Listings archived for lack of an answer
+}
+
+// HashConfirmationToken returns the stored form of an emailed confirmation token
+func HashConfirmationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestListingConfirmations queues a confirmation for each of the user's active
+// listings that doesn't already have an open one, and returns how many were queued.
+// The keep-alive job picks them up and emails the seller.
+func RequestListingConfirmations(db *gorm.DB, userID uint) (int, error) {
+	var listings []models.Listing
+	if err := db.Select("id").
+		Where("owner_id = ? AND status = ?", userID, models.ListingStatusActive).
+		Where("NOT EXISTS (SELECT 1 FROM listing_confirmations lc WHERE lc.listing_id = listings.id AND lc.resolution = '')").
+		Find(&listings).Error; err != nil {
+		return 0, err
+	}
+	if len(listings) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]models.ListingConfirmation, len(listings))
+	for i, l := range listings {
+		rows[i] = models.ListingConfirmation{ListingID: l.ID, UserID: userID}
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// ResolveListingConfirmation records the outcome of a confirmation. Sold listings
+// are marked sold and archived ones are made inactive.
+func ResolveListingConfirmation(db *gorm.DB, conf *models.ListingConfirmation, resolution string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if resolution != models.ListingConfirmationConfirmed {
+			// Loaded first so the listing count hooks see the status change
+			var listing models.Listing
+			err := tx.Where("id = ? AND status = ?", conf.ListingID, models.ListingStatusActive).First(&listing).Error
+			if err == nil {
+				status := models.ListingStatusInactive
+				if resolution == models.ListingConfirmationSold {
+					status = models.ListingStatusSold
+				}
+				err = tx.Model(&listing).Update("status", status).Error
+			} else if errors.Is(err, gorm.ErrRecordNotFound) {
+				err = nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		conf.Resolution = resolution
+		conf.ResolvedAt = &now
+		return tx.Model(conf).Updates(map[string]interface{}{
+			"resolution":  resolution,
+			"resolved_at": now,
+		}).Error
+	})
+}
+
+// ProcessListingConfirmations emails sellers their queued confirmations, one
+// email per seller, and archives listings whose confirmation went unanswered
+// past the deadline. The deadline is set when the email goes out, so a stalled
+// job never shortens the time a seller has to answer.
+func ProcessListingConfirmations(db *gorm.DB, emails *auth.EmailService, grace time.Duration, now time.Time) (KeepAliveResult, error) {
+	var result KeepAliveResult
+
+	var queued []models.ListingConfirmation
+	if err := db.Preload("Listing").
+		Where("notified_at IS NULL AND resolution = ''").
+		Order("user_id, id").
+		Find(&queued).Error; err != nil {
+		return result, fmt.Errorf("failed to load queued confirmations: %w", err)
+	}
+
+	byUser := make(map[uint][]*models.ListingConfirmation)
+	var userIDs []uint
+	for i := range queued {
+		conf := &queued[i]
+		// Listings taken down since the seller logged in need no answer
+		if conf.Listing.Status != models.ListingStatusActive {
+			if err := db.Delete(conf).Error; err != nil {
+				return result, fmt.Errorf("failed to drop confirmation %d: %w", conf.ID, err)
+			}
+			continue
+		}
+		if _, ok := byUser[conf.UserID]; !ok {
+			userIDs = append(userIDs, conf.UserID)
+		}
+		byUser[conf.UserID] = append(byUser[conf.UserID], conf)
+	}
+
+	deadline := now.Add(grace)
+	for _, userID := range userIDs {
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			return result, fmt.Errorf("failed to load user %d: %w", userID, err)
+		}
+
+		confs := byUser[userID]
+		links := make([]auth.ListingConfirmationLink, len(confs))
+		for i, conf := range confs {
+			token := emails.GenerateListingConfirmationToken()
+			hash := HashConfirmationToken(token)
+			if err := db.Model(conf).Updates(map[string]interface{}{
+				"token_hash":  hash,
+				"notified_at": now,
+				"deadline":    deadline,
+			}).Error; err != nil {
+				return result, fmt.Errorf("failed to record confirmation %d: %w", conf.ID, err)
+			}
+			links[i] = auth.ListingConfirmationLink{Title: conf.Listing.Title, Token: token}
+		}
+
+		// The confirmations also show on the dashboard, so a suppressed address
+		// still counts as notified
+		if err := emails.SendListingConfirmationRequest(&user, links, deadline); err != nil && !errors.Is(err, auth.ErrEmailSuppressed) {
+			return result, fmt.Errorf("failed to email user %d: %w", userID, err)
+		}
+		result.Notified++
+	}
+
+	var expired []models.ListingConfirmation
+	if err := db.Where("resolution = '' AND deadline <= ?", now).Find(&expired).Error; err != nil {
+		return result, fmt.Errorf("failed to load expired confirmations: %w", err)
+	}
+	for i := range expired {
+		if err := ResolveListingConfirmation(db, &expired[i], models.ListingConfirmationArchived, now); err != nil {
+			return result, fmt.Errorf("failed to archive listing %d: %w", expired[i].ListingID, err)
+		}
+		result.Archived++
+	}
+
+	return result, nil
+}
+
+// RunKeepAlive sends and expires listing confirmations every interval until ctx is cancelled.
+func RunKeepAlive(ctx context.Context, db *gorm.DB, emails *auth.EmailService, log *zap.Logger, grace, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := ProcessListingConfirmations(db, emails, grace, time.Now())
+		if err != nil {
+			log.Error("Failed to process listing confirmations", logger.Err(err))
+		}
+		if result.Notified > 0 || result.Archived > 0 {
+			log.Info("Processed listing confirmations", zap.Int("notified", result.Notified), zap.Int("archived", result.Archived))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+)
+
+func TestProcessListingConfirmations(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	grace := 30 * 24 * time.Hour
+	db := newUnverifiedTest(t)
+	if err := db.AutoMigrate(&models.ListingConfirmation{}); err != nil {
+		t.Fatal(err)
+	}
+	emails, sent := newRecordingEmailService(t)
+
+	alice := models.User{Email: "alice@example.com", Username: "alice", EmailNotifications: true}
+	bob := models.User{Email: "bob@example.com", Username: "bob", EmailNotifications: true, EmailUndeliverable: true}
+	for _, u := range []*models.User{&alice, &bob} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	listing := func(owner uint, title string) *models.Listing {
+		l := &models.Listing{Title: title, Price: 1, OwnerID: owner, Status: models.ListingStatusActive}
+		if err := db.Create(l).Error; err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	kept := listing(alice.ID, "Kept cafe")
+	forgotten := listing(alice.ID, "Forgotten bakery")
+	bobs := listing(bob.ID, "Bob's bar")
+	takenDown := listing(bob.ID, "Closed shop")
+	for _, id := range []uint{alice.ID, bob.ID} {
+		if _, err := RequestListingConfirmations(db, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Taken down between the login and the job: nothing to ask about
+	db.Model(takenDown).Update("status", models.ListingStatusInactive)
+
+	result, err := ProcessListingConfirmations(db, emails, grace, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bob's address bounces, but the dashboard still shows him the question
+	if result != (KeepAliveResult{Notified: 2}) {
+		t.Errorf("first pass %+v, want 2 notified", result)
+	}
+	if got := sent.recipients(); strings.Join(got, ",") != "alice@example.com" {
+		t.Errorf("emails to %v, want alice only", got)
+	}
+	var confs []models.ListingConfirmation
+	db.Order("listing_id").Find(&confs)
+	if len(confs) != 3 {
+		t.Fatalf("%d confirmations, want 3 after dropping the taken-down listing", len(confs))
+	}
+	hashes := map[string]bool{}
+	for _, conf := range confs {
+		if conf.TokenHash == nil || conf.NotifiedAt == nil || conf.Deadline == nil || !conf.Deadline.Equal(now.Add(grace)) {
+			t.Fatalf("confirmation %+v not sent with a token and deadline", conf)
+		}
+		hashes[*conf.TokenHash] = true
+	}
+	if len(hashes) != 3 {
+		t.Error("confirmations share a token")
+	}
+
+	// Alice keeps one listing; the rest go unanswered
+	if err := ResolveListingConfirmation(db, &confs[0], models.ListingConfirmationConfirmed, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody is emailed twice, and nothing is archived before the deadline
+	result, err = ProcessListingConfirmations(db, emails, grace, now.Add(grace-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (KeepAliveResult{}) || len(sent.recipients()) != 0 {
+		t.Errorf("pass before the deadline %+v", result)
+	}
+
+	result, err = ProcessListingConfirmations(db, emails, grace, now.Add(grace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (KeepAliveResult{Archived: 2}) {
+		t.Errorf("pass at the deadline %+v, want 2 archived", result)
+	}
+	want := map[uint]models.ListingStatus{
+		kept.ID:      models.ListingStatusActive,
+		forgotten.ID: models.ListingStatusInactive,
+		bobs.ID:      models.ListingStatusInactive,
+	}
+	for id, status := range want {
+		var l models.Listing
+		db.First(&l, id)
+		if l.Status != status {
+			t.Errorf("listing %q is %q, want %q", l.Title, l.Status, status)
+		}
+	}
+	var archived int64
+	db.Model(&models.ListingConfirmation{}).Where("resolution = ?", models.ListingConfirmationArchived).Count(&archived)
+	if archived != 2 {
+		t.Errorf("%d confirmations archived, want 2", archived)
+	}
+}
+
+func TestResolveListingConfirmationSold(t *testing.T) {
+	db := newUnverifiedTest(t)
+	if err := db.AutoMigrate(&models.ListingConfirmation{}); err != nil {
+		t.Fatal(err)
+	}
+	owner := models.User{Email: "seller@example.com", Username: "seller"}
+	db.Create(&owner)
+	listing := models.Listing{Title: "Shop", Price: 1, OwnerID: owner.ID, Status: models.ListingStatusActive}
+	db.Create(&listing)
+	conf := models.ListingConfirmation{ListingID: listing.ID, UserID: owner.ID}
+	db.Create(&conf)
+
+	now := time.Now()
+	if err := ResolveListingConfirmation(db, &conf, models.ListingConfirmationSold, now); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&listing, listing.ID)
+	if listing.Status != models.ListingStatusSold {
+		t.Errorf("listing %q, want sold", listing.Status)
+	}
+	if conf.Resolution != models.ListingConfirmationSold || conf.ResolvedAt == nil {
+		t.Errorf("confirmation %+v", conf)
+	}
+	if HashConfirmationToken("a") == HashConfirmationToken("b") || len(HashConfirmationToken("a")) != 64 {
+		t.Error("token hashes aren't distinct SHA-256 hex")
+	}
+}
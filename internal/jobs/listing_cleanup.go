@@ -0,0 +1,150 @@
+// Package jobs contains background work that runs alongside the HTTP server.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ListingCleanupInterval is how often expired pending deletions are finalized and
+// listings past their expiry are taken down
+const ListingCleanupInterval = time.Hour
+
+// FinalizeListingDeletion soft deletes a listing and removes its image and document files and records.
+func FinalizeListingDeletion(db *gorm.DB, store *storage.Storage, listing *models.Listing) error {
+	var images []models.Image
+	if err := db.Where("listing_id = ?", listing.ID).Find(&images).Error; err != nil {
+		return fmt.Errorf("failed to load listing images: %w", err)
+	}
+	var documents []models.ListingDocument
+	if err := db.Where("listing_id = ?", listing.ID).Find(&documents).Error; err != nil {
+		return fmt.Errorf("failed to load listing documents: %w", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("listing_id = ?", listing.ID).Delete(&models.Image{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("listing_id = ?", listing.ID).Delete(&models.ListingDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(listing).Updates(map[string]interface{}{
+			"status":       models.ListingStatusDeleted,
+			"delete_after": nil,
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finalize listing deletion: %w", err)
+	}
+
+	// Files go last so a failed transaction never leaves records pointing at missing images
+	for _, img := range images {
+		if err := RemoveImageFile(db, store, img.Filename); err != nil {
+			return err
+		}
+	}
+	for _, doc := range documents {
+		if err := RemoveDocumentFile(db, store, doc.StorageKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveImageFile deletes an image file once no image record uses it. Files are
+// named by content hash, so identical uploads share one file.
+func RemoveImageFile(db *gorm.DB, store *storage.Storage, filename string) error {
+	var count int64
+	if err := db.Model(&models.Image{}).Where("filename = ?", filename).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check image references: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	return store.RemovePublic(filename)
+}
+
+// RemoveDocumentFile is RemoveImageFile for listing documents
+func RemoveDocumentFile(db *gorm.DB, store *storage.Storage, key string) error {
+	var count int64
+	if err := db.Model(&models.ListingDocument{}).Where("storage_key = ?", key).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check document references: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	return store.RemovePrivate(key)
+}
+
+// PurgeExpiredListings finalizes every pending deletion whose undo window has passed
+// and returns how many listings were deleted.
+func PurgeExpiredListings(db *gorm.DB, store *storage.Storage, log *zap.Logger) int {
+	var listings []models.Listing
+	if err := db.Where("status = ? AND delete_after <= ?", models.ListingStatusPendingDelete, time.Now()).
+		Find(&listings).Error; err != nil {
+		log.Error("Failed to load expired listings", logger.Err(err))
+		return 0
+	}
+
+	purged := 0
+	for i := range listings {
+		if err := FinalizeListingDeletion(db, store, &listings[i]); err != nil {
+			log.Error("Failed to purge listing", zap.Uint("listing_id", listings[i].ID), logger.Err(err))
+			continue
+		}
+		purged++
+	}
+	return purged
+}
+
+// DeactivateExpiredListings makes active listings whose expiry has passed
+// inactive and returns how many were taken down. Owners renew them to go live again.
+func DeactivateExpiredListings(db *gorm.DB, now time.Time, log *zap.Logger) int {
+	var listings []models.Listing
+	if err := db.Select("id", "status", "visibility", "category", "industry").
+		Where("status = ? AND expires_at <= ?", models.ListingStatusActive, now).
+		Find(&listings).Error; err != nil {
+		log.Error("Failed to load expired active listings", logger.Err(err))
+		return 0
+	}
+
+	deactivated := 0
+	for i := range listings {
+		// Updated one by one so the listing count hooks see each status change
+		if err := db.Model(&listings[i]).Update("status", models.ListingStatusInactive).Error; err != nil {
+			log.Error("Failed to deactivate expired listing", zap.Uint("listing_id", listings[i].ID), logger.Err(err))
+			continue
+		}
+		deactivated++
+	}
+	return deactivated
+}
+
+// RunListingCleanup purges expired listings every interval until ctx is cancelled.
+func RunListingCleanup(ctx context.Context, db *gorm.DB, store *storage.Storage, log *zap.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n := PurgeExpiredListings(db, store, log); n > 0 {
+			log.Info("Purged expired listings", zap.Int("count", n))
+		}
+		if n := DeactivateExpiredListings(db, time.Now(), log); n > 0 {
+			log.Info("Deactivated listings past their expiry", zap.Int("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
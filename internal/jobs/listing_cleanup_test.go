@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+	"trade_company/internal/storage"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newCleanupTest(t *testing.T) (*gorm.DB, *storage.Storage) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Listing{}, &models.ListingCount{}, &models.Image{}, &models.ListingDocument{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	store := storage.New(&config.Config{PublicUploadDir: t.TempDir(), PrivateUploadDir: t.TempDir()})
+	return db, store
+}
+
+func TestFinalizeListingDeletion(t *testing.T) {
+	tests := []struct {
+		name string
+		// Files referenced by the deleted listing and by another listing
+		images, otherImages []string
+		docs, otherDocs     []string
+		wantKept            []string
+	}{
+		{name: "unshared files are removed", images: []string{"a.jpg", "b.jpg"}, docs: []string{"a.pdf"}},
+		{name: "duplicate upload within the listing", images: []string{"a.jpg", "a.jpg"}},
+		{name: "image still used elsewhere", images: []string{"a.jpg", "b.jpg"}, otherImages: []string{"b.jpg"}, wantKept: []string{"b.jpg"}},
+		{name: "document still used elsewhere", docs: []string{"a.pdf"}, otherDocs: []string{"a.pdf"}, wantKept: []string{"a.pdf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, store := newCleanupTest(t)
+			owner := models.User{Email: "seller@example.com", Username: "seller"}
+			if err := db.Create(&owner).Error; err != nil {
+				t.Fatal(err)
+			}
+			listing := models.Listing{Title: "Shop", Price: 1, OwnerID: owner.ID, Status: models.ListingStatusPendingDelete}
+			other := models.Listing{Title: "Other shop", Price: 1, OwnerID: owner.ID, Status: models.ListingStatusActive}
+			for _, l := range []*models.Listing{&listing, &other} {
+				if err := db.Create(l).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			add := func(listingID uint, images, docs []string) {
+				for _, name := range images {
+					if err := db.Create(&models.Image{ListingID: listingID, Filename: name, URL: "/uploads/" + name}).Error; err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(filepath.Join(store.PublicDir, name), []byte(name), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for _, key := range docs {
+					if err := db.Create(&models.ListingDocument{ListingID: listingID, Filename: key, MimeType: "application/pdf", StorageKey: key}).Error; err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(filepath.Join(store.PrivateDir, key), []byte(key), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			add(listing.ID, tt.images, tt.docs)
+			add(other.ID, tt.otherImages, tt.otherDocs)
+
+			if err := FinalizeListingDeletion(db, store, &listing); err != nil {
+				t.Fatalf("FinalizeListingDeletion: %v", err)
+			}
+
+			kept := map[string]bool{}
+			for _, name := range tt.wantKept {
+				kept[name] = true
+			}
+			for _, f := range append(append([]string{}, tt.images...), tt.docs...) {
+				dir := store.PublicDir
+				if filepath.Ext(f) == ".pdf" {
+					dir = store.PrivateDir
+				}
+				_, err := os.Stat(filepath.Join(dir, f))
+				if exists := err == nil; exists != kept[f] {
+					t.Errorf("file %s exists %v, want %v", f, exists, kept[f])
+				}
+			}
+
+			var images, docs int64
+			db.Model(&models.Image{}).Where("listing_id = ?", listing.ID).Count(&images)
+			db.Model(&models.ListingDocument{}).Where("listing_id = ?", listing.ID).Count(&docs)
+			if images != 0 || docs != 0 {
+				t.Errorf("%d images and %d documents left on the deleted listing", images, docs)
+			}
+			var after models.Listing
+			db.First(&after, listing.ID)
+			if after.Status != models.ListingStatusDeleted || after.DeleteAfter != nil {
+				t.Errorf("listing status %q, delete_after %v", after.Status, after.DeleteAfter)
+			}
+		})
+	}
+}
+
+func TestPurgeExpiredListings(t *testing.T) {
+	db, store := newCleanupTest(t)
+	owner := models.User{Email: "seller@example.com", Username: "seller"}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	expired := models.Listing{Title: "Expired", Price: 1, OwnerID: owner.ID, Status: models.ListingStatusPendingDelete, DeleteAfter: &past}
+	pending := models.Listing{Title: "Pending", Price: 1, OwnerID: owner.ID, Status: models.ListingStatusPendingDelete, DeleteAfter: &future}
+	for _, l := range []*models.Listing{&expired, &pending} {
+		if err := db.Create(l).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := PurgeExpiredListings(db, store, zap.NewNop()); n != 1 {
+		t.Errorf("purged %d listings, want 1", n)
+	}
+	for _, tc := range []struct {
+		listing *models.Listing
+		want    models.ListingStatus
+	}{{&expired, models.ListingStatusDeleted}, {&pending, models.ListingStatusPendingDelete}} {
+		var l models.Listing
+		db.First(&l, tc.listing.ID)
+		if l.Status != tc.want {
+			t.Errorf("%s: status %q, want %q", l.Title, l.Status, tc.want)
+		}
+	}
+}
+
+func TestDeactivateExpiredListings(t *testing.T) {
+	db, _ := newCleanupTest(t)
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	listings := []struct {
+		title     string
+		status    models.ListingStatus
+		expiresAt *time.Time
+		want      models.ListingStatus
+	}{
+		{title: "expired", status: models.ListingStatusActive, expiresAt: &past, want: models.ListingStatusInactive},
+		{title: "expires now", status: models.ListingStatusActive, expiresAt: &now, want: models.ListingStatusInactive},
+		{title: "not yet", status: models.ListingStatusActive, expiresAt: &future, want: models.ListingStatusActive},
+		{title: "never expires", status: models.ListingStatusActive, want: models.ListingStatusActive},
+		{title: "already sold", status: models.ListingStatusSold, expiresAt: &past, want: models.ListingStatusSold},
+	}
+	ids := map[string]uint{}
+	for _, l := range listings {
+		listing := models.Listing{Title: l.title, Price: 1, OwnerID: 1, Status: l.status, ExpiresAt: l.expiresAt}
+		if err := db.Create(&listing).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[l.title] = listing.ID
+	}
+
+	if n := DeactivateExpiredListings(db, now, zap.NewNop()); n != 2 {
+		t.Errorf("deactivated %d, want 2", n)
+	}
+	for _, l := range listings {
+		var stored models.Listing
+		db.First(&stored, ids[l.title])
+		if stored.Status != l.want {
+			t.Errorf("%s: status %q, want %q", l.title, stored.Status, l.want)
+		}
+	}
+	if n := DeactivateExpiredListings(db, now, zap.NewNop()); n != 0 {
+		t.Errorf("second pass deactivated %d", n)
+	}
+}
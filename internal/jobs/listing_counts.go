@@ -0,0 +1,143 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/metrics"
+	"trade_company/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ListingCountReconcileInterval is how often listing_counts is rebuilt from the listings table
+const ListingCountReconcileInterval = 24 * time.Hour
+
+// CountDrift is a listing_counts row that disagreed with the listings table
+type CountDrift struct {
+	Dimension string `json:"dimension"`
+	Value     string `json:"value"`
+	Stored    int    `json:"stored"`
+	Actual    int    `json:"actual"`
+}
+
+// Diff is how far the stored total was off
+func (d CountDrift) Diff() int {
+	if d.Stored > d.Actual {
+		return d.Stored - d.Actual
+	}
+	return d.Actual - d.Stored
+}
+
+// DetectCountDrift compares stored totals with actual ones and returns every mismatch.
+// A key missing on either side counts as zero there.
+func DetectCountDrift(stored, actual []models.ListingCount) []CountDrift {
+	type key struct{ dimension, value string }
+	totals := make(map[key]*CountDrift)
+	for _, c := range stored {
+		totals[key{c.Dimension, c.Value}] = &CountDrift{Dimension: c.Dimension, Value: c.Value, Stored: c.Total}
+	}
+	for _, c := range actual {
+		k := key{c.Dimension, c.Value}
+		if d, ok := totals[k]; ok {
+			d.Actual = c.Total
+		} else {
+			totals[k] = &CountDrift{Dimension: c.Dimension, Value: c.Value, Actual: c.Total}
+		}
+	}
+
+	var drift []CountDrift
+	for _, d := range totals {
+		if d.Stored != d.Actual {
+			drift = append(drift, *d)
+		}
+	}
+	return drift
+}
+
+// actualListingCounts recomputes the active, public listing counts from the listings table
+func actualListingCounts(db *gorm.DB) ([]models.ListingCount, error) {
+	var counts []models.ListingCount
+	for _, dimension := range []string{models.ListingCountCategory, models.ListingCountIndustry} {
+		var rows []models.ListingCount
+		if err := db.Model(&models.Listing{}).
+			Select("? AS dimension, "+dimension+" AS value, COUNT(*) AS total", dimension).
+			Where("status = ? AND visibility = ? AND "+dimension+" <> ''", models.ListingStatusActive, models.ListingVisibilityPublic).
+			Group(dimension).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		counts = append(counts, rows...)
+	}
+	return counts, nil
+}
+
+// ReconcileListingCounts rebuilds listing_counts from the listings table and returns
+// the rows that had drifted from the incrementally maintained totals.
+func ReconcileListingCounts(db *gorm.DB) ([]CountDrift, error) {
+	var drift []CountDrift
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var stored []models.ListingCount
+		if err := tx.Find(&stored).Error; err != nil {
+			return err
+		}
+		actual, err := actualListingCounts(tx)
+		if err != nil {
+			return err
+		}
+
+		drift = DetectCountDrift(stored, actual)
+		if len(drift) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("1 = 1").Delete(&models.ListingCount{}).Error; err != nil {
+			return err
+		}
+		if len(actual) == 0 {
+			return nil
+		}
+		return tx.Create(&actual).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile listing counts: %w", err)
+	}
+	return drift, nil
+}
+
+// RunListingCountReconciliation reconciles listing_counts every interval until ctx is
+// cancelled, logging an error for any row off by more than threshold.
+func RunListingCountReconciliation(ctx context.Context, db *gorm.DB, log *zap.Logger, interval time.Duration, threshold int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		drift, err := ReconcileListingCounts(db)
+		if err != nil {
+			log.Error("Listing count reconciliation failed", logger.Err(err))
+		}
+		for _, d := range drift {
+			metrics.IncListingCountDrift()
+			if d.Diff() > threshold {
+				log.Error("Listing count drift above threshold",
+					zap.String("dimension", d.Dimension),
+					zap.String("value", d.Value),
+					zap.Int("stored", d.Stored),
+					zap.Int("actual", d.Actual),
+					zap.Int("threshold", threshold))
+			}
+		}
+		if len(drift) > 0 {
+			log.Info("Reconciled listing counts", zap.Int("rows_corrected", len(drift)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"trade_company/internal/metrics"
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newListingCountTest(t *testing.T) (*gorm.DB, *models.User) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Listing{}, &models.ListingCount{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	owner := &models.User{Email: "seller@example.com", Username: "seller"}
+	if err := db.Create(owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, owner
+}
+
+// createCountedListing creates an active, public listing
+func createCountedListing(t *testing.T, db *gorm.DB, ownerID uint, category, industry string) *models.Listing {
+	t.Helper()
+	listing := &models.Listing{
+		Title: "Shop", Price: 1, OwnerID: ownerID, Category: category, Industry: industry,
+		Status: models.ListingStatusActive, Visibility: models.ListingVisibilityPublic,
+	}
+	if err := db.Create(listing).Error; err != nil {
+		t.Fatal(err)
+	}
+	return listing
+}
+
+// storedCounts returns the non-zero listing_counts totals keyed by dimension:value
+func storedCounts(t *testing.T, db *gorm.DB) map[string]int {
+	t.Helper()
+	var counts []models.ListingCount
+	if err := db.Where("total <> 0").Find(&counts).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]int)
+	for _, c := range counts {
+		out[c.Dimension+":"+c.Value] = c.Total
+	}
+	return out
+}
+
+func TestDetectCountDrift(t *testing.T) {
+	count := func(dimension, value string, total int) models.ListingCount {
+		return models.ListingCount{Dimension: dimension, Value: value, Total: total}
+	}
+	tests := []struct {
+		name           string
+		stored, actual []models.ListingCount
+		want           []CountDrift
+	}{
+		{name: "in sync",
+			stored: []models.ListingCount{count("category", "food", 2), count("industry", "food", 1)},
+			actual: []models.ListingCount{count("industry", "food", 1), count("category", "food", 2)}},
+		{name: "total off",
+			stored: []models.ListingCount{count("category", "food", 5)},
+			actual: []models.ListingCount{count("category", "food", 2)},
+			want:   []CountDrift{{Dimension: "category", Value: "food", Stored: 5, Actual: 2}}},
+		{name: "missing row",
+			actual: []models.ListingCount{count("industry", "retail", 3)},
+			want:   []CountDrift{{Dimension: "industry", Value: "retail", Actual: 3}}},
+		{name: "stale row",
+			stored: []models.ListingCount{count("category", "gone", 1), count("category", "empty", 0)},
+			want:   []CountDrift{{Dimension: "category", Value: "gone", Stored: 1}}},
+		{name: "same value in another dimension",
+			stored: []models.ListingCount{count("category", "food", 1)},
+			actual: []models.ListingCount{count("industry", "food", 1)},
+			want: []CountDrift{
+				{Dimension: "category", Value: "food", Stored: 1},
+				{Dimension: "industry", Value: "food", Actual: 1},
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectCountDrift(tt.stored, tt.actual)
+			sort.Slice(got, func(i, j int) bool { return got[i].Dimension < got[j].Dimension })
+			if len(got) != len(tt.want) {
+				t.Fatalf("drift %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("drift %+v, want %+v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListingCountHooks(t *testing.T) {
+	db, owner := newListingCountTest(t)
+	a := createCountedListing(t, db, owner.ID, "food", "restaurant")
+	b := createCountedListing(t, db, owner.ID, "food", "cafe")
+	c := createCountedListing(t, db, owner.ID, "retail", "")
+
+	// Unpublishing, recategorizing, hiding, selling and deleting each move the counts
+	db.Model(a).Update("status", models.ListingStatusInactive)
+	db.Model(b).Update("category", "retail")
+	db.Model(c).Update("visibility", models.ListingVisibilityPrivate)
+	createCountedListing(t, db, owner.ID, "food", "restaurant")
+	sold := createCountedListing(t, db, owner.ID, "food", "restaurant")
+	db.Model(sold).Update("status", models.ListingStatusSold)
+	deleted := createCountedListing(t, db, owner.ID, "services", "")
+	db.Delete(deleted)
+
+	want := map[string]int{"category:food": 1, "category:retail": 1, "industry:cafe": 1, "industry:restaurant": 1}
+	got := storedCounts(t, db)
+	if len(got) != len(want) {
+		t.Errorf("counts %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %d, want %d", k, got[k], v)
+		}
+	}
+
+	drift, err := ReconcileListingCounts(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drift) != 0 {
+		t.Errorf("hooks drifted from the listings table: %+v", drift)
+	}
+}
+
+func TestReconcileListingCounts(t *testing.T) {
+	db, owner := newListingCountTest(t)
+	createCountedListing(t, db, owner.ID, "food", "restaurant")
+	createCountedListing(t, db, owner.ID, "food", "restaurant")
+
+	// Bulk updates bypass the hooks and leave the counts stale
+	db.Model(&models.ListingCount{}).Where("dimension = ? AND value = ?", "category", "food").Update("total", 7)
+	db.Create(&models.ListingCount{Dimension: "category", Value: "ghost", Total: 1})
+	db.Where("dimension = ?", "industry").Delete(&models.ListingCount{})
+
+	drift, err := ReconcileListingCounts(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drift) != 3 {
+		t.Errorf("%d drifted rows, want 3: %+v", len(drift), drift)
+	}
+	got := storedCounts(t, db)
+	if len(got) != 2 || got["category:food"] != 2 || got["industry:restaurant"] != 2 {
+		t.Errorf("counts after reconciling %v, want food and restaurant at 2", got)
+	}
+
+	if drift, err := ReconcileListingCounts(db); err != nil || len(drift) != 0 {
+		t.Errorf("second reconcile drift %+v, err %v; want none", drift, err)
+	}
+}
+
+func TestListingCountDriftAlert(t *testing.T) {
+	db, owner := newListingCountTest(t)
+	createCountedListing(t, db, owner.ID, "food", "restaurant")
+	// One row off by 1, one off by 4; only the second is above the threshold
+	db.Model(&models.ListingCount{}).Where("value = ?", "food").Update("total", 2)
+	db.Model(&models.ListingCount{}).Where("value = ?", "restaurant").Update("total", 5)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	before := metrics.ListingCountDrift.Value()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	RunListingCountReconciliation(ctx, db, zap.New(core), ListingCountReconcileInterval, 3)
+
+	if got := metrics.ListingCountDrift.Value() - before; got != 2 {
+		t.Errorf("drift metric grew by %d, want 2", got)
+	}
+	alerts := logs.FilterMessage("Listing count drift above threshold").All()
+	if len(alerts) != 1 || alerts[0].ContextMap()["value"] != "restaurant" || alerts[0].Level != zapcore.ErrorLevel {
+		t.Errorf("alerts %+v, want one error for restaurant", alerts)
+	}
+	if got := storedCounts(t, db); got["category:food"] != 1 || got["industry:restaurant"] != 1 {
+		t.Errorf("counts %v not corrected", got)
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// OrphanBatchSize is how many orphaned rows are fixed per statement
+const OrphanBatchSize = 1000
+
+// Orphan cleanup modes
+const (
+	OrphanModeDelete   = "delete"   // Delete every orphaned row
+	OrphanModeReassign = "reassign" // Keep messages, leads and transactions by pointing them at another user
+)
+
+// OrphanOptions controls an orphan scan
+type OrphanOptions struct {
+	Mode       string
+	ReassignTo uint  // User that takes over missing senders, receivers, buyers and sellers in reassign mode
+	ActorID    *uint // Admin who started the run, for the audit log
+	DryRun     bool  // Only count the orphans
+}
+
+// OrphanResult reports orphans found per reference, keyed "table.column", and
+// what was done about them
+type OrphanResult struct {
+	Counts     map[string]int64 `json:"counts"`
+	Total      int64            `json:"total"`
+	Mode       string           `json:"mode"`
+	ReassignTo uint             `json:"reassign_to,omitempty"`
+	DryRun     bool             `json:"dry_run"`
+}
+
+// orphanRef is a reference column whose target row may be missing
+type orphanRef struct {
+	table  string
+	column string
+	parent string
+	// fix is "null" for optional references, which are cleared in either mode
+	// like ON DELETE SET NULL would have, and "user" for user references that
+	// reassign mode points at ReassignTo. Anything else is deleted.
+	fix string
+}
+
+// orphanRefs are the references checked. Foreign keys rule these out on a
+// healthy database, but seed resets and manual fixes with checks disabled
+// have left rows behind.
+var orphanRefs = []orphanRef{
+	{"images", "listing_id", "listings", ""},
+	{"favorites", "listing_id", "listings", ""},
+	{"favorites", "user_id", "users", ""},
+	{"messages", "sender_id", "users", "user"},
+	{"messages", "receiver_id", "users", "user"},
+	{"messages", "listing_id", "listings", "null"},
+	{"leads", "sender_id", "users", "user"},
+	{"leads", "receiver_id", "users", "user"},
+	{"leads", "listing_id", "listings", "null"},
+	{"transactions", "listing_id", "listings", ""},
+	{"transactions", "buyer_id", "users", "user"},
+	{"transactions", "seller_id", "users", "user"},
+}
+
+// FixOrphans finds rows referencing missing listings or users and, unless it is
+// a dry run, deletes or reassigns them. Each reference is scanned with an
+// anti-join walked in id batches, so large tables are never scanned or locked
+// in one statement. The summary of a real run is written to the audit log.
+func FixOrphans(db *gorm.DB, opts OrphanOptions) (OrphanResult, error) {
+	result := OrphanResult{Counts: map[string]int64{}, Mode: opts.Mode, DryRun: opts.DryRun}
+	switch opts.Mode {
+	case OrphanModeDelete:
+	case OrphanModeReassign:
+		if err := db.Select("id").First(&models.User{}, opts.ReassignTo).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return result, fmt.Errorf("reassign target user %d does not exist", opts.ReassignTo)
+			}
+			return result, fmt.Errorf("failed to load reassign target: %w", err)
+		}
+		result.ReassignTo = opts.ReassignTo
+	default:
+		return result, fmt.Errorf("unknown orphan mode %q", opts.Mode)
+	}
+
+	for _, ref := range orphanRefs {
+		n, err := fixOrphanRef(db, ref, opts)
+		result.Counts[ref.table+"."+ref.column] = n
+		result.Total += n
+		if err != nil {
+			return result, fmt.Errorf("%s.%s: %w", ref.table, ref.column, err)
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	details, _ := json.Marshal(result)
+	if err := db.Create(&models.AuditLog{UserID: opts.ActorID, Event: "orphan_cleanup", Details: string(details)}).Error; err != nil {
+		return result, fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return result, nil
+}
+
+// fixOrphanRef handles one reference and returns how many orphans it had
+func fixOrphanRef(db *gorm.DB, ref orphanRef, opts OrphanOptions) (int64, error) {
+	query := fmt.Sprintf(
+		"SELECT c.id FROM %[1]s c LEFT JOIN %[3]s p ON p.id = c.%[2]s WHERE c.%[2]s IS NOT NULL AND p.id IS NULL AND c.id > ? ORDER BY c.id LIMIT ?",
+		ref.table, ref.column, ref.parent)
+
+	var total int64
+	var lastID uint
+	for {
+		// Fixed rows no longer match, but walking by id also keeps a dry run moving
+		var ids []uint
+		if err := db.Raw(query, lastID, OrphanBatchSize).Scan(&ids).Error; err != nil {
+			return total, fmt.Errorf("failed to scan: %w", err)
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		lastID = ids[len(ids)-1]
+		total += int64(len(ids))
+
+		if !opts.DryRun {
+			rows := db.Table(ref.table).Where("id IN ?", ids)
+			var err error
+			switch {
+			case ref.fix == "null":
+				err = rows.Update(ref.column, nil).Error
+			case ref.fix == "user" && opts.Mode == OrphanModeReassign:
+				err = rows.Update(ref.column, opts.ReassignTo).Error
+			default:
+				err = rows.Delete(map[string]interface{}{}).Error
+			}
+			if err != nil {
+				return total, fmt.Errorf("failed to fix orphans: %w", err)
+			}
+		}
+
+		if len(ids) < OrphanBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// orphanedRows are seeded by seedOrphans: one row per reference except images,
+// which have more orphans than a batch
+var orphanedRows = map[string]int64{
+	"images.listing_id":       OrphanBatchSize + 1,
+	"favorites.listing_id":    1,
+	"favorites.user_id":       1,
+	"messages.sender_id":      1,
+	"messages.receiver_id":    0,
+	"messages.listing_id":     1,
+	"leads.sender_id":         0,
+	"leads.receiver_id":       1,
+	"leads.listing_id":        1,
+	"transactions.listing_id": 1,
+	"transactions.buyer_id":   1,
+	"transactions.seller_id":  0,
+}
+
+// seedOrphans creates a healthy row in each table plus rows pointing at
+// listing and user 999, which don't exist. It returns the seller, the buyer
+// and their listing.
+func seedOrphans(t *testing.T) (db *gorm.DB, seller, buyer models.User, listing models.Listing) {
+	t.Helper()
+	db, _ = newCleanupTest(t)
+	if err := db.AutoMigrate(&models.Favorite{}, &models.Message{}, &models.Lead{}, &models.Transaction{}, &models.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	seller = models.User{Email: "seller@example.com", Username: "seller"}
+	buyer = models.User{Email: "buyer@example.com", Username: "buyer"}
+	db.Create(&seller)
+	db.Create(&buyer)
+	listing = models.Listing{Title: "Shop", Price: 1, OwnerID: seller.ID, Status: models.ListingStatusActive}
+	db.Create(&listing)
+	missing := uint(999)
+
+	images := []models.Image{{ListingID: listing.ID, Filename: "kept.jpg"}}
+	for i := int64(0); i < orphanedRows["images.listing_id"]; i++ {
+		images = append(images, models.Image{ListingID: missing, Filename: "gone.jpg"})
+	}
+	rows := []interface{}{
+		&images,
+		&[]models.Favorite{
+			{UserID: buyer.ID, ListingID: listing.ID},
+			{UserID: buyer.ID, ListingID: missing},
+			{UserID: missing, ListingID: listing.ID},
+		},
+		&[]models.Message{
+			{SenderID: buyer.ID, ReceiverID: seller.ID, ListingID: &listing.ID, Content: "healthy"},
+			{SenderID: missing, ReceiverID: seller.ID, Content: "from a deleted user"},
+			{SenderID: buyer.ID, ReceiverID: seller.ID, ListingID: &missing, Content: "about a deleted listing"},
+		},
+		&[]models.Lead{
+			{SenderID: buyer.ID, ReceiverID: seller.ID, Subject: "healthy", Message: "hi"},
+			{SenderID: buyer.ID, ReceiverID: missing, Subject: "to a deleted user", Message: "hi"},
+			{SenderID: buyer.ID, ReceiverID: seller.ID, ListingID: &missing, Subject: "about a deleted listing", Message: "hi"},
+		},
+		&[]models.Transaction{
+			{ListingID: listing.ID, BuyerID: buyer.ID, SellerID: seller.ID, Amount: 1},
+			{ListingID: missing, BuyerID: buyer.ID, SellerID: seller.ID, Amount: 2},
+			{ListingID: listing.ID, BuyerID: missing, SellerID: seller.ID, Amount: 3},
+		},
+	}
+	for _, r := range rows {
+		if err := db.CreateInBatches(r, 500).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, seller, buyer, listing
+}
+
+// countRows counts model rows matching an optional condition
+func countRows(db *gorm.DB, model interface{}, query string, args ...interface{}) int64 {
+	var n int64
+	q := db.Model(model)
+	if query != "" {
+		q = q.Where(query, args...)
+	}
+	q.Count(&n)
+	return n
+}
+
+// checkOrphanCounts compares the per-reference counts and their total to want
+func checkOrphanCounts(t *testing.T, result OrphanResult, want map[string]int64) {
+	t.Helper()
+	var total int64
+	for ref, n := range want {
+		if result.Counts[ref] != n {
+			t.Errorf("%s: %d orphans, want %d", ref, result.Counts[ref], n)
+		}
+		total += n
+	}
+	if result.Total != total {
+		t.Errorf("total %d, want %d", result.Total, total)
+	}
+}
+
+func TestFixOrphansDryRun(t *testing.T) {
+	db, _, _, _ := seedOrphans(t)
+
+	result, err := FixOrphans(db, OrphanOptions{Mode: OrphanModeDelete, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrphanCounts(t, result, orphanedRows)
+	if n := countRows(db, &models.Image{}, ""); n != orphanedRows["images.listing_id"]+1 {
+		t.Errorf("dry run left %d images", n)
+	}
+	if n := countRows(db, &models.AuditLog{}, ""); n != 0 {
+		t.Errorf("dry run wrote %d audit logs", n)
+	}
+}
+
+func TestFixOrphansDelete(t *testing.T) {
+	db, seller, _, listing := seedOrphans(t)
+	admin := seller.ID
+
+	result, err := FixOrphans(db, OrphanOptions{Mode: OrphanModeDelete, ActorID: &admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrphanCounts(t, result, orphanedRows)
+
+	// Healthy rows stay; optional listing references are cleared instead of deleted
+	checks := []struct {
+		name  string
+		model interface{}
+		want  int64
+	}{
+		{"images", &models.Image{}, 1},
+		{"favorites", &models.Favorite{}, 1},
+		{"messages", &models.Message{}, 2},
+		{"leads", &models.Lead{}, 2},
+		{"transactions", &models.Transaction{}, 1},
+	}
+	for _, c := range checks {
+		if n := countRows(db, c.model, ""); n != c.want {
+			t.Errorf("%d %s left, want %d", n, c.name, c.want)
+		}
+	}
+	if n := countRows(db, &models.Message{}, "listing_id IS NULL"); n != 1 {
+		t.Errorf("%d messages with a cleared listing, want 1", n)
+	}
+	if n := countRows(db, &models.Message{}, "listing_id = ?", listing.ID); n != 1 {
+		t.Error("healthy message lost its listing")
+	}
+
+	var audit models.AuditLog
+	if err := db.Where("event = ?", "orphan_cleanup").First(&audit).Error; err != nil {
+		t.Fatalf("no audit log: %v", err)
+	}
+	var logged OrphanResult
+	json.Unmarshal([]byte(audit.Details), &logged)
+	if audit.UserID == nil || *audit.UserID != admin || logged.Total != result.Total {
+		t.Errorf("audit log %+v", audit)
+	}
+
+	// Nothing is left for a second run
+	result, err = FixOrphans(db, OrphanOptions{Mode: OrphanModeDelete, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 0 {
+		t.Errorf("second scan found %v", result.Counts)
+	}
+}
+
+func TestFixOrphansReassign(t *testing.T) {
+	db, seller, buyer, _ := seedOrphans(t)
+	archive := models.User{Email: "deleted-users@example.com", Username: "deleted-users"}
+	db.Create(&archive)
+
+	result, err := FixOrphans(db, OrphanOptions{Mode: OrphanModeReassign, ReassignTo: archive.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrphanCounts(t, result, orphanedRows)
+	if result.ReassignTo != archive.ID {
+		t.Errorf("reassign_to %d", result.ReassignTo)
+	}
+
+	if n := countRows(db, &models.Message{}, "sender_id = ? AND receiver_id = ?", archive.ID, seller.ID); n != 1 {
+		t.Errorf("%d messages reassigned, want 1", n)
+	}
+	if n := countRows(db, &models.Lead{}, "sender_id = ? AND receiver_id = ?", buyer.ID, archive.ID); n != 1 {
+		t.Errorf("%d leads reassigned, want 1", n)
+	}
+	if n := countRows(db, &models.Transaction{}, "buyer_id = ?", archive.ID); n != 1 {
+		t.Errorf("%d transactions reassigned, want 1", n)
+	}
+	// Rows that need a listing, and favorites, can't be reassigned
+	if n := countRows(db, &models.Transaction{}, ""); n != 2 {
+		t.Errorf("%d transactions left, want 2", n)
+	}
+	if n := countRows(db, &models.Favorite{}, ""); n != 1 {
+		t.Errorf("%d favorites left, want 1", n)
+	}
+}
+
+func TestFixOrphansOptions(t *testing.T) {
+	db, _, _, _ := seedOrphans(t)
+	for name, opts := range map[string]OrphanOptions{
+		"unknown mode":            {Mode: "archive"},
+		"missing reassign target": {Mode: OrphanModeReassign, ReassignTo: 999},
+	} {
+		if _, err := FixOrphans(db, opts); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+	if n := countRows(db, &models.Image{}, ""); n != orphanedRows["images.listing_id"]+1 {
+		t.Errorf("rejected run changed images: %d left", n)
+	}
+}
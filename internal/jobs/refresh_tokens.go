@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenCleanupInterval is how often expired refresh tokens are deleted
+const RefreshTokenCleanupInterval = 6 * time.Hour
+
+// PurgeExpiredRefreshTokens deletes refresh tokens that expired before now.
+// Revoked tokens are kept until then so that reusing one still revokes its
+// family.
+func PurgeExpiredRefreshTokens(db *gorm.DB, now time.Time) (int64, error) {
+	res := db.Where("expires_at < ?", now).Delete(&models.RefreshToken{})
+	return res.RowsAffected, res.Error
+}
+
+// RunRefreshTokenCleanup purges expired refresh tokens every interval until ctx is cancelled.
+func RunRefreshTokenCleanup(ctx context.Context, db *gorm.DB, log *zap.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := PurgeExpiredRefreshTokens(db, time.Now())
+		if err != nil {
+			log.Error("Failed to purge expired refresh tokens", logger.Err(err))
+		}
+		if removed > 0 {
+			log.Info("Purged expired refresh tokens", zap.Int64("removed", removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
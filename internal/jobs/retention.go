@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// retentionPlaceholder replaces the content of anonymized leads and messages
+const retentionPlaceholder = "[removed]"
+
+// RetentionOptions controls a data-retention purge run
+type RetentionOptions struct {
+	LeadsOlderThan    time.Duration
+	MessagesOlderThan time.Duration
+	Anonymize         bool // Blank the content instead of deleting the rows
+	BatchSize         int
+	DryRun            bool // Only count what would be purged
+}
+
+// RetentionResult reports how many rows a purge affected, or would affect on a dry run
+type RetentionResult struct {
+	Leads    int64  `json:"leads"`
+	Messages int64  `json:"messages"`
+	Mode     string `json:"mode"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+// retentionTarget describes how to purge one table
+type retentionTarget struct {
+	model     interface{}
+	olderThan time.Duration
+	anonymize map[string]interface{}
+	count     *int64
+}
+
+// PurgeOldRecords deletes or anonymizes leads and messages created before their
+// retention period, skipping rows under legal hold. Work is done in batches of
+// primary keys so a run never holds long locks and can safely be interrupted and
+// rerun. Unless it is a dry run, the counts are written to the audit log.
+func PurgeOldRecords(db *gorm.DB, opts RetentionOptions, now time.Time) (RetentionResult, error) {
+	result := RetentionResult{Mode: "delete", DryRun: opts.DryRun}
+	if opts.Anonymize {
+		result.Mode = "anonymize"
+	}
+
+	targets := []retentionTarget{
+		{
+			model:     &models.Lead{},
+			olderThan: opts.LeadsOlderThan,
+			anonymize: map[string]interface{}{
+				"subject":       retentionPlaceholder,
+				"message":       retentionPlaceholder,
+				"contact_phone": "",
+			},
+			count: &result.Leads,
+		},
+		{
+			model:     &models.Message{},
+			olderThan: opts.MessagesOlderThan,
+			anonymize: map[string]interface{}{
+				"subject": retentionPlaceholder,
+				"content": retentionPlaceholder,
+			},
+			count: &result.Messages,
+		},
+	}
+
+	for _, t := range targets {
+		n, err := purgeTable(db, t, opts, now.Add(-t.olderThan))
+		*t.count = n
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	details, _ := json.Marshal(result)
+	if err := db.Create(&models.AuditLog{Event: "data_retention_purge", Details: string(details)}).Error; err != nil {
+		return result, fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return result, nil
+}
+
+// purgeTable purges one table's rows created before cutoff and returns how many were affected
+func purgeTable(db *gorm.DB, t retentionTarget, opts RetentionOptions, cutoff time.Time) (int64, error) {
+	expired := func() *gorm.DB {
+		return db.Model(t.model).Where("created_at < ? AND legal_hold = ?", cutoff, false)
+	}
+
+	if opts.DryRun {
+		var count int64
+		if err := expired().Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to count expired records: %w", err)
+		}
+		return count, nil
+	}
+
+	var total int64
+	var lastID uint
+	for {
+		// Walk by id so anonymized rows, which still match the cutoff, aren't revisited
+		var ids []uint
+		if err := expired().Where("id > ?", lastID).Order("id").Limit(opts.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, fmt.Errorf("failed to load expired records: %w", err)
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		lastID = ids[len(ids)-1]
+
+		var res *gorm.DB
+		if opts.Anonymize {
+			res = db.Model(t.model).Where("id IN ?", ids).Updates(t.anonymize)
+		} else {
+			res = db.Where("id IN ?", ids).Delete(t.model)
+		}
+		if res.Error != nil {
+			return total, fmt.Errorf("failed to purge expired records: %w", res.Error)
+		}
+		total += res.RowsAffected
+
+		if len(ids) < opts.BatchSize {
+			return total, nil
+		}
+	}
+}
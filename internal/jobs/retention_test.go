@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestPurgeOldRecords(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	const day = 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		anonymize bool
+		dryRun    bool
+	}{
+		{name: "delete"},
+		{name: "anonymize", anonymize: true},
+		{name: "dry run", dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Silent),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.AutoMigrate(&models.Lead{}, &models.Message{}, &models.AuditLog{}); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if sqlDB, err := db.DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+
+			// Leads are kept 90 days and messages 30; five of each are past
+			// that, so the batches of two end on a partial one
+			ages := []time.Duration{200 * day, 120 * day, 100 * day, 95 * day, 91 * day, 89 * day, 10 * day}
+			for _, age := range ages {
+				db.Create(&models.Lead{SenderID: 1, ReceiverID: 2, Subject: "Hi", Message: "Hello", ContactPhone: "0912345678", CreatedAt: now.Add(-age)})
+				db.Create(&models.Message{SenderID: 1, ReceiverID: 2, Subject: "Hi", Content: "Hello", CreatedAt: now.Add(-age / 3)})
+			}
+			db.Create(&models.Lead{SenderID: 1, ReceiverID: 2, Subject: "Held", Message: "Hello", LegalHold: true, CreatedAt: now.Add(-300 * day)})
+			db.Create(&models.Message{SenderID: 1, ReceiverID: 2, Subject: "Held", Content: "Hello", LegalHold: true, CreatedAt: now.Add(-300 * day)})
+
+			result, err := PurgeOldRecords(db, RetentionOptions{
+				LeadsOlderThan:    90 * day,
+				MessagesOlderThan: 30 * day,
+				Anonymize:         tt.anonymize,
+				BatchSize:         2,
+				DryRun:            tt.dryRun,
+			}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Leads != 5 || result.Messages != 5 {
+				t.Errorf("purged %d leads and %d messages, want 5 each", result.Leads, result.Messages)
+			}
+
+			// Rows newer than the cutoff and under legal hold are untouched
+			wantKept := int64(3)
+			if tt.dryRun || tt.anonymize {
+				wantKept = int64(len(ages) + 1)
+			}
+			var leads, messages, untouchedLeads, untouchedMessages int64
+			db.Model(&models.Lead{}).Count(&leads)
+			db.Model(&models.Message{}).Count(&messages)
+			db.Model(&models.Lead{}).Where("message = ?", "Hello").Count(&untouchedLeads)
+			db.Model(&models.Message{}).Where("content = ?", "Hello").Count(&untouchedMessages)
+			if leads != wantKept || messages != wantKept {
+				t.Errorf("%d leads and %d messages left, want %d each", leads, messages, wantKept)
+			}
+			wantUntouched := int64(3)
+			if tt.dryRun {
+				wantUntouched = int64(len(ages) + 1)
+			}
+			if untouchedLeads != wantUntouched || untouchedMessages != wantUntouched {
+				t.Errorf("%d leads and %d messages untouched, want %d each", untouchedLeads, untouchedMessages, wantUntouched)
+			}
+			if tt.anonymize {
+				var phones int64
+				db.Model(&models.Lead{}).Where("message = ? AND contact_phone <> ''", retentionPlaceholder).Count(&phones)
+				if phones != 0 {
+					t.Errorf("%d anonymized leads kept a phone number", phones)
+				}
+			}
+
+			var audits int64
+			db.Model(&models.AuditLog{}).Where("event = ?", "data_retention_purge").Count(&audits)
+			if wantAudits := map[bool]int64{true: 0, false: 1}[tt.dryRun]; audits != wantAudits {
+				t.Errorf("%d audit entries, want %d", audits, wantAudits)
+			}
+		})
+	}
+}
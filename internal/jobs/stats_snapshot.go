@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"trade_company/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// StatsSnapshot is a point-in-time summary of the marketplace for ops checks
+type StatsSnapshot struct {
+	TakenAt          time.Time                      `json:"taken_at"`
+	Users            int64                          `json:"users"`
+	Listings         int64                          `json:"listings"` // Excludes finalized deletions
+	Transactions     int64                          `json:"transactions"`
+	ListingsByStatus map[models.ListingStatus]int64 `json:"listings_by_status"`
+	Leads            int64                          `json:"leads"` // Non-spam
+	LeadsRead        int64                          `json:"leads_read"`
+	LeadReadRate     float64                        `json:"lead_read_rate"` // 0-1; 0 without leads
+	Today            models.DailyStat               `json:"today"`
+	DBPool           DBPoolStats                    `json:"db_pool"`
+}
+
+// DBPoolStats is the part of sql.DBStats worth reading at a glance
+type DBPoolStats struct {
+	MaxOpen      int           `json:"max_open"`
+	Open         int           `json:"open"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration_ns"`
+}
+
+// TakeStatsSnapshot counts the totals and today's activity. Today's numbers
+// are the ones the admin stats endpoint shows for the current day.
+func TakeStatsSnapshot(db *gorm.DB) (*StatsSnapshot, error) {
+	now := time.Now()
+	snap := &StatsSnapshot{TakenAt: now}
+
+	counts := []struct {
+		name  string
+		query *gorm.DB
+		dest  *int64
+	}{
+		{"users", db.Model(&models.User{}), &snap.Users},
+		{"listings", db.Model(&models.Listing{}).Where("status <> ?", models.ListingStatusDeleted), &snap.Listings},
+		{"transactions", db.Model(&models.Transaction{}), &snap.Transactions},
+		{"leads", db.Model(&models.Lead{}).Where("is_spam = ?", false), &snap.Leads},
+		{"read leads", db.Model(&models.Lead{}).Where("is_spam = ? AND is_read = ?", false, true), &snap.LeadsRead},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", c.name, err)
+		}
+	}
+	if snap.Leads > 0 {
+		snap.LeadReadRate = float64(snap.LeadsRead) / float64(snap.Leads)
+	}
+
+	var rows []struct {
+		Status models.ListingStatus
+		Count  int64
+	}
+	if err := db.Model(&models.Listing{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count listings by status: %w", err)
+	}
+	snap.ListingsByStatus = make(map[models.ListingStatus]int64, len(models.ListingStatuses))
+	for _, s := range models.ListingStatuses {
+		snap.ListingsByStatus[s] = 0
+	}
+	for _, r := range rows {
+		snap.ListingsByStatus[r.Status] = r.Count
+	}
+
+	today, err := ComputeDailyStats(db, StatsDay(now))
+	if err != nil {
+		return nil, err
+	}
+	snap.Today = today
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	pool := sqlDB.Stats()
+	snap.DBPool = DBPoolStats{
+		MaxOpen:      pool.MaxOpenConnections,
+		Open:         pool.OpenConnections,
+		InUse:        pool.InUse,
+		Idle:         pool.Idle,
+		WaitCount:    pool.WaitCount,
+		WaitDuration: pool.WaitDuration,
+	}
+	return snap, nil
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+)
+
+func TestTakeStatsSnapshot(t *testing.T) {
+	db, at := newStatsTest(t)
+	// Older activity on top of the seeded days: a sold and a deleted listing and
+	// a lead the seller has read
+	old := at(-10, 9*time.Hour)
+	db.Create(&models.Listing{Title: "Sold", Price: 1, OwnerID: 1, Status: models.ListingStatusSold, CreatedAt: old})
+	db.Create(&models.Listing{Title: "Gone", Price: 1, OwnerID: 1, Status: models.ListingStatusDeleted, CreatedAt: old})
+	db.Create(&models.Lead{SenderID: 2, ReceiverID: 1, Subject: "Hi", Message: "Read me", IsRead: true, CreatedAt: old})
+
+	snap, err := TakeStatsSnapshot(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Users != 4 || snap.Listings != 6 || snap.Transactions != 4 {
+		t.Errorf("%d users, %d listings, %d transactions, want 4, 6 and 4", snap.Users, snap.Listings, snap.Transactions)
+	}
+	// Spam is left out of the lead totals
+	if snap.Leads != 2 || snap.LeadsRead != 1 || snap.LeadReadRate != 0.5 {
+		t.Errorf("%d leads, %d read, rate %v, want 2, 1 and 0.5", snap.Leads, snap.LeadsRead, snap.LeadReadRate)
+	}
+
+	wantByStatus := map[models.ListingStatus]int64{
+		models.ListingStatusActive: 5, models.ListingStatusSold: 1, models.ListingStatusDeleted: 1,
+		models.ListingStatusInactive: 0, models.ListingStatusPendingDelete: 0,
+	}
+	if len(snap.ListingsByStatus) != len(wantByStatus) {
+		t.Errorf("listings by status %v, want every status", snap.ListingsByStatus)
+	}
+	for status, want := range wantByStatus {
+		if snap.ListingsByStatus[status] != want {
+			t.Errorf("%s listings %d, want %d", status, snap.ListingsByStatus[status], want)
+		}
+	}
+
+	// Today matches what the admin stats endpoint computes for the day
+	today, err := ComputeDailyStats(db, at(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statKey(snap.Today) != statKey(today) || snap.Today.NewUsers != 1 || snap.Today.NewListings != 1 {
+		t.Errorf("today %s, want %s", statKey(snap.Today), statKey(today))
+	}
+	if snap.DBPool.Open < 1 {
+		t.Errorf("pool stats %+v", snap.DBPool)
+	}
+}
+
+func TestTakeStatsSnapshotEmpty(t *testing.T) {
+	db := newUnverifiedTest(t)
+	if err := db.AutoMigrate(&models.Lead{}, &models.DailyStat{}); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := TakeStatsSnapshot(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No division by zero without leads
+	if snap.Users != 0 || snap.Leads != 0 || snap.LeadReadRate != 0 {
+		t.Errorf("snapshot %+v", snap)
+	}
+}
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+	"trade_company/internal/sanitize"
+	"trade_company/internal/translate"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TranslationInterval is how often queued listings are translated. The batch
+// size per pass is the configured jobs-per-minute, so this is the rate limit.
+const TranslationInterval = time.Minute
+
+// Listing text is stored in Traditional Chinese and translated to English
+const (
+	translationSource = "zh-TW"
+	translationTarget = "en"
+)
+
+// TranslationResult reports what one translation pass did
+type TranslationResult struct {
+	Translated int
+	Failed     int   // Requeued to be retried
+	Characters int64 // Sent to the provider
+	OverBudget bool  // The monthly character cap stopped the pass
+}
+
+// TranslateQueuedListings translates up to batch listings queued by ?lang=en,
+// oldest request first. Characters sent are added to the month's usage, and
+// once the monthly cap would be exceeded the rest waits for the next month.
+func TranslateQueuedListings(ctx context.Context, db *gorm.DB, translator translate.Translator, batch int, monthlyLimit int64, log *zap.Logger) (TranslationResult, error) {
+	var result TranslationResult
+
+	var listings []models.Listing
+	if err := db.Select("id", "title", "description").
+		Where("translation_requested_at IS NOT NULL").
+		Order("translation_requested_at").
+		Limit(batch).
+		Find(&listings).Error; err != nil {
+		return result, fmt.Errorf("failed to load queued listings: %w", err)
+	}
+
+	month := models.TranslationMonth(time.Now())
+	var usage models.TranslationUsage
+	if err := db.Where("month = ?", month).Limit(1).Find(&usage).Error; err != nil {
+		return result, fmt.Errorf("failed to load translation usage: %w", err)
+	}
+
+	for i := range listings {
+		l := &listings[i]
+		chars := int64(utf8.RuneCountInString(l.Title) + utf8.RuneCountInString(l.Description))
+		if monthlyLimit > 0 && usage.Characters+chars > monthlyLimit {
+			result.OverBudget = true
+			break
+		}
+
+		titleEn, descEn, err := translateListing(ctx, translator, l)
+		// Characters are billed even when one of the two calls fails
+		usage.Characters += chars
+		result.Characters += chars
+		if uerr := models.AddTranslationUsage(db, month, chars); uerr != nil {
+			return result, fmt.Errorf("failed to record translation usage: %w", uerr)
+		}
+		if err != nil {
+			log.Warn("Listing translation failed", zap.Uint("listing_id", l.ID), logger.Err(err))
+			result.Failed++
+			// Requeue at the back so one failing listing doesn't hold up the rest
+			if err := db.Model(&models.Listing{}).Where("id = ? AND translation_requested_at IS NOT NULL", l.ID).
+				UpdateColumn("translation_requested_at", time.Now()).Error; err != nil {
+				return result, fmt.Errorf("failed to requeue listing %d: %w", l.ID, err)
+			}
+			continue
+		}
+
+		// Only stored if the source text is unchanged; an edit meanwhile cleared
+		// the queue entry and the listing is translated again on the next request
+		if err := db.Model(&models.Listing{}).
+			Where("id = ? AND title = ? AND description = ?", l.ID, l.Title, l.Description).
+			UpdateColumns(map[string]interface{}{
+				"title_en":                 titleEn,
+				"description_en":           descEn,
+				"translation_requested_at": nil,
+			}).Error; err != nil {
+			return result, fmt.Errorf("failed to store translation for listing %d: %w", l.ID, err)
+		}
+		result.Translated++
+	}
+
+	return result, nil
+}
+
+// translateListing translates the title as text and the sanitized description
+// as HTML. The provider's HTML is sanitized again before it is stored.
+func translateListing(ctx context.Context, translator translate.Translator, l *models.Listing) (string, string, error) {
+	title, err := translator.Translate(ctx, l.Title, translationSource, translationTarget, translate.FormatText)
+	if err != nil {
+		return "", "", err
+	}
+	if l.Description == "" {
+		return title, "", nil
+	}
+	desc, err := translator.Translate(ctx, l.DescriptionHTML(), translationSource, translationTarget, translate.FormatHTML)
+	if err != nil {
+		return "", "", err
+	}
+	descHTML, _ := sanitize.Description(desc)
+	return title, descHTML, nil
+}
+
+// RunTranslations translates queued listings every interval until ctx is cancelled.
+func RunTranslations(ctx context.Context, db *gorm.DB, translator translate.Translator, log *zap.Logger, interval time.Duration, perInterval int, monthlyLimit int64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := TranslateQueuedListings(ctx, db, translator, perInterval, monthlyLimit, log)
+		if err != nil {
+			log.Error("Failed to translate listings", logger.Err(err))
+		}
+		if result.Translated > 0 || result.Failed > 0 {
+			log.Info("Translated listings", zap.Int("translated", result.Translated), zap.Int("failed", result.Failed), zap.Int64("characters", result.Characters))
+		}
+		if result.OverBudget {
+			log.Warn("Monthly translation character limit reached; queued listings wait for next month", zap.Int64("limit", monthlyLimit))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
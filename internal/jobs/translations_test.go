@@ -0,0 +1,182 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeTranslator prefixes text with the target language and counts calls
+type fakeTranslator struct {
+	calls  int
+	err    error
+	during func() // Runs on every call, to change things mid-translation
+}
+
+func (f *fakeTranslator) Translate(_ context.Context, text, _, target, _ string) (string, error) {
+	f.calls++
+	if f.during != nil {
+		f.during()
+	}
+	if f.err != nil {
+		return "", f.err
+	}
+	return target + ": " + text, nil
+}
+
+func newTranslationTest(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Listing{}, &models.TranslationUsage{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestTranslateQueuedListings(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantResult TranslationResult
+		wantTitle  *string
+		wantQueued bool
+	}{
+		{name: "translation stored and dequeued", wantResult: TranslationResult{Translated: 1, Characters: 10}, wantTitle: strPtr("en: Shop")},
+		{name: "failure stays queued", err: errors.New("provider down"), wantResult: TranslationResult{Failed: 1, Characters: 10}, wantQueued: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTranslationTest(t)
+
+			now := time.Now()
+			listing := models.Listing{Title: "Shop", Description: "A cafe", Price: 1, TranslationRequestedAt: &now}
+			if err := db.Create(&listing).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			translator := &fakeTranslator{err: tt.err}
+			result, err := TranslateQueuedListings(context.Background(), db, translator, 10, 0, zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != tt.wantResult {
+				t.Errorf("result %+v, want %+v", result, tt.wantResult)
+			}
+
+			var stored models.Listing
+			db.First(&stored, listing.ID)
+			if (stored.TitleEn == nil) != (tt.wantTitle == nil) || (tt.wantTitle != nil && *stored.TitleEn != *tt.wantTitle) {
+				t.Errorf("title_en %v, want %v", stored.TitleEn, tt.wantTitle)
+			}
+			if queued := stored.TranslationRequestedAt != nil; queued != tt.wantQueued {
+				t.Errorf("queued %v, want %v", queued, tt.wantQueued)
+			}
+
+			// A stored translation is served from the database; the next pass
+			// only calls the provider for listings still queued
+			calls := translator.calls
+			if _, err := TranslateQueuedListings(context.Background(), db, translator, 10, 0, zap.NewNop()); err != nil {
+				t.Fatal(err)
+			}
+			if again := translator.calls - calls; (again > 0) != tt.wantQueued {
+				t.Errorf("second pass made %d provider calls, want calls only while queued", again)
+			}
+		})
+	}
+}
+
+func TestTranslationRateAndBudget(t *testing.T) {
+	db := newTranslationTest(t)
+	start := time.Now().Add(-time.Hour)
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		requested := start.Add(time.Duration(i) * time.Minute)
+		// 10 characters each
+		l := models.Listing{Title: "Shop", Description: "A cafe", Price: 1, TranslationRequestedAt: &requested}
+		if err := db.Create(&l).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, l.ID)
+	}
+	translator := &fakeTranslator{}
+	usage := func() int64 {
+		var u models.TranslationUsage
+		db.Where("month = ?", models.TranslationMonth(time.Now())).Limit(1).Find(&u)
+		return u.Characters
+	}
+
+	passes := []struct {
+		name  string
+		batch int
+		limit int64
+		want  TranslationResult
+		usage int64
+	}{
+		// The batch size is the per-interval rate limit; oldest requests go first
+		{name: "batch", batch: 2, want: TranslationResult{Translated: 2, Characters: 20}, usage: 20},
+		{name: "over the monthly cap", batch: 2, limit: 29, want: TranslationResult{OverBudget: true}, usage: 20},
+		{name: "exactly the cap", batch: 2, limit: 30, want: TranslationResult{Translated: 1, Characters: 10}, usage: 30},
+	}
+	for _, pass := range passes {
+		result, err := TranslateQueuedListings(context.Background(), db, translator, pass.batch, pass.limit, zap.NewNop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != pass.want {
+			t.Errorf("%s: result %+v, want %+v", pass.name, result, pass.want)
+		}
+		if got := usage(); got != pass.usage {
+			t.Errorf("%s: month usage %d, want %d", pass.name, got, pass.usage)
+		}
+		if pass.name == "batch" {
+			var last models.Listing
+			db.First(&last, ids[2])
+			if last.TitleEn != nil {
+				t.Error("newest request translated ahead of the batch")
+			}
+		}
+	}
+}
+
+func TestTranslationDiscardedAfterEdit(t *testing.T) {
+	db := newTranslationTest(t)
+	now := time.Now()
+	listing := models.Listing{Title: "Shop", Description: "A cafe", Price: 1, TranslationRequestedAt: &now}
+	db.Create(&listing)
+	// The seller edits the listing while the provider is working; the edit
+	// clears the queue entry as the update handler does
+	translator := &fakeTranslator{during: func() {
+		db.Model(&models.Listing{}).Where("id = ?", listing.ID).
+			Updates(map[string]interface{}{"title": "Bigger shop", "translation_requested_at": nil})
+	}}
+
+	if _, err := TranslateQueuedListings(context.Background(), db, translator, 10, 0, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	var stored models.Listing
+	db.First(&stored, listing.ID)
+	if stored.TitleEn != nil || stored.DescriptionEn != nil {
+		t.Errorf("translation of the old text stored: %q", *stored.TitleEn)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/redisclient"
+
+	"go.uber.org/zap"
+)
+
+// TrendingDecayInterval is how often trending scores are decayed
+const TrendingDecayInterval = time.Hour
+
+// RunTrendingDecay shrinks trending scores every interval, halving them once per
+// halfLife, until ctx is cancelled. The first decay waits a full interval so a
+// restart doesn't decay scores twice.
+func RunTrendingDecay(ctx context.Context, trending *redisclient.Trending, log *zap.Logger, interval, halfLife time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	factor := redisclient.DecayFactor(interval, halfLife)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := trending.Decay(ctx, factor); err != nil {
+			log.Error("Failed to decay trending scores", logger.Err(err))
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/logger"
+	"trade_company/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// UnverifiedAccountInterval is how often unverified accounts are reminded and expired
+const UnverifiedAccountInterval = time.Hour
+
+// UnverifiedReminderLead is how long before removal the reminder email goes out
+const UnverifiedReminderLead = 24 * time.Hour
+
+// UnverifiedAccountOptions controls the unverified account cleanup
+type UnverifiedAccountOptions struct {
+	MaxAge    time.Duration // Accounts created longer ago than this are removed
+	Anonymize bool          // Free the email but keep the row instead of deleting it
+}
+
+// UnverifiedAccountResult reports what one cleanup pass did
+type UnverifiedAccountResult struct {
+	Reminded int
+	Removed  int
+}
+
+// unverifiedScope selects inactive, never-verified accounts that own nothing worth
+// keeping: no listings, no messages either way and no transactions on either side.
+func unverifiedScope(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.User{}).
+		Where("users.is_active = ? AND users.email_verified_at IS NULL", false).
+		Where("NOT EXISTS (SELECT 1 FROM listings WHERE listings.owner_id = users.id)").
+		Where("NOT EXISTS (SELECT 1 FROM messages WHERE messages.sender_id = users.id OR messages.receiver_id = users.id)").
+		Where("NOT EXISTS (SELECT 1 FROM transactions WHERE transactions.buyer_id = users.id OR transactions.seller_id = users.id)")
+}
+
+// StaleUnverifiedUser returns the account holding email if it is unverified,
+// older than maxAge and otherwise eligible for cleanup, or nil when there is
+// none. Signup uses it to let a new registration reclaim the address.
+func StaleUnverifiedUser(db *gorm.DB, email string, maxAge time.Duration, now time.Time) (*models.User, error) {
+	var users []models.User
+	if err := unverifiedScope(db).
+		Where("users.email = ? AND users.created_at <= ?", email, now.Add(-maxAge)).
+		Limit(1).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return &users[0], nil
+}
+
+// RemoveUnverifiedUser deletes the account, or anonymizes it so the email and
+// username can be registered again. Sessions and reset tokens cascade on delete.
+func RemoveUnverifiedUser(db *gorm.DB, user *models.User, anonymize bool) error {
+	if !anonymize {
+		return db.Delete(&models.User{}, user.ID).Error
+	}
+
+	placeholder := fmt.Sprintf("expired-%d", user.ID)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"email":                    placeholder + "@invalid",
+			"username":                 placeholder,
+			"password_hash":            "",
+			"first_name":               "",
+			"last_name":                "",
+			"phone":                    "",
+			"company_name":             "",
+			"tax_id":                   "",
+			"contact_phone":            "",
+			"email_verification_token": "",
+		}).Error
+	})
+}
+
+// ExpireUnverifiedAccounts sends the one-time reminder to accounts within a day of
+// expiring, then removes accounts past maxAge. An account is only removed once
+// its reminder has been out for a full day, so a stalled job never removes
+// anyone without warning; it just removes them a little later.
+func ExpireUnverifiedAccounts(db *gorm.DB, emails *auth.EmailService, opts UnverifiedAccountOptions, now time.Time) (UnverifiedAccountResult, error) {
+	var result UnverifiedAccountResult
+
+	var due []models.User
+	if err := unverifiedScope(db).
+		Where("users.unverified_reminder_sent_at IS NULL AND users.created_at <= ?", now.Add(-(opts.MaxAge - UnverifiedReminderLead))).
+		Find(&due).Error; err != nil {
+		return result, fmt.Errorf("failed to load accounts to remind: %w", err)
+	}
+	for i := range due {
+		user := &due[i]
+		expiresAt := user.CreatedAt.Add(opts.MaxAge)
+		if earliest := now.Add(UnverifiedReminderLead); expiresAt.Before(earliest) {
+			expiresAt = earliest
+		}
+		// A suppressed address still counts as reminded, or it would never expire
+		if err := emails.SendUnverifiedAccountReminder(user, expiresAt); err != nil && !errors.Is(err, auth.ErrEmailSuppressed) {
+			return result, fmt.Errorf("failed to send reminder to user %d: %w", user.ID, err)
+		}
+		if err := db.Model(user).Update("unverified_reminder_sent_at", now).Error; err != nil {
+			return result, fmt.Errorf("failed to record reminder for user %d: %w", user.ID, err)
+		}
+		result.Reminded++
+	}
+
+	var expired []models.User
+	if err := unverifiedScope(db).
+		Where("users.created_at <= ? AND users.unverified_reminder_sent_at <= ?", now.Add(-opts.MaxAge), now.Add(-UnverifiedReminderLead)).
+		Find(&expired).Error; err != nil {
+		return result, fmt.Errorf("failed to load expired accounts: %w", err)
+	}
+	for i := range expired {
+		if err := RemoveUnverifiedUser(db, &expired[i], opts.Anonymize); err != nil {
+			return result, fmt.Errorf("failed to remove user %d: %w", expired[i].ID, err)
+		}
+		result.Removed++
+	}
+
+	return result, nil
+}
+
+// RunUnverifiedAccountCleanup reminds and expires unverified accounts every interval until ctx is cancelled.
+func RunUnverifiedAccountCleanup(ctx context.Context, db *gorm.DB, emails *auth.EmailService, log *zap.Logger, opts UnverifiedAccountOptions, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := ExpireUnverifiedAccounts(db, emails, opts, time.Now())
+		if err != nil {
+			log.Error("Failed to expire unverified accounts", logger.Err(err))
+		}
+		if result.Reminded > 0 || result.Removed > 0 {
+			log.Info("Expired unverified accounts", zap.Int("reminded", result.Reminded), zap.Int("removed", result.Removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package jobs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"trade_company/internal/auth"
+	"trade_company/internal/config"
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sendGridRecorder stands in for SendGrid and records who each email went to
+type sendGridRecorder struct {
+	mu sync.Mutex
+	to []string
+}
+
+func (s *sendGridRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var msg struct {
+		Personalizations []struct {
+			To []struct {
+				Email string `json:"email"`
+			} `json:"to"`
+		} `json:"personalizations"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&msg); err == nil {
+		s.mu.Lock()
+		for _, p := range msg.Personalizations {
+			for _, to := range p.To {
+				s.to = append(s.to, to.Email)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+// recipients returns the sorted addresses emailed so far and forgets them
+func (s *sendGridRecorder) recipients() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	to := s.to
+	s.to = nil
+	sort.Strings(to)
+	return to
+}
+
+// newRecordingEmailService returns an email service whose SendGrid requests
+// are answered by a recorder instead of the network
+func newRecordingEmailService(t *testing.T) (*auth.EmailService, *sendGridRecorder) {
+	t.Helper()
+	rec := &sendGridRecorder{}
+	original := http.DefaultTransport
+	http.DefaultTransport = rec
+	t.Cleanup(func() { http.DefaultTransport = original })
+	return auth.NewEmailService(&config.Config{AppEnv: "test", SendGridAPIKey: "SG.test", AppName: "https://example.com"}), rec
+}
+
+func newUnverifiedTest(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Listing{}, &models.ListingCount{}, &models.Message{},
+		&models.Transaction{}, &models.UserSession{}, &models.PasswordResetToken{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// createUnverifiedUser creates an inactive, unverified account of the given age
+func createUnverifiedUser(t *testing.T, db *gorm.DB, name string, createdAt time.Time, reminded *time.Time) *models.User {
+	t.Helper()
+	user := &models.User{
+		Email: name + "@example.com", Username: name, EmailNotifications: true,
+		CreatedAt: createdAt, UnverifiedReminderSentAt: reminded,
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	// is_active defaults to true in the schema, so false has to be set explicitly
+	if err := db.Model(user).Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// remainingUsers returns the sorted usernames still in the users table
+func remainingUsers(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var names []string
+	if err := db.Model(&models.User{}).Order("username").Pluck("username", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestExpireUnverifiedAccounts(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	db := newUnverifiedTest(t)
+	emails, sent := newRecordingEmailService(t)
+	opts := UnverifiedAccountOptions{MaxAge: 7 * day}
+
+	longAgo := now.Add(-2 * day)
+	createUnverifiedUser(t, db, "fresh", now.Add(-day), nil)
+	createUnverifiedUser(t, db, "due", now.Add(-6*day-12*time.Hour), nil)
+	createUnverifiedUser(t, db, "late", now.Add(-8*day), nil)
+	createUnverifiedUser(t, db, "expired", now.Add(-8*day), &longAgo)
+	bounced := createUnverifiedUser(t, db, "bounced", now.Add(-6*day-12*time.Hour), nil)
+	db.Model(bounced).Update("email_undeliverable", true)
+
+	// Old and unverified, but each has something worth keeping or is verified
+	verified := createUnverifiedUser(t, db, "verified", now.Add(-30*day), &longAgo)
+	db.Model(verified).Update("email_verified_at", now.Add(-29*day))
+	active := createUnverifiedUser(t, db, "active", now.Add(-30*day), &longAgo)
+	db.Model(active).Update("is_active", true)
+	seller := createUnverifiedUser(t, db, "seller", now.Add(-30*day), &longAgo)
+	listing := models.Listing{Title: "Shop", Price: 1, OwnerID: seller.ID, Status: models.ListingStatusInactive}
+	db.Create(&listing)
+	sender := createUnverifiedUser(t, db, "sender", now.Add(-30*day), &longAgo)
+	receiver := createUnverifiedUser(t, db, "receiver", now.Add(-30*day), &longAgo)
+	db.Create(&models.Message{SenderID: sender.ID, ReceiverID: verified.ID, Content: "hi"})
+	db.Create(&models.Message{SenderID: verified.ID, ReceiverID: receiver.ID, Content: "hi"})
+	buyer := createUnverifiedUser(t, db, "buyer", now.Add(-30*day), &longAgo)
+	db.Create(&models.Transaction{ListingID: listing.ID, BuyerID: buyer.ID, SellerID: verified.ID, Amount: 1})
+
+	// First pass: a day-ahead reminder for every account within a day of
+	// expiring that hasn't had one, and removal only for "expired"
+	result, err := ExpireUnverifiedAccounts(db, emails, opts, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reminded != 3 || result.Removed != 1 {
+		t.Errorf("first pass %+v, want 3 reminded and 1 removed", result)
+	}
+	if got := sent.recipients(); strings.Join(got, ",") != "due@example.com,late@example.com" {
+		t.Errorf("reminders to %v, want due and late; bounced is suppressed", got)
+	}
+	want := "active,bounced,buyer,due,fresh,late,receiver,seller,sender,verified"
+	if got := remainingUsers(t, db); strings.Join(got, ",") != want {
+		t.Errorf("users %v, want %s", got, want)
+	}
+
+	// An hour later nobody gets a second reminder and the reminded accounts
+	// still have most of their day
+	result, err = ExpireUnverifiedAccounts(db, emails, opts, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (UnverifiedAccountResult{}) || len(sent.recipients()) != 0 {
+		t.Errorf("second pass %+v, want nothing done", result)
+	}
+
+	// A day after the reminders, the three reminded accounts are removed
+	result, err = ExpireUnverifiedAccounts(db, emails, opts, now.Add(day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 3 {
+		t.Errorf("third pass %+v, want 3 removed", result)
+	}
+	want = "active,buyer,fresh,receiver,seller,sender,verified"
+	if got := remainingUsers(t, db); strings.Join(got, ",") != want {
+		t.Errorf("users %v, want %s", got, want)
+	}
+}
+
+func TestExpireUnverifiedAccountsAnonymize(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	db := newUnverifiedTest(t)
+	emails, _ := newRecordingEmailService(t)
+
+	reminded := now.Add(-48 * time.Hour)
+	user := createUnverifiedUser(t, db, "expired", now.Add(-8*24*time.Hour), &reminded)
+	db.Create(&models.UserSession{UserID: user.ID, SessionID: "s1", ExpiresAt: now.Add(time.Hour)})
+
+	result, err := ExpireUnverifiedAccounts(db, emails, UnverifiedAccountOptions{MaxAge: 7 * 24 * time.Hour, Anonymize: true}, now)
+	if err != nil || result.Removed != 1 {
+		t.Fatalf("result %+v, err %v", result, err)
+	}
+	var stored models.User
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("anonymized row is gone: %v", err)
+	}
+	if stored.Email == user.Email || !strings.HasSuffix(stored.Email, "@invalid") || stored.Username == user.Username {
+		t.Errorf("email %q, username %q still identify the user", stored.Email, stored.Username)
+	}
+	var sessions int64
+	db.Model(&models.UserSession{}).Where("user_id = ?", user.ID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("%d sessions left", sessions)
+	}
+}
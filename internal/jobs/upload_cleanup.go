@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"trade_company/internal/logger"
+	"trade_company/internal/uploads"
+
+	"go.uber.org/zap"
+)
+
+// UploadCleanupInterval is how often abandoned resumable uploads are swept
+const UploadCleanupInterval = time.Hour
+
+// RunUploadCleanup deletes the staged chunks of expired uploads every interval until ctx is cancelled.
+func RunUploadCleanup(ctx context.Context, manager *uploads.Manager, log *zap.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := manager.CleanupAbandoned(ctx, time.Now())
+		if err != nil {
+			log.Error("Failed to clean up abandoned uploads", logger.Err(err))
+		}
+		if removed > 0 {
+			log.Info("Removed abandoned uploads", zap.Int("removed", removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
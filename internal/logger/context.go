@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// contextKey is the gin context key holding the request-scoped logger
+const contextKey = "logger"
+
+// WithContext stores a request-scoped logger on the gin context.
+func WithContext(c *gin.Context, l *zap.Logger) {
+	c.Set(contextKey, l)
+}
+
+// FromContext returns the request-scoped logger set by middleware.RequestLogger,
+// which already carries the request ID. Falls back to the global zap logger.
+func FromContext(c *gin.Context) *zap.Logger {
+	if v, ok := c.Get(contextKey); ok {
+		if l, ok := v.(*zap.Logger); ok {
+			return l
+		}
+	}
+	return zap.L()
+}
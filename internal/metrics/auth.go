@@ -0,0 +1,15 @@
+package metrics
+
+// bcryptBuckets spans costs 10-14 on slow and fast CPUs; each cost step doubles the time
+var bcryptBuckets = []float64{0.01, 0.025, 0.05, 0.075, 0.1, 0.15, 0.25, 0.5, 1, 2}
+
+// jwtBuckets covers HMAC signing and verification, which take microseconds
+var jwtBuckets = []float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.01}
+
+// Auth operation latency
+var (
+	BcryptHashSeconds   = NewHistogram("auth_bcrypt_hash_seconds", "Time to hash a password with bcrypt.", bcryptBuckets)
+	BcryptVerifySeconds = NewHistogram("auth_bcrypt_verify_seconds", "Time to compare a password with its bcrypt hash.", bcryptBuckets)
+	JWTGenerateSeconds  = NewHistogram("auth_jwt_generate_seconds", "Time to sign a JWT.", jwtBuckets)
+	JWTParseSeconds     = NewHistogram("auth_jwt_parse_seconds", "Time to parse and verify a JWT.", jwtBuckets)
+)
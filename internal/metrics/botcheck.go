@@ -0,0 +1,15 @@
+package metrics
+
+import "expvar"
+
+// BotChecks counts bot scoring verdicts per endpoint ("lead_flag",
+// "signup_reject", ...) and how often each signal fired ("signal_honeypot", ...).
+var BotChecks = expvar.NewMap("botcheck")
+
+// IncBotCheck counts one scored submission and the signals it raised.
+func IncBotCheck(endpoint, verdict string, signals []string) {
+	BotChecks.Add(endpoint+"_"+verdict, 1)
+	for _, s := range signals {
+		BotChecks.Add("signal_"+s, 1)
+	}
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import "expvar"
+
+// Exports counts exports let through or turned away by the export gate.
+var Exports = expvar.NewMap("exports")
+
+// Export gate event names
+const (
+	ExportStarted        = "started"
+	ExportRejectedHolder = "rejected_holder_busy"
+	ExportRejectedCap    = "rejected_cap"
+)
+
+// Export queue depth across all instances, as last seen by this one
+var (
+	ExportsRunning = expvar.NewInt("exports_running")
+	ExportsWaiting = expvar.NewInt("exports_waiting")
+)
+
+// ExportSeconds is how long exports hold their slot
+var ExportSeconds = NewHistogram("export_duration_seconds", "Time an export held its slot in the export gate.",
+	[]float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300})
+
+// IncExports increments an export gate event counter.
+func IncExports(event string) {
+	Exports.Add(event, 1)
+}
+
+// SetExportQueue records the running and waiting exports.
+func SetExportQueue(running, waiting int64) {
+	ExportsRunning.Set(running)
+	ExportsWaiting.Set(waiting)
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import "expvar"
+
+// PersistedQueries counts GraphQL persisted query lookups and registrations.
+var PersistedQueries = expvar.NewMap("graphql_persisted_queries")
+
+// Persisted query event names
+const (
+	PersistedQueryHit        = "hit"        // Hash resolved to a query
+	PersistedQueryMiss       = "miss"       // Unknown hash; the client has to send the query
+	PersistedQueryStored     = "stored"     // Query cached by APQ
+	PersistedQueryRejected   = "rejected"   // Unregistered query refused in allowlist mode
+	PersistedQueryRegistered = "registered" // Query added through the admin API
+)
+
+// IncPersistedQuery increments a persisted query event counter.
+func IncPersistedQuery(event string) {
+	PersistedQueries.Add(event, 1)
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Histogram counts observed durations into cumulative buckets, like a
+// Prometheus histogram. It is published through expvar and can be written in
+// the Prometheus text format with WritePrometheus.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64 // Upper bounds in seconds, ascending
+
+	mu     sync.Mutex
+	counts []uint64 // Per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+var (
+	histogramsMu sync.Mutex
+	histograms   = map[string]*Histogram{}
+)
+
+// NewHistogram creates and registers a histogram. buckets are upper bounds in
+// seconds. Like expvar.Publish, a duplicate name panics.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		buckets: append([]float64(nil), buckets...),
+		counts:  make([]uint64, len(buckets)+1),
+	}
+	sort.Float64s(h.buckets)
+	expvar.Publish(name, h)
+
+	histogramsMu.Lock()
+	histograms[name] = h
+	histogramsMu.Unlock()
+	return h
+}
+
+// Observe records one duration
+func (h *Histogram) Observe(d time.Duration) {
+	v := d.Seconds()
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// Since records the time elapsed since start; use as defer h.Since(time.Now())
+func (h *Histogram) Since(start time.Time) {
+	h.Observe(time.Since(start))
+}
+
+// snapshot returns the cumulative bucket counts, sum and count
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cumulative := make([]uint64, len(h.counts))
+	var running uint64
+	for i, n := range h.counts {
+		running += n
+		cumulative[i] = running
+	}
+	return cumulative, h.sum, h.count
+}
+
+// String implements expvar.Var
+func (h *Histogram) String() string {
+	cumulative, sum, count := h.snapshot()
+	var b strings.Builder
+	b.WriteString(`{"buckets":{`)
+	for i, le := range h.buckets {
+		fmt.Fprintf(&b, `"%s":%d,`, formatBound(le), cumulative[i])
+	}
+	fmt.Fprintf(&b, `"+Inf":%d},"sum":%s,"count":%d}`, cumulative[len(h.buckets)], strconv.FormatFloat(sum, 'g', -1, 64), count)
+	return b.String()
+}
+
+// WritePrometheus writes every registered histogram in the Prometheus text
+// exposition format, sorted by name
+func WritePrometheus(w io.Writer) error {
+	histogramsMu.Lock()
+	all := make([]*Histogram, 0, len(histograms))
+	for _, h := range histograms {
+		all = append(all, h)
+	}
+	histogramsMu.Unlock()
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+
+	for _, h := range all {
+		name := h.name
+		cumulative, sum, count := h.snapshot()
+		var b strings.Builder
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, h.help, name)
+		for i, le := range h.buckets {
+			fmt.Fprintf(&b, "%s_bucket{le=\"%s\"} %d\n", name, formatBound(le), cumulative[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{le=\"+Inf\"} %d\n", name, cumulative[len(h.buckets)])
+		fmt.Fprintf(&b, "%s_sum %s\n%s_count %d\n", name, strconv.FormatFloat(sum, 'g', -1, 64), name, count)
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatBound(le float64) string {
+	return strconv.FormatFloat(le, 'g', -1, 64)
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_histogram_seconds", "A test histogram.", []float64{0.5, 0.1, 1})
+	for _, d := range []time.Duration{
+		50 * time.Millisecond,
+		100 * time.Millisecond, // On a bound counts in that bucket
+		300 * time.Millisecond,
+		2 * time.Second,
+	} {
+		h.Observe(d)
+	}
+
+	var got struct {
+		Buckets map[string]uint64
+		Sum     float64
+		Count   uint64
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("test_histogram_seconds").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{"0.1": 2, "0.5": 3, "1": 3, "+Inf": 4}
+	for le, n := range want {
+		if got.Buckets[le] != n {
+			t.Errorf("bucket le=%s: %d, want %d", le, got.Buckets[le], n)
+		}
+	}
+	if got.Count != 4 || got.Sum < 2.449 || got.Sum > 2.451 {
+		t.Errorf("count %d sum %v, want 4 and 2.45", got.Count, got.Sum)
+	}
+
+	var out bytes.Buffer
+	if err := WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, line := range []string{
+		"# HELP test_histogram_seconds A test histogram.",
+		"# TYPE test_histogram_seconds histogram",
+		`test_histogram_seconds_bucket{le="0.1"} 2`,
+		`test_histogram_seconds_bucket{le="0.5"} 3`,
+		`test_histogram_seconds_bucket{le="+Inf"} 4`,
+		"test_histogram_seconds_count 4",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("exposition lacks %q", line)
+		}
+	}
+}
+
+func TestAuthHistogramsRegistered(t *testing.T) {
+	var out bytes.Buffer
+	if err := WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"auth_bcrypt_hash_seconds", "auth_bcrypt_verify_seconds", "auth_jwt_generate_seconds", "auth_jwt_parse_seconds"} {
+		if expvar.Get(name) == nil {
+			t.Errorf("%s is not published", name)
+		}
+		if !strings.Contains(out.String(), "# TYPE "+name+" histogram\n") {
+			t.Errorf("%s is missing from the Prometheus output", name)
+		}
+	}
+	// Sorted by name, so scrapes diff cleanly
+	if i, j := strings.Index(out.String(), "auth_bcrypt_hash"), strings.Index(out.String(), "auth_jwt_parse"); i < 0 || j < i {
+		t.Error("histograms are not sorted by name")
+	}
+}
+
+func TestNewHistogramDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice didn't panic")
+		}
+	}()
+	NewHistogram("auth_jwt_parse_seconds", "Duplicate.", jwtBuckets)
+}
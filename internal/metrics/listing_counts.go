@@ -0,0 +1,11 @@
+package metrics
+
+import "expvar"
+
+// ListingCountDrift counts listing_counts rows corrected by reconciliation.
+var ListingCountDrift = expvar.NewInt("listing_count_drift")
+
+// IncListingCountDrift records one corrected listing_counts row.
+func IncListingCountDrift() {
+	ListingCountDrift.Add(1)
+}
@@ -0,0 +1,23 @@
+// Package metrics exposes process counters and latency histograms through expvar
+// (served at /debug/vars). Histograms can also be written in the Prometheus text format.
+package metrics
+
+import "expvar"
+
+// Popularity counts view and favorite events, including the ones the bot guards drop.
+var Popularity = expvar.NewMap("popularity")
+
+// Popularity event names
+const (
+	ViewCounted                = "views_counted"
+	ViewExcludedNoUserAgent    = "views_excluded_no_user_agent"
+	ViewExcludedVelocity       = "views_excluded_velocity"
+	ViewExcludedRepeat         = "views_excluded_repeat"
+	FavoriteCounted            = "favorites_counted"
+	FavoriteExcludedNewAccount = "favorites_excluded_new_account"
+)
+
+// IncPopularity increments a popularity event counter.
+func IncPopularity(event string) {
+	Popularity.Add(event, 1)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import "expvar"
+
+// ReadLimiter counts requests admitted to or turned away by the heavy read limiter.
+var ReadLimiter = expvar.NewMap("read_limiter")
+
+// Read limiter event names
+const (
+	ReadLimiterAdmitted = "admitted"
+	ReadLimiterQueued   = "queued"
+	ReadLimiterRejected = "rejected"
+)
+
+// Heavy read limiter occupancy, in weight units, and requests waiting for room
+var (
+	ReadLimiterInUse   = expvar.NewInt("read_limiter_in_use")
+	ReadLimiterWaiting = expvar.NewInt("read_limiter_waiting")
+)
+
+// IncReadLimiter increments a read limiter event counter.
+func IncReadLimiter(event string) {
+	ReadLimiter.Add(event, 1)
+}
+
+// SetReadLimiterOccupancy records the weight in use and the waiting requests.
+func SetReadLimiterOccupancy(inUse, waiting int64) {
+	ReadLimiterInUse.Set(inUse)
+	ReadLimiterWaiting.Set(waiting)
+}
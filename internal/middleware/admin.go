@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequireAdmin only lets users with the admin role through. JWT claims don't
+// carry the role, so it is read from the database on each request; run it
+// after the JWT middleware.
+func RequireAdmin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			JSONError(c, http.StatusUnauthorized, "User not authenticated")
+			return
+		}
+
+		var user models.User
+		if err := db.Select("role").First(&user, userID).Error; err != nil || !user.Role.IsAdmin() {
+			JSONError(c, http.StatusForbidden, "Admin access required")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const bytesPerMB = 1 << 20
+
+// BodyLimiter caps request body size with a global limit that individual
+// routes can override, e.g. to allow large uploads while JSON endpoints stay tight
+type BodyLimiter struct {
+	global int64
+	routes map[string]int64
+}
+
+func NewBodyLimiter(globalMB int) *BodyLimiter {
+	return &BodyLimiter{
+		global: int64(globalMB) * bytesPerMB,
+		routes: make(map[string]int64),
+	}
+}
+
+// SetRouteLimit overrides the global limit for a registered route pattern,
+// e.g. SetRouteLimit(http.MethodPost, "/api/v1/listings/:id/images", 25)
+func (bl *BodyLimiter) SetRouteLimit(method, path string, limitMB int) {
+	bl.routes[method+" "+path] = int64(limitMB) * bytesPerMB
+}
+
+// Limit returns the body limit in bytes that applies to a route
+func (bl *BodyLimiter) Limit(method, path string) int64 {
+	if limit, ok := bl.routes[method+" "+path]; ok {
+		return limit
+	}
+	return bl.global
+}
+
+// Middleware enforces the limit for the matched route
+func (bl *BodyLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := bl.Limit(c.Request.Method, c.FullPath())
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Request body too large",
+			})
+			return
+		}
+
+		// Bodies without a Content-Length are still cut off once they pass the limit
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bl := NewBodyLimiter(1)
+	bl.SetRouteLimit(http.MethodPost, "/upload/:id", 3)
+
+	r := gin.New()
+	r.Use(bl.Middleware())
+	read := func(c *gin.Context) {
+		if _, err := io.Copy(io.Discard, c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	r.POST("/json", read)
+	r.POST("/upload/:id", read)
+	r.PUT("/upload/:id", read)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		size    int64
+		chunked bool // send without a Content-Length
+		want    int
+	}{
+		{name: "global limit accepts a small body", method: http.MethodPost, path: "/json", size: bytesPerMB, want: http.StatusOK},
+		{name: "global limit rejects a large body", method: http.MethodPost, path: "/json", size: 2 * bytesPerMB, want: http.StatusRequestEntityTooLarge},
+		{name: "global limit cuts off a chunked body", method: http.MethodPost, path: "/json", size: 2 * bytesPerMB, chunked: true, want: http.StatusRequestEntityTooLarge},
+		{name: "raised route limit accepts it", method: http.MethodPost, path: "/upload/7", size: 2 * bytesPerMB, want: http.StatusOK},
+		{name: "raised route limit accepts it chunked", method: http.MethodPost, path: "/upload/7", size: 2 * bytesPerMB, chunked: true, want: http.StatusOK},
+		{name: "raised route limit still caps", method: http.MethodPost, path: "/upload/7", size: 4 * bytesPerMB, want: http.StatusRequestEntityTooLarge},
+		{name: "override is per method", method: http.MethodPut, path: "/upload/7", size: 2 * bytesPerMB, want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = bytes.NewReader(make([]byte, tt.size))
+			if tt.chunked {
+				body = io.MultiReader(body) // hides the length from NewRequest
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
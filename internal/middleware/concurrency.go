@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"trade_company/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimiter is a weighted semaphore in front of the expensive read
+// endpoints. It keeps a burst of them from holding every database connection
+// and starving the cheap endpoints, which don't go through it. A nil
+// *ConcurrencyLimiter admits everything.
+type ConcurrencyLimiter struct {
+	capacity int64
+	maxWait  time.Duration
+
+	mu      sync.Mutex
+	used    int64
+	waiters list.List // of *limiterWaiter, first come first served
+}
+
+type limiterWaiter struct {
+	weight int64
+	ready  chan struct{} // Closed once the weight is granted
+}
+
+// NewConcurrencyLimiter returns a limiter admitting up to capacity weight at
+// once, where a request that doesn't fit waits up to maxWait for room. It
+// returns nil when capacity is 0.
+func NewConcurrencyLimiter(capacity int, maxWait time.Duration) *ConcurrencyLimiter {
+	if capacity <= 0 {
+		return nil
+	}
+	return &ConcurrencyLimiter{capacity: int64(capacity), maxWait: maxWait}
+}
+
+// Middleware admits the request with weight units, or answers 503 if none
+// free up within the wait
+func (l *ConcurrencyLimiter) Middleware(weight int64) gin.HandlerFunc {
+	return l.WeightedMiddleware(func(*gin.Context) int64 { return weight })
+}
+
+// WeightedMiddleware is Middleware for routes whose cost depends on the
+// request, e.g. a search costing more than a plain list
+func (l *ConcurrencyLimiter) WeightedMiddleware(weight func(*gin.Context) int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+
+		w := weight(c)
+		if !l.acquire(c.Request.Context(), w) {
+			metrics.IncReadLimiter(metrics.ReadLimiterRejected)
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Server busy, please retry shortly",
+				"code":  "SERVER_BUSY",
+			})
+			return
+		}
+		metrics.IncReadLimiter(metrics.ReadLimiterAdmitted)
+		defer l.release(w)
+		c.Next()
+	}
+}
+
+// acquire takes weight units, waiting in line up to maxWait. A weight above
+// the capacity is clamped, so it runs alone rather than never.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context, weight int64) bool {
+	if weight > l.capacity {
+		weight = l.capacity
+	}
+
+	l.mu.Lock()
+	if l.waiters.Len() == 0 && l.used+weight <= l.capacity {
+		l.used += weight
+		l.recordOccupancy()
+		l.mu.Unlock()
+		return true
+	}
+	if l.maxWait <= 0 {
+		l.mu.Unlock()
+		return false
+	}
+	waiter := &limiterWaiter{weight: weight, ready: make(chan struct{})}
+	elem := l.waiters.PushBack(waiter)
+	l.recordOccupancy()
+	l.mu.Unlock()
+	metrics.IncReadLimiter(metrics.ReadLimiterQueued)
+
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+	select {
+	case <-waiter.ready:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-waiter.ready:
+		// Granted while timing out; keep it
+		return true
+	default:
+	}
+	// Leaving the head of the line may let the requests behind in
+	front := l.waiters.Front() == elem
+	l.waiters.Remove(elem)
+	if front {
+		l.grant()
+	}
+	l.recordOccupancy()
+	return false
+}
+
+func (l *ConcurrencyLimiter) release(weight int64) {
+	if weight > l.capacity {
+		weight = l.capacity
+	}
+	l.mu.Lock()
+	l.used -= weight
+	l.grant()
+	l.recordOccupancy()
+	l.mu.Unlock()
+}
+
+// grant admits waiters from the front of the line while they fit. Requires l.mu.
+func (l *ConcurrencyLimiter) grant() {
+	for front := l.waiters.Front(); front != nil; front = l.waiters.Front() {
+		waiter := front.Value.(*limiterWaiter)
+		if l.used+waiter.weight > l.capacity {
+			return
+		}
+		l.used += waiter.weight
+		l.waiters.Remove(front)
+		close(waiter.ready)
+	}
+}
+
+// recordOccupancy publishes the current state. Requires l.mu.
+func (l *ConcurrencyLimiter) recordOccupancy() {
+	metrics.SetReadLimiterOccupancy(l.used, int64(l.waiters.Len()))
+}
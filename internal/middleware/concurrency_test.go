@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"trade_company/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// queued is how many requests wait in l's line
+func (l *ConcurrencyLimiter) queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiters.Len()
+}
+
+func TestConcurrencyLimiterShedsLoad(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const capacity, requests = 4, 20
+	maxWait := 50 * time.Millisecond
+	limiter := NewConcurrencyLimiter(capacity, maxWait)
+
+	// Admitted requests hold their slot until the test lets them finish, like
+	// slow queries holding database connections
+	var running, peak int64
+	finish := make(chan struct{})
+	r := gin.New()
+	r.GET("/listings", limiter.Middleware(1), func(c *gin.Context) {
+		n := atomic.AddInt64(&running, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		<-finish
+		atomic.AddInt64(&running, -1)
+		c.Status(http.StatusOK)
+	})
+
+	type result struct {
+		w       *httptest.ResponseRecorder
+		elapsed time.Duration
+	}
+	results := make(chan result, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/listings", nil))
+			results <- result{w, time.Since(start)}
+		}()
+	}
+
+	// Everything beyond capacity fails while the admitted requests still run
+	for i := 0; i < requests-capacity; i++ {
+		select {
+		case res := <-results:
+			if res.w.Code != http.StatusServiceUnavailable || res.w.Header().Get("Retry-After") != "1" {
+				t.Fatalf("shed request: status %d, Retry-After %q", res.w.Code, res.w.Header().Get("Retry-After"))
+			}
+			var body map[string]interface{}
+			json.Unmarshal(res.w.Body.Bytes(), &body)
+			if body["code"] != "SERVER_BUSY" {
+				t.Errorf("shed request body %v", body)
+			}
+			if res.elapsed > maxWait+500*time.Millisecond {
+				t.Errorf("shed request took %v, want about %v", res.elapsed, maxWait)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of %d requests over capacity were shed", i, requests-capacity)
+		}
+	}
+	if got := atomic.LoadInt64(&running); got != capacity {
+		t.Errorf("%d requests running, want %d", got, capacity)
+	}
+	if got := metrics.ReadLimiterInUse.Value(); got != capacity {
+		t.Errorf("read_limiter_in_use %d, want %d", got, capacity)
+	}
+
+	close(finish)
+	wg.Wait()
+	close(results)
+	for res := range results {
+		if res.w.Code != http.StatusOK {
+			t.Errorf("admitted request: status %d", res.w.Code)
+		}
+	}
+	if peak > capacity {
+		t.Errorf("%d requests ran at once, want at most %d", peak, capacity)
+	}
+	if got := metrics.ReadLimiterInUse.Value(); got != 0 {
+		t.Errorf("read_limiter_in_use %d after the burst, want 0", got)
+	}
+}
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("waiter admitted when room frees up", func(t *testing.T) {
+		l := NewConcurrencyLimiter(4, time.Second)
+		l.acquire(ctx, 3)
+		admitted := make(chan bool)
+		go func() { admitted <- l.acquire(ctx, 2) }()
+		waitFor(t, "the waiter to queue", func() bool { return l.queued() == 1 })
+		l.release(3)
+		if !<-admitted {
+			t.Error("waiter turned away after room freed up")
+		}
+	})
+
+	t.Run("first come first served", func(t *testing.T) {
+		l := NewConcurrencyLimiter(4, time.Second)
+		l.acquire(ctx, 3)
+		heavy := make(chan bool)
+		go func() { heavy <- l.acquire(ctx, 2) }()
+		waitFor(t, "the heavy request to queue", func() bool { return l.queued() == 1 })
+		// A light request would fit, but mustn't overtake the heavy one
+		light := make(chan bool)
+		go func() { light <- l.acquire(ctx, 1) }()
+		waitFor(t, "the light request to queue", func() bool { return l.queued() == 2 })
+		l.release(3)
+		if !<-heavy || !<-light {
+			t.Error("queued requests not admitted")
+		}
+		if l.used != 3 {
+			t.Errorf("%d in use, want 3", l.used)
+		}
+	})
+
+	t.Run("timed-out head of the line lets the rest in", func(t *testing.T) {
+		l := NewConcurrencyLimiter(4, 50*time.Millisecond)
+		l.acquire(ctx, 3)
+		heavy := make(chan bool)
+		go func() { heavy <- l.acquire(ctx, 4) }()
+		waitFor(t, "the heavy request to queue", func() bool { return l.queued() == 1 })
+		light := make(chan bool)
+		go func() { light <- l.acquire(ctx, 1) }()
+		if <-heavy {
+			t.Error("heavy request admitted over capacity")
+		}
+		if !<-light {
+			t.Error("light request stuck behind a request that gave up")
+		}
+	})
+
+	t.Run("client gone", func(t *testing.T) {
+		l := NewConcurrencyLimiter(1, time.Minute)
+		l.acquire(ctx, 1)
+		cancelled, cancel := context.WithCancel(ctx)
+		done := make(chan bool)
+		go func() { done <- l.acquire(cancelled, 1) }()
+		waitFor(t, "the request to queue", func() bool { return l.queued() == 1 })
+		cancel()
+		select {
+		case ok := <-done:
+			if ok || l.queued() != 0 {
+				t.Errorf("cancelled request admitted %v, %d still queued", ok, l.queued())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("cancelled request still waiting")
+		}
+	})
+
+	t.Run("weight over capacity runs alone", func(t *testing.T) {
+		l := NewConcurrencyLimiter(4, 0)
+		if !l.acquire(ctx, 10) {
+			t.Fatal("oversized request never admitted")
+		}
+		if l.acquire(ctx, 1) {
+			t.Error("request admitted alongside the oversized one")
+		}
+		l.release(10)
+		if l.used != 0 {
+			t.Errorf("%d in use after release, want 0", l.used)
+		}
+	})
+}
+
+func TestConcurrencyLimiterDisabled(t *testing.T) {
+	l := NewConcurrencyLimiter(0, time.Second)
+	if l != nil {
+		t.Fatal("limiter with no capacity is not nil")
+	}
+	r := gin.New()
+	r.GET("/listings", l.Middleware(100), func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/listings", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status %d, want every request admitted", w.Code)
+	}
+}
@@ -2,67 +2,70 @@ package middleware
 
 import (
 	"net/http"
+	"path"
 	"strings"
 
+	"trade_company/internal/config"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORS middleware configuration
-func CORS() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
+// CORSOrigins returns the configured origin allowlist. Entries may contain "*"
+// wildcards, e.g. "http://localhost:*" or "https://*.run.app"; a lone "*"
+// allows any origin without credentials.
+func CORSOrigins(cfg *config.Config) []string {
+	var origins []string
+	for _, o := range strings.Split(cfg.CORSAllowedOrigins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
 
-		// Allow localhost and 127.0.0.1 with any port for development
-		allowed := false
-		if origin != "" {
-			// Allow localhost with any port (http)
-			if strings.HasPrefix(origin, "http://localhost:") {
-				allowed = true
-			}
-			// Allow localhost with any port (https)
-			if strings.HasPrefix(origin, "https://localhost:") {
-				allowed = true
-			}
-			// Allow 127.0.0.1 with any port (http)
-			if strings.HasPrefix(origin, "http://127.0.0.1:") {
-				allowed = true
-			}
-			// Allow 127.0.0.1 with any port (https)
-			if strings.HasPrefix(origin, "https://127.0.0.1:") {
-				allowed = true
-			}
-			// Allow specific network IPs for development (http)
-			if strings.HasPrefix(origin, "http://192.168.") {
-				allowed = true
-			}
-			// Allow specific network IPs for development (http)
-			if strings.HasPrefix(origin, "http://172.") {
-				allowed = true
-			}
-			// Allow Cloud Run frontend domain
-			if origin == "https://business-exchange-frontend-430730011391.us-central1.run.app" {
-				allowed = true
-			}
-			// Allow any .run.app domain for Google Cloud Run
-			if strings.HasSuffix(origin, ".run.app") {
-				allowed = true
-			}
+// originAllowed reports whether origin matches an allowlist entry. "*" doesn't
+// cross "/", so a pattern can't widen past the scheme or host it is written in.
+func originAllowed(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" {
+			continue
+		}
+		if ok, _ := path.Match(o, origin); ok {
+			return true
 		}
+	}
+	return false
+}
 
-		if allowed {
+// CORS applies CORS_ALLOWED_ORIGINS, CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS.
+// Allowed origins are echoed back; credentials are only allowed for an echoed
+// origin, never for the wildcard fallback.
+func CORS(cfg *config.Config) gin.HandlerFunc {
+	origins := CORSOrigins(cfg)
+	wildcard := false
+	for _, o := range origins {
+		if o == "*" {
+			wildcard = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		if origin != "" && originAllowed(origins, origin) {
 			c.Header("Access-Control-Allow-Origin", origin)
 			c.Header("Vary", "Origin")
-		} else {
-			// For development, allow all origins if none match
+			if cfg.CORSAllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		} else if wildcard {
 			c.Header("Access-Control-Allow-Origin", "*")
 		}
 
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID, Origin")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Header("Access-Control-Allow-Headers", cfg.CORSAllowedHeaders)
+		c.Header("Access-Control-Allow-Methods", cfg.CORSAllowedMethods)
 
 		// Handle preflight requests
-		if c.Request.Method == "OPTIONS" {
+		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
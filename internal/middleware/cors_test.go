@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trade_company/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		origins     string
+		credentials bool
+		origin      string
+		wantOrigin  string
+		wantCreds   string
+	}{
+		{name: "allowlisted origin with credentials", origins: "https://app.example.com", credentials: true,
+			origin: "https://app.example.com", wantOrigin: "https://app.example.com", wantCreds: "true"},
+		{name: "allowlisted pattern with credentials", origins: "https://*.example.com", credentials: true,
+			origin: "https://admin.example.com", wantOrigin: "https://admin.example.com", wantCreds: "true"},
+		{name: "origin outside the allowlist", origins: "https://app.example.com", credentials: true,
+			origin: "https://evil.example.org"},
+		{name: "allowlisted origin with credentials off", origins: "https://app.example.com",
+			origin: "https://app.example.com", wantOrigin: "https://app.example.com"},
+		{name: "wildcard with credentials off", origins: "*",
+			origin: "https://evil.example.org", wantOrigin: "*"},
+		// Credentials are never sent with the wildcard, even when enabled
+		{name: "wildcard with credentials on", origins: "*", credentials: true,
+			origin: "https://evil.example.org", wantOrigin: "*"},
+		{name: "wildcard next to an allowlist", origins: "https://app.example.com,*", credentials: true,
+			origin: "https://evil.example.org", wantOrigin: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				CORSAllowedOrigins:   tt.origins,
+				CORSAllowedMethods:   "GET,POST",
+				CORSAllowedHeaders:   "Content-Type",
+				CORSAllowCredentials: tt.credentials,
+			}
+			r := gin.New()
+			r.Use(CORS(cfg))
+			r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for _, method := range []string{http.MethodGet, http.MethodOptions} {
+				req := httptest.NewRequest(method, "/ping", nil)
+				req.Header.Set("Origin", tt.origin)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+					t.Errorf("%s: Access-Control-Allow-Origin %q, want %q", method, got, tt.wantOrigin)
+				}
+				if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+					t.Errorf("%s: Access-Control-Allow-Credentials %q, want %q", method, got, tt.wantCreds)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSCredentialsDefault(t *testing.T) {
+	tests := []struct {
+		origins string
+		want    bool
+	}{
+		{origins: "https://app.example.com", want: true},
+		{origins: "*", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origins, func(t *testing.T) {
+			t.Setenv("APP_ENV", "development")
+			t.Setenv("CORS_ALLOWED_ORIGINS", tt.origins)
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.CORSAllowCredentials != tt.want {
+				t.Errorf("credentials %v, want %v", cfg.CORSAllowCredentials, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrCodeDBUnavailable is the stable error code clients can match on when the database is down
+const ErrCodeDBUnavailable = "DB_UNAVAILABLE"
+
+// RequireDB rejects requests with 503 when the database was never connected or
+// stops answering pings, so handlers can assume a usable connection.
+func RequireDB(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if db == nil {
+			dbUnavailable(c, "Database not available")
+			return
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			dbUnavailable(c, "Database connection error")
+			return
+		}
+
+		if err := sqlDB.PingContext(c.Request.Context()); err != nil {
+			dbUnavailable(c, "Database ping failed")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func dbUnavailable(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error": message,
+		"code":  ErrCodeDBUnavailable,
+	})
+}
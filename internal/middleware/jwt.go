@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
+
+	"trade_company/internal/metrics"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -14,6 +17,8 @@ import (
 type JWTConfig struct {
 	Secret string
 	Issuer string
+	// Passed to every parse, e.g. the clock skew leeway from auth.ParserOptions
+	ParserOptions []jwt.ParserOption
 }
 
 // JWT middleware for authentication
@@ -81,6 +86,7 @@ func JWT(config JWTConfig, logger *zap.Logger) gin.HandlerFunc {
 			zap.String("ip", clientIP),
 			zap.String("token_length", fmt.Sprintf("%d", len(tokenString))))
 
+		parseStart := time.Now()
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			// Validate signing method
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -94,7 +100,8 @@ func JWT(config JWTConfig, logger *zap.Logger) gin.HandlerFunc {
 				zap.String("request_id", requestID),
 				zap.String("ip", clientIP))
 			return []byte(config.Secret), nil
-		})
+		}, config.ParserOptions...)
+		metrics.JWTParseSeconds.Since(parseStart)
 
 		if err != nil {
 			logger.Warn("JWT middleware: Token validation failed",
@@ -157,6 +164,9 @@ func JWT(config JWTConfig, logger *zap.Logger) gin.HandlerFunc {
 
 			// Store the token string in context for proxy handlers
 			c.Set("jwt_token", tokenString)
+			if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+				c.Set("jwt_expires_at", exp.Time)
+			}
 
 			// Set user info in context
 			if userID, exists := claims["uid"]; exists {
@@ -225,6 +235,11 @@ func JWT(config JWTConfig, logger *zap.Logger) gin.HandlerFunc {
 					zap.String("ip", clientIP),
 					zap.String("user_role", fmt.Sprintf("%v", role)))
 			}
+			// Tokens from a login carry its refresh token family, which
+			// identifies the current session
+			if sid, ok := claims["sid"].(string); ok {
+				c.Set("login_session", sid)
+			}
 		} else {
 			logger.Error("JWT middleware: Failed to extract JWT claims",
 				zap.String("request_id", requestID),
@@ -257,32 +272,37 @@ func OptionalJWT(config JWTConfig, logger *zap.Logger) gin.HandlerFunc {
 			zap.String("method", c.Request.Method),
 			zap.String("user_agent", userAgent))
 
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			logger.Info("OptionalJWT middleware: No Authorization header found - proceeding without authentication",
-				zap.String("request_id", requestID),
-				zap.String("ip", clientIP))
-			c.Next()
-			return
-		}
+		// Prefer the auth cookie, like JWT, then fall back to the Authorization header
+		tokenString, _ := c.Cookie("authToken")
+		if tokenString == "" {
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				logger.Info("OptionalJWT middleware: No token found - proceeding without authentication",
+					zap.String("request_id", requestID),
+					zap.String("ip", clientIP))
+				c.Next()
+				return
+			}
 
-		// Try to parse JWT token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			logger.Info("OptionalJWT middleware: Invalid Authorization header format - proceeding without authentication",
-				zap.String("request_id", requestID),
-				zap.String("ip", clientIP),
-				zap.String("auth_header_format", authHeader))
-			c.Next()
-			return
-		}
+			// Try to parse JWT token
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				logger.Info("OptionalJWT middleware: Invalid Authorization header format - proceeding without authentication",
+					zap.String("request_id", requestID),
+					zap.String("ip", clientIP),
+					zap.String("auth_header_format", authHeader))
+				c.Next()
+				return
+			}
 
-		tokenString := parts[1]
+			tokenString = parts[1]
+		}
 		logger.Info("OptionalJWT middleware: Found Bearer token - attempting validation",
 			zap.String("request_id", requestID),
 			zap.String("ip", clientIP),
 			zap.String("token_length", fmt.Sprintf("%d", len(tokenString))))
 
+		parseStart := time.Now()
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				logger.Warn("OptionalJWT middleware: Invalid signing method in optional token",
@@ -292,7 +312,8 @@ func OptionalJWT(config JWTConfig, logger *zap.Logger) gin.HandlerFunc {
 				return nil, jwt.ErrSignatureInvalid
 			}
 			return []byte(config.Secret), nil
-		})
+		}, config.ParserOptions...)
+		metrics.JWTParseSeconds.Since(parseStart)
 
 		if err != nil || !token.Valid {
 			logger.Info("OptionalJWT middleware: Token validation failed - proceeding without authentication",
@@ -316,12 +337,17 @@ func OptionalJWT(config JWTConfig, logger *zap.Logger) gin.HandlerFunc {
 					zap.String("ip", clientIP),
 					zap.String("issuer", config.Issuer))
 
-				if userID, exists := claims["sub"]; exists {
-					c.Set("user_id", userID)
-					logger.Info("OptionalJWT middleware: User ID extracted from sub claim",
+				// Same claim precedence as JWT: uid first, sub for older tokens
+				userID, exists := claims["uid"]
+				if !exists {
+					userID, exists = claims["sub"]
+				}
+				if userIDFloat, ok := userID.(float64); exists && ok {
+					c.Set("user_id", uint(userIDFloat))
+					logger.Info("OptionalJWT middleware: User ID extracted from claims",
 						zap.String("request_id", requestID),
 						zap.String("ip", clientIP),
-						zap.Any("user_id", userID))
+						zap.Uint("user_id", uint(userIDFloat)))
 				}
 				if email, exists := claims["email"]; exists {
 					c.Set("user_email", email)
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"time"
+
+	"trade_company/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequestLogger attaches a child logger carrying the request ID, client IP and
+// user agent to the context; handlers get it with logger.FromContext. Once the
+// request is served it logs one access line through the same logger.
+// Must run after RequestID.
+func RequestLogger(log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		reqLog := log.With(
+			zap.String("request_id", c.GetString("request_id")),
+			zap.String("ip", c.ClientIP()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		)
+		logger.WithContext(c, reqLog)
+		c.Next()
+
+		reqLog.Info("request",
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("query", c.Request.URL.RawQuery),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trade_company/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLoggerCarriesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+
+	r := gin.New()
+	r.Use(RequestID(), RequestLogger(zap.New(core)))
+	r.GET("/ping", func(c *gin.Context) {
+		logger.FromContext(c).Info("handler ran")
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "client request ID", header: "req-123"},
+		{name: "generated request ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			want := w.Header().Get("X-Request-ID")
+			if want == "" || (tt.header != "" && want != tt.header) {
+				t.Fatalf("X-Request-ID %q", want)
+			}
+			entries := logs.All()
+			if len(entries) != 2 {
+				t.Fatalf("%d log entries, want the handler's and the access line", len(entries))
+			}
+			for _, e := range entries {
+				if got := e.ContextMap()["request_id"]; got != want {
+					t.Errorf("%q logged with request_id %v, want %s", e.Message, got, want)
+				}
+			}
+		})
+	}
+}
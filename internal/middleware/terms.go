@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"trade_company/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TermsReacceptPath is where users accept the current terms; RequireTerms
+// never blocks it
+const TermsReacceptPath = "/api/v1/user/accept-terms"
+
+// RequireTerms blocks changes by users who haven't accepted the current terms
+// of service version, with 403 TERMS_REACCEPT_REQUIRED, until they accept it.
+// Reads and the auth endpoints stay open, so users can still sign in, look
+// around and find the new terms. Run it after the JWT middleware.
+func RequireTerms(db *gorm.DB, version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		path := c.FullPath()
+		if db == nil || path == TermsReacceptPath || strings.HasPrefix(path, "/api/v1/auth/") {
+			c.Next()
+			return
+		}
+
+		userID, exists := GetUserID(c)
+		if !exists {
+			JSONError(c, http.StatusUnauthorized, "User not authenticated")
+			return
+		}
+
+		var accepted int64
+		if err := db.Model(&models.TermsAcceptance{}).
+			Where("user_id = ? AND version = ?", userID, version).
+			Count(&accepted).Error; err != nil {
+			JSONError(c, http.StatusInternalServerError, "Failed to check terms acceptance")
+			return
+		}
+		if accepted == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":         "The terms of service have changed; accept them to continue",
+				"code":          "TERMS_REACCEPT_REQUIRED",
+				"terms_version": version,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
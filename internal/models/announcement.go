@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Announcement levels
+const (
+	AnnouncementLevelInfo    = "info"
+	AnnouncementLevelWarning = "warning"
+)
+
+// Announcement audiences
+const (
+	AnnouncementAudienceAll     = "all"
+	AnnouncementAudienceSellers = "sellers"
+	AnnouncementAudienceBuyers  = "buyers"
+)
+
+// Announcement is a site-wide banner shown between StartsAt and EndsAt
+type Announcement struct {
+	ID        uint          `gorm:"primaryKey" json:"id"`
+	Messages  LocalizedText `gorm:"type:json;not null" json:"messages"`
+	Level     string        `gorm:"size:20;not null;default:info" json:"level"`
+	Audience  string        `gorm:"size:20;not null;default:all" json:"audience"`
+	StartsAt  time.Time     `gorm:"not null;index:idx_announcements_window" json:"starts_at"`
+	EndsAt    *time.Time    `gorm:"index:idx_announcements_window" json:"ends_at,omitempty"` // nil runs until removed
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// LiveAt reports whether the announcement is inside its scheduling window
+func (a *Announcement) LiveAt(now time.Time) bool {
+	return !now.Before(a.StartsAt) && (a.EndsAt == nil || now.Before(*a.EndsAt))
+}
+
+// ShownTo reports whether an audience ("sellers" or "buyers"; "" for anonymous
+// visitors) should see the announcement
+func (a *Announcement) ShownTo(audience string) bool {
+	return a.Audience == AnnouncementAudienceAll || a.Audience == audience
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Auction service webhook event types
+const (
+	AuctionEventClosed    = "auction.closed"
+	AuctionEventBidWinner = "bid.winner"
+)
+
+// AuctionEvent is a webhook delivery from the auction service, stored as
+// received so it can be replayed. EventID makes redeliveries no-ops once
+// ProcessedAt is set; Error holds why the last attempt failed.
+type AuctionEvent struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	EventID     string     `gorm:"size:100;not null;uniqueIndex" json:"event_id"`
+	Type        string     `gorm:"size:50;not null" json:"type"`
+	Payload     string     `gorm:"type:text;not null" json:"payload"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+	Error       string     `gorm:"size:500" json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
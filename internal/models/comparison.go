@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ComparisonList is a user's saved, named set of listings to compare
+type ComparisonList struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relations
+	Items []ComparisonListItem `gorm:"foreignKey:ComparisonListID" json:"items,omitempty"`
+}
+
+// ComparisonListItem is one listing in a comparison list. Items are kept when
+// their listing stops being active so the user can see what changed.
+type ComparisonListItem struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ComparisonListID uint      `gorm:"uniqueIndex:unique_comparison_listing;not null" json:"comparison_list_id"`
+	ListingID        uint      `gorm:"uniqueIndex:unique_comparison_listing;not null" json:"listing_id"`
+	CreatedAt        time.Time `json:"created_at"`
+
+	// Relations
+	Listing Listing `gorm:"foreignKey:ListingID" json:"listing,omitempty"`
+}
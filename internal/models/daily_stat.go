@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// DailyStat is the admin dashboard snapshot of one calendar day in Taipei time.
+// Rows are written by the daily stats job and rebuilt by `jobs backfill-stats`.
+type DailyStat struct {
+	Date                  time.Time `gorm:"primaryKey;type:date" json:"date"`
+	NewUsers              int64     `gorm:"not null;default:0" json:"new_users"`
+	NewListings           int64     `gorm:"not null;default:0" json:"new_listings"`
+	Leads                 int64     `gorm:"not null;default:0" json:"leads"` // Excludes spam
+	TransactionsCompleted int64     `gorm:"not null;default:0" json:"transactions_completed"`
+	TransactionVolume     int64     `gorm:"not null;default:0" json:"transaction_volume"` // Sum of completed amounts
+	ActiveUsers           int64     `gorm:"not null;default:0" json:"active_users"`       // Logged in, messaged or sent a lead
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+func (DailyStat) TableName() string {
+	return "daily_stats"
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Email delivery events recorded from the SendGrid event webhook
+const (
+	EmailEventDelivered    = "delivered"
+	EmailEventBounce       = "bounce"
+	EmailEventDropped      = "dropped"
+	EmailEventDeferred     = "deferred"
+	EmailEventSpamReport   = "spamreport"
+	EmailEventUnsubscribed = "unsubscribe"
+)
+
+// EmailBounceTypeBlocked is SendGrid's soft bounce; any other bounce type is permanent
+const EmailBounceTypeBlocked = "blocked"
+
+// EmailEvent is one delivery event for an outgoing email. UserID is set when
+// the recipient matches a registered user.
+type EmailEvent struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	UserID            *uint     `gorm:"index" json:"user_id,omitempty"`
+	Email             string    `gorm:"size:255;not null;index" json:"email"`
+	Event             string    `gorm:"size:32;not null" json:"event"`
+	BounceType        string    `gorm:"size:32" json:"bounce_type,omitempty"`
+	Reason            string    `gorm:"size:500" json:"reason,omitempty"`
+	ProviderEventID   string    `gorm:"size:100;not null;uniqueIndex" json:"-"`
+	ProviderMessageID string    `gorm:"size:255" json:"provider_message_id,omitempty"`
+	OccurredAt        time.Time `gorm:"not null" json:"occurred_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// MakesUndeliverable reports whether the event means no more non-critical
+// email should go to the address: a hard bounce or a spam complaint.
+func (e *EmailEvent) MakesUndeliverable() bool {
+	switch e.Event {
+	case EmailEventBounce:
+		return e.BounceType != EmailBounceTypeBlocked
+	case EmailEventSpamReport:
+		return true
+	}
+	return false
+}
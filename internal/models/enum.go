@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// enum is a string type with a fixed set of values: Role, ListingStatus,
+// TransactionStatus, InquiryType and MemberRole. Valid lists every value in a switch, so adding one means
+// updating it, the helpers next to it and the column's CHECK constraint.
+type enum interface {
+	~string
+	Valid() bool
+}
+
+// InvalidEnumError reports a value outside an enum, from JSON or the database
+type InvalidEnumError struct {
+	Type  string
+	Value string
+}
+
+func (e *InvalidEnumError) Error() string {
+	return fmt.Sprintf("invalid %s %q", e.Type, e.Value)
+}
+
+// enumValue implements driver.Valuer, so an invalid value never reaches the
+// database even where the column isn't constrained yet
+func enumValue[T enum](v T, typ string) (driver.Value, error) {
+	if !v.Valid() {
+		return nil, &InvalidEnumError{Type: typ, Value: string(v)}
+	}
+	return string(v), nil
+}
+
+// scanEnum implements sql.Scanner
+func scanEnum[T enum](dst *T, src interface{}, typ string) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into %s", src, typ)
+	}
+	if !T(s).Valid() {
+		return &InvalidEnumError{Type: typ, Value: s}
+	}
+	*dst = T(s)
+	return nil
+}
+
+// unmarshalEnum implements json.Unmarshaler, so request bodies with an unknown
+// value fail to bind. An empty string is left as the zero value, i.e. not set;
+// handlers that require the field check it like any other.
+func unmarshalEnum[T enum](dst *T, data []byte, typ string) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s != "" && !T(s).Valid() {
+		return &InvalidEnumError{Type: typ, Value: s}
+	}
+	*dst = T(s)
+	return nil
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// enumPtr is an enum's pointer type, which scans and unmarshals
+type enumPtr[T enum] interface {
+	*T
+	sql.Scanner
+	json.Unmarshaler
+}
+
+// checkEnum tests that every value in values passes and that bad is rejected
+// by Valid, Value, Scan and UnmarshalJSON
+func checkEnum[T interface {
+	enum
+	driver.Valuer
+}, P enumPtr[T]](t *testing.T, values []T, bad T) {
+	t.Helper()
+	for _, v := range values {
+		var scanned, decoded T
+		if !v.Valid() {
+			t.Errorf("%q is not valid", v)
+		}
+		if got, err := v.Value(); err != nil || got != string(v) {
+			t.Errorf("%q Value() = %v, %v", v, got, err)
+		}
+		if err := P(&scanned).Scan([]byte(v)); err != nil || scanned != v {
+			t.Errorf("scan %q: %q, %v", v, scanned, err)
+		}
+		data, _ := json.Marshal(string(v))
+		if err := json.Unmarshal(data, P(&decoded)); err != nil || decoded != v {
+			t.Errorf("unmarshal %q: %q, %v", v, decoded, err)
+		}
+	}
+
+	var invalid *InvalidEnumError
+	var scanned, decoded T
+	if bad.Valid() {
+		t.Errorf("%q is valid", bad)
+	}
+	if _, err := bad.Value(); !errors.As(err, &invalid) {
+		t.Errorf("%q Value() error %v", bad, err)
+	}
+	if err := P(&scanned).Scan(string(bad)); !errors.As(err, &invalid) || scanned != "" {
+		t.Errorf("scan %q: %q, %v", bad, scanned, err)
+	}
+	data, _ := json.Marshal(string(bad))
+	if err := json.Unmarshal(data, P(&decoded)); !errors.As(err, &invalid) {
+		t.Errorf("unmarshal %q error %v", bad, err)
+	}
+	// An empty JSON value means not set and is left to the handler
+	if err := json.Unmarshal([]byte(`""`), P(&decoded)); err != nil {
+		t.Errorf("unmarshal empty: %v", err)
+	}
+}
+
+func TestEnums(t *testing.T) {
+	t.Run("Role", func(t *testing.T) { checkEnum(t, Roles, "Admin") })
+	t.Run("ListingStatus", func(t *testing.T) { checkEnum(t, ListingStatuses, "active") })
+	t.Run("TransactionStatus", func(t *testing.T) { checkEnum(t, TransactionStatuses, "paid ") })
+	t.Run("InquiryType", func(t *testing.T) { checkEnum(t, InquiryTypes, "other") })
+	t.Run("MemberRole", func(t *testing.T) { checkEnum(t, MemberRoles, "owner") })
+}
+
+func TestEnumHelpersCoverEveryValue(t *testing.T) {
+	for _, s := range ListingStatuses {
+		if s.Hidden() && s.OwnerSettable() {
+			t.Errorf("%q is both hidden and owner-settable", s)
+		}
+	}
+	hidden := 0
+	for _, s := range ListingStatuses {
+		if s.Hidden() {
+			hidden++
+		}
+	}
+	if hidden != len(HiddenListingStatuses) {
+		t.Errorf("%d statuses report Hidden, HiddenListingStatuses has %d", hidden, len(HiddenListingStatuses))
+	}
+	for _, r := range Roles {
+		if r.IsAdmin() != (r == RoleAdmin) {
+			t.Errorf("%q IsAdmin() = %v", r, r.IsAdmin())
+		}
+	}
+	for _, s := range TransactionStatuses {
+		final := s == TransactionStatusCancelled || s == TransactionStatusRefunded
+		if s.Final() != final {
+			t.Errorf("%q Final() = %v", s, s.Final())
+		}
+	}
+}
+
+func TestEnumColumnsRejectInvalidValues(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&User{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// Writes of an invalid value fail before reaching the database
+	var invalid *InvalidEnumError
+	err = db.Create(&User{Email: "typo@example.com", Username: "typo", Role: "amdin"}).Error
+	if !errors.As(err, &invalid) {
+		t.Fatalf("create with invalid role: %v", err)
+	}
+	if err := db.Create(&User{Email: "ok@example.com", Username: "ok", Role: RoleSeller}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// A row changed behind the app's back doesn't load as a silent state
+	db.Exec("UPDATE users SET role = 'superuser'")
+	var user User
+	if err := db.First(&user).Error; !errors.As(err, &invalid) {
+		t.Errorf("load of invalid role: %v, role %q", err, user.Role)
+	}
+}
@@ -2,17 +2,39 @@ package models
 
 import "time"
 
+// Image moderation statuses. Pending images are only shown to the owner until
+// the moderation job approves them; flagged ones wait for an admin.
+const (
+	ImageModerationPending  = "pending"
+	ImageModerationApproved = "approved"
+	ImageModerationFlagged  = "flagged"
+)
+
 type Image struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	ListingID uint      `gorm:"index;not null" json:"listing_id"`
-	Filename  string    `gorm:"size:255;not null" json:"filename"`
-	URL       string    `gorm:"size:500;not null" json:"url"`
-	AltText   string    `gorm:"size:255" json:"alt_text"`
-	Order     int       `gorm:"default:0" json:"order"`
-	IsPrimary bool      `gorm:"default:false" json:"is_primary"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ListingID        uint       `gorm:"index;not null" json:"listing_id"`
+	Filename         string     `gorm:"size:255;not null" json:"filename"`
+	URL              string     `gorm:"size:500;not null" json:"url"`
+	AltText          string     `gorm:"size:255" json:"alt_text"`
+	Order            int        `gorm:"default:0" json:"order"`
+	IsPrimary        bool       `gorm:"default:false" json:"is_primary"`
+	ModerationStatus string     `gorm:"size:20;not null;default:pending;index" json:"moderation_status"`
+	ModerationReason string     `gorm:"size:255" json:"moderation_reason,omitempty"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 	
 	// Relations
 	Listing Listing `gorm:"foreignKey:ListingID" json:"listing,omitempty"`
 }
+
+// ApprovedImages returns the images that may appear in public responses
+func ApprovedImages(images []Image) []Image {
+	approved := make([]Image, 0, len(images))
+	for _, img := range images {
+		if img.ModerationStatus == ImageModerationApproved {
+			approved = append(approved, img)
+		}
+	}
+	return approved
+}
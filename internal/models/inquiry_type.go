@@ -0,0 +1,54 @@
+package models
+
+import "database/sql/driver"
+
+// InquiryType is what a buyer's lead is about, chosen on the contact form so
+// sellers can triage their leads. Free-text leads are InquiryGeneral.
+type InquiryType string
+
+const (
+	InquiryGeneral           InquiryType = "general"
+	InquiryPriceNegotiation  InquiryType = "price_negotiation"
+	InquiryRequestFinancials InquiryType = "request_financials"
+	InquiryScheduleViewing   InquiryType = "schedule_viewing"
+	InquiryFinancingQuestion InquiryType = "financing_question"
+)
+
+// InquiryTypes are the values allowed in leads.inquiry_type
+var InquiryTypes = []InquiryType{
+	InquiryGeneral,
+	InquiryPriceNegotiation,
+	InquiryRequestFinancials,
+	InquiryScheduleViewing,
+	InquiryFinancingQuestion,
+}
+
+// Valid reports whether t is one of InquiryTypes
+func (t InquiryType) Valid() bool {
+	switch t {
+	case InquiryGeneral, InquiryPriceNegotiation, InquiryRequestFinancials, InquiryScheduleViewing, InquiryFinancingQuestion:
+		return true
+	}
+	return false
+}
+
+// Label is the inquiry type as shown to sellers in notification emails
+func (t InquiryType) Label() string {
+	switch t {
+	case InquiryPriceNegotiation:
+		return "Price negotiation"
+	case InquiryRequestFinancials:
+		return "Request for financials"
+	case InquiryScheduleViewing:
+		return "Schedule a viewing"
+	case InquiryFinancingQuestion:
+		return "Financing question"
+	}
+	return "General inquiry"
+}
+
+func (t InquiryType) Value() (driver.Value, error) { return enumValue(t, "inquiry type") }
+func (t *InquiryType) Scan(src interface{}) error  { return scanEnum(t, src, "inquiry type") }
+func (t *InquiryType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(t, data, "inquiry type")
+}
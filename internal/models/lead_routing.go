@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MemberRole is what an organization member may do with its leads
+type MemberRole string
+
+const (
+	// MemberRoleAdmin members see every lead of the organization and reassign them
+	MemberRoleAdmin MemberRole = "admin"
+	// MemberRoleAgent members work the leads assigned to them
+	MemberRoleAgent MemberRole = "agent"
+)
+
+// MemberRoles are the values allowed in organization_members.role
+var MemberRoles = []MemberRole{MemberRoleAdmin, MemberRoleAgent}
+
+// Valid reports whether r is one of MemberRoles
+func (r MemberRole) Valid() bool {
+	switch r {
+	case MemberRoleAdmin, MemberRoleAgent:
+		return true
+	}
+	return false
+}
+
+func (r MemberRole) Value() (driver.Value, error)     { return enumValue(r, "member role") }
+func (r *MemberRole) Scan(src interface{}) error      { return scanEnum(r, src, "member role") }
+func (r *MemberRole) UnmarshalJSON(data []byte) error { return unmarshalEnum(r, data, "member role") }
+
+// OrganizationMember is a user who works the leads of an organization. An
+// organization is the seller account its listings belong to; that account is
+// always an admin of it and has no member row.
+type OrganizationMember struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"not null;uniqueIndex:idx_organization_members_org_user" json:"organization_id"`
+	UserID         uint       `gorm:"not null;uniqueIndex:idx_organization_members_org_user;index" json:"user_id"`
+	Role           MemberRole `gorm:"size:20;not null;default:agent" json:"role"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// LeadRoutingRule is how an organization's new leads are handed out: extra
+// addresses copied on every lead email, and whether leads rotate among the
+// members instead of all going to the seller account
+type LeadRoutingRule struct {
+	OrganizationID uint      `gorm:"primaryKey;autoIncrement:false" json:"organization_id"`
+	NotifyEmails   string    `gorm:"size:1000;not null;default:''" json:"-"` // Comma-separated
+	RoundRobin     bool      `gorm:"not null;default:false" json:"round_robin"`
+	LastAssigneeID *uint     `json:"last_assignee_id,omitempty"` // Round-robin position
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Emails are the rule's extra notification addresses
+func (r *LeadRoutingRule) Emails() []string {
+	var emails []string
+	for _, e := range strings.Split(r.NotifyEmails, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			emails = append(emails, e)
+		}
+	}
+	return emails
+}
+
+// LeadRoute is who a new lead goes to
+type LeadRoute struct {
+	AssigneeID   uint
+	NotifyEmails []string
+}
+
+// RouteLead picks the assignee for a new lead to organizationID and advances
+// the round-robin position. Without a rule, or with round robin off or no
+// members, the lead goes to the organization's own account. Run it in the
+// transaction that creates the lead: the rule row is locked so concurrent
+// leads take turns rather than landing on the same member.
+func RouteLead(tx *gorm.DB, organizationID uint) (LeadRoute, error) {
+	route := LeadRoute{AssigneeID: organizationID}
+
+	var rule LeadRoutingRule
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rule, organizationID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return route, nil
+	}
+	if err != nil {
+		return route, err
+	}
+	route.NotifyEmails = rule.Emails()
+	if !rule.RoundRobin {
+		return route, nil
+	}
+
+	var members []uint
+	if err := tx.Model(&OrganizationMember{}).
+		Where("organization_id = ?", organizationID).
+		Order("user_id").
+		Pluck("user_id", &members).Error; err != nil {
+		return route, err
+	}
+	if len(members) == 0 {
+		return route, nil
+	}
+
+	route.AssigneeID = NextAssignee(members, rule.LastAssigneeID)
+	err = tx.Model(&rule).Update("last_assignee_id", route.AssigneeID).Error
+	return route, err
+}
+
+// NextAssignee is the member after last in members (sorted by ID), wrapping
+// around, so every member gets one lead per round. Removed members are
+// skipped and new ones join the rotation at their place in the order.
+func NextAssignee(members []uint, last *uint) uint {
+	if last != nil {
+		for _, id := range members {
+			if id > *last {
+				return id
+			}
+		}
+	}
+	return members[0]
+}
+
+// OrganizationAdmin reports whether userID administers organizationID: it is
+// the organization's own account or an admin member
+func OrganizationAdmin(db *gorm.DB, organizationID, userID uint) (bool, error) {
+	if organizationID == userID {
+		return true, nil
+	}
+	var count int64
+	err := db.Model(&OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ? AND role = ?", organizationID, userID, MemberRoleAdmin).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// OrganizationMemberOf reports whether userID may be assigned leads of
+// organizationID: the organization's own account or any member
+func OrganizationMemberOf(db *gorm.DB, organizationID, userID uint) (bool, error) {
+	if organizationID == userID {
+		return true, nil
+	}
+	var count int64
+	err := db.Model(&OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
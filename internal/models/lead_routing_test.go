@@ -0,0 +1,44 @@
+package models
+
+import "testing"
+
+func TestNextAssignee(t *testing.T) {
+	id := func(n uint) *uint { return &n }
+	tests := []struct {
+		name    string
+		members []uint
+		last    *uint
+		want    uint
+	}{
+		{name: "first lead", members: []uint{3, 5, 9}, want: 3},
+		{name: "next in order", members: []uint{3, 5, 9}, last: id(3), want: 5},
+		{name: "wraps around", members: []uint{3, 5, 9}, last: id(9), want: 3},
+		{name: "last was removed", members: []uint{3, 9}, last: id(5), want: 9},
+		{name: "last removed at the end", members: []uint{3, 5}, last: id(9), want: 3},
+		{name: "new member joins in order", members: []uint{3, 4, 5}, last: id(3), want: 4},
+		{name: "single member", members: []uint{7}, last: id(7), want: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextAssignee(tt.members, tt.last); got != tt.want {
+				t.Errorf("NextAssignee(%v, %v) = %d, want %d", tt.members, tt.last, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextAssigneeIsFair(t *testing.T) {
+	members := []uint{2, 4, 6, 8}
+	counts := map[uint]int{}
+	var last *uint
+	for i := 0; i < 10*len(members); i++ {
+		next := NextAssignee(members, last)
+		counts[next]++
+		last = &next
+	}
+	for _, m := range members {
+		if counts[m] != 10 {
+			t.Errorf("member %d got %d leads, want 10: %v", m, counts[m], counts)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LeadTemplate is a curated question a buyer can start an inquiry from.
+// Templates are managed by admins; retired ones are deactivated rather than
+// deleted so the leads they seeded still count towards usage.
+type LeadTemplate struct {
+	ID        uint          `gorm:"primaryKey" json:"id"`
+	Key       string        `gorm:"column:template_key;size:50;not null;uniqueIndex" json:"key"`
+	Industry  string        `gorm:"size:100;not null;default:''" json:"industry"` // "" for every industry
+	Questions LocalizedText `gorm:"type:json;not null" json:"questions"`
+	SortOrder int           `gorm:"not null;default:0" json:"sort_order"`
+	Active    bool          `gorm:"not null" json:"active"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
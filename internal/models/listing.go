@@ -1,34 +1,138 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// ListingStatus is a listing's lifecycle state. Active, inactive and sold are
+// stored in Chinese for the frontend; the two-step deletion statuses are
+// internal. Inactive is a pause the owner can undo, sold is a final sale that
+// stays visible to its owner and in message history, and pending_delete/deleted
+// remove the listing entirely.
+type ListingStatus string
+
+const (
+	ListingStatusActive        ListingStatus = "活躍"
+	ListingStatusInactive      ListingStatus = "不活躍"
+	ListingStatusSold          ListingStatus = "已售出"
+	ListingStatusPendingDelete ListingStatus = "pending_delete"
+	ListingStatusDeleted       ListingStatus = "deleted"
+)
+
+// ListingStatuses are the values allowed in listings.status
+var ListingStatuses = []ListingStatus{
+	ListingStatusActive, ListingStatusInactive, ListingStatusSold, ListingStatusPendingDelete, ListingStatusDeleted,
+}
+
+// HiddenListingStatuses are excluded from every public listing endpoint
+var HiddenListingStatuses = []ListingStatus{ListingStatusPendingDelete, ListingStatusDeleted}
+
+// Valid reports whether s is one of ListingStatuses
+func (s ListingStatus) Valid() bool {
+	switch s {
+	case ListingStatusActive, ListingStatusInactive, ListingStatusSold, ListingStatusPendingDelete, ListingStatusDeleted:
+		return true
+	}
+	return false
+}
+
+// Hidden reports whether the status is one of HiddenListingStatuses
+func (s ListingStatus) Hidden() bool {
+	switch s {
+	case ListingStatusPendingDelete, ListingStatusDeleted:
+		return true
+	case ListingStatusActive, ListingStatusInactive, ListingStatusSold:
+		return false
+	}
+	return false
+}
+
+// OwnerSettable reports whether an owner may set the status through a listing
+// update. Sold has its own endpoint and deletion goes through DELETE.
+func (s ListingStatus) OwnerSettable() bool {
+	switch s {
+	case ListingStatusActive, ListingStatusInactive:
+		return true
+	case ListingStatusSold, ListingStatusPendingDelete, ListingStatusDeleted:
+		return false
+	}
+	return false
+}
+
+func (s ListingStatus) Value() (driver.Value, error) { return enumValue(s, "listing status") }
+func (s *ListingStatus) Scan(src interface{}) error  { return scanEnum(s, src, "listing status") }
+func (s *ListingStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(s, data, "listing status")
+}
+
+// Listing visibilities. Unlisted listings are left out of browsing and search but
+// open to anyone with the link; private listings are only shown to their owner.
+const (
+	ListingVisibilityPublic   = "public"
+	ListingVisibilityUnlisted = "unlisted"
+	ListingVisibilityPrivate  = "private"
+)
+
+// ListingVisibilities are the values accepted for Listing.Visibility
+var ListingVisibilities = []string{ListingVisibilityPublic, ListingVisibilityUnlisted, ListingVisibilityPrivate}
+
+// ListingDeleteUndoWindow is how long an owner can restore a listing after deleting it
+const ListingDeleteUndoWindow = 7 * 24 * time.Hour
 
 type Listing struct {
-	ID                uint      `gorm:"primaryKey" json:"id"`
-	Title             string    `gorm:"size:255;not null;index" json:"title"`
-	Description       string    `gorm:"type:text" json:"description"`
-	Price             int64     `gorm:"not null;index" json:"price"`
-	Category          string    `gorm:"size:100;index" json:"category"`
-	Condition         string    `gorm:"size:50;default:used" json:"condition"`
-	Location          string    `gorm:"size:255;index" json:"location"`
-	Status            string    `gorm:"size:50;default:活躍;index" json:"status"`
-	OwnerID           uint      `gorm:"index;not null" json:"owner_id"`
-	ViewCount         int       `gorm:"default:0" json:"view_count"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
-	BrandStory        string    `gorm:"type:text" json:"brand_story,omitempty"`
-	Rent              int64     `gorm:"index" json:"rent,omitempty"`
-	Floor             int       `json:"floor,omitempty"`
-	Equipment         string    `gorm:"type:text" json:"equipment,omitempty"`
-	Decoration        string    `gorm:"size:100" json:"decoration,omitempty"`
-	AnnualRevenue     int64     `json:"annual_revenue,omitempty"`
-	GrossProfitRate   float64   `json:"gross_profit_rate,omitempty"`
-	FastestMovingDate time.Time `json:"fastest_moving_date,omitempty"`
-	PhoneNumber       string    `gorm:"size:20" json:"phone_number,omitempty"`
-	SquareMeters      float64   `json:"square_meters,omitempty"`
-	Industry          string    `gorm:"size:100;index" json:"industry,omitempty"`
-	Deposit           int64     `json:"deposit,omitempty"`
+	ID                uint          `gorm:"primaryKey" json:"id"`
+	Title             string        `gorm:"size:255;not null;index" json:"title"`
+	Description       string        `gorm:"type:text" json:"description"`      // Sanitized HTML, see sanitize.Description
+	DescriptionText   string        `gorm:"type:text" json:"description_text"` // Plain-text version for search and previews
+	Price             int64         `gorm:"not null;index" json:"price"`
+	Category          string        `gorm:"size:100;index" json:"category"`
+	Condition         string        `gorm:"size:50;default:used" json:"condition"`
+	Location          string        `gorm:"size:255;index" json:"location"`
+	Status            ListingStatus `gorm:"size:50;default:活躍;index" json:"status"`
+	Visibility        string        `gorm:"size:20;not null;default:public;index" json:"visibility"`
+	DeleteAfter       *time.Time    `gorm:"index" json:"delete_after,omitempty"`
+	ExpiresAt         *time.Time    `gorm:"index" json:"expires_at,omitempty"` // nil never expires
+	OwnerID           uint          `gorm:"index;not null" json:"owner_id"`
+	ViewCount         int           `gorm:"default:0" json:"view_count"`
+	FavoriteCount     int           `gorm:"default:0" json:"favorite_count"` // Excludes favorites from new accounts
+	HideViewCount     bool          `gorm:"default:false" json:"hide_view_count"`
+	HideFavoriteCount bool          `gorm:"default:false" json:"hide_favorite_count"`
+	HideLastActive    bool          `gorm:"default:false" json:"hide_last_active"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+	BrandStory        string        `gorm:"type:text" json:"brand_story,omitempty"`
+	Rent              int64         `gorm:"index" json:"rent,omitempty"`
+	Floor             int           `json:"floor,omitempty"`
+	Equipment         string        `gorm:"type:text" json:"equipment,omitempty"`
+	Decoration        string        `gorm:"size:100" json:"decoration,omitempty"`
+	AnnualRevenue     int64         `json:"annual_revenue,omitempty"`
+	GrossProfitRate   float64       `json:"gross_profit_rate,omitempty"`
+	FastestMovingDate time.Time     `json:"fastest_moving_date,omitempty"`
+	PhoneNumber       string        `gorm:"size:20" json:"phone_number,omitempty"`
+	SquareMeters      float64       `json:"square_meters,omitempty"`
+	Industry          string        `gorm:"size:100;index" json:"industry,omitempty"`
+	Deposit           int64         `json:"deposit,omitempty"`
+	// Machine translations, cleared whenever the title or description changes
+	TitleEn                *string    `gorm:"size:255" json:"title_en,omitempty"`
+	DescriptionEn          *string    `gorm:"type:text" json:"description_en,omitempty"` // Sanitized HTML
+	TranslationRequestedAt *time.Time `gorm:"index" json:"-"`                            // Queued for the translation job
+	// Revenue and margin checked by an admin against submitted statements;
+	// cleared whenever either figure changes
+	FinancialsVerified    bool       `gorm:"not null;default:false;index" json:"financials_verified"`
+	FinancialsVerifiedAt  *time.Time `json:"financials_verified_at,omitempty"`
+	FinancialsVerifiedBy  *uint      `json:"-"`              // Reviewing admin
+	FinancialsSubmittedAt *time.Time `gorm:"index" json:"-"` // Statements awaiting review
+	// Status the listing had when it was deleted, which undoing the deletion restores
+	StatusBeforeDelete *ListingStatus `gorm:"size:50" json:"-"`
 	// Relations
-	Owner     User       `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
-	Images    []Image    `gorm:"foreignKey:ListingID" json:"images,omitempty"`
-	Favorites []Favorite `gorm:"foreignKey:ListingID" json:"favorites,omitempty"`
+	Owner     User              `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	Images    []Image           `gorm:"foreignKey:ListingID" json:"images,omitempty"`
+	Documents []ListingDocument `gorm:"foreignKey:ListingID" json:"-"`
+	Favorites []Favorite        `gorm:"foreignKey:ListingID" json:"favorites,omitempty"`
+
+	// privateStatsVisible is set by ShowPrivateStats for owner/admin responses
+	privateStatsVisible bool
+	// countedBefore carries the listing_counts keys from BeforeUpdate to AfterUpdate
+	countedBefore *[]ListingCount
 }
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Listing confirmation outcomes. A confirmation without one is still waiting
+// for the seller.
+const (
+	ListingConfirmationConfirmed = "confirmed" // Seller says the listing is still available
+	ListingConfirmationSold      = "sold"      // Seller took it down from the email
+	ListingConfirmationArchived  = "archived"  // No answer before the deadline
+)
+
+// ListingConfirmation asks a seller who returns after a long absence whether an
+// active listing is still available. Rows are created at login and emailed by
+// the keep-alive job, which also sets the token and the deadline; unanswered
+// listings are archived once the deadline passes.
+type ListingConfirmation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ListingID  uint       `gorm:"not null;index" json:"listing_id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash  *string    `gorm:"size:64;uniqueIndex" json:"-"` // SHA-256 of the emailed token; nil until sent
+	NotifiedAt *time.Time `gorm:"index" json:"notified_at,omitempty"`
+	Deadline   *time.Time `gorm:"index" json:"deadline,omitempty"`
+	Resolution string     `gorm:"size:20;not null;default:'';index" json:"resolution"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Relations
+	Listing Listing `gorm:"foreignKey:ListingID" json:"listing,omitempty"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Listing count dimensions
+const (
+	ListingCountCategory = "category"
+	ListingCountIndustry = "industry"
+)
+
+// ListingCount is the number of active listings with a given category or industry
+type ListingCount struct {
+	Dimension string    `gorm:"primaryKey;size:20" json:"dimension"`
+	Value     string    `gorm:"primaryKey;size:100" json:"value"`
+	Total     int       `gorm:"not null;default:0" json:"total"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// countKeys returns the listing_counts rows a listing contributes to; only
+// active, public listings are counted
+func (l *Listing) countKeys() []ListingCount {
+	if l.Status != ListingStatusActive || !l.IsPublic() {
+		return nil
+	}
+	var keys []ListingCount
+	if l.Category != "" {
+		keys = append(keys, ListingCount{Dimension: ListingCountCategory, Value: l.Category})
+	}
+	if l.Industry != "" {
+		keys = append(keys, ListingCount{Dimension: ListingCountIndustry, Value: l.Industry})
+	}
+	return keys
+}
+
+// adjustListingCounts adds delta to each key's total, creating missing rows
+func adjustListingCounts(tx *gorm.DB, keys []ListingCount, delta int) error {
+	for _, k := range keys {
+		k.Total = delta
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"total":      gorm.Expr("total + ?", delta),
+				"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			}),
+		}).Create(&k).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCountKeys reads the counted columns of a listing as currently stored
+func loadCountKeys(tx *gorm.DB, id uint) ([]ListingCount, error) {
+	var current Listing
+	if err := tx.Session(&gorm.Session{NewDB: true}).
+		Select("id", "status", "visibility", "category", "industry").
+		First(&current, id).Error; err != nil {
+		return nil, err
+	}
+	return current.countKeys(), nil
+}
+
+// AfterCreate counts a listing published as active
+func (l *Listing) AfterCreate(tx *gorm.DB) error {
+	return adjustListingCounts(tx, l.countKeys(), 1)
+}
+
+// BeforeUpdate remembers what a listing counted towards before a change to its
+// status, visibility, category or industry. Bulk updates without a listing ID are not tracked.
+func (l *Listing) BeforeUpdate(tx *gorm.DB) error {
+	l.countedBefore = nil
+	if l.ID == 0 || !tx.Statement.Changed("Status", "Visibility", "Category", "Industry") {
+		return nil
+	}
+	keys, err := loadCountKeys(tx, l.ID)
+	if err != nil {
+		return err
+	}
+	l.countedBefore = &keys
+	return nil
+}
+
+// AfterUpdate moves the listing's contribution from its old keys to its new ones
+func (l *Listing) AfterUpdate(tx *gorm.DB) error {
+	if l.countedBefore == nil {
+		return nil
+	}
+	before := *l.countedBefore
+	l.countedBefore = nil
+
+	after, err := loadCountKeys(tx, l.ID)
+	if err != nil {
+		return err
+	}
+	if err := adjustListingCounts(tx, before, -1); err != nil {
+		return err
+	}
+	return adjustListingCounts(tx, after, 1)
+}
+
+// AfterDelete uncounts a hard-deleted listing
+func (l *Listing) AfterDelete(tx *gorm.DB) error {
+	return adjustListingCounts(tx, l.countKeys(), -1)
+}
@@ -0,0 +1,21 @@
+package models
+
+import "trade_company/internal/sanitize"
+
+// DescriptionHTML returns the description reduced to the allowed HTML subset.
+// Descriptions are sanitized when saved; rows written before that hold the
+// text as entered, so rendering sanitizes again. Sanitizing is idempotent.
+func (l *Listing) DescriptionHTML() string {
+	descHTML, _ := sanitize.Description(l.Description)
+	return descHTML
+}
+
+// DescriptionEnHTML is DescriptionHTML for the English translation, or nil
+// when the listing has not been translated.
+func (l *Listing) DescriptionEnHTML() *string {
+	if l.DescriptionEn == nil {
+		return nil
+	}
+	descHTML, _ := sanitize.Description(*l.DescriptionEn)
+	return &descHTML
+}
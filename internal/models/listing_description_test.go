@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestListingDescriptionHTML(t *testing.T) {
+	en := func(s string) *string { return &s }
+	tests := []struct {
+		name   string
+		desc   string
+		en     *string
+		want   string
+		wantEn *string
+	}{
+		{name: "legacy plain text", desc: "Tom & Jerry", want: "Tom &amp; Jerry"},
+		{name: "sanitized row renders unchanged", desc: "Tom &amp; Jerry", want: "Tom &amp; Jerry"},
+		{name: "markup is reduced to the allowlist", desc: `<p onclick="x()">Hi <i>there</i></p><script>x()</script>`,
+			want: "<p>Hi <em>there</em></p>"},
+		{name: "translation is sanitized too", desc: "店", en: en("Shop <b>&</b> café<iframe></iframe>"),
+			want: "店", wantEn: en("Shop <strong>&amp;</strong> café")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := Listing{Description: tt.desc, DescriptionEn: tt.en}
+			if got := l.DescriptionHTML(); got != tt.want {
+				t.Errorf("DescriptionHTML() = %q, want %q", got, tt.want)
+			}
+
+			b, err := json.Marshal(l)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var out struct {
+				Description   string  `json:"description"`
+				DescriptionEn *string `json:"description_en"`
+			}
+			if err := json.Unmarshal(b, &out); err != nil {
+				t.Fatalf("unmarshal %s: %v", b, err)
+			}
+			if out.Description != tt.want {
+				t.Errorf("JSON description %q, want %q", out.Description, tt.want)
+			}
+			if (out.DescriptionEn == nil) != (tt.wantEn == nil) || (tt.wantEn != nil && *out.DescriptionEn != *tt.wantEn) {
+				t.Errorf("JSON description_en %v, want %v", out.DescriptionEn, tt.wantEn)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Listing document visibility
+const (
+	DocumentVisibilityPublic     = "public"
+	DocumentVisibilityBuyersOnly = "buyers_only" // Buyers who sent a lead or have a transaction on the listing
+	DocumentVisibilityReview     = "review"      // Owner and admins only: financial statements sent for verification
+)
+
+// ListingDocument is a non-image attachment on a listing, such as a PDF
+// financial summary. Files live in private storage and are served through
+// the download endpoint, which enforces Visibility.
+type ListingDocument struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ListingID  uint      `gorm:"index;not null" json:"listing_id"`
+	Filename   string    `gorm:"size:255;not null" json:"filename"` // Original upload name
+	Size       int64     `gorm:"not null" json:"size"`
+	MimeType   string    `gorm:"size:100;not null" json:"mime_type"`
+	StorageKey string    `gorm:"size:255;not null" json:"-"`
+	Visibility string    `gorm:"size:20;not null;default:public" json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "encoding/json"
+
+// ShowPrivateStats marks the listing as being serialized for its owner or an
+// admin, who always see the counters the privacy flags hide from the public.
+func (l *Listing) ShowPrivateStats() {
+	l.privateStatsVisible = true
+}
+
+// PublicViewCount returns the view count, or nil when the owner hides it.
+func (l *Listing) PublicViewCount() *int {
+	if l.HideViewCount && !l.privateStatsVisible {
+		return nil
+	}
+	n := l.ViewCount
+	return &n
+}
+
+// PublicFavoriteCount returns the favorite count, or nil when the owner hides it.
+func (l *Listing) PublicFavoriteCount() *int {
+	if l.HideFavoriteCount && !l.privateStatsVisible {
+		return nil
+	}
+	n := l.FavoriteCount
+	return &n
+}
+
+// PublicOwner returns the owner with the last-active time removed when the
+// listing hides it.
+func (l *Listing) PublicOwner() User {
+	owner := l.Owner
+	if l.HideLastActive && !l.privateStatsVisible {
+		owner.LastLoginAt = nil
+	}
+	return owner
+}
+
+// listingJSON has Listing's fields without its MarshalJSON method
+type listingJSON Listing
+
+// MarshalJSON applies the listing's privacy flags and sanitizes its
+// descriptions, so every response that serializes a Listing directly
+// (favorites, GraphQL helpers, caches) respects them.
+func (l Listing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		listingJSON
+		Description   string  `json:"description"`
+		DescriptionEn *string `json:"description_en,omitempty"`
+		ViewCount     *int    `json:"view_count"`
+		FavoriteCount *int    `json:"favorite_count"`
+		Owner         User    `json:"owner,omitempty"`
+	}{
+		listingJSON:   listingJSON(l),
+		Description:   l.DescriptionHTML(),
+		DescriptionEn: l.DescriptionEnHTML(),
+		ViewCount:     l.PublicViewCount(),
+		FavoriteCount: l.PublicFavoriteCount(),
+		Owner:         l.PublicOwner(),
+	})
+}
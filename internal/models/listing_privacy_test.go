@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestListingMarshalJSONPrivacy(t *testing.T) {
+	lastLogin := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for mask := 0; mask < 8; mask++ {
+		hideViews, hideFavs, hideActive := mask&1 != 0, mask&2 != 0, mask&4 != 0
+		for _, private := range []bool{false, true} {
+			l := Listing{
+				ViewCount:         12,
+				FavoriteCount:     4,
+				HideViewCount:     hideViews,
+				HideFavoriteCount: hideFavs,
+				HideLastActive:    hideActive,
+				Owner:             User{ID: 1, LastLoginAt: &lastLogin},
+			}
+			if private {
+				l.ShowPrivateStats()
+			}
+
+			b, err := json.Marshal(l)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var out struct {
+				ViewCount     *int `json:"view_count"`
+				FavoriteCount *int `json:"favorite_count"`
+				Owner         struct {
+					LastLoginAt *time.Time `json:"last_login_at"`
+				} `json:"owner"`
+			}
+			if err := json.Unmarshal(b, &out); err != nil {
+				t.Fatalf("unmarshal %s: %v", b, err)
+			}
+
+			checks := []struct {
+				field  string
+				hidden bool
+				shown  bool
+			}{
+				{"view_count", hideViews && !private, out.ViewCount != nil},
+				{"favorite_count", hideFavs && !private, out.FavoriteCount != nil},
+				{"owner.last_login_at", hideActive && !private, out.Owner.LastLoginAt != nil},
+			}
+			for _, c := range checks {
+				if c.shown == c.hidden {
+					t.Errorf("flags %03b private=%v: %s shown=%v, want %v", mask, private, c.field, c.shown, !c.hidden)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Quality score weights; they add up to 100
+const (
+	qualityImagesWeight      = 25
+	qualityDescriptionWeight = 20
+	qualityRevenueWeight     = 10
+	qualityProfitRateWeight  = 10
+	qualityRentWeight        = 10
+	qualityContactWeight     = 15
+	qualityLocationWeight    = 5
+	qualityIndustryWeight    = 5
+
+	// Thresholds for full marks on the graded factors
+	qualityFullImages          = 3
+	qualityFullDescriptionRune = 300
+)
+
+// QualityFactor is one component of a listing's quality score
+type QualityFactor struct {
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	MaxScore int    `json:"max_score"`
+	Tip      string `json:"tip,omitempty"`
+}
+
+// ListingQuality is a 0-100 completeness score with its per-factor breakdown
+type ListingQuality struct {
+	Score   int             `json:"score"`
+	Factors []QualityFactor `json:"factors"`
+}
+
+// ScoreListing rates how complete a listing is. It only looks at the listing
+// fields and the number of images, so it is safe to call anywhere.
+func ScoreListing(l *Listing, imageCount int) ListingQuality {
+	factors := []QualityFactor{
+		gradedFactor("images", imageCount, qualityFullImages, qualityImagesWeight,
+			"Add at least 3 photos of the business"),
+		gradedFactor("description", utf8.RuneCountInString(strings.TrimSpace(l.DescriptionText)), qualityFullDescriptionRune, qualityDescriptionWeight,
+			"Write a description of at least 300 characters"),
+		presenceFactor("annual_revenue", l.AnnualRevenue > 0, qualityRevenueWeight,
+			"Provide the annual revenue"),
+		presenceFactor("gross_profit_rate", l.GrossProfitRate > 0, qualityProfitRateWeight,
+			"Provide the gross profit rate"),
+		presenceFactor("rent", l.Rent > 0, qualityRentWeight,
+			"Provide the monthly rent"),
+		presenceFactor("contact", strings.TrimSpace(l.PhoneNumber) != "", qualityContactWeight,
+			"Add a contact phone number"),
+		presenceFactor("location", strings.TrimSpace(l.Location) != "", qualityLocationWeight,
+			"Add the business location"),
+		presenceFactor("industry", strings.TrimSpace(l.Industry) != "", qualityIndustryWeight,
+			"Choose an industry"),
+	}
+
+	total := 0
+	for _, f := range factors {
+		total += f.Score
+	}
+
+	return ListingQuality{Score: total, Factors: factors}
+}
+
+// gradedFactor awards points in proportion to value, capped at full
+func gradedFactor(name string, value, full, weight int, tip string) QualityFactor {
+	if value > full {
+		value = full
+	}
+	if value < 0 {
+		value = 0
+	}
+	f := QualityFactor{Name: name, Score: weight * value / full, MaxScore: weight}
+	if f.Score < weight {
+		f.Tip = tip
+	}
+	return f
+}
+
+// presenceFactor awards all points when the field is filled in
+func presenceFactor(name string, present bool, weight int, tip string) QualityFactor {
+	if present {
+		return QualityFactor{Name: name, Score: weight, MaxScore: weight}
+	}
+	return QualityFactor{Name: name, Score: 0, MaxScore: weight, Tip: tip}
+}
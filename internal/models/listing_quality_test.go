@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScoreListing(t *testing.T) {
+	full := Listing{
+		DescriptionText: strings.Repeat("a", 300),
+		AnnualRevenue:   1200000,
+		GrossProfitRate: 0.35,
+		Rent:            40000,
+		PhoneNumber:     "0987654321",
+		Location:        "Taipei",
+		Industry:        "Food",
+	}
+	tests := []struct {
+		name     string
+		listing  Listing
+		images   int
+		want     int
+		wantTips int
+	}{
+		{name: "empty listing", want: 0, wantTips: 8},
+		{name: "fully populated", listing: full, images: 3, want: 100, wantTips: 0},
+		{name: "extra images do not add points", listing: full, images: 10, want: 100, wantTips: 0},
+		{name: "graded images and description", listing: Listing{DescriptionText: strings.Repeat("b", 150)}, images: 1, want: 25/3 + 10, wantTips: 8},
+		{name: "markup does not count toward the description", listing: Listing{
+			Description:     "<p><strong>" + strings.Repeat("c", 30) + "</strong></p>" + strings.Repeat("<br>", 100),
+			DescriptionText: strings.Repeat("c", 30),
+		}, want: 2, wantTips: 8},
+		{name: "blank fields do not count", listing: Listing{PhoneNumber: "  ", Location: " ", DescriptionText: strings.Repeat(" ", 400)}, want: 0, wantTips: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := ScoreListing(&tt.listing, tt.images)
+			if q.Score != tt.want {
+				t.Errorf("score %d, want %d", q.Score, tt.want)
+			}
+			tips, max := 0, 0
+			for _, f := range q.Factors {
+				max += f.MaxScore
+				if f.Tip != "" {
+					tips++
+				}
+			}
+			if max != 100 {
+				t.Errorf("factor weights add up to %d, want 100", max)
+			}
+			if tips != tt.wantTips {
+				t.Errorf("%d tips, want %d: %+v", tips, tt.wantTips, q.Factors)
+			}
+		})
+	}
+}
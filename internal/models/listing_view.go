@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ListingViewDaily holds the accepted (non-bot) views of a listing per day.
+// Listing.ViewCount is a running total that can be rebuilt from these rows.
+type ListingViewDaily struct {
+	ListingID uint      `gorm:"primaryKey" json:"listing_id"`
+	ViewDate  time.Time `gorm:"primaryKey;type:date" json:"view_date"`
+	Views     int       `gorm:"not null;default:0" json:"views"`
+}
+
+func (ListingViewDaily) TableName() string {
+	return "listing_view_daily"
+}
+
+// ListingViewHourly breaks accepted views down by hour of day (0-23) for the
+// seller's browsing-time heatmap.
+type ListingViewHourly struct {
+	ListingID uint      `gorm:"primaryKey" json:"listing_id"`
+	ViewDate  time.Time `gorm:"primaryKey;type:date" json:"view_date"`
+	Hour      int       `gorm:"primaryKey" json:"hour"`
+	Views     int       `gorm:"not null;default:0" json:"views"`
+}
+
+func (ListingViewHourly) TableName() string {
+	return "listing_view_hourly"
+}
+
+// ListingUserView is the last time a signed-in user opened a listing. It feeds
+// the buyer's recommendations, so only the latest view per listing is kept.
+type ListingUserView struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	ListingID uint      `gorm:"primaryKey" json:"listing_id"`
+	ViewedAt  time.Time `gorm:"not null;index" json:"viewed_at"`
+}
+
+func (ListingUserView) TableName() string {
+	return "listing_user_views"
+}
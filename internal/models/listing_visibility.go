@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// IsPublic reports whether the listing appears in browsing, search and facets.
+// Listings created before visibility existed have no value and count as public.
+func (l *Listing) IsPublic() bool {
+	return l.Visibility == "" || l.Visibility == ListingVisibilityPublic
+}
+
+// VisibleTo reports whether a viewer who has the listing's link may open it.
+// userID is 0 for anonymous viewers; admins are checked by the caller.
+func (l *Listing) VisibleTo(userID uint) bool {
+	return l.Visibility != ListingVisibilityPrivate || (userID != 0 && userID == l.OwnerID)
+}
+
+// ValidListingVisibility reports whether v is one of ListingVisibilities
+func ValidListingVisibility(v string) bool {
+	for _, allowed := range ListingVisibilities {
+		if v == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrListingUnavailable is returned for a new lead or message about a listing
+// that is no longer active
+var ErrListingUnavailable = errors.New("listing is no longer available")
+
+// AvailableTo reports whether the listing is live for the user: it exists, is
+// active and the user may open it. Favorites and message threads about a listing
+// that isn't show it as unavailable rather than dropping it.
+func (l *Listing) AvailableTo(userID uint) bool {
+	return l.ID != 0 && l.Status == ListingStatusActive && l.VisibleTo(userID)
+}
+
+// InquiryListing loads the listing a new lead or message from senderID refers
+// to. A missing listing, or one the sender can't open, is gorm.ErrRecordNotFound;
+// one that is sold, inactive or deleted is ErrListingUnavailable. The owner may
+// still write about their own listing, e.g. to answer earlier inquiries.
+func InquiryListing(db *gorm.DB, listingID, senderID uint) (*Listing, error) {
+	var listing Listing
+	if err := db.First(&listing, listingID).Error; err != nil {
+		return nil, err
+	}
+	if !listing.VisibleTo(senderID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if listing.OwnerID != senderID && !listing.AvailableTo(senderID) {
+		return &listing, ErrListingUnavailable
+	}
+	return &listing, nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestListingVisibleTo(t *testing.T) {
+	tests := []struct {
+		visibility string
+		viewer     uint
+		public     bool
+		visible    bool
+	}{
+		{visibility: "", viewer: 0, public: true, visible: true},
+		{visibility: ListingVisibilityPublic, viewer: 0, public: true, visible: true},
+		{visibility: ListingVisibilityUnlisted, viewer: 0, visible: true},
+		{visibility: ListingVisibilityPrivate, viewer: 0},
+		{visibility: ListingVisibilityPrivate, viewer: 2},
+		{visibility: ListingVisibilityPrivate, viewer: 1, visible: true},
+	}
+	for _, tt := range tests {
+		l := &Listing{OwnerID: 1, Visibility: tt.visibility}
+		if l.IsPublic() != tt.public || l.VisibleTo(tt.viewer) != tt.visible {
+			t.Errorf("%q to user %d: public %v visible %v, want %v %v", tt.visibility, tt.viewer, l.IsPublic(), l.VisibleTo(tt.viewer), tt.public, tt.visible)
+		}
+	}
+	valid := append([]string(nil), ListingVisibilities...)
+	sort.Strings(valid)
+	if strings.Join(valid, ",") != "private,public,unlisted" || ValidListingVisibility("") {
+		t.Errorf("visibilities %v", valid)
+	}
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ListingWarning is a non-blocking hint shown to the seller; it never stops a save or publish
+type ListingWarning struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// WarningRule flags one quality or consistency issue. Applies only looks at the
+// listing fields and the number of images.
+type WarningRule struct {
+	Code    string
+	Field   string
+	Message string
+	Applies func(l *Listing, imageCount int) bool
+}
+
+// WarningOptions tunes the thresholds used by the default rules
+type WarningOptions struct {
+	MaxPriceToRevenue   int64 // asking price above this many years of revenue looks high
+	MinDescriptionRunes int
+}
+
+// DefaultWarningRules returns the built-in rule set. Callers can drop rules by
+// code or append their own before passing them to ListingWarnings.
+func DefaultWarningRules(opts WarningOptions) []WarningRule {
+	return []WarningRule{
+		{
+			Code:    "price_high_vs_revenue",
+			Field:   "price",
+			Message: "Price seems high relative to annual revenue",
+			Applies: func(l *Listing, _ int) bool {
+				return opts.MaxPriceToRevenue > 0 && l.AnnualRevenue > 0 && l.Price > l.AnnualRevenue*opts.MaxPriceToRevenue
+			},
+		},
+		{
+			Code:    "rent_exceeds_revenue",
+			Field:   "rent",
+			Message: "Yearly rent is higher than the annual revenue",
+			Applies: func(l *Listing, _ int) bool {
+				return l.AnnualRevenue > 0 && l.Rent*12 > l.AnnualRevenue
+			},
+		},
+		{
+			Code:    "deposit_exceeds_price",
+			Field:   "deposit",
+			Message: "Deposit is higher than the asking price",
+			Applies: func(l *Listing, _ int) bool {
+				return l.Price > 0 && l.Deposit > l.Price
+			},
+		},
+		{
+			Code:    "gross_profit_rate_out_of_range",
+			Field:   "gross_profit_rate",
+			Message: "Gross profit rate should be a percentage between 0 and 100",
+			Applies: func(l *Listing, _ int) bool {
+				return l.GrossProfitRate < 0 || l.GrossProfitRate > 100
+			},
+		},
+		{
+			Code:    "missing_brand_story",
+			Field:   "brand_story",
+			Message: "Missing brand story; buyers want to know how the business started",
+			Applies: func(l *Listing, _ int) bool {
+				return strings.TrimSpace(l.BrandStory) == ""
+			},
+		},
+		{
+			Code:    "short_description",
+			Field:   "description",
+			Message: "Description is short; add details about operations, staff and customers",
+			Applies: func(l *Listing, _ int) bool {
+				text := l.DescriptionText
+				if text == "" {
+					text = l.Description
+				}
+				return utf8.RuneCountInString(strings.TrimSpace(text)) < opts.MinDescriptionRunes
+			},
+		},
+		{
+			Code:    "no_images",
+			Field:   "images",
+			Message: "Listings without photos get far fewer inquiries",
+			Applies: func(_ *Listing, imageCount int) bool {
+				return imageCount == 0
+			},
+		},
+	}
+}
+
+// ListingWarnings runs the rules against a listing, in rule order. It always
+// returns a non-nil slice so responses serialize as [] rather than null.
+func ListingWarnings(l *Listing, imageCount int, rules []WarningRule) []ListingWarning {
+	warnings := []ListingWarning{}
+	for _, r := range rules {
+		if r.Applies(l, imageCount) {
+			warnings = append(warnings, ListingWarning{Code: r.Code, Field: r.Field, Message: r.Message})
+		}
+	}
+	return warnings
+}
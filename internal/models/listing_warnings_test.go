@@ -0,0 +1,97 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// warningCodes returns the codes of the warnings in order
+func warningCodes(warnings []ListingWarning) []string {
+	codes := []string{}
+	for _, w := range warnings {
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+func TestListingWarnings(t *testing.T) {
+	rules := DefaultWarningRules(WarningOptions{MaxPriceToRevenue: 5, MinDescriptionRunes: 50})
+	clean := Listing{
+		Price:           3000000,
+		AnnualRevenue:   1200000,
+		Rent:            40000,
+		Deposit:         120000,
+		GrossProfitRate: 35,
+		BrandStory:      "Opened by two sisters in 2010",
+		DescriptionText: strings.Repeat("a", 50),
+	}
+	tests := []struct {
+		name   string
+		edit   func(l *Listing)
+		images int
+		want   []string
+	}{
+		{name: "clean listing", images: 2, want: []string{}},
+		{name: "no images", want: []string{"no_images"}},
+		{name: "price high vs revenue", images: 1, edit: func(l *Listing) { l.Price = 6000001 },
+			want: []string{"price_high_vs_revenue"}},
+		{name: "price exactly at the limit", images: 1, edit: func(l *Listing) { l.Price = 6000000 }, want: []string{}},
+		{name: "rent exceeds revenue", images: 1, edit: func(l *Listing) { l.Rent = 100001 },
+			want: []string{"rent_exceeds_revenue"}},
+		{name: "no revenue skips revenue rules", images: 1, edit: func(l *Listing) { l.AnnualRevenue = 0; l.Rent = 500000 },
+			want: []string{}},
+		{name: "deposit exceeds price", images: 1, edit: func(l *Listing) { l.Deposit = 3000001 },
+			want: []string{"deposit_exceeds_price"}},
+		{name: "gross profit rate above 100", images: 1, edit: func(l *Listing) { l.GrossProfitRate = 135 },
+			want: []string{"gross_profit_rate_out_of_range"}},
+		{name: "negative gross profit rate", images: 1, edit: func(l *Listing) { l.GrossProfitRate = -1 },
+			want: []string{"gross_profit_rate_out_of_range"}},
+		{name: "blank brand story", images: 1, edit: func(l *Listing) { l.BrandStory = "  " },
+			want: []string{"missing_brand_story"}},
+		{name: "short description", images: 1, edit: func(l *Listing) { l.DescriptionText = strings.Repeat("店", 49) },
+			want: []string{"short_description"}},
+		{name: "plain description used when text is unset", images: 1, edit: func(l *Listing) { l.DescriptionText = ""; l.Description = "short" },
+			want: []string{"short_description"}},
+		{name: "several issues in rule order", edit: func(l *Listing) { l.Price = 100000; l.Deposit = 200000; l.BrandStory = "" },
+			want: []string{"deposit_exceeds_price", "missing_brand_story", "no_images"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := clean
+			if tt.edit != nil {
+				tt.edit(&l)
+			}
+			got := ListingWarnings(&l, tt.images, rules)
+			if codes := warningCodes(got); !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("warnings %v, want %v", codes, tt.want)
+			}
+			for _, w := range got {
+				if w.Field == "" || w.Message == "" {
+					t.Errorf("warning %+v missing a field or message", w)
+				}
+			}
+		})
+	}
+}
+
+func TestListingWarningsCustomRules(t *testing.T) {
+	l := Listing{Price: 100, AnnualRevenue: 10}
+
+	// A zero price-to-revenue limit turns that check off
+	if codes := warningCodes(ListingWarnings(&l, 1, DefaultWarningRules(WarningOptions{})[:1])); len(codes) != 0 {
+		t.Errorf("warnings %v with the price check off, want none", codes)
+	}
+
+	rules := append(DefaultWarningRules(WarningOptions{MaxPriceToRevenue: 5})[:1], WarningRule{
+		Code: "too_many_images", Field: "images", Message: "Trim the gallery",
+		Applies: func(_ *Listing, imageCount int) bool { return imageCount > 10 },
+	})
+	if codes := warningCodes(ListingWarnings(&l, 11, rules)); !reflect.DeepEqual(codes, []string{"price_high_vs_revenue", "too_many_images"}) {
+		t.Errorf("warnings %v, want the default and custom rule", codes)
+	}
+	if got := ListingWarnings(&Listing{}, 1, nil); got == nil || len(got) != 0 {
+		t.Errorf("no rules gave %#v, want an empty non-nil slice", got)
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// DefaultLocale is the language used when a translation is missing
+const DefaultLocale = "zh-TW"
+
+// LocalizedText maps a locale (e.g. "zh-TW", "en") to its text, stored as a JSON column
+type LocalizedText map[string]string
+
+// Value implements driver.Valuer
+func (t LocalizedText) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (t *LocalizedText) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*t = nil
+		return nil
+	default:
+		return errors.New("unsupported type for LocalizedText")
+	}
+	return json.Unmarshal(data, t)
+}
+
+// In returns the text for locale, falling back to the default locale and then
+// to any available translation
+func (t LocalizedText) In(locale string) (string, string) {
+	if text, ok := t[locale]; ok {
+		return text, locale
+	}
+	if text, ok := t[DefaultLocale]; ok {
+		return text, DefaultLocale
+	}
+	for l, text := range t {
+		return text, l
+	}
+	return "", ""
+}
@@ -11,6 +11,7 @@ type Message struct {
 	Content     string    `gorm:"type:text;not null" json:"content"`
 	IsRead      bool      `gorm:"default:false;index" json:"is_read"`
 	ReadAt      *time.Time `json:"read_at,omitempty"`
+	LegalHold   bool      `gorm:"default:false" json:"-"` // Exempt from the data-retention purge
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// NotificationDigest records the last unread-items digest emailed to a user:
+// when it went out and the newest lead and message it covered. The next digest
+// only mentions items after those, so nothing is reported twice.
+type NotificationDigest struct {
+	UserID        uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	LastLeadID    uint      `gorm:"not null;default:0" json:"last_lead_id"`
+	LastMessageID uint      `gorm:"not null;default:0" json:"last_message_id"`
+	SentAt        time.Time `gorm:"not null" json:"sent_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived token exchanged at POST /auth/refresh for a new
+// access token. Each use revokes it and issues its successor in the same
+// family; a family starts at login.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	FamilyID  string    `gorm:"size:32;not null;index" json:"-"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"` // SHA-256 of the token
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	Revoked   bool      `gorm:"not null;default:false" json:"revoked"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "database/sql/driver"
+
+// Role is a user's role. Registration always creates RoleUser; admins promote.
+type Role string
+
+const (
+	RoleUser   Role = "user"
+	RoleSeller Role = "seller"
+	RoleAdmin  Role = "admin"
+)
+
+// Roles are the values allowed in users.role
+var Roles = []Role{RoleUser, RoleSeller, RoleAdmin}
+
+// Valid reports whether r is one of Roles
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleSeller, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// IsAdmin reports whether the role grants access to the admin endpoints
+func (r Role) IsAdmin() bool {
+	switch r {
+	case RoleAdmin:
+		return true
+	case RoleUser, RoleSeller:
+		return false
+	}
+	return false
+}
+
+func (r Role) Value() (driver.Value, error)     { return enumValue(r, "role") }
+func (r *Role) Scan(src interface{}) error      { return scanEnum(r, src, "role") }
+func (r *Role) UnmarshalJSON(data []byte) error { return unmarshalEnum(r, data, "role") }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TermsAcceptance records a user accepting one version of the terms of
+// service, kept as proof of acceptance. Rows are never updated or deleted
+// while the user exists.
+type TermsAcceptance struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_terms_acceptances_user_version" json:"user_id"`
+	Version    string    `gorm:"size:50;not null;uniqueIndex:idx_terms_acceptances_user_version" json:"version"`
+	AcceptedAt time.Time `gorm:"not null" json:"accepted_at"`
+	IP         string    `gorm:"size:45" json:"ip"`
+}
@@ -1,21 +1,153 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TransactionStatus is where a transaction is in its lifecycle
+type TransactionStatus string
+
+const (
+	TransactionStatusPending   TransactionStatus = "pending"
+	TransactionStatusPaid      TransactionStatus = "paid"
+	TransactionStatusCompleted TransactionStatus = "completed"
+	TransactionStatusCancelled TransactionStatus = "cancelled"
+	TransactionStatusRefunded  TransactionStatus = "refunded"
+)
+
+// TransactionStatuses are the values allowed in transactions.status
+var TransactionStatuses = []TransactionStatus{
+	TransactionStatusPending, TransactionStatusPaid, TransactionStatusCompleted, TransactionStatusCancelled, TransactionStatusRefunded,
+}
+
+// Valid reports whether s is one of TransactionStatuses
+func (s TransactionStatus) Valid() bool {
+	switch s {
+	case TransactionStatusPending, TransactionStatusPaid, TransactionStatusCompleted, TransactionStatusCancelled, TransactionStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// Final reports whether no further status change is possible
+func (s TransactionStatus) Final() bool {
+	switch s {
+	case TransactionStatusCancelled, TransactionStatusRefunded:
+		return true
+	case TransactionStatusPending, TransactionStatusPaid, TransactionStatusCompleted:
+		return false
+	}
+	return false
+}
+
+func (s TransactionStatus) Value() (driver.Value, error) { return enumValue(s, "transaction status") }
+func (s *TransactionStatus) Scan(src interface{}) error {
+	return scanEnum(s, src, "transaction status")
+}
+func (s *TransactionStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(s, data, "transaction status")
+}
+
+var (
+	ErrTransactionTransition = errors.New("transaction status change is not allowed")
+	ErrTransactionDisputed   = errors.New("transaction has an open dispute")
+)
+
+// transactionTransitions lists the statuses each status may move to:
+// pending -> paid -> completed, with cancellation before payment and refunds
+// after it. Cancelled and refunded are final.
+var transactionTransitions = map[TransactionStatus][]TransactionStatus{
+	TransactionStatusPending:   {TransactionStatusPaid, TransactionStatusCancelled},
+	TransactionStatusPaid:      {TransactionStatusCompleted, TransactionStatusRefunded},
+	TransactionStatusCompleted: {TransactionStatusRefunded},
+}
+
+// TransactionActor is which party may move a transaction to a status
+type TransactionActor string
+
+const (
+	TransactionActorBuyer  TransactionActor = "buyer"
+	TransactionActorSeller TransactionActor = "seller"
+)
+
+// transactionActors: the buyer reports payment and may back out before it;
+// only the seller, who holds the money, completes or refunds
+var transactionActors = map[TransactionStatus]TransactionActor{
+	TransactionStatusPaid:      TransactionActorBuyer,
+	TransactionStatusCancelled: TransactionActorBuyer,
+	TransactionStatusCompleted: TransactionActorSeller,
+	TransactionStatusRefunded:  TransactionActorSeller,
+}
+
+// Actor is the party allowed to move a transaction to s
+func (s TransactionStatus) Actor() TransactionActor {
+	return transactionActors[s]
+}
+
+// CanTransitionTo reports whether the transaction may move to status
+func (t *Transaction) CanTransitionTo(status TransactionStatus) bool {
+	for _, s := range transactionTransitions[t.Status] {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
 
 type Transaction struct {
-	ID            uint       `gorm:"primaryKey" json:"id"`
-	ListingID     uint       `gorm:"index;not null" json:"listing_id"`
-	BuyerID       uint       `gorm:"index;not null" json:"buyer_id"`
-	SellerID      uint       `gorm:"index;not null" json:"seller_id"`
-	Amount        int64      `gorm:"not null" json:"amount"`
-	Status        string     `gorm:"size:20;default:pending;index" json:"status"`
-	PaymentMethod string     `gorm:"size:50" json:"payment_method"`
-	CompletedAt   *time.Time `json:"completed_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID               uint              `gorm:"primaryKey" json:"id"`
+	ListingID        uint              `gorm:"index;not null" json:"listing_id"`
+	BuyerID          uint              `gorm:"index;not null" json:"buyer_id"`
+	SellerID         uint              `gorm:"index;not null" json:"seller_id"`
+	Amount           int64             `gorm:"not null" json:"amount"`
+	Status           TransactionStatus `gorm:"size:20;default:pending;index" json:"status"`
+	PaymentMethod    string            `gorm:"size:50" json:"payment_method"`
+	PaymentReference string            `gorm:"size:255" json:"payment_reference,omitempty"` // e.g. "auction:12" for a won auction
+	CompletedAt      *time.Time        `json:"completed_at,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
 
 	// Relations
 	Listing Listing `gorm:"foreignKey:ListingID" json:"listing,omitempty"`
 	Buyer   User    `gorm:"foreignKey:BuyerID" json:"buyer,omitempty"`
 	Seller  User    `gorm:"foreignKey:SellerID" json:"seller,omitempty"`
 }
+
+// TransitionTransaction moves a transaction to status, setting completed_at when
+// it completes. Status changes are paused while a dispute is open; resolving a
+// dispute passes disputed to apply its outcome.
+func TransitionTransaction(db *gorm.DB, t *Transaction, status TransactionStatus, disputed bool) error {
+	if !t.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s to %s", ErrTransactionTransition, t.Status, status)
+	}
+	if !disputed {
+		var open int64
+		if err := db.Model(&TransactionDispute{}).
+			Where("transaction_id = ? AND status = ?", t.ID, DisputeStatusOpen).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrTransactionDisputed
+		}
+	}
+
+	updates := map[string]interface{}{"status": status}
+	if status == TransactionStatusCompleted {
+		updates["completed_at"] = time.Now()
+	}
+	// Guarded on the old status so concurrent changes can't both apply
+	res := db.Model(&Transaction{}).Where("id = ? AND status = ?", t.ID, t.Status).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: status changed concurrently", ErrTransactionTransition)
+	}
+	return db.First(t, t.ID).Error
+}
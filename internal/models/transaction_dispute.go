@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Dispute statuses
+const (
+	DisputeStatusOpen     = "open"
+	DisputeStatusResolved = "resolved"
+)
+
+// TransactionDispute is raised by the buyer or seller of a transaction and
+// settled by an admin. While a dispute is open the transaction's status is
+// frozen except for the outcome the admin applies when resolving it.
+type TransactionDispute struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	TransactionID uint       `gorm:"not null;index" json:"transaction_id"`
+	OpenedByID    uint       `gorm:"not null;index" json:"opened_by_id"`
+	Reason        string     `gorm:"type:text;not null" json:"reason"`
+	Status        string     `gorm:"size:20;not null;default:open;index" json:"status"`
+	Resolution    string     `gorm:"type:text" json:"resolution,omitempty"` // Admin's note
+	ResolvedByID  *uint      `json:"resolved_by_id,omitempty"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Relations
+	Transaction Transaction `gorm:"foreignKey:TransactionID" json:"transaction,omitempty"`
+	OpenedBy    User        `gorm:"foreignKey:OpenedByID" json:"opened_by,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TranslationUsage counts the characters sent to the translation provider in
+// one calendar month, which is what the provider bills for
+type TranslationUsage struct {
+	Month      string `gorm:"primaryKey;size:7" json:"month"` // "2006-01"
+	Characters int64  `gorm:"not null;default:0" json:"characters"`
+}
+
+func (TranslationUsage) TableName() string {
+	return "translation_usage"
+}
+
+// TranslationMonth is the usage row key for t
+func TranslationMonth(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// AddTranslationUsage adds n characters to the month's counter
+func AddTranslationUsage(db *gorm.DB, month string, n int64) error {
+	return db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"characters": gorm.Expr("characters + ?", n)}),
+	}).Create(&TranslationUsage{Month: month, Characters: n}).Error
+}
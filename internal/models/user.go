@@ -32,9 +32,10 @@ type User struct {
 	Username     string     `gorm:"uniqueIndex;size:100;not null" json:"username"`   // Display name (unique)
 	PasswordHash string     `gorm:"size:255;not null" json:"-"`                      // bcrypt hashed password (excluded from JSON)
 	FirstName    string     `gorm:"size:100" json:"first_name"`                      // User's first name
-	LastName     string     `gorm:"size:100" json:"last_name"`                       // User's last name  
+	LastName     string     `gorm:"size:100" json:"last_name"`                       // User's last name
 	Phone        string     `gorm:"size:20" json:"phone"`                            // Contact phone number
-	Role         string     `gorm:"size:32;not null;default:user;index" json:"role"` // User role (user/seller/admin)
+	AvatarURL    string     `gorm:"size:500" json:"avatar_url,omitempty"`            // Profile picture URL
+	Role         Role       `gorm:"size:32;not null;default:user;index" json:"role"` // User role (user/seller/admin)
 	IsActive     bool       `gorm:"default:true;index" json:"is_active"`             // Account activation status
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`                         // Most recent login timestamp
 	CreatedAt    time.Time  `json:"created_at"`                                      // Account creation time
@@ -42,13 +43,16 @@ type User struct {
 
 	// Email Verification System
 	// Ensures users have access to their registered email address
-	EmailVerifiedAt        *time.Time `gorm:"index" json:"email_verified_at,omitempty"` // Email verification timestamp
-	EmailVerificationToken string     `gorm:"size:255" json:"-"`                        // Verification token (excluded from JSON)
+	EmailVerifiedAt          *time.Time `gorm:"index" json:"email_verified_at,omitempty"` // Email verification timestamp
+	EmailVerificationToken   string     `gorm:"size:255" json:"-"`                        // Verification token (excluded from JSON)
+	UnverifiedReminderSentAt *time.Time `json:"-"`                                        // Last-chance reminder before an unverified account expires
+	EmailUndeliverable       bool       `gorm:"default:false" json:"-"`                   // Hard bounce or spam report; non-critical email is suppressed
 
 	// Two-Factor Authentication (2FA) Support
 	// Provides additional security layer for sensitive accounts
-	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"` // 2FA activation status
-	TwoFactorSecret  string `gorm:"size:255" json:"-"`                       // TOTP secret key (excluded from JSON)
+	TwoFactorEnabled  bool   `gorm:"default:false" json:"two_factor_enabled"` // 2FA activation status
+	TwoFactorSecret   string `gorm:"size:255" json:"-"`                       // TOTP secret key (excluded from JSON)
+	TwoFactorLastStep int64  `gorm:"default:0" json:"-"`                      // Time step of the last accepted code; older or equal steps are replays
 
 	// Seller-specific fields
 	CompanyName  string `gorm:"size:255" json:"company_name,omitempty"`
@@ -75,34 +79,40 @@ type User struct {
 
 // UserSession represents user login sessions
 type UserSession struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	SessionID string    `gorm:"size:255;not null;uniqueIndex" json:"session_id"`
-	IPAddress string    `gorm:"size:45" json:"ip_address"`
-	UserAgent string    `gorm:"size:500" json:"user_agent"`
-	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	UserID          uint      `gorm:"not null;index" json:"user_id"`
+	SessionID       string    `gorm:"size:255;not null;uniqueIndex" json:"session_id"`
+	RefreshFamilyID string    `gorm:"size:32;index" json:"-"` // Refresh token family of a JWT login; empty for cookie sessions
+	IPAddress       string    `gorm:"size:45" json:"ip_address"`
+	UserAgent       string    `gorm:"size:500" json:"user_agent"`
+	ExpiresAt       time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 // Lead represents contact form submissions from buyers to sellers
 type Lead struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	SenderID     uint      `gorm:"not null;index" json:"sender_id"`
-	ReceiverID   uint      `gorm:"not null;index" json:"receiver_id"`
-	ListingID    *uint     `gorm:"index" json:"listing_id,omitempty"`
-	Subject      string    `gorm:"size:255;not null" json:"subject"`
-	Message      string    `gorm:"type:text;not null" json:"message"`
-	ContactPhone string    `gorm:"size:20" json:"contact_phone,omitempty"`
-	IsRead       bool      `gorm:"default:false;index" json:"is_read"`
-	IsSpam       bool      `gorm:"default:false;index" json:"is_spam"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint        `gorm:"primaryKey" json:"id"`
+	SenderID     uint        `gorm:"not null;index" json:"sender_id"`
+	ReceiverID   uint        `gorm:"not null;index" json:"receiver_id"`
+	AssignedTo   *uint       `gorm:"index" json:"assigned_to,omitempty"` // Member working the lead; the receiver unless routed
+	ListingID    *uint       `gorm:"index" json:"listing_id,omitempty"`
+	Subject      string      `gorm:"size:255;not null" json:"subject"`
+	InquiryType  InquiryType `gorm:"size:30;not null;default:general" json:"inquiry_type"`
+	TemplateID   *uint       `gorm:"index" json:"template_id,omitempty"` // Lead template the message started from
+	Message      string      `gorm:"type:text;not null" json:"message"`
+	ContactPhone string      `gorm:"size:20" json:"contact_phone,omitempty"`
+	IsRead       bool        `gorm:"default:false;index" json:"is_read"`
+	IsSpam       bool        `gorm:"default:false;index" json:"is_spam"`
+	LegalHold    bool        `gorm:"default:false" json:"-"` // Exempt from the data-retention purge
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
 
 	Sender   User     `gorm:"foreignKey:SenderID" json:"sender,omitempty"`
 	Receiver User     `gorm:"foreignKey:ReceiverID" json:"receiver,omitempty"`
+	Assignee *User    `gorm:"foreignKey:AssignedTo" json:"assignee,omitempty"`
 	Listing  *Listing `gorm:"foreignKey:ListingID" json:"listing,omitempty"`
 }
 
@@ -0,0 +1,49 @@
+// Package moderation checks uploaded listing images before they are shown to
+// the public. The checker is pluggable: development runs allow everything,
+// production can use Google Cloud Vision SafeSearch.
+package moderation
+
+import (
+	"context"
+	"fmt"
+
+	"trade_company/internal/config"
+)
+
+// Checker providers selectable with IMAGE_MODERATION_PROVIDER
+const (
+	ProviderNone   = "none"
+	ProviderVision = "vision"
+)
+
+// Verdict is a checker's decision on one image. A flagged image is hidden until
+// an admin approves or rejects it.
+type Verdict struct {
+	Flagged bool
+	Reason  string
+}
+
+// Checker inspects an image. An error means the image could not be checked and
+// should be retried later; it is never treated as approval.
+type Checker interface {
+	Check(ctx context.Context, image []byte) (Verdict, error)
+}
+
+// AllowAll approves every image
+type AllowAll struct{}
+
+// Check implements Checker.
+func (AllowAll) Check(context.Context, []byte) (Verdict, error) {
+	return Verdict{}, nil
+}
+
+// NewChecker returns the checker selected in config.
+func NewChecker(cfg *config.Config) (Checker, error) {
+	switch cfg.ImageModerationProvider {
+	case ProviderNone:
+		return AllowAll{}, nil
+	case ProviderVision:
+		return NewVisionChecker(cfg.GoogleVisionAPIKey), nil
+	}
+	return nil, fmt.Errorf("unknown image moderation provider %q", cfg.ImageModerationProvider)
+}
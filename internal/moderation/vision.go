@@ -0,0 +1,96 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// visionAnnotateURL is the Cloud Vision images:annotate REST endpoint
+const visionAnnotateURL = "https://vision.googleapis.com/v1/images:annotate"
+
+// flaggedLikelihoods are the SafeSearch likelihoods that flag an image
+var flaggedLikelihoods = map[string]bool{
+	"LIKELY":      true,
+	"VERY_LIKELY": true,
+}
+
+// VisionChecker flags images that Cloud Vision SafeSearch rates as likely adult,
+// violent or racy content
+type VisionChecker struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewVisionChecker creates a SafeSearch checker authenticated with an API key.
+func NewVisionChecker(apiKey string) *VisionChecker {
+	return &VisionChecker{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Check implements Checker.
+func (v *VisionChecker) Check(ctx context.Context, image []byte) (Verdict, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{
+				"image":    map[string]string{"content": base64.StdEncoding.EncodeToString(image)},
+				"features": []map[string]string{{"type": "SAFE_SEARCH_DETECTION"}},
+			},
+		},
+	})
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, visionAnnotateURL+"?key="+v.apiKey, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to reach Cloud Vision: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("cloud vision returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Responses []struct {
+			SafeSearchAnnotation map[string]string `json:"safeSearchAnnotation"`
+			Error                *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"responses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Verdict{}, fmt.Errorf("failed to decode Cloud Vision response: %w", err)
+	}
+	if len(result.Responses) == 0 {
+		return Verdict{}, fmt.Errorf("cloud vision returned no result")
+	}
+	r := result.Responses[0]
+	if r.Error != nil {
+		return Verdict{}, fmt.Errorf("cloud vision: %s", r.Error.Message)
+	}
+
+	var reasons []string
+	for _, category := range []string{"adult", "violence", "racy"} {
+		if flaggedLikelihoods[r.SafeSearchAnnotation[category]] {
+			reasons = append(reasons, category)
+		}
+	}
+	if len(reasons) > 0 {
+		return Verdict{Flagged: true, Reason: "safesearch: " + strings.Join(reasons, ", ")}, nil
+	}
+	return Verdict{}, nil
+}
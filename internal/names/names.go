@@ -0,0 +1,172 @@
+// Package names validates user-chosen display names (usernames and organization
+// names) against a reserved-word list and a basic profanity list.
+package names
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Error codes returned to clients
+const (
+	CodeUsernameInvalid         = "USERNAME_INVALID"
+	CodeUsernameReserved        = "USERNAME_RESERVED"
+	CodeUsernameProfane         = "USERNAME_PROFANE"
+	CodeOrganizationNameInvalid = "ORGANIZATION_NAME_INVALID"
+	CodeOrganizationNameProfane = "ORGANIZATION_NAME_PROFANE"
+)
+
+// Error is a rejected name with a stable code clients can match on
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// usernamePattern is the allowed username shape
+var usernamePattern = regexp.MustCompile(`^[\p{L}\p{N}_.-]{3,30}$`)
+
+// maxOrganizationNameLength matches the users.company_name column
+const maxOrganizationNameLength = 255
+
+// reservedPrefixes can't start a username at all, so "admin_tw" or "support2" are
+// taken too; these are the names people use to impersonate staff
+var reservedPrefixes = []string{"admin", "administrator", "support", "staff", "system", "sysadmin", "moderator", "official", "root", "security", "客服", "管理員", "官方"}
+
+// reservedNames collide with routes or generic accounts and are matched exactly
+var reservedNames = []string{
+	"api", "auth", "login", "logout", "register", "signup", "dashboard", "market",
+	"static", "uploads", "graphql", "playground", "health", "healthz", "listings",
+	"user", "users", "favorites", "messages", "leads", "auctions", "settings",
+	"help", "contact", "about", "null", "undefined", "anonymous", "me", "www",
+	"mail", "noreply", "webmaster", "postmaster", "business-exchange", "businessexchange",
+}
+
+// profanity is a deliberately short list of unambiguous terms, matched as substrings
+var profanity = []string{
+	"fuck", "shit", "bitch", "cunt", "asshole", "dick", "pussy", "whore", "slut", "nigger", "faggot", "retard",
+	"幹你", "幹妳", "操你", "肏", "屌", "婊子", "雞掰", "機掰", "靠北", "靠杯", "王八蛋", "去死", "賤人", "智障", "白痴", "白癡",
+}
+
+// confusables folds look-alike characters and common digit substitutions onto
+// the Latin letters they imitate
+var confusables = map[rune]rune{
+	'ı': 'i', 'і': 'i', 'ӏ': 'l', 'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c',
+	'у': 'y', 'х': 'x', 'ѕ': 's', 'ԁ': 'd', 'ո': 'n', 'м': 'm', 'т': 't', 'к': 'k',
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's', '!': 'i',
+}
+
+// Checker validates names. The zero value has no reserved names; use NewChecker.
+type Checker struct {
+	prefixes []string
+	reserved map[string]bool
+}
+
+// NewChecker builds a checker from the built-in lists plus, when extraFile is set,
+// one reserved name per line of that file (blank lines and # comments ignored).
+func NewChecker(extraFile string) (*Checker, error) {
+	c := &Checker{reserved: make(map[string]bool)}
+	for _, p := range reservedPrefixes {
+		c.prefixes = append(c.prefixes, Fold(p))
+	}
+	for _, n := range reservedNames {
+		c.reserved[Fold(n)] = true
+	}
+
+	if extraFile == "" {
+		return c, nil
+	}
+	f, err := os.Open(extraFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reserved names file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c.reserved[Fold(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reserved names file: %w", err)
+	}
+	return c, nil
+}
+
+// Fold reduces a name to the form used for comparisons: NFKC-normalized (so
+// full-width and other compatibility forms collapse), lower-cased, look-alike
+// characters mapped to Latin letters, and separators removed.
+func Fold(s string) string {
+	s = strings.ToLower(norm.NFKC.String(s))
+	var b strings.Builder
+	for _, r := range s {
+		if folded, ok := confusables[r]; ok {
+			r = folded
+		}
+		if r == '_' || r == '.' || r == '-' || unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// containsProfanity reports whether a folded name contains a profane term
+func containsProfanity(folded string) bool {
+	for _, word := range profanity {
+		if strings.Contains(folded, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidUsername checks only the username's shape, for admin overrides
+func ValidUsername(name string) error {
+	if !usernamePattern.MatchString(norm.NFKC.String(name)) {
+		return &Error{Code: CodeUsernameInvalid, Message: "Username must be 3-30 letters, digits, '_', '.' or '-'"}
+	}
+	return nil
+}
+
+// CheckUsername rejects malformed, reserved and profane usernames
+func (c *Checker) CheckUsername(name string) error {
+	if err := ValidUsername(name); err != nil {
+		return err
+	}
+
+	folded := Fold(name)
+	if c.reserved[folded] {
+		return &Error{Code: CodeUsernameReserved, Message: "This username is reserved"}
+	}
+	for _, p := range c.prefixes {
+		if strings.HasPrefix(folded, p) {
+			return &Error{Code: CodeUsernameReserved, Message: "This username is reserved"}
+		}
+	}
+	if containsProfanity(folded) {
+		return &Error{Code: CodeUsernameProfane, Message: "This username is not allowed"}
+	}
+	return nil
+}
+
+// CheckOrganizationName rejects overly long and profane organization names
+func (c *Checker) CheckOrganizationName(name string) error {
+	if len([]rune(name)) > maxOrganizationNameLength {
+		return &Error{Code: CodeOrganizationNameInvalid, Message: "Organization name is too long"}
+	}
+	if containsProfanity(Fold(name)) {
+		return &Error{Code: CodeOrganizationNameProfane, Message: "This organization name is not allowed"}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package names
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// errorCode returns the code of a names.Error, or "" for nil
+func errorCode(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var nameErr *Error
+	if !errors.As(err, &nameErr) {
+		t.Fatalf("error %v is not a *names.Error", err)
+	}
+	return nameErr.Code
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Admin", "admin"},
+		{"admın", "admin"},     // dotless i
+		{"аdmin", "admin"},     // Cyrillic a
+		{"ＡＤＭＩＮ", "admin"},     // full-width
+		{"ᵃdmin", "admin"},     // superscript a
+		{"adm1n", "admin"},     // digit substitution
+		{"a.d-m_i n", "admin"}, // separators
+		{"客服", "客服"},           // no Latin form
+		{"corner_cafe", "cornercafe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Fold(tt.in); got != tt.want {
+				t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUsername(t *testing.T) {
+	c, err := NewChecker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		username string
+		want     string
+	}{
+		{name: "ordinary", username: "corner_cafe"},
+		{name: "chinese", username: "台北咖啡"},
+		{name: "reserved word inside a name", username: "myadmin"},
+		{name: "route name as a prefix", username: "apiary"},
+		{name: "too short", username: "ab", want: CodeUsernameInvalid},
+		{name: "space", username: "corner cafe", want: CodeUsernameInvalid},
+		{name: "route name", username: "graphql", want: CodeUsernameReserved},
+		{name: "reserved prefix", username: "support_tw", want: CodeUsernameReserved},
+		{name: "upper case", username: "ADMIN", want: CodeUsernameReserved},
+		{name: "dotless i", username: "admın", want: CodeUsernameReserved},
+		{name: "cyrillic look-alikes", username: "ѕуѕtеm", want: CodeUsernameReserved},
+		{name: "full width", username: "ｓｔａｆｆ", want: CodeUsernameReserved},
+		{name: "digit substitution", username: "r00t", want: CodeUsernameReserved},
+		{name: "separated", username: "s.u.p.p.o.r.t", want: CodeUsernameReserved},
+		{name: "chinese staff name", username: "官方客服", want: CodeUsernameReserved},
+		{name: "profane", username: "shit_seller", want: CodeUsernameProfane},
+		{name: "profane with substitution", username: "5h1t_seller", want: CodeUsernameProfane},
+		{name: "profane chinese", username: "王八蛋123", want: CodeUsernameProfane},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(t, c.CheckUsername(tt.username)); got != tt.want {
+				t.Errorf("CheckUsername(%q) code %q, want %q", tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOrganizationName(t *testing.T) {
+	c, err := NewChecker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		org  string
+		want string
+	}{
+		{name: "ordinary", org: "Corner Cafe Ltd."},
+		{name: "reserved words are fine", org: "Admin Support Services"},
+		{name: "too long", org: strings.Repeat("店", maxOrganizationNameLength+1), want: CodeOrganizationNameInvalid},
+		{name: "profane", org: "Bitch Bistro", want: CodeOrganizationNameProfane},
+		{name: "profane full width", org: "ＳＨＩＴ Co", want: CodeOrganizationNameProfane},
+		{name: "profane chinese", org: "雞掰有限公司", want: CodeOrganizationNameProfane},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(t, c.CheckOrganizationName(tt.org)); got != tt.want {
+				t.Errorf("CheckOrganizationName(%q) code %q, want %q", tt.org, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCheckerReservedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reserved.txt")
+	if err := os.WriteFile(path, []byte("# brand names\n\n  Acme-Corp  \nbizex\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewChecker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, username := range []string{"acmecorp", "ACME_CORP", "bizex", "admin"} {
+		if got := errorCode(t, c.CheckUsername(username)); got != CodeUsernameReserved {
+			t.Errorf("CheckUsername(%q) code %q, want %q", username, got, CodeUsernameReserved)
+		}
+	}
+	// File entries are exact names, not prefixes
+	if err := c.CheckUsername("bizex_fan"); err != nil {
+		t.Errorf("CheckUsername(bizex_fan) = %v, want nil", err)
+	}
+
+	if _, err := NewChecker(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("NewChecker with a missing file succeeded")
+	}
+}
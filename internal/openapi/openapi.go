@@ -0,0 +1,78 @@
+// Package openapi describes the REST API as an OpenAPI 3 document, served at
+// /openapi.json, and reports registered routes the document is missing.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIPrefix is the path prefix of the routes the document must cover
+const APIPrefix = "/api/v1"
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+)
+
+// JSON returns the encoded document. It is built once; the operations table
+// doesn't change at runtime.
+func JSON() []byte {
+	specOnce.Do(func() {
+		specJSON, _ = json.Marshal(Spec())
+	})
+	return specJSON
+}
+
+// Serve writes the OpenAPI document
+func Serve(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", JSON())
+}
+
+//go:embed swagger.html
+var swaggerHTML []byte
+
+// SwaggerUI serves a Swagger UI page for /openapi.json. It loads the UI from a
+// CDN, so it is only routed outside production.
+func SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerHTML)
+}
+
+// ginParam matches a gin path parameter such as :id
+var ginParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// openAPIPath rewrites a gin route path to OpenAPI's template syntax,
+// e.g. /listings/:id to /listings/{id}
+func openAPIPath(path string) string {
+	return ginParam.ReplaceAllString(path, "{$1}")
+}
+
+// Undocumented returns the API routes, as "METHOD /path", that have no
+// operation in the document. Routes outside APIPrefix are pages and assets
+// and aren't expected to be documented.
+func Undocumented(routes gin.RoutesInfo) []string {
+	documented := make(map[string]bool, len(operations))
+	for _, op := range operations {
+		documented[op.method+" "+op.path] = true
+	}
+
+	var missing []string
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, APIPrefix+"/") {
+			continue
+		}
+		key := r.Method + " " + openAPIPath(strings.TrimPrefix(r.Path, APIPrefix))
+		if !documented[key] {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,733 @@
+package openapi
+
+import (
+	"strconv"
+	"strings"
+
+	"trade_company/internal/models"
+)
+
+// operation is one documented route. Paths are relative to APIPrefix and use
+// OpenAPI's {param} syntax.
+type operation struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	auth    authMode
+	query   []param
+	body    string // Request schema name, if any
+	status  int    // Success status, 200 when zero
+	result  object // Success response properties; empty means a generic object
+}
+
+type authMode int
+
+const (
+	authNone authMode = iota
+	authOptional
+	authRequired
+	authAdmin
+)
+
+// param is a query parameter
+type param struct {
+	name, typ, description string
+}
+
+// object lists response properties as name to schema name, or "[]Name" for
+// an array. Plain JSON types (string, integer, ...) are inlined.
+type object map[string]string
+
+var (
+	pageParams = []param{
+		{"page", "integer", "Page number, starting at 1"},
+		{"limit", "integer", "Page size; capped at the endpoint's maximum"},
+	}
+	messageResult = object{"message": "string"}
+)
+
+func withPage(params ...param) []param {
+	return append(append([]param{}, pageParams...), params...)
+}
+
+// operations is every /api/v1 route. Adding a route without an entry here is
+// reported at startup outside production.
+var operations = []operation{
+	{method: "GET", path: "/capabilities", tag: "system", summary: "Report which optional features and dependencies are available"},
+	{method: "GET", path: "/forms/token", tag: "system", summary: "A signed form_token to send with the signup and contact forms for bot scoring", result: object{"form_token": "string"}},
+
+	// Auth
+	{method: "POST", path: "/auth/register", tag: "auth", summary: "Create an account", body: "RegisterRequest", status: 201, result: object{"token": "string", "expires_at": "string", "refresh_token": "string", "refresh_expires_at": "string"}},
+	{method: "POST", path: "/auth/login", tag: "auth", summary: "Log in and receive the authToken cookie; with 2FA on, a login_token for /auth/2fa/login instead. 429 ACCOUNT_LOCKED after repeated failures, 409 TOO_MANY_SESSIONS at the strict session limit", body: "LoginRequest", result: object{"message": "string", "user_id": "integer", "expires_at": "string", "refresh_expires_at": "string", "requires_2fa": "boolean", "login_token": "string", "login_expires_at": "string"}},
+	{method: "POST", path: "/auth/2fa/login", tag: "auth", summary: "Finish a requires_2fa login with an authenticator code and receive the authToken cookie", body: "TwoFactorLogin", result: object{"message": "string", "user_id": "integer", "expires_at": "string", "refresh_expires_at": "string"}},
+	{method: "POST", path: "/auth/refresh", tag: "auth", summary: "Exchange a refresh token (body or refreshToken cookie) for a new access token and the next refresh token; reusing one revokes its login", body: "RefreshToken", result: object{"token": "string", "expires_at": "string", "refresh_token": "string", "refresh_expires_at": "string"}},
+	{method: "POST", path: "/auth/logout", tag: "auth", summary: "Clear the authToken cookie and revoke the refresh token from the refreshToken cookie or a RefreshToken body", result: messageResult},
+	{method: "GET", path: "/auth/me", tag: "auth", summary: "The logged in user's profile", auth: authRequired, result: object{"data": "User", "user": "User", "expires_at": "string"}},
+	{method: "POST", path: "/auth/2fa/enroll", tag: "auth", summary: "Generate a TOTP secret for the caller; 2FA turns on once a code from it is verified", auth: authRequired, result: object{"secret": "string", "otpauth_url": "string"}},
+	{method: "POST", path: "/auth/2fa/verify", tag: "auth", summary: "Turn on 2FA with a code from the enrolled secret", auth: authRequired, body: "TwoFactorCode", result: object{"message": "string", "two_factor_enabled": "boolean"}},
+	{method: "POST", path: "/auth/extend", tag: "auth", summary: "Replace a token in its last minutes with a fresh one", auth: authRequired, result: object{"token": "string", "expires_at": "string"}},
+
+	// Listings
+	{method: "GET", path: "/listings", tag: "listings", summary: "Search public listings", auth: authOptional, query: withPage(
+		param{"q", "string", "Keywords, all of which must appear in the title, description, brand story or equipment"},
+		param{"location", "string", "Substring of the listing location"},
+		param{"min_price", "integer", "Minimum price in NT$"},
+		param{"max_price", "integer", "Maximum price in NT$"},
+		param{"category", "string", "Categories, repeated or comma-separated"},
+		param{"condition", "string", "Conditions, repeated or comma-separated"},
+		param{"industry", "string", "Industries, repeated or comma-separated"},
+		param{"min_rent", "number", "Minimum monthly rent in NT$; 0 means unset"},
+		param{"max_rent", "number", "Maximum monthly rent in NT$; 0 means unset"},
+		param{"min_sqm", "number", "Minimum floor area in square meters; 0 means unset"},
+		param{"max_sqm", "number", "Maximum floor area in square meters; 0 means unset"},
+		param{"min_annual_revenue", "number", "Minimum annual revenue in NT$; 0 means unset"},
+		param{"min_gross_profit_rate", "number", "Minimum gross profit rate; 0 means unset"},
+		param{"verified_financials", "boolean", "true for only listings whose financials an admin verified"},
+		param{"sort", "string", "newest (default without q), views_desc, favorites_desc or relevance (default with q)"},
+		param{"lang", "string", "en for English translations where available"},
+		param{"explain", "boolean", "Admins only: add each result's ranking components under ranking"},
+	), result: object{"listings": "[]Listing", "pagination": "Pagination"}},
+	{method: "GET", path: "/listings/metadata", tag: "listings", summary: "Filter values and counts for the listing search"},
+	{method: "GET", path: "/listings/trending", tag: "listings", summary: "Listings trending by recent views, favorites and leads", result: object{"listings": "[]Listing"}},
+	{method: "GET", path: "/listings/{id}", tag: "listings", summary: "Get a listing", auth: authOptional, query: []param{
+		{"lang", "string", "en for English translations where available"},
+	}, result: object{"listing": "Listing"}},
+	{method: "GET", path: "/listings/{id}/images.zip", tag: "listings", summary: "Download a listing's images as a zip archive", auth: authOptional},
+	{method: "GET", path: "/listings/{id}/documents/{docId}", tag: "listings", summary: "Download a listing document; redirects to a short-lived signed link", auth: authOptional},
+	{method: "POST", path: "/listings", tag: "listings", summary: "Create a listing", auth: authRequired, body: "ListingInput", status: 201, result: object{"message": "string", "listing": "Listing"}},
+	{method: "PUT", path: "/listings/{id}", tag: "listings", summary: "Update one of the caller's listings", auth: authRequired, body: "ListingUpdate", result: object{"message": "string", "listing": "Listing"}},
+	{method: "DELETE", path: "/listings/{id}", tag: "listings", summary: "Delete one of the caller's listings; it can be restored during the undo window", auth: authRequired},
+	{method: "POST", path: "/listings/{id}/restore", tag: "listings", summary: "Undo a listing deletion", auth: authRequired},
+	{method: "POST", path: "/listings/{id}/renew", tag: "listings", summary: "Restart a listing's expiry period", auth: authRequired},
+	{method: "POST", path: "/listings/{id}/mark-sold", tag: "listings", summary: "Close one of the caller's listings as sold, optionally recording the sale", auth: authRequired, body: "MarkSold", result: object{"message": "string", "status": "string", "transaction": "Transaction"}},
+	{method: "GET", path: "/listings/{id}/preview", tag: "listings", summary: "One of the caller's listings as it appears in search results and listing cards, with its quality score and warnings", auth: authRequired},
+	{method: "GET", path: "/listings/{id}/views-by-hour", tag: "listings", summary: "Hourly view counts of one of the caller's listings", auth: authRequired, query: []param{
+		{"from", "string", "First day, YYYY-MM-DD"},
+		{"to", "string", "Last day, YYYY-MM-DD"},
+	}},
+	{method: "GET", path: "/listings/{id}/interest", tag: "listings", summary: "Distinct interested users and an anonymous recent-interest timeline for one of the caller's listings", auth: authRequired},
+	{method: "GET", path: "/listings/{id}/contact", tag: "listings", summary: "Reveal the seller's contact details", auth: authRequired},
+	{method: "POST", path: "/listings/{id}/images", tag: "listings", summary: "Upload listing images (multipart)", auth: authRequired},
+	{method: "POST", path: "/listings/{id}/documents", tag: "listings", summary: "Upload a listing document (multipart); visibility=review sends a financial statement for verification", auth: authRequired},
+	{method: "DELETE", path: "/listings/{id}/documents/{docId}", tag: "listings", summary: "Delete a listing document", auth: authRequired},
+	{method: "GET", path: "/categories", tag: "listings", summary: "Listing categories"},
+	{method: "GET", path: "/industries", tag: "listings", summary: "Industries with active listings and their counts", result: object{"industries": "[]string"}},
+	{method: "GET", path: "/terms", tag: "users", summary: "The current terms of service version, and whether the caller accepted it", auth: authOptional, result: object{"version": "string", "accepted": "boolean", "accepted_at": "string"}},
+	{method: "GET", path: "/recommendations", tag: "listings", summary: "Recommended listings", auth: authOptional},
+
+	// Users
+	{method: "GET", path: "/user/profile", tag: "users", summary: "The caller's profile", auth: authRequired, result: object{"data": "User", "user": "User", "expires_at": "string"}},
+	{method: "PUT", path: "/user/profile", tag: "users", summary: "Update the caller's profile", auth: authRequired, body: "ProfileUpdate", result: object{"message": "string", "user": "User"}},
+	{method: "PUT", path: "/user/password", tag: "users", summary: "Change the caller's password", auth: authRequired, body: "PasswordChange", result: messageResult},
+	{method: "GET", path: "/user/dashboard", tag: "users", summary: "Counts for the seller dashboard", auth: authRequired},
+	{method: "GET", path: "/user/sessions", tag: "users", summary: "The caller's active logins, oldest first, with the current one marked", auth: authRequired, result: object{"sessions": "[]Session", "max_sessions": "integer"}},
+	{method: "POST", path: "/user/accept-terms", tag: "users", summary: "Accept the current terms of service, lifting TERMS_REACCEPT_REQUIRED", auth: authRequired, body: "AcceptTerms", result: object{"message": "string", "version": "string", "accepted_at": "string"}},
+	{method: "GET", path: "/user/listings", tag: "users", summary: "The caller's listings, each with its quality score and warnings", auth: authRequired, query: withPage(
+		param{"status", "string", "Status filter, repeated or comma-separated"},
+	), result: object{"listings": "[]Listing", "pagination": "Pagination"}},
+	{method: "GET", path: "/user/listings/expiring", tag: "users", summary: "The caller's listings expiring soon", auth: authRequired, query: []param{
+		{"within_days", "integer", "Window in days, 1-365 (default 14)"},
+	}},
+
+	// Resumable uploads
+	{method: "POST", path: "/uploads/initiate", tag: "uploads", summary: "Start a resumable image upload", auth: authRequired, body: "UploadInitiate", status: 201},
+	{method: "GET", path: "/uploads/{id}", tag: "uploads", summary: "Progress of a resumable upload", auth: authRequired},
+	{method: "PUT", path: "/uploads/{id}/chunks/{index}", tag: "uploads", summary: "Upload one chunk (raw body)", auth: authRequired},
+	{method: "POST", path: "/uploads/{id}/complete", tag: "uploads", summary: "Assemble the chunks into a listing image", auth: authRequired},
+	{method: "DELETE", path: "/uploads/{id}", tag: "uploads", summary: "Abort a resumable upload", auth: authRequired},
+
+	// Favorites
+	{method: "GET", path: "/favorites", tag: "favorites", summary: "The caller's favorites", auth: authRequired, query: withPage(
+		param{"only_active", "boolean", "Leave out favorites whose listing is no longer active"},
+	), result: object{"favorites": "[]Favorite", "pagination": "Pagination"}},
+	{method: "GET", path: "/favorites/{id}", tag: "favorites", summary: "Get one of the caller's favorites", auth: authRequired, result: object{"favorite": "Favorite"}},
+	{method: "GET", path: "/favorites/by-listing/{listingId}", tag: "favorites", summary: "The caller's favorite of a listing", auth: authRequired, result: object{"favorite": "Favorite"}},
+	{method: "POST", path: "/favorites", tag: "favorites", summary: "Favorite a listing", auth: authRequired, body: "FavoriteInput", status: 201, result: object{"message": "string", "favorite": "Favorite"}},
+	{method: "POST", path: "/favorites/toggle", tag: "favorites", summary: "Favorite a listing, or unfavorite it if already favorited", auth: authRequired, body: "FavoriteInput", result: object{"listing_id": "integer", "favorited": "boolean"}},
+	{method: "DELETE", path: "/favorites/{id}", tag: "favorites", summary: "Remove a favorite", auth: authRequired, result: messageResult},
+	{method: "GET", path: "/listings/{id}/favorites/count", tag: "favorites", summary: "How many users favorited a listing; null when the owner hides it", auth: authOptional, result: object{"listing_id": "integer", "favorite_count": "integer"}},
+
+	// Comparisons
+	{method: "GET", path: "/comparisons", tag: "comparisons", summary: "The caller's saved listing comparisons", auth: authRequired},
+	{method: "POST", path: "/comparisons", tag: "comparisons", summary: "Save a listing comparison", auth: authRequired, status: 201},
+	{method: "GET", path: "/comparisons/{id}", tag: "comparisons", summary: "Get a saved comparison", auth: authRequired},
+	{method: "PUT", path: "/comparisons/{id}", tag: "comparisons", summary: "Update a saved comparison", auth: authRequired},
+	{method: "DELETE", path: "/comparisons/{id}", tag: "comparisons", summary: "Delete a saved comparison", auth: authRequired},
+
+	// Messages
+	{method: "GET", path: "/messages", tag: "messages", summary: "Messages the caller sent or received, newest first", auth: authRequired, query: withPage(
+		param{"listing_id", "integer", "Only messages about this listing, which the caller owns or has messaged about"},
+		param{"unread", "boolean", "Only received messages not yet read"},
+		param{"direction", "string", "sent or received"},
+		param{"since", "string", "Only messages created at or after this RFC3339 time"},
+		param{"cursor", "string", "Page by keyset instead of page; empty for the first page, then next_cursor"},
+	), result: object{"messages": "[]Message", "pagination": "Pagination"}},
+	{method: "GET", path: "/messages/{id}", tag: "messages", summary: "Get a message", auth: authRequired, result: object{"message": "Message"}},
+	{method: "POST", path: "/messages", tag: "messages", summary: "Send a message", auth: authRequired, body: "MessageInput", status: 201, result: object{"message": "string", "data": "Message"}},
+	{method: "PUT", path: "/messages/{id}/read", tag: "messages", summary: "Mark a received message as read", auth: authRequired, result: object{"message": "string", "data": "Message"}},
+	{method: "GET", path: "/conversations", tag: "messages", summary: "The caller's message threads, one per other user and listing, most recent first", auth: authRequired, query: pageParams,
+		result: object{"conversations": "[]Conversation", "pagination": "Pagination"}},
+	{method: "GET", path: "/conversations/{userId}", tag: "messages", summary: "Messages with one user, pages going back from the newest, each page oldest first; received ones are marked read", auth: authRequired, query: []param{
+		param{"listing_id", "integer", "Only messages about this listing"},
+		param{"markRead", "boolean", "false leaves the page's messages unread"},
+		param{"cursor", "string", "next_cursor of the previous (newer) page"},
+		param{"limit", "integer", "Messages per page"},
+	}, result: object{"participant": "Participant", "messages": "[]Message", "pagination": "CursorPagination"}},
+
+	// Leads
+	{method: "POST", path: "/listings/{id}/leads", tag: "leads", summary: "Send the listing's seller an inquiry; having sent one reveals their contact details when CONTACT_REVEAL_REQUIRES_LEAD is on", auth: authRequired, body: "LeadInput", result: object{"message": "string", "lead_id": "integer"}},
+	{method: "GET", path: "/leads", tag: "leads", summary: "Leads assigned to the caller, newest first", auth: authRequired, query: withPage(
+		param{"inquiry_type", "string", "Inquiry types, repeated or comma-separated"},
+		param{"all", "boolean", "Every lead of the organizations the caller administers, whoever works them"},
+	), result: object{"leads": "[]Lead", "pagination": "Pagination"}},
+	{method: "PUT", path: "/leads/{id}/read", tag: "leads", summary: "Mark a lead the caller received or works as read", auth: authRequired, result: messageResult},
+	{method: "PUT", path: "/leads/{id}/assignee", tag: "leads", summary: "Hand a lead to another member of its organization; for the organization's account and admin members", auth: authRequired, body: "LeadAssignee", result: object{"message": "string", "lead_id": "integer", "assigned_to": "integer"}},
+	{method: "GET", path: "/user/lead-routing", tag: "leads", summary: "The caller's lead routing rule and organization members", auth: authRequired, result: object{"notify_emails": "[]string", "round_robin": "boolean", "members": "[]OrganizationMember"}},
+	{method: "PUT", path: "/user/lead-routing", tag: "leads", summary: "Replace the addresses copied on the caller's lead emails and turn round-robin assignment among members on or off", auth: authRequired, body: "LeadRouting", result: object{"notify_emails": "[]string", "round_robin": "boolean"}},
+	{method: "POST", path: "/user/organization/members", tag: "leads", summary: "Add a user to the caller's organization by email, or change their role", auth: authRequired, body: "OrganizationMemberInput", result: object{"member": "OrganizationMember"}},
+	{method: "DELETE", path: "/user/organization/members/{userId}", tag: "leads", summary: "Remove a member; their leads go back to the organization's account", auth: authRequired, result: messageResult},
+
+	// Transactions
+	{method: "POST", path: "/transactions", tag: "transactions", summary: "Start buying a listing; the transaction starts pending", auth: authRequired, body: "TransactionInput", status: 201, result: object{"transaction": "Transaction"}},
+	{method: "GET", path: "/transactions", tag: "transactions", summary: "Transactions the caller bought or sold", auth: authRequired, query: withPage(
+		param{"role", "string", "buyer or seller; both when omitted"},
+		param{"status", "string", "Statuses, repeated or comma-separated"},
+	), result: object{"transactions": "[]Transaction", "pagination": "Pagination"}},
+	{method: "GET", path: "/transactions/{id}", tag: "transactions", summary: "One of the caller's transactions", auth: authRequired, result: object{"transaction": "Transaction"}},
+	{method: "PATCH", path: "/transactions/{id}/status", tag: "transactions", summary: "Move a transaction along pending, paid, completed; the buyer pays or cancels, the seller completes or refunds", auth: authRequired, body: "TransactionStatusUpdate", result: object{"transaction": "Transaction"}},
+	{method: "POST", path: "/transactions/{id}/disputes", tag: "transactions", summary: "Dispute a transaction the caller bought or sold", auth: authRequired, body: "DisputeInput", status: 201, result: object{"dispute": "Dispute"}},
+	{method: "GET", path: "/disputes", tag: "transactions", summary: "Disputes on the caller's transactions, or all for an admin", auth: authRequired, query: withPage(
+		param{"status", "string", "open or resolved"},
+	), result: object{"disputes": "[]Dispute", "pagination": "Pagination"}},
+
+	// Announcements
+	{method: "GET", path: "/announcements/active", tag: "announcements", summary: "Announcements currently shown to the caller", auth: authOptional, query: []param{
+		{"locale", "string", "Preferred locale"},
+	}},
+
+	// Lead templates
+	{method: "GET", path: "/lead-templates", tag: "leads", summary: "Question templates buyers can start an inquiry from", query: []param{
+		{"listing_id", "integer", "Only templates for this listing's industry, plus general ones"},
+		{"industry", "string", "Only templates for this industry, plus general ones"},
+		{"locale", "string", "Preferred locale"},
+	}, result: object{"templates": "[]LeadTemplateOption"}},
+
+	// Webhooks
+	{method: "POST", path: "/webhooks/sendgrid", tag: "webhooks", summary: "SendGrid event webhook (signed)"},
+	{method: "POST", path: "/webhooks/auction-events", tag: "webhooks", summary: "Auction service event webhook (signed)"},
+
+	// Auctions, proxied to the auction service
+	{method: "GET", path: "/auctions", tag: "auctions", summary: "List auctions"},
+	{method: "GET", path: "/auctions/{id}", tag: "auctions", summary: "Get an auction"},
+	{method: "POST", path: "/auctions", tag: "auctions", summary: "Create an auction", auth: authRequired},
+	{method: "POST", path: "/auctions/{id}/activate", tag: "auctions", summary: "Start an auction", auth: authRequired},
+	{method: "POST", path: "/auctions/{id}/bids", tag: "auctions", summary: "Place a bid", auth: authRequired},
+	{method: "GET", path: "/auctions/{id}/my-bids", tag: "auctions", summary: "The caller's bids on an auction", auth: authRequired},
+	{method: "GET", path: "/auctions/{id}/results", tag: "auctions", summary: "Auction results"},
+	{method: "GET", path: "/auctions/{id}/ws-url", tag: "auctions", summary: "WebSocket URL for live bids", auth: authRequired},
+
+	// Admin
+	{method: "POST", path: "/admin/listings/recount", tag: "admin", summary: "Rebuild listing view and favorite counts", auth: authAdmin},
+	{method: "GET", path: "/admin/maintenance/orphans", tag: "admin", summary: "Report orphaned rows", auth: authAdmin},
+	{method: "POST", path: "/admin/maintenance/orphans", tag: "admin", summary: "Delete or reassign orphaned rows", auth: authAdmin},
+	{method: "GET", path: "/admin/metrics", tag: "admin", summary: "Process metrics (expvar)", auth: authAdmin},
+	{method: "GET", path: "/admin/metrics/prometheus", tag: "admin", summary: "Latency histograms in the Prometheus text format", auth: authAdmin},
+	{method: "GET", path: "/admin/stats", tag: "admin", summary: "Daily activity stats", auth: authAdmin, query: []param{
+		{"days", "integer", "Number of days including today, 1-365 (default 30)"},
+	}},
+	{method: "PUT", path: "/admin/users/{id}/username", tag: "admin", summary: "Set a user's username", auth: authAdmin},
+	{method: "GET", path: "/admin/users/{id}/email-status", tag: "admin", summary: "A user's email deliverability", auth: authAdmin},
+	{method: "POST", path: "/admin/auction-events/{id}/replay", tag: "admin", summary: "Apply a stored auction event again", auth: authAdmin},
+	{method: "POST", path: "/admin/disputes/{id}/resolve", tag: "admin", summary: "Resolve a dispute", auth: authAdmin, body: "DisputeResolution", result: object{"dispute": "Dispute"}},
+	{method: "GET", path: "/admin/moderation/images", tag: "admin", summary: "Image moderation queue", auth: authAdmin, query: withPage(
+		param{"status", "string", "Moderation status"},
+	)},
+	{method: "POST", path: "/admin/moderation/images/{id}/approve", tag: "admin", summary: "Approve a flagged image", auth: authAdmin},
+	{method: "POST", path: "/admin/moderation/images/{id}/reject", tag: "admin", summary: "Reject a flagged image", auth: authAdmin},
+	{method: "GET", path: "/admin/financials", tag: "admin", summary: "Listings with financial statements awaiting verification", auth: authAdmin, query: pageParams},
+	{method: "POST", path: "/admin/listings/{id}/financials/verify", tag: "admin", summary: "Mark a listing's revenue and margin as verified", auth: authAdmin},
+	{method: "POST", path: "/admin/listings/{id}/financials/reject", tag: "admin", summary: "Decline to verify a listing's financials, with an optional reason", auth: authAdmin},
+	{method: "GET", path: "/admin/announcements", tag: "admin", summary: "All announcements", auth: authAdmin},
+	{method: "POST", path: "/admin/announcements", tag: "admin", summary: "Create an announcement", auth: authAdmin, status: 201},
+	{method: "PUT", path: "/admin/announcements/{id}", tag: "admin", summary: "Update an announcement", auth: authAdmin},
+	{method: "DELETE", path: "/admin/announcements/{id}", tag: "admin", summary: "Delete an announcement", auth: authAdmin},
+	{method: "GET", path: "/admin/lead-templates", tag: "admin", summary: "All lead templates, with every translation", auth: authAdmin, result: object{"templates": "[]LeadTemplate"}},
+	{method: "POST", path: "/admin/lead-templates", tag: "admin", summary: "Create a lead template", auth: authAdmin, status: 201, body: "LeadTemplateInput", result: object{"template": "LeadTemplate"}},
+	{method: "PUT", path: "/admin/lead-templates/{id}", tag: "admin", summary: "Update a lead template", auth: authAdmin, body: "LeadTemplateInput", result: object{"template": "LeadTemplate"}},
+	{method: "DELETE", path: "/admin/lead-templates/{id}", tag: "admin", summary: "Deactivate a lead template; leads keep their reference to it", auth: authAdmin},
+	{method: "POST", path: "/admin/graphql/persisted-queries", tag: "admin", summary: "Register a GraphQL query so it can run by hash and under GRAPHQL_APQ_MODE=allowlist", auth: authAdmin, status: 201, body: "PersistedQuery", result: object{"sha256_hash": "string"}},
+	{method: "DELETE", path: "/admin/graphql/persisted-queries/{hash}", tag: "admin", summary: "Unregister a GraphQL query added through the admin API", auth: authAdmin},
+}
+
+// schemas are the named request and response bodies
+var schemas = map[string]interface{}{
+	"Error": properties(map[string]interface{}{"error": str("What went wrong")}, "error"),
+	"Pagination": properties(map[string]interface{}{
+		"limit":       integer(""),
+		"page":        integer(""),
+		"total":       integer(""),
+		"total_pages": integer(""),
+	}),
+	"CursorPagination": properties(map[string]interface{}{
+		"has_more":    boolean(""),
+		"limit":       integer(""),
+		"next_cursor": str("Pass as cursor for the next page; absent on the last page"),
+	}),
+
+	"RegisterRequest": properties(map[string]interface{}{
+		"email":         str("", "format", "email"),
+		"password":      str("", "minLength", 8),
+		"accept_terms":  boolean("Must be true"),
+		"terms_version": str("The current terms of service version, from GET /terms"),
+	}, "email", "password", "accept_terms", "terms_version"),
+	"AcceptTerms": properties(map[string]interface{}{
+		"version": str("The current terms of service version"),
+	}, "version"),
+	"TwoFactorCode": properties(map[string]interface{}{
+		"code": str("6-digit code from the authenticator app"),
+	}, "code"),
+	"TwoFactorLogin": properties(map[string]interface{}{
+		"login_token":     str("login_token from /auth/login"),
+		"code":            str("6-digit code from the authenticator app"),
+		"challenge_token": str("Turnstile token, required after repeated failed logins"),
+	}, "login_token", "code"),
+	"RefreshToken": properties(map[string]interface{}{
+		"refresh_token": str("Refresh token; the refreshToken cookie is used when omitted"),
+	}),
+	"LoginRequest": properties(map[string]interface{}{
+		"email":           str("", "format", "email"),
+		"password":        str(""),
+		"challenge_token": str("Turnstile token, required after repeated failed logins"),
+	}, "email", "password"),
+	"User": properties(map[string]interface{}{
+		"id":                  integer(""),
+		"email":               str("", "format", "email"),
+		"username":            str(""),
+		"first_name":          str(""),
+		"last_name":           str(""),
+		"phone":               str(""),
+		"avatar_url":          str(""),
+		"role":                str("", "enum", models.Roles),
+		"is_active":           boolean(""),
+		"company_name":        str(""),
+		"email_notifications": boolean(""),
+		"created_at":          dateTime(""),
+	}),
+	"Session": properties(map[string]interface{}{
+		"id":         integer(""),
+		"ip_address": str(""),
+		"user_agent": str(""),
+		"created_at": dateTime(""),
+		"expires_at": dateTime(""),
+		"current":    boolean("The session the request's token belongs to"),
+	}),
+	"ProfileUpdate": properties(map[string]interface{}{
+		"first_name":   str(""),
+		"last_name":    str(""),
+		"phone":        str(""),
+		"username":     str(""),
+		"company_name": str(""),
+	}),
+	"PasswordChange": properties(map[string]interface{}{
+		"current_password": str(""),
+		"new_password":     str(""),
+	}, "current_password", "new_password"),
+
+	"Listing": properties(map[string]interface{}{
+		"id":                  integer(""),
+		"title":               str(""),
+		"title_en":            str("Machine translation, when available"),
+		"description":         str("Sanitized HTML"),
+		"description_en":      str("Machine translation, when available"),
+		"description_text":    str("Plain-text description"),
+		"price":               integer("NT$"),
+		"category":            str(""),
+		"condition":           str(""),
+		"location":            str(""),
+		"status":              str("", "enum", models.ListingStatuses),
+		"visibility":          str("public, unlisted or private"),
+		"expires_at":          dateTime(""),
+		"owner_id":            integer(""),
+		"view_count":          integer(""),
+		"favorite_count":      integer(""),
+		"rent":                integer("Monthly rent, NT$"),
+		"deposit":             integer("NT$"),
+		"floor":               integer(""),
+		"equipment":           str(""),
+		"decoration":          str(""),
+		"annual_revenue":      integer("NT$"),
+		"gross_profit_rate":   number("0-1"),
+		"square_meters":       number(""),
+		"industry":            str(""),
+		"fastest_moving_date": dateTime(""),
+		"images":              arrayOf("Image"),
+		"created_at":          dateTime(""),
+		"updated_at":          dateTime(""),
+	}),
+	"Image": properties(map[string]interface{}{
+		"id":         integer(""),
+		"url":        str(""),
+		"alt_text":   str(""),
+		"order":      integer(""),
+		"is_primary": boolean(""),
+	}),
+	"ListingInput": properties(map[string]interface{}{
+		"title":       str(""),
+		"description": str("HTML; sanitized on save"),
+		"price":       integer("NT$"),
+		"category":    str(""),
+		"condition":   str(""),
+		"location":    str(""),
+		"visibility":  str("public (default), unlisted or private"),
+	}, "title", "price"),
+	"ListingUpdate": properties(map[string]interface{}{
+		"title":             str(""),
+		"description":       str("HTML; sanitized on save"),
+		"price":             integer("NT$"),
+		"category":          str(""),
+		"condition":         str(""),
+		"location":          str(""),
+		"status":            str("", "enum", []models.ListingStatus{models.ListingStatusActive, models.ListingStatusInactive}),
+		"visibility":        str("public, unlisted or private"),
+		"rent":              integer("NT$"),
+		"deposit":           integer("NT$"),
+		"annual_revenue":    integer("NT$"),
+		"gross_profit_rate": number("0-1"),
+	}),
+	"MarkSold": properties(map[string]interface{}{
+		"buyer_id": integer("Buyer to record a completed transaction for; requires amount"),
+		"amount":   integer("Sale price; requires buyer_id"),
+	}),
+
+	"Favorite": properties(map[string]interface{}{
+		"id":         integer(""),
+		"user_id":    integer(""),
+		"listing_id": integer(""),
+		"created_at": dateTime(""),
+		"listing":    ref("Listing"),
+	}),
+	"FavoriteInput": properties(map[string]interface{}{"listing_id": integer("")}, "listing_id"),
+
+	"Message": properties(map[string]interface{}{
+		"id":          integer(""),
+		"sender_id":   integer(""),
+		"receiver_id": integer(""),
+		"listing_id":  integer(""),
+		"subject":     str(""),
+		"content":     str(""),
+		"is_read":     boolean(""),
+		"read_at":     dateTime(""),
+		"created_at":  dateTime(""),
+	}),
+	"Participant": properties(map[string]interface{}{
+		"id":           integer(""),
+		"username":     str(""),
+		"first_name":   str(""),
+		"last_name":    str(""),
+		"company_name": str(""),
+		"avatar_url":   str(""),
+	}),
+	"Conversation": properties(map[string]interface{}{
+		"participant":    ref("Participant"),
+		"listing_id":     integer("The listing the thread is about; null for messages about none"),
+		"message_count":  integer(""),
+		"unread_count":   integer("Messages the caller received and hasn't read"),
+		"latest_message": ref("Message"),
+	}),
+	"MessageInput": properties(map[string]interface{}{
+		"receiver_id": integer(""),
+		"listing_id":  integer(""),
+		"subject":     str(""),
+		"content":     str(""),
+	}, "receiver_id", "content"),
+
+	"Transaction": properties(map[string]interface{}{
+		"id":                integer(""),
+		"listing_id":        integer(""),
+		"buyer_id":          integer(""),
+		"seller_id":         integer(""),
+		"amount":            integer("NT$"),
+		"status":            str("", "enum", models.TransactionStatuses),
+		"payment_method":    str(""),
+		"payment_reference": str(""),
+		"completed_at":      dateTime(""),
+		"created_at":        dateTime(""),
+	}),
+	"Dispute": properties(map[string]interface{}{
+		"id":             integer(""),
+		"transaction_id": integer(""),
+		"opened_by_id":   integer(""),
+		"reason":         str(""),
+		"status":         str("open or resolved"),
+		"resolution":     str(""),
+		"resolved_by_id": integer(""),
+		"resolved_at":    dateTime(""),
+		"created_at":     dateTime(""),
+		"transaction":    ref("Transaction"),
+	}),
+	"TransactionInput": properties(map[string]interface{}{
+		"listing_id":     integer(""),
+		"seller_id":      integer("Optional; must be the listing's owner"),
+		"amount":         integer("NT$; defaults to the asking price"),
+		"payment_method": str("", "maxLength", 50),
+	}, "listing_id"),
+	"TransactionStatusUpdate": properties(map[string]interface{}{
+		"status": str("", "enum", models.TransactionStatuses),
+	}, "status"),
+	"PersistedQuery": properties(map[string]interface{}{
+		"query":       str("The exact query document; its hash covers every character"),
+		"sha256_hash": str("Optional; must match the hex sha256 of query"),
+	}, "query"),
+	"DisputeInput": properties(map[string]interface{}{"reason": str("", "maxLength", 5000)}, "reason"),
+	"DisputeResolution": properties(map[string]interface{}{
+		"resolution":         str("", "maxLength", 5000),
+		"transaction_status": str("Optional new transaction status, e.g. cancelled or refunded", "enum", models.TransactionStatuses),
+	}, "resolution"),
+
+	"Lead": properties(map[string]interface{}{
+		"id":            integer(""),
+		"sender_id":     integer(""),
+		"receiver_id":   integer("The seller"),
+		"assigned_to":   integer("The member working the lead; the seller unless routed"),
+		"listing_id":    integer(""),
+		"subject":       str(""),
+		"inquiry_type":  str("", "enum", models.InquiryTypes),
+		"template_id":   integer("The lead template the buyer started from"),
+		"message":       str(""),
+		"contact_phone": str(""),
+		"is_read":       boolean(""),
+		"is_spam":       boolean(""),
+		"created_at":    dateTime(""),
+		"sender":        ref("User"),
+		"listing":       ref("Listing"),
+	}),
+	"LeadInput": properties(map[string]interface{}{
+		"seller_id":     integer("Optional; must be the listing's owner"),
+		"subject":       str("", "maxLength", 255),
+		"inquiry_type":  str("Defaults to general", "enum", models.InquiryTypes),
+		"template_id":   integer("An active lead template from GET /lead-templates"),
+		"message":       str("", "maxLength", 2000),
+		"contact_phone": str(""),
+		"form_token":    str("From GET /forms/token"),
+	}, "subject", "message"),
+	"LeadAssignee": properties(map[string]interface{}{"user_id": integer("A member of the lead's organization")}, "user_id"),
+	"LeadRouting": properties(map[string]interface{}{
+		"notify_emails": typed("array", "At most 10 addresses copied on every lead email", "items", str("")),
+		"round_robin":   boolean("Rotate new leads among the members instead of the account itself"),
+	}),
+	"OrganizationMember": properties(map[string]interface{}{
+		"id":              integer(""),
+		"organization_id": integer("The seller account"),
+		"user_id":         integer(""),
+		"role":            str("", "enum", models.MemberRoles),
+		"created_at":      dateTime(""),
+		"user":            ref("User"),
+	}),
+	"OrganizationMemberInput": properties(map[string]interface{}{
+		"email": str("An active user's email"),
+		"role":  str("Defaults to agent", "enum", models.MemberRoles),
+	}, "email"),
+	"LeadTemplateOption": properties(map[string]interface{}{
+		"id":       integer(""),
+		"key":      str(""),
+		"industry": str("Empty for templates offered on every listing"),
+		"question": str("The question in the requested locale, or the closest available"),
+		"locale":   str("Locale of question"),
+	}),
+	"LeadTemplate": properties(map[string]interface{}{
+		"id":         integer(""),
+		"key":        str(""),
+		"industry":   str("Empty for templates offered on every listing"),
+		"questions":  typed("object", "The question by locale", "additionalProperties", str("")),
+		"sort_order": integer(""),
+		"active":     boolean(""),
+		"created_at": dateTime(""),
+		"updated_at": dateTime(""),
+	}),
+	"LeadTemplateInput": properties(map[string]interface{}{
+		"key":        str("Lowercase letters, digits and underscores"),
+		"industry":   str("Leave empty to offer the template on every listing"),
+		"questions":  typed("object", "The question by locale", "additionalProperties", str("")),
+		"sort_order": integer(""),
+		"active":     boolean("Defaults to true"),
+	}, "key", "questions"),
+
+	"UploadInitiate": properties(map[string]interface{}{
+		"listing_id":   integer(""),
+		"filename":     str(""),
+		"size":         integer("Bytes"),
+		"content_type": str(""),
+	}, "listing_id", "filename", "size", "content_type"),
+}
+
+// Spec builds the OpenAPI document
+func Spec() map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	for _, op := range operations {
+		if paths[op.path] == nil {
+			paths[op.path] = map[string]interface{}{}
+		}
+		paths[op.path][strings.ToLower(op.method)] = op.build()
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Business Exchange Marketplace API",
+			"version":     "1",
+			"description": "REST API of the marketplace. Authenticated endpoints accept the authToken cookie set by login, or a Bearer token.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": APIPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"cookieAuth": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "authToken"},
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+func (op operation) build() map[string]interface{} {
+	out := map[string]interface{}{
+		"tags":        []string{op.tag},
+		"summary":     op.summary,
+		"operationId": operationID(op.method, op.path),
+	}
+
+	var params []interface{}
+	for _, name := range pathParams(op.path) {
+		params = append(params, map[string]interface{}{
+			"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range op.query {
+		params = append(params, map[string]interface{}{
+			"name": q.name, "in": "query", "description": q.description, "schema": map[string]interface{}{"type": q.typ},
+		})
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if op.body != "" {
+		out["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": ref(op.body)}},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = 200
+	}
+	result := map[string]interface{}{"type": "object"}
+	if len(op.result) > 0 {
+		props := map[string]interface{}{}
+		for name, schema := range op.result {
+			props[name] = schemaFor(schema)
+		}
+		result["properties"] = props
+	}
+	responses := map[string]interface{}{
+		strconv.Itoa(status): map[string]interface{}{
+			"description": "Success",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": result}},
+		},
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("Error")}},
+		},
+	}
+
+	switch op.auth {
+	case authOptional:
+		// No credentials is fine; with them the caller may see more
+		out["security"] = []interface{}{map[string]interface{}{}, map[string]interface{}{"cookieAuth": []string{}}, map[string]interface{}{"bearerAuth": []string{}}}
+	case authRequired, authAdmin:
+		out["security"] = []interface{}{map[string]interface{}{"cookieAuth": []string{}}, map[string]interface{}{"bearerAuth": []string{}}}
+		responses["401"] = map[string]interface{}{"description": "Not logged in", "content": map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("Error")}}}
+		if op.auth == authAdmin {
+			responses["403"] = map[string]interface{}{"description": "Not an admin", "content": map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("Error")}}}
+		}
+	}
+	out["responses"] = responses
+	return out
+}
+
+// pathParams returns the {param} names in path
+func pathParams(path string) []string {
+	var names []string
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, seg[1:len(seg)-1])
+		}
+	}
+	return names
+}
+
+// operationID derives a stable ID, e.g. GET /listings/{id}/preview becomes get_listings_id_preview
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.Trim(seg, "{}")
+		seg = strings.NewReplacer("-", "_", ".", "_").Replace(seg)
+		if seg != "" {
+			id += "_" + seg
+		}
+	}
+	return id
+}
+
+func schemaFor(name string) interface{} {
+	if strings.HasPrefix(name, "[]") {
+		return arrayOf(strings.TrimPrefix(name, "[]"))
+	}
+	switch name {
+	case "string", "integer", "number", "boolean":
+		return map[string]interface{}{"type": name}
+	}
+	return ref(name)
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func arrayOf(name string) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": schemaFor(name)}
+}
+
+func properties(props map[string]interface{}, required ...string) map[string]interface{} {
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// typed builds a schema of a JSON type; extra is key/value pairs such as "format", "email"
+func typed(typ, description string, extra ...interface{}) map[string]interface{} {
+	s := map[string]interface{}{"type": typ}
+	if description != "" {
+		s["description"] = description
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		s[extra[i].(string)] = extra[i+1]
+	}
+	return s
+}
+
+func str(description string, extra ...interface{}) map[string]interface{} {
+	return typed("string", description, extra...)
+}
+
+func integer(description string) map[string]interface{} { return typed("integer", description) }
+func number(description string) map[string]interface{}  { return typed("number", description) }
+func boolean(description string) map[string]interface{} { return typed("boolean", description) }
+
+func dateTime(description string) map[string]interface{} {
+	return typed("string", description, "format", "date-time")
+}
@@ -0,0 +1,150 @@
+// Package pagination parses page/limit and cursor query parameters for list
+// endpoints and builds the pagination block returned with their results.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned when a cursor wasn't produced by EncodeCursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPrefix versions the cursor format so it can change without breaking old links
+const cursorPrefix = "id:"
+
+// Limits are an endpoint's default and maximum page size
+type Limits struct {
+	Default int
+	Max     int
+}
+
+// clamp falls back to the default for a missing or invalid limit and caps it at the max
+func (l Limits) clamp(raw string) int {
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		limit = l.Default
+	}
+	if limit > l.Max {
+		limit = l.Max
+	}
+	return limit
+}
+
+// Params holds the page and limit requested by a list endpoint
+type Params struct {
+	Page   int
+	Limit  int
+	Offset int
+}
+
+// Parse reads the page and limit query params. Out-of-range values are corrected
+// rather than rejected, matching what clients have always relied on.
+func Parse(c *gin.Context, limits Limits) Params {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := limits.clamp(c.Query("limit"))
+	return Params{Page: page, Limit: limit, Offset: (page - 1) * limit}
+}
+
+// Scope applies the page's offset and limit to a query
+func (p Params) Scope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Offset(p.Offset).Limit(p.Limit)
+	}
+}
+
+// Meta is the pagination block returned alongside page-based list results.
+// Fields are in alphabetical order so the output matches the map it replaced.
+type Meta struct {
+	Limit      int   `json:"limit"`
+	Page       int   `json:"page"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// NewMeta builds the pagination block for a page out of total results
+func NewMeta(p Params, total int64) Meta {
+	return Meta{
+		Limit:      p.Limit,
+		Page:       p.Page,
+		Total:      total,
+		TotalPages: (int(total) + p.Limit - 1) / p.Limit,
+	}
+}
+
+// CursorParams holds a cursor-based page request. After is the ID of the last
+// item on the previous page, or 0 for the first page.
+type CursorParams struct {
+	After uint
+	Limit int
+}
+
+// ParseCursor reads the cursor and limit query params
+func ParseCursor(c *gin.Context, limits Limits) (CursorParams, error) {
+	p := CursorParams{Limit: limits.clamp(c.Query("limit"))}
+	if raw := c.Query("cursor"); raw != "" {
+		id, err := DecodeCursor(raw)
+		if err != nil {
+			return p, err
+		}
+		p.After = id
+	}
+	return p, nil
+}
+
+// Scope orders the query by column descending and returns the rows after the
+// cursor. It fetches one extra row so NewCursorMeta can tell if there is more.
+func (p CursorParams) Scope(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if p.After > 0 {
+			db = db.Where(column+" < ?", p.After)
+		}
+		return db.Order(column + " DESC").Limit(p.Limit + 1)
+	}
+}
+
+// CursorMeta is the pagination block returned alongside cursor-based list results
+type CursorMeta struct {
+	HasMore    bool   `json:"has_more"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NewCursorMeta builds the pagination block from the IDs fetched with Scope, in
+// order, and returns how many of them belong on this page.
+func NewCursorMeta(p CursorParams, ids []uint) (CursorMeta, int) {
+	meta := CursorMeta{Limit: p.Limit}
+	n := len(ids)
+	if n > p.Limit {
+		n = p.Limit
+		meta.HasMore = true
+		meta.NextCursor = EncodeCursor(ids[n-1])
+	}
+	return meta, n
+}
+
+// EncodeCursor turns an item ID into an opaque cursor
+func EncodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatUint(uint64(id), 10)))
+}
+
+// DecodeCursor returns the item ID in a cursor made by EncodeCursor
+func DecodeCursor(cursor string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(raw), cursorPrefix), 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
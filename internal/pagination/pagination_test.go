@@ -0,0 +1,112 @@
+package pagination
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryContext returns a gin context for a GET with the given query string
+func queryContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+query, nil)
+	return c
+}
+
+func TestParse(t *testing.T) {
+	limits := Limits{Default: 20, Max: 100}
+	tests := []struct {
+		query string
+		want  Params
+	}{
+		{query: "", want: Params{Page: 1, Limit: 20, Offset: 0}},
+		{query: "page=3", want: Params{Page: 3, Limit: 20, Offset: 40}},
+		{query: "page=2&limit=5", want: Params{Page: 2, Limit: 5, Offset: 5}},
+		{query: "limit=1000", want: Params{Page: 1, Limit: 100, Offset: 0}},
+		{query: "page=0&limit=0", want: Params{Page: 1, Limit: 20, Offset: 0}},
+		{query: "page=-2&limit=-5", want: Params{Page: 1, Limit: 20, Offset: 0}},
+		{query: "page=abc&limit=xyz", want: Params{Page: 1, Limit: 20, Offset: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := Parse(queryContext(tt.query), limits); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// The pagination blocks are part of the public API; these golden strings lock
+// their exact JSON so refactors can't rename, reorder or drop a field
+func TestMetaJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		meta   interface{}
+		golden string
+	}{
+		{name: "page block", meta: NewMeta(Params{Page: 2, Limit: 10, Offset: 10}, 25),
+			golden: `{"limit":10,"page":2,"total":25,"total_pages":3}`},
+		{name: "empty page block", meta: NewMeta(Params{Page: 1, Limit: 10}, 0),
+			golden: `{"limit":10,"page":1,"total":0,"total_pages":0}`},
+		{name: "exact multiple", meta: NewMeta(Params{Page: 1, Limit: 5}, 10),
+			golden: `{"limit":5,"page":1,"total":10,"total_pages":2}`},
+		{name: "cursor block with more", meta: CursorMeta{HasMore: true, Limit: 2, NextCursor: EncodeCursor(7)},
+			golden: `{"has_more":true,"limit":2,"next_cursor":"aWQ6Nw"}`},
+		{name: "last cursor block", meta: CursorMeta{Limit: 2},
+			golden: `{"has_more":false,"limit":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.golden {
+				t.Errorf("JSON %s, want %s", b, tt.golden)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	limits := Limits{Default: 2, Max: 10}
+
+	// Walk IDs 9..1 two at a time, as a client following next_cursor would
+	ids := []uint{9, 8, 7, 6, 5, 4, 3, 2, 1}
+	query := ""
+	var pages [][]uint
+	for {
+		p, err := ParseCursor(queryContext(query), limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fetched []uint
+		for _, id := range ids {
+			if (p.After == 0 || id < p.After) && len(fetched) < p.Limit+1 {
+				fetched = append(fetched, id)
+			}
+		}
+		meta, n := NewCursorMeta(p, fetched)
+		pages = append(pages, fetched[:n])
+		if !meta.HasMore {
+			if meta.NextCursor != "" {
+				t.Errorf("last page has next_cursor %q", meta.NextCursor)
+			}
+			break
+		}
+		query = "cursor=" + meta.NextCursor
+	}
+	if len(pages) != 5 || pages[0][0] != 9 || pages[4][0] != 1 || len(pages[4]) != 1 {
+		t.Errorf("pages %v, want 9..1 in pages of two", pages)
+	}
+
+	for _, bad := range []string{"not-base64!", "Nw", EncodeCursor(0), "aWQ6eA"} {
+		if _, err := ParseCursor(queryContext("cursor="+bad), limits); err != ErrInvalidCursor {
+			t.Errorf("cursor %q: err %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
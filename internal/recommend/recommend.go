@@ -0,0 +1,174 @@
+// Package recommend scores listings against a buyer's intent profile. It only
+// does arithmetic on values passed in; loading favorites, views and candidate
+// listings is up to the caller.
+package recommend
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signal weights: a favorite says more about a buyer than a view
+const (
+	FavoriteWeight = 3.0
+	ViewWeight     = 1.0
+)
+
+// Score component weights
+const (
+	industryWeight   = 3.0
+	cityWeight       = 2.0
+	priceWeight      = 1.5
+	popularityWeight = 0.5
+	freshnessWeight  = 0.5
+)
+
+// FreshFor is how long a new listing keeps a freshness bonus
+const FreshFor = 14 * 24 * time.Hour
+
+// ExploreEvery reserves every nth recommendation for the newest listing not
+// picked yet, so buyers see more than what they already looked at
+const ExploreEvery = 4
+
+// Signal is one listing the buyer favorited or viewed
+type Signal struct {
+	Industry string
+	Location string
+	Price    int64
+	Weight   float64
+}
+
+// Profile is a buyer's intent: the share of their interest per industry and
+// city, and the price band they have been looking in
+type Profile struct {
+	Industries map[string]float64
+	Cities     map[string]float64
+	PriceLow   int64
+	PriceHigh  int64
+}
+
+// NewProfile folds signals into a profile. Industry and city shares each sum to 1.
+// The price band spans the prices seen, widened by 20% on both sides.
+func NewProfile(signals []Signal) Profile {
+	p := Profile{Industries: map[string]float64{}, Cities: map[string]float64{}}
+	var industryTotal, cityTotal float64
+	for _, s := range signals {
+		if s.Industry != "" {
+			p.Industries[s.Industry] += s.Weight
+			industryTotal += s.Weight
+		}
+		if city := City(s.Location); city != "" {
+			p.Cities[city] += s.Weight
+			cityTotal += s.Weight
+		}
+		if s.Price > 0 {
+			if p.PriceLow == 0 || s.Price < p.PriceLow {
+				p.PriceLow = s.Price
+			}
+			if s.Price > p.PriceHigh {
+				p.PriceHigh = s.Price
+			}
+		}
+	}
+	for k := range p.Industries {
+		p.Industries[k] /= industryTotal
+	}
+	for k := range p.Cities {
+		p.Cities[k] /= cityTotal
+	}
+	p.PriceLow = p.PriceLow * 8 / 10
+	p.PriceHigh = p.PriceHigh * 12 / 10
+	return p
+}
+
+// Empty reports whether the profile has nothing to score against
+func (p Profile) Empty() bool {
+	return len(p.Industries) == 0 && len(p.Cities) == 0 && p.PriceHigh == 0
+}
+
+// City returns the city or county a location starts with ("台北市大安區…" gives
+// "台北市"), or the whole trimmed location when it names neither.
+func City(location string) string {
+	location = strings.TrimSpace(location)
+	for i, r := range location {
+		if r == '市' || r == '縣' {
+			return location[:i+len(string(r))]
+		}
+	}
+	return location
+}
+
+// Candidate is a listing that may be recommended
+type Candidate struct {
+	ID          uint
+	Industry    string
+	Location    string
+	Price       int64
+	CreatedAt   time.Time
+	WeeklyViews int64
+}
+
+// Score rates how well a candidate matches the profile; higher is better
+func Score(p Profile, c Candidate, now time.Time) float64 {
+	score := industryWeight*p.Industries[c.Industry] + cityWeight*p.Cities[City(c.Location)]
+	if p.PriceHigh > 0 && c.Price >= p.PriceLow && c.Price <= p.PriceHigh {
+		score += priceWeight
+	}
+	if c.WeeklyViews > 0 {
+		score += popularityWeight * float64(c.WeeklyViews) / float64(c.WeeklyViews+20)
+	}
+	if age := now.Sub(c.CreatedAt); age < FreshFor {
+		score += freshnessWeight * (1 - float64(age)/float64(FreshFor))
+	}
+	return score
+}
+
+// Rank returns up to limit candidate IDs, best first. Every ExploreEvery-th slot
+// goes to the newest candidate not already picked instead of the next best.
+func Rank(p Profile, candidates []Candidate, now time.Time, limit int) []uint {
+	byScore := make([]Candidate, len(candidates))
+	copy(byScore, candidates)
+	scores := make(map[uint]float64, len(candidates))
+	for _, c := range candidates {
+		scores[c.ID] = Score(p, c, now)
+	}
+	sort.SliceStable(byScore, func(i, j int) bool {
+		if scores[byScore[i].ID] != scores[byScore[j].ID] {
+			return scores[byScore[i].ID] > scores[byScore[j].ID]
+		}
+		return byScore[i].ID > byScore[j].ID
+	})
+
+	byAge := make([]Candidate, len(candidates))
+	copy(byAge, candidates)
+	sort.SliceStable(byAge, func(i, j int) bool {
+		if !byAge[i].CreatedAt.Equal(byAge[j].CreatedAt) {
+			return byAge[i].CreatedAt.After(byAge[j].CreatedAt)
+		}
+		return byAge[i].ID > byAge[j].ID
+	})
+
+	picked := make(map[uint]bool, limit)
+	ranked := make([]uint, 0, limit)
+	next := func(from []Candidate) bool {
+		for _, c := range from {
+			if !picked[c.ID] {
+				picked[c.ID] = true
+				ranked = append(ranked, c.ID)
+				return true
+			}
+		}
+		return false
+	}
+	for len(ranked) < limit {
+		from := byScore
+		if (len(ranked)+1)%ExploreEvery == 0 {
+			from = byAge
+		}
+		if !next(from) {
+			break
+		}
+	}
+	return ranked
+}
@@ -0,0 +1,128 @@
+package recommend
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCity(t *testing.T) {
+	tests := map[string]string{
+		"台北市大安區忠孝東路":    "台北市",
+		"花蓮縣吉安鄉":        "花蓮縣",
+		"  新北市  ":       "新北市",
+		" Taipei ":      "Taipei",
+		"":              "",
+		"大安區台北市 (typo)": "大安區台北市",
+	}
+	for location, want := range tests {
+		if got := City(location); got != want {
+			t.Errorf("City(%q) = %q, want %q", location, got, want)
+		}
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	p := NewProfile([]Signal{
+		{Industry: "restaurant", Location: "台北市大安區", Price: 1000, Weight: FavoriteWeight},
+		{Industry: "cafe", Location: "新北市板橋區", Price: 2000, Weight: ViewWeight},
+		// Blank attributes add nothing
+		{Weight: ViewWeight},
+	})
+	if !reflect.DeepEqual(p.Industries, map[string]float64{"restaurant": 0.75, "cafe": 0.25}) {
+		t.Errorf("industries %v", p.Industries)
+	}
+	if !reflect.DeepEqual(p.Cities, map[string]float64{"台北市": 0.75, "新北市": 0.25}) {
+		t.Errorf("cities %v", p.Cities)
+	}
+	if p.PriceLow != 800 || p.PriceHigh != 2400 {
+		t.Errorf("price band %d-%d, want 800-2400", p.PriceLow, p.PriceHigh)
+	}
+	if p.Empty() {
+		t.Error("profile with signals is empty")
+	}
+	if !NewProfile(nil).Empty() || !NewProfile([]Signal{{Weight: ViewWeight}}).Empty() {
+		t.Error("profile without usable signals isn't empty")
+	}
+}
+
+func TestScore(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * FreshFor)
+	p := Profile{
+		Industries: map[string]float64{"restaurant": 1},
+		Cities:     map[string]float64{"台北市": 0.5},
+		PriceLow:   800,
+		PriceHigh:  1200,
+	}
+	tests := []struct {
+		name string
+		c    Candidate
+		want float64
+	}{
+		{name: "no match", c: Candidate{Industry: "retail", Location: "高雄市", Price: 5000, CreatedAt: old}, want: 0},
+		{name: "industry", c: Candidate{Industry: "restaurant", CreatedAt: old}, want: industryWeight},
+		{name: "city share", c: Candidate{Location: "台北市信義區", CreatedAt: old}, want: cityWeight * 0.5},
+		{name: "price band edge", c: Candidate{Price: 1200, CreatedAt: old}, want: priceWeight},
+		{name: "price outside band", c: Candidate{Price: 1201, CreatedAt: old}, want: 0},
+		{name: "popular", c: Candidate{WeeklyViews: 20, CreatedAt: old}, want: popularityWeight / 2},
+		{name: "brand new", c: Candidate{CreatedAt: now}, want: freshnessWeight},
+		{name: "half fresh", c: Candidate{CreatedAt: now.Add(-FreshFor / 2)}, want: freshnessWeight / 2},
+		{name: "everything", c: Candidate{Industry: "restaurant", Location: "台北市", Price: 1000, CreatedAt: old},
+			want: industryWeight + cityWeight*0.5 + priceWeight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Score(p, tt.c, now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// An empty price band doesn't match every price
+	if got := Score(Profile{}, Candidate{Price: 0, CreatedAt: old}, now); got != 0 {
+		t.Errorf("empty profile scored %v", got)
+	}
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * FreshFor)
+	p := Profile{Industries: map[string]float64{"a": 0.5, "b": 0.3, "c": 0.2}, Cities: map[string]float64{}}
+	candidates := []Candidate{
+		{ID: 1, Industry: "c", CreatedAt: old},
+		{ID: 2, Industry: "a", CreatedAt: old},
+		{ID: 3, Industry: "b", CreatedAt: old},
+		{ID: 4, Industry: "a", CreatedAt: old},
+		{ID: 5, Industry: "none", CreatedAt: now.Add(-time.Hour)},
+		{ID: 6, Industry: "none", CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []uint
+	}{
+		// Ties on score go to the higher ID; the fourth slot explores the newest
+		{name: "one page", limit: 4, want: []uint{4, 2, 3, 5}},
+		{name: "more than the candidates", limit: 10, want: []uint{4, 2, 3, 5, 1, 6}},
+		{name: "short page never explores", limit: 3, want: []uint{4, 2, 3}},
+		{name: "zero", limit: 0, want: []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rank(p, candidates, now, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Ranking leaves the caller's slice alone
+	if candidates[0].ID != 1 || candidates[5].ID != 6 {
+		t.Errorf("candidates reordered: %v", candidates)
+	}
+	if got := Rank(p, nil, now, 4); len(got) != 0 {
+		t.Errorf("Rank of nothing = %v", got)
+	}
+}
@@ -2,8 +2,11 @@ package redisclient
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -21,9 +24,17 @@ func NewCacheService(client *redis.Client) *CacheService {
 // Cache keys
 const (
 	ListingSearchKey = "listing:search:"
+	// ListingSearchTagKey is the set of cached search keys, so invalidation
+	// finds them without scanning the keyspace
+	ListingSearchTagKey = "listing:search-keys"
 	ListingDetailKey = "listing:detail:"
+	ListingViewsKey  = "listing:views:"
 	UserProfileKey   = "user:profile:"
+	UserDashboardKey = "user:dashboard:"
+	UserCountsKey    = "user:counts:"
+	AnnouncementsKey = "announcements:current"
 	CategoryListKey  = "category:list"
+	RecommendationsKey = "recommendations:"
 )
 
 // TTL constants
@@ -31,11 +42,22 @@ const (
 	SearchResultTTL = 15 * time.Minute
 	ListingDetailTTL = 30 * time.Minute
 	UserProfileTTL = 1 * time.Hour
+	UserDashboardTTL = 60 * time.Second
+	UserCountsTTL = 30 * time.Second
+	AnnouncementsTTL = 5 * time.Minute
 	CategoryListTTL = 24 * time.Hour
+	RecommendationsTTL = 10 * time.Minute
 )
 
+// ListingSearchResult is a cached page of search results with the total
+// number of matches
+type ListingSearchResult struct {
+	Listings []models.Listing `json:"listings"`
+	Total    int64            `json:"total"`
+}
+
 // CacheListingSearch caches search results
-func (c *CacheService) CacheListingSearch(query string, filters map[string]interface{}, results []models.Listing) error {
+func (c *CacheService) CacheListingSearch(query string, filters map[string]interface{}, results *ListingSearchResult) error {
 	key := fmt.Sprintf("%s%s", ListingSearchKey, hashQuery(query, filters))
 	
 	data, err := json.Marshal(results)
@@ -43,12 +65,19 @@ func (c *CacheService) CacheListingSearch(query string, filters map[string]inter
 		return fmt.Errorf("failed to marshal search results: %w", err)
 	}
 	
+	// The tag set lives as long as its newest member; older members have
+	// expired by then
 	ctx := context.Background()
-	return c.client.Set(ctx, key, data, SearchResultTTL).Err()
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, key, data, SearchResultTTL)
+	pipe.SAdd(ctx, ListingSearchTagKey, key)
+	pipe.Expire(ctx, ListingSearchTagKey, SearchResultTTL)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 // GetCachedListingSearch retrieves cached search results
-func (c *CacheService) GetCachedListingSearch(query string, filters map[string]interface{}) ([]models.Listing, error) {
+func (c *CacheService) GetCachedListingSearch(query string, filters map[string]interface{}) (*ListingSearchResult, error) {
 	key := fmt.Sprintf("%s%s", ListingSearchKey, hashQuery(query, filters))
 	
 	ctx := context.Background()
@@ -60,46 +89,102 @@ func (c *CacheService) GetCachedListingSearch(query string, filters map[string]i
 		return nil, fmt.Errorf("failed to get cached search results: %w", err)
 	}
 	
-	var results []models.Listing
+	var results ListingSearchResult
 	if err := json.Unmarshal(data, &results); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal cached search results: %w", err)
 	}
 	
-	return results, nil
+	return &results, nil
+}
+
+// listingDetail is the cached form of a listing detail. Documents are kept
+// out of the listing's own JSON, so they are stored alongside it.
+type listingDetail struct {
+	Listing   *models.Listing          `json:"listing"`
+	Documents []models.ListingDocument `json:"documents"`
 }
 
-// CacheListingDetail caches individual listing details
+// incrIfExists bumps a live counter only while its cached listing is
+// around, so a view never creates a counter that would outlive the entry
+var incrIfExists = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("INCR", KEYS[1])
+end
+return false
+`)
+
+// CacheListingDetail caches individual listing details, with its images, owner
+// and documents. The view count is also kept in a counter of its own that
+// IncrCachedListingViews bumps, so cached responses show current views.
 func (c *CacheService) CacheListingDetail(listingID uint, listing *models.Listing) error {
 	key := fmt.Sprintf("%s%d", ListingDetailKey, listingID)
-	
-	data, err := json.Marshal(listing)
+
+	data, err := json.Marshal(listingDetail{Listing: listing, Documents: listing.Documents})
 	if err != nil {
 		return fmt.Errorf("failed to marshal listing: %w", err)
 	}
-	
+
 	ctx := context.Background()
-	return c.client.Set(ctx, key, data, ListingDetailTTL).Err()
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, key, data, ListingDetailTTL)
+	pipe.Set(ctx, fmt.Sprintf("%s%d", ListingViewsKey, listingID), listing.ViewCount, ListingDetailTTL)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
-// GetCachedListingDetail retrieves cached listing details
+// GetCachedListingDetail retrieves cached listing details, with the view
+// count as of now
 func (c *CacheService) GetCachedListingDetail(listingID uint) (*models.Listing, error) {
 	key := fmt.Sprintf("%s%d", ListingDetailKey, listingID)
-	
+
 	ctx := context.Background()
-	data, err := c.client.Get(ctx, key).Bytes()
+	pipe := c.client.Pipeline()
+	detailCmd := pipe.Get(ctx, key)
+	viewsCmd := pipe.Get(ctx, fmt.Sprintf("%s%d", ListingViewsKey, listingID))
+	_, _ = pipe.Exec(ctx)
+
+	data, err := detailCmd.Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil // Cache miss
 		}
 		return nil, fmt.Errorf("failed to get cached listing: %w", err)
 	}
-	
-	var listing models.Listing
-	if err := json.Unmarshal(data, &listing); err != nil {
+
+	var detail listingDetail
+	if err := json.Unmarshal(data, &detail); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal cached listing: %w", err)
 	}
-	
-	return &listing, nil
+	if detail.Listing == nil {
+		return nil, nil // Written in an older format; treat as a miss
+	}
+	listing := detail.Listing
+	listing.Documents = detail.Documents
+	if views, err := viewsCmd.Int(); err == nil {
+		listing.ViewCount = views
+	}
+
+	return listing, nil
+}
+
+// IncrCachedListingViews adds a counted view to a cached listing's view
+// count. It does nothing when the listing isn't cached.
+func (c *CacheService) IncrCachedListingViews(listingID uint) error {
+	ctx := context.Background()
+	err := incrIfExists.Run(ctx, c.client, []string{fmt.Sprintf("%s%d", ListingViewsKey, listingID)}).Err()
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
+
+// InvalidateListingDetail drops a listing's cached detail only, for changes
+// that don't affect search results
+func (c *CacheService) InvalidateListingDetail(listingID uint) error {
+	ctx := context.Background()
+	return c.client.Del(ctx,
+		fmt.Sprintf("%s%d", ListingDetailKey, listingID),
+		fmt.Sprintf("%s%d", ListingViewsKey, listingID)).Err()
 }
 
 // InvalidateListingCache invalidates all listing-related caches
@@ -107,25 +192,184 @@ func (c *CacheService) InvalidateListingCache(listingID uint) error {
 	ctx := context.Background()
 	
 	// Invalidate listing detail cache
-	detailKey := fmt.Sprintf("%s%d", ListingDetailKey, listingID)
-	if err := c.client.Del(ctx, detailKey).Err(); err != nil {
+	if err := c.InvalidateListingDetail(listingID); err != nil {
 		return fmt.Errorf("failed to invalidate listing detail cache: %w", err)
 	}
 	
-	// Invalidate all search caches (pattern matching)
-	pattern := fmt.Sprintf("%s*", ListingSearchKey)
-	keys, err := c.client.Keys(ctx, pattern).Result()
-	if err != nil {
-		return fmt.Errorf("failed to get search cache keys: %w", err)
+	// Invalidate all search caches through the tag set. It is renamed first so
+	// searches cached from here on start a new set and aren't lost.
+	pending := fmt.Sprintf("%s:invalidating:%d", ListingSearchTagKey, time.Now().UnixNano())
+	if err := c.client.Rename(ctx, ListingSearchTagKey, pending).Err(); err != nil {
+		if isNoSuchKey(err) {
+			return nil // No cached searches
+		}
+		return fmt.Errorf("failed to claim search cache keys: %w", err)
 	}
 	
-	if len(keys) > 0 {
-		if err := c.client.Del(ctx, keys...).Err(); err != nil {
-			return fmt.Errorf("failed to invalidate search caches: %w", err)
+	var cursor uint64
+	for {
+		keys, next, err := c.client.SScan(ctx, pending, cursor, "", searchInvalidateBatch).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read search cache keys: %w", err)
+		}
+		if len(keys) > 0 {
+			if err := c.client.Unlink(ctx, keys...).Err(); err != nil {
+				return fmt.Errorf("failed to invalidate search caches: %w", err)
+			}
+		}
+		if cursor = next; cursor == 0 {
+			break
 		}
 	}
 	
-	return nil
+	return c.client.Del(ctx, pending).Err()
+}
+
+// searchInvalidateBatch is how many search keys one UNLINK removes
+const searchInvalidateBatch = 500
+
+// isNoSuchKey reports whether err is Redis refusing to RENAME a missing key
+func isNoSuchKey(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no such key")
+}
+
+// CacheUserDashboard caches a user's dashboard stats
+func (c *CacheService) CacheUserDashboard(userID uint, stats map[string]interface{}) error {
+	key := fmt.Sprintf("%s%d", UserDashboardKey, userID)
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dashboard stats: %w", err)
+	}
+
+	ctx := context.Background()
+	return c.client.Set(ctx, key, data, UserDashboardTTL).Err()
+}
+
+// GetCachedUserDashboard retrieves a user's cached dashboard stats
+func (c *CacheService) GetCachedUserDashboard(userID uint) (map[string]interface{}, error) {
+	key := fmt.Sprintf("%s%d", UserDashboardKey, userID)
+
+	ctx := context.Background()
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Cache miss
+		}
+		return nil, fmt.Errorf("failed to get cached dashboard stats: %w", err)
+	}
+
+	var stats map[string]interface{}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached dashboard stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// CacheUserCounts caches the header counts shown in a user's profile
+func (c *CacheService) CacheUserCounts(userID uint, counts map[string]int64) error {
+	key := fmt.Sprintf("%s%d", UserCountsKey, userID)
+
+	data, err := json.Marshal(counts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user counts: %w", err)
+	}
+
+	ctx := context.Background()
+	return c.client.Set(ctx, key, data, UserCountsTTL).Err()
+}
+
+// GetCachedUserCounts retrieves a user's cached header counts
+func (c *CacheService) GetCachedUserCounts(userID uint) (map[string]int64, error) {
+	key := fmt.Sprintf("%s%d", UserCountsKey, userID)
+
+	ctx := context.Background()
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Cache miss
+		}
+		return nil, fmt.Errorf("failed to get cached user counts: %w", err)
+	}
+
+	var counts map[string]int64
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached user counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// CacheRecommendations caches a user's recommended listings; user 0 holds the
+// trending listings shown to anonymous visitors
+func (c *CacheService) CacheRecommendations(userID uint, recommendations map[string]interface{}) error {
+	key := fmt.Sprintf("%s%d", RecommendationsKey, userID)
+
+	data, err := json.Marshal(recommendations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recommendations: %w", err)
+	}
+
+	ctx := context.Background()
+	return c.client.Set(ctx, key, data, RecommendationsTTL).Err()
+}
+
+// GetCachedRecommendations retrieves a user's cached recommended listings
+func (c *CacheService) GetCachedRecommendations(userID uint) (map[string]interface{}, error) {
+	key := fmt.Sprintf("%s%d", RecommendationsKey, userID)
+
+	ctx := context.Background()
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Cache miss
+		}
+		return nil, fmt.Errorf("failed to get cached recommendations: %w", err)
+	}
+
+	var recommendations map[string]interface{}
+	if err := json.Unmarshal(data, &recommendations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached recommendations: %w", err)
+	}
+
+	return recommendations, nil
+}
+
+// CacheAnnouncements caches the announcements that have not ended yet
+func (c *CacheService) CacheAnnouncements(announcements []models.Announcement) error {
+	data, err := json.Marshal(announcements)
+	if err != nil {
+		return fmt.Errorf("failed to marshal announcements: %w", err)
+	}
+
+	ctx := context.Background()
+	return c.client.Set(ctx, AnnouncementsKey, data, AnnouncementsTTL).Err()
+}
+
+// GetCachedAnnouncements retrieves the cached announcements
+func (c *CacheService) GetCachedAnnouncements() ([]models.Announcement, error) {
+	ctx := context.Background()
+	data, err := c.client.Get(ctx, AnnouncementsKey).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Cache miss
+		}
+		return nil, fmt.Errorf("failed to get cached announcements: %w", err)
+	}
+
+	announcements := []models.Announcement{}
+	if err := json.Unmarshal(data, &announcements); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached announcements: %w", err)
+	}
+
+	return announcements, nil
+}
+
+// InvalidateAnnouncements drops the cached announcements after an admin change
+func (c *CacheService) InvalidateAnnouncements() error {
+	ctx := context.Background()
+	return c.client.Del(ctx, AnnouncementsKey).Err()
 }
 
 // InvalidateUserCache invalidates user-related caches
@@ -164,8 +408,19 @@ func (c *CacheService) GetCacheStats() (map[string]interface{}, error) {
 	return stats, nil
 }
 
-// hashQuery creates a hash for the search query and filters
+// hashQuery creates a hash for the search query and filters. The pair is
+// serialized as JSON first, which writes map keys in sorted order, so equal
+// searches always get the same key; the SHA-256 keeps long queries from
+// turning into long Redis keys.
 func hashQuery(query string, filters map[string]interface{}) string {
-	// Simple hash implementation - in production, use a proper hash function
-	return fmt.Sprintf("%s_%v", query, filters)
+	canonical, err := json.Marshal(struct {
+		Query   string                 `json:"q"`
+		Filters map[string]interface{} `json:"f"`
+	}{query, filters})
+	if err != nil {
+		// Unserializable filter values; fall back to their printed form
+		canonical = []byte(fmt.Sprintf("%q_%v", query, filters))
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,200 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"trade_company/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestHashQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     map[string]interface{}
+		qa, qb   string
+		wantSame bool
+	}{
+		{name: "same search", qa: "cafe", qb: "cafe",
+			a: map[string]interface{}{"category": "food"}, b: map[string]interface{}{"category": "food"}, wantSame: true},
+		{name: "filter order does not matter", qa: "cafe", qb: "cafe",
+			a: map[string]interface{}{"category": "food", "location": "Taipei", "min_price": 100},
+			b: map[string]interface{}{"min_price": 100, "location": "Taipei", "category": "food"}, wantSame: true},
+		{name: "different query", qa: "cafe", qb: "bakery"},
+		{name: "different filter value", qa: "cafe", qb: "cafe",
+			a: map[string]interface{}{"location": "Taipei"}, b: map[string]interface{}{"location": "Tainan"}},
+		{name: "number and string differ", qa: "cafe", qb: "cafe",
+			a: map[string]interface{}{"page": 1}, b: map[string]interface{}{"page": "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha, hb := hashQuery(tt.qa, tt.a), hashQuery(tt.qb, tt.b)
+			if (ha == hb) != tt.wantSame {
+				t.Errorf("hashQuery equal = %v, want %v (%s, %s)", ha == hb, tt.wantSame, ha, hb)
+			}
+			for _, h := range []string{ha, hb} {
+				if len(h) != 64 || strings.Trim(h, "0123456789abcdef") != "" {
+					t.Errorf("hash %q is not 64 hex characters", h)
+				}
+			}
+		})
+	}
+}
+
+func TestHashQueryLongQueryHasFixedLength(t *testing.T) {
+	if h := hashQuery(strings.Repeat("cafe ", 1000), nil); len(h) != 64 {
+		t.Errorf("hash length %d, want 64", len(h))
+	}
+}
+
+// commandRecorder is a redis.Hook that records the name of every command sent
+type commandRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *commandRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (r *commandRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		r.record(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (r *commandRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			r.record(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (r *commandRecorder) record(cmd redis.Cmder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, strings.ToUpper(cmd.Name()))
+}
+
+// sent reports whether any of the named commands went out since the last reset
+func (r *commandRecorder) sent(names ...string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sent := range r.names {
+		for _, name := range names {
+			if sent == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *commandRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = nil
+}
+
+func newTestCache(t *testing.T) (*CacheService, *miniredis.Miniredis, *commandRecorder) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	rec := &commandRecorder{}
+	client.AddHook(rec)
+	return NewCacheService(client), mr, rec
+}
+
+func countSearchKeys(mr *miniredis.Miniredis) int {
+	n := 0
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, ListingSearchKey) && key != ListingSearchTagKey {
+			n++
+		}
+	}
+	return n
+}
+
+func TestInvalidateListingCacheRemovesEverySearch(t *testing.T) {
+	cache, mr, rec := newTestCache(t)
+	// More than one UNLINK batch
+	const searches = 2*searchInvalidateBatch + 7
+	for i := 0; i < searches; i++ {
+		page := &ListingSearchResult{Listings: []models.Listing{{Title: "Corner cafe"}}, Total: 1}
+		if err := cache.CacheListingSearch(fmt.Sprintf("q=cafe%d", i), map[string]interface{}{"page": 1}, page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache.CacheListingDetail(1, &models.Listing{Title: "Corner cafe"})
+	cache.CacheListingDetail(2, &models.Listing{Title: "Noodle stand"})
+	mr.Set("user:profile:1", "unrelated")
+	if n := countSearchKeys(mr); n != searches {
+		t.Fatalf("%d search keys cached, want %d", n, searches)
+	}
+
+	rec.reset()
+	if err := cache.InvalidateListingCache(1); err != nil {
+		t.Fatalf("InvalidateListingCache: %v", err)
+	}
+	if rec.sent("KEYS", "SCAN") {
+		t.Error("invalidation walked the keyspace")
+	}
+	if n := countSearchKeys(mr); n != 0 {
+		t.Errorf("%d search keys left", n)
+	}
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, ListingSearchTagKey) {
+			t.Errorf("tag set %s left behind", key)
+		}
+	}
+	if mr.Exists(fmt.Sprintf("%s%d", ListingDetailKey, 1)) {
+		t.Error("invalidated listing's detail still cached")
+	}
+	if !mr.Exists(fmt.Sprintf("%s%d", ListingDetailKey, 2)) || !mr.Exists("user:profile:1") {
+		t.Error("invalidation removed unrelated keys")
+	}
+
+	// Searches cached afterwards start a new tag set, and the next change clears them
+	page := &ListingSearchResult{Total: 0}
+	cache.CacheListingSearch("q=bakery", nil, page)
+	if got, err := cache.GetCachedListingSearch("q=bakery", nil); err != nil || got == nil {
+		t.Fatalf("search cached after invalidation: %v, %v", got, err)
+	}
+	if err := cache.InvalidateListingCache(2); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := cache.GetCachedListingSearch("q=bakery", nil); got != nil {
+		t.Error("search cached after the first invalidation survived the second")
+	}
+}
+
+func TestInvalidateListingCacheWithoutSearches(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	cache.CacheListingDetail(1, &models.Listing{Title: "Corner cafe"})
+	if err := cache.InvalidateListingCache(1); err != nil {
+		t.Fatalf("InvalidateListingCache: %v", err)
+	}
+	if mr.Exists(fmt.Sprintf("%s%d", ListingDetailKey, 1)) {
+		t.Error("detail still cached")
+	}
+}
+
+func TestCacheListingSearchTagExpiry(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	cache.CacheListingSearch("q=cafe", nil, &ListingSearchResult{})
+	if ttl := mr.TTL(ListingSearchTagKey); ttl <= 0 || ttl > SearchResultTTL {
+		t.Errorf("tag set TTL %v, want up to %v", ttl, SearchResultTTL)
+	}
+	// Once every search has expired the tag set goes with them
+	mr.FastForward(SearchResultTTL + 1)
+	if mr.Exists(ListingSearchTagKey) || countSearchKeys(mr) != 0 {
+		t.Errorf("keys left after the TTL: %v", mr.Keys())
+	}
+}
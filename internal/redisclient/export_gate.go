@@ -0,0 +1,187 @@
+package redisclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"trade_company/internal/metrics"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Export gate keys. Running slots are a sorted set of tokens scored by when
+// they expire; waiting holders are scored by when they first asked.
+const (
+	exportRunningKey = "exports:running"
+	exportWaitingKey = "exports:waiting"
+	exportAvgKey     = "exports:avg_ms"
+)
+
+// exportDefaultDuration is the ETA basis until an export has finished
+const exportDefaultDuration = 30 * time.Second
+
+// exportAcquireScript takes a slot for a holder if they have none running and
+// the global cap allows it. Expired slots and long-idle waiters are dropped
+// first, so a crashed worker's slot frees itself once its TTL passes.
+// Returns {status, position, user slot ms left, running, waiting}: status 1
+// acquired, 0 the holder already has an export running, -1 the cap is reached.
+var exportAcquireScript = redis.NewScript(`
+local now, ttl = tonumber(ARGV[2]), tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', now)
+redis.call('ZREMRANGEBYSCORE', KEYS[3], '-inf', now - ttl)
+local running = redis.call('ZCARD', KEYS[2])
+if redis.call('EXISTS', KEYS[1]) == 1 then
+  return {0, 0, redis.call('PTTL', KEYS[1]), running, redis.call('ZCARD', KEYS[3])}
+end
+if running >= tonumber(ARGV[4]) then
+  if not redis.call('ZSCORE', KEYS[3], ARGV[5]) then
+    -- Ties would be ordered by name, so a waiter in the same millisecond
+    -- goes after the last one rather than jumping ahead of it
+    local last = redis.call('ZRANGE', KEYS[3], -1, -1, 'WITHSCORES')[2]
+    local score = now
+    if last and tonumber(last) >= now then score = tonumber(last) + 1 end
+    redis.call('ZADD', KEYS[3], score, ARGV[5])
+  end
+  local rank = redis.call('ZRANK', KEYS[3], ARGV[5])
+  return {-1, rank + 1, 0, running, redis.call('ZCARD', KEYS[3])}
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+redis.call('ZADD', KEYS[2], now + ttl, ARGV[1])
+redis.call('ZREM', KEYS[3], ARGV[5])
+return {1, 0, 0, running + 1, redis.call('ZCARD', KEYS[3])}
+`)
+
+// exportReleaseScript frees a slot, leaving the holder's key alone if it has
+// expired and been taken by a newer export, and folds the export's duration
+// into the running average used for ETAs
+var exportReleaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('DEL', KEYS[1])
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+local avg = tonumber(redis.call('GET', KEYS[3]))
+local d = tonumber(ARGV[2])
+if avg then d = math.floor(avg * 0.8 + d * 0.2) end
+redis.call('SET', KEYS[3], d)
+return redis.call('ZCARD', KEYS[2])
+`)
+
+// ExportBusyError is returned by Acquire when the export has to wait
+type ExportBusyError struct {
+	HolderBusy bool          // The holder already has an export running
+	Position   int           // Place among holders waiting for a free slot; 0 when HolderBusy
+	ETA        time.Duration // Rough wait, from the average export duration
+}
+
+func (e *ExportBusyError) Error() string {
+	if e.HolderBusy {
+		return "an export is already running"
+	}
+	return fmt.Sprintf("too many exports running; position %d in line", e.Position)
+}
+
+// ExportGate limits expensive exports to one at a time per holder (a user,
+// or an IP for anonymous downloads) and to a cap across all instances. Slots
+// expire after ttl, so an export whose worker died without releasing it can't
+// block its holder or the cap for longer than that.
+//
+// A nil *ExportGate lets every export through, so handlers don't need to
+// check whether Redis is configured.
+type ExportGate struct {
+	client *redis.Client
+	max    int
+	ttl    time.Duration
+}
+
+// NewExportGate returns the gate, or nil when Redis is not configured
+func NewExportGate(client *redis.Client, max int, ttl time.Duration) *ExportGate {
+	if client == nil {
+		return nil
+	}
+	return &ExportGate{client: client, max: max, ttl: ttl}
+}
+
+// ExportSlot is a running export's place in the gate; Release it when done
+type ExportSlot struct {
+	gate    *ExportGate
+	key     string
+	token   string
+	started time.Time
+}
+
+// Acquire takes a slot for holder, or returns an *ExportBusyError saying how
+// long to wait. Any other error means Redis is unavailable; callers let the
+// export run rather than fail it.
+func (g *ExportGate) Acquire(ctx context.Context, holder string) (*ExportSlot, error) {
+	if g == nil {
+		return nil, nil
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	slot := &ExportSlot{gate: g, key: "exports:holder:" + holder, token: hex.EncodeToString(buf), started: time.Now()}
+
+	res, err := exportAcquireScript.Run(ctx, g.client,
+		[]string{slot.key, exportRunningKey, exportWaitingKey},
+		slot.token, slot.started.UnixMilli(), g.ttl.Milliseconds(), g.max, holder,
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	status, position, holderTTL := res[0], int(res[1]), time.Duration(res[2])*time.Millisecond
+	metrics.SetExportQueue(res[3], res[4])
+
+	switch status {
+	case 1:
+		metrics.IncExports(metrics.ExportStarted)
+		return slot, nil
+	case 0:
+		metrics.IncExports(metrics.ExportRejectedHolder)
+		// The running export has been going for ttl - holderTTL
+		eta := g.averageDuration(ctx) - (g.ttl - holderTTL)
+		if eta < time.Second {
+			eta = time.Second
+		}
+		return nil, &ExportBusyError{HolderBusy: true, ETA: eta}
+	default:
+		metrics.IncExports(metrics.ExportRejectedCap)
+		rounds := (position + g.max - 1) / g.max
+		return nil, &ExportBusyError{Position: position, ETA: time.Duration(rounds) * g.averageDuration(ctx)}
+	}
+}
+
+// averageDuration is the running average export duration
+func (g *ExportGate) averageDuration(ctx context.Context) time.Duration {
+	ms, err := g.client.Get(ctx, exportAvgKey).Int64()
+	if err != nil || ms <= 0 {
+		return exportDefaultDuration
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Release frees the slot, whether the export succeeded or failed. It is safe
+// on a nil slot. It uses its own context, so an export cut short by the
+// client going away still releases.
+func (s *ExportSlot) Release() {
+	if s == nil {
+		return
+	}
+	elapsed := time.Since(s.started)
+	metrics.ExportSeconds.Observe(elapsed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	running, err := exportReleaseScript.Run(ctx, s.gate.client,
+		[]string{s.key, exportRunningKey, exportAvgKey},
+		s.token, elapsed.Milliseconds(),
+	).Int64()
+	if err == nil {
+		metrics.ExportsRunning.Set(running)
+	}
+	// On error the slot expires with its TTL
+}
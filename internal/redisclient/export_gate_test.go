@@ -0,0 +1,191 @@
+package redisclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestExportGate(t *testing.T, max int, ttl time.Duration) (*ExportGate, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return NewExportGate(redis.NewClient(&redis.Options{Addr: mr.Addr()}), max, ttl), mr
+}
+
+// acquireAll asks for a slot for every holder at once
+func acquireAll(gate *ExportGate, holders []string) ([]*ExportSlot, []*ExportBusyError) {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		slots []*ExportSlot
+		busy  []*ExportBusyError
+	)
+	for _, holder := range holders {
+		wg.Add(1)
+		go func(holder string) {
+			defer wg.Done()
+			slot, err := gate.Acquire(context.Background(), holder)
+			mu.Lock()
+			defer mu.Unlock()
+			var b *ExportBusyError
+			switch {
+			case errors.As(err, &b):
+				busy = append(busy, b)
+			case err == nil:
+				slots = append(slots, slot)
+			}
+		}(holder)
+	}
+	wg.Wait()
+	return slots, busy
+}
+
+func TestExportGateConcurrentHolders(t *testing.T) {
+	gate, mr := newTestExportGate(t, 3, time.Minute)
+	var holders []string
+	for i := 1; i <= 10; i++ {
+		holders = append(holders, fmt.Sprintf("user:%d", i))
+	}
+
+	slots, busy := acquireAll(gate, holders)
+	if len(slots) != 3 || len(busy) != 7 {
+		t.Fatalf("%d acquired and %d turned away, want 3 and 7", len(slots), len(busy))
+	}
+	var positions []int
+	for _, b := range busy {
+		if b.HolderBusy {
+			t.Errorf("a waiting holder was reported as busy: %v", b)
+		}
+		positions = append(positions, b.Position)
+		// Each round of 3 takes the default duration
+		if want := time.Duration((b.Position+2)/3) * exportDefaultDuration; b.ETA != want {
+			t.Errorf("position %d ETA %v, want %v", b.Position, b.ETA, want)
+		}
+	}
+	sort.Ints(positions)
+	for i, p := range positions {
+		if p != i+1 {
+			t.Fatalf("positions %v, want 1 to 7", positions)
+		}
+	}
+	if waiting, _ := mr.ZMembers(exportWaitingKey); len(waiting) != 7 {
+		t.Errorf("waiting %v, want 7 holders", waiting)
+	}
+
+	// A freed slot goes to whoever asks next, leaving the line
+	slots[0].Release()
+	waiting, _ := mr.ZMembers(exportWaitingKey)
+	waiter := waiting[0]
+	slot, err := gate.Acquire(context.Background(), waiter)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	defer slot.Release()
+	if waiting, _ = mr.ZMembers(exportWaitingKey); len(waiting) != 6 {
+		t.Errorf("waiting %v after %s got a slot, want 6 holders", waiting, waiter)
+	}
+}
+
+func TestExportGateOnePerHolder(t *testing.T) {
+	gate, _ := newTestExportGate(t, 10, time.Minute)
+	holders := []string{"user:1", "user:1", "user:1", "user:1", "user:1", "ip:192.0.2.1"}
+
+	slots, busy := acquireAll(gate, holders)
+	if len(slots) != 2 || len(busy) != 4 {
+		t.Fatalf("%d acquired and %d turned away, want 2 and 4", len(slots), len(busy))
+	}
+	for _, b := range busy {
+		if !b.HolderBusy || b.Position != 0 {
+			t.Errorf("busy %+v, want the holder's own export reported", b)
+		}
+		if b.ETA < time.Second || b.ETA > exportDefaultDuration {
+			t.Errorf("ETA %v, want between a second and %v", b.ETA, exportDefaultDuration)
+		}
+	}
+
+	// A failed export releases like a finished one
+	for _, slot := range slots {
+		slot.Release()
+	}
+	slot, err := gate.Acquire(context.Background(), "user:1")
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	slot.Release()
+}
+
+func TestExportGateWorkerDeath(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+	gate, mr := newTestExportGate(t, 1, ttl)
+	ctx := context.Background()
+
+	// The worker dies holding its slot and never releases it
+	dead, err := gate.Acquire(ctx, "user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var busy *ExportBusyError
+	if _, err := gate.Acquire(ctx, "user:1"); !errors.As(err, &busy) || !busy.HolderBusy {
+		t.Fatalf("same holder: %v, want busy", err)
+	}
+	if _, err := gate.Acquire(ctx, "user:2"); !errors.As(err, &busy) || busy.Position != 1 {
+		t.Fatalf("other holder: %v, want first in line", err)
+	}
+
+	// The holder key expires in Redis time and the running set by the wall clock
+	mr.FastForward(ttl)
+	time.Sleep(ttl + 20*time.Millisecond)
+
+	next, err := gate.Acquire(ctx, "user:1")
+	if err != nil {
+		t.Fatalf("acquire after the slot expired: %v", err)
+	}
+	// A late release from the dead worker must not free the new slot
+	dead.Release()
+	if _, err := gate.Acquire(ctx, "user:1"); !errors.As(err, &busy) || !busy.HolderBusy {
+		t.Errorf("after the stale release: %v, want the new export still running", err)
+	}
+	if _, err := gate.Acquire(ctx, "user:2"); !errors.As(err, &busy) || busy.HolderBusy {
+		t.Errorf("after the stale release: %v, want the cap still reached", err)
+	}
+	next.Release()
+}
+
+func TestExportGateAverageDuration(t *testing.T) {
+	gate, mr := newTestExportGate(t, 1, time.Minute)
+	ctx := context.Background()
+	if d := gate.averageDuration(ctx); d != exportDefaultDuration {
+		t.Errorf("average %v before any export, want %v", d, exportDefaultDuration)
+	}
+	mr.Set(exportAvgKey, "10000")
+	slot, err := gate.Acquire(ctx, "user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot.Release()
+	// Folded in at a fifth: 10s * 0.8 + ~0s * 0.2
+	if d := gate.averageDuration(ctx); d < 8*time.Second || d > 8100*time.Millisecond {
+		t.Errorf("average %v, want about 8s", d)
+	}
+}
+
+func TestExportGateWithoutRedis(t *testing.T) {
+	gate := NewExportGate(nil, 1, time.Minute)
+	if gate != nil {
+		t.Fatal("gate without Redis is not nil")
+	}
+	for i := 0; i < 3; i++ {
+		slot, err := gate.Acquire(context.Background(), "user:1")
+		if slot != nil || err != nil {
+			t.Fatalf("Acquire = %v, %v; want every export let through", slot, err)
+		}
+		slot.Release()
+	}
+}
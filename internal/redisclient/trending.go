@@ -0,0 +1,82 @@
+package redisclient
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TrendingKey is the sorted set of listing IDs scored by recent activity
+const TrendingKey = "listings:trending"
+
+// Activity weights: a lead says more about interest than a favorite, and a
+// favorite more than a view
+const (
+	TrendingViewWeight     = 1.0
+	TrendingFavoriteWeight = 3.0
+	TrendingLeadWeight     = 5.0
+)
+
+// trendingMinScore is the score below which a decayed listing is dropped
+const trendingMinScore = 0.01
+
+// Trending keeps a leaderboard of listings by recent activity. Every event adds
+// its weight to the listing's score, and Decay periodically shrinks all scores,
+// so a burst of activity today outranks a larger one last month.
+//
+// A nil *Trending is valid and records nothing, so handlers don't need to check
+// whether Redis is configured.
+type Trending struct {
+	client *redis.Client
+}
+
+// NewTrending returns the leaderboard, or nil when Redis is not configured.
+func NewTrending(client *redis.Client) *Trending {
+	if client == nil {
+		return nil
+	}
+	return &Trending{client: client}
+}
+
+// Record adds an activity of the given weight to a listing's score. It is best
+// effort: a Redis error only means the event is missed.
+func (t *Trending) Record(ctx context.Context, listingID uint, weight float64) {
+	if t == nil {
+		return
+	}
+	t.client.ZIncrBy(ctx, TrendingKey, weight, strconv.FormatUint(uint64(listingID), 10))
+}
+
+// Top returns up to n listing IDs with the highest scores, best first
+func (t *Trending) Top(ctx context.Context, n int) ([]uint, error) {
+	members, err := t.client.ZRevRange(ctx, TrendingKey, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseUint(m, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+// DecayFactor is what scores are multiplied by every interval so that they
+// halve once per halfLife
+func DecayFactor(interval, halfLife time.Duration) float64 {
+	return math.Pow(0.5, float64(interval)/float64(halfLife))
+}
+
+// Decay multiplies every score by factor and drops listings whose score has
+// faded to almost nothing
+func (t *Trending) Decay(ctx context.Context, factor float64) error {
+	pipe := t.client.TxPipeline()
+	pipe.ZUnionStore(ctx, TrendingKey, &redis.ZStore{Keys: []string{TrendingKey}, Weights: []float64{factor}})
+	pipe.ZRemRangeByScore(ctx, TrendingKey, "-inf", strconv.FormatFloat(trendingMinScore, 'f', -1, 64))
+	_, err := pipe.Exec(ctx)
+	return err
+}
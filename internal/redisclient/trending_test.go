@@ -0,0 +1,105 @@
+package redisclient
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestTrending(t *testing.T) (*Trending, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return NewTrending(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr
+}
+
+func TestTrendingRecencyWeighting(t *testing.T) {
+	ctx := context.Background()
+	trending, _ := newTestTrending(t)
+	const oldPopular, newlyActive = 1, 2
+
+	// Last month's hit: 40 views and 5 favorites
+	for i := 0; i < 40; i++ {
+		trending.Record(ctx, oldPopular, TrendingViewWeight)
+	}
+	for i := 0; i < 5; i++ {
+		trending.Record(ctx, oldPopular, TrendingFavoriteWeight)
+	}
+	if top, _ := trending.Top(ctx, 2); !reflect.DeepEqual(top, []uint{oldPopular}) {
+		t.Fatalf("top %v, want [%d]", top, oldPopular)
+	}
+
+	// Three weeks of hourly decay at a three-day half-life, then a little activity
+	factor := DecayFactor(time.Hour, 72*time.Hour)
+	for i := 0; i < 21*24; i++ {
+		if err := trending.Decay(ctx, factor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trending.Record(ctx, newlyActive, TrendingViewWeight)
+	trending.Record(ctx, newlyActive, TrendingLeadWeight)
+
+	top, err := trending.Top(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(top, []uint{newlyActive, oldPopular}) {
+		t.Errorf("top %v, want the newly active listing %d above the old popular %d", top, newlyActive, oldPopular)
+	}
+}
+
+func TestTrendingDecay(t *testing.T) {
+	ctx := context.Background()
+	trending, mr := newTestTrending(t)
+	trending.Record(ctx, 1, 10)
+	trending.Record(ctx, 2, 0.015)
+
+	if err := trending.Decay(ctx, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if score, _ := mr.ZScore(TrendingKey, "1"); score != 5 {
+		t.Errorf("score %v after halving, want 5", score)
+	}
+	// Scores that fade to almost nothing are dropped
+	if members, _ := mr.ZMembers(TrendingKey); !reflect.DeepEqual(members, []string{"1"}) {
+		t.Errorf("members %v, want only listing 1", members)
+	}
+	if top, _ := trending.Top(ctx, 10); !reflect.DeepEqual(top, []uint{1}) {
+		t.Errorf("top %v", top)
+	}
+}
+
+func TestDecayFactor(t *testing.T) {
+	tests := []struct {
+		interval, halfLife time.Duration
+		want               float64
+	}{
+		{interval: time.Hour, halfLife: time.Hour, want: 0.5},
+		{interval: 2 * time.Hour, halfLife: time.Hour, want: 0.25},
+		{interval: time.Hour, halfLife: 24 * time.Hour, want: math.Pow(0.5, 1.0/24)},
+	}
+	for _, tt := range tests {
+		if got := DecayFactor(tt.interval, tt.halfLife); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("DecayFactor(%v, %v) = %v, want %v", tt.interval, tt.halfLife, got, tt.want)
+		}
+	}
+
+	// A day of hourly decays halves a score with a one-day half-life
+	day := math.Pow(DecayFactor(time.Hour, 24*time.Hour), 24)
+	if math.Abs(day-0.5) > 1e-12 {
+		t.Errorf("24 hourly decays = %v, want 0.5", day)
+	}
+}
+
+func TestTrendingWithoutRedis(t *testing.T) {
+	trending := NewTrending(nil)
+	if trending != nil {
+		t.Fatal("NewTrending(nil) isn't nil")
+	}
+	// Recording on the nil leaderboard is a no-op
+	trending.Record(context.Background(), 1, TrendingViewWeight)
+}
@@ -1,24 +1,41 @@
 package router
 
 import (
+	"expvar"
+	"html/template"
 	logOri "log"
 	"net/http"
-	"strings"
 	"time"
 
 	"trade_company/graph"
+	"trade_company/internal/auth"
+	"trade_company/internal/botcheck"
 	"trade_company/internal/config"
+	"trade_company/internal/format"
 	gqlctx "trade_company/internal/graphql"
 	"trade_company/internal/handlers"
+	"trade_company/internal/imageproxy"
+	"trade_company/internal/metrics"
 	"trade_company/internal/middleware"
 	"trade_company/internal/models"
+	"trade_company/internal/names"
+	"trade_company/internal/openapi"
+	"trade_company/internal/redisclient"
+	"trade_company/internal/sanitize"
+	"trade_company/internal/storage"
+	"trade_company/internal/uploads"
+	"trade_company/internal/wordfilter"
 
 	"strconv"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"github.com/vektah/gqlparser/v2/ast"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -35,15 +52,49 @@ func NewRouter(cfg *config.Config, log *zap.Logger, db *gorm.DB, redisClient *re
 	// Global middleware
 	r.Use(middleware.Recovery(log))
 	r.Use(middleware.RequestID())
-	r.Use(middleware.CORS())
-	r.Use(requestLogger(log))
-
-	// Load templates
+	r.Use(middleware.RequestLogger(log))
+	r.Use(middleware.CORS(cfg))
+	log.Info("CORS configured",
+		zap.Strings("allowed_origins", middleware.CORSOrigins(cfg)),
+		zap.String("allowed_methods", cfg.CORSAllowedMethods),
+		zap.Bool("allow_credentials", cfg.CORSAllowCredentials))
+
+	// Request body limits: the global cap applies unless a route registers its own
+	bodyLimiter := middleware.NewBodyLimiter(cfg.GlobalBodyLimitMB)
+	r.Use(bodyLimiter.Middleware())
+
+	// Load templates; descriptions are sanitized again at render time for rows
+	// saved before sanitizing, and amounts and dates go through the zh-TW formatters
+	funcs := format.FuncMap()
+	funcs["richText"] = func(s string) template.HTML {
+		descHTML, _ := sanitize.Description(s)
+		return template.HTML(descHTML)
+	}
+	funcs["plainText"] = sanitize.PlainText
+	r.SetFuncMap(funcs)
 	r.LoadHTMLGlob("templates/*.html")
 
 	// Static files
 	r.Static("/static", "./static")
-	r.Static("/uploads", "./uploads")
+
+	// Public uploads use content-hashed names, so they can be cached forever
+	fileStore := storage.New(cfg)
+	publicUploads := r.Group(storage.PublicURLPrefix)
+	publicUploads.Use(middleware.CacheControl("public, max-age=31536000, immutable"))
+	publicUploads.Static("/", fileStore.PublicDir)
+
+	// Private uploads are only reachable through short-lived signed URLs
+	fileH := &handlers.FileHandler{Storage: fileStore}
+	r.GET(storage.PrivateURLPrefix+"/:name", fileH.ServePrivate)
+
+	// Resized listing images; without the disk cache variants are made on every request
+	imageCache, err := imageproxy.NewDiskCache(cfg.ImageCacheDir, int64(cfg.ImageCacheMaxSizeMB)<<20)
+	if err != nil {
+		log.Error("image variant cache disabled", zap.Error(err))
+	}
+	widths, _ := imageproxy.ParseWidths(cfg.ImageVariantWidths) // Checked by config.Load
+	imageH := &handlers.ImageProxyHandler{DB: db, Storage: fileStore, Cache: imageCache, Widths: widths, StaticDir: "./static"}
+	r.GET("/img/:imageID", imageH.Serve)
 
 	// Health check endpoints
 	healthHandler := func(c *gin.Context) {
@@ -56,6 +107,13 @@ func NewRouter(cfg *config.Config, log *zap.Logger, db *gorm.DB, redisClient *re
 	r.GET("/health", healthHandler)
 	r.GET("/healthz", healthHandler)
 
+	// Machine-readable API description; the Swagger UI pulls assets from a CDN,
+	// so it stays out of production
+	r.GET("/openapi.json", openapi.Serve)
+	if cfg.AppEnv != "production" {
+		r.GET("/docs", openapi.SwaggerUI)
+	}
+
 	// Public pages
 	r.GET("/", func(c *gin.Context) {
 		var txs []models.Transaction
@@ -63,7 +121,7 @@ func NewRouter(cfg *config.Config, log *zap.Logger, db *gorm.DB, redisClient *re
 
 		if db != nil {
 			_ = db.Order("created_at desc").Limit(10).Find(&txs).Error
-			_ = db.Order("id desc").Limit(8).Find(&listings).Error
+			_ = db.Where("status NOT IN ? AND visibility = ?", models.HiddenListingStatuses, models.ListingVisibilityPublic).Order("id desc").Limit(8).Find(&listings).Error
 		}
 
 		c.HTML(http.StatusOK, "index.html", gin.H{
@@ -78,7 +136,7 @@ func NewRouter(cfg *config.Config, log *zap.Logger, db *gorm.DB, redisClient *re
 
 		if db != nil {
 			_ = db.Order("created_at desc").Limit(10).Find(&txs).Error
-			_ = db.Order("id desc").Limit(8).Find(&listings).Error
+			_ = db.Where("status NOT IN ? AND visibility = ?", models.HiddenListingStatuses, models.ListingVisibilityPublic).Order("id desc").Limit(8).Find(&listings).Error
 		}
 
 		c.HTML(http.StatusOK, "market_home.html", gin.H{
@@ -109,7 +167,7 @@ func NewRouter(cfg *config.Config, log *zap.Logger, db *gorm.DB, redisClient *re
 			return
 		}
 		var ls models.Listing
-		if err := db.Where("title LIKE ?", "%"+q+"%").Order("id desc").First(&ls).Error; err != nil {
+		if err := db.Where("title LIKE ? AND status NOT IN ? AND visibility = ?", "%"+q+"%", models.HiddenListingStatuses, models.ListingVisibilityPublic).Order("id desc").First(&ls).Error; err != nil {
 			c.Redirect(http.StatusFound, "/market")
 			return
 		}
@@ -124,91 +182,331 @@ func NewRouter(cfg *config.Config, log *zap.Logger, db *gorm.DB, redisClient *re
 			return
 		}
 		var ls models.Listing
-		if err := db.First(&ls, idStr).Error; err != nil {
+		// Pages are rendered for anonymous visitors, so private listings never show here
+		if err := db.Where("status NOT IN ? AND visibility <> ?", models.HiddenListingStatuses, models.ListingVisibilityPrivate).First(&ls, idStr).Error; err != nil {
 			c.String(http.StatusNotFound, "listing not found")
 			return
 		}
+		if !ls.IsPublic() {
+			c.Header("X-Robots-Tag", "noindex")
+		}
 		var images []models.Image
-		_ = db.Where("listing_id = ?", ls.ID).Order("id asc").Find(&images).Error
+		_ = db.Where("listing_id = ? AND moderation_status = ?", ls.ID, models.ImageModerationApproved).Order("id asc").Find(&images).Error
 		// log.Printf("Go syntax: %#v\n", p)
 		logOri.Printf("===== LS: %+v\n", ls)
+		phone := ls.PhoneNumber
+		if cfg.ContactRevealRequiresLead {
+			phone = handlers.MaskPhone(phone)
+		}
 		c.HTML(http.StatusOK, "market_listing.html", gin.H{
 			"listing": ls,
 			"images":  images,
+			"phone":   phone,
 		})
 	})
 
+	// Keep-alive email links; the token authenticates the seller
+	keepAliveH := &handlers.KeepAliveHandler{DB: db}
+	r.GET("/keep-alive/:token", keepAliveH.Show)
+	r.POST("/keep-alive/:token", keepAliveH.Resolve)
+
+	// Unsubscribe links in notification and marketing emails; the token is signed
+	unsubscribeH := &handlers.UnsubscribeHandler{DB: db, Cfg: cfg}
+	r.GET("/unsubscribe/:token", unsubscribeH.Show)
+	r.POST("/unsubscribe/:token", unsubscribeH.Resolve)
+
 	r.GET("/login", func(c *gin.Context) { c.HTML(http.StatusOK, "login.html", nil) })
-	r.GET("/register", func(c *gin.Context) { c.HTML(http.StatusOK, "register.html", nil) })
+	r.GET("/register", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "register.html", gin.H{"termsVersion": cfg.TermsVersion})
+	})
 	r.GET("/dashboard", func(c *gin.Context) { c.HTML(http.StatusOK, "dashboard.html", nil) })
 
 	// REST API v1
-	authH := &handlers.AuthHandler{DB: db, Cfg: cfg, Log: log}
-	listH := &handlers.ListingsHandler{DB: db}
-	userH := &handlers.UserHandler{DB: db}
-	favH := &handlers.FavoriteHandler{DB: db}
-	msgH := &handlers.MessageHandler{DB: db}
-	auctionProxyH := handlers.NewAuctionProxyHandler(cfg, log)
+	var cacheSvc *redisclient.CacheService
+	if redisClient != nil {
+		cacheSvc = redisclient.NewCacheService(redisClient)
+	}
+	authH := &handlers.AuthHandler{
+		DB:        db,
+		Cfg:       cfg,
+		Cache:     cacheSvc,
+		Challenge: auth.NewLoginChallenge(redisClient, cfg, auth.NewTurnstileVerifier(cfg.TurnstileSecretKey)),
+		Lockout:   auth.NewLockoutTracker(redisClient, cfg),
+
+		SessionManager: auth.NewSessionManager(redisClient, db, cfg),
+	}
+	trending := redisclient.NewTrending(redisClient)
+	listingCache := cacheSvc
+	if !cfg.ListingCacheEnabled {
+		listingCache = nil
+	}
+	// Listing searches, image archives and admin stats share one budget, so a
+	// burst of them queues briefly and then sheds instead of draining the DB pool
+	heavyReads := middleware.NewConcurrencyLimiter(cfg.HeavyReadMaxConcurrent, time.Duration(cfg.HeavyReadQueueMillis)*time.Millisecond)
+	exportGate := redisclient.NewExportGate(redisClient, cfg.ExportMaxConcurrent, time.Duration(cfg.ExportSlotTTLMinutes)*time.Minute)
+	words, err := wordfilter.New(cfg.SensitiveWordsMode, cfg.SensitiveWordsFile)
+	if err != nil {
+		log.Warn("failed to load sensitive words file, using built-in list", zap.String("file", cfg.SensitiveWordsFile), zap.Error(err))
+		words, _ = wordfilter.New(cfg.SensitiveWordsMode, "")
+	}
+	listH := &handlers.ListingsHandler{DB: db, Cfg: cfg, Storage: fileStore, RedisClient: redisClient, Leaderboard: trending, Exports: exportGate, Emails: auth.NewEmailService(cfg), Words: words, Cache: listingCache}
+
+	nameChecker, err := names.NewChecker(cfg.ReservedUsernamesFile)
+	if err != nil {
+		log.Warn("failed to load reserved usernames file, using built-in list", zap.String("file", cfg.ReservedUsernamesFile), zap.Error(err))
+		nameChecker, _ = names.NewChecker("")
+	}
+	userH := &handlers.UserHandler{DB: db, Cache: cacheSvc, Names: nameChecker, BcryptCost: cfg.BcryptCost}
+	favH := &handlers.FavoriteHandler{DB: db, Cfg: cfg, Leaderboard: trending, Cache: listingCache}
+	recH := &handlers.RecommendationHandler{DB: db, Cache: cacheSvc}
+	uploadH := &handlers.UploadHandler{DB: db, Cfg: cfg, Storage: fileStore, Uploads: uploads.NewManager(redisClient, cfg)}
+	compareH := &handlers.ComparisonHandler{DB: db, Cfg: cfg}
+	msgH := &handlers.MessageHandler{DB: db, Cfg: cfg, Words: words}
+	leadTemplateH := &handlers.LeadTemplateHandler{DB: db}
+	adminH := &handlers.AdminHandler{DB: db}
+	txnH := &handlers.TransactionHandler{DB: db}
+	disputeH := &handlers.DisputeHandler{DB: db}
+	moderationH := &handlers.ModerationHandler{DB: db, Storage: fileStore, Emails: auth.NewEmailService(cfg), Cache: listingCache}
+	financialsH := &handlers.FinancialsHandler{DB: db, Emails: auth.NewEmailService(cfg), Cache: listingCache}
+	announceH := &handlers.AnnouncementHandler{DB: db, Cache: cacheSvc}
+	apqTTL := time.Duration(cfg.GraphQLAPQTTLHours) * time.Hour
+	persistedQueries, err := gqlctx.NewPersistedQueries(redisClient, cfg.GraphQLAPQMode, apqTTL, cfg.GraphQLPersistedQueriesFile)
+	if err != nil {
+		log.Error("failed to load persisted query manifest, only queries registered via the admin API are known",
+			zap.String("file", cfg.GraphQLPersistedQueriesFile), zap.Error(err))
+		persistedQueries, _ = gqlctx.NewPersistedQueries(redisClient, cfg.GraphQLAPQMode, apqTTL, "")
+	}
+	persistedQueryH := &handlers.PersistedQueryHandler{Queries: persistedQueries}
+	auctionProxyH := handlers.NewAuctionProxyHandler(cfg, log, redisClient)
+	capabilitiesH := handlers.NewCapabilitiesHandler(cfg, db, redisClient)
+	emailWebhookH, err := handlers.NewEmailWebhookHandler(db, cfg.SendGridWebhookPublicKey)
+	if err != nil {
+		log.Error("SendGrid webhook disabled", zap.Error(err))
+	}
+	auctionWebhookH := handlers.NewAuctionWebhookHandler(db, auth.NewEmailService(cfg), cfg.AuctionWebhookSecret)
+	termsH := &handlers.TermsHandler{DB: db, Cfg: cfg}
+
+	jwtConfig := middleware.JWTConfig{
+		Secret:        cfg.JWTSecret,
+		Issuer:        cfg.JWTIssuer,
+		ParserOptions: auth.ParserOptions(cfg),
+	}
+	jwtAuth := middleware.JWT(jwtConfig, log)
+	requireTerms := middleware.RequireTerms(db, cfg.TermsVersion)
+
+	// 4xx responses per IP feed the bot scoring of signups and leads
+	bots := botcheck.New(cfg, redisClient)
+	formH := &handlers.FormHandler{Bots: bots}
+	leadH := &handlers.LeadHandler{
+		DB:           db,
+		RedisClient:  redisClient,
+		Config:       cfg,
+		EmailService: auth.NewEmailService(cfg),
+		Leaderboard:  trending,
+		Words:        words,
+		Bots:         bots,
+	}
 
 	api := r.Group("/api/v1")
+	api.Use(bots.TrackErrors())
 	{
+		// Reports degraded dependencies, so it must work without the database
+		api.GET("/capabilities", capabilitiesH.Get)
+		api.GET("/forms/token", formH.Token)
+
+		// Everything except the auction proxy needs the database
+		data := api.Group("")
+		data.Use(middleware.RequireDB(db))
+
 		// Public endpoints
-		api.POST("/auth/register", authH.Register)
-		api.POST("/auth/login", authH.Login)
-		api.POST("/auth/logout", authH.Logout)
-		api.GET("/listings", listH.List)
-		api.GET("/listings/:id", listH.Get)
-		api.GET("/categories", listH.GetCategories)
+		data.POST("/auth/register", authH.Register)
+		data.POST("/auth/login", authH.Login)
+		data.POST("/auth/2fa/login", authH.TwoFactorLogin)
+		data.POST("/auth/refresh", authH.Refresh)
+		data.POST("/auth/logout", authH.Logout)
+		data.GET("/listings", heavyReads.WeightedMiddleware(handlers.ListWeight), middleware.OptionalJWT(jwtConfig, log), listH.List)
+		data.GET("/listings/metadata", listH.Metadata)
+		data.GET("/listings/trending", listH.Trending)
+		data.GET("/listings/:id", middleware.OptionalJWT(jwtConfig, log), listH.Get)
+		data.GET("/listings/:id/images.zip", heavyReads.Middleware(4), middleware.OptionalJWT(jwtConfig, log), listH.DownloadImages)
+		data.GET("/listings/:id/documents/:docId", middleware.OptionalJWT(jwtConfig, log), listH.DownloadDocument)
+		data.GET("/listings/:id/favorites/count", middleware.OptionalJWT(jwtConfig, log), favH.CountForListing)
+		data.GET("/recommendations", middleware.OptionalJWT(jwtConfig, log), recH.List)
+		data.GET("/announcements/active", middleware.OptionalJWT(jwtConfig, log), announceH.Active)
+		data.GET("/categories", listH.GetCategories)
+		data.GET("/industries", listH.GetIndustries)
+		data.GET("/lead-templates", leadTemplateH.List)
+		data.GET("/terms", middleware.OptionalJWT(jwtConfig, log), termsH.Current)
+
+		// Provider webhooks authenticate with their own signatures
+		data.POST("/webhooks/sendgrid", emailWebhookH.SendGrid)
+		data.POST("/webhooks/auction-events", auctionWebhookH.AuctionEvents)
 
 		// Protected endpoints
-		authd := api.Group("")
-		authd.Use(middleware.JWT(middleware.JWTConfig{
-			Secret: cfg.JWTSecret,
-			Issuer: cfg.JWTIssuer,
-		}, log))
+		authd := data.Group("")
+		authd.Use(jwtAuth, requireTerms)
 		{
 			// Authentication
 			authd.GET("/auth/me", authH.Me)
+			authd.POST("/auth/extend", authH.Extend)
+			authd.POST("/auth/2fa/enroll", authH.EnrollTwoFactor)
+			authd.POST("/auth/2fa/verify", authH.VerifyTwoFactor)
 
 			// User management
 			authd.GET("/user/profile", userH.GetProfile)
 			authd.PUT("/user/profile", userH.UpdateProfile)
 			authd.PUT("/user/password", userH.ChangePassword)
+			authd.GET("/user/dashboard", userH.Dashboard)
+			authd.GET("/user/sessions", authH.Sessions)
+			authd.POST("/user/accept-terms", termsH.Accept)
+			authd.GET("/user/listings", listH.Mine)
+			authd.GET("/user/listings/expiring", listH.Expiring)
 
 			// Listings
 			authd.POST("/listings", listH.Create)
 			authd.PUT("/listings/:id", listH.Update)
 			authd.DELETE("/listings/:id", listH.Delete)
+			authd.POST("/listings/:id/restore", listH.Restore)
+			authd.POST("/listings/:id/renew", listH.Renew)
+			authd.POST("/listings/:id/mark-sold", listH.MarkSold)
+			authd.GET("/listings/:id/preview", listH.Preview)
+			authd.GET("/listings/:id/views-by-hour", listH.ViewsByHour)
+			authd.GET("/listings/:id/interest", listH.Interest)
+			authd.GET("/listings/:id/contact", listH.RevealContact)
+			// Upload limits are raised over the global cap to the largest allowed
+			// files, plus a megabyte for the multipart encoding
 			authd.POST("/listings/:id/images", listH.UploadImages)
+			bodyLimiter.SetRouteLimit(http.MethodPost, "/api/v1/listings/:id/images", cfg.MaxTotalSizeMB+1)
+			authd.POST("/listings/:id/documents", listH.UploadDocument)
+			bodyLimiter.SetRouteLimit(http.MethodPost, "/api/v1/listings/:id/documents", cfg.MaxDocumentSizeMB+1)
+			authd.DELETE("/listings/:id/documents/:docId", listH.DeleteDocument)
+
+			// Resumable image uploads
+			authd.POST("/uploads/initiate", uploadH.Initiate)
+			authd.GET("/uploads/:id", uploadH.Status)
+			authd.PUT("/uploads/:id/chunks/:index", uploadH.PutChunk)
+			bodyLimiter.SetRouteLimit(http.MethodPut, "/api/v1/uploads/:id/chunks/:index", cfg.UploadChunkSizeMB+1)
+			authd.POST("/uploads/:id/complete", uploadH.Complete)
+			authd.DELETE("/uploads/:id", uploadH.Abort)
 
 			// Favorites
 			authd.GET("/favorites", favH.List)
+			authd.GET("/favorites/:id", favH.Get)
+			authd.GET("/favorites/by-listing/:listingId", favH.GetByListing)
 			authd.POST("/favorites", favH.Add)
+			authd.POST("/favorites/toggle", favH.Toggle)
 			authd.DELETE("/favorites/:id", favH.Remove)
 
+			// Saved comparison lists
+			authd.GET("/comparisons", compareH.List)
+			authd.POST("/comparisons", compareH.Create)
+			authd.GET("/comparisons/:id", compareH.Get)
+			authd.PUT("/comparisons/:id", compareH.Update)
+			authd.DELETE("/comparisons/:id", compareH.Delete)
+
 			// Messages
 			authd.GET("/messages", msgH.List)
 			authd.GET("/messages/:id", msgH.Get)
 			authd.POST("/messages", msgH.Create)
 			authd.PUT("/messages/:id/read", msgH.MarkAsRead)
+			authd.GET("/conversations", msgH.Conversations)
+			authd.GET("/conversations/:userId", msgH.Conversation)
+
+			// Leads
+			authd.POST("/listings/:id/leads", leadH.ContactSeller)
+			authd.GET("/leads", leadH.GetUserLeads)
+			authd.PUT("/leads/:id/read", leadH.MarkLeadAsRead)
+			authd.PUT("/leads/:id/assignee", leadH.ReassignLead)
+			authd.GET("/user/lead-routing", leadH.GetLeadRouting)
+			authd.PUT("/user/lead-routing", leadH.UpdateLeadRouting)
+			authd.POST("/user/organization/members", leadH.AddOrganizationMember)
+			authd.DELETE("/user/organization/members/:userId", leadH.RemoveOrganizationMember)
+
+			// Transactions
+			authd.POST("/transactions", txnH.Create)
+			authd.GET("/transactions", txnH.List)
+			authd.GET("/transactions/:id", txnH.Get)
+			authd.PATCH("/transactions/:id/status", txnH.UpdateStatus)
+
+			// Transaction disputes
+			authd.POST("/transactions/:id/disputes", disputeH.Open)
+			authd.GET("/disputes", disputeH.List)
+
+			// Admin
+			admin := authd.Group("/admin")
+			admin.Use(middleware.RequireAdmin(db))
+			{
+				admin.GET("/lead-templates", leadTemplateH.AdminList)
+				admin.POST("/lead-templates", leadTemplateH.Create)
+				admin.PUT("/lead-templates/:id", leadTemplateH.Update)
+				admin.DELETE("/lead-templates/:id", leadTemplateH.Delete)
+
+				admin.POST("/listings/recount", adminH.RecountPopularity)
+				admin.GET("/maintenance/orphans", adminH.Orphans)
+				admin.POST("/maintenance/orphans", adminH.FixOrphans)
+				admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+				admin.GET("/metrics/prometheus", func(c *gin.Context) {
+					c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+					_ = metrics.WritePrometheus(c.Writer)
+				})
+				admin.GET("/stats", heavyReads.Middleware(2), adminH.Stats)
+				admin.PUT("/users/:id/username", adminH.SetUsername)
+				admin.GET("/users/:id/email-status", adminH.EmailStatus)
+				admin.POST("/auction-events/:id/replay", auctionWebhookH.ReplayAuctionEvent)
+				admin.POST("/disputes/:id/resolve", disputeH.Resolve)
+
+				admin.GET("/moderation/images", moderationH.Queue)
+				admin.POST("/moderation/images/:id/approve", moderationH.Approve)
+				admin.POST("/moderation/images/:id/reject", moderationH.Reject)
+
+				admin.GET("/financials", financialsH.Queue)
+				admin.POST("/listings/:id/financials/verify", financialsH.Verify)
+				admin.POST("/listings/:id/financials/reject", financialsH.Reject)
+
+				admin.GET("/announcements", announceH.AdminList)
+				admin.POST("/announcements", announceH.Create)
+				admin.PUT("/announcements/:id", announceH.Update)
+				admin.DELETE("/announcements/:id", announceH.Delete)
+
+				admin.POST("/graphql/persisted-queries", persistedQueryH.Register)
+				admin.DELETE("/graphql/persisted-queries/:hash", persistedQueryH.Unregister)
+			}
+		}
 
-			// Auction proxy endpoints (forward to auction service)
-			authd.GET("/auctions", auctionProxyH.GetAuctions)
-			authd.GET("/auctions/:id", auctionProxyH.GetAuction)
-			authd.POST("/auctions", auctionProxyH.CreateAuction)
-			authd.POST("/auctions/:id/activate", auctionProxyH.ActivateAuction)
-			authd.POST("/auctions/:id/bids", auctionProxyH.PlaceBid)
-			authd.GET("/auctions/:id/my-bids", auctionProxyH.GetMyBids)
-			authd.GET("/auctions/:id/results", auctionProxyH.GetAuctionResults)
-			authd.GET("/auctions/:id/ws-url", auctionProxyH.WebSocketProxy)
+		// Auction proxy endpoints (forward to auction service)
+		auctions := api.Group("")
+		auctions.Use(jwtAuth, requireTerms)
+		{
+			auctions.GET("/auctions", auctionProxyH.GetAuctions)
+			auctions.GET("/auctions/:id", auctionProxyH.GetAuction)
+			auctions.POST("/auctions", auctionProxyH.CreateAuction)
+			auctions.POST("/auctions/:id/activate", auctionProxyH.ActivateAuction)
+			auctions.POST("/auctions/:id/bids", auctionProxyH.PlaceBid)
+			auctions.GET("/auctions/:id/my-bids", auctionProxyH.GetMyBids)
+			auctions.GET("/auctions/:id/results", auctionProxyH.GetAuctionResults)
+			auctions.GET("/auctions/:id/ws-url", auctionProxyH.WebSocketProxy)
 		}
 	}
 
 	// GraphQL
 	es := graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{DB: db, Cfg: cfg}})
-	gh := handler.NewDefaultServer(es)
+	// gqlgen's default server, with its in-memory APQ cache swapped for the
+	// shared one that also enforces the allowlist
+	gh := handler.New(es)
+	gh.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
+	gh.AddTransport(transport.Options{})
+	gh.AddTransport(transport.GET{})
+	gh.AddTransport(transport.POST{})
+	gh.AddTransport(transport.MultipartForm{})
+	gh.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	gh.Use(extension.Introspection{})
+	gh.Use(persistedQueries)
+	log.Info("GraphQL persisted queries configured",
+		zap.String("mode", cfg.GraphQLAPQMode),
+		zap.Int("manifest_queries", persistedQueries.ManifestSize()))
 
 	graphqlGroup := r.Group("")
+	graphqlGroup.Use(middleware.RequireDB(db))
 	graphqlGroup.Use(func(c *gin.Context) {
 		// Enrich request context with userID if token provided
 		ctx := gqlctx.ExtractUserFromAuthHeader(cfg, c.Request.Context(), c.GetHeader("Authorization"))
@@ -218,63 +516,12 @@ func NewRouter(cfg *config.Config, log *zap.Logger, db *gorm.DB, redisClient *re
 	graphqlGroup.POST("/graphql", gin.WrapH(gh))
 	r.GET("/playground", gin.WrapH(playground.Handler("GraphQL", "/graphql")))
 
-	return r
-}
-
-func requestLogger(log *zap.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		c.Next()
-		dur := time.Since(start)
-
-		requestID := c.GetString("request_id")
-		if requestID == "" {
-			requestID = "unknown"
+	// Keep the OpenAPI document in step with the routes
+	if cfg.AppEnv != "production" {
+		for _, route := range openapi.Undocumented(r.Routes()) {
+			log.Warn("route missing from the OpenAPI document", zap.String("route", route))
 		}
-
-		log.Info("request",
-			zap.String("request_id", requestID),
-			zap.String("method", c.Request.Method),
-			zap.String("path", c.Request.URL.Path),
-			zap.String("query", c.Request.URL.RawQuery),
-			zap.String("ip", c.ClientIP()),
-			zap.String("user_agent", c.Request.UserAgent()),
-			zap.Int("status", c.Writer.Status()),
-			zap.Duration("duration", dur),
-		)
 	}
-}
 
-func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
-	allowedOrigins := strings.Split(cfg.CORSAllowedOrigins, ",")
-	allowedMethods := cfg.CORSAllowedMethods
-	allowedHeaders := cfg.CORSAllowedHeaders
-
-	return func(c *gin.Context) {
-		origin := c.GetHeader("Origin")
-		if origin != "" && (cfg.CORSAllowedOrigins == "*" || contains(allowedOrigins, origin)) {
-			c.Header("Access-Control-Allow-Origin", origin)
-			c.Header("Vary", "Origin")
-		} else if cfg.CORSAllowedOrigins == "*" {
-			c.Header("Access-Control-Allow-Origin", "*")
-		}
-		c.Header("Access-Control-Allow-Methods", allowedMethods)
-		c.Header("Access-Control-Allow-Headers", allowedHeaders)
-		c.Header("Access-Control-Allow-Credentials", "true")
-
-		if c.Request.Method == http.MethodOptions {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
-		c.Next()
-	}
-}
-
-func contains(values []string, target string) bool {
-	for _, v := range values {
-		if strings.TrimSpace(v) == target {
-			return true
-		}
-	}
-	return false
+	return r
 }
@@ -0,0 +1,272 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"trade_company/internal/config"
+	"trade_company/internal/openapi"
+	"trade_company/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+)
+
+// newTestRouter builds the router against an in-memory database, without Redis
+func newTestRouter(t *testing.T) (http.Handler, *config.Config, *observer.ObservedLogs) {
+	t.Helper()
+	return newTestRouterEnv(t, nil)
+}
+
+// newTestRouterEnv is newTestRouter with env set on top of the development defaults
+func newTestRouterEnv(t *testing.T, env map[string]string) (http.Handler, *config.Config, *observer.ObservedLogs) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	return newTestRouterDB(t, env, db)
+}
+
+// newTestRouterDB is newTestRouterEnv against db, which may be nil as when
+// main.go fails to connect
+func newTestRouterDB(t *testing.T, env map[string]string, db *gorm.DB) (http.Handler, *config.Config, *observer.ObservedLogs) {
+	t.Helper()
+	t.Setenv("APP_ENV", "development")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	// Templates are loaded relative to the repo root
+	wd, _ := os.Getwd()
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	core, logs := observer.New(zap.WarnLevel)
+	return NewRouter(cfg, zap.New(core), db, nil), cfg, logs
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	handler, _, logs := newTestRouter(t)
+
+	// Check the served document itself against every registered API route
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("decode /openapi.json: %v", err)
+	}
+
+	apiRoutes := 0
+	for _, route := range handler.(*gin.Engine).Routes() {
+		path, ok := strings.CutPrefix(route.Path, openapi.APIPrefix+"/")
+		if !ok {
+			continue
+		}
+		apiRoutes++
+		path = "/" + ginParamPattern.ReplaceAllString(path, "{$1}")
+		if spec.Paths[path][strings.ToLower(route.Method)] == nil {
+			t.Errorf("%s %s is missing from /openapi.json", route.Method, route.Path)
+		}
+	}
+	if apiRoutes == 0 {
+		t.Fatal("no API routes registered")
+	}
+
+	// The startup warning agrees
+	for _, entry := range logs.FilterMessage("route missing from the OpenAPI document").All() {
+		t.Errorf("undocumented route %v", entry.ContextMap()["route"])
+	}
+}
+
+// ginParamPattern matches a gin path parameter such as :id
+var ginParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+func TestRoutesWithoutDatabase(t *testing.T) {
+	r, _, _ := newTestRouterDB(t, nil, nil)
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{method: http.MethodGet, target: "/api/v1/listings", want: http.StatusServiceUnavailable},
+		{method: http.MethodPost, target: "/api/v1/auth/login", want: http.StatusServiceUnavailable},
+		{method: http.MethodGet, target: "/api/v1/user/profile", want: http.StatusServiceUnavailable},
+		{method: http.MethodPost, target: "/graphql", want: http.StatusServiceUnavailable},
+		// Reports the outage instead of failing with it
+		{method: http.MethodGet, target: "/api/v1/capabilities", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusServiceUnavailable && !strings.Contains(w.Body.String(), `"code":"DB_UNAVAILABLE"`) {
+				t.Errorf("body %s, want code DB_UNAVAILABLE", w.Body)
+			}
+		})
+	}
+}
+
+func TestUploadCacheHeaders(t *testing.T) {
+	publicDir, privateDir := t.TempDir(), t.TempDir()
+	t.Setenv("PUBLIC_UPLOAD_DIR", publicDir)
+	t.Setenv("PRIVATE_UPLOAD_DIR", privateDir)
+	r, cfg, _ := newTestRouter(t)
+
+	const name = "listing_1_0123456789abcdef.pdf"
+	const image = "listing_1_fedcba9876543210.jpg"
+	for _, dir := range []string{publicDir, privateDir} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("%PDF-1.4"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(publicDir, image), []byte("\xff\xd8\xff"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	signed := storage.New(cfg).SignedURL(name, "")
+	expiredCfg := *cfg
+	expiredCfg.SignedURLTTLMinutes = -1
+	expired := storage.New(&expiredCfg).SignedURL(name, "")
+	tampered := signed[:len(signed)-1] + "0"
+	if strings.HasSuffix(signed, "0") {
+		tampered = signed[:len(signed)-1] + "1"
+	}
+
+	tests := []struct {
+		name      string
+		target    string
+		want      int
+		wantCache string
+		wantError string
+	}{
+		{name: "public upload", target: storage.PublicURLPrefix + "/" + name, want: http.StatusOK, wantCache: "public, max-age=31536000, immutable"},
+		{name: "public listing image", target: storage.PublicURLPrefix + "/" + image, want: http.StatusOK, wantCache: "public, max-age=31536000, immutable"},
+		{name: "private file without a signature", target: storage.PrivateURLPrefix + "/" + name, want: http.StatusForbidden},
+		{name: "private file with a signed link", target: signed, want: http.StatusOK, wantCache: "private, no-store"},
+		{name: "private file with an expired link", target: expired, want: http.StatusForbidden, wantError: "Link has expired"},
+		{name: "private file with a tampered signature", target: tampered, want: http.StatusForbidden, wantError: "Invalid link"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.wantCache != "" && w.Header().Get("Cache-Control") != tt.wantCache {
+				t.Errorf("Cache-Control %q, want %q", w.Header().Get("Cache-Control"), tt.wantCache)
+			}
+			if tt.wantError != "" && !strings.Contains(w.Body.String(), tt.wantError) {
+				t.Errorf("body %s, want error %q", w.Body, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCORSFromConfig(t *testing.T) {
+	production, err := godotenv.Read("../../env.production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Injected from Secret Manager at deploy time
+	production["UPLOAD_SIGNING_SECRET"] = "test-signing-secret"
+	const frontend = "https://business-exchange-frontend-430730011391.us-central1.run.app"
+
+	tests := []struct {
+		name            string
+		env             map[string]string
+		origin          string
+		wantOrigin      string
+		wantCredentials bool
+	}{
+		{name: "production frontend logs in with cookies", env: production, origin: frontend, wantOrigin: frontend, wantCredentials: true},
+		{name: "production rejects other origins", env: production, origin: "https://evil.example.com"},
+		{name: "configured allowlist", env: map[string]string{"CORS_ALLOWED_ORIGINS": "https://app.example.com,https://*.example.org"},
+			origin: "https://admin.example.org", wantOrigin: "https://admin.example.org", wantCredentials: true},
+		{name: "allowlist with credentials off", env: map[string]string{"CORS_ALLOWED_ORIGINS": "https://app.example.com", "CORS_ALLOW_CREDENTIALS": "false"},
+			origin: "https://app.example.com", wantOrigin: "https://app.example.com"},
+		{name: "wildcard never sends credentials", env: map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"},
+			origin: "https://app.example.com", wantOrigin: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := newTestRouterEnv(t, tt.env)
+			req := httptest.NewRequest(http.MethodOptions, "/api/v1/auth/login", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("credentials allowed %v, want %v", got, tt.wantCredentials)
+			}
+		})
+	}
+}
+
+func TestUploadBodyLimits(t *testing.T) {
+	const mb = 1 << 20
+	r, _, _ := newTestRouterEnv(t, map[string]string{
+		"GLOBAL_BODY_LIMIT_MB": "1",
+		"MAX_TOTAL_SIZE_MB":    "2",
+		"MAX_DOCUMENT_SIZE_MB": "3",
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		size     int
+		tooLarge bool
+	}{
+		{name: "JSON route over the global limit", method: http.MethodPost, target: "/api/v1/listings", size: mb + 1, tooLarge: true},
+		{name: "images at the upload limit with multipart overhead", method: http.MethodPost, target: "/api/v1/listings/1/images", size: 2*mb + 64<<10},
+		{name: "images over the upload limit", method: http.MethodPost, target: "/api/v1/listings/1/images", size: 3*mb + 1, tooLarge: true},
+		{name: "document at its limit", method: http.MethodPost, target: "/api/v1/listings/1/documents", size: 3*mb + 64<<10},
+		{name: "document over its limit", method: http.MethodPost, target: "/api/v1/listings/1/documents", size: 4*mb + 1, tooLarge: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewReader(make([]byte, tt.size)))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			// Accepted bodies go on to the JWT check
+			if (w.Code == http.StatusRequestEntityTooLarge) != tt.tooLarge {
+				t.Errorf("status %d, too large want %v", w.Code, tt.tooLarge)
+			}
+		})
+	}
+}
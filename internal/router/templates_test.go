@@ -0,0 +1,93 @@
+package router
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"trade_company/internal/format"
+	"trade_company/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// updateGolden rewrites the template snapshots: go test ./internal/router -run TestTemplateSnapshots -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestTemplateSnapshots renders the market pages with fixture data and
+// compares them to testdata, so amounts and dates can't lose their formatting
+func TestTemplateSnapshots(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Listing{}, &models.ListingCount{}, &models.Image{}, &models.Transaction{}); err != nil {
+		t.Fatal(err)
+	}
+	owner := models.User{Email: "seller@example.com", Username: "seller"}
+	db.Create(&owner)
+	created := time.Date(2019, 3, 1, 9, 0, 0, 0, format.Taipei)
+	listings := []models.Listing{
+		{
+			Title: "大安區早午餐店", Description: "<p>營業中，<b>設備齊全</b></p>", Price: 3500000,
+			Location: "台北市大安區", Industry: "餐飲", Rent: 65000, SquareMeters: 82.5, Floor: 1,
+			Equipment: "咖啡機、冷藏櫃", AnnualRevenue: 12800000, GrossProfitRate: 0.355,
+			FastestMovingDate: time.Date(2026, 11, 30, 16, 0, 0, 0, time.UTC),
+			BrandStory:        "十年老店", CreatedAt: created,
+		},
+		// Blank optional fields show placeholders instead of zero values
+		{Title: "板橋手搖飲", Description: "轉讓", Price: 880000, Location: "新北市板橋區", CreatedAt: created},
+	}
+	for i := range listings {
+		listings[i].OwnerID = owner.ID
+		listings[i].Status = models.ListingStatusActive
+		listings[i].Visibility = models.ListingVisibilityPublic
+		if err := db.Create(&listings[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Create(&models.Image{ListingID: listings[0].ID, Filename: "a.jpg", URL: "/uploads/a.jpg", ModerationStatus: models.ImageModerationApproved})
+
+	// newTestRouterDB moves to the repo root for the templates
+	wd, _ := os.Getwd()
+	r, _, _ := newTestRouterDB(t, map[string]string{"CONTACT_REVEAL_REQUIRES_LEAD": "false"}, db)
+	pages := []struct {
+		golden string
+		target string
+	}{
+		{golden: "market_home.golden", target: "/market"},
+		{golden: "market_listing.golden", target: "/market/listings/1"},
+		{golden: "market_listing_blank.golden", target: "/market/listings/2"},
+	}
+	for _, page := range pages {
+		t.Run(page.golden, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, page.target, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+
+			path := filepath.Join(wd, "testdata", page.golden)
+			if *updateGolden {
+				if err := os.WriteFile(path, w.Body.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if w.Body.String() != string(want) {
+				t.Errorf("%s differs from %s; rerun with -update if the change is intended:\n%s", page.target, path, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Package sanitize reduces user-supplied rich text to a safe HTML subset.
+package sanitize
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// MaxDescriptionRunes is the longest listing description allowed, counted on the plain text
+const MaxDescriptionRunes = 10000
+
+// allowedTags is the formatting subset kept in descriptions. Word and browsers
+// often paste <b>/<i>, so those are mapped onto their semantic equivalents.
+var allowedTags = map[string]string{
+	"p":      "p",
+	"br":     "br",
+	"ul":     "ul",
+	"ol":     "ol",
+	"li":     "li",
+	"strong": "strong",
+	"b":      "strong",
+	"em":     "em",
+	"i":      "em",
+}
+
+// droppedTags are removed together with everything inside them
+var droppedTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"template": true,
+	"noscript": true,
+	"textarea": true,
+	"title":    true,
+	"head":     true,
+	"svg":      true,
+	"math":     true,
+}
+
+// blockTags end a line in the plain-text version
+var blockTags = map[string]bool{
+	"p":  true,
+	"br": true,
+	"li": true,
+	"ul": true,
+	"ol": true,
+}
+
+// Description sanitizes a listing description. It returns the allowlisted HTML
+// (no attributes, balanced tags) and a plain-text version for search and previews.
+// Plain-text input without markup comes back escaped but otherwise unchanged.
+func Description(raw string) (string, string) {
+	var out, text strings.Builder
+	var open []string
+	skipDepth := 0
+
+	z := xhtml.NewTokenizer(strings.NewReader(raw))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			// io.EOF or malformed input; everything read so far is kept
+			break
+		}
+
+		tok := z.Token()
+		name := strings.ToLower(tok.Data)
+
+		switch tt {
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if droppedTags[name] {
+				if tt == xhtml.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			tag, ok := allowedTags[name]
+			if !ok {
+				continue
+			}
+			if tag == "br" {
+				out.WriteString("<br>")
+				text.WriteString("\n")
+				continue
+			}
+			if tt == xhtml.SelfClosingTagToken {
+				continue
+			}
+			// A new <li> or <p> implicitly closes the previous one, as browsers do
+			if tag == "li" || tag == "p" {
+				open = closeImplicit(&out, open, tag)
+			}
+			out.WriteString("<" + tag + ">")
+			open = append(open, tag)
+			if tag == "li" || tag == "p" {
+				text.WriteString("\n")
+			}
+
+		case xhtml.EndTagToken:
+			if droppedTags[name] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			tag, ok := allowedTags[name]
+			if !ok || tag == "br" {
+				continue
+			}
+			// Close back to the matching open tag; stray end tags are ignored
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tag {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					out.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+			if blockTags[tag] {
+				text.WriteString("\n")
+			}
+
+		case xhtml.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			out.WriteString(html.EscapeString(tok.Data))
+			text.WriteString(tok.Data)
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+
+	return strings.TrimSpace(out.String()), normalizeText(text.String())
+}
+
+// closeImplicit closes an open tag of the same name unless a list was opened after it
+func closeImplicit(out *strings.Builder, open []string, tag string) []string {
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] == "ul" || open[i] == "ol" {
+			return open
+		}
+		if open[i] == tag {
+			for j := len(open) - 1; j >= i; j-- {
+				out.WriteString("</" + open[j] + ">")
+			}
+			return open[:i]
+		}
+	}
+	return open
+}
+
+// PlainText returns only the plain-text version of a description
+func PlainText(raw string) string {
+	_, text := Description(raw)
+	return text
+}
+
+// DescriptionTooLong reports whether a plain-text description exceeds the length policy
+func DescriptionTooLong(text string) bool {
+	return utf8.RuneCountInString(text) > MaxDescriptionRunes
+}
+
+// normalizeText trims trailing spaces on each line and collapses runs of blank lines
+func normalizeText(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	result := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		result = append(result, line)
+	}
+	return strings.TrimSpace(strings.Join(result, "\n"))
+}
@@ -0,0 +1,109 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// hostileDescriptions are markup tricks a pasted or crafted description may contain
+var hostileDescriptions = []string{
+	`<script>alert(1)</script>`,
+	`<SCRIPT SRC=//evil.example/x.js></SCRIPT>`,
+	`<img src=x onerror=alert(1)>`,
+	`<p onclick="alert(1)" style="x:expression(alert(1))">hi</p>`,
+	`<a href="javascript:alert(1)">click</a>`,
+	`<svg><script>alert(1)</script></svg>`,
+	`<math><mi xlink:href="javascript:alert(1)">x</mi></math>`,
+	`<iframe srcdoc="<script>alert(1)</script>"></iframe>`,
+	`<scr<script>ipt>alert(1)</script>`,
+	`<<script>script>alert(1)<</script>/script>`,
+	`<p>unclosed <strong>tags <em>everywhere`,
+	`</p></li></ul>stray end tags`,
+	`<!-- <script>alert(1)</script> -->`,
+	`<![CDATA[<script>alert(1)</script>]]>`,
+	`<style>body{background:url(javascript:alert(1))}</style>`,
+	`<textarea><script>alert(1)</script></textarea>`,
+	`<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`,
+	`&lt;script&gt;alert(1)&lt;/script&gt;`,
+	`<b/onmouseover=alert(1)>bold</b>`,
+	`<li><li><ul><li>nested</ul></li>`,
+	"<p>\x00null\x00byte</p>",
+	`<template><script>alert(1)</script></template>x`,
+	`<br/><br /><BR>`,
+}
+
+// checkSafe fails if descHTML has anything outside the allowlist: other tags,
+// any attribute, comments or doctypes
+func checkSafe(t *testing.T, input, descHTML string) {
+	t.Helper()
+	allowed := map[string]bool{"p": true, "br": true, "ul": true, "ol": true, "li": true, "strong": true, "em": true}
+	z := xhtml.NewTokenizer(strings.NewReader(descHTML))
+	for {
+		tt := z.Next()
+		switch tt {
+		case xhtml.ErrorToken:
+			return
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			tok := z.Token()
+			if !allowed[tok.Data] {
+				t.Fatalf("Description(%q) kept tag <%s>: %q", input, tok.Data, descHTML)
+			}
+			if len(tok.Attr) > 0 {
+				t.Fatalf("Description(%q) kept attributes on <%s>: %q", input, tok.Data, descHTML)
+			}
+		case xhtml.CommentToken, xhtml.DoctypeToken:
+			t.Fatalf("Description(%q) kept a comment or doctype: %q", input, descHTML)
+		}
+	}
+}
+
+func TestDescriptionHostileHTML(t *testing.T) {
+	for _, input := range hostileDescriptions {
+		descHTML, _ := Description(input)
+		checkSafe(t, input, descHTML)
+		if again, _ := Description(descHTML); again != descHTML {
+			t.Errorf("sanitizing %q twice changed it: %q then %q", input, descHTML, again)
+		}
+	}
+}
+
+func TestDescription(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantHTML string
+		wantText string
+	}{
+		{name: "legacy plain text", raw: "Open daily\nNear MRT", wantHTML: "Open daily\nNear MRT", wantText: "Open daily\nNear MRT"},
+		{name: "entities are escaped once", raw: "Tom & Jerry's <3", wantHTML: "Tom &amp; Jerry&#39;s &lt;3", wantText: "Tom & Jerry's <3"},
+		{name: "word formatting", raw: `<p class="MsoNormal"><b>Revenue</b> <i>up</i></p><ul><li>one<li>two</ul>`,
+			wantHTML: "<p><strong>Revenue</strong> <em>up</em></p><ul><li>one</li><li>two</li></ul>", wantText: "Revenue up\n\none\ntwo"},
+		{name: "unclosed tags are balanced", raw: "<p>a<strong>b", wantHTML: "<p>a<strong>b</strong></p>", wantText: "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descHTML, text := Description(tt.raw)
+			if descHTML != tt.wantHTML || text != tt.wantText {
+				t.Errorf("Description(%q) = %q, %q; want %q, %q", tt.raw, descHTML, text, tt.wantHTML, tt.wantText)
+			}
+		})
+	}
+}
+
+func FuzzDescription(f *testing.F) {
+	for _, s := range hostileDescriptions {
+		f.Add(s)
+	}
+	f.Add("<p>Corner cafe &amp; bakery</p><ul><li>20 seats</li></ul>")
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		descHTML, _ := Description(raw)
+		checkSafe(t, raw, descHTML)
+		if again, _ := Description(descHTML); again != descHTML {
+			t.Errorf("sanitizing %q twice changed it: %q then %q", raw, descHTML, again)
+		}
+	})
+}
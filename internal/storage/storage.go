@@ -0,0 +1,227 @@
+// Package storage manages uploaded files for the Business Exchange Marketplace.
+//
+// Files are split into two roots:
+//   - Public: listing images, stored under content-hashed names so they can be
+//     cached forever by browsers and CDNs
+//   - Private: verification documents and avatars pending moderation, only
+//     reachable through short-lived HMAC-signed URLs
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"trade_company/internal/config"
+)
+
+const (
+	// PublicURLPrefix is the route public uploads are served from
+	PublicURLPrefix = "/uploads"
+	// PrivateURLPrefix is the route signed private files are served from
+	PrivateURLPrefix = "/private"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpiredSignature = errors.New("signature expired")
+	ErrInvalidName      = errors.New("invalid file name")
+)
+
+// Storage stores uploads on local disk and signs access to private files.
+type Storage struct {
+	PublicDir  string
+	PrivateDir string
+	secret     []byte
+	ttl        time.Duration
+}
+
+// New creates a Storage from the upload settings in the application config.
+func New(cfg *config.Config) *Storage {
+	return &Storage{
+		PublicDir:  cfg.PublicUploadDir,
+		PrivateDir: cfg.PrivateUploadDir,
+		secret:     []byte(cfg.UploadSigningSecret),
+		ttl:        time.Duration(cfg.SignedURLTTLMinutes) * time.Minute,
+	}
+}
+
+// SavePublic stores a file in the public root under a content-hashed name
+// prefixed with prefix, and returns the stored name and its public URL.
+func (s *Storage) SavePublic(file *multipart.FileHeader, prefix string) (string, string, error) {
+	name, err := s.save(file, s.PublicDir, prefix)
+	if err != nil {
+		return "", "", err
+	}
+	return name, PublicURLPrefix + "/" + name, nil
+}
+
+// SavePublicFile stores a file already on disk, such as an assembled chunked
+// upload, like SavePublic. filename supplies the extension.
+func (s *Storage) SavePublicFile(path, filename, prefix string) (string, string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer src.Close()
+
+	name, err := s.write(src, filename, s.PublicDir, prefix)
+	if err != nil {
+		return "", "", err
+	}
+	return name, PublicURLPrefix + "/" + name, nil
+}
+
+// SavePrivate stores a file in the private root and returns the stored name.
+// Use SignedURL to hand out temporary access to it.
+func (s *Storage) SavePrivate(file *multipart.FileHeader, prefix string) (string, error) {
+	return s.save(file, s.PrivateDir, prefix)
+}
+
+// SignedURL returns a URL to a private file that stays valid for the configured
+// TTL. A non-empty filename is signed along with it, and the file is then
+// downloaded as an attachment under that name.
+func (s *Storage) SignedURL(name, filename string) string {
+	expires := time.Now().Add(s.ttl).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	if filename != "" {
+		q.Set("filename", filename)
+	}
+	q.Set("signature", s.sign(name, filename, expires))
+	return PrivateURLPrefix + "/" + url.PathEscape(name) + "?" + q.Encode()
+}
+
+// Verify checks the signature and expiry of a private file request and returns
+// the file's path on disk.
+func (s *Storage) Verify(name, filename, expires, signature string) (string, error) {
+	if !validName(name) {
+		return "", ErrInvalidName
+	}
+
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	expected := s.sign(name, filename, exp)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", ErrInvalidSignature
+	}
+
+	if time.Now().Unix() > exp {
+		return "", ErrExpiredSignature
+	}
+
+	return filepath.Join(s.PrivateDir, name), nil
+}
+
+// save hashes the upload content and writes it to dir as <prefix>_<hash><ext>.
+func (s *Storage) save(file *multipart.FileHeader, dir, prefix string) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer src.Close()
+
+	return s.write(src, file.Filename, dir, prefix)
+}
+
+// write hashes src and copies it to dir as <prefix>_<hash><ext>.
+func (s *Storage) write(src io.ReadSeeker, filename, dir, prefix string) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, src); err != nil {
+		return "", fmt.Errorf("failed to hash upload: %w", err)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind upload: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	name := fmt.Sprintf("%s_%s%s", prefix, hex.EncodeToString(hasher.Sum(nil))[:32], ext)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create upload dir: %w", err)
+	}
+
+	dst, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return name, nil
+}
+
+// sign computes the HMAC-SHA256 signature for a file name, download name and expiry.
+func (s *Storage) sign(name, filename string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(name))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(filename))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validName rejects empty names and anything that could escape the storage root.
+func validName(name string) bool {
+	if name == "" || name != filepath.Base(name) {
+		return false
+	}
+	return !strings.Contains(name, "..") && !strings.ContainsAny(name, `/\`)
+}
+
+// RemovePublic deletes a file from the public root. Missing files are not an error.
+func (s *Storage) RemovePublic(name string) error {
+	if !validName(name) {
+		return ErrInvalidName
+	}
+	if err := os.Remove(filepath.Join(s.PublicDir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
+
+// PublicPath returns the on-disk path of a public file for server-side reads.
+func (s *Storage) PublicPath(name string) (string, error) {
+	if !validName(name) {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(s.PublicDir, name), nil
+}
+
+// PrivatePath returns the on-disk path of a private file for server-side use.
+// Clients only ever get private files through SignedURL.
+func (s *Storage) PrivatePath(name string) (string, error) {
+	if !validName(name) {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(s.PrivateDir, name), nil
+}
+
+// RemovePrivate deletes a file from the private root. Missing files are not an error.
+func (s *Storage) RemovePrivate(name string) error {
+	path, err := s.PrivatePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStorage(t *testing.T, ttl time.Duration) *Storage {
+	t.Helper()
+	dir := t.TempDir()
+	return &Storage{
+		PublicDir:  filepath.Join(dir, "public"),
+		PrivateDir: filepath.Join(dir, "private"),
+		secret:     []byte("test-secret"),
+		ttl:        ttl,
+	}
+}
+
+// signedParams splits a signed URL into the values ServePrivate reads
+func signedParams(t *testing.T, signed string) (name, filename, expires, signature string) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err = url.PathUnescape(strings.TrimPrefix(u.Path, PrivateURLPrefix+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	return name, q.Get("filename"), q.Get("expires"), q.Get("signature")
+}
+
+func TestVerify(t *testing.T) {
+	s := newTestStorage(t, time.Minute)
+	const name = "listing_1_doc_abc.pdf"
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name   string
+		url    string
+		edit   func(name, filename, expires, signature *string)
+		secret string
+		want   error
+	}{
+		{name: "valid", url: s.SignedURL(name, "")},
+		{name: "valid with download name", url: s.SignedURL(name, "財報 2024.pdf")},
+		{name: "expired", url: s.SignedURL(name, ""),
+			edit: func(n, f, e, sig *string) { *e = past; *sig = s.sign(*n, *f, mustParse(t, past)) },
+			want: ErrExpiredSignature},
+		{name: "expiry extended", url: s.SignedURL(name, ""),
+			edit: func(_, _, e, _ *string) { *e = strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) },
+			want: ErrInvalidSignature},
+		{name: "other file", url: s.SignedURL(name, ""),
+			edit: func(n, _, _, _ *string) { *n = "listing_2_doc_def.pdf" }, want: ErrInvalidSignature},
+		{name: "download name changed", url: s.SignedURL(name, "report.pdf"),
+			edit: func(_, f, _, _ *string) { *f = "report.html" }, want: ErrInvalidSignature},
+		{name: "download name added", url: s.SignedURL(name, ""),
+			edit: func(_, f, _, _ *string) { *f = "report.html" }, want: ErrInvalidSignature},
+		{name: "tampered signature", url: s.SignedURL(name, ""),
+			edit: func(_, _, _, sig *string) { *sig = strings.Repeat("0", len(*sig)) }, want: ErrInvalidSignature},
+		{name: "non-numeric expiry", url: s.SignedURL(name, ""),
+			edit: func(_, _, e, _ *string) { *e = "soon" }, want: ErrInvalidSignature},
+		{name: "path traversal", url: s.SignedURL(name, ""),
+			edit: func(n, _, _, _ *string) { *n = "../secret.pdf" }, want: ErrInvalidName},
+		{name: "signed with another secret", url: s.SignedURL(name, ""), secret: "other-secret", want: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, f, e, sig := signedParams(t, tt.url)
+			if tt.edit != nil {
+				tt.edit(&n, &f, &e, &sig)
+			}
+			verifier := s
+			if tt.secret != "" {
+				other := *s
+				other.secret = []byte(tt.secret)
+				verifier = &other
+			}
+			path, err := verifier.Verify(n, f, e, sig)
+			if err != tt.want {
+				t.Fatalf("Verify error %v, want %v", err, tt.want)
+			}
+			if err == nil && path != filepath.Join(s.PrivateDir, name) {
+				t.Errorf("path %q, want the file in the private root", path)
+			}
+		})
+	}
+}
+
+func mustParse(t *testing.T, s string) int64 {
+	t.Helper()
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSavePublicFileIsContentHashed(t *testing.T) {
+	s := newTestStorage(t, time.Minute)
+	src := filepath.Join(t.TempDir(), "photo.JPG")
+	if err := os.WriteFile(src, []byte("same bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, url1, err := s.SavePublicFile(src, "photo.JPG", "listing_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := s.SavePublicFile(src, "copy.jpg", "listing_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("same content stored as %q and %q", first, second)
+	}
+	if !strings.HasPrefix(first, "listing_1_") || !strings.HasSuffix(first, ".jpg") {
+		t.Errorf("stored name %q, want listing_1_<hash>.jpg", first)
+	}
+	if url1 != PublicURLPrefix+"/"+first {
+		t.Errorf("public URL %q", url1)
+	}
+}
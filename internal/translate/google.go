@@ -0,0 +1,77 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+)
+
+// googleTranslateURL is the Cloud Translation v2 REST endpoint
+const googleTranslateURL = "https://translation.googleapis.com/language/translate/v2"
+
+// GoogleTranslator uses the Cloud Translation API (Basic)
+type GoogleTranslator struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewGoogleTranslator creates a translator authenticated with an API key.
+func NewGoogleTranslator(apiKey string) *GoogleTranslator {
+	return &GoogleTranslator{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Translate implements Translator.
+func (g *GoogleTranslator) Translate(ctx context.Context, text, source, target, format string) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"q":      text,
+		"source": source,
+		"target": target,
+		"format": format,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleTranslateURL+"?key="+g.apiKey, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Cloud Translation: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cloud translation returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText string `json:"translatedText"`
+			} `json:"translations"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Cloud Translation response: %w", err)
+	}
+	if len(result.Data.Translations) == 0 {
+		return "", fmt.Errorf("cloud translation returned no result")
+	}
+
+	translated := result.Data.Translations[0].TranslatedText
+	// Plain text comes back with HTML entities escaped
+	if format == FormatText {
+		translated = html.UnescapeString(translated)
+	}
+	return translated, nil
+}
@@ -0,0 +1,52 @@
+// Package translate machine-translates listing text for buyers who don't read
+// Traditional Chinese. The translator is pluggable: by default nothing is
+// translated, production can use the Google Cloud Translation API.
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"trade_company/internal/config"
+)
+
+// Translator providers selectable with TRANSLATION_PROVIDER
+const (
+	ProviderNone   = "none"
+	ProviderGoogle = "google"
+)
+
+// Text formats a translator accepts. HTML keeps markup intact.
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+)
+
+// ErrDisabled is returned by the no-op translator
+var ErrDisabled = errors.New("translation is disabled")
+
+// Translator translates text between languages given as BCP-47 codes. An error
+// means the text should be retried later.
+type Translator interface {
+	Translate(ctx context.Context, text, source, target, format string) (string, error)
+}
+
+// Noop translates nothing
+type Noop struct{}
+
+// Translate implements Translator.
+func (Noop) Translate(context.Context, string, string, string, string) (string, error) {
+	return "", ErrDisabled
+}
+
+// NewTranslator returns the translator selected in config.
+func NewTranslator(cfg *config.Config) (Translator, error) {
+	switch cfg.TranslationProvider {
+	case ProviderNone:
+		return Noop{}, nil
+	case ProviderGoogle:
+		return NewGoogleTranslator(cfg.GoogleTranslateAPIKey), nil
+	}
+	return nil, fmt.Errorf("unknown translation provider %q", cfg.TranslationProvider)
+}
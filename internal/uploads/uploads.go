@@ -0,0 +1,328 @@
+// Package uploads implements resumable, chunked file uploads. Upload state lives
+// in Redis and expires after UploadTTL; chunk data is staged on disk until the
+// upload is completed, aborted or cleaned up as abandoned.
+package uploads
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"trade_company/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// UploadTTL is how long an upload may stay unfinished before it expires
+const UploadTTL = 24 * time.Hour
+
+// assembledName is the staged file a completed upload is assembled into
+const assembledName = "assembled"
+
+var (
+	ErrNotFound         = errors.New("upload not found")
+	ErrTooManyUploads   = errors.New("too many open uploads")
+	ErrTooLarge         = errors.New("upload is too large")
+	ErrInvalidChunk     = errors.New("chunk index out of range")
+	ErrChunkSize        = errors.New("chunk has the wrong size")
+	ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+	ErrIncomplete       = errors.New("upload is missing chunks")
+)
+
+// Upload is the state of one resumable upload
+type Upload struct {
+	ID          string    `json:"upload_id"`
+	UserID      uint      `json:"-"`
+	ListingID   uint      `json:"listing_id"`
+	Filename    string    `json:"filename"`
+	Size        int64     `json:"size"`
+	ChunkSize   int64     `json:"chunk_size"`
+	TotalChunks int       `json:"total_chunks"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// chunkLength is the exact size chunk index must have
+func (u *Upload) chunkLength(index int) int64 {
+	if index == u.TotalChunks-1 {
+		return u.Size - int64(index)*u.ChunkSize
+	}
+	return u.ChunkSize
+}
+
+// Manager tracks uploads in Redis and stages their chunks under Dir
+type Manager struct {
+	redisClient *redis.Client
+	Dir         string
+	ChunkSize   int64
+	MaxSize     int64
+	MaxOpen     int
+}
+
+// NewManager returns the upload manager, or nil when Redis is not configured.
+func NewManager(redisClient *redis.Client, cfg *config.Config) *Manager {
+	if redisClient == nil {
+		return nil
+	}
+	return &Manager{
+		redisClient: redisClient,
+		Dir:         cfg.UploadChunkDir,
+		ChunkSize:   int64(cfg.UploadChunkSizeMB) << 20,
+		MaxSize:     int64(cfg.MaxFileSizeMB) << 20,
+		MaxOpen:     cfg.MaxOpenUploadsPerUser,
+	}
+}
+
+func uploadKey(id string) string {
+	return fmt.Sprintf("upload:%s", id)
+}
+
+func chunksKey(id string) string {
+	return fmt.Sprintf("upload:%s:chunks", id)
+}
+
+func userUploadsKey(userID uint) string {
+	return fmt.Sprintf("upload:user:%d", userID)
+}
+
+// Initiate starts an upload of size bytes for the user's listing. A user may
+// have at most MaxOpen unfinished uploads; expired ones no longer count.
+func (m *Manager) Initiate(ctx context.Context, userID, listingID uint, filename string, size int64) (*Upload, error) {
+	if size <= 0 || size > m.MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	now := time.Now()
+	userKey := userUploadsKey(userID)
+	if err := m.redisClient.ZRemRangeByScore(ctx, userKey, "-inf", strconv.FormatInt(now.Unix(), 10)).Err(); err != nil {
+		return nil, err
+	}
+	open, err := m.redisClient.ZCard(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if open >= int64(m.MaxOpen) {
+		return nil, ErrTooManyUploads
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	u := &Upload{
+		ID:          id,
+		UserID:      userID,
+		ListingID:   listingID,
+		Filename:    filepath.Base(filename),
+		Size:        size,
+		ChunkSize:   m.ChunkSize,
+		TotalChunks: int((size + m.ChunkSize - 1) / m.ChunkSize),
+		ExpiresAt:   now.Add(UploadTTL),
+	}
+
+	pipe := m.redisClient.TxPipeline()
+	pipe.HSet(ctx, uploadKey(id), map[string]interface{}{
+		"user_id":      u.UserID,
+		"listing_id":   u.ListingID,
+		"filename":     u.Filename,
+		"size":         u.Size,
+		"chunk_size":   u.ChunkSize,
+		"total_chunks": u.TotalChunks,
+		"expires_at":   u.ExpiresAt.Unix(),
+	})
+	pipe.Expire(ctx, uploadKey(id), UploadTTL)
+	pipe.ZAdd(ctx, userKey, redis.Z{Score: float64(u.ExpiresAt.Unix()), Member: id})
+	pipe.Expire(ctx, userKey, UploadTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Get loads an upload owned by userID. Another user's upload is reported as not found.
+func (m *Manager) Get(ctx context.Context, id string, userID uint) (*Upload, error) {
+	fields, err := m.redisClient.HGetAll(ctx, uploadKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+
+	u := &Upload{ID: id, Filename: fields["filename"]}
+	owner, _ := strconv.ParseUint(fields["user_id"], 10, 64)
+	listingID, _ := strconv.ParseUint(fields["listing_id"], 10, 64)
+	u.UserID = uint(owner)
+	u.ListingID = uint(listingID)
+	u.Size, _ = strconv.ParseInt(fields["size"], 10, 64)
+	u.ChunkSize, _ = strconv.ParseInt(fields["chunk_size"], 10, 64)
+	u.TotalChunks, _ = strconv.Atoi(fields["total_chunks"])
+	expiresAt, _ := strconv.ParseInt(fields["expires_at"], 10, 64)
+	u.ExpiresAt = time.Unix(expiresAt, 0)
+
+	if u.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+// Received returns the indexes of the chunks stored so far, in order
+func (m *Manager) Received(ctx context.Context, u *Upload) ([]int, error) {
+	members, err := m.redisClient.SMembers(ctx, chunksKey(u.ID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	received := make([]int, 0, len(members))
+	for _, s := range members {
+		if i, err := strconv.Atoi(s); err == nil {
+			received = append(received, i)
+		}
+	}
+	sort.Ints(received)
+	return received, nil
+}
+
+// PutChunk stores chunk index of the upload. Chunks may arrive in any order and
+// may be sent again; checksum is the hex SHA-256 of the chunk's bytes.
+func (m *Manager) PutChunk(ctx context.Context, u *Upload, index int, checksum string, data io.Reader) error {
+	if index < 0 || index >= u.TotalChunks {
+		return ErrInvalidChunk
+	}
+	want := u.chunkLength(index)
+
+	dir := filepath.Join(m.Dir, u.ID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create chunk dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "chunk-*")
+	if err != nil {
+		return fmt.Errorf("failed to create chunk file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(data, want+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+	if n != want {
+		return ErrChunkSize
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != checksum {
+		return ErrChecksumMismatch
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, chunkName(index))); err != nil {
+		return fmt.Errorf("failed to store chunk: %w", err)
+	}
+
+	pipe := m.redisClient.TxPipeline()
+	pipe.SAdd(ctx, chunksKey(u.ID), index)
+	pipe.ExpireAt(ctx, chunksKey(u.ID), u.ExpiresAt)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Assemble joins the chunks of a complete upload into one file and returns its
+// path. The caller hands the file on and then calls Remove.
+func (m *Manager) Assemble(ctx context.Context, u *Upload) (string, error) {
+	count, err := m.redisClient.SCard(ctx, chunksKey(u.ID)).Result()
+	if err != nil {
+		return "", err
+	}
+	if count != int64(u.TotalChunks) {
+		return "", ErrIncomplete
+	}
+
+	dir := filepath.Join(m.Dir, u.ID)
+	path := filepath.Join(dir, assembledName)
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create assembled file: %w", err)
+	}
+	defer dst.Close()
+
+	for i := 0; i < u.TotalChunks; i++ {
+		src, err := os.Open(filepath.Join(dir, chunkName(i)))
+		if err != nil {
+			return "", fmt.Errorf("failed to open chunk %d: %w", i, err)
+		}
+		_, err = io.Copy(dst, src)
+		src.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to copy chunk %d: %w", i, err)
+		}
+	}
+	return path, nil
+}
+
+// Remove deletes an upload's state and staged chunks
+func (m *Manager) Remove(ctx context.Context, u *Upload) error {
+	pipe := m.redisClient.TxPipeline()
+	pipe.Del(ctx, uploadKey(u.ID), chunksKey(u.ID))
+	pipe.ZRem(ctx, userUploadsKey(u.UserID), u.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(m.Dir, u.ID))
+}
+
+// CleanupAbandoned deletes staged chunks of uploads whose state has expired and
+// returns how many were removed. Directories younger than UploadTTL are left
+// alone so an upload that was just initiated is never caught in between.
+func (m *Manager) CleanupAbandoned(ctx context.Context, now time.Time) (int, error) {
+	entries, err := os.ReadDir(m.Dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < UploadTTL {
+			continue
+		}
+		exists, err := m.redisClient.Exists(ctx, uploadKey(entry.Name())).Result()
+		if err != nil {
+			return removed, err
+		}
+		if exists > 0 {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(m.Dir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func chunkName(index int) string {
+	return fmt.Sprintf("%06d.part", index)
+}
+
+// newUploadID returns a random hex ID that is also safe as a directory name
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,180 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestManager stages chunks of 4 bytes in a temporary directory
+func newTestManager(t *testing.T) (*Manager, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return &Manager{
+		redisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		Dir:         t.TempDir(),
+		ChunkSize:   4,
+		MaxSize:     64,
+		MaxOpen:     2,
+	}, mr
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// chunk returns the bytes of chunk index of data
+func chunk(u *Upload, data []byte, index int) []byte {
+	start := int64(index) * u.ChunkSize
+	return data[start : start+u.chunkLength(index)]
+}
+
+func TestOutOfOrderChunks(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager(t)
+	data := []byte("0123456789")
+	u, err := m.Initiate(ctx, 1, 7, "../photo.jpg", int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.TotalChunks != 3 || u.Filename != "photo.jpg" {
+		t.Fatalf("upload %+v", u)
+	}
+
+	for _, i := range []int{2, 0, 1, 0} { // the first chunk is sent twice
+		if err := m.PutChunk(ctx, u, i, checksum(chunk(u, data, i)), bytes.NewReader(chunk(u, data, i))); err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+	}
+	received, err := m.Received(ctx, u)
+	if err != nil || len(received) != 3 {
+		t.Fatalf("received %v, %v", received, err)
+	}
+
+	path, err := m.Assemble(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Errorf("assembled %q, want %q", got, data)
+	}
+
+	if err := m.Remove(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(m.Dir, u.ID)); !os.IsNotExist(err) {
+		t.Errorf("staged chunks left behind: %v", err)
+	}
+	if _, err := m.Get(ctx, u.ID, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("removed upload: err %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestPutChunkRejects(t *testing.T) {
+	data := []byte("0123456789")
+	tests := []struct {
+		name     string
+		index    int
+		body     []byte
+		checksum string
+		want     error
+	}{
+		{name: "checksum mismatch", index: 0, body: []byte("0123"), checksum: checksum([]byte("0124")), want: ErrChecksumMismatch},
+		{name: "checksum of another chunk", index: 1, body: []byte("4567"), checksum: checksum([]byte("0123")), want: ErrChecksumMismatch},
+		{name: "short chunk", index: 0, body: []byte("012"), checksum: checksum([]byte("012")), want: ErrChunkSize},
+		{name: "long last chunk", index: 2, body: []byte("890"), checksum: checksum([]byte("890")), want: ErrChunkSize},
+		{name: "index past the end", index: 3, body: []byte("0123"), checksum: checksum([]byte("0123")), want: ErrInvalidChunk},
+		{name: "negative index", index: -1, body: []byte("0123"), checksum: checksum([]byte("0123")), want: ErrInvalidChunk},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m, _ := newTestManager(t)
+			u, err := m.Initiate(ctx, 1, 7, "photo.jpg", int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := m.PutChunk(ctx, u, tt.index, tt.checksum, bytes.NewReader(tt.body)); !errors.Is(err, tt.want) {
+				t.Fatalf("err %v, want %v", err, tt.want)
+			}
+			if received, _ := m.Received(ctx, u); len(received) != 0 {
+				t.Errorf("rejected chunk recorded: %v", received)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(m.Dir, u.ID)); len(entries) != 0 {
+				t.Errorf("rejected chunk staged: %v", entries)
+			}
+			if _, err := m.Assemble(ctx, u); !errors.Is(err, ErrIncomplete) {
+				t.Errorf("assemble: err %v, want %v", err, ErrIncomplete)
+			}
+		})
+	}
+}
+
+func TestUploadExpiry(t *testing.T) {
+	ctx := context.Background()
+	m, mr := newTestManager(t)
+	data := []byte("0123456789")
+
+	u, err := m.Initiate(ctx, 1, 7, "photo.jpg", int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.PutChunk(ctx, u, 0, checksum(chunk(u, data, 0)), bytes.NewReader(chunk(u, data, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Initiate(ctx, 1, 7, "other.jpg", 8); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Initiate(ctx, 1, 7, "third.jpg", 8); !errors.Is(err, ErrTooManyUploads) {
+		t.Fatalf("third open upload: err %v, want %v", err, ErrTooManyUploads)
+	}
+
+	mr.FastForward(UploadTTL)
+	if _, err := m.Get(ctx, u.ID, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired upload: err %v, want %v", err, ErrNotFound)
+	}
+	// Expired uploads no longer count against the limit
+	if _, err := m.Initiate(ctx, 1, 7, "third.jpg", 8); err != nil {
+		t.Errorf("upload after expiry: %v", err)
+	}
+
+	// Its chunks stay on disk until the cleanup job finds them old enough
+	if removed, err := m.CleanupAbandoned(ctx, time.Now()); err != nil || removed != 0 {
+		t.Errorf("cleanup of a fresh directory removed %d: %v", removed, err)
+	}
+	if removed, err := m.CleanupAbandoned(ctx, time.Now().Add(UploadTTL+time.Minute)); err != nil || removed != 1 {
+		t.Errorf("cleanup removed %d, want 1: %v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(m.Dir, u.ID)); !os.IsNotExist(err) {
+		t.Errorf("abandoned chunks left behind: %v", err)
+	}
+}
+
+func TestCleanupKeepsLiveUploads(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager(t)
+	data := []byte("0123")
+	u, err := m.Initiate(ctx, 1, 7, "photo.jpg", int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.PutChunk(ctx, u, 0, checksum(data), bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if removed, err := m.CleanupAbandoned(ctx, time.Now().Add(UploadTTL+time.Minute)); err != nil || removed != 0 {
+		t.Errorf("cleanup removed %d live uploads: %v", removed, err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package wordfilter screens user-written text (listings, messages, leads)
+// against a blocklist of terms that mark scams and other prohibited content.
+// Matching ignores case, full-width forms and separators placed inside a term,
+// so "人 頭 帳 戶" is caught like "人頭帳戶".
+package wordfilter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Modes selectable with SENSITIVE_WORDS_MODE
+const (
+	ModeOff    = "off"
+	ModeReject = "reject"
+	ModeMask   = "mask"
+)
+
+// maskRune replaces each character of a masked term
+const maskRune = '*'
+
+// defaultTerms are common in Taiwan's scam and illegal-service ads and have
+// no legitimate place in a business listing; SENSITIVE_WORDS_FILE adds more
+var defaultTerms = []string{
+	"人頭帳戶", "收購帳戶", "租借帳戶", "出售個資", "代辦貸款", "地下錢莊", "洗錢",
+	"老鼠會", "穩賺不賠", "保證獲利", "線上博弈", "網路博弈", "代收包裹",
+	"假投資", "虛擬貨幣代操",
+}
+
+// Filter finds blocked terms in text. A nil *Filter is valid and blocks
+// nothing, so callers don't need to check whether filtering is off.
+type Filter struct {
+	mode  string
+	terms [][]rune // Folded, longest first so masking covers the longest match
+}
+
+// New builds the filter for mode from the built-in terms plus, when extraFile
+// is set, one term per line of that file (blank lines and # comments
+// ignored). It returns nil for ModeOff.
+func New(mode, extraFile string) (*Filter, error) {
+	switch mode {
+	case ModeOff:
+		return nil, nil
+	case ModeReject, ModeMask:
+	default:
+		return nil, fmt.Errorf("unknown sensitive words mode %q", mode)
+	}
+
+	terms := append([]string{}, defaultTerms...)
+	if extraFile != "" {
+		f, err := os.Open(extraFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sensitive words file: %w", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			terms = append(terms, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read sensitive words file: %w", err)
+		}
+	}
+
+	filter := &Filter{mode: mode}
+	seen := make(map[string]bool)
+	for _, t := range terms {
+		folded, _ := fold(t)
+		if len(folded) == 0 || seen[string(folded)] {
+			continue
+		}
+		seen[string(folded)] = true
+		filter.terms = append(filter.terms, folded)
+	}
+	sort.SliceStable(filter.terms, func(i, j int) bool { return len(filter.terms[i]) > len(filter.terms[j]) })
+	return filter, nil
+}
+
+// Mode is the filter's mode, ModeOff for a nil filter
+func (f *Filter) Mode() string {
+	if f == nil {
+		return ModeOff
+	}
+	return f.mode
+}
+
+// Rejects reports whether text with a blocked term must be refused rather
+// than masked
+func (f *Filter) Rejects() bool {
+	return f != nil && f.mode == ModeReject
+}
+
+// Screen returns the blocked terms found in text, as written there, and text
+// with them masked in ModeMask (text unchanged otherwise)
+func (f *Filter) Screen(text string) (string, []string) {
+	if f == nil || text == "" {
+		return text, nil
+	}
+
+	original := []rune(text)
+	folded, positions := fold(text)
+	masked := make([]bool, len(original))
+	var found []string
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(folded); i++ {
+		for _, term := range f.terms {
+			if !hasPrefix(folded[i:], term) {
+				continue
+			}
+			first, last := positions[i], positions[i+len(term)-1]
+			for j := first; j <= last; j++ {
+				masked[j] = true
+			}
+			if match := string(original[first : last+1]); !seen[match] {
+				seen[match] = true
+				found = append(found, match)
+			}
+			i += len(term) - 1
+			break
+		}
+	}
+
+	if len(found) == 0 || f.mode != ModeMask {
+		return text, found
+	}
+	for i := range original {
+		if masked[i] && !unicode.IsSpace(original[i]) {
+			original[i] = maskRune
+		}
+	}
+	return string(original), found
+}
+
+// fold reduces text to the runes compared against terms: NFKC-normalized,
+// lower-cased, with spaces and punctuation dropped. positions[i] is the index
+// in []rune(text) of the rune folded[i] came from.
+func fold(text string) (folded []rune, positions []int) {
+	for i, r := range []rune(text) {
+		for _, fr := range strings.ToLower(norm.NFKC.String(string(r))) {
+			if unicode.IsSpace(fr) || unicode.IsPunct(fr) || unicode.IsSymbol(fr) {
+				continue
+			}
+			folded = append(folded, fr)
+			positions = append(positions, i)
+		}
+	}
+	return folded, positions
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
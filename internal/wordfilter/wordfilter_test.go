@@ -0,0 +1,90 @@
+package wordfilter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if f, err := New(ModeOff, ""); f != nil || err != nil {
+		t.Errorf("off: %v, %v; want a nil filter", f, err)
+	}
+	if _, err := New("block", ""); err == nil {
+		t.Error("unknown mode accepted")
+	}
+	if _, err := New(ModeReject, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing words file accepted")
+	}
+
+	file := filepath.Join(t.TempDir(), "words.txt")
+	os.WriteFile(file, []byte("# Local additions\n\n  Pump and Dump  \n洗錢\n"), 0o644)
+	f, err := New(ModeReject, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file's duplicate of a built-in term is dropped
+	if len(f.terms) != len(defaultTerms)+1 {
+		t.Errorf("%d terms, want the %d built-in plus 1", len(f.terms), len(defaultTerms))
+	}
+	for i := 1; i < len(f.terms); i++ {
+		if len(f.terms[i]) > len(f.terms[i-1]) {
+			t.Fatalf("terms not longest first: %q before %q", string(f.terms[i-1]), string(f.terms[i]))
+		}
+	}
+	if _, found := f.Screen("Classic PUMP-AND-DUMP scheme"); !reflect.DeepEqual(found, []string{"PUMP-AND-DUMP"}) {
+		t.Errorf("file term found %q", found)
+	}
+}
+
+func TestScreen(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		found  []string
+		masked string // Text stored in mask mode
+	}{
+		{name: "clean", text: "老字號咖啡廳，穩定客源", masked: "老字號咖啡廳，穩定客源"},
+		{name: "Chinese term", text: "本店穩賺不賠，歡迎洽詢", found: []string{"穩賺不賠"}, masked: "本店****，歡迎洽詢"},
+		{name: "separators inside a term", text: "收 人 頭 帳 戶", found: []string{"人 頭 帳 戶"}, masked: "收 * * * *"},
+		{name: "punctuation inside a term", text: "地下-錢莊!", found: []string{"地下-錢莊"}, masked: "*****!"},
+		{name: "each term once", text: "洗錢、洗錢與老鼠會", found: []string{"洗錢", "老鼠會"}, masked: "**、**與***"},
+		{name: "longest term wins", text: "虛擬貨幣代操", found: []string{"虛擬貨幣代操"}, masked: "******"},
+	}
+
+	reject, _ := New(ModeReject, "")
+	mask, _ := New(ModeMask, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, found := reject.Screen(tt.text)
+			if text != tt.text || !reflect.DeepEqual(found, tt.found) {
+				t.Errorf("reject: %q, %q; want text unchanged and %q", text, found, tt.found)
+			}
+			text, found = mask.Screen(tt.text)
+			if text != tt.masked || !reflect.DeepEqual(found, tt.found) {
+				t.Errorf("mask: %q, %q; want %q and %q", text, found, tt.masked, tt.found)
+			}
+		})
+	}
+}
+
+func TestScreenFullWidthAndCase(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "words.txt")
+	os.WriteFile(file, []byte("usdt\n"), 0o644)
+	f, _ := New(ModeMask, file)
+	text, found := f.Screen("收購ＵＳＤＴ")
+	if text != "收購****" || !reflect.DeepEqual(found, []string{"ＵＳＤＴ"}) {
+		t.Errorf("Screen = %q, %q", text, found)
+	}
+}
+
+func TestNilFilter(t *testing.T) {
+	var f *Filter
+	if text, found := f.Screen("穩賺不賠"); text != "穩賺不賠" || found != nil {
+		t.Errorf("nil filter: %q, %q", text, found)
+	}
+	if f.Mode() != ModeOff || f.Rejects() {
+		t.Errorf("nil filter mode %q, rejects %v", f.Mode(), f.Rejects())
+	}
+}
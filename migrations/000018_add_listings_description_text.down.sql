@@ -0,0 +1,3 @@
+-- Remove plain-text description column from listings table
+ALTER TABLE listings
+DROP COLUMN description_text;
@@ -0,0 +1,6 @@
+-- Plain-text copy of the sanitized HTML description, used for search, previews and feeds
+ALTER TABLE listings
+ADD COLUMN description_text TEXT NULL AFTER description;
+
+-- Legacy descriptions are plain text already
+UPDATE listings SET description_text = description WHERE description_text IS NULL;
@@ -0,0 +1,4 @@
+-- Remove delete_after column from listings table
+ALTER TABLE listings
+DROP INDEX idx_listings_delete_after,
+DROP COLUMN delete_after;
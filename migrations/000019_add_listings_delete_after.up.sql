@@ -0,0 +1,4 @@
+-- Listings marked pending_delete are finalized by the cleanup job once delete_after passes
+ALTER TABLE listings
+ADD COLUMN delete_after TIMESTAMP NULL AFTER status,
+ADD INDEX idx_listings_delete_after (delete_after);
@@ -0,0 +1,7 @@
+-- Drop lead inquiry templates
+ALTER TABLE leads
+    DROP FOREIGN KEY fk_leads_template,
+    DROP INDEX idx_leads_receiver_template,
+    DROP COLUMN template_id;
+
+DROP TABLE IF EXISTS lead_templates;
@@ -0,0 +1,29 @@
+-- Curated questions buyers can start an inquiry from. A template without an
+-- industry is offered on every listing. Leads record the template they were
+-- seeded from so sellers can see which questions buyers ask most.
+CREATE TABLE lead_templates (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    template_key VARCHAR(50) NOT NULL,
+    industry VARCHAR(100) NOT NULL DEFAULT '',
+    questions JSON NOT NULL,
+    sort_order INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY idx_lead_templates_key (template_key),
+    INDEX idx_lead_templates_industry (active, industry)
+);
+
+INSERT INTO lead_templates (template_key, industry, questions, sort_order) VALUES
+    ('reason_for_sale', '', JSON_OBJECT('zh-TW', '請問出售的原因是什麼？', 'en', 'Why are you selling the business?'), 10),
+    ('revenue_trend', '', JSON_OBJECT('zh-TW', '過去三年的營收與獲利狀況如何？', 'en', 'How have revenue and profit developed over the last three years?'), 20),
+    ('owner_involvement', '', JSON_OBJECT('zh-TW', '目前老闆每週投入多少時間經營？', 'en', 'How many hours a week does the owner put into the business?'), 30),
+    ('staff_retention', '', JSON_OBJECT('zh-TW', '現有員工是否願意留任？', 'en', 'Will the current staff stay on after the sale?'), 40),
+    ('lease_terms', '', JSON_OBJECT('zh-TW', '店面租約還剩多久？可以轉讓嗎？', 'en', 'How long is left on the lease, and can it be transferred?'), 50),
+    ('transition_support', '', JSON_OBJECT('zh-TW', '交接期間可以提供多久的協助？', 'en', 'How long will you support the handover?'), 60);
+
+ALTER TABLE leads
+    ADD COLUMN template_id BIGINT NULL AFTER subject,
+    ADD INDEX idx_leads_receiver_template (receiver_id, template_id),
+    ADD CONSTRAINT fk_leads_template FOREIGN KEY (template_id) REFERENCES lead_templates(id) ON DELETE SET NULL;
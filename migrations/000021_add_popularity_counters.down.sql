@@ -0,0 +1,8 @@
+-- Remove popularity counters and daily view table
+DROP TABLE IF EXISTS listing_view_daily;
+
+ALTER TABLE favorites
+DROP COLUMN counted;
+
+ALTER TABLE listings
+DROP COLUMN favorite_count;
@@ -0,0 +1,29 @@
+-- Public popularity counters and the daily view table they are rebuilt from
+ALTER TABLE listings
+ADD COLUMN favorite_count INT NOT NULL DEFAULT 0 AFTER view_count;
+
+-- Favorites from brand-new accounts are stored but left out of public counts
+ALTER TABLE favorites
+ADD COLUMN counted BOOLEAN NOT NULL DEFAULT FALSE AFTER listing_id;
+
+CREATE TABLE listing_view_daily (
+    listing_id BIGINT NOT NULL,
+    view_date DATE NOT NULL,
+    views INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (listing_id, view_date),
+    FOREIGN KEY (listing_id) REFERENCES listings(id) ON DELETE CASCADE
+);
+
+-- Backfill: existing favorites count if the account was at least a day old when it favorited
+UPDATE favorites f
+JOIN users u ON u.id = f.user_id
+SET f.counted = TRUE
+WHERE f.created_at >= u.created_at + INTERVAL 24 HOUR;
+
+UPDATE listings l
+SET l.favorite_count = (SELECT COUNT(*) FROM favorites f WHERE f.listing_id = l.id AND f.counted = TRUE);
+
+-- Keep historical view counts so a recount does not reset them. They are
+-- dated to the listing's creation so they never show up as recent views.
+INSERT INTO listing_view_daily (listing_id, view_date, views)
+SELECT id, DATE(created_at), view_count FROM listings WHERE view_count > 0;
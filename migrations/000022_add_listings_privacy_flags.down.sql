@@ -0,0 +1,5 @@
+-- Remove listing privacy flags
+ALTER TABLE listings
+DROP COLUMN hide_last_active,
+DROP COLUMN hide_favorite_count,
+DROP COLUMN hide_view_count;
@@ -0,0 +1,5 @@
+-- Per-listing privacy flags for public statistics
+ALTER TABLE listings
+ADD COLUMN hide_view_count BOOLEAN NOT NULL DEFAULT FALSE AFTER favorite_count,
+ADD COLUMN hide_favorite_count BOOLEAN NOT NULL DEFAULT FALSE AFTER hide_view_count,
+ADD COLUMN hide_last_active BOOLEAN NOT NULL DEFAULT FALSE AFTER hide_favorite_count;
@@ -0,0 +1,2 @@
+-- Drop listing view hourly table
+DROP TABLE IF EXISTS listing_view_hourly;
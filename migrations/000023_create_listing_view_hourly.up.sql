@@ -0,0 +1,9 @@
+-- Hour-of-day view histogram per listing and day (hour is 0-23, server local time)
+CREATE TABLE listing_view_hourly (
+    listing_id BIGINT NOT NULL,
+    view_date DATE NOT NULL,
+    hour TINYINT UNSIGNED NOT NULL,
+    views INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (listing_id, view_date, hour),
+    FOREIGN KEY (listing_id) REFERENCES listings(id) ON DELETE CASCADE
+);
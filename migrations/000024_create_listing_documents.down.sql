@@ -0,0 +1,2 @@
+-- Drop listing documents table
+DROP TABLE IF EXISTS listing_documents;
@@ -0,0 +1,14 @@
+-- Non-image attachments (PDF financial summaries, floor plans) on listings
+CREATE TABLE listing_documents (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    listing_id BIGINT NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    visibility VARCHAR(20) NOT NULL DEFAULT 'public',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_listing_documents_listing_id (listing_id),
+    FOREIGN KEY (listing_id) REFERENCES listings(id) ON DELETE CASCADE
+);
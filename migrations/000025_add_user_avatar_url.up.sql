@@ -0,0 +1,3 @@
+-- Profile picture shown in the header and on seller pages
+ALTER TABLE users
+ADD COLUMN avatar_url VARCHAR(500) NULL AFTER phone;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS comparison_list_items;
+DROP TABLE IF EXISTS comparison_lists;
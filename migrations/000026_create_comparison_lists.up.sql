@@ -0,0 +1,20 @@
+-- Named, user-owned lists of listings to compare side by side
+CREATE TABLE comparison_lists (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_comparison_lists_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE comparison_list_items (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    comparison_list_id BIGINT NOT NULL,
+    listing_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_comparison_listing (comparison_list_id, listing_id),
+    FOREIGN KEY (comparison_list_id) REFERENCES comparison_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (listing_id) REFERENCES listings(id) ON DELETE CASCADE
+);
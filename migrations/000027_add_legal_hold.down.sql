@@ -0,0 +1,8 @@
+DROP INDEX idx_messages_created_at ON messages;
+DROP INDEX idx_leads_created_at ON leads;
+
+ALTER TABLE messages
+DROP COLUMN legal_hold;
+
+ALTER TABLE leads
+DROP COLUMN legal_hold;
@@ -0,0 +1,9 @@
+-- Records under legal hold are skipped by the data-retention purge
+ALTER TABLE leads
+ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE messages
+ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_leads_created_at ON leads (created_at);
+CREATE INDEX idx_messages_created_at ON messages (created_at);
@@ -0,0 +1,12 @@
+-- Operator-managed site banners shown during a scheduled window
+CREATE TABLE announcements (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    messages JSON NOT NULL,
+    level VARCHAR(20) NOT NULL DEFAULT 'info',
+    audience VARCHAR(20) NOT NULL DEFAULT 'all',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_announcements_window (starts_at, ends_at)
+);
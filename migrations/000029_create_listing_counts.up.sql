@@ -0,0 +1,19 @@
+-- Active listing counts per category and industry, maintained by the Listing
+-- model hooks and reconciled nightly against the listings table
+CREATE TABLE listing_counts (
+    dimension VARCHAR(20) NOT NULL,
+    value VARCHAR(100) NOT NULL,
+    total INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (dimension, value)
+);
+
+INSERT INTO listing_counts (dimension, value, total)
+SELECT 'category', category, COUNT(*) FROM listings
+WHERE status = '活躍' AND category IS NOT NULL AND category <> ''
+GROUP BY category;
+
+INSERT INTO listing_counts (dimension, value, total)
+SELECT 'industry', industry, COUNT(*) FROM listings
+WHERE status = '活躍' AND industry IS NOT NULL AND industry <> ''
+GROUP BY industry;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+DROP INDEX idx_users_unverified,
+DROP COLUMN unverified_reminder_sent_at;
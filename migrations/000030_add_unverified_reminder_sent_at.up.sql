@@ -0,0 +1,4 @@
+-- When the "verify or lose your account" reminder went out to an unverified user
+ALTER TABLE users
+ADD COLUMN unverified_reminder_sent_at TIMESTAMP NULL AFTER email_verification_token,
+ADD INDEX idx_users_unverified (is_active, email_verified_at, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+DROP COLUMN email_undeliverable;
+
+DROP TABLE IF EXISTS email_events;
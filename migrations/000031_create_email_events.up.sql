@@ -0,0 +1,21 @@
+-- Delivery events reported by the SendGrid event webhook, one row per event
+CREATE TABLE email_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NULL,
+    email VARCHAR(255) NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    bounce_type VARCHAR(32) NOT NULL DEFAULT '',
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    provider_event_id VARCHAR(100) NOT NULL,
+    provider_message_id VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_email_events_provider_event (provider_event_id),
+    INDEX idx_email_events_user (user_id, occurred_at),
+    INDEX idx_email_events_email (email),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Set after a hard bounce or spam report; suppresses all non-critical email
+ALTER TABLE users
+ADD COLUMN email_undeliverable BOOLEAN NOT NULL DEFAULT FALSE AFTER email_verification_token;
@@ -0,0 +1,5 @@
+ALTER TABLE images
+DROP INDEX idx_images_moderation_status,
+DROP COLUMN moderated_at,
+DROP COLUMN moderation_reason,
+DROP COLUMN moderation_status;
@@ -0,0 +1,8 @@
+-- New images wait for the moderation job; images uploaded before moderation existed are approved
+ALTER TABLE images
+ADD COLUMN moderation_status VARCHAR(20) NOT NULL DEFAULT 'pending' AFTER is_primary,
+ADD COLUMN moderation_reason VARCHAR(255) NOT NULL DEFAULT '' AFTER moderation_status,
+ADD COLUMN moderated_at TIMESTAMP NULL AFTER moderation_reason,
+ADD INDEX idx_images_moderation_status (moderation_status);
+
+UPDATE images SET moderation_status = 'approved', moderated_at = CURRENT_TIMESTAMP;
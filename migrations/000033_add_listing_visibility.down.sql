@@ -0,0 +1,3 @@
+ALTER TABLE listings
+DROP INDEX idx_listings_visibility,
+DROP COLUMN visibility;
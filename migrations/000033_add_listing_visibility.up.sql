@@ -0,0 +1,4 @@
+-- Unlisted listings are reachable by link only; private ones by their owner only
+ALTER TABLE listings
+ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT 'public' AFTER status,
+ADD INDEX idx_listings_visibility (visibility);
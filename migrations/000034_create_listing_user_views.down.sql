@@ -0,0 +1,2 @@
+-- Drop listing user views table
+DROP TABLE IF EXISTS listing_user_views;
@@ -0,0 +1,10 @@
+-- Latest view of each listing per signed-in user, used for recommendations
+CREATE TABLE listing_user_views (
+    user_id BIGINT NOT NULL,
+    listing_id BIGINT NOT NULL,
+    viewed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, listing_id),
+    INDEX idx_listing_user_views_viewed_at (viewed_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (listing_id) REFERENCES listings(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- Drop listing confirmations table
+DROP TABLE IF EXISTS listing_confirmations;
@@ -0,0 +1,20 @@
+-- "Is this still available?" checks sent to sellers returning after a long absence
+CREATE TABLE listing_confirmations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    listing_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    token_hash CHAR(64) NULL,
+    notified_at TIMESTAMP NULL,
+    deadline TIMESTAMP NULL,
+    resolution VARCHAR(20) NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_listing_confirmations_token_hash (token_hash),
+    INDEX idx_listing_confirmations_listing_id (listing_id),
+    INDEX idx_listing_confirmations_user_id (user_id),
+    INDEX idx_listing_confirmations_notified_at (notified_at),
+    INDEX idx_listing_confirmations_deadline (deadline),
+    INDEX idx_listing_confirmations_resolution (resolution),
+    FOREIGN KEY (listing_id) REFERENCES listings(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Drop auction events table
+DROP INDEX idx_transactions_payment_reference ON transactions;
+DROP TABLE IF EXISTS auction_events;
@@ -0,0 +1,15 @@
+-- Raw webhook events from the auction service, kept for idempotency and replay
+CREATE TABLE auction_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR(100) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    processed_at TIMESTAMP NULL,
+    error VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_auction_events_event_id (event_id)
+);
+
+-- One transaction per won auction
+CREATE INDEX idx_transactions_payment_reference ON transactions (payment_reference);
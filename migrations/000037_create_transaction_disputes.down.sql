@@ -0,0 +1,2 @@
+-- Drop transaction_disputes table
+DROP TABLE IF EXISTS transaction_disputes;
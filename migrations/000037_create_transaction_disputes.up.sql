@@ -0,0 +1,19 @@
+-- Disputes raised by a buyer or seller on a transaction and settled by an admin
+CREATE TABLE transaction_disputes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    opened_by_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    resolution TEXT,
+    resolved_by_id BIGINT NULL,
+    resolved_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_transaction_disputes_transaction_status (transaction_id, status),
+    INDEX idx_transaction_disputes_opened_by_id (opened_by_id),
+    INDEX idx_transaction_disputes_status (status),
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (opened_by_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (resolved_by_id) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,8 @@
+-- Drop translation_usage table and listing translation columns
+DROP TABLE IF EXISTS translation_usage;
+
+ALTER TABLE listings
+    DROP INDEX idx_listings_translation_requested_at,
+    DROP COLUMN translation_requested_at,
+    DROP COLUMN description_en,
+    DROP COLUMN title_en;
@@ -0,0 +1,12 @@
+-- English machine translations of listing titles and descriptions
+ALTER TABLE listings
+    ADD COLUMN title_en VARCHAR(255) NULL,
+    ADD COLUMN description_en TEXT NULL,
+    ADD COLUMN translation_requested_at TIMESTAMP NULL,
+    ADD INDEX idx_listings_translation_requested_at (translation_requested_at);
+
+-- Characters sent to the translation provider per calendar month
+CREATE TABLE translation_usage (
+    month CHAR(7) PRIMARY KEY,
+    characters BIGINT NOT NULL DEFAULT 0
+);
@@ -0,0 +1,4 @@
+-- Remove listing expiry
+ALTER TABLE listings
+    DROP INDEX idx_listings_expires_at,
+    DROP COLUMN expires_at;
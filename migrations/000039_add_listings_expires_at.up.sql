@@ -0,0 +1,5 @@
+-- Listings expire after a configurable period unless the owner renews them;
+-- existing listings have no expiry
+ALTER TABLE listings
+    ADD COLUMN expires_at TIMESTAMP NULL,
+    ADD INDEX idx_listings_expires_at (expires_at);
@@ -0,0 +1,2 @@
+-- Drop daily_stats table
+DROP TABLE IF EXISTS daily_stats;
@@ -0,0 +1,11 @@
+-- Per-day admin stats snapshots, one row per Taipei calendar day
+CREATE TABLE daily_stats (
+    date DATE PRIMARY KEY,
+    new_users BIGINT NOT NULL DEFAULT 0,
+    new_listings BIGINT NOT NULL DEFAULT 0,
+    leads BIGINT NOT NULL DEFAULT 0,
+    transactions_completed BIGINT NOT NULL DEFAULT 0,
+    transaction_volume BIGINT NOT NULL DEFAULT 0,
+    active_users BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NULL
+);
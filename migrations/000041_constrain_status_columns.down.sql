@@ -0,0 +1,7 @@
+-- Relax listings.status and users.role back to their earlier definitions
+ALTER TABLE listings
+    MODIFY COLUMN status VARCHAR(50) DEFAULT '活躍';
+
+UPDATE users SET role = 'user' WHERE role = 'seller';
+ALTER TABLE users
+    MODIFY COLUMN role ENUM('user', 'admin') DEFAULT 'user';
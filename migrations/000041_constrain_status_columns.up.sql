@@ -0,0 +1,19 @@
+-- Constrain the status-like columns to the values the Go enums allow.
+-- transactions.status has been an ENUM of exactly those values since it was
+-- created. Run `make check-enums` first: the server logs the same report before
+-- migrating, and any value the cleanup below doesn't handle makes the ALTERs
+-- fail until it is fixed by hand.
+
+-- Missing roles always behaved as regular users
+UPDATE users SET role = 'user' WHERE role IS NULL OR role = '';
+
+-- Stray whitespace from hand edits; listings without a status were never shown
+UPDATE listings SET status = TRIM(status) WHERE CHAR_LENGTH(status) <> CHAR_LENGTH(TRIM(status));
+UPDATE listings SET status = '不活躍' WHERE status IS NULL OR status = '';
+
+-- seller was always a valid role in the app but couldn't be stored
+ALTER TABLE users
+    MODIFY COLUMN role ENUM('user', 'seller', 'admin') NOT NULL DEFAULT 'user';
+
+ALTER TABLE listings
+    MODIFY COLUMN status ENUM('活躍', '不活躍', '已售出', 'pending_delete', 'deleted') NOT NULL DEFAULT '活躍';
@@ -0,0 +1,4 @@
+-- Drop lead inquiry types
+ALTER TABLE leads
+    DROP INDEX idx_leads_receiver_inquiry_type,
+    DROP COLUMN inquiry_type;
@@ -0,0 +1,5 @@
+-- Structured inquiry types on the contact form; existing leads were free text
+ALTER TABLE leads
+    ADD COLUMN inquiry_type ENUM('general', 'price_negotiation', 'request_financials', 'schedule_viewing', 'financing_question')
+        NOT NULL DEFAULT 'general' AFTER subject,
+    ADD INDEX idx_leads_receiver_inquiry_type (receiver_id, inquiry_type);
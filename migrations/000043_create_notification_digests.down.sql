@@ -0,0 +1,2 @@
+-- Drop notification_digests table
+DROP TABLE IF EXISTS notification_digests;
@@ -0,0 +1,10 @@
+-- The last unread leads/messages digest sent to each user, so the next one
+-- only covers newer items
+CREATE TABLE notification_digests (
+    user_id BIGINT PRIMARY KEY,
+    last_lead_id BIGINT NOT NULL DEFAULT 0,
+    last_message_id BIGINT NOT NULL DEFAULT 0,
+    sent_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Drop the listing keyword search index
+ALTER TABLE listings
+    DROP INDEX ft_listings_search;
@@ -0,0 +1,4 @@
+-- Keyword search over the listing text (?q= on GET /listings). The ngram parser
+-- splits Chinese text, which has no spaces between words, into bigrams.
+ALTER TABLE listings
+    ADD FULLTEXT INDEX ft_listings_search (title, description_text, brand_story, equipment) WITH PARSER ngram;
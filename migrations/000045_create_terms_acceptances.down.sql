@@ -0,0 +1,2 @@
+-- Drop the terms of service acceptance records
+DROP TABLE IF EXISTS terms_acceptances;
@@ -0,0 +1,11 @@
+-- Audit trail of terms of service acceptance, one row per user and version
+CREATE TABLE terms_acceptances (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    accepted_at TIMESTAMP NOT NULL,
+    ip VARCHAR(45),
+
+    UNIQUE KEY idx_terms_acceptances_user_version (user_id, version),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
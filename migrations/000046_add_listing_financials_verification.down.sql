@@ -0,0 +1,8 @@
+-- Drop listing financials verification
+ALTER TABLE listings
+    DROP INDEX idx_listings_financials_submitted_at,
+    DROP INDEX idx_listings_financials_verified,
+    DROP COLUMN financials_submitted_at,
+    DROP COLUMN financials_verified_by,
+    DROP COLUMN financials_verified_at,
+    DROP COLUMN financials_verified;
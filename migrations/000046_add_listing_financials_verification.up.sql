@@ -0,0 +1,9 @@
+-- Admin-verified revenue and margin figures, checked against financial
+-- statements uploaded as review documents
+ALTER TABLE listings
+    ADD COLUMN financials_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN financials_verified_at TIMESTAMP NULL,
+    ADD COLUMN financials_verified_by BIGINT NULL,
+    ADD COLUMN financials_submitted_at TIMESTAMP NULL,
+    ADD INDEX idx_listings_financials_verified (financials_verified),
+    ADD INDEX idx_listings_financials_submitted_at (financials_submitted_at);
@@ -0,0 +1,3 @@
+-- Drop the last accepted TOTP step
+ALTER TABLE users
+    DROP COLUMN two_factor_last_step;
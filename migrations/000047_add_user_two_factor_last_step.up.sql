@@ -0,0 +1,4 @@
+-- Time step of the last TOTP code accepted for the user, so a code can't be
+-- used twice within its validity window
+ALTER TABLE users
+    ADD COLUMN two_factor_last_step BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Drop the paid status; paid transactions go back to pending
+UPDATE transactions SET status = 'pending' WHERE status = 'paid';
+ALTER TABLE transactions
+    MODIFY COLUMN status ENUM('pending', 'completed', 'cancelled', 'refunded') DEFAULT 'pending';
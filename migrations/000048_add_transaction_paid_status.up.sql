@@ -0,0 +1,3 @@
+-- Transactions are paid before the seller completes them
+ALTER TABLE transactions
+    MODIFY COLUMN status ENUM('pending', 'paid', 'completed', 'cancelled', 'refunded') DEFAULT 'pending';
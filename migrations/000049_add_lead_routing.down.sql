@@ -0,0 +1,7 @@
+-- Drop lead routing
+ALTER TABLE leads
+    DROP INDEX idx_leads_assigned_to,
+    DROP COLUMN assigned_to;
+
+DROP TABLE IF EXISTS lead_routing_rules;
+DROP TABLE IF EXISTS organization_members;
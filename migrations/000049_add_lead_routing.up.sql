@@ -0,0 +1,31 @@
+-- Lead routing: sellers (organizations) add members who work their leads,
+-- extra notification addresses and round-robin assignment. Every lead records
+-- who it is assigned to; existing leads belong to their receiver.
+CREATE TABLE organization_members (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    organization_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role ENUM('admin', 'agent') NOT NULL DEFAULT 'agent',
+    created_at TIMESTAMP NULL,
+
+    UNIQUE KEY idx_organization_members_org_user (organization_id, user_id),
+    INDEX idx_organization_members_user (user_id),
+    FOREIGN KEY (organization_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE lead_routing_rules (
+    organization_id BIGINT PRIMARY KEY,
+    notify_emails VARCHAR(1000) NOT NULL DEFAULT '',
+    round_robin BOOLEAN NOT NULL DEFAULT FALSE,
+    last_assignee_id BIGINT NULL,
+    updated_at TIMESTAMP NULL,
+
+    FOREIGN KEY (organization_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE leads
+    ADD COLUMN assigned_to BIGINT NULL AFTER receiver_id,
+    ADD INDEX idx_leads_assigned_to (assigned_to);
+
+UPDATE leads SET assigned_to = receiver_id;
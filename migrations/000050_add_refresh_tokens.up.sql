@@ -0,0 +1,17 @@
+-- Refresh tokens: long-lived, stored as SHA-256 hashes and rotated on every
+-- use. Tokens rotated from the same login share a family_id so reuse of a
+-- rotated token can revoke the whole chain.
+CREATE TABLE refresh_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    family_id CHAR(32) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NULL,
+
+    UNIQUE KEY idx_refresh_tokens_token_hash (token_hash),
+    INDEX idx_refresh_tokens_user (user_id),
+    INDEX idx_refresh_tokens_family (family_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
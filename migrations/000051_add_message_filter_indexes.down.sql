@@ -0,0 +1,3 @@
+-- Drop the message list filter indexes
+DROP INDEX idx_messages_receiver_read ON messages;
+DROP INDEX idx_messages_listing_created ON messages;
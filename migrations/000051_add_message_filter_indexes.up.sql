@@ -0,0 +1,4 @@
+-- Indexes for the message list filters: unread messages of a receiver, and
+-- messages about a listing newest first
+CREATE INDEX idx_messages_receiver_read ON messages (receiver_id, is_read);
+CREATE INDEX idx_messages_listing_created ON messages (listing_id, created_at);
@@ -0,0 +1,3 @@
+-- Remove status_before_delete column from listings table
+ALTER TABLE listings
+DROP COLUMN status_before_delete;
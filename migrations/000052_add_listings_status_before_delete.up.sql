@@ -0,0 +1,3 @@
+-- The status a listing had when it was deleted, so undoing the deletion restores it
+ALTER TABLE listings
+ADD COLUMN status_before_delete VARCHAR(50) NULL AFTER delete_after;
@@ -0,0 +1,4 @@
+-- Remove refresh_family_id column from user_sessions table
+ALTER TABLE user_sessions
+DROP INDEX idx_user_sessions_refresh_family,
+DROP COLUMN refresh_family_id;
@@ -0,0 +1,5 @@
+-- Logins through /auth/login are tracked as sessions too, so the per-user cap
+-- covers them. Such a session is tied to the login's refresh token family.
+ALTER TABLE user_sessions
+ADD COLUMN refresh_family_id CHAR(32) NULL AFTER session_id,
+ADD INDEX idx_user_sessions_refresh_family (refresh_family_id);